@@ -240,7 +240,7 @@ the way that the Fleet server works.
 				}
 			}
 
-			cancelBackground := runCrons(ds, kitlog.With(logger, "component", "crons"), config)
+			cancelBackground := runCrons(ds, svc, kitlog.With(logger, "component", "crons"), config)
 
 			// Flush seen hosts every second
 			go func() {
@@ -432,6 +432,8 @@ const (
 	lockKeyLeader          = "leader"
 	lockKeyVulnerabilities = "vulnerabilities"
 	lockKeyWebhooks        = "webhooks"
+	lockKeyQuerySweeps     = "query_sweeps"
+	lockKeyLiveQueries     = "live_queries"
 )
 
 func trySendStatistics(ctx context.Context, ds fleet.Datastore, frequency time.Duration, url string) error {
@@ -458,7 +460,7 @@ func trySendStatistics(ctx context.Context, ds fleet.Datastore, frequency time.D
 	return ds.RecordStatisticsSent(ctx)
 }
 
-func runCrons(ds fleet.Datastore, logger kitlog.Logger, config config.FleetConfig) context.CancelFunc {
+func runCrons(ds fleet.Datastore, svc fleet.Service, logger kitlog.Logger, config config.FleetConfig) context.CancelFunc {
 	locker, ok := ds.(Locker)
 	if !ok {
 		initFatal(errors.New("No global locker available"), "")
@@ -470,15 +472,17 @@ func runCrons(ds fleet.Datastore, logger kitlog.Logger, config config.FleetConfi
 		initFatal(errors.New("Error generating random instance identifier"), "")
 	}
 
-	go cronCleanups(ctx, ds, kitlog.With(logger, "cron", "cleanups"), locker, ourIdentifier)
+	go cronCleanups(ctx, ds, kitlog.With(logger, "cron", "cleanups"), locker, ourIdentifier, config)
 	go cronVulnerabilities(
 		ctx, ds, kitlog.With(logger, "cron", "vulnerabilities"), locker, ourIdentifier, config)
 	go cronWebhooks(ctx, ds, kitlog.With(logger, "cron", "webhooks"), locker, ourIdentifier)
+	go cronQuerySweeps(ctx, svc, kitlog.With(logger, "cron", "query_sweeps"), locker, ourIdentifier)
+	go cronLiveQueries(ctx, svc, kitlog.With(logger, "cron", "live_queries"), locker, ourIdentifier)
 
 	return cancelBackground
 }
 
-func cronCleanups(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, locker Locker, identifier string) {
+func cronCleanups(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger, locker Locker, identifier string, config config.FleetConfig) {
 	ticker := time.NewTicker(1 * time.Hour)
 	for {
 		level.Debug(logger).Log("waiting", "on ticker")
@@ -509,6 +513,35 @@ func cronCleanups(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger,
 		if err != nil {
 			level.Error(logger).Log("err", "cleaning scheduled query stats", "details", err)
 		}
+		err = ds.CleanupOrphanScheduledQueryResults(ctx)
+		if err != nil {
+			level.Error(logger).Log("err", "cleaning scheduled query results", "details", err)
+		}
+		err = ds.CleanupDistributedQueryCampaignResults(ctx, time.Now().Add(-config.Osquery.LiveQueryResultsRetention))
+		if err != nil {
+			level.Error(logger).Log("err", "cleaning distributed query campaign results", "details", err)
+		}
+		// Keep the hosts of the campaigns that may still be running.
+		campaignHostsRetention := config.Osquery.LiveQueryResultsRetention
+		if campaignHostsRetention < config.Osquery.LiveQueryTimeout {
+			campaignHostsRetention = config.Osquery.LiveQueryTimeout
+		}
+		err = ds.CleanupDistributedQueryCampaignHosts(ctx, time.Now().Add(-campaignHostsRetention))
+		if err != nil {
+			level.Error(logger).Log("err", "cleaning distributed query campaign hosts", "details", err)
+		}
+		err = ds.CleanupPolicyMembershipHistory(ctx, time.Now().Add(-fleet.PolicyHistoryRetention))
+		if err != nil {
+			level.Error(logger).Log("err", "cleaning policy membership history", "details", err)
+		}
+		err = ds.CalculateSoftwareHostCounts(ctx, time.Now())
+		if err != nil {
+			level.Error(logger).Log("err", "calculating software host counts", "details", err)
+		}
+		err = ds.CalculateOSVersionHostCounts(ctx, time.Now())
+		if err != nil {
+			level.Error(logger).Log("err", "calculating os version host counts", "details", err)
+		}
 
 		err = trySendStatistics(ctx, ds, fleet.StatisticsFrequency, "https://fleetdm.com/api/v1/webhooks/receive-usage-analytics")
 		if err != nil {
@@ -583,7 +616,12 @@ func cronVulnerabilities(
 			}
 		}
 
-		err := vulnerabilities.TranslateSoftwareToCPE(ctx, ds, vulnPath, logger, config)
+		err := vulnerabilities.TranslateOVALToCVE(ctx, ds, vulnPath, logger, config)
+		if err != nil {
+			level.Error(logger).Log("msg", "analyzing vulnerable software: OVAL", "err", err)
+		}
+
+		err = vulnerabilities.TranslateSoftwareToCPE(ctx, ds, vulnPath, logger, config)
 		if err != nil {
 			level.Error(logger).Log("msg", "analyzing vulnerable software: Software->CPE", "err", err)
 			continue
@@ -595,6 +633,24 @@ func cronVulnerabilities(
 			continue
 		}
 
+		// Reread app config to pick up changes to the vulnerabilities webhook.
+		appConfig, err := ds.AppConfig(ctx)
+		if err != nil {
+			level.Error(logger).Log("config", "couldn't read app config", "err", err)
+			continue
+		}
+		// The CVEs found while the webhook is disabled are never sent.
+		err = ds.SyncVulnerableHostCVEs(ctx, appConfig.WebhookSettings.VulnerabilitiesWebhook.Enable)
+		if err != nil {
+			level.Error(logger).Log("msg", "syncing vulnerable hosts", "err", err)
+			continue
+		}
+		err = webhooks.TriggerVulnerabilitiesWebhook(
+			ctx, ds, kitlog.With(logger, "webhook", "vulnerabilities"), appConfig)
+		if err != nil {
+			level.Error(logger).Log("err", "triggering vulnerabilities webhook", "details", err)
+		}
+
 		level.Debug(logger).Log("loop", "done")
 	}
 }
@@ -628,6 +684,12 @@ func cronWebhooks(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger,
 			level.Error(logger).Log("err", "triggering host status webhook", "details", err)
 		}
 
+		err = webhooks.TriggerFailingPoliciesWebhook(
+			ctx, ds, kitlog.With(logger, "webhook", "failing_policies"), appConfig)
+		if err != nil {
+			level.Error(logger).Log("err", "triggering failing policies webhook", "details", err)
+		}
+
 		// Reread app config to be able to change interval somewhat on the fly
 		appConfig, err = ds.AppConfig(ctx)
 		if err != nil {
@@ -641,6 +703,57 @@ func cronWebhooks(ctx context.Context, ds fleet.Datastore, logger kitlog.Logger,
 	}
 }
 
+func cronQuerySweeps(ctx context.Context, svc fleet.Service, logger kitlog.Logger, locker Locker, identifier string) {
+	ticker := time.NewTicker(1 * time.Minute)
+	for {
+		level.Debug(logger).Log("waiting", "on ticker")
+		select {
+		case <-ticker.C:
+			level.Debug(logger).Log("waiting", "done")
+		case <-ctx.Done():
+			level.Debug(logger).Log("exit", "done with cron.")
+			return
+		}
+		if locked, err := locker.Lock(ctx, lockKeyQuerySweeps, identifier, time.Minute); err != nil || !locked {
+			level.Debug(logger).Log("leader", "Not the leader. Skipping...")
+			continue
+		}
+
+		if err := svc.RunQuerySweeps(ctx, time.Now()); err != nil {
+			level.Error(logger).Log("err", "running query sweeps", "details", err)
+		}
+
+		level.Debug(logger).Log("loop", "done")
+	}
+}
+
+// cronLiveQueries completes the live query campaigns that timed out, which
+// are otherwise left running when the server listening to their results
+// stops.
+func cronLiveQueries(ctx context.Context, svc fleet.Service, logger kitlog.Logger, locker Locker, identifier string) {
+	ticker := time.NewTicker(1 * time.Minute)
+	for {
+		level.Debug(logger).Log("waiting", "on ticker")
+		select {
+		case <-ticker.C:
+			level.Debug(logger).Log("waiting", "done")
+		case <-ctx.Done():
+			level.Debug(logger).Log("exit", "done with cron.")
+			return
+		}
+		if locked, err := locker.Lock(ctx, lockKeyLiveQueries, identifier, time.Minute); err != nil || !locked {
+			level.Debug(logger).Log("leader", "Not the leader. Skipping...")
+			continue
+		}
+
+		if err := svc.CompleteExpiredCampaigns(ctx, time.Now()); err != nil {
+			level.Error(logger).Log("err", "completing expired campaigns", "details", err)
+		}
+
+		level.Debug(logger).Log("loop", "done")
+	}
+}
+
 // Support for TLS security profiles, we set up the TLS configuation based on
 // value supplied to server_tls_compatibility command line flag. The default
 // profile is 'modern'.
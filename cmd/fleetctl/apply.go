@@ -23,10 +23,11 @@ type specMetadata struct {
 }
 
 type specGroup struct {
-	Queries []*fleet.QuerySpec
-	Teams   []*fleet.TeamSpec
-	Packs   []*fleet.PackSpec
-	Labels  []*fleet.LabelSpec
+	Queries  []*fleet.QuerySpec
+	Teams    []*fleet.TeamSpec
+	Packs    []*fleet.PackSpec
+	Labels   []*fleet.LabelSpec
+	Policies []*fleet.PolicySpec
 	// This needs to be interface{} to allow for the patch logic. Otherwise we send a request that looks to the
 	// server like the user explicitly set the zero values.
 	AppConfig    interface{}
@@ -79,6 +80,13 @@ func specGroupFromBytes(b []byte) (*specGroup, error) {
 			}
 			specs.Labels = append(specs.Labels, labelSpec)
 
+		case fleet.PolicyKind:
+			var policySpec *fleet.PolicySpec
+			if err := yaml.Unmarshal(s.Spec, &policySpec); err != nil {
+				return nil, errors.Wrap(err, "unmarshaling "+kind+" spec")
+			}
+			specs.Policies = append(specs.Policies, policySpec)
+
 		case fleet.AppConfigKind:
 			if specs.AppConfig != nil {
 				return nil, errors.New("config defined twice in the same file")
@@ -177,6 +185,13 @@ func applyCommand() *cli.Command {
 				logf(c, "[+] applied %d labels\n", len(specs.Labels))
 			}
 
+			if len(specs.Policies) > 0 {
+				if err := fleetClient.ApplyPolicies(specs.Policies); err != nil {
+					return errors.Wrap(err, "applying policies")
+				}
+				logf(c, "[+] applied %d policies\n", len(specs.Policies))
+			}
+
 			if len(specs.Packs) > 0 {
 				if err := fleetClient.ApplyPacks(specs.Packs); err != nil {
 					return errors.Wrap(err, "applying packs")
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"io/ioutil"
 	"os"
 	"testing"
@@ -234,3 +235,52 @@ spec:
 	assert.True(t, savedAppConfig.HostSettings.EnableHostUsers)
 	assert.True(t, savedAppConfig.HostSettings.EnableSoftwareInventory)
 }
+
+func TestApplyPolicies(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	var appliedPolicySpecs []*fleet.PolicySpec
+	ds.ApplyPolicySpecsFunc = func(ctx context.Context, specs []*fleet.PolicySpec) error {
+		appliedPolicySpecs = specs
+		return nil
+	}
+	ds.TeamByNameFunc = func(ctx context.Context, name string) (*fleet.Team, error) {
+		if name == "Team1" {
+			return &fleet.Team{ID: 123}, nil
+		}
+		return nil, errors.New("unexpected team name!")
+	}
+
+	tmpFile, err := ioutil.TempFile(os.TempDir(), "*.yml")
+	require.NoError(t, err)
+	defer os.Remove(tmpFile.Name())
+
+	tmpFile.WriteString(`
+---
+apiVersion: v1
+kind: policy
+spec:
+  name: Is Gatekeeper enabled on macOS devices?
+  query: SELECT 1 FROM gatekeeper WHERE assessments_enabled = 1;
+  description: Checks to make sure that the Gatekeeper feature is enabled on macOS devices.
+  resolution: "Resolution steps: Use your MDM to turn on Gatekeeper."
+  platform: darwin
+---
+apiVersion: v1
+kind: policy
+spec:
+  name: Is disk encryption enabled on Windows devices?
+  query: SELECT 1 FROM bitlocker_info WHERE protection_status = 1;
+  description: Checks to make sure that device encryption is enabled on Windows devices.
+  team: Team1
+  platform: windows
+`)
+
+	assert.Equal(t, "[+] applied 2 policies\n", runAppForTest(t, []string{"apply", "-f", tmpFile.Name()}))
+	require.Len(t, appliedPolicySpecs, 2)
+	assert.Equal(t, "Is Gatekeeper enabled on macOS devices?", appliedPolicySpecs[0].Name)
+	assert.Equal(t, "darwin", appliedPolicySpecs[0].Platform)
+	assert.Equal(t, "", appliedPolicySpecs[0].Team)
+	assert.Equal(t, "Team1", appliedPolicySpecs[1].Team)
+	assert.Equal(t, "windows", appliedPolicySpecs[1].Platform)
+}
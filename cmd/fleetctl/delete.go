@@ -85,6 +85,18 @@ func deleteCommand() *cli.Command {
 				}
 			}
 
+			for _, policy := range specs.Policies {
+				fmt.Printf("[+] deleting policy %q\n", policy.Name)
+				if err := fleet.DeletePolicy(policy); err != nil {
+					switch err.(type) {
+					case service.NotFoundErr:
+						fmt.Printf("[!] policy %q doesn't exist\n", policy.Name)
+						continue
+					}
+					return err
+				}
+			}
+
 			return nil
 		},
 	}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/fleetdm/fleet/v4/pkg/secure"
 	"gopkg.in/guregu/null.v3"
@@ -24,6 +28,14 @@ const (
 	withQueriesFlagName = "with-queries"
 	expiredFlagName     = "expired"
 	stdoutFlagName      = "stdout"
+	historyFlagName     = "history"
+	daysFlagName        = "days"
+	minSeverityFlagName = "min-severity"
+	vulnerableFlagName  = "vulnerable"
+	sourceFlagName      = "source"
+	queryFlagName       = "query"
+	platformFlagName    = "platform"
+	formatFlagName      = "format"
 )
 
 type specGeneric struct {
@@ -102,6 +114,16 @@ func printQuery(c *cli.Context, query *fleet.QuerySpec) error {
 	return printSpec(c, spec)
 }
 
+func printPolicy(c *cli.Context, policy *fleet.PolicySpec) error {
+	spec := specGeneric{
+		Kind:    fleet.PolicyKind,
+		Version: fleet.ApiVersion,
+		Spec:    policy,
+	}
+
+	return printSpec(c, spec)
+}
+
 func printPack(c *cli.Context, pack *fleet.PackSpec) error {
 	spec := specGeneric{
 		Kind:    fleet.PackKind,
@@ -238,6 +260,9 @@ func getCommand() *cli.Command {
 			getUserRolesCommand(),
 			getTeamsCommand(),
 			getSoftwareCommand(),
+			getPoliciesCommand(),
+			getOSVersionsCommand(),
+			getCampaignResultsCommand(),
 		},
 	}
 }
@@ -577,6 +602,26 @@ func getHostsCommand() *cli.Command {
 				Usage:    "filter hosts by team_id",
 				Required: false,
 			},
+			&cli.UintFlag{
+				Name:     "software",
+				Usage:    "filter hosts by software_id installed",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "cve",
+				Usage:    "filter hosts by CVE affecting their software",
+				Required: false,
+			},
+			&cli.StringFlag{
+				Name:     "os-version",
+				Usage:    "filter hosts by OS version",
+				Required: false,
+			},
+			&cli.IntSliceFlag{
+				Name:     "label",
+				Usage:    "filter hosts by label_id, repeat to list hosts members of all the labels",
+				Required: false,
+			},
 			jsonFlag(),
 			yamlFlag(),
 			configFlag(),
@@ -592,11 +637,27 @@ func getHostsCommand() *cli.Command {
 			identifier := c.Args().First()
 
 			if identifier == "" {
-				query := ""
+				query := url.Values{}
 				if c.Uint("team") > 0 {
-					query = fmt.Sprintf("team_id=%d", c.Uint("team"))
+					query.Set("team_id", fmt.Sprint(c.Uint("team")))
+				}
+				if c.Uint("software") > 0 {
+					query.Set("software_id", fmt.Sprint(c.Uint("software")))
+				}
+				if cve := c.String("cve"); cve != "" {
+					query.Set("cve", cve)
+				}
+				if osVersion := c.String("os-version"); osVersion != "" {
+					query.Set("os_version", osVersion)
+				}
+				if labelIDs := c.IntSlice("label"); len(labelIDs) > 0 {
+					ids := make([]string, 0, len(labelIDs))
+					for _, id := range labelIDs {
+						ids = append(ids, strconv.Itoa(id))
+					}
+					query.Set("label_ids", strings.Join(ids, ","))
 				}
-				hosts, err := client.GetHosts(query)
+				hosts, err := client.GetHosts(query.Encode())
 				if err != nil {
 					return errors.Wrap(err, "could not list hosts")
 				}
@@ -911,6 +972,22 @@ func getSoftwareCommand() *cli.Command {
 				Name:  teamFlagName,
 				Usage: "Only list software of hosts that belong to the specified team",
 			},
+			&cli.StringFlag{
+				Name:  minSeverityFlagName,
+				Usage: "Only list software with vulnerabilities of at least this severity (low, medium, high or critical)",
+			},
+			&cli.BoolFlag{
+				Name:  vulnerableFlagName,
+				Usage: "Only list software with vulnerabilities",
+			},
+			&cli.StringFlag{
+				Name:  sourceFlagName,
+				Usage: "Only list software of this source, such as apps, deb_packages or chrome_extensions",
+			},
+			&cli.StringFlag{
+				Name:  queryFlagName,
+				Usage: "Only list software with a name or version matching this search query",
+			},
 			jsonFlag(),
 			yamlFlag(),
 			configFlag(),
@@ -927,14 +1004,24 @@ func getSoftwareCommand() *cli.Command {
 				return errors.New("Can't specify both yaml and json flags.")
 			}
 
-			var teamID *uint
-
-			teamIDFlag := c.Uint(teamFlagName)
-			if teamIDFlag != 0 {
-				teamID = &teamIDFlag
+			query := url.Values{}
+			if teamID := c.Uint(teamFlagName); teamID != 0 {
+				query.Set("team_id", fmt.Sprint(teamID))
+			}
+			if minSeverity := c.String(minSeverityFlagName); minSeverity != "" {
+				query.Set("min_severity", minSeverity)
+			}
+			if c.Bool(vulnerableFlagName) {
+				query.Set("vulnerable", "true")
+			}
+			if source := c.String(sourceFlagName); source != "" {
+				query.Set("source", source)
+			}
+			if search := c.String(queryFlagName); search != "" {
+				query.Set("query", search)
 			}
 
-			software, err := client.ListSoftware(teamID)
+			software, err := client.ListSoftware(query.Encode())
 			if err != nil {
 				return errors.Wrap(err, "could not list software")
 			}
@@ -961,18 +1048,338 @@ func getSoftwareCommand() *cli.Command {
 			data := [][]string{}
 
 			for _, s := range software {
+				maxScore := ""
+				if s.MaxCVSSScore != nil {
+					maxScore = fmt.Sprintf("%.1f", *s.MaxCVSSScore)
+				}
 				data = append(data, []string{
 					s.Name,
 					s.Version,
 					s.Source,
 					s.GenerateCPE,
 					fmt.Sprint(len(s.Vulnerabilities)),
+					maxScore,
+					fmt.Sprint(s.HostsCount),
 				})
 			}
-			columns := []string{"Name", "Version", "Source", "CPE", "# of CVEs"}
+			columns := []string{"Name", "Version", "Source", "CPE", "# of CVEs", "Max CVSS", "Hosts"}
 			printTable(c, columns, data)
 
 			return nil
 		},
 	}
 }
+
+func getOSVersionsCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "os_versions",
+		Aliases: []string{"os_version", "os"},
+		Usage:   "List the number of hosts per platform, OS version and osquery version",
+		Flags: []cli.Flag{
+			&cli.UintFlag{
+				Name:  teamFlagName,
+				Usage: "Only count the hosts that belong to the specified team",
+			},
+			&cli.StringFlag{
+				Name:  platformFlagName,
+				Usage: "Only count the hosts of this platform, such as darwin, windows or ubuntu",
+			},
+			jsonFlag(),
+			yamlFlag(),
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			if c.Bool(yamlFlagName) && c.Bool(jsonFlagName) {
+				return errors.New("Can't specify both yaml and json flags.")
+			}
+
+			query := url.Values{}
+			if teamID := c.Uint(teamFlagName); teamID != 0 {
+				query.Set("team_id", fmt.Sprint(teamID))
+			}
+			if platform := c.String(platformFlagName); platform != "" {
+				query.Set("platform", platform)
+			}
+
+			osVersions, err := client.OSVersions(query.Encode())
+			if err != nil {
+				return errors.Wrap(err, "could not list os versions")
+			}
+
+			if len(osVersions.OSVersions) == 0 {
+				log(c, "No OS versions found")
+				return nil
+			}
+
+			if c.Bool(jsonFlagName) || c.Bool(yamlFlagName) {
+				spec := specGeneric{
+					Kind:    "os_versions",
+					Version: "1",
+					Spec:    osVersions,
+				}
+				return printSpec(c, spec)
+			}
+
+			// Default to printing as table
+			data := [][]string{}
+			for _, v := range osVersions.OSVersions {
+				data = append(data, []string{
+					v.Platform,
+					v.OSVersion,
+					v.OsqueryVersion,
+					fmt.Sprint(v.HostsCount),
+				})
+			}
+			columns := []string{"Platform", "OS version", "Osquery version", "Hosts"}
+			printTable(c, columns, data)
+
+			return nil
+		},
+	}
+}
+
+func getPoliciesCommand() *cli.Command {
+	return &cli.Command{
+		Name:    "policies",
+		Aliases: []string{"policy"},
+		Usage:   "List policies",
+		Flags: []cli.Flag{
+			&cli.UintFlag{
+				Name:  teamFlagName,
+				Usage: "Only list policies that belong to the specified team",
+			},
+			&cli.BoolFlag{
+				Name:  historyFlagName,
+				Usage: "Show the days failing and pass rate of the policies over time",
+			},
+			&cli.UintFlag{
+				Name:  daysFlagName,
+				Usage: "Number of days the history is computed for (default 30)",
+			},
+			jsonFlag(),
+			yamlFlag(),
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			if c.Bool(yamlFlagName) && c.Bool(jsonFlagName) {
+				return errors.New("Can't specify both yaml and json flags.")
+			}
+
+			var teamID *uint
+
+			teamIDFlag := c.Uint(teamFlagName)
+			if teamIDFlag != 0 {
+				teamID = &teamIDFlag
+			}
+
+			if c.Bool(historyFlagName) {
+				return printPoliciesHistory(c, client, teamID)
+			}
+
+			policies, err := client.ListPolicies(teamID)
+			if err != nil {
+				return errors.Wrap(err, "could not list policies")
+			}
+
+			if len(policies) == 0 {
+				log(c, "No policies found")
+				return nil
+			}
+
+			if c.Bool(jsonFlagName) || c.Bool(yamlFlagName) {
+				teamName := ""
+				if teamID != nil {
+					teams, err := client.ListTeams()
+					if err != nil {
+						return errors.Wrap(err, "could not list teams")
+					}
+					for _, team := range teams {
+						if team.ID == *teamID {
+							teamName = team.Name
+						}
+					}
+				}
+				for _, p := range policies {
+					if err := printPolicy(c, &fleet.PolicySpec{
+						Name:        p.Name,
+						Query:       p.Query,
+						Description: p.Description,
+						Resolution:  p.Resolution,
+						Team:        teamName,
+						Platform:    p.Platform,
+					}); err != nil {
+						return errors.Wrap(err, "unable to print policy")
+					}
+				}
+				return nil
+			}
+
+			// Default to printing as table
+			data := [][]string{}
+
+			for _, p := range policies {
+				data = append(data, []string{
+					fmt.Sprint(p.ID),
+					p.Name,
+					fmt.Sprint(p.PassingHostCount),
+					fmt.Sprint(p.FailingHostCount),
+				})
+			}
+			columns := []string{"ID", "Name", "Passing", "Failing"}
+			printTable(c, columns, data)
+
+			return nil
+		},
+	}
+}
+
+func printPoliciesHistory(c *cli.Context, client *service.Client, teamID *uint) error {
+	summaries, err := client.ListPoliciesHistory(c.Uint(daysFlagName))
+	if err != nil {
+		return errors.Wrap(err, "could not list policies history")
+	}
+
+	if teamID != nil {
+		var teamSummaries []*fleet.PolicyHistorySummary
+		for _, s := range summaries {
+			if s.TeamID != nil && *s.TeamID == *teamID {
+				teamSummaries = append(teamSummaries, s)
+			}
+		}
+		summaries = teamSummaries
+	}
+
+	if len(summaries) == 0 {
+		log(c, "No policies found")
+		return nil
+	}
+
+	if c.Bool(jsonFlagName) || c.Bool(yamlFlagName) {
+		spec := specGeneric{
+			Kind:    "policy_history",
+			Version: "1",
+			Spec:    summaries,
+		}
+		return printSpec(c, spec)
+	}
+
+	// Default to printing as table
+	data := [][]string{}
+
+	for _, s := range summaries {
+		team := ""
+		if s.TeamID != nil {
+			team = fmt.Sprint(*s.TeamID)
+		}
+		data = append(data, []string{
+			fmt.Sprint(s.PolicyID),
+			s.Name,
+			team,
+			fmt.Sprint(s.FailingHostCount),
+			fmt.Sprintf("%.1f", s.DaysFailing),
+			fmt.Sprintf("%.1f%%", s.PassRate*100),
+		})
+	}
+	columns := []string{"ID", "Name", "Team", "Failing", "Days failing", "Pass rate"}
+	printTable(c, columns, data)
+
+	return nil
+}
+
+func getCampaignResultsCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "campaign-results",
+		Aliases:   []string{"campaign-result"},
+		Usage:     "Retrieve the stored results of a live query campaign by ID",
+		ArgsUsage: "<campaign ID>",
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  formatFlagName,
+				Usage: "Export the results in this format instead of printing a table, one of csv or json (csv if --outfile is set)",
+			},
+			outfileFlag(),
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			idString := c.Args().First()
+			if idString == "" {
+				return errors.Errorf("must provide campaign ID as first argument")
+			}
+			id, err := strconv.ParseUint(idString, 10, 64)
+			if err != nil {
+				return errors.Wrap(err, "unable to parse campaign ID as int")
+			}
+
+			outFile := getOutfile(c)
+			format := c.String(formatFlagName)
+			switch format {
+			case "":
+				if outFile != "" {
+					format = "csv"
+				}
+			case "csv", "json":
+			default:
+				return errors.Errorf("unsupported format %q, must be one of csv or json", format)
+			}
+
+			if format == "" {
+				// The table is printed from the CSV export, that already
+				// lays out the results of all hosts in the same columns.
+				data, err := client.ExportCampaignResults(uint(id), "csv")
+				if err != nil {
+					return err
+				}
+				records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+				if err != nil {
+					return errors.Wrap(err, "parse campaign results")
+				}
+				if len(records) < 2 {
+					log(c, "No results found")
+					return nil
+				}
+				printTable(c, records[0], records[1:])
+				return nil
+			}
+
+			data, err := client.ExportCampaignResults(uint(id), format)
+			if err != nil {
+				return err
+			}
+
+			out := writerOrStdout(c.App.Writer)
+			if outFile != "" {
+				f, err := secure.OpenFile(outFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, defaultFileMode)
+				if err != nil {
+					return errors.Wrap(err, "open out file")
+				}
+				defer f.Close()
+				out = f
+			}
+			if _, err := out.Write(data); err != nil {
+				return errors.Wrap(err, "write campaign results")
+			}
+			return nil
+		},
+	}
+}
@@ -249,6 +249,9 @@ func TestGetHosts(t *testing.T) {
 	ds.LoadHostSoftwareFunc = func(ctx context.Context, host *fleet.Host) error {
 		return nil
 	}
+	ds.LoadHostVitalsFunc = func(ctx context.Context, host *fleet.Host) error {
+		return nil
+	}
 	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
 		return make([]*fleet.Label, 0), nil
 	}
@@ -339,6 +342,26 @@ func TestGetHosts(t *testing.T) {
 	assert.Equal(t, expectedText, runAppForTest(t, []string{"get", "hosts"}))
 }
 
+func TestGetHostsFilters(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	var gotOpt fleet.HostListOptions
+	ds.ListHostsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.HostListOptions) ([]*fleet.Host, error) {
+		gotOpt = opt
+		return []*fleet.Host{{Hostname: "test_host"}}, nil
+	}
+
+	runAppForTest(t, []string{
+		"get", "hosts", "--team", "1", "--software", "2", "--cve", "CVE-2021-0001",
+		"--os-version", "macOS 11.6", "--label", "3", "--label", "4",
+	})
+	assert.Equal(t, ptr.Uint(1), gotOpt.TeamFilter)
+	assert.Equal(t, ptr.Uint(2), gotOpt.SoftwareIDFilter)
+	assert.Equal(t, "CVE-2021-0001", gotOpt.CVEFilter)
+	assert.Equal(t, "macOS 11.6", gotOpt.OSVersionFilter)
+	assert.Equal(t, []uint{3, 4}, gotOpt.LabelIDsFilter)
+}
+
 func TestGetConfig(t *testing.T) {
 	_, ds := runServerWithMockedDS(t)
 
@@ -381,25 +404,44 @@ spec:
     user_name: ""
     verify_ssl_certs: false
   sso_settings:
+    enable_jit_provisioning: false
     enable_sso: false
     enable_sso_idp_login: false
     entity_id: ""
     idp_image_url: ""
     idp_name: ""
     issuer_uri: ""
+    jit_group_roles: ""
+    jit_role_attribute: ""
+    jit_team_ids: null
     metadata: ""
     metadata_url: ""
+    oidc_client_id: ""
+    oidc_client_secret: ""
+    oidc_email_claim: ""
+    oidc_issuer_url: ""
+    oidc_scopes: null
+    provider_type: ""
   vulnerability_settings:
     databases_path: /some/path
   webhook_settings:
+    failing_policies_webhook:
+      destination_url: ""
+      enable_failing_policies_webhook: false
+      host_batch_size: 0
+      policy_ids: null
     host_status_webhook:
       days_count: 0
       destination_url: ""
       enable_host_status_webhook: false
       host_percentage: 0
     interval: 0s
+    vulnerabilities_webhook:
+      destination_url: ""
+      enable_vulnerabilities_webhook: false
+      host_batch_size: 0
 `
-	expectedJson := `{"kind":"config","apiVersion":"v1","spec":{"org_info":{"org_name":"","org_logo_url":""},"server_settings":{"server_url":"","live_query_disabled":false,"enable_analytics":false},"smtp_settings":{"enable_smtp":false,"configured":false,"sender_address":"","server":"","port":0,"authentication_type":"","user_name":"","password":"","enable_ssl_tls":false,"authentication_method":"","domain":"","verify_ssl_certs":false,"enable_start_tls":false},"host_expiry_settings":{"host_expiry_enabled":false,"host_expiry_window":0},"host_settings":{"enable_host_users":true,"enable_software_inventory":false},"sso_settings":{"entity_id":"","issuer_uri":"","idp_image_url":"","metadata":"","metadata_url":"","idp_name":"","enable_sso":false,"enable_sso_idp_login":false},"vulnerability_settings":{"databases_path":"/some/path"},"webhook_settings":{"host_status_webhook":{"enable_host_status_webhook":false,"destination_url":"","host_percentage":0,"days_count":0},"interval":"0s"}}}
+	expectedJson := `{"kind":"config","apiVersion":"v1","spec":{"org_info":{"org_name":"","org_logo_url":""},"server_settings":{"server_url":"","live_query_disabled":false,"enable_analytics":false},"smtp_settings":{"enable_smtp":false,"configured":false,"sender_address":"","server":"","port":0,"authentication_type":"","user_name":"","password":"","enable_ssl_tls":false,"authentication_method":"","domain":"","verify_ssl_certs":false,"enable_start_tls":false},"host_expiry_settings":{"host_expiry_enabled":false,"host_expiry_window":0},"host_settings":{"enable_host_users":true,"enable_software_inventory":false},"sso_settings":{"entity_id":"","issuer_uri":"","idp_image_url":"","metadata":"","metadata_url":"","idp_name":"","enable_sso":false,"enable_sso_idp_login":false,"provider_type":"","oidc_issuer_url":"","oidc_client_id":"","oidc_client_secret":"","oidc_scopes":null,"oidc_email_claim":"","enable_jit_provisioning":false,"jit_role_attribute":"","jit_group_roles":"","jit_team_ids":null},"vulnerability_settings":{"databases_path":"/some/path"},"webhook_settings":{"host_status_webhook":{"enable_host_status_webhook":false,"destination_url":"","host_percentage":0,"days_count":0},"failing_policies_webhook":{"enable_failing_policies_webhook":false,"destination_url":"","policy_ids":null,"host_batch_size":0},"vulnerabilities_webhook":{"enable_vulnerabilities_webhook":false,"destination_url":"","host_batch_size":0},"interval":"0s"}}}
 `
 
 	assert.Equal(t, expectedYaml, runAppForTest(t, []string{"get", "config"}))
@@ -413,32 +455,37 @@ func TestGetSoftawre(t *testing.T) {
 	foo001 := fleet.Software{
 		Name: "foo", Version: "0.0.1", Source: "chrome_extensions", GenerateCPE: "somecpe",
 		Vulnerabilities: fleet.VulnerabilitiesSlice{
-			{CVE: "cve-321-432-543", DetailsLink: "https://nvd.nist.gov/vuln/detail/cve-321-432-543"},
+			{
+				CVE: "cve-321-432-543", DetailsLink: "https://nvd.nist.gov/vuln/detail/cve-321-432-543",
+				CVSSScore: ptr.Float64(9.8), Severity: ptr.String("CRITICAL"),
+			},
 			{CVE: "cve-333-444-555", DetailsLink: "https://nvd.nist.gov/vuln/detail/cve-333-444-555"},
 		},
+		MaxCVSSScore: ptr.Float64(9.8),
+		HostsCount:   2,
 	}
-	foo002 := fleet.Software{Name: "foo", Version: "0.0.2", Source: "chrome_extensions"}
-	foo003 := fleet.Software{Name: "foo", Version: "0.0.3", Source: "chrome_extensions", GenerateCPE: "someothercpewithoutvulns"}
-	bar003 := fleet.Software{Name: "bar", Version: "0.0.3", Source: "deb_packages"}
+	foo002 := fleet.Software{Name: "foo", Version: "0.0.2", Source: "chrome_extensions", HostsCount: 1}
+	foo003 := fleet.Software{Name: "foo", Version: "0.0.3", Source: "chrome_extensions", GenerateCPE: "someothercpewithoutvulns", HostsCount: 1}
+	bar003 := fleet.Software{Name: "bar", Version: "0.0.3", Source: "deb_packages", HostsCount: 1}
 
-	var gotTeamID *uint
+	var gotOpt fleet.SoftwareListOptions
 
-	ds.ListSoftwareFunc = func(ctx context.Context, teamId *uint, opt fleet.ListOptions) ([]fleet.Software, error) {
-		gotTeamID = teamId
+	ds.ListSoftwareFunc = func(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, error) {
+		gotOpt = opt
 		return []fleet.Software{foo001, foo002, foo003, bar003}, nil
 	}
 
-	expected := `+------+---------+-------------------+--------------------------+-----------+
-| NAME | VERSION |      SOURCE       |           CPE            | # OF CVES |
-+------+---------+-------------------+--------------------------+-----------+
-| foo  | 0.0.1   | chrome_extensions | somecpe                  |         2 |
-+------+---------+-------------------+--------------------------+-----------+
-| foo  | 0.0.2   | chrome_extensions |                          |         0 |
-+------+---------+-------------------+--------------------------+-----------+
-| foo  | 0.0.3   | chrome_extensions | someothercpewithoutvulns |         0 |
-+------+---------+-------------------+--------------------------+-----------+
-| bar  | 0.0.3   | deb_packages      |                          |         0 |
-+------+---------+-------------------+--------------------------+-----------+
+	expected := `+------+---------+-------------------+--------------------------+-----------+----------+-------+
+| NAME | VERSION |      SOURCE       |           CPE            | # OF CVES | MAX CVSS | HOSTS |
++------+---------+-------------------+--------------------------+-----------+----------+-------+
+| foo  | 0.0.1   | chrome_extensions | somecpe                  |         2 |      9.8 |     2 |
++------+---------+-------------------+--------------------------+-----------+----------+-------+
+| foo  | 0.0.2   | chrome_extensions |                          |         0 |          |     1 |
++------+---------+-------------------+--------------------------+-----------+----------+-------+
+| foo  | 0.0.3   | chrome_extensions | someothercpewithoutvulns |         0 |          |     1 |
++------+---------+-------------------+--------------------------+-----------+----------+-------+
+| bar  | 0.0.3   | deb_packages      |                          |         0 |          |     1 |
++------+---------+-------------------+--------------------------+-----------+----------+-------+
 `
 
 	expectedYaml := `---
@@ -446,35 +493,42 @@ apiVersion: "1"
 kind: software
 spec:
 - generated_cpe: somecpe
+  hosts_count: 2
   id: 0
+  max_cvss_score: 9.8
   name: foo
   source: chrome_extensions
   version: 0.0.1
   vulnerabilities:
   - cve: cve-321-432-543
+    cvss_score: 9.8
     details_link: https://nvd.nist.gov/vuln/detail/cve-321-432-543
+    severity: CRITICAL
   - cve: cve-333-444-555
     details_link: https://nvd.nist.gov/vuln/detail/cve-333-444-555
 - generated_cpe: ""
+  hosts_count: 1
   id: 0
   name: foo
   source: chrome_extensions
   version: 0.0.2
   vulnerabilities: null
 - generated_cpe: someothercpewithoutvulns
+  hosts_count: 1
   id: 0
   name: foo
   source: chrome_extensions
   version: 0.0.3
   vulnerabilities: null
 - generated_cpe: ""
+  hosts_count: 1
   id: 0
   name: bar
   source: deb_packages
   version: 0.0.3
   vulnerabilities: null
 `
-	expectedJson := `{"kind":"software","apiVersion":"1","spec":[{"id":0,"name":"foo","version":"0.0.1","source":"chrome_extensions","generated_cpe":"somecpe","vulnerabilities":[{"cve":"cve-321-432-543","details_link":"https://nvd.nist.gov/vuln/detail/cve-321-432-543"},{"cve":"cve-333-444-555","details_link":"https://nvd.nist.gov/vuln/detail/cve-333-444-555"}]},{"id":0,"name":"foo","version":"0.0.2","source":"chrome_extensions","generated_cpe":"","vulnerabilities":null},{"id":0,"name":"foo","version":"0.0.3","source":"chrome_extensions","generated_cpe":"someothercpewithoutvulns","vulnerabilities":null},{"id":0,"name":"bar","version":"0.0.3","source":"deb_packages","generated_cpe":"","vulnerabilities":null}]}
+	expectedJson := `{"kind":"software","apiVersion":"1","spec":[{"id":0,"name":"foo","version":"0.0.1","source":"chrome_extensions","generated_cpe":"somecpe","vulnerabilities":[{"cve":"cve-321-432-543","details_link":"https://nvd.nist.gov/vuln/detail/cve-321-432-543","cvss_score":9.8,"severity":"CRITICAL"},{"cve":"cve-333-444-555","details_link":"https://nvd.nist.gov/vuln/detail/cve-333-444-555"}],"max_cvss_score":9.8,"hosts_count":2},{"id":0,"name":"foo","version":"0.0.2","source":"chrome_extensions","generated_cpe":"","vulnerabilities":null,"hosts_count":1},{"id":0,"name":"foo","version":"0.0.3","source":"chrome_extensions","generated_cpe":"someothercpewithoutvulns","vulnerabilities":null,"hosts_count":1},{"id":0,"name":"bar","version":"0.0.3","source":"deb_packages","generated_cpe":"","vulnerabilities":null,"hosts_count":1}]}
 `
 
 	assert.Equal(t, expected, runAppForTest(t, []string{"get", "software"}))
@@ -482,6 +536,168 @@ spec:
 	assert.Equal(t, expectedJson, runAppForTest(t, []string{"get", "software", "--json"}))
 
 	runAppForTest(t, []string{"get", "software", "--json", "--team", "999"})
-	require.NotNil(t, gotTeamID)
-	assert.Equal(t, uint(999), *gotTeamID)
+	require.NotNil(t, gotOpt.TeamID)
+	assert.Equal(t, uint(999), *gotOpt.TeamID)
+	assert.Empty(t, gotOpt.MinSeverity)
+	assert.False(t, gotOpt.VulnerableOnly)
+	assert.Empty(t, gotOpt.Source)
+	assert.Empty(t, gotOpt.MatchQuery)
+
+	runAppForTest(t, []string{"get", "software", "--json", "--min-severity", "high"})
+	assert.Equal(t, "high", gotOpt.MinSeverity)
+
+	runAppForTest(t, []string{"get", "software", "--json", "--vulnerable", "--source", "deb_packages", "--query", "openssl"})
+	assert.True(t, gotOpt.VulnerableOnly)
+	assert.Equal(t, "deb_packages", gotOpt.Source)
+	assert.Equal(t, "openssl", gotOpt.MatchQuery)
+
+	_, _, err := runAppNoChecks([]string{"get", "software", "--min-severity", "severe"})
+	require.Error(t, err)
+}
+
+func TestGetOSVersions(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	var gotOpt fleet.OSVersionsOptions
+	updatedAt := time.Date(2021, 10, 8, 9, 0, 0, 0, time.UTC)
+	ds.OSVersionsFunc = func(ctx context.Context, opt fleet.OSVersionsOptions) (*fleet.OSVersions, error) {
+		gotOpt = opt
+		return &fleet.OSVersions{
+			CountsUpdatedAt: &updatedAt,
+			OSVersions: []fleet.OSVersion{
+				{Platform: "darwin", OSVersion: "macOS 11.6", OsqueryVersion: "4.9.0", HostsCount: 12},
+				{Platform: "ubuntu", OSVersion: "Ubuntu 20.04.3 LTS", OsqueryVersion: "4.9.0", HostsCount: 3},
+			},
+		}, nil
+	}
+
+	expected := `+----------+--------------------+-----------------+-------+
+| PLATFORM |     OS VERSION     | OSQUERY VERSION | HOSTS |
++----------+--------------------+-----------------+-------+
+| darwin   | macOS 11.6         | 4.9.0           |    12 |
++----------+--------------------+-----------------+-------+
+| ubuntu   | Ubuntu 20.04.3 LTS | 4.9.0           |     3 |
++----------+--------------------+-----------------+-------+
+`
+	expectedJson := `{"kind":"os_versions","apiVersion":"1","spec":{"counts_updated_at":"2021-10-08T09:00:00Z","os_versions":[{"platform":"darwin","os_version":"macOS 11.6","osquery_version":"4.9.0","hosts_count":12},{"platform":"ubuntu","os_version":"Ubuntu 20.04.3 LTS","osquery_version":"4.9.0","hosts_count":3}]}}
+`
+
+	assert.Equal(t, expected, runAppForTest(t, []string{"get", "os_versions"}))
+	assert.Nil(t, gotOpt.TeamID)
+	assert.Empty(t, gotOpt.Platform)
+	assert.Equal(t, expectedJson, runAppForTest(t, []string{"get", "os_versions", "--json"}))
+
+	runAppForTest(t, []string{"get", "os_versions", "--team", "2", "--platform", "darwin"})
+	require.NotNil(t, gotOpt.TeamID)
+	assert.Equal(t, uint(2), *gotOpt.TeamID)
+	assert.Equal(t, "darwin", gotOpt.Platform)
+}
+
+func TestGetCampaignResults(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		user, err := ds.UserByIDFunc(ctx, 1)
+		if err != nil {
+			return nil, err
+		}
+		return &fleet.DistributedQueryCampaign{ID: id, UserID: user.ID}, nil
+	}
+	var gotCampaignID uint
+	ds.ListDistributedQueryCampaignResultsFunc = func(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignResult, error) {
+		gotCampaignID = campaignID
+		return []*fleet.DistributedQueryCampaignResult{
+			{CampaignID: campaignID, HostID: 1, Hostname: "bar", Error: ptr.String("no such table")},
+			{CampaignID: campaignID, HostID: 2, Hostname: "foo", Rows: []map[string]string{{"hour": "10"}}},
+		}, nil
+	}
+
+	expected := `+---------------+------+---------------+
+| HOST HOSTNAME | HOUR |     ERROR     |
++---------------+------+---------------+
+| bar           |      | no such table |
++---------------+------+---------------+
+| foo           |   10 |               |
++---------------+------+---------------+
+`
+	assert.Equal(t, expected, runAppForTest(t, []string{"get", "campaign-results", "42"}))
+	assert.Equal(t, uint(42), gotCampaignID)
+
+	expectedCSV := "host_hostname,hour,error\nbar,,no such table\nfoo,10,\n"
+	assert.Equal(t, expectedCSV, runAppForTest(t, []string{"get", "campaign-results", "--format", "csv", "42"}))
+
+	var results []*fleet.DistributedQueryCampaignResult
+	require.NoError(t, json.Unmarshal([]byte(runAppForTest(t, []string{"get", "campaign-results", "--format", "json", "42"})), &results))
+	require.Len(t, results, 2)
+	assert.Equal(t, "foo", results[1].Hostname)
+	assert.Equal(t, []map[string]string{{"hour": "10"}}, results[1].Rows)
+
+	runAppCheckErr(t, []string{"get", "campaign-results", "--format", "xml", "42"}, `unsupported format "xml", must be one of csv or json`)
+}
+
+func TestGetPoliciesHistory(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	var gotSince, gotNow time.Time
+	ds.ListPoliciesHistoryFunc = func(ctx context.Context, filter fleet.TeamFilter, since, now time.Time) ([]*fleet.PolicyHistorySummary, error) {
+		gotSince, gotNow = since, now
+		return []*fleet.PolicyHistorySummary{
+			{PolicyID: 1, Name: "disk encryption", FailingHostCount: 2, DaysFailing: 3.25, PassRate: 0.8},
+			{PolicyID: 2, Name: "firewall", TeamID: ptr.Uint(3), FailingHostCount: 0, DaysFailing: 0, PassRate: 1},
+		}, nil
+	}
+
+	expected := `+----+-----------------+------+---------+--------------+-----------+
+| ID |      NAME       | TEAM | FAILING | DAYS FAILING | PASS RATE |
++----+-----------------+------+---------+--------------+-----------+
+|  1 | disk encryption |      |       2 |          3.2 | 80.0%     |
++----+-----------------+------+---------+--------------+-----------+
+|  2 | firewall        |    3 |       0 |          0.0 | 100.0%    |
++----+-----------------+------+---------+--------------+-----------+
+`
+	assert.Equal(t, expected, runAppForTest(t, []string{"get", "policies", "--history"}))
+	assert.Equal(t, 30*24*time.Hour, gotNow.Sub(gotSince))
+
+	expectedJson := `{"kind":"policy_history","apiVersion":"1","spec":[{"policy_id":2,"name":"firewall","team_id":3,"failing_host_count":0,"days_failing":0,"pass_rate":1}]}
+`
+	assert.Equal(t, expectedJson, runAppForTest(t, []string{"get", "policies", "--history", "--team", "3", "--days", "7", "--json"}))
+	assert.Equal(t, 7*24*time.Hour, gotNow.Sub(gotSince))
+}
+
+func TestGetPolicies(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	ds.ListGlobalPoliciesFunc = func(ctx context.Context) ([]*fleet.Policy, error) {
+		return []*fleet.Policy{
+			{
+				ID:               1,
+				Name:             "disk encryption",
+				Query:            "select 1 from disk_encryption where encrypted = 1;",
+				Description:      "Checks the disk is encrypted",
+				Resolution:       "Turn on FileVault",
+				Platform:         "darwin",
+				PassingHostCount: 4,
+				FailingHostCount: 2,
+			},
+		}, nil
+	}
+
+	expected := `+----+-----------------+---------+---------+
+| ID |      NAME       | PASSING | FAILING |
++----+-----------------+---------+---------+
+|  1 | disk encryption |       4 |       2 |
++----+-----------------+---------+---------+
+`
+	expectedYaml := `---
+apiVersion: v1
+kind: policy
+spec:
+  description: Checks the disk is encrypted
+  name: disk encryption
+  platform: darwin
+  query: select 1 from disk_encryption where encrypted = 1;
+  resolution: Turn on FileVault
+`
+	assert.Equal(t, expected, runAppForTest(t, []string{"get", "policies"}))
+	assert.Equal(t, expectedYaml, runAppForTest(t, []string{"get", "policies", "--yaml"}))
 }
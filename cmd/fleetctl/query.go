@@ -3,12 +3,14 @@ package main
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"strings"
 	"time"
 
 	"github.com/briandowns/spinner"
+	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/urfave/cli/v2"
 )
 
@@ -73,14 +75,14 @@ func queryCommand() *cli.Command {
 				Name:        "timeout",
 				EnvVars:     []string{"TIMEOUT"},
 				Destination: &flTimeout,
-				Usage:       "How long to run query before exiting (10s, 1h, etc.)",
+				Usage:       "How long to run query before exiting (10s, 1h, etc.), then list the hosts that did not return rows",
 			},
 			configFlag(),
 			contextFlag(),
 			debugFlag(),
 		},
 		Action: func(c *cli.Context) error {
-			fleet, err := clientFromCLI(c)
+			client, err := clientFromCLI(c)
 			if err != nil {
 				return err
 			}
@@ -94,7 +96,7 @@ func queryCommand() *cli.Command {
 			}
 
 			if flQueryName != "" {
-				q, err := fleet.GetQuery(flQueryName)
+				q, err := client.GetQuery(flQueryName)
 				if err != nil {
 					return fmt.Errorf("Query '%s' not found", flQueryName)
 				}
@@ -115,11 +117,25 @@ func queryCommand() *cli.Command {
 			hosts := strings.Split(flHosts, ",")
 			labels := strings.Split(flLabels, ",")
 
-			res, err := fleet.LiveQuery(flQuery, labels, hosts)
+			res, err := client.LiveQuery(flQuery, labels, hosts)
 			if err != nil {
 				return err
 			}
 
+			// With a timeout, list the hosts that did not return rows when
+			// the query stops.
+			printHostsSummary := func() {
+				if flTimeout <= 0 || flQuiet {
+					return
+				}
+				campaignHosts, err := client.CampaignHosts(res.CampaignID())
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "Error retrieving hosts status: %s\n", err)
+					return
+				}
+				printCampaignHostsSummary(os.Stderr, campaignHosts)
+			}
+
 			tick := time.NewTicker(100 * time.Millisecond)
 			defer tick.Stop()
 
@@ -177,6 +193,8 @@ func queryCommand() *cli.Command {
 					}
 
 					if responded >= online && flExit {
+						s.Stop()
+						printHostsSummary()
 						return nil
 					}
 
@@ -187,18 +205,58 @@ func queryCommand() *cli.Command {
 						if !flQuiet {
 							fmt.Fprintln(os.Stderr, msg)
 						}
+						printHostsSummary()
 						return nil
 					}
 
 				// Check for timeout expiring
 				case <-timeoutChan:
 					s.Stop()
+					if err := client.CancelCampaign(res.CampaignID()); err != nil {
+						fmt.Fprintf(os.Stderr, "Error cancelling query: %s\n", err)
+					}
 					if !flQuiet {
 						fmt.Fprintln(os.Stderr, s.Suffix+"\nStopped by timeout")
 					}
+					printHostsSummary()
 					return nil
 				}
 			}
 		},
 	}
 }
+
+// printCampaignHostsSummary writes a table of the hosts targeted by a live
+// query that were offline, did not respond, failed to run the query or
+// returned no rows.
+func printCampaignHostsSummary(w io.Writer, hosts []*fleet.DistributedQueryCampaignHost) {
+	var data [][]string
+	for _, h := range hosts {
+		var status, errMsg string
+		switch {
+		case h.Status == fleet.ExecutionWaiting:
+			status = "offline"
+		case h.Status == fleet.ExecutionRequested:
+			status = "no response"
+		case h.Status == fleet.ExecutionFailed:
+			status = "error"
+			if h.Error != nil {
+				errMsg = *h.Error
+			}
+		case h.RowsCount == 0:
+			status = "no rows"
+		default:
+			continue
+		}
+		data = append(data, []string{h.Hostname, status, errMsg})
+	}
+	if len(data) == 0 {
+		fmt.Fprintln(w, "All hosts returned rows")
+		return
+	}
+
+	table := defaultTable(w)
+	table.SetHeader([]string{"Host", "Status", "Error"})
+	table.AppendBulk(data)
+	table.Render()
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"testing"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/live_query"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/pubsub"
 	"github.com/fleetdm/fleet/v4/server/service"
 	"github.com/stretchr/testify/assert"
@@ -17,6 +19,14 @@ func TestLiveQuery(t *testing.T) {
 	rs := pubsub.NewInmemQueryResults()
 	lq := new(live_query.MockLiveQuery)
 	_, ds := runServerWithMockedDS(t, service.TestServerOpts{Rs: rs, Lq: lq})
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		return nil
+	}
+	ds.NewDistributedQueryCampaignHostsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint) error {
+		return nil
+	}
 
 	ds.HostIDsByNameFunc = func(ctx context.Context, filter fleet.TeamFilter, hostnames []string) ([]uint, error) {
 		return []uint{1234}, nil
@@ -94,3 +104,33 @@ func TestLiveQuery(t *testing.T) {
 `
 	assert.Equal(t, expected, runAppForTest(t, []string{"query", "--hosts", "1234", "--query", "select 42, * from time"}))
 }
+
+func TestPrintCampaignHostsSummary(t *testing.T) {
+	var buf bytes.Buffer
+	printCampaignHostsSummary(&buf, []*fleet.DistributedQueryCampaignHost{
+		{Hostname: "foo", Status: fleet.ExecutionSucceeded, RowsCount: 2},
+		{Hostname: "bar", Status: fleet.ExecutionSucceeded},
+		{Hostname: "baz", Status: fleet.ExecutionFailed, Error: ptr.String("no such table")},
+		{Hostname: "qux", Status: fleet.ExecutionRequested},
+		{Hostname: "quux", Status: fleet.ExecutionWaiting},
+	})
+	expected := `+------+-------------+---------------+
+| HOST |   STATUS    |     ERROR     |
++------+-------------+---------------+
+| bar  | no rows     |               |
++------+-------------+---------------+
+| baz  | error       | no such table |
++------+-------------+---------------+
+| qux  | no response |               |
++------+-------------+---------------+
+| quux | offline     |               |
++------+-------------+---------------+
+`
+	assert.Equal(t, expected, buf.String())
+
+	buf.Reset()
+	printCampaignHostsSummary(&buf, []*fleet.DistributedQueryCampaignHost{
+		{Hostname: "foo", Status: fleet.ExecutionSucceeded, RowsCount: 2},
+	})
+	assert.Equal(t, "All hosts returned rows\n", buf.String())
+}
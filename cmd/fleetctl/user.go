@@ -6,6 +6,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
@@ -22,6 +23,9 @@ const (
 	nameFlagName       = "name"
 	ssoFlagName        = "sso"
 	apiOnlyFlagName    = "api-only"
+	readOnlyFlagName   = "read-only"
+	expiresInFlagName  = "expires-in"
+	idFlagName         = "id"
 )
 
 func userCommand() *cli.Command {
@@ -31,6 +35,7 @@ func userCommand() *cli.Command {
 		Subcommands: []*cli.Command{
 			createUserCommand(),
 			deleteUserCommand(),
+			userTokensCommand(),
 		},
 	}
 }
@@ -199,3 +204,185 @@ func deleteUserCommand() *cli.Command {
 		},
 	}
 }
+
+func userTokensCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "tokens",
+		Usage: "Manage the API tokens of the current user",
+		Subcommands: []*cli.Command{
+			createUserTokenCommand(),
+			listUserTokensCommand(),
+			revokeUserTokenCommand(),
+		},
+	}
+}
+
+func createUserTokenCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "create",
+		Usage: "Create an API token",
+		UsageText: `This command will create an API token for the current user, and print its key. The key is not shown again.
+
+   Use the key as the token of fleetctl, or in the "Authorization: Bearer <key>" header of API requests.`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:     nameFlagName,
+				Usage:    "Name of the token (required)",
+				Required: true,
+			},
+			&cli.BoolFlag{
+				Name:  readOnlyFlagName,
+				Usage: "Restrict the token to read-only actions",
+			},
+			&cli.IntSliceFlag{
+				Name:    teamFlagName,
+				Aliases: []string{"t"},
+				Usage:   "Restrict the token to the roles of the user in the team with this ID (multiple may be specified)",
+			},
+			&cli.DurationFlag{
+				Name:  expiresInFlagName,
+				Usage: "Expire the token after this duration, such as 720h (default: never)",
+			},
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			payload := fleet.APITokenPayload{
+				Name:     c.String(nameFlagName),
+				ReadOnly: c.Bool(readOnlyFlagName),
+			}
+			for _, teamID := range c.IntSlice(teamFlagName) {
+				if teamID <= 0 {
+					return errors.Errorf("'%d' is not a valid team ID", teamID)
+				}
+				payload.TeamIDs = append(payload.TeamIDs, uint(teamID))
+			}
+			if expiresIn := c.Duration(expiresInFlagName); expiresIn > 0 {
+				payload.ExpiresAt = ptr.Time(time.Now().Add(expiresIn).UTC())
+			}
+
+			token, err := client.CreateAPIToken(payload)
+			if err != nil {
+				return errors.Wrap(err, "Failed to create API token")
+			}
+
+			logf(c, "[+] Created API token %q. Store the key now, it will not be shown again:\n%s\n", token.Name, token.Key)
+			return nil
+		},
+	}
+}
+
+func listUserTokensCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "list",
+		Usage: "List the API tokens of the current user",
+		Flags: []cli.Flag{
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			tokens, err := client.ListAPITokens()
+			if err != nil {
+				return errors.Wrap(err, "Failed to list API tokens")
+			}
+			if len(tokens) == 0 {
+				log(c, "No API tokens found")
+				return nil
+			}
+
+			formatTime := func(t *time.Time, none string) string {
+				if t == nil {
+					return none
+				}
+				return t.UTC().Format(time.RFC3339)
+			}
+			data := [][]string{}
+			for _, token := range tokens {
+				scope := "read-write"
+				if token.ReadOnly {
+					scope = "read-only"
+				}
+				if len(token.TeamIDs) > 0 {
+					var teams []string
+					for _, teamID := range token.TeamIDs {
+						teams = append(teams, fmt.Sprint(teamID))
+					}
+					scope += ", teams " + strings.Join(teams, ",")
+				}
+				data = append(data, []string{
+					fmt.Sprint(token.ID),
+					token.Name,
+					scope,
+					formatTime(token.ExpiresAt, "never"),
+					formatTime(token.LastUsedAt, "never"),
+				})
+			}
+			printTable(c, []string{"ID", "Name", "Scope", "Expires", "Last used"}, data)
+			return nil
+		},
+	}
+}
+
+func revokeUserTokenCommand() *cli.Command {
+	return &cli.Command{
+		Name:      "revoke",
+		Usage:     "Revoke an API token",
+		UsageText: `This command will revoke an API token of the current user, specified by its name or by its ID.`,
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  nameFlagName,
+				Usage: "Name of the token",
+			},
+			&cli.UintFlag{
+				Name:  idFlagName,
+				Usage: "ID of the token",
+			},
+			configFlag(),
+			contextFlag(),
+			debugFlag(),
+		},
+		Action: func(c *cli.Context) error {
+			client, err := clientFromCLI(c)
+			if err != nil {
+				return err
+			}
+
+			id, name := c.Uint(idFlagName), c.String(nameFlagName)
+			if (id == 0) == (name == "") {
+				return errors.New("Exactly one of --id and --name must be provided.")
+			}
+			if name != "" {
+				tokens, err := client.ListAPITokens()
+				if err != nil {
+					return errors.Wrap(err, "Failed to list API tokens")
+				}
+				for _, token := range tokens {
+					if token.Name == name {
+						id = token.ID
+					}
+				}
+				if id == 0 {
+					return errors.Errorf("No API token named %q", name)
+				}
+			}
+
+			if err := client.DeleteAPIToken(id); err != nil {
+				return errors.Wrap(err, "Failed to revoke API token")
+			}
+			logf(c, "[+] Revoked API token %d\n", id)
+			return nil
+		},
+	}
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestUserDelete(t *testing.T) {
@@ -29,3 +32,44 @@ func TestUserDelete(t *testing.T) {
 	assert.Equal(t, "", runAppForTest(t, []string{"user", "delete", "--email", "user1@test.com"}))
 	assert.Equal(t, uint(42), deletedUser)
 }
+
+func TestUserTokens(t *testing.T) {
+	_, ds := runServerWithMockedDS(t)
+
+	var tokens []*fleet.APIToken
+	ds.NewAPITokenFunc = func(ctx context.Context, token *fleet.APIToken) (*fleet.APIToken, error) {
+		token.ID = uint(len(tokens) + 1)
+		tokens = append(tokens, token)
+		return token, nil
+	}
+	ds.ListAPITokensForUserFunc = func(ctx context.Context, userID uint) ([]*fleet.APIToken, error) {
+		return tokens, nil
+	}
+	ds.APITokenFunc = func(ctx context.Context, id uint) (*fleet.APIToken, error) {
+		return tokens[id-1], nil
+	}
+	deletedToken := uint(0)
+	ds.DeleteAPITokenFunc = func(ctx context.Context, id uint) error {
+		deletedToken = id
+		return nil
+	}
+
+	out := runAppForTest(t, []string{"user", "tokens", "create", "--name", "ci", "--read-only", "--team", "2", "--expires-in", "24h"})
+	require.Len(t, tokens, 1)
+	assert.Contains(t, out, `[+] Created API token "ci"`)
+	assert.True(t, fleet.IsAPITokenKey(strings.TrimSpace(strings.SplitN(out, "\n", 2)[1])))
+	assert.True(t, tokens[0].ReadOnly)
+	assert.Equal(t, []uint{2}, tokens[0].TeamIDs)
+	require.NotNil(t, tokens[0].ExpiresAt)
+	assert.WithinDuration(t, time.Now().Add(24*time.Hour), *tokens[0].ExpiresAt, time.Minute)
+
+	runAppForTest(t, []string{"user", "tokens", "create", "--name", "deploy"})
+	out = runAppForTest(t, []string{"user", "tokens", "list"})
+	assert.Contains(t, out, "read-only, teams 2")
+	assert.Contains(t, out, "deploy")
+
+	assert.Equal(t, "[+] Revoked API token 2\n", runAppForTest(t, []string{"user", "tokens", "revoke", "--name", "deploy"}))
+	assert.Equal(t, uint(2), deletedToken)
+	runAppCheckErr(t, []string{"user", "tokens", "revoke", "--name", "unknown"}, `No API token named "unknown"`)
+	runAppCheckErr(t, []string{"user", "tokens", "revoke"}, "Exactly one of --id and --name must be provided.")
+}
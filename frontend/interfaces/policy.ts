@@ -1,7 +1,12 @@
 export interface IPolicy {
   id: number;
-  query_id: number;
-  query_name: string;
+  name: string;
+  query: string;
+  description: string;
+  resolution: string;
+  platform: string;
+  query_id?: number;
+  team_id?: number;
   passing_host_count: number;
   failing_host_count: number;
 }
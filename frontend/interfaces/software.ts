@@ -8,6 +8,7 @@ export default PropTypes.shape({
   source: PropTypes.string,
   id: PropTypes.number,
   vulnerabilities: PropTypes.arrayOf(vulnerabilityInterface),
+  max_cvss_score: PropTypes.number,
 });
 
 export interface ISoftware {
@@ -17,4 +18,5 @@ export interface ISoftware {
   source: string;
   id: number;
   vulnerabilities: IVulnerability[];
+  max_cvss_score?: number;
 }
@@ -3,9 +3,17 @@ import PropTypes from "prop-types";
 export default PropTypes.shape({
   cve: PropTypes.string,
   details_link: PropTypes.string,
+  cvss_score: PropTypes.number,
+  cvss_vector: PropTypes.string,
+  severity: PropTypes.string,
+  cve_published: PropTypes.string,
 });
 
 export interface IVulnerability {
   cve: string;
   details_link: string;
+  cvss_score?: number;
+  cvss_vector?: string;
+  severity?: string;
+  cve_published?: string;
 }
@@ -58,7 +58,7 @@ const PoliciesListWrapper = (props: IPoliciesListWrapperProps): JSX.Element => {
         columns={tableHeaders}
         data={generateDataSet(policiesList)}
         isLoading={isLoading}
-        defaultSortHeader={"query_name"}
+        defaultSortHeader={"name"}
         defaultSortDirection={"asc"}
         manualSortBy
         showMarkAllPages={false}
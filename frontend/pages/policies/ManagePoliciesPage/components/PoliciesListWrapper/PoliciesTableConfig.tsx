@@ -85,11 +85,11 @@ const generateTableHeaders = (): IDataColumn[] => {
       disableHidden: true,
     },
     {
-      title: "Query",
-      Header: "Query",
+      title: "Name",
+      Header: "Name",
       disableSortBy: true,
       // sortType: "caseInsensitive",
-      accessor: "query_name",
+      accessor: "name",
       Cell: (cellProps: ICellProps): JSX.Element => (
         <TextCell value={cellProps.cell.value} />
       ),
@@ -135,7 +135,7 @@ const generateTableHeaders = (): IDataColumn[] => {
 
 const generateDataSet = memoize((all_policies: IPolicy[] = []): IPolicy[] => {
   all_policies = all_policies.sort((a, b) =>
-    sortUtils.caseInsensitiveAsc(b.query_name, a.query_name)
+    sortUtils.caseInsensitiveAsc(b.name, a.name)
   );
   return all_policies;
 });
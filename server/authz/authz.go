@@ -35,7 +35,7 @@ var policy string
 func NewAuthorizer() (*Authorizer, error) {
 	ctx := context.Background()
 	query, err := rego.New(
-		rego.Query("allowed = data.authz.allow"),
+		rego.Query("allowed = data.authz.authorized"),
 		rego.Module("policy.rego", policy),
 	).PrepareForEval(ctx)
 	if err != nil {
@@ -105,6 +105,11 @@ func (a *Authorizer) Authorize(ctx context.Context, object, action interface{})
 		"object":  objectInterface,
 		"action":  action,
 	}
+	if token := apiTokenFromContext(ctx); token != nil {
+		input["api_token"] = map[string]interface{}{
+			"read_only": token.ReadOnly,
+		}
+	}
 	results, err := a.query.Eval(ctx, rego.EvalInput(input))
 	if err != nil {
 		return ForbiddenWithInternal("policy evaluation failed: "+err.Error(), subject, object, action)
@@ -125,6 +130,10 @@ func (a *Authorizer) TeamAuthorize(ctx context.Context, teamID uint, action stri
 		return ForbiddenWithInternal("nil subject always forbidden", subject, nil, action)
 	}
 
+	if token := apiTokenFromContext(ctx); token != nil && token.ReadOnly && action != fleet.ActionRead && action != fleet.ActionList {
+		return ForbiddenWithInternal("read-only api token", subject, nil, action)
+	}
+
 	// global admins and maintainers are authorized to work with teams
 	if subject.GlobalRole != nil {
 		switch *subject.GlobalRole {
@@ -193,6 +202,16 @@ func jsonToInterface(in interface{}) (interface{}, error) {
 	return out, nil
 }
 
+// apiTokenFromContext retrieves the API token the user authenticated with from
+// the viewer context, returning nil if there is none.
+func apiTokenFromContext(ctx context.Context) *fleet.APIToken {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	return vc.APIToken
+}
+
 // UserFromContext retrieves a user from the viewer context, returning nil if
 // there is no user.
 func UserFromContext(ctx context.Context) *fleet.User {
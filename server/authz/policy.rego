@@ -25,6 +25,15 @@ observer := "observer"
 # Default deny
 default allow = false
 
+# The result of the policy: the subject is allowed to perform the action on the
+# object, within the scope of the API token the request is authenticated with,
+# if any.
+default authorized = false
+authorized {
+  allow
+  api_token_allow
+}
+
 # team_role gets the role that the subject has for the team, returning undefined
 # if the user has no explicit role for that team.
 team_role(subject, team_id) = role {
@@ -346,6 +355,22 @@ allow {
   action == [read, write][_]
 }
 
+##
+# Query sweeps
+##
+
+# Only global admins and maintainers can read/write query sweeps
+allow {
+  object.type == "query_sweep"
+  subject.global_role == admin
+  action == [read, write][_]
+}
+allow {
+  object.type == "query_sweep"
+  subject.global_role == maintainer
+  action == [read, write][_]
+}
+
 ##
 # File Carves
 ##
@@ -374,11 +399,18 @@ allow {
   action == [read, write][_]
 }
 
-# Team Maintainers can read and write policies
+# Team Maintainers can read policies
 allow {
   object.type == "policy"
   team_role(subject, subject.teams[_].id) == maintainer
-  action == [read, write][_]
+  action == read
+}
+
+# Team Maintainers can write the policies of their teams
+allow {
+  object.type == "policy"
+  team_role(subject, object.team_id) == maintainer
+  action == write
 }
 
 ##
@@ -390,4 +422,62 @@ allow {
   not is_null(subject)
   object.type == "software"
   action == read
-}
\ No newline at end of file
+}
+
+##
+# API tokens
+##
+
+# Users can read and write their own API tokens
+allow {
+  object.type == "api_token"
+  object.user_id == subject.id
+  action == [read, write][_]
+}
+
+# Admins can read and write the API tokens of all the users
+allow {
+  object.type == "api_token"
+  subject.global_role == admin
+  action == [read, write][_]
+}
+
+##
+# SCIM groups
+##
+
+# Only global admins can read and write the groups provisioned through SCIM
+allow {
+  object.type == "scim_group"
+  subject.global_role == admin
+  action == [read, write][_]
+}
+
+# Requests not authenticated with an API token are not restricted
+api_token_allow {
+  not input.api_token
+}
+
+# API tokens cannot manage API tokens, and read-only API tokens are restricted
+# to the read and list actions. The restriction of API tokens to teams is
+# applied to the roles of the subject.
+api_token_allow {
+  input.api_token
+  object.type != "api_token"
+  not input.api_token.read_only
+  not api_token_self_write
+}
+api_token_allow {
+  input.api_token
+  object.type != "api_token"
+  input.api_token.read_only
+  action == [read, list][_]
+}
+
+# API tokens cannot modify the user they belong to, such as their password or
+# their email.
+api_token_self_write {
+  object.type == "user"
+  object.id == subject.id
+  action == [write, write_role][_]
+}
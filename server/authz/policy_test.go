@@ -1,9 +1,11 @@
 package authz
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/test"
@@ -438,6 +440,36 @@ func TestAuthorizePacks(t *testing.T) {
 	})
 }
 
+func TestAuthorizeQuerySweeps(t *testing.T) {
+	t.Parallel()
+
+	teamMaintainer := &fleet.User{
+		Teams: []fleet.UserTeam{
+			{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer},
+		},
+	}
+	sweep := &fleet.QuerySweep{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: sweep, action: read, allow: false},
+		{user: nil, object: sweep, action: write, allow: false},
+
+		{user: test.UserNoRoles, object: sweep, action: read, allow: false},
+		{user: test.UserNoRoles, object: sweep, action: write, allow: false},
+
+		{user: test.UserAdmin, object: sweep, action: read, allow: true},
+		{user: test.UserAdmin, object: sweep, action: write, allow: true},
+
+		{user: test.UserMaintainer, object: sweep, action: read, allow: true},
+		{user: test.UserMaintainer, object: sweep, action: write, allow: true},
+
+		{user: test.UserObserver, object: sweep, action: read, allow: false},
+		{user: test.UserObserver, object: sweep, action: write, allow: false},
+
+		{user: teamMaintainer, object: sweep, action: read, allow: false},
+		{user: teamMaintainer, object: sweep, action: write, allow: false},
+	})
+}
+
 func TestAuthorizeCarves(t *testing.T) {
 	t.Parallel()
 
@@ -487,6 +519,109 @@ func runTestCases(t *testing.T, testCases []authTestCase) {
 
 }
 
+func TestAuthorizePolicies(t *testing.T) {
+	t.Parallel()
+
+	teamMaintainer := &fleet.User{
+		Teams: []fleet.UserTeam{
+			{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer},
+		},
+	}
+	globalPolicy := &fleet.Policy{}
+	teamPolicy := &fleet.Policy{TeamID: ptr.Uint(1)}
+	otherTeamPolicy := &fleet.Policy{TeamID: ptr.Uint(2)}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: globalPolicy, action: read, allow: false},
+		{user: nil, object: globalPolicy, action: write, allow: false},
+
+		{user: test.UserAdmin, object: globalPolicy, action: write, allow: true},
+		{user: test.UserAdmin, object: teamPolicy, action: write, allow: true},
+
+		{user: test.UserMaintainer, object: globalPolicy, action: write, allow: true},
+		{user: test.UserMaintainer, object: teamPolicy, action: write, allow: true},
+
+		{user: test.UserObserver, object: globalPolicy, action: write, allow: false},
+		{user: test.UserObserver, object: teamPolicy, action: write, allow: false},
+
+		// Team maintainers can only write the policies of their teams
+		{user: teamMaintainer, object: globalPolicy, action: read, allow: true},
+		{user: teamMaintainer, object: globalPolicy, action: write, allow: false},
+		{user: teamMaintainer, object: teamPolicy, action: write, allow: true},
+		{user: teamMaintainer, object: otherTeamPolicy, action: write, allow: false},
+	})
+}
+
+func TestAuthorizeAPITokens(t *testing.T) {
+	t.Parallel()
+
+	ownToken := &fleet.APIToken{UserID: test.UserMaintainer.ID}
+	otherToken := &fleet.APIToken{UserID: test.UserObserver.ID}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: ownToken, action: read, allow: false},
+		{user: nil, object: ownToken, action: write, allow: false},
+
+		{user: test.UserMaintainer, object: ownToken, action: read, allow: true},
+		{user: test.UserMaintainer, object: ownToken, action: write, allow: true},
+		{user: test.UserMaintainer, object: otherToken, action: read, allow: false},
+		{user: test.UserMaintainer, object: otherToken, action: write, allow: false},
+
+		{user: test.UserAdmin, object: otherToken, action: read, allow: true},
+		{user: test.UserAdmin, object: otherToken, action: write, allow: true},
+	})
+}
+
+func TestAuthorizeSCIMGroups(t *testing.T) {
+	t.Parallel()
+
+	group := &fleet.SCIMGroup{}
+	runTestCases(t, []authTestCase{
+		{user: nil, object: group, action: read, allow: false},
+		{user: test.UserMaintainer, object: group, action: read, allow: false},
+		{user: test.UserMaintainer, object: group, action: write, allow: false},
+		{user: test.UserAdmin, object: group, action: read, allow: true},
+		{user: test.UserAdmin, object: group, action: write, allow: true},
+	})
+}
+
+func TestAuthorizeWithAPIToken(t *testing.T) {
+	t.Parallel()
+
+	tokenContext := func(user *fleet.User, token *fleet.APIToken) context.Context {
+		return viewer.NewContext(context.Background(), viewer.Viewer{User: user, APIToken: token})
+	}
+	readOnly := tokenContext(test.UserAdmin, &fleet.APIToken{ID: 1, UserID: test.UserAdmin.ID, ReadOnly: true})
+	readWrite := tokenContext(test.UserAdmin, &fleet.APIToken{ID: 2, UserID: test.UserAdmin.ID})
+
+	// Read-only tokens are restricted to the read and list actions.
+	assert.NoError(t, auth.Authorize(readOnly, &fleet.AppConfig{}, read))
+	assert.NoError(t, auth.Authorize(readOnly, &fleet.Host{}, list))
+	assert.Error(t, auth.Authorize(readOnly, &fleet.AppConfig{}, write))
+	assert.Error(t, auth.Authorize(readOnly, &fleet.Query{}, run))
+	assert.Error(t, auth.TeamAuthorize(readOnly, 1, write))
+	assert.NoError(t, auth.TeamAuthorize(readOnly, 1, read))
+
+	assert.NoError(t, auth.Authorize(readWrite, &fleet.AppConfig{}, write))
+	assert.NoError(t, auth.TeamAuthorize(readWrite, 1, write))
+
+	// API tokens cannot manage API tokens.
+	assert.Error(t, auth.Authorize(readWrite, &fleet.APIToken{UserID: test.UserAdmin.ID}, write))
+	assert.Error(t, auth.Authorize(readOnly, &fleet.APIToken{UserID: test.UserAdmin.ID}, read))
+
+	// API tokens cannot modify their user, but admins can modify the other
+	// users with them.
+	assert.Error(t, auth.Authorize(readWrite, &fleet.User{ID: test.UserAdmin.ID}, write))
+	assert.Error(t, auth.Authorize(readWrite, &fleet.User{ID: test.UserAdmin.ID}, writeRole))
+	assert.NoError(t, auth.Authorize(readWrite, &fleet.User{ID: test.UserAdmin.ID}, read))
+	assert.NoError(t, auth.Authorize(readWrite, &fleet.User{ID: test.UserObserver.ID}, write))
+
+	// The roles of the user are restricted to the teams of the token.
+	teamToken := &fleet.APIToken{ID: 3, UserID: test.UserAdmin.ID, TeamIDs: []uint{1}}
+	teamCtx := tokenContext(teamToken.ScopedUser(test.UserAdmin), teamToken)
+	assert.Error(t, auth.Authorize(teamCtx, &fleet.AppConfig{}, write))
+	assert.NoError(t, auth.Authorize(teamCtx, &fleet.Policy{TeamID: ptr.Uint(1)}, write))
+	assert.Error(t, auth.Authorize(teamCtx, &fleet.Policy{TeamID: ptr.Uint(2)}, write))
+}
+
 func TestJSONToInterfaceUser(t *testing.T) {
 	t.Parallel()
 
@@ -95,6 +95,23 @@ type OsqueryConfig struct {
 	StatusLogFile        string        `yaml:"status_log_file"`
 	ResultLogFile        string        `yaml:"result_log_file"`
 	EnableLogRotation    bool          `yaml:"enable_log_rotation"`
+	// LiveQueryResultsRetention is how long the results of live queries are
+	// kept. A value of 0 disables storing them.
+	LiveQueryResultsRetention time.Duration `yaml:"live_query_results_retention"`
+	// LiveQueryTimeout is how long live query campaigns run before they are
+	// stopped by the server.
+	LiveQueryTimeout time.Duration `yaml:"live_query_timeout"`
+	// ResultStoreMaxRows is how many rows of the results of scheduled queries
+	// are kept per scheduled query and host. A value of 0 disables storing
+	// them.
+	ResultStoreMaxRows int `yaml:"result_store_max_rows"`
+	// ResultLogRoutes is the semicolon-separated list of the routes of the
+	// result logs to other plugins than ResultLogPlugin, by query name, in
+	// the "pattern=plugin[,plugin]" format.
+	ResultLogRoutes string `yaml:"result_log_routes"`
+	// LogEnrichmentFields is the comma-separated list of the host metadata
+	// fields injected in the status and result logs.
+	LogEnrichmentFields string `yaml:"log_enrichment_fields"`
 }
 
 // LoggingConfig defines configs related to logging
@@ -126,6 +143,29 @@ type KinesisConfig struct {
 	ResultStream     string `yaml:"result_stream"`
 }
 
+// SplunkConfig defines configs for the Splunk HTTP Event Collector logging
+// plugin
+type SplunkConfig struct {
+	URL              string
+	Token            string
+	StatusIndex      string        `yaml:"status_index"`
+	ResultIndex      string        `yaml:"result_index"`
+	StatusSourcetype string        `yaml:"status_sourcetype"`
+	ResultSourcetype string        `yaml:"result_sourcetype"`
+	Timeout          time.Duration `yaml:"timeout"`
+}
+
+// ElasticsearchConfig defines configs for the Elasticsearch logging plugin
+type ElasticsearchConfig struct {
+	URL         string
+	Username    string
+	Password    string
+	APIKey      string        `yaml:"api_key"`
+	StatusIndex string        `yaml:"status_index"`
+	ResultIndex string        `yaml:"result_index"`
+	Timeout     time.Duration `yaml:"timeout"`
+}
+
 // LambdaConfig defines configs for the AWS Lambda logging plugin
 type LambdaConfig struct {
 	Region           string
@@ -153,6 +193,49 @@ type PubSubConfig struct {
 	AddAttributes bool   `json:"add_attributes" yaml:"add_attributes"`
 }
 
+// KafkaConfig defines configs for the Kafka logging plugin
+type KafkaConfig struct {
+	// Brokers is the comma-separated list of the addresses of the brokers
+	// used to discover the cluster.
+	Brokers       string
+	StatusTopic   string        `yaml:"status_topic"`
+	ResultTopic   string        `yaml:"result_topic"`
+	UseTLS        bool          `yaml:"use_tls"`
+	TLSCA         string        `yaml:"tls_ca"`
+	TLSCert       string        `yaml:"tls_cert"`
+	TLSKey        string        `yaml:"tls_key"`
+	TLSServerName string        `yaml:"tls_server_name"`
+	SASLMechanism string        `yaml:"sasl_mechanism"`
+	SASLUsername  string        `yaml:"sasl_username"`
+	SASLPassword  string        `yaml:"sasl_password"`
+	Timeout       time.Duration `yaml:"timeout"`
+}
+
+// HTTPLogConfig defines configs for the HTTP logging plugin
+type HTTPLogConfig struct {
+	StatusURL string `yaml:"status_url"`
+	ResultURL string `yaml:"result_url"`
+	// Headers is the comma-separated list of the "Name: value" headers added
+	// to the requests.
+	Headers string
+	Gzip    bool
+	Timeout time.Duration
+}
+
+// LogBufferConfig defines configs for the buffering of the osquery logs on
+// disk, when their logging plugin fails
+type LogBufferConfig struct {
+	// Directory is where the logs are queued. Buffering is disabled when
+	// it is empty.
+	Directory string
+	// MaxSizeMB is the maximum size of the queue of each log type.
+	MaxSizeMB  int           `yaml:"max_size_mb"`
+	MaxBackoff time.Duration `yaml:"max_backoff"`
+	// WriteTimeout is how long the logs are written to the logging plugin
+	// before they are queued.
+	WriteTimeout time.Duration `yaml:"write_timeout"`
+}
+
 // FilesystemConfig defines configs for the Filesystem logging plugin
 type FilesystemConfig struct {
 	StatusLogFile        string `json:"status_log_file" yaml:"status_log_file"`
@@ -176,6 +259,29 @@ type VulnerabilitiesConfig struct {
 	DisableDataSync       bool          `json:"disable_data_sync" yaml:"disable_data_sync"`
 }
 
+// SCIMConfig defines configs related to the provisioning of users through
+// the SCIM API
+type SCIMConfig struct {
+	// GroupRoles is the semicolon-separated list of the roles of the members
+	// of the SCIM groups, in the "group=role" format for global roles and the
+	// "group=team_id:role" format for team roles.
+	GroupRoles string `yaml:"group_roles"`
+	// DefaultRole is the global role of the provisioned users that are not
+	// members of a group with a role.
+	DefaultRole string `yaml:"default_role"`
+}
+
+// SAMLConfig defines configs related to Fleet as a SAML service provider
+type SAMLConfig struct {
+	// SPCert and SPKey are the paths to the PEM-encoded certificate and RSA
+	// key that sign the authorization requests.
+	SPCert string `yaml:"sp_cert"`
+	SPKey  string `yaml:"sp_key"`
+	// ClockSkew is the allowed difference between the clocks of the identity
+	// provider and of Fleet when checking the validity of assertions.
+	ClockSkew time.Duration `yaml:"clock_skew"`
+}
+
 // FleetConfig stores the application configuration. Each subcategory is
 // broken up into it's own struct, defined above. When editing any of these
 // structs, Manager.addConfigs and Manager.LoadConfig should be
@@ -192,12 +298,19 @@ type FleetConfig struct {
 	Logging          LoggingConfig
 	Firehose         FirehoseConfig
 	Kinesis          KinesisConfig
+	Splunk           SplunkConfig
+	Elasticsearch    ElasticsearchConfig
 	Lambda           LambdaConfig
 	S3               S3Config
 	PubSub           PubSubConfig
+	Kafka            KafkaConfig
+	HTTPLog          HTTPLogConfig   `yaml:"http_log"`
+	LogBuffer        LogBufferConfig `yaml:"log_buffer"`
 	Filesystem       FilesystemConfig
 	License          LicenseConfig
 	Vulnerabilities  VulnerabilitiesConfig
+	SCIM             SCIMConfig
+	SAML             SAMLConfig
 }
 
 // addConfigs adds the configuration keys and default values that will be
@@ -293,13 +406,23 @@ func (man Manager) addConfigs() {
 	man.addConfigDuration("osquery.enroll_cooldown", 0,
 		"Cooldown period for duplicate host enrollment (default off)")
 	man.addConfigString("osquery.status_log_plugin", "filesystem",
-		"Log plugin to use for status logs")
+		"Comma-separated list of log plugins to use for status logs")
 	man.addConfigString("osquery.result_log_plugin", "filesystem",
-		"Log plugin to use for result logs")
+		"Comma-separated list of log plugins to use for result logs")
+	man.addConfigString("osquery.result_log_routes", "",
+		"Semicolon-separated list of \"pattern=plugin[,plugin]\" routes of the result logs by query name")
+	man.addConfigString("osquery.log_enrichment_fields", "",
+		"Comma-separated list of host metadata fields to inject in the status and result logs")
 	man.addConfigDuration("osquery.label_update_interval", 1*time.Hour,
 		"Interval to update host label membership (i.e. 1h)")
 	man.addConfigDuration("osquery.detail_update_interval", 1*time.Hour,
 		"Interval to update host details (i.e. 1h)")
+	man.addConfigDuration("osquery.live_query_results_retention", 7*24*time.Hour,
+		"Duration the results of live queries are kept for later retrieval (0 to disable)")
+	man.addConfigDuration("osquery.live_query_timeout", 1*time.Hour,
+		"Duration live query campaigns run before they are stopped")
+	man.addConfigInt("osquery.result_store_max_rows", 0,
+		"Rows of scheduled query results kept per query and host (0 to disable)")
 	man.addConfigString("osquery.status_log_file", "",
 		"(DEPRECATED: Use filesystem.status_log_file) Path for osqueryd status logs")
 	man.addConfigString("osquery.result_log_file", "",
@@ -352,6 +475,24 @@ func (man Manager) addConfigs() {
 	man.addConfigString("lambda.result_function", "",
 		"Lambda function name for result logs")
 
+	// Splunk
+	man.addConfigString("splunk.url", "", "URL of the Splunk HTTP Event Collector")
+	man.addConfigString("splunk.token", "", "Splunk HTTP Event Collector token")
+	man.addConfigString("splunk.status_index", "", "Splunk index for status logs")
+	man.addConfigString("splunk.result_index", "", "Splunk index for result logs")
+	man.addConfigString("splunk.status_sourcetype", "osquery:status", "Splunk sourcetype for status logs")
+	man.addConfigString("splunk.result_sourcetype", "osquery:result", "Splunk sourcetype for result logs")
+	man.addConfigDuration("splunk.timeout", 10*time.Second, "Timeout of the requests to Splunk")
+
+	// Elasticsearch
+	man.addConfigString("elasticsearch.url", "", "URL of the Elasticsearch cluster")
+	man.addConfigString("elasticsearch.username", "", "Username for Elasticsearch basic authentication")
+	man.addConfigString("elasticsearch.password", "", "Password for Elasticsearch basic authentication")
+	man.addConfigString("elasticsearch.api_key", "", "Base64 encoded API key for Elasticsearch")
+	man.addConfigString("elasticsearch.status_index", "osquery_status", "Elasticsearch index for status logs")
+	man.addConfigString("elasticsearch.result_index", "osquery_result", "Elasticsearch index for result logs")
+	man.addConfigDuration("elasticsearch.timeout", 10*time.Second, "Timeout of the requests to Elasticsearch")
+
 	// S3 for file carving
 	man.addConfigString("s3.bucket", "", "Bucket where to store file carves")
 	man.addConfigString("s3.prefix", "", "Prefix under which carves are stored")
@@ -365,6 +506,33 @@ func (man Manager) addConfigs() {
 	man.addConfigString("pubsub.result_topic", "", "PubSub topic for result logs")
 	man.addConfigBool("pubsub.add_attributes", false, "Add PubSub attributes in addition to the message body")
 
+	// Kafka
+	man.addConfigString("kafka.brokers", "", "Comma-separated list of Kafka broker addresses")
+	man.addConfigString("kafka.status_topic", "", "Kafka topic for status logs")
+	man.addConfigString("kafka.result_topic", "", "Kafka topic for result logs")
+	man.addConfigBool("kafka.use_tls", false, "Connect to the Kafka brokers with TLS")
+	man.addConfigString("kafka.tls_ca", "", "Path to the CA certificate of the Kafka brokers")
+	man.addConfigString("kafka.tls_cert", "", "Path to the client certificate for Kafka")
+	man.addConfigString("kafka.tls_key", "", "Path to the client key for Kafka")
+	man.addConfigString("kafka.tls_server_name", "", "Server name of the Kafka brokers certificates")
+	man.addConfigString("kafka.sasl_mechanism", "", "SASL mechanism to authenticate to Kafka (PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512)")
+	man.addConfigString("kafka.sasl_username", "", "SASL username for Kafka")
+	man.addConfigString("kafka.sasl_password", "", "SASL password for Kafka")
+	man.addConfigDuration("kafka.timeout", 10*time.Second, "Timeout of the requests to the Kafka brokers")
+
+	// HTTP log
+	man.addConfigString("http_log.status_url", "", "URL to post status logs to")
+	man.addConfigString("http_log.result_url", "", "URL to post result logs to")
+	man.addConfigString("http_log.headers", "", "Comma-separated list of \"Name: value\" headers to add to the requests")
+	man.addConfigBool("http_log.gzip", false, "Compress the requests with gzip")
+	man.addConfigDuration("http_log.timeout", 10*time.Second, "Timeout of the requests")
+
+	// Log buffer
+	man.addConfigString("log_buffer.directory", "", "Directory to queue osquery logs in when their logging plugin fails (disabled if empty)")
+	man.addConfigInt("log_buffer.max_size_mb", 1024, "Maximum size in MB of the queue of each log type")
+	man.addConfigDuration("log_buffer.max_backoff", 1*time.Minute, "Maximum delay between attempts to write the queued logs")
+	man.addConfigDuration("log_buffer.write_timeout", 2*time.Second, "Time given to the logging plugin to write the logs before they are queued")
+
 	// Filesystem
 	man.addConfigString("filesystem.status_log_file", filepath.Join(os.TempDir(), "osquery_status"),
 		"Log file path to use for status logs")
@@ -391,6 +559,20 @@ func (man Manager) addConfigs() {
 		"Allows to manually select an instance to do the vulnerability processing.")
 	man.addConfigBool("vulnerabilities.disable_data_sync", false,
 		"Skips synchronizing data streams and expects them to be available in the databases_path.")
+
+	// SCIM provisioning
+	man.addConfigString("scim.group_roles", "",
+		"Roles of the members of the SCIM groups (group=role or group=team_id:role, semicolon-separated)")
+	man.addConfigString("scim.default_role", "observer",
+		"Global role of the SCIM users that are not members of a group with a role")
+
+	// SAML service provider
+	man.addConfigString("saml.sp_cert", "",
+		"Path to the PEM-encoded certificate signing the SAML authorization requests")
+	man.addConfigString("saml.sp_key", "",
+		"Path to the PEM-encoded RSA key signing the SAML authorization requests")
+	man.addConfigDuration("saml.clock_skew", 3*time.Minute,
+		"Allowed clock skew between the identity provider and Fleet when validating SAML assertions")
 }
 
 // LoadConfig will load the config variables into a fully initialized
@@ -453,16 +635,21 @@ func (man Manager) LoadConfig() FleetConfig {
 			Duration: man.getConfigDuration("session.duration"),
 		},
 		Osquery: OsqueryConfig{
-			NodeKeySize:          man.getConfigInt("osquery.node_key_size"),
-			HostIdentifier:       man.getConfigString("osquery.host_identifier"),
-			EnrollCooldown:       man.getConfigDuration("osquery.enroll_cooldown"),
-			StatusLogPlugin:      man.getConfigString("osquery.status_log_plugin"),
-			ResultLogPlugin:      man.getConfigString("osquery.result_log_plugin"),
-			StatusLogFile:        man.getConfigString("osquery.status_log_file"),
-			ResultLogFile:        man.getConfigString("osquery.result_log_file"),
-			LabelUpdateInterval:  man.getConfigDuration("osquery.label_update_interval"),
-			DetailUpdateInterval: man.getConfigDuration("osquery.detail_update_interval"),
-			EnableLogRotation:    man.getConfigBool("osquery.enable_log_rotation"),
+			NodeKeySize:               man.getConfigInt("osquery.node_key_size"),
+			HostIdentifier:            man.getConfigString("osquery.host_identifier"),
+			EnrollCooldown:            man.getConfigDuration("osquery.enroll_cooldown"),
+			StatusLogPlugin:           man.getConfigString("osquery.status_log_plugin"),
+			ResultLogPlugin:           man.getConfigString("osquery.result_log_plugin"),
+			StatusLogFile:             man.getConfigString("osquery.status_log_file"),
+			ResultLogFile:             man.getConfigString("osquery.result_log_file"),
+			LabelUpdateInterval:       man.getConfigDuration("osquery.label_update_interval"),
+			DetailUpdateInterval:      man.getConfigDuration("osquery.detail_update_interval"),
+			EnableLogRotation:         man.getConfigBool("osquery.enable_log_rotation"),
+			LiveQueryResultsRetention: man.getConfigDuration("osquery.live_query_results_retention"),
+			LiveQueryTimeout:          man.getConfigDuration("osquery.live_query_timeout"),
+			ResultStoreMaxRows:        man.getConfigInt("osquery.result_store_max_rows"),
+			ResultLogRoutes:           man.getConfigString("osquery.result_log_routes"),
+			LogEnrichmentFields:       man.getConfigString("osquery.log_enrichment_fields"),
 		},
 		Logging: LoggingConfig{
 			Debug:         man.getConfigBool("logging.debug"),
@@ -495,6 +682,24 @@ func (man Manager) LoadConfig() FleetConfig {
 			ResultFunction:   man.getConfigString("lambda.result_function"),
 			StsAssumeRoleArn: man.getConfigString("lambda.sts_assume_role_arn"),
 		},
+		Splunk: SplunkConfig{
+			URL:              man.getConfigString("splunk.url"),
+			Token:            man.getConfigString("splunk.token"),
+			StatusIndex:      man.getConfigString("splunk.status_index"),
+			ResultIndex:      man.getConfigString("splunk.result_index"),
+			StatusSourcetype: man.getConfigString("splunk.status_sourcetype"),
+			ResultSourcetype: man.getConfigString("splunk.result_sourcetype"),
+			Timeout:          man.getConfigDuration("splunk.timeout"),
+		},
+		Elasticsearch: ElasticsearchConfig{
+			URL:         man.getConfigString("elasticsearch.url"),
+			Username:    man.getConfigString("elasticsearch.username"),
+			Password:    man.getConfigString("elasticsearch.password"),
+			APIKey:      man.getConfigString("elasticsearch.api_key"),
+			StatusIndex: man.getConfigString("elasticsearch.status_index"),
+			ResultIndex: man.getConfigString("elasticsearch.result_index"),
+			Timeout:     man.getConfigDuration("elasticsearch.timeout"),
+		},
 		S3: S3Config{
 			Bucket:           man.getConfigString("s3.bucket"),
 			Prefix:           man.getConfigString("s3.prefix"),
@@ -508,6 +713,33 @@ func (man Manager) LoadConfig() FleetConfig {
 			ResultTopic:   man.getConfigString("pubsub.result_topic"),
 			AddAttributes: man.getConfigBool("pubsub.add_attributes"),
 		},
+		Kafka: KafkaConfig{
+			Brokers:       man.getConfigString("kafka.brokers"),
+			StatusTopic:   man.getConfigString("kafka.status_topic"),
+			ResultTopic:   man.getConfigString("kafka.result_topic"),
+			UseTLS:        man.getConfigBool("kafka.use_tls"),
+			TLSCA:         man.getConfigString("kafka.tls_ca"),
+			TLSCert:       man.getConfigString("kafka.tls_cert"),
+			TLSKey:        man.getConfigString("kafka.tls_key"),
+			TLSServerName: man.getConfigString("kafka.tls_server_name"),
+			SASLMechanism: man.getConfigString("kafka.sasl_mechanism"),
+			SASLUsername:  man.getConfigString("kafka.sasl_username"),
+			SASLPassword:  man.getConfigString("kafka.sasl_password"),
+			Timeout:       man.getConfigDuration("kafka.timeout"),
+		},
+		HTTPLog: HTTPLogConfig{
+			StatusURL: man.getConfigString("http_log.status_url"),
+			ResultURL: man.getConfigString("http_log.result_url"),
+			Headers:   man.getConfigString("http_log.headers"),
+			Gzip:      man.getConfigBool("http_log.gzip"),
+			Timeout:   man.getConfigDuration("http_log.timeout"),
+		},
+		LogBuffer: LogBufferConfig{
+			Directory:    man.getConfigString("log_buffer.directory"),
+			MaxSizeMB:    man.getConfigInt("log_buffer.max_size_mb"),
+			MaxBackoff:   man.getConfigDuration("log_buffer.max_backoff"),
+			WriteTimeout: man.getConfigDuration("log_buffer.write_timeout"),
+		},
 		Filesystem: FilesystemConfig{
 			StatusLogFile:        man.getConfigString("filesystem.status_log_file"),
 			ResultLogFile:        man.getConfigString("filesystem.result_log_file"),
@@ -525,6 +757,15 @@ func (man Manager) LoadConfig() FleetConfig {
 			CurrentInstanceChecks: man.getConfigString("vulnerabilities.current_instance_checks"),
 			DisableDataSync:       man.getConfigBool("vulnerabilities.disable_data_sync"),
 		},
+		SCIM: SCIMConfig{
+			GroupRoles:  man.getConfigString("scim.group_roles"),
+			DefaultRole: man.getConfigString("scim.default_role"),
+		},
+		SAML: SAMLConfig{
+			SPCert:    man.getConfigString("saml.sp_cert"),
+			SPKey:     man.getConfigString("saml.sp_key"),
+			ClockSkew: man.getConfigDuration("saml.clock_skew"),
+		},
 	}
 }
 
@@ -749,6 +990,7 @@ func TestConfig() FleetConfig {
 			ResultLogPlugin:      "filesystem",
 			LabelUpdateInterval:  1 * time.Hour,
 			DetailUpdateInterval: 1 * time.Hour,
+			LiveQueryTimeout:     1 * time.Hour,
 		},
 		Logging: LoggingConfig{
 			Debug:         true,
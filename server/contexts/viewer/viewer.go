@@ -24,10 +24,11 @@ func FromContext(ctx context.Context) (Viewer, bool) {
 }
 
 // Viewer holds information about the current
-// user and the user's session
+// user and the user's session, or the API token the user authenticated with
 type Viewer struct {
-	User    *fleet.User
-	Session *fleet.Session
+	User     *fleet.User
+	Session  *fleet.Session
+	APIToken *fleet.APIToken
 }
 
 // UserID is a helper that enables quick access to the user ID of the current
@@ -74,6 +75,9 @@ func (v Viewer) IsUserID(id uint) bool {
 // IsLoggedIn determines whether or not the current VC is attached to a user
 // account
 func (v Viewer) IsLoggedIn() bool {
+	if v.APIToken != nil && v.APIToken.ID != 0 {
+		return true
+	}
 	if v.Session != nil {
 		// Without having access to a service to call GetInfoAboutSession(id),
 		// we can't synchronously check the database here.
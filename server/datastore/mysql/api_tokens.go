@@ -0,0 +1,111 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// apiTokenRow is an API token as stored in the api_tokens table. The team IDs
+// are stored as JSON rather than in a join table, so that deleting a team
+// does not lift the team restriction of a token.
+type apiTokenRow struct {
+	fleet.APIToken
+	TeamIDsJSON []byte `db:"team_ids"`
+}
+
+func (r *apiTokenRow) token() (*fleet.APIToken, error) {
+	token := r.APIToken
+	token.TeamIDs = []uint{}
+	if len(r.TeamIDsJSON) > 0 {
+		if err := json.Unmarshal(r.TeamIDsJSON, &token.TeamIDs); err != nil {
+			return nil, errors.Wrap(err, "unmarshal api token team ids")
+		}
+	}
+	return &token, nil
+}
+
+const apiTokenColumns = `id, user_id, name, key_hash, read_only, team_ids, expires_at, last_used_at, created_at`
+
+func (d *Datastore) NewAPIToken(ctx context.Context, token *fleet.APIToken) (*fleet.APIToken, error) {
+	teamIDs := token.TeamIDs
+	if teamIDs == nil {
+		teamIDs = []uint{}
+	}
+	teamIDsJSON, err := json.Marshal(teamIDs)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal api token team ids")
+	}
+
+	sqlStatement := `
+		INSERT INTO api_tokens (user_id, name, key_hash, read_only, team_ids, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+	result, err := d.writer.ExecContext(ctx, sqlStatement,
+		token.UserID, token.Name, token.KeyHash, token.ReadOnly, teamIDsJSON, token.ExpiresAt)
+	if err != nil {
+		if isDuplicate(err) {
+			return nil, alreadyExists("APIToken", token.Name)
+		}
+		return nil, errors.Wrap(err, "inserting api token")
+	}
+
+	id, _ := result.LastInsertId()
+	return d.APIToken(ctx, uint(id))
+}
+
+func (d *Datastore) getAPIToken(ctx context.Context, db sqlx.QueryerContext, where string, args ...interface{}) (*fleet.APIToken, error) {
+	var row apiTokenRow
+	err := sqlx.GetContext(ctx, db, &row, `SELECT `+apiTokenColumns+` FROM api_tokens WHERE `+where+` LIMIT 1`, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("APIToken")
+		}
+		return nil, errors.Wrap(err, "selecting api token")
+	}
+	return row.token()
+}
+
+func (d *Datastore) APIToken(ctx context.Context, id uint) (*fleet.APIToken, error) {
+	// Read from the primary, as the token is loaded right after its creation.
+	return d.getAPIToken(ctx, d.writer, `id = ?`, id)
+}
+
+func (d *Datastore) APITokenByKeyHash(ctx context.Context, keyHash string) (*fleet.APIToken, error) {
+	return d.getAPIToken(ctx, d.reader, `key_hash = ?`, keyHash)
+}
+
+func (d *Datastore) ListAPITokensForUser(ctx context.Context, userID uint) ([]*fleet.APIToken, error) {
+	var rows []apiTokenRow
+	sqlStatement := `SELECT ` + apiTokenColumns + ` FROM api_tokens WHERE user_id = ? ORDER BY name`
+	if err := sqlx.SelectContext(ctx, d.reader, &rows, sqlStatement, userID); err != nil {
+		return nil, errors.Wrap(err, "selecting api tokens for user")
+	}
+
+	tokens := make([]*fleet.APIToken, 0, len(rows))
+	for i := range rows {
+		token, err := rows[i].token()
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+func (d *Datastore) DeleteAPIToken(ctx context.Context, id uint) error {
+	return d.deleteEntity(ctx, "api_tokens", id)
+}
+
+func (d *Datastore) MarkAPITokenUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	sqlStatement := `UPDATE api_tokens SET last_used_at = ? WHERE id = ?`
+	if _, err := d.writer.ExecContext(ctx, sqlStatement, usedAt, id); err != nil {
+		return errors.Wrap(err, "marking api token used")
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPITokens(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	user := test.NewUser(t, ds, "Admin", "admin@fleet.co", true)
+	team, err := ds.NewTeam(context.Background(), &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	token, err := ds.NewAPIToken(context.Background(), &fleet.APIToken{
+		UserID:    user.ID,
+		Name:      "ci",
+		KeyHash:   fleet.HashAPITokenKey(fleet.APITokenPrefix + "ci"),
+		ReadOnly:  true,
+		TeamIDs:   []uint{team.ID},
+		ExpiresAt: &expiresAt,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, token.ID)
+	assert.True(t, token.ReadOnly)
+	assert.Equal(t, []uint{team.ID}, token.TeamIDs)
+	assert.Equal(t, expiresAt, token.ExpiresAt.UTC())
+	assert.Nil(t, token.LastUsedAt)
+
+	// The names are unique per user.
+	_, err = ds.NewAPIToken(context.Background(), &fleet.APIToken{UserID: user.ID, Name: "ci", KeyHash: fleet.HashAPITokenKey("other")})
+	require.Error(t, err)
+
+	deploy, err := ds.NewAPIToken(context.Background(), &fleet.APIToken{
+		UserID:  user.ID,
+		Name:    "deploy",
+		KeyHash: fleet.HashAPITokenKey(fleet.APITokenPrefix + "deploy"),
+	})
+	require.NoError(t, err)
+	assert.Empty(t, deploy.TeamIDs)
+	assert.Nil(t, deploy.ExpiresAt)
+
+	found, err := ds.APITokenByKeyHash(context.Background(), fleet.HashAPITokenKey(fleet.APITokenPrefix+"ci"))
+	require.NoError(t, err)
+	assert.Equal(t, token.ID, found.ID)
+	_, err = ds.APITokenByKeyHash(context.Background(), fleet.HashAPITokenKey("unknown"))
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+
+	usedAt := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, ds.MarkAPITokenUsed(context.Background(), deploy.ID, usedAt))
+
+	tokens, err := ds.ListAPITokensForUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	assert.Equal(t, "ci", tokens[0].Name)
+	assert.Equal(t, "deploy", tokens[1].Name)
+	require.NotNil(t, tokens[1].LastUsedAt)
+	assert.Equal(t, usedAt, tokens[1].LastUsedAt.UTC())
+
+	// Deleting a team does not lift the restriction of the tokens.
+	require.NoError(t, ds.DeleteTeam(context.Background(), team.ID))
+	found, err = ds.APIToken(context.Background(), token.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{team.ID}, found.TeamIDs)
+
+	require.NoError(t, ds.DeleteAPIToken(context.Background(), token.ID))
+	_, err = ds.APIToken(context.Background(), token.ID)
+	require.Error(t, err)
+	require.Error(t, ds.DeleteAPIToken(context.Background(), token.ID))
+
+	// The tokens are deleted with their user.
+	require.NoError(t, ds.DeleteUser(context.Background(), user.ID))
+	tokens, err = ds.ListAPITokensForUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
@@ -2,6 +2,8 @@ package mysql
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -134,3 +136,188 @@ func (d *Datastore) CleanupDistributedQueryCampaigns(ctx context.Context, now ti
 
 	return uint(exp), nil
 }
+
+func (d *Datastore) CompleteExpiredDistributedQueryCampaigns(ctx context.Context, createdBefore time.Time) ([]uint, error) {
+	var expiredIDs []uint
+	err := d.withTx(ctx, func(tx sqlx.ExtContext) error {
+		stmt := `
+			SELECT id FROM distributed_query_campaigns
+			WHERE status != ? AND created_at < ?
+			FOR UPDATE
+		`
+		if err := sqlx.SelectContext(ctx, tx, &expiredIDs, stmt, fleet.QueryComplete, createdBefore); err != nil {
+			return errors.Wrap(err, "select expired distributed query campaigns")
+		}
+		if len(expiredIDs) == 0 {
+			return nil
+		}
+
+		stmt, args, err := sqlx.In(`UPDATE distributed_query_campaigns SET status = ? WHERE id IN (?)`, fleet.QueryComplete, expiredIDs)
+		if err != nil {
+			return errors.Wrap(err, "IN for UPDATE distributed_query_campaigns")
+		}
+		if _, err := tx.ExecContext(ctx, stmt, args...); err != nil {
+			return errors.Wrap(err, "complete expired distributed query campaigns")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expiredIDs, nil
+}
+
+func (d *Datastore) SaveDistributedQueryCampaignResult(ctx context.Context, result *fleet.DistributedQueryCampaignResult) error {
+	rows := result.Rows
+	if rows == nil {
+		rows = []map[string]string{}
+	}
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return errors.Wrap(err, "marshal distributed query campaign result rows")
+	}
+
+	sqlStatement := `
+		INSERT INTO distributed_query_campaign_results (
+			distributed_query_campaign_id,
+			host_id,
+			hostname,
+			data,
+			error
+		)
+		VALUES (?,?,?,?,?)
+		ON DUPLICATE KEY UPDATE
+			hostname = VALUES(hostname),
+			data = VALUES(data),
+			error = VALUES(error)
+	`
+	if _, err := d.writer.ExecContext(ctx, sqlStatement,
+		result.CampaignID, result.HostID, result.Hostname, data, result.Error,
+	); err != nil {
+		return errors.Wrap(err, "insert distributed query campaign result")
+	}
+	return nil
+}
+
+func (d *Datastore) ListDistributedQueryCampaignResults(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignResult, error) {
+	if opt.OrderKey == "" {
+		opt.OrderKey = "hostname"
+	}
+	sqlStatement := `
+		SELECT distributed_query_campaign_id, host_id, hostname, data, error, created_at
+		FROM distributed_query_campaign_results
+		WHERE distributed_query_campaign_id = ?
+	`
+	sqlStatement = appendListOptionsToSQL(sqlStatement, opt)
+
+	var rows []struct {
+		fleet.DistributedQueryCampaignResult
+		Data json.RawMessage `db:"data"`
+	}
+	if err := sqlx.SelectContext(ctx, d.reader, &rows, sqlStatement, campaignID); err != nil {
+		return nil, errors.Wrap(err, "list distributed query campaign results")
+	}
+
+	results := make([]*fleet.DistributedQueryCampaignResult, 0, len(rows))
+	for _, row := range rows {
+		result := row.DistributedQueryCampaignResult
+		if err := json.Unmarshal(row.Data, &result.Rows); err != nil {
+			return nil, errors.Wrap(err, "unmarshal distributed query campaign result rows")
+		}
+		results = append(results, &result)
+	}
+	return results, nil
+}
+
+func (d *Datastore) CountDistributedQueryCampaignResults(ctx context.Context, campaignID uint) (uint, error) {
+	var count uint
+	err := sqlx.GetContext(ctx, d.reader, &count,
+		`SELECT COUNT(*) FROM distributed_query_campaign_results WHERE distributed_query_campaign_id = ?`, campaignID)
+	if err != nil {
+		return 0, errors.Wrap(err, "count distributed query campaign results")
+	}
+	return count, nil
+}
+
+func (d *Datastore) CleanupDistributedQueryCampaignResults(ctx context.Context, olderThan time.Time) error {
+	_, err := d.writer.ExecContext(ctx, `DELETE FROM distributed_query_campaign_results WHERE created_at < ?`, olderThan)
+	if err != nil {
+		return errors.Wrap(err, "delete distributed query campaign results")
+	}
+	return nil
+}
+
+func (d *Datastore) NewDistributedQueryCampaignHosts(ctx context.Context, campaignID uint, hostIDs []uint) error {
+	// Insert in batches to stay well under the MySQL max number of parameters.
+	const batchSize = 10000
+	for len(hostIDs) > 0 {
+		batch := hostIDs
+		if len(batch) > batchSize {
+			batch = batch[:batchSize]
+		}
+		hostIDs = hostIDs[len(batch):]
+
+		values := strings.TrimSuffix(strings.Repeat("(?,?),", len(batch)), ",")
+		args := make([]interface{}, 0, len(batch)*2)
+		for _, hostID := range batch {
+			args = append(args, campaignID, hostID)
+		}
+		sqlStatement := `
+			INSERT IGNORE INTO distributed_query_campaign_hosts (distributed_query_campaign_id, host_id)
+			VALUES ` + values
+		if _, err := d.writer.ExecContext(ctx, sqlStatement, args...); err != nil {
+			return errors.Wrap(err, "insert distributed query campaign hosts")
+		}
+	}
+	return nil
+}
+
+func (d *Datastore) UpdateDistributedQueryCampaignHostStatus(
+	ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+) error {
+	// A host that returned results (successfully or not) is in its final
+	// status, any other status can only move forward.
+	maxCurrent := status
+	if maxCurrent > fleet.ExecutionSucceeded {
+		maxCurrent = fleet.ExecutionSucceeded
+	}
+	sqlStatement := `
+		UPDATE distributed_query_campaign_hosts
+		SET status = ?, rows_count = ?, error = ?
+		WHERE distributed_query_campaign_id = ? AND host_id = ? AND status < ?
+	`
+	if _, err := d.writer.ExecContext(ctx, sqlStatement, status, rowsCount, errMsg, campaignID, hostID, maxCurrent); err != nil {
+		return errors.Wrap(err, "update distributed query campaign host status")
+	}
+	return nil
+}
+
+func (d *Datastore) ListDistributedQueryCampaignHosts(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignHost, error) {
+	if opt.OrderKey == "" {
+		opt.OrderKey = "hostname"
+	}
+	// Hosts deleted since the campaign started are listed without hostname.
+	sqlStatement := `
+		SELECT * FROM (
+			SELECT dqch.host_id, COALESCE(h.hostname, '') AS hostname, dqch.status, dqch.rows_count, dqch.error, dqch.updated_at
+			FROM distributed_query_campaign_hosts dqch
+			LEFT JOIN hosts h ON (h.id = dqch.host_id)
+			WHERE dqch.distributed_query_campaign_id = ?
+		) campaign_hosts
+	`
+	sqlStatement = appendListOptionsToSQL(sqlStatement, opt)
+
+	hosts := []*fleet.DistributedQueryCampaignHost{}
+	if err := sqlx.SelectContext(ctx, d.reader, &hosts, sqlStatement, campaignID); err != nil {
+		return nil, errors.Wrap(err, "list distributed query campaign hosts")
+	}
+	return hosts, nil
+}
+
+func (d *Datastore) CleanupDistributedQueryCampaignHosts(ctx context.Context, olderThan time.Time) error {
+	_, err := d.writer.ExecContext(ctx, `DELETE FROM distributed_query_campaign_hosts WHERE created_at < ?`, olderThan)
+	if err != nil {
+		return errors.Wrap(err, "delete distributed query campaign hosts")
+	}
+	return nil
+}
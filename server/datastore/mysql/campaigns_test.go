@@ -152,6 +152,40 @@ func TestCleanupDistributedQueryCampaigns(t *testing.T) {
 
 }
 
+func TestCompleteExpiredDistributedQueryCampaigns(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, "test", "select * from time", user.ID, false)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	c1 := test.NewCampaign(t, ds, query.ID, fleet.QueryRunning, now.Add(-2*time.Minute))
+	c2 := test.NewCampaign(t, ds, query.ID, fleet.QueryComplete, now.Add(-2*time.Minute))
+	c3 := test.NewCampaign(t, ds, query.ID, fleet.QueryRunning, now)
+
+	expired, err := ds.CompleteExpiredDistributedQueryCampaigns(context.Background(), now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, []uint{c1.ID}, expired)
+
+	for _, c := range []struct {
+		id     uint
+		status fleet.DistributedQueryStatus
+	}{
+		{c1.ID, fleet.QueryComplete},
+		{c2.ID, fleet.QueryComplete},
+		{c3.ID, fleet.QueryRunning},
+	} {
+		retrieved, err := ds.DistributedQueryCampaign(context.Background(), c.id)
+		require.NoError(t, err)
+		assert.Equal(t, c.status, retrieved.Status)
+	}
+
+	expired, err = ds.CompleteExpiredDistributedQueryCampaigns(context.Background(), now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Empty(t, expired)
+}
+
 func TestSaveDistributedQueryCampaign(t *testing.T) {
 	ds := CreateMySQLDS(t)
 	defer ds.Close()
@@ -174,3 +208,123 @@ func TestSaveDistributedQueryCampaign(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, fleet.QueryComplete, gotC.Status)
 }
+
+func TestDistributedQueryCampaignResults(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, "test", "select * from time", user.ID, false)
+	campaign := test.NewCampaign(t, ds, query.ID, fleet.QueryRunning, time.Now())
+
+	results, err := ds.ListDistributedQueryCampaignResults(context.Background(), campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	errMsg := "query failed"
+	require.NoError(t, ds.SaveDistributedQueryCampaignResult(context.Background(), &fleet.DistributedQueryCampaignResult{
+		CampaignID: campaign.ID,
+		HostID:     2,
+		Hostname:   "foo.local",
+		Rows:       []map[string]string{{"hour": "10"}, {"hour": "11"}},
+	}))
+	require.NoError(t, ds.SaveDistributedQueryCampaignResult(context.Background(), &fleet.DistributedQueryCampaignResult{
+		CampaignID: campaign.ID,
+		HostID:     1,
+		Hostname:   "bar.local",
+		Error:      &errMsg,
+	}))
+	require.NoError(t, ds.SaveDistributedQueryCampaignResult(context.Background(), &fleet.DistributedQueryCampaignResult{
+		CampaignID: campaign.ID + 1,
+		HostID:     1,
+		Hostname:   "bar.local",
+	}))
+
+	results, err = ds.ListDistributedQueryCampaignResults(context.Background(), campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, "bar.local", results[0].Hostname)
+	assert.Equal(t, &errMsg, results[0].Error)
+	assert.Empty(t, results[0].Rows)
+	assert.Equal(t, "foo.local", results[1].Hostname)
+	assert.Nil(t, results[1].Error)
+	assert.Equal(t, []map[string]string{{"hour": "10"}, {"hour": "11"}}, results[1].Rows)
+
+	count, err := ds.CountDistributedQueryCampaignResults(context.Background(), campaign.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), count)
+
+	results, err = ds.ListDistributedQueryCampaignResults(context.Background(), campaign.ID, fleet.ListOptions{Page: 1, PerPage: 1})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, "foo.local", results[0].Hostname)
+
+	require.NoError(t, ds.CleanupDistributedQueryCampaignResults(context.Background(), time.Now().Add(-time.Hour)))
+	results, err = ds.ListDistributedQueryCampaignResults(context.Background(), campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	require.NoError(t, ds.CleanupDistributedQueryCampaignResults(context.Background(), time.Now().Add(time.Hour)))
+	results, err = ds.ListDistributedQueryCampaignResults(context.Background(), campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
+
+func TestDistributedQueryCampaignHosts(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+	query := test.NewQuery(t, ds, "test", "select * from time", user.ID, false)
+	campaign := test.NewCampaign(t, ds, query.ID, fleet.QueryRunning, time.Now())
+	h1 := test.NewHost(t, ds, "foo.local", "192.168.1.10", "1", "1", time.Now())
+	h2 := test.NewHost(t, ds, "bar.local", "192.168.1.11", "2", "2", time.Now())
+	h3 := test.NewHost(t, ds, "baz.local", "192.168.1.12", "3", "3", time.Now())
+
+	require.NoError(t, ds.NewDistributedQueryCampaignHosts(context.Background(), campaign.ID, []uint{h1.ID, h2.ID, h3.ID}))
+	// Recording the same hosts again is a no-op.
+	require.NoError(t, ds.NewDistributedQueryCampaignHosts(context.Background(), campaign.ID, []uint{h1.ID}))
+
+	hosts, err := ds.ListDistributedQueryCampaignHosts(context.Background(), campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+	for _, h := range hosts {
+		assert.Equal(t, fleet.ExecutionWaiting, h.Status)
+	}
+
+	errMsg := "query failed"
+	require.NoError(t, ds.UpdateDistributedQueryCampaignHostStatus(context.Background(), campaign.ID, h1.ID, fleet.ExecutionRequested, 0, nil))
+	require.NoError(t, ds.UpdateDistributedQueryCampaignHostStatus(context.Background(), campaign.ID, h1.ID, fleet.ExecutionSucceeded, 2, nil))
+	require.NoError(t, ds.UpdateDistributedQueryCampaignHostStatus(context.Background(), campaign.ID, h2.ID, fleet.ExecutionFailed, 0, &errMsg))
+	// Statuses never go back.
+	require.NoError(t, ds.UpdateDistributedQueryCampaignHostStatus(context.Background(), campaign.ID, h1.ID, fleet.ExecutionRequested, 0, nil))
+	require.NoError(t, ds.UpdateDistributedQueryCampaignHostStatus(context.Background(), campaign.ID, h2.ID, fleet.ExecutionSucceeded, 1, nil))
+
+	hosts, err = ds.ListDistributedQueryCampaignHosts(context.Background(), campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, hosts, 3)
+	assert.Equal(t, "bar.local", hosts[0].Hostname)
+	assert.Equal(t, fleet.ExecutionFailed, hosts[0].Status)
+	assert.Equal(t, &errMsg, hosts[0].Error)
+	assert.Equal(t, "baz.local", hosts[1].Hostname)
+	assert.Equal(t, fleet.ExecutionWaiting, hosts[1].Status)
+	assert.Equal(t, "foo.local", hosts[2].Hostname)
+	assert.Equal(t, fleet.ExecutionSucceeded, hosts[2].Status)
+	assert.Equal(t, uint(2), hosts[2].RowsCount)
+	assert.Nil(t, hosts[2].Error)
+
+	hosts, err = ds.ListDistributedQueryCampaignHosts(context.Background(), campaign.ID, fleet.ListOptions{Page: 1, PerPage: 2})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, "foo.local", hosts[0].Hostname)
+
+	require.NoError(t, ds.CleanupDistributedQueryCampaignHosts(context.Background(), time.Now().Add(-time.Hour)))
+	hosts, err = ds.ListDistributedQueryCampaignHosts(context.Background(), campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Len(t, hosts, 3)
+
+	require.NoError(t, ds.CleanupDistributedQueryCampaignHosts(context.Background(), time.Now().Add(time.Hour)))
+	hosts, err = ds.ListDistributedQueryCampaignHosts(context.Background(), campaign.ID, fleet.ListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, hosts)
+}
@@ -0,0 +1,105 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// saveHostVitalsDB stores the vitals that are set in the host, leaving the
+// ones that are not set untouched.
+func saveHostVitalsDB(ctx context.Context, tx sqlx.ExtContext, host *fleet.Host) error {
+	if host.DiskEncryption != nil {
+		sql := `
+			INSERT INTO host_disk_encryption (host_id, encrypted) VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE encrypted = VALUES(encrypted)
+		`
+		if _, err := tx.ExecContext(ctx, sql, host.ID, host.DiskEncryption.Encrypted); err != nil {
+			return errors.Wrap(err, "save host disk encryption")
+		}
+	}
+
+	if host.MDM != nil {
+		sql := `
+			INSERT INTO host_mdm (host_id, enrolled, server_url) VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE enrolled = VALUES(enrolled), server_url = VALUES(server_url)
+		`
+		if _, err := tx.ExecContext(ctx, sql, host.ID, host.MDM.Enrolled, host.MDM.ServerURL); err != nil {
+			return errors.Wrap(err, "save host mdm")
+		}
+	}
+
+	if host.Munki != nil {
+		sql := `
+			INSERT INTO host_munki_info (host_id, version) VALUES (?, ?)
+			ON DUPLICATE KEY UPDATE version = VALUES(version)
+		`
+		if _, err := tx.ExecContext(ctx, sql, host.ID, host.Munki.Version); err != nil {
+			return errors.Wrap(err, "save host munki info")
+		}
+	}
+
+	if host.Batteries != nil {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM host_batteries WHERE host_id = ?`, host.ID); err != nil {
+			return errors.Wrap(err, "delete host batteries")
+		}
+		if len(host.Batteries) > 0 {
+			values := strings.TrimSuffix(strings.Repeat("(?,?,?,?),", len(host.Batteries)), ",")
+			var args []interface{}
+			for _, b := range host.Batteries {
+				args = append(args, host.ID, b.SerialNumber, b.CycleCount, b.Health)
+			}
+			sql := `INSERT IGNORE INTO host_batteries (host_id, serial_number, cycle_count, health) VALUES ` + values
+			if _, err := tx.ExecContext(ctx, sql, args...); err != nil {
+				return errors.Wrap(err, "insert host batteries")
+			}
+		}
+	}
+
+	return nil
+}
+
+func (d *Datastore) LoadHostVitals(ctx context.Context, host *fleet.Host) error {
+	var diskEncryption fleet.HostDiskEncryption
+	err := sqlx.GetContext(ctx, d.reader, &diskEncryption,
+		`SELECT encrypted FROM host_disk_encryption WHERE host_id = ?`, host.ID)
+	switch {
+	case err == nil:
+		host.DiskEncryption = &diskEncryption
+	case err != sql.ErrNoRows:
+		return errors.Wrap(err, "load host disk encryption")
+	}
+
+	var mdm fleet.HostMDM
+	err = sqlx.GetContext(ctx, d.reader, &mdm,
+		`SELECT enrolled, server_url FROM host_mdm WHERE host_id = ?`, host.ID)
+	switch {
+	case err == nil:
+		host.MDM = &mdm
+	case err != sql.ErrNoRows:
+		return errors.Wrap(err, "load host mdm")
+	}
+
+	var munki fleet.HostMunkiInfo
+	err = sqlx.GetContext(ctx, d.reader, &munki,
+		`SELECT version FROM host_munki_info WHERE host_id = ?`, host.ID)
+	switch {
+	case err == nil:
+		host.Munki = &munki
+	case err != sql.ErrNoRows:
+		return errors.Wrap(err, "load host munki info")
+	}
+
+	host.Batteries = nil
+	if err := sqlx.SelectContext(ctx, d.reader, &host.Batteries,
+		`SELECT serial_number, cycle_count, health FROM host_batteries WHERE host_id = ? ORDER BY serial_number`, host.ID,
+	); err != nil {
+		return errors.Wrap(err, "load host batteries")
+	}
+
+	return nil
+}
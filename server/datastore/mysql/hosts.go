@@ -146,6 +146,10 @@ func (d *Datastore) SaveHost(ctx context.Context, host *fleet.Host) error {
 			}
 		}
 
+		if err := saveHostVitalsDB(ctx, tx, host); err != nil {
+			return err
+		}
+
 		ac, err := d.AppConfig(ctx)
 		if err != nil {
 			return errors.Wrap(err, "failed to get app config to see if we need to update host users and inventory")
@@ -373,6 +377,10 @@ func (d *Datastore) ListHosts(ctx context.Context, filter fleet.TeamFilter, opt
 	sql, params = filterHostsByStatus(sql, opt, params)
 	sql, params = filterHostsByTeam(sql, opt, params)
 	sql, params = filterHostsByPolicy(sql, opt, params)
+	sql, params = filterHostsBySoftware(sql, opt, params)
+	sql, params = filterHostsByCVE(sql, opt, params)
+	sql, params = filterHostsByOSVersion(sql, opt, params)
+	sql, params = filterHostsByLabels(sql, opt, params)
 	sql, params = searchLike(sql, params, opt.MatchQuery, hostSearchColumns...)
 
 	sql = appendListOptionsToSQL(sql, opt.ListOptions)
@@ -404,6 +412,51 @@ func filterHostsByPolicy(sql string, opt fleet.HostListOptions, params []interfa
 	return sql, params
 }
 
+func filterHostsBySoftware(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.SoftwareIDFilter != nil {
+		sql += ` AND EXISTS (SELECT 1 FROM host_software hs WHERE hs.host_id = h.id AND hs.software_id = ?)`
+		params = append(params, *opt.SoftwareIDFilter)
+	}
+	return sql, params
+}
+
+func filterHostsByCVE(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.CVEFilter != "" {
+		sql += fmt.Sprintf(` AND EXISTS (
+			SELECT 1 FROM host_software hs JOIN (%s) v ON (hs.software_id = v.software_id)
+			WHERE hs.host_id = h.id AND v.cve = ?
+		)`, softwareCVEsSQL)
+		params = append(params, opt.CVEFilter)
+	}
+	return sql, params
+}
+
+func filterHostsByOSVersion(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if opt.OSVersionFilter != "" {
+		sql += ` AND h.os_version = ?`
+		params = append(params, opt.OSVersionFilter)
+	}
+	return sql, params
+}
+
+func filterHostsByLabels(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
+	if len(opt.LabelIDsFilter) > 0 {
+		// A host matches when it is a member of every one of the labels.
+		sql += fmt.Sprintf(` AND h.id IN (
+			SELECT lm.host_id FROM label_membership lm
+			WHERE lm.label_id IN (%s)
+			GROUP BY lm.host_id HAVING COUNT(DISTINCT lm.label_id) = ?
+		)`, strings.TrimSuffix(strings.Repeat("?,", len(opt.LabelIDsFilter)), ","))
+		labelIDs := make(map[uint]bool)
+		for _, id := range opt.LabelIDsFilter {
+			params = append(params, id)
+			labelIDs[id] = true
+		}
+		params = append(params, len(labelIDs))
+	}
+	return sql, params
+}
+
 func filterHostsByStatus(sql string, opt fleet.HostListOptions, params []interface{}) (string, []interface{}) {
 	switch opt.StatusFilter {
 	case "new":
@@ -1468,7 +1468,7 @@ func TestListHostsByPolicy(t *testing.T) {
 	filter := fleet.TeamFilter{User: test.UserAdmin}
 
 	q := test.NewQuery(t, ds, "query1", "select 1", 0, true)
-	p, err := ds.NewGlobalPolicy(context.Background(), q.ID)
+	p, err := ds.NewGlobalPolicy(context.Background(), fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name, Query: q.Query})
 	require.NoError(t, err)
 
 	// When policy response is null, we list all hosts that haven't reported at all for the policy, or errored out
@@ -1506,6 +1506,138 @@ func TestListHostsByPolicy(t *testing.T) {
 	require.Len(t, hosts, 8)
 }
 
+func TestListHostsBySoftwareCVEOSVersionAndLabels(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	var hosts []*fleet.Host
+	for i := 0; i < 3; i++ {
+		h, err := ds.NewHost(context.Background(), &fleet.Host{
+			DetailUpdatedAt: time.Now(),
+			LabelUpdatedAt:  time.Now(),
+			SeenTime:        time.Now(),
+			OsqueryHostID:   strconv.Itoa(i),
+			NodeKey:         fmt.Sprintf("%d", i),
+			UUID:            fmt.Sprintf("%d", i),
+			Hostname:        fmt.Sprintf("foo.local%d", i),
+			OSVersion:       fmt.Sprintf("macOS 11.%d", i%2),
+		})
+		require.NoError(t, err)
+		hosts = append(hosts, h)
+	}
+	h0, h1, h2 := hosts[0], hosts[1], hosts[2]
+
+	h0.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{
+			{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"},
+			{Name: "bar", Version: "0.0.2", Source: "apps"},
+		},
+	}
+	h1.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"}},
+	}
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), h0))
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), h1))
+	require.NoError(t, ds.LoadHostSoftware(context.Background(), h0))
+	sort.Slice(h0.Software, func(i, j int) bool { return h0.Software[i].Name < h0.Software[j].Name })
+	bar, foo := h0.Software[0], h0.Software[1]
+	require.NoError(t, ds.AddCPEForSoftware(context.Background(), bar, "barcpe"))
+	require.NoError(t, ds.InsertCVEForCPE(context.Background(), "CVE-2021-0001", []string{"barcpe"}))
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	listHostIDs := func(opt fleet.HostListOptions) []uint {
+		opt.ListOptions = fleet.ListOptions{OrderKey: "id"}
+		hosts, err := ds.ListHosts(context.Background(), filter, opt)
+		require.NoError(t, err)
+		var ids []uint
+		for _, h := range hosts {
+			ids = append(ids, h.ID)
+		}
+		return ids
+	}
+
+	assert.Equal(t, []uint{h0.ID, h1.ID}, listHostIDs(fleet.HostListOptions{SoftwareIDFilter: &foo.ID}))
+	assert.Equal(t, []uint{h0.ID}, listHostIDs(fleet.HostListOptions{SoftwareIDFilter: &bar.ID}))
+	assert.Equal(t, []uint{h0.ID}, listHostIDs(fleet.HostListOptions{CVEFilter: "CVE-2021-0001"}))
+	assert.Empty(t, listHostIDs(fleet.HostListOptions{CVEFilter: "CVE-2021-0002"}))
+	assert.Equal(t, []uint{h0.ID, h2.ID}, listHostIDs(fleet.HostListOptions{OSVersionFilter: "macOS 11.0"}))
+	assert.Equal(t, []uint{h1.ID}, listHostIDs(fleet.HostListOptions{OSVersionFilter: "macOS 11.1", SoftwareIDFilter: &foo.ID}))
+
+	l1 := &fleet.LabelSpec{ID: 1, Name: "label foo", Query: "query1"}
+	l2 := &fleet.LabelSpec{ID: 2, Name: "label bar", Query: "query2"}
+	require.NoError(t, ds.ApplyLabelSpecs(context.Background(), []*fleet.LabelSpec{l1, l2}))
+	require.NoError(t, ds.RecordLabelQueryExecutions(context.Background(), h0, map[uint]*bool{l1.ID: ptr.Bool(true), l2.ID: ptr.Bool(true)}, time.Now()))
+	require.NoError(t, ds.RecordLabelQueryExecutions(context.Background(), h1, map[uint]*bool{l1.ID: ptr.Bool(true)}, time.Now()))
+	require.NoError(t, ds.RecordLabelQueryExecutions(context.Background(), h2, map[uint]*bool{l2.ID: ptr.Bool(true)}, time.Now()))
+
+	assert.Equal(t, []uint{h0.ID, h1.ID}, listHostIDs(fleet.HostListOptions{LabelIDsFilter: []uint{l1.ID}}))
+	assert.Equal(t, []uint{h0.ID}, listHostIDs(fleet.HostListOptions{LabelIDsFilter: []uint{l1.ID, l2.ID}}))
+	assert.Equal(t, []uint{h0.ID, h2.ID}, listHostIDs(fleet.HostListOptions{LabelIDsFilter: []uint{l2.ID}, OSVersionFilter: "macOS 11.0"}))
+	assert.Empty(t, listHostIDs(fleet.HostListOptions{LabelIDsFilter: []uint{l1.ID, l2.ID}, SoftwareIDFilter: ptr.Uint(1000)}))
+
+	hosts, err := ds.ListHostsInLabel(context.Background(), filter, l1.ID, fleet.HostListOptions{SoftwareIDFilter: &bar.ID})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, h0.ID, hosts[0].ID)
+}
+
+func TestHostVitals(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	host, err := ds.NewHost(context.Background(), &fleet.Host{
+		DetailUpdatedAt: time.Now(),
+		LabelUpdatedAt:  time.Now(),
+		SeenTime:        time.Now(),
+		OsqueryHostID:   "1",
+		NodeKey:         "1",
+		UUID:            "1",
+		Hostname:        "foo.local",
+	})
+	require.NoError(t, err)
+
+	// Nothing is loaded before the vitals are collected.
+	require.NoError(t, ds.LoadHostVitals(context.Background(), host))
+	assert.Nil(t, host.DiskEncryption)
+	assert.Nil(t, host.MDM)
+	assert.Nil(t, host.Munki)
+	assert.Nil(t, host.Batteries)
+
+	host.DiskEncryption = &fleet.HostDiskEncryption{Encrypted: true}
+	host.MDM = &fleet.HostMDM{Enrolled: true, ServerURL: "https://mdm.example.com"}
+	host.Munki = &fleet.HostMunkiInfo{Version: "5.5.1"}
+	host.Batteries = []fleet.HostBattery{
+		{SerialNumber: "a", CycleCount: 10, Health: "Good"},
+		{SerialNumber: "b", CycleCount: 900, Health: "Poor"},
+	}
+	require.NoError(t, ds.SaveHost(context.Background(), host))
+
+	loaded, err := ds.Host(context.Background(), host.ID)
+	require.NoError(t, err)
+	require.NoError(t, ds.LoadHostVitals(context.Background(), loaded))
+	assert.Equal(t, host.DiskEncryption, loaded.DiskEncryption)
+	assert.Equal(t, host.MDM, loaded.MDM)
+	assert.Equal(t, host.Munki, loaded.Munki)
+	assert.Equal(t, host.Batteries, loaded.Batteries)
+
+	// Vitals that are not set are left untouched, the others are replaced.
+	loaded, err = ds.Host(context.Background(), host.ID)
+	require.NoError(t, err)
+	loaded.MDM = &fleet.HostMDM{Enrolled: false}
+	loaded.Batteries = []fleet.HostBattery{}
+	require.NoError(t, ds.SaveHost(context.Background(), loaded))
+
+	loaded, err = ds.Host(context.Background(), host.ID)
+	require.NoError(t, err)
+	require.NoError(t, ds.LoadHostVitals(context.Background(), loaded))
+	assert.Equal(t, host.DiskEncryption, loaded.DiskEncryption)
+	assert.Equal(t, &fleet.HostMDM{Enrolled: false}, loaded.MDM)
+	assert.Equal(t, host.Munki, loaded.Munki)
+	assert.Empty(t, loaded.Batteries)
+}
+
 func TestSaveTonsOfUsers(t *testing.T) {
 	ds := CreateMySQLDS(t)
 	defer ds.Close()
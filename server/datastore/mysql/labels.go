@@ -444,6 +444,9 @@ func (d *Datastore) ListHostsInLabel(ctx context.Context, filter fleet.TeamFilte
 
 	sql, params = filterHostsByStatus(sql, opt, params)
 	sql, params = filterHostsByTeam(sql, opt, params)
+	sql, params = filterHostsBySoftware(sql, opt, params)
+	sql, params = filterHostsByCVE(sql, opt, params)
+	sql, params = filterHostsByOSVersion(sql, opt, params)
 	sql, params = searchLike(sql, params, opt.MatchQuery, hostSearchColumns...)
 
 	sql = appendListOptionsToSQL(sql, opt.ListOptions)
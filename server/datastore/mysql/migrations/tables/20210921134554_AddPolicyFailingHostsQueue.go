@@ -0,0 +1,34 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20210921134554, Down_20210921134554)
+}
+
+func Up_20210921134554(tx *sql.Tx) error {
+	sql := `
+		CREATE TABLE IF NOT EXISTS policy_failing_hosts_queue (
+			id int(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+			policy_id int(10) UNSIGNED NOT NULL,
+			host_id int(10) UNSIGNED NOT NULL,
+			created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_policy_failing_hosts_queue_policy_host (policy_id, host_id),
+			FOREIGN KEY fk_policy_failing_hosts_queue_policy_id (policy_id) REFERENCES policies(id) ON DELETE CASCADE,
+			FOREIGN KEY fk_policy_failing_hosts_queue_host_id (host_id) REFERENCES hosts(id) ON DELETE CASCADE
+		);
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create policy_failing_hosts_queue table")
+	}
+	return nil
+}
+
+func Down_20210921134554(tx *sql.Tx) error {
+	return nil
+}
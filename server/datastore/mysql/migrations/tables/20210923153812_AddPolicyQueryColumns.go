@@ -0,0 +1,37 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20210923153812, Down_20210923153812)
+}
+
+func Up_20210923153812(tx *sql.Tx) error {
+	if _, err := tx.Exec(`ALTER TABLE policies
+		ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT '',
+		ADD COLUMN query MEDIUMTEXT NOT NULL,
+		ADD COLUMN description TEXT NOT NULL,
+		ADD COLUMN resolution TEXT NOT NULL,
+		ADD COLUMN platforms VARCHAR(255) NOT NULL DEFAULT '',
+		MODIFY query_id INT(10) UNSIGNED DEFAULT NULL
+	`); err != nil {
+		return errors.Wrap(err, "add policy query columns")
+	}
+
+	// Policies used to be a reference to a saved query, so they start with
+	// the name, query and description of that query.
+	if _, err := tx.Exec(`UPDATE policies p JOIN queries q ON (p.query_id = q.id)
+		SET p.name = q.name, p.query = q.query, p.description = q.description
+	`); err != nil {
+		return errors.Wrap(err, "copy queries into policies")
+	}
+	return nil
+}
+
+func Down_20210923153812(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,30 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20210927143115, Down_20210927143115)
+}
+
+func Up_20210927143115(tx *sql.Tx) error {
+	// CVEs found through OVAL definitions are matched against the software
+	// itself rather than against a CPE.
+	sql := `
+		ALTER TABLE software_cve
+		ADD COLUMN software_id bigint(20) UNSIGNED DEFAULT NULL,
+		ADD UNIQUE KEY unique_software_cve (software_id, cve),
+		ADD FOREIGN KEY fk_software_cve_software_id (software_id) REFERENCES software(id) ON DELETE CASCADE
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "add software_id to software_cve")
+	}
+	return nil
+}
+
+func Down_20210927143115(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,33 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20210929102318, Down_20210929102318)
+}
+
+func Up_20210929102318(tx *sql.Tx) error {
+	sql := `
+		CREATE TABLE IF NOT EXISTS cve_meta (
+			cve varchar(20) NOT NULL,
+			cvss_score double DEFAULT NULL,
+			cvss_vector varchar(255) DEFAULT NULL,
+			severity varchar(20) DEFAULT NULL,
+			published timestamp NULL DEFAULT NULL,
+			PRIMARY KEY (cve),
+			KEY idx_cve_meta_cvss_score (cvss_score)
+		) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_unicode_ci;
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create cve_meta")
+	}
+	return nil
+}
+
+func Down_20210929102318(tx *sql.Tx) error {
+	return nil
+}
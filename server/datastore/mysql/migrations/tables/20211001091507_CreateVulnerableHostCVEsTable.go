@@ -0,0 +1,35 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211001091507, Down_20211001091507)
+}
+
+func Up_20211001091507(tx *sql.Tx) error {
+	// Keeps the CVEs that affect each host, to detect the newly found ones and
+	// send them to the vulnerabilities webhook.
+	sql := `
+		CREATE TABLE IF NOT EXISTS vulnerable_host_cves (
+			host_id int(10) UNSIGNED NOT NULL,
+			cve varchar(20) NOT NULL,
+			created_at timestamp DEFAULT CURRENT_TIMESTAMP,
+			sent tinyint(1) NOT NULL DEFAULT FALSE,
+			PRIMARY KEY (host_id, cve),
+			KEY idx_vulnerable_host_cves_sent_cve (sent, cve),
+			FOREIGN KEY fk_vulnerable_host_cves_host_id (host_id) REFERENCES hosts(id) ON DELETE CASCADE
+		);
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create vulnerable_host_cves table")
+	}
+	return nil
+}
+
+func Down_20211001091507(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,34 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211004135237, Down_20211004135237)
+}
+
+func Up_20211004135237(tx *sql.Tx) error {
+	// A team_id of 0 holds the count across all hosts.
+	sql := `
+		CREATE TABLE IF NOT EXISTS software_host_counts (
+			software_id bigint(20) UNSIGNED NOT NULL,
+			team_id int(10) UNSIGNED NOT NULL DEFAULT 0,
+			hosts_count int(10) UNSIGNED NOT NULL,
+			updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (software_id, team_id),
+			KEY idx_software_host_counts_team_id_hosts_count (team_id, hosts_count),
+			KEY idx_software_host_counts_updated_at (updated_at)
+		);
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create software_host_counts table")
+	}
+	return nil
+}
+
+func Down_20211004135237(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211005101527, Down_20211005101527)
+}
+
+func Up_20211005101527(tx *sql.Tx) error {
+	// The release of rpm packages is stored apart from the version, so that
+	// the version stays comparable with the NVD ranges of the CPEs.
+	sql := "ALTER TABLE software " +
+		"ADD COLUMN `release` varchar(64) NOT NULL DEFAULT '', " +
+		"DROP INDEX idx_name_version, " +
+		"ADD UNIQUE KEY idx_name_version (name, version, source, `release`)"
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "add release to software")
+	}
+	return nil
+}
+
+func Down_20211005101527(tx *sql.Tx) error {
+	return nil
+}
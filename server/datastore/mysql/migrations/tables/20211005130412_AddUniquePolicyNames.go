@@ -0,0 +1,31 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211005130412, Down_20211005130412)
+}
+
+func Up_20211005130412(tx *sql.Tx) error {
+	// Several policies could be created from the same saved query, so they
+	// may share a name. All but the oldest are renamed with their ID, since
+	// policies are now identified by name within their team.
+	if _, err := tx.Exec(`UPDATE policies p JOIN policies p2 ON (p.name = p2.name AND p.team_id <=> p2.team_id AND p2.id < p.id)
+		SET p.name = CONCAT(LEFT(p.name, 240), ' (', p.id, ')')
+	`); err != nil {
+		return errors.Wrap(err, "rename duplicate policies")
+	}
+
+	if _, err := tx.Exec(`ALTER TABLE policies ADD UNIQUE KEY idx_policies_team_id_name (team_id, name)`); err != nil {
+		return errors.Wrap(err, "add unique policy name key")
+	}
+	return nil
+}
+
+func Down_20211005130412(tx *sql.Tx) error {
+	return nil
+}
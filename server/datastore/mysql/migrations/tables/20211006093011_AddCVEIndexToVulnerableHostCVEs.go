@@ -0,0 +1,24 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211006093011, Down_20211006093011)
+}
+
+func Up_20211006093011(tx *sql.Tx) error {
+	// The vulnerable hosts of each CVE are synced one CVE at a time.
+	sql := "ALTER TABLE vulnerable_host_cves ADD INDEX idx_vulnerable_host_cves_cve (cve)"
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "add cve index to vulnerable_host_cves")
+	}
+	return nil
+}
+
+func Down_20211006093011(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,67 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211007104523, Down_20211007104523)
+}
+
+func Up_20211007104523(tx *sql.Tx) error {
+	// Each of the host vitals collected by the detail queries is kept in its
+	// own table, keyed by host.
+	tables := []struct{ name, sql string }{
+		{"host_disk_encryption", `
+			CREATE TABLE IF NOT EXISTS host_disk_encryption (
+				host_id int(10) UNSIGNED NOT NULL,
+				encrypted tinyint(1) NOT NULL DEFAULT FALSE,
+				updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				PRIMARY KEY (host_id),
+				FOREIGN KEY fk_host_disk_encryption_host_id (host_id) REFERENCES hosts(id) ON DELETE CASCADE
+			);
+		`},
+		{"host_mdm", `
+			CREATE TABLE IF NOT EXISTS host_mdm (
+				host_id int(10) UNSIGNED NOT NULL,
+				enrolled tinyint(1) NOT NULL DEFAULT FALSE,
+				server_url varchar(255) NOT NULL DEFAULT '',
+				updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				PRIMARY KEY (host_id),
+				FOREIGN KEY fk_host_mdm_host_id (host_id) REFERENCES hosts(id) ON DELETE CASCADE
+			);
+		`},
+		{"host_munki_info", `
+			CREATE TABLE IF NOT EXISTS host_munki_info (
+				host_id int(10) UNSIGNED NOT NULL,
+				version varchar(255) NOT NULL DEFAULT '',
+				updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				PRIMARY KEY (host_id),
+				FOREIGN KEY fk_host_munki_info_host_id (host_id) REFERENCES hosts(id) ON DELETE CASCADE
+			);
+		`},
+		{"host_batteries", `
+			CREATE TABLE IF NOT EXISTS host_batteries (
+				host_id int(10) UNSIGNED NOT NULL,
+				serial_number varchar(255) NOT NULL,
+				cycle_count int(10) NOT NULL DEFAULT 0,
+				health varchar(40) NOT NULL DEFAULT '',
+				updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+				PRIMARY KEY (host_id, serial_number),
+				FOREIGN KEY fk_host_batteries_host_id (host_id) REFERENCES hosts(id) ON DELETE CASCADE
+			);
+		`},
+	}
+	for _, table := range tables {
+		if _, err := tx.Exec(table.sql); err != nil {
+			return errors.Wrapf(err, "create %s table", table.name)
+		}
+	}
+	return nil
+}
+
+func Down_20211007104523(tx *sql.Tx) error {
+	return nil
+}
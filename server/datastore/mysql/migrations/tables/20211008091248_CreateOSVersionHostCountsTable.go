@@ -0,0 +1,36 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211008091248, Down_20211008091248)
+}
+
+func Up_20211008091248(tx *sql.Tx) error {
+	// A team_id of 0 holds the counts across all hosts.
+	sql := `
+		CREATE TABLE IF NOT EXISTS os_version_host_counts (
+			id int(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+			team_id int(10) UNSIGNED NOT NULL DEFAULT 0,
+			platform varchar(255) NOT NULL DEFAULT '',
+			os_version varchar(255) NOT NULL DEFAULT '',
+			osquery_version varchar(255) NOT NULL DEFAULT '',
+			hosts_count int(10) UNSIGNED NOT NULL,
+			updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY idx_os_version_host_counts_team_id (team_id)
+		);
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create os_version_host_counts table")
+	}
+	return nil
+}
+
+func Down_20211008091248(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,36 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211011120315, Down_20211011120315)
+}
+
+func Up_20211011120315(tx *sql.Tx) error {
+	sql := `
+		CREATE TABLE IF NOT EXISTS distributed_query_campaign_results (
+			id int(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+			distributed_query_campaign_id int(10) UNSIGNED NOT NULL,
+			host_id int(10) UNSIGNED NOT NULL,
+			hostname varchar(255) NOT NULL DEFAULT '',
+			data json NOT NULL,
+			error text,
+			created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_campaign_results_campaign_host (distributed_query_campaign_id, host_id),
+			KEY idx_campaign_results_created_at (created_at)
+		);
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create distributed_query_campaign_results table")
+	}
+	return nil
+}
+
+func Down_20211011120315(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,35 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211013094216, Down_20211013094216)
+}
+
+func Up_20211013094216(tx *sql.Tx) error {
+	sql := `
+		CREATE TABLE IF NOT EXISTS distributed_query_campaign_hosts (
+			distributed_query_campaign_id int(10) UNSIGNED NOT NULL,
+			host_id int(10) UNSIGNED NOT NULL,
+			status tinyint(1) NOT NULL DEFAULT 0,
+			rows_count int(10) UNSIGNED NOT NULL DEFAULT 0,
+			error text,
+			created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (distributed_query_campaign_id, host_id),
+			KEY idx_campaign_hosts_created_at (created_at)
+		);
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create distributed_query_campaign_hosts table")
+	}
+	return nil
+}
+
+func Down_20211013094216(tx *sql.Tx) error {
+	return nil
+}
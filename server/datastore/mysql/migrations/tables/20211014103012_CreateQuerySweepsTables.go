@@ -0,0 +1,56 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211014103012, Down_20211014103012)
+}
+
+func Up_20211014103012(tx *sql.Tx) error {
+	sweepsTable := `
+		CREATE TABLE IF NOT EXISTS query_sweeps (
+			id int(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+			name varchar(255) NOT NULL,
+			description text NOT NULL,
+			query mediumtext NOT NULL,
+			targets json NOT NULL,
+			` + "`interval`" + ` int(10) UNSIGNED NOT NULL,
+			webhook_url text NOT NULL,
+			author_id int(10) UNSIGNED DEFAULT NULL,
+			last_run_at timestamp NULL DEFAULT NULL,
+			last_campaign_id int(10) UNSIGNED DEFAULT NULL,
+			diffed_campaign_id int(10) UNSIGNED DEFAULT NULL,
+			created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_query_sweeps_name (name),
+			CONSTRAINT query_sweeps_ibfk_1 FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE SET NULL
+		);
+	`
+	if _, err := tx.Exec(sweepsTable); err != nil {
+		return errors.Wrap(err, "create query_sweeps table")
+	}
+
+	hostResultsTable := `
+		CREATE TABLE IF NOT EXISTS query_sweep_host_results (
+			query_sweep_id int(10) UNSIGNED NOT NULL,
+			host_id int(10) UNSIGNED NOT NULL,
+			data json NOT NULL,
+			updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (query_sweep_id, host_id),
+			CONSTRAINT query_sweep_host_results_ibfk_1 FOREIGN KEY (query_sweep_id) REFERENCES query_sweeps (id) ON DELETE CASCADE
+		);
+	`
+	if _, err := tx.Exec(hostResultsTable); err != nil {
+		return errors.Wrap(err, "create query_sweep_host_results table")
+	}
+	return nil
+}
+
+func Down_20211014103012(tx *sql.Tx) error {
+	return nil
+}
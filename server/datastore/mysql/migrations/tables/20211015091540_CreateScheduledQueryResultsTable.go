@@ -0,0 +1,35 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211015091540, Down_20211015091540)
+}
+
+func Up_20211015091540(tx *sql.Tx) error {
+	sql := `
+		CREATE TABLE IF NOT EXISTS scheduled_query_results (
+			id bigint(20) UNSIGNED NOT NULL AUTO_INCREMENT,
+			scheduled_query_id int(10) UNSIGNED NOT NULL,
+			host_id int(10) UNSIGNED NOT NULL,
+			action varchar(16) NOT NULL,
+			data json NOT NULL,
+			collected_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			KEY idx_scheduled_query_results_query_host (scheduled_query_id, host_id),
+			KEY idx_scheduled_query_results_host (host_id)
+		);
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create scheduled_query_results table")
+	}
+	return nil
+}
+
+func Down_20211015091540(tx *sql.Tx) error {
+	return nil
+}
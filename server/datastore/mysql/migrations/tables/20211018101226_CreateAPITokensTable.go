@@ -0,0 +1,39 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211018101226, Down_20211018101226)
+}
+
+func Up_20211018101226(tx *sql.Tx) error {
+	sql := `
+		CREATE TABLE IF NOT EXISTS api_tokens (
+			id int(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+			user_id int(10) UNSIGNED NOT NULL,
+			name varchar(255) NOT NULL,
+			key_hash char(64) NOT NULL,
+			read_only tinyint(1) NOT NULL DEFAULT FALSE,
+			team_ids json DEFAULT NULL,
+			expires_at timestamp NULL DEFAULT NULL,
+			last_used_at timestamp NULL DEFAULT NULL,
+			created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_api_tokens_key_hash (key_hash),
+			UNIQUE KEY idx_api_tokens_user_name (user_id, name),
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE
+		);
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create api_tokens table")
+	}
+	return nil
+}
+
+func Down_20211018101226(tx *sql.Tx) error {
+	return nil
+}
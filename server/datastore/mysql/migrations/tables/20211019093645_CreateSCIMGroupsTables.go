@@ -0,0 +1,46 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211019093645, Down_20211019093645)
+}
+
+func Up_20211019093645(tx *sql.Tx) error {
+	sql := `
+		CREATE TABLE IF NOT EXISTS scim_groups (
+			id int(10) UNSIGNED NOT NULL AUTO_INCREMENT,
+			display_name varchar(255) NOT NULL,
+			created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			UNIQUE KEY idx_scim_groups_display_name (display_name)
+		);
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create scim_groups table")
+	}
+
+	sql = `
+		CREATE TABLE IF NOT EXISTS scim_group_members (
+			group_id int(10) UNSIGNED NOT NULL,
+			user_id int(10) UNSIGNED NOT NULL,
+			PRIMARY KEY (group_id, user_id),
+			KEY idx_scim_group_members_user_id (user_id),
+			FOREIGN KEY (group_id) REFERENCES scim_groups (id) ON DELETE CASCADE ON UPDATE CASCADE,
+			FOREIGN KEY (user_id) REFERENCES users (id) ON DELETE CASCADE ON UPDATE CASCADE
+		);
+	`
+	if _, err := tx.Exec(sql); err != nil {
+		return errors.Wrap(err, "create scim_group_members table")
+	}
+	return nil
+}
+
+func Down_20211019093645(tx *sql.Tx) error {
+	return nil
+}
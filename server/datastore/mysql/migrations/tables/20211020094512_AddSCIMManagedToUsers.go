@@ -0,0 +1,24 @@
+package tables
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211020094512, Down_20211020094512)
+}
+
+func Up_20211020094512(tx *sql.Tx) error {
+	// Only the users provisioned through the SCIM API are managed by it, so
+	// that the existing users are not.
+	if _, err := tx.Exec(`ALTER TABLE users ADD COLUMN scim_managed TINYINT(1) NOT NULL DEFAULT '0'`); err != nil {
+		return errors.Wrap(err, "add users scim_managed column")
+	}
+	return nil
+}
+
+func Down_20211020094512(tx *sql.Tx) error {
+	return nil
+}
@@ -0,0 +1,47 @@
+package tables
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func init() {
+	MigrationClient.AddMigration(Up_20211021153812, Down_20211021153812)
+}
+
+func Up_20211021153812(tx *sql.Tx) error {
+	referencedTables := map[string]struct{}{"queries": {}}
+	table := "policies"
+
+	constraints, err := constraintsForTable(tx, table, referencedTables)
+	if err != nil {
+		return err
+	}
+
+	for _, constraint := range constraints {
+		_, err = tx.Exec(fmt.Sprintf(`ALTER TABLE policies DROP FOREIGN KEY %s;`, constraint))
+		if err != nil {
+			if !strings.Contains(err.Error(), "check that column/key exists") {
+				return errors.Wrapf(err, "dropping fk %s", constraint)
+			}
+		}
+	}
+
+	// Policies have their own query, so they outlive the saved query they
+	// were created from.
+	if _, err := tx.Exec(`
+		ALTER TABLE policies
+		ADD CONSTRAINT policies_queries_fk FOREIGN KEY (query_id) REFERENCES queries (id) ON DELETE SET NULL
+	`); err != nil {
+		return errors.Wrap(err, "add fk on policies queries")
+	}
+
+	return nil
+}
+
+func Down_20211021153812(tx *sql.Tx) error {
+	return nil
+}
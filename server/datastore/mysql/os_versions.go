@@ -0,0 +1,79 @@
+package mysql
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+func (d *Datastore) OSVersions(ctx context.Context, opt fleet.OSVersionsOptions) (*fleet.OSVersions, error) {
+	var teamID uint
+	if opt.TeamID != nil {
+		teamID = *opt.TeamID
+	}
+
+	sql := `
+		SELECT platform, os_version, osquery_version, hosts_count, updated_at
+		FROM os_version_host_counts
+		WHERE team_id = ?
+	`
+	args := []interface{}{teamID}
+	if opt.Platform != "" {
+		sql += ` AND platform = ?`
+		args = append(args, opt.Platform)
+	}
+	sql += ` ORDER BY hosts_count DESC, platform, os_version, osquery_version`
+
+	var rows []struct {
+		fleet.OSVersion
+		UpdatedAt time.Time `db:"updated_at"`
+	}
+	if err := sqlx.SelectContext(ctx, d.reader, &rows, sql, args...); err != nil {
+		return nil, errors.Wrap(err, "list os versions")
+	}
+
+	res := &fleet.OSVersions{OSVersions: []fleet.OSVersion{}}
+	for _, row := range rows {
+		res.OSVersions = append(res.OSVersions, row.OSVersion)
+		if res.CountsUpdatedAt == nil || row.UpdatedAt.After(*res.CountsUpdatedAt) {
+			updatedAt := row.UpdatedAt
+			res.CountsUpdatedAt = &updatedAt
+		}
+	}
+	return res, nil
+}
+
+func (d *Datastore) CalculateOSVersionHostCounts(ctx context.Context, updatedAt time.Time) error {
+	return d.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		// The table is rebuilt from scratch so that the versions no longer run
+		// by any host (of the team) are removed.
+		if _, err := tx.ExecContext(ctx, `DELETE FROM os_version_host_counts`); err != nil {
+			return errors.Wrap(err, "delete os version host counts")
+		}
+
+		globalStmt := `
+			INSERT INTO os_version_host_counts (team_id, platform, os_version, osquery_version, hosts_count, updated_at)
+			SELECT 0, h.platform, h.os_version, h.osquery_version, COUNT(*), ?
+			FROM hosts h
+			GROUP BY h.platform, h.os_version, h.osquery_version
+		`
+		if _, err := tx.ExecContext(ctx, globalStmt, updatedAt); err != nil {
+			return errors.Wrap(err, "insert global os version host counts")
+		}
+
+		teamStmt := `
+			INSERT INTO os_version_host_counts (team_id, platform, os_version, osquery_version, hosts_count, updated_at)
+			SELECT h.team_id, h.platform, h.os_version, h.osquery_version, COUNT(*), ?
+			FROM hosts h
+			WHERE h.team_id IS NOT NULL
+			GROUP BY h.team_id, h.platform, h.os_version, h.osquery_version
+		`
+		if _, err := tx.ExecContext(ctx, teamStmt, updatedAt); err != nil {
+			return errors.Wrap(err, "insert team os version host counts")
+		}
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOSVersions(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	// Nothing is returned until the counts are calculated.
+	osVersions, err := ds.OSVersions(context.Background(), fleet.OSVersionsOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, osVersions.CountsUpdatedAt)
+	assert.Empty(t, osVersions.OSVersions)
+
+	hosts := []*fleet.Host{
+		test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now()),
+		test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now()),
+		test.NewHost(t, ds, "host3", "", "host3key", "host3uuid", time.Now()),
+	}
+	versions := []struct{ platform, osVersion string }{
+		{"darwin", "macOS 11.6"},
+		{"darwin", "macOS 11.6"},
+		{"ubuntu", "Ubuntu 20.04.3 LTS"},
+	}
+	for i, h := range hosts {
+		h.Platform = versions[i].platform
+		h.OSVersion = versions[i].osVersion
+		h.OsqueryVersion = "4.9.0"
+		require.NoError(t, ds.SaveHost(context.Background(), h))
+	}
+
+	team1, err := ds.NewTeam(context.Background(), &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	require.NoError(t, ds.AddHostsToTeam(context.Background(), &team1.ID, []uint{hosts[0].ID, hosts[2].ID}))
+
+	updatedAt := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, ds.CalculateOSVersionHostCounts(context.Background(), updatedAt))
+
+	osVersions, err = ds.OSVersions(context.Background(), fleet.OSVersionsOptions{})
+	require.NoError(t, err)
+	require.NotNil(t, osVersions.CountsUpdatedAt)
+	assert.Equal(t, updatedAt, osVersions.CountsUpdatedAt.UTC())
+	assert.Equal(t, []fleet.OSVersion{
+		{Platform: "darwin", OSVersion: "macOS 11.6", OsqueryVersion: "4.9.0", HostsCount: 2},
+		{Platform: "ubuntu", OSVersion: "Ubuntu 20.04.3 LTS", OsqueryVersion: "4.9.0", HostsCount: 1},
+	}, osVersions.OSVersions)
+
+	osVersions, err = ds.OSVersions(context.Background(), fleet.OSVersionsOptions{TeamID: &team1.ID})
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.OSVersion{
+		{Platform: "darwin", OSVersion: "macOS 11.6", OsqueryVersion: "4.9.0", HostsCount: 1},
+		{Platform: "ubuntu", OSVersion: "Ubuntu 20.04.3 LTS", OsqueryVersion: "4.9.0", HostsCount: 1},
+	}, osVersions.OSVersions)
+
+	osVersions, err = ds.OSVersions(context.Background(), fleet.OSVersionsOptions{TeamID: &team1.ID, Platform: "ubuntu"})
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.OSVersion{
+		{Platform: "ubuntu", OSVersion: "Ubuntu 20.04.3 LTS", OsqueryVersion: "4.9.0", HostsCount: 1},
+	}, osVersions.OSVersions)
+
+	osVersions, err = ds.OSVersions(context.Background(), fleet.OSVersionsOptions{TeamID: ptr.Uint(999)})
+	require.NoError(t, err)
+	assert.Empty(t, osVersions.OSVersions)
+
+	// Versions no longer run by any host are removed.
+	hosts[2].OSVersion = "Ubuntu 21.04"
+	require.NoError(t, ds.SaveHost(context.Background(), hosts[2]))
+	require.NoError(t, ds.CalculateOSVersionHostCounts(context.Background(), time.Now()))
+
+	osVersions, err = ds.OSVersions(context.Background(), fleet.OSVersionsOptions{Platform: "ubuntu"})
+	require.NoError(t, err)
+	assert.Equal(t, []fleet.OSVersion{
+		{Platform: "ubuntu", OSVersion: "Ubuntu 21.04", OsqueryVersion: "4.9.0", HostsCount: 1},
+	}, osVersions.OSVersions)
+}
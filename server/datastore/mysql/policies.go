@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"sort"
 	"strings"
@@ -12,9 +13,31 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (ds *Datastore) NewGlobalPolicy(ctx context.Context, queryID uint) (*fleet.Policy, error) {
-	res, err := ds.writer.ExecContext(ctx, `INSERT INTO policies (query_id) VALUES (?)`, queryID)
+func (ds *Datastore) NewGlobalPolicy(ctx context.Context, args fleet.PolicyPayload) (*fleet.Policy, error) {
+	return newPolicyDB(ctx, ds.writer, nil, args)
+}
+
+func newPolicyDB(ctx context.Context, q sqlx.ExtContext, teamID *uint, args fleet.PolicyPayload) (*fleet.Policy, error) {
+	// The unique key on the team and name doesn't cover global policies,
+	// whose team_id is NULL, so their names are checked here.
+	var exists bool
+	err := sqlx.GetContext(ctx, q, &exists,
+		`SELECT EXISTS (SELECT 1 FROM policies WHERE name = ? AND team_id <=> ?)`, args.Name, teamID)
+	if err != nil {
+		return nil, errors.Wrap(err, "checking policy name")
+	}
+	if exists {
+		return nil, alreadyExists("Policy", args.Name)
+	}
+
+	res, err := q.ExecContext(ctx,
+		`INSERT INTO policies (name, query, description, resolution, platforms, query_id, team_id) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		args.Name, args.Query, args.Description, args.Resolution, args.Platform, args.QueryID, teamID,
+	)
 	if err != nil {
+		if isDuplicate(err) {
+			return nil, alreadyExists("Policy", args.Name)
+		}
 		return nil, errors.Wrap(err, "inserting new policy")
 	}
 	lastIdInt64, err := res.LastInsertId()
@@ -22,7 +45,7 @@ func (ds *Datastore) NewGlobalPolicy(ctx context.Context, queryID uint) (*fleet.
 		return nil, errors.Wrap(err, "getting last id after inserting policy")
 	}
 
-	return policyDB(ctx, ds.writer, uint(lastIdInt64), nil)
+	return policyDB(ctx, q, uint(lastIdInt64), nil)
 }
 
 func (ds *Datastore) Policy(ctx context.Context, id uint) (*fleet.Policy, error) {
@@ -41,12 +64,14 @@ func policyDB(ctx context.Context, q sqlx.QueryerContext, id uint, teamID *uint)
 	err := sqlx.GetContext(ctx, q, &policy,
 		fmt.Sprintf(`SELECT
        		p.*,
-       		q.name as query_name,
        		(select count(*) from policy_membership where policy_id=p.id and passes=true) as passing_host_count,
        		(select count(*) from policy_membership where policy_id=p.id and passes=false) as failing_host_count
-		FROM policies p JOIN queries q ON (p.query_id=q.id) WHERE p.id=? AND %s`, teamWhere),
+		FROM policies p WHERE p.id=? AND %s`, teamWhere),
 		args...)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("Policy").WithID(id)
+		}
 		return nil, errors.Wrap(err, "getting policy")
 	}
 	return &policy, nil
@@ -61,27 +86,73 @@ func (ds *Datastore) RecordPolicyQueryExecutions(ctx context.Context, host *flee
 	}
 	sort.Slice(orderedIDs, func(i, j int) bool { return orderedIDs[i] < orderedIDs[j] })
 
-	// Loop through results, collecting which labels we need to insert/update
-	vals := []interface{}{}
-	bindvars := []string{}
-	for _, policyID := range orderedIDs {
-		matches := results[policyID]
-		bindvars = append(bindvars, "(?,?,?,?)")
-		vals = append(vals, updated, policyID, host.ID, matches)
-	}
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		// The history only keeps the transitions of the results, so a result
+		// equal to the latest one recorded for the host and policy only bumps
+		// the time it was last seen.
+		var latest []struct {
+			ID       uint  `db:"id"`
+			PolicyID uint  `db:"policy_id"`
+			Passes   *bool `db:"passes"`
+		}
+		err := sqlx.SelectContext(ctx, tx, &latest,
+			`SELECT id, policy_id, passes FROM policy_membership_history
+			WHERE id IN (SELECT MAX(id) FROM policy_membership_history WHERE host_id = ? GROUP BY policy_id)`,
+			host.ID,
+		)
+		if err != nil {
+			return errors.Wrap(err, "select latest policy results")
+		}
+		latestByPolicy := make(map[uint]int, len(latest))
+		for i, l := range latest {
+			latestByPolicy[l.PolicyID] = i
+		}
 
-	query := fmt.Sprintf(
-		`INSERT INTO policy_membership_history (updated_at, policy_id, host_id, passes)
+		// Loop through results, collecting which results we need to
+		// insert/update
+		var unchangedIDs []uint
+		vals := []interface{}{}
+		bindvars := []string{}
+		for _, policyID := range orderedIDs {
+			matches := results[policyID]
+			if i, ok := latestByPolicy[policyID]; ok && equalBoolPtr(latest[i].Passes, matches) {
+				unchangedIDs = append(unchangedIDs, latest[i].ID)
+				continue
+			}
+			bindvars = append(bindvars, "(?,?,?,?,?)")
+			vals = append(vals, updated, updated, policyID, host.ID, matches)
+		}
+
+		if len(unchangedIDs) > 0 {
+			query, args, err := sqlx.In(`UPDATE policy_membership_history SET updated_at = ? WHERE id IN (?)`, updated, unchangedIDs)
+			if err != nil {
+				return errors.Wrap(err, "build update policy_membership")
+			}
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return errors.Wrapf(err, "update policy_membership (%v)", unchangedIDs)
+			}
+		}
+
+		if len(bindvars) > 0 {
+			query := fmt.Sprintf(
+				`INSERT INTO policy_membership_history (created_at, updated_at, policy_id, host_id, passes)
 				VALUES %s`,
-		strings.Join(bindvars, ","),
-	)
+				strings.Join(bindvars, ","),
+			)
+			if _, err := tx.ExecContext(ctx, query, vals...); err != nil {
+				return errors.Wrapf(err, "insert policy_membership (%v)", vals)
+			}
+		}
 
-	_, err := ds.writer.ExecContext(ctx, query, vals...)
-	if err != nil {
-		return errors.Wrapf(err, "insert policy_membership (%v)", vals)
-	}
+		return nil
+	})
+}
 
-	return nil
+func equalBoolPtr(a, b *bool) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
 }
 
 func (ds *Datastore) ListGlobalPolicies(ctx context.Context) ([]*fleet.Policy, error) {
@@ -102,10 +173,9 @@ func listPoliciesDB(ctx context.Context, q sqlx.QueryerContext, teamID *uint) ([
 		&policies,
 		fmt.Sprintf(`SELECT
        		p.*,
-       		q.name as query_name,
        		(select count(*) from policy_membership where policy_id=p.id and passes=true) as passing_host_count,
        		(select count(*) from policy_membership where policy_id=p.id and passes=false) as failing_host_count
-		FROM policies p JOIN queries q ON (p.query_id=q.id) WHERE %s`, teamWhere), args...,
+		FROM policies p WHERE %s`, teamWhere), args...,
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "listing policies")
@@ -138,67 +208,274 @@ func deletePolicyDB(ctx context.Context, q sqlx.ExtContext, ids []uint, teamID *
 }
 
 func (ds *Datastore) PolicyQueriesForHost(ctx context.Context, host *fleet.Host) (map[string]string, error) {
-	var globalRows, teamRows []struct {
-		Id    string `db:"id"`
-		Query string `db:"query"`
+	var rows []struct {
+		ID        string `db:"id"`
+		Query     string `db:"query"`
+		Platforms string `db:"platforms"`
+	}
+	teamWhere := "team_id IS NULL"
+	var args []interface{}
+	if host.TeamID != nil {
+		teamWhere = "(team_id IS NULL OR team_id = ?)"
+		args = append(args, *host.TeamID)
 	}
 	err := sqlx.SelectContext(
 		ctx,
 		ds.reader,
-		&globalRows,
-		`SELECT p.id, q.query FROM policies p JOIN queries q ON (p.query_id=q.id) WHERE team_id is NULL`,
+		&rows,
+		fmt.Sprintf(`SELECT id, query, platforms FROM policies WHERE %s`, teamWhere),
+		args...,
 	)
 	if err != nil {
 		return nil, errors.Wrap(err, "selecting policies for host")
 	}
 
 	results := map[string]string{}
+	for _, row := range rows {
+		if fleet.PolicyTargetsHostPlatform(row.Platforms, host.Platform) {
+			results[row.ID] = row.Query
+		}
+	}
 
-	if host.TeamID != nil {
-		err := sqlx.SelectContext(
-			ctx,
-			ds.reader,
-			&teamRows,
-			`SELECT p.id, q.query FROM policies p JOIN queries q ON (p.query_id=q.id) WHERE team_id = ?`,
-			*host.TeamID,
-		)
-		if err != nil {
-			return nil, errors.Wrap(err, "selecting policies for host in team")
+	return results, nil
+}
+
+func (ds *Datastore) NewTeamPolicy(ctx context.Context, teamID uint, args fleet.PolicyPayload) (*fleet.Policy, error) {
+	return newPolicyDB(ctx, ds.writer, &teamID, args)
+}
+
+func (ds *Datastore) ListTeamPolicies(ctx context.Context, teamID uint) ([]*fleet.Policy, error) {
+	return listPoliciesDB(ctx, ds.reader, &teamID)
+}
+
+func (ds *Datastore) DeleteTeamPolicies(ctx context.Context, teamID uint, ids []uint) ([]uint, error) {
+	return deletePolicyDB(ctx, ds.writer, ids, &teamID)
+}
+
+func (ds *Datastore) TeamPolicy(ctx context.Context, teamID uint, policyID uint) (*fleet.Policy, error) {
+	return policyDB(ctx, ds.reader, policyID, &teamID)
+}
+
+func (ds *Datastore) NewlyFailingPoliciesForHost(ctx context.Context, hostID uint, results map[uint]*bool) ([]uint, error) {
+	var failingIDs []uint
+	for policyID, passes := range results {
+		if passes != nil && !*passes {
+			failingIDs = append(failingIDs, policyID)
 		}
 	}
+	if len(failingIDs) == 0 {
+		return nil, nil
+	}
 
-	for _, row := range globalRows {
-		results[row.Id] = row.Query
+	// A policy is newly failing if the host is not already failing it, that
+	// is, if the host passed it on the last run or never ran it at all.
+	query, args, err := sqlx.In(
+		`SELECT policy_id FROM policy_membership WHERE host_id = ? AND policy_id IN (?) AND passes = false`,
+		hostID, failingIDs,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "build select already failing policies")
+	}
+	var alreadyFailing []uint
+	if err := sqlx.SelectContext(ctx, ds.reader, &alreadyFailing, query, args...); err != nil {
+		return nil, errors.Wrap(err, "select already failing policies")
+	}
+	skip := make(map[uint]bool, len(alreadyFailing))
+	for _, id := range alreadyFailing {
+		skip[id] = true
 	}
 
-	for _, row := range teamRows {
-		results[row.Id] = row.Query
+	var newlyFailing []uint
+	for _, id := range failingIDs {
+		if !skip[id] {
+			newlyFailing = append(newlyFailing, id)
+		}
 	}
+	sort.Slice(newlyFailing, func(i, j int) bool { return newlyFailing[i] < newlyFailing[j] })
+	return newlyFailing, nil
+}
 
-	return results, nil
+func (ds *Datastore) QueueFailingPolicyHost(ctx context.Context, hostID uint, policyIDs []uint, updated time.Time) error {
+	if len(policyIDs) == 0 {
+		return nil
+	}
+
+	vals := []interface{}{}
+	bindvars := []string{}
+	for _, policyID := range policyIDs {
+		bindvars = append(bindvars, "(?,?,?)")
+		vals = append(vals, policyID, hostID, updated)
+	}
+	query := fmt.Sprintf(
+		`INSERT IGNORE INTO policy_failing_hosts_queue (policy_id, host_id, created_at) VALUES %s`,
+		strings.Join(bindvars, ","),
+	)
+	if _, err := ds.writer.ExecContext(ctx, query, vals...); err != nil {
+		return errors.Wrap(err, "queue failing policy host")
+	}
+	return nil
 }
 
-func (ds *Datastore) NewTeamPolicy(ctx context.Context, teamID uint, queryID uint) (*fleet.Policy, error) {
-	res, err := ds.writer.ExecContext(ctx, `INSERT INTO policies (query_id, team_id) VALUES (?, ?)`, queryID, teamID)
-	if err != nil {
-		return nil, errors.Wrap(err, "inserting new team policy")
+func (ds *Datastore) ListQueuedFailingPolicyHosts(ctx context.Context, policyID uint, limit int) ([]*fleet.PolicyFailingHost, error) {
+	query := `SELECT q.id, q.policy_id, q.host_id, h.hostname
+		FROM policy_failing_hosts_queue q JOIN hosts h ON (q.host_id=h.id)
+		WHERE q.policy_id = ? ORDER BY q.id`
+	args := []interface{}{policyID}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
 	}
-	lastIdInt64, err := res.LastInsertId()
+	var hosts []*fleet.PolicyFailingHost
+	// Read from the writer: callers delete the rows they sent between batches
+	// and a lagging replica would hand the same hosts out again.
+	if err := sqlx.SelectContext(ctx, ds.writer, &hosts, query, args...); err != nil {
+		return nil, errors.Wrap(err, "list queued failing policy hosts")
+	}
+	return hosts, nil
+}
+
+func (ds *Datastore) DeleteQueuedFailingPolicyHosts(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`DELETE FROM policy_failing_hosts_queue WHERE id IN (?)`, ids)
 	if err != nil {
-		return nil, errors.Wrap(err, "getting last id after inserting policy")
+		return errors.Wrap(err, "build delete queued failing policy hosts")
+	}
+	if _, err := ds.writer.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "delete queued failing policy hosts")
 	}
+	return nil
+}
 
-	return policyDB(ctx, ds.writer, uint(lastIdInt64), nil)
+// policyTransitionsSQL selects the policy result transitions of the hosts
+// matching the team filter that were still ongoing at or after the since
+// argument, along with the time each of them ended (or the now argument for
+// the current one). It expects the since and now arguments, in that order,
+// followed by the arguments of the WHERE clause.
+const policyTransitionsSQL = `
+	SELECT
+		pmh.policy_id,
+		pmh.host_id,
+		h.hostname,
+		pmh.passes,
+		pmh.created_at AS started_at,
+		pmh.updated_at AS last_seen_at,
+		(SELECT MIN(n.created_at) FROM policy_membership_history n
+			WHERE n.host_id = pmh.host_id AND n.policy_id = pmh.policy_id AND n.id > pmh.id) AS ended_at,
+		TIMESTAMPDIFF(SECOND, GREATEST(pmh.created_at, ?),
+			COALESCE((SELECT MIN(n.created_at) FROM policy_membership_history n
+				WHERE n.host_id = pmh.host_id AND n.policy_id = pmh.policy_id AND n.id > pmh.id), ?)) AS seconds
+	FROM policy_membership_history pmh JOIN hosts h ON (pmh.host_id = h.id)
+	WHERE %s`
+
+func (ds *Datastore) ListPoliciesHistory(ctx context.Context, filter fleet.TeamFilter, since, now time.Time) ([]*fleet.PolicyHistorySummary, error) {
+	var summaries []*fleet.PolicyHistorySummary
+	err := sqlx.SelectContext(ctx, ds.reader, &summaries,
+		fmt.Sprintf(`SELECT
+			p.id AS policy_id,
+			p.name,
+			p.team_id,
+			COUNT(DISTINCT CASE WHEN t.ended_at IS NULL AND t.passes = false THEN t.host_id END) AS failing_host_count,
+			COALESCE(SUM(CASE WHEN t.passes = false THEN t.seconds END), 0) / 86400 AS days_failing,
+			COALESCE(SUM(CASE WHEN t.passes = true THEN t.seconds END) / SUM(CASE WHEN t.passes IS NOT NULL THEN t.seconds END), 0) AS pass_rate
+		FROM policies p
+		LEFT JOIN (`+policyTransitionsSQL+`) t ON (t.policy_id = p.id AND t.seconds > 0)
+		WHERE p.team_id IS NULL OR %s
+		GROUP BY p.id, p.name, p.team_id
+		ORDER BY p.id`,
+			ds.whereFilterHostsByTeams(filter, "h"),
+			ds.whereFilterHostsByTeams(filter, "p"),
+		),
+		since, now,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "listing policies history")
+	}
+	return summaries, nil
 }
 
-func (ds *Datastore) ListTeamPolicies(ctx context.Context, teamID uint) ([]*fleet.Policy, error) {
-	return listPoliciesDB(ctx, ds.reader, &teamID)
+func (ds *Datastore) PolicyHistory(ctx context.Context, filter fleet.TeamFilter, policyID uint, opt fleet.PolicyHistoryOptions, since, now time.Time) ([]*fleet.PolicyTransition, error) {
+	where := fmt.Sprintf("%s AND pmh.policy_id = ?", ds.whereFilterHostsByTeams(filter, "h"))
+	args := []interface{}{since, now, policyID}
+	if opt.HostID != nil {
+		where += " AND pmh.host_id = ?"
+		args = append(args, *opt.HostID)
+	}
+
+	var transitions []*fleet.PolicyTransition
+	err := sqlx.SelectContext(ctx, ds.reader, &transitions,
+		fmt.Sprintf(`SELECT policy_id, host_id, hostname, passes, started_at, last_seen_at, ended_at
+			FROM (`+policyTransitionsSQL+`) t WHERE t.seconds > 0 ORDER BY t.host_id, t.started_at`, where),
+		args...,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "getting policy history")
+	}
+	return transitions, nil
 }
 
-func (ds *Datastore) DeleteTeamPolicies(ctx context.Context, teamID uint, ids []uint) ([]uint, error) {
-	return deletePolicyDB(ctx, ds.writer, ids, &teamID)
+func (ds *Datastore) CleanupPolicyMembershipHistory(ctx context.Context, before time.Time) error {
+	// The latest result of each host and policy is kept regardless of its age,
+	// as it is the current state of the policy for that host.
+	_, err := ds.writer.ExecContext(ctx,
+		`DELETE pmh FROM policy_membership_history pmh
+		JOIN policy_membership_history n ON (n.host_id = pmh.host_id AND n.policy_id = pmh.policy_id AND n.id > pmh.id)
+		WHERE n.created_at < ?`,
+		before,
+	)
+	if err != nil {
+		return errors.Wrap(err, "cleanup policy membership history")
+	}
+	return nil
 }
 
-func (ds *Datastore) TeamPolicy(ctx context.Context, teamID uint, policyID uint) (*fleet.Policy, error) {
-	return policyDB(ctx, ds.reader, policyID, &teamID)
+func (ds *Datastore) ApplyPolicySpecs(ctx context.Context, specs []*fleet.PolicySpec) error {
+	return ds.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		for _, spec := range specs {
+			var teamID *uint
+			if spec.Team != "" {
+				var id uint
+				if err := sqlx.GetContext(ctx, tx, &id, `SELECT id FROM teams WHERE name = ?`, spec.Team); err != nil {
+					if err == sql.ErrNoRows {
+						return notFound("Team").WithName(spec.Team)
+					}
+					return errors.Wrap(err, "getting policy team")
+				}
+				teamID = &id
+			}
+
+			teamWhere := "team_id IS NULL"
+			args := []interface{}{spec.Name}
+			if teamID != nil {
+				teamWhere = "team_id = ?"
+				args = append(args, *teamID)
+			}
+			var policyID uint
+			err := sqlx.GetContext(ctx, tx, &policyID,
+				fmt.Sprintf(`SELECT id FROM policies WHERE name = ? AND %s`, teamWhere), args...)
+			switch {
+			case err == sql.ErrNoRows:
+				if _, err := newPolicyDB(ctx, tx, teamID, fleet.PolicyPayload{
+					Name:        spec.Name,
+					Query:       spec.Query,
+					Description: spec.Description,
+					Resolution:  spec.Resolution,
+					Platform:    spec.Platform,
+				}); err != nil {
+					return errors.Wrapf(err, "creating policy %s", spec.Name)
+				}
+			case err != nil:
+				return errors.Wrapf(err, "getting policy %s", spec.Name)
+			default:
+				if _, err := tx.ExecContext(ctx,
+					`UPDATE policies SET query = ?, description = ?, resolution = ?, platforms = ? WHERE id = ?`,
+					spec.Query, spec.Description, spec.Resolution, spec.Platform, policyID,
+				); err != nil {
+					return errors.Wrapf(err, "updating policy %s", spec.Name)
+				}
+			}
+		}
+		return nil
+	})
 }
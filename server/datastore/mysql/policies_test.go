@@ -8,6 +8,7 @@ import (
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -23,10 +24,10 @@ func TestNewGlobalPolicy(t *testing.T) {
 		Saved:       true,
 	})
 	require.NoError(t, err)
-	p, err := ds.NewGlobalPolicy(context.Background(), q.ID)
+	p, err := ds.NewGlobalPolicy(context.Background(), fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name, Query: q.Query})
 	require.NoError(t, err)
 
-	assert.Equal(t, "query1", p.QueryName)
+	assert.Equal(t, "query1", p.Name)
 
 	q2, err := ds.NewQuery(context.Background(), &fleet.Query{
 		Name:        "query2",
@@ -35,17 +36,28 @@ func TestNewGlobalPolicy(t *testing.T) {
 		Saved:       true,
 	})
 	require.NoError(t, err)
-	_, err = ds.NewGlobalPolicy(context.Background(), q2.ID)
+	_, err = ds.NewGlobalPolicy(context.Background(), fleet.PolicyPayload{QueryID: &q2.ID, Name: q2.Name, Query: q2.Query})
 	require.NoError(t, err)
 
+	// Global policy names are unique.
+	_, err = ds.NewGlobalPolicy(context.Background(), fleet.PolicyPayload{QueryID: &q2.ID, Name: q2.Name, Query: q2.Query})
+	require.Error(t, err)
+
 	policies, err := ds.ListGlobalPolicies(context.Background())
 	require.NoError(t, err)
 	require.Len(t, policies, 2)
-	assert.Equal(t, q.ID, policies[0].QueryID)
-	assert.Equal(t, q2.ID, policies[1].QueryID)
+	assert.Equal(t, &q.ID, policies[0].QueryID)
+	assert.Equal(t, &q2.ID, policies[1].QueryID)
+
+	// Deleting a query keeps the policies created from it
+	require.NoError(t, ds.DeleteQuery(context.Background(), q.Name))
 
-	// Cannot delete a query if it's in a policy
-	require.Error(t, ds.DeleteQuery(context.Background(), q.Name))
+	policies, err = ds.ListGlobalPolicies(context.Background())
+	require.NoError(t, err)
+	require.Len(t, policies, 2)
+	assert.Nil(t, policies[0].QueryID)
+	assert.Equal(t, "select 1;", policies[0].Query)
+	assert.Equal(t, &q2.ID, policies[1].QueryID)
 
 	_, err = ds.DeleteGlobalPolicies(context.Background(), []uint{policies[0].ID, policies[1].ID})
 	require.NoError(t, err)
@@ -54,8 +66,7 @@ func TestNewGlobalPolicy(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, policies, 0)
 
-	// But you can delete the query if the policy is gone
-	require.NoError(t, ds.DeleteQuery(context.Background(), q.Name))
+	require.NoError(t, ds.DeleteQuery(context.Background(), q2.Name))
 }
 
 func TestPolicyMembershipView(t *testing.T) {
@@ -91,7 +102,7 @@ func TestPolicyMembershipView(t *testing.T) {
 		Saved:       true,
 	})
 	require.NoError(t, err)
-	p, err := ds.NewGlobalPolicy(context.Background(), q.ID)
+	p, err := ds.NewGlobalPolicy(context.Background(), fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name, Query: q.Query})
 	require.NoError(t, err)
 
 	q2, err := ds.NewQuery(context.Background(), &fleet.Query{
@@ -101,10 +112,10 @@ func TestPolicyMembershipView(t *testing.T) {
 		Saved:       true,
 	})
 	require.NoError(t, err)
-	p2, err := ds.NewGlobalPolicy(context.Background(), q2.ID)
+	p2, err := ds.NewGlobalPolicy(context.Background(), fleet.PolicyPayload{QueryID: &q2.ID, Name: q2.Name, Query: q2.Query})
 	require.NoError(t, err)
 
-	assert.Equal(t, "query1", p.QueryName)
+	assert.Equal(t, "query1", p.Name)
 
 	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), host1, map[uint]*bool{p.ID: ptr.Bool(true)}, time.Now()))
 	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), host1, map[uint]*bool{p.ID: ptr.Bool(true)}, time.Now()))
@@ -178,30 +189,36 @@ func TestTeamPolicy(t *testing.T) {
 	prevPolicies, err := ds.ListGlobalPolicies(context.Background())
 	require.NoError(t, err)
 
-	_, err = ds.NewTeamPolicy(context.Background(), 99999999, q.ID)
+	_, err = ds.NewTeamPolicy(context.Background(), 99999999, fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name, Query: q.Query})
 	require.Error(t, err)
 
-	p, err := ds.NewTeamPolicy(context.Background(), team1.ID, q.ID)
+	p, err := ds.NewTeamPolicy(context.Background(), team1.ID, fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name, Query: q.Query})
 	require.NoError(t, err)
 
-	assert.Equal(t, "query1", p.QueryName)
+	assert.Equal(t, "query1", p.Name)
 
 	globalPolicies, err := ds.ListGlobalPolicies(context.Background())
 	require.NoError(t, err)
 	require.Len(t, globalPolicies, len(prevPolicies))
 
-	_, err = ds.NewTeamPolicy(context.Background(), team2.ID, q2.ID)
+	_, err = ds.NewTeamPolicy(context.Background(), team2.ID, fleet.PolicyPayload{QueryID: &q2.ID, Name: q2.Name, Query: q2.Query})
+	require.NoError(t, err)
+
+	// Policy names are unique within a team, but not across teams.
+	_, err = ds.NewTeamPolicy(context.Background(), team1.ID, fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name, Query: q.Query})
+	require.Error(t, err)
+	_, err = ds.NewTeamPolicy(context.Background(), team2.ID, fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name, Query: q.Query})
 	require.NoError(t, err)
 
 	teamPolicies, err := ds.ListTeamPolicies(context.Background(), team1.ID)
 	require.NoError(t, err)
 	require.Len(t, teamPolicies, 1)
-	assert.Equal(t, q.ID, teamPolicies[0].QueryID)
+	assert.Equal(t, &q.ID, teamPolicies[0].QueryID)
 
 	team2Policies, err := ds.ListTeamPolicies(context.Background(), team2.ID)
 	require.NoError(t, err)
-	require.Len(t, team2Policies, 1)
-	assert.Equal(t, q2.ID, team2Policies[0].QueryID)
+	require.Len(t, team2Policies, 2)
+	assert.Equal(t, &q2.ID, team2Policies[0].QueryID)
 
 	_, err = ds.DeleteTeamPolicies(context.Background(), team1.ID, []uint{teamPolicies[0].ID})
 	require.NoError(t, err)
@@ -251,7 +268,7 @@ func TestPolicyQueriesForHost(t *testing.T) {
 		Saved:       true,
 	})
 	require.NoError(t, err)
-	_, err = ds.NewGlobalPolicy(context.Background(), q.ID)
+	_, err = ds.NewGlobalPolicy(context.Background(), fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name, Query: q.Query})
 	require.NoError(t, err)
 
 	q2, err := ds.NewQuery(context.Background(), &fleet.Query{
@@ -261,7 +278,7 @@ func TestPolicyQueriesForHost(t *testing.T) {
 		Saved:       true,
 	})
 	require.NoError(t, err)
-	_, err = ds.NewTeamPolicy(context.Background(), team1.ID, q2.ID)
+	_, err = ds.NewTeamPolicy(context.Background(), team1.ID, fleet.PolicyPayload{QueryID: &q2.ID, Name: q2.Name, Query: q2.Query})
 	require.NoError(t, err)
 
 	queries, err := ds.PolicyQueriesForHost(context.Background(), host1)
@@ -275,3 +292,233 @@ func TestPolicyQueriesForHost(t *testing.T) {
 	require.Len(t, queries, 1)
 	assert.Equal(t, q.Query, queries[fmt.Sprint(q.ID)])
 }
+
+func TestFailingPolicyHostsQueue(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	host1, err := ds.NewHost(context.Background(), &fleet.Host{
+		OsqueryHostID:   "1234",
+		DetailUpdatedAt: time.Now(),
+		LabelUpdatedAt:  time.Now(),
+		SeenTime:        time.Now(),
+		NodeKey:         "1",
+		UUID:            "1",
+		Hostname:        "foo.local",
+	})
+	require.NoError(t, err)
+
+	q, err := ds.NewQuery(context.Background(), &fleet.Query{
+		Name:        "query1",
+		Description: "query1 desc",
+		Query:       "select 1;",
+		Saved:       true,
+	})
+	require.NoError(t, err)
+	p1, err := ds.NewGlobalPolicy(context.Background(), fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name, Query: q.Query})
+	require.NoError(t, err)
+	p2, err := ds.NewGlobalPolicy(context.Background(), fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name + " 2", Query: q.Query})
+	require.NoError(t, err)
+
+	// Never run policies that fail are newly failing.
+	results := map[uint]*bool{p1.ID: ptr.Bool(false), p2.ID: ptr.Bool(true)}
+	failing, err := ds.NewlyFailingPoliciesForHost(context.Background(), host1.ID, results)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{p1.ID}, failing)
+	require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), host1, results, time.Now()))
+
+	// Already failing policies are not, but passing ones that fail are.
+	results = map[uint]*bool{p1.ID: ptr.Bool(false), p2.ID: ptr.Bool(false)}
+	failing, err = ds.NewlyFailingPoliciesForHost(context.Background(), host1.ID, results)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{p2.ID}, failing)
+
+	require.NoError(t, ds.QueueFailingPolicyHost(context.Background(), host1.ID, []uint{p1.ID, p2.ID}, time.Now()))
+	// Queueing the same host twice is a no-op.
+	require.NoError(t, ds.QueueFailingPolicyHost(context.Background(), host1.ID, []uint{p1.ID}, time.Now()))
+
+	queued, err := ds.ListQueuedFailingPolicyHosts(context.Background(), p1.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, queued, 1)
+	assert.Equal(t, host1.ID, queued[0].HostID)
+	assert.Equal(t, "foo.local", queued[0].Hostname)
+
+	require.NoError(t, ds.DeleteQueuedFailingPolicyHosts(context.Background(), []uint{queued[0].ID}))
+	queued, err = ds.ListQueuedFailingPolicyHosts(context.Background(), p1.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, queued, 0)
+	queued, err = ds.ListQueuedFailingPolicyHosts(context.Background(), p2.ID, 1)
+	require.NoError(t, err)
+	require.Len(t, queued, 1)
+}
+
+func TestPolicyHistory(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	host1, err := ds.NewHost(context.Background(), &fleet.Host{
+		OsqueryHostID:   "1234",
+		DetailUpdatedAt: time.Now(),
+		LabelUpdatedAt:  time.Now(),
+		SeenTime:        time.Now(),
+		NodeKey:         "1",
+		UUID:            "1",
+		Hostname:        "foo.local",
+	})
+	require.NoError(t, err)
+
+	q, err := ds.NewQuery(context.Background(), &fleet.Query{
+		Name:        "query1",
+		Description: "query1 desc",
+		Query:       "select 1;",
+		Saved:       true,
+	})
+	require.NoError(t, err)
+	p, err := ds.NewGlobalPolicy(context.Background(), fleet.PolicyPayload{QueryID: &q.ID, Name: q.Name, Query: q.Query})
+	require.NoError(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	day := 24 * time.Hour
+	record := func(passes bool, at time.Time) {
+		require.NoError(t, ds.RecordPolicyQueryExecutions(context.Background(), host1, map[uint]*bool{p.ID: ptr.Bool(passes)}, at))
+	}
+	record(true, now.Add(-10*day))
+	record(true, now.Add(-9*day))
+	record(false, now.Add(-4*day))
+	record(false, now.Add(-3*day))
+	record(true, now.Add(-1*day))
+
+	// Only the transitions are stored.
+	var count int
+	require.NoError(t, ds.writer.Get(&count, `SELECT COUNT(*) FROM policy_membership_history`))
+	assert.Equal(t, 3, count)
+
+	filter := fleet.TeamFilter{User: test.UserAdmin}
+	transitions, err := ds.PolicyHistory(context.Background(), filter, p.ID, fleet.PolicyHistoryOptions{}, now.Add(-5*day), now)
+	require.NoError(t, err)
+	require.Len(t, transitions, 3)
+	assert.True(t, *transitions[0].Passes)
+	assert.Equal(t, now.Add(-9*day), transitions[0].LastSeenAt.UTC())
+	assert.False(t, *transitions[1].Passes)
+	assert.Equal(t, now.Add(-4*day), transitions[1].StartedAt.UTC())
+	require.NotNil(t, transitions[1].EndedAt)
+	assert.Equal(t, now.Add(-1*day), transitions[1].EndedAt.UTC())
+	assert.Nil(t, transitions[2].EndedAt)
+
+	summaries, err := ds.ListPoliciesHistory(context.Background(), filter, now.Add(-5*day), now)
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, uint(0), summaries[0].FailingHostCount)
+	assert.InDelta(t, 3, summaries[0].DaysFailing, 0.001)
+	assert.InDelta(t, 0.4, summaries[0].PassRate, 0.001)
+
+	// Cleaning up removes the transitions that ended before the cutoff, but
+	// never the current one.
+	require.NoError(t, ds.CleanupPolicyMembershipHistory(context.Background(), now.Add(-2*day)))
+	require.NoError(t, ds.writer.Get(&count, `SELECT COUNT(*) FROM policy_membership_history`))
+	assert.Equal(t, 2, count)
+	require.NoError(t, ds.CleanupPolicyMembershipHistory(context.Background(), now))
+	require.NoError(t, ds.writer.Get(&count, `SELECT COUNT(*) FROM policy_membership_history`))
+	assert.Equal(t, 1, count)
+
+	// The policies of other teams are not listed to team users.
+	team1, err := ds.NewTeam(context.Background(), &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	team2, err := ds.NewTeam(context.Background(), &fleet.Team{Name: "team2"})
+	require.NoError(t, err)
+	tp1, err := ds.NewTeamPolicy(context.Background(), team1.ID, fleet.PolicyPayload{Name: "team1 policy", Query: "select 1;"})
+	require.NoError(t, err)
+	_, err = ds.NewTeamPolicy(context.Background(), team2.ID, fleet.PolicyPayload{Name: "team2 policy", Query: "select 1;"})
+	require.NoError(t, err)
+
+	teamFilter := fleet.TeamFilter{User: &fleet.User{
+		Teams: []fleet.UserTeam{{Team: *team1, Role: fleet.RoleMaintainer}},
+	}}
+	summaries, err = ds.ListPoliciesHistory(context.Background(), teamFilter, now.Add(-5*day), now)
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	assert.Equal(t, p.ID, summaries[0].PolicyID)
+	assert.Equal(t, tp1.ID, summaries[1].PolicyID)
+}
+
+func TestApplyPolicySpecs(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	team1, err := ds.NewTeam(context.Background(), &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+
+	require.NoError(t, ds.ApplyPolicySpecs(context.Background(), []*fleet.PolicySpec{
+		{
+			Name:        "query1",
+			Query:       "select 1;",
+			Description: "query1 desc",
+			Resolution:  "some resolution",
+		},
+		{
+			Name:        "query2",
+			Query:       "select 2;",
+			Description: "query2 desc",
+			Team:        "team1",
+			Platform:    "darwin",
+		},
+	}))
+
+	policies, err := ds.ListGlobalPolicies(context.Background())
+	require.NoError(t, err)
+	require.Len(t, policies, 1)
+	assert.Equal(t, "query1", policies[0].Name)
+	assert.Equal(t, "select 1;", policies[0].Query)
+	assert.Equal(t, "query1 desc", policies[0].Description)
+	assert.Equal(t, "some resolution", policies[0].Resolution)
+	assert.Nil(t, policies[0].QueryID)
+	assert.Nil(t, policies[0].TeamID)
+
+	teamPolicies, err := ds.ListTeamPolicies(context.Background(), team1.ID)
+	require.NoError(t, err)
+	require.Len(t, teamPolicies, 1)
+	assert.Equal(t, "query2", teamPolicies[0].Name)
+	assert.Equal(t, "darwin", teamPolicies[0].Platform)
+	require.NotNil(t, teamPolicies[0].TeamID)
+	assert.Equal(t, team1.ID, *teamPolicies[0].TeamID)
+
+	// Applying again with the same name updates the policy in place.
+	require.NoError(t, ds.ApplyPolicySpecs(context.Background(), []*fleet.PolicySpec{
+		{
+			Name:        "query1",
+			Query:       "select 42;",
+			Description: "query1 other desc",
+			Resolution:  "some other resolution",
+		},
+	}))
+
+	updated, err := ds.ListGlobalPolicies(context.Background())
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, policies[0].ID, updated[0].ID)
+	assert.Equal(t, "select 42;", updated[0].Query)
+	assert.Equal(t, "query1 other desc", updated[0].Description)
+	assert.Equal(t, "some other resolution", updated[0].Resolution)
+
+	// Only darwin hosts of the team get the darwin policy.
+	linuxHost, err := ds.NewHost(context.Background(), &fleet.Host{
+		OsqueryHostID:   "1234",
+		DetailUpdatedAt: time.Now(),
+		LabelUpdatedAt:  time.Now(),
+		SeenTime:        time.Now(),
+		NodeKey:         "1",
+		UUID:            "1",
+		Hostname:        "foo.local",
+		Platform:        "ubuntu",
+		TeamID:          &team1.ID,
+	})
+	require.NoError(t, err)
+	queries, err := ds.PolicyQueriesForHost(context.Background(), linuxHost)
+	require.NoError(t, err)
+	require.Len(t, queries, 1)
+	assert.Equal(t, "select 42;", queries[fmt.Sprint(updated[0].ID)])
+
+	require.Error(t, ds.ApplyPolicySpecs(context.Background(), []*fleet.PolicySpec{
+		{Name: "query3", Query: "select 3;", Team: "no such team"},
+	}))
+}
@@ -0,0 +1,209 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// querySweepRow is a row of the query_sweeps table, with the targets as
+// stored JSON.
+type querySweepRow struct {
+	ID               uint       `db:"id"`
+	Name             string     `db:"name"`
+	Description      string     `db:"description"`
+	Query            string     `db:"query"`
+	Targets          []byte     `db:"targets"`
+	Interval         uint       `db:"interval"`
+	WebhookURL       string     `db:"webhook_url"`
+	AuthorID         *uint      `db:"author_id"`
+	LastRunAt        *time.Time `db:"last_run_at"`
+	LastCampaignID   *uint      `db:"last_campaign_id"`
+	DiffedCampaignID *uint      `db:"diffed_campaign_id"`
+	CreatedAt        time.Time  `db:"created_at"`
+	UpdatedAt        time.Time  `db:"updated_at"`
+}
+
+func (r querySweepRow) toQuerySweep() (*fleet.QuerySweep, error) {
+	sweep := &fleet.QuerySweep{
+		ID:               r.ID,
+		Name:             r.Name,
+		Description:      r.Description,
+		Query:            r.Query,
+		Interval:         r.Interval,
+		WebhookURL:       r.WebhookURL,
+		AuthorID:         r.AuthorID,
+		LastRunAt:        r.LastRunAt,
+		LastCampaignID:   r.LastCampaignID,
+		DiffedCampaignID: r.DiffedCampaignID,
+	}
+	sweep.CreatedAt = r.CreatedAt
+	sweep.UpdatedAt = r.UpdatedAt
+	if err := json.Unmarshal(r.Targets, &sweep.Targets); err != nil {
+		return nil, errors.Wrap(err, "unmarshal query sweep targets")
+	}
+	return sweep, nil
+}
+
+const selectQuerySweepsSQL = `
+	SELECT id, name, description, query, targets, ` + "`interval`" + `, webhook_url, author_id,
+		last_run_at, last_campaign_id, diffed_campaign_id, created_at, updated_at
+	FROM query_sweeps
+`
+
+func (d *Datastore) NewQuerySweep(ctx context.Context, sweep *fleet.QuerySweep) (*fleet.QuerySweep, error) {
+	targets, err := json.Marshal(sweep.Targets)
+	if err != nil {
+		return nil, errors.Wrap(err, "marshal query sweep targets")
+	}
+
+	sqlStatement := `
+		INSERT INTO query_sweeps (name, description, query, targets, ` + "`interval`" + `, webhook_url, author_id)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+	res, err := d.writer.ExecContext(ctx, sqlStatement,
+		sweep.Name, sweep.Description, sweep.Query, targets, sweep.Interval, sweep.WebhookURL, sweep.AuthorID,
+	)
+	if err != nil {
+		if isDuplicate(err) {
+			return nil, alreadyExists("QuerySweep", sweep.Name)
+		}
+		return nil, errors.Wrap(err, "insert query sweep")
+	}
+
+	id, _ := res.LastInsertId()
+	return d.QuerySweep(ctx, uint(id))
+}
+
+func (d *Datastore) QuerySweep(ctx context.Context, id uint) (*fleet.QuerySweep, error) {
+	var row querySweepRow
+	if err := sqlx.GetContext(ctx, d.reader, &row, selectQuerySweepsSQL+` WHERE id = ?`, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("QuerySweep").WithID(id)
+		}
+		return nil, errors.Wrap(err, "get query sweep")
+	}
+	return row.toQuerySweep()
+}
+
+func (d *Datastore) ListQuerySweeps(ctx context.Context, opt fleet.ListOptions) ([]*fleet.QuerySweep, error) {
+	if opt.OrderKey == "" {
+		opt.OrderKey = "id"
+	}
+	var rows []querySweepRow
+	if err := sqlx.SelectContext(ctx, d.reader, &rows, appendListOptionsToSQL(selectQuerySweepsSQL, opt)); err != nil {
+		return nil, errors.Wrap(err, "list query sweeps")
+	}
+
+	sweeps := make([]*fleet.QuerySweep, 0, len(rows))
+	for _, row := range rows {
+		sweep, err := row.toQuerySweep()
+		if err != nil {
+			return nil, err
+		}
+		sweeps = append(sweeps, sweep)
+	}
+	return sweeps, nil
+}
+
+func (d *Datastore) SaveQuerySweep(ctx context.Context, sweep *fleet.QuerySweep) error {
+	targets, err := json.Marshal(sweep.Targets)
+	if err != nil {
+		return errors.Wrap(err, "marshal query sweep targets")
+	}
+
+	sqlStatement := `
+		UPDATE query_sweeps SET
+			name = ?,
+			description = ?,
+			query = ?,
+			targets = ?,
+			` + "`interval`" + ` = ?,
+			webhook_url = ?,
+			last_run_at = ?,
+			last_campaign_id = ?,
+			diffed_campaign_id = ?
+		WHERE id = ?
+	`
+	res, err := d.writer.ExecContext(ctx, sqlStatement,
+		sweep.Name, sweep.Description, sweep.Query, targets, sweep.Interval, sweep.WebhookURL,
+		sweep.LastRunAt, sweep.LastCampaignID, sweep.DiffedCampaignID, sweep.ID,
+	)
+	if err != nil {
+		if isDuplicate(err) {
+			return alreadyExists("QuerySweep", sweep.Name)
+		}
+		return errors.Wrap(err, "update query sweep")
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "rows affected updating query sweep")
+	}
+	if rows == 0 {
+		return notFound("QuerySweep").WithID(sweep.ID)
+	}
+	return nil
+}
+
+func (d *Datastore) DeleteQuerySweep(ctx context.Context, id uint) error {
+	return d.deleteEntity(ctx, "query_sweeps", id)
+}
+
+func (d *Datastore) QuerySweepHostResults(ctx context.Context, sweepID uint, hostIDs []uint) (map[uint][]map[string]string, error) {
+	results := make(map[uint][]map[string]string)
+	if len(hostIDs) == 0 {
+		return results, nil
+	}
+
+	sqlStatement, args, err := sqlx.In(
+		`SELECT host_id, data FROM query_sweep_host_results WHERE query_sweep_id = ? AND host_id IN (?)`,
+		sweepID, hostIDs,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "build query sweep host results query")
+	}
+	var rows []struct {
+		HostID uint   `db:"host_id"`
+		Data   []byte `db:"data"`
+	}
+	if err := sqlx.SelectContext(ctx, d.reader, &rows, sqlStatement, args...); err != nil {
+		return nil, errors.Wrap(err, "select query sweep host results")
+	}
+
+	for _, row := range rows {
+		var data []map[string]string
+		if err := json.Unmarshal(row.Data, &data); err != nil {
+			return nil, errors.Wrap(err, "unmarshal query sweep host results")
+		}
+		results[row.HostID] = data
+	}
+	return results, nil
+}
+
+func (d *Datastore) SaveQuerySweepHostResults(ctx context.Context, sweepID uint, results map[uint][]map[string]string) error {
+	return d.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		sqlStatement := `
+			INSERT INTO query_sweep_host_results (query_sweep_id, host_id, data)
+			VALUES (?, ?, ?)
+			ON DUPLICATE KEY UPDATE data = VALUES(data)
+		`
+		for hostID, rows := range results {
+			if rows == nil {
+				rows = []map[string]string{}
+			}
+			data, err := json.Marshal(rows)
+			if err != nil {
+				return errors.Wrap(err, "marshal query sweep host results")
+			}
+			if _, err := tx.ExecContext(ctx, sqlStatement, sweepID, hostID, data); err != nil {
+				return errors.Wrap(err, "save query sweep host results")
+			}
+		}
+		return nil
+	})
+}
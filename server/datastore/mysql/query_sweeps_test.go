@@ -0,0 +1,82 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuerySweeps(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	user := test.NewUser(t, ds, "Zach", "zwass@fleet.co", true)
+
+	sweep, err := ds.NewQuerySweep(context.Background(), &fleet.QuerySweep{
+		Name:     "listening ports",
+		Query:    "select port from listening_ports",
+		Targets:  fleet.HostTargets{LabelIDs: []uint{1}},
+		Interval: 3600,
+		AuthorID: &user.ID,
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, sweep.ID)
+	assert.Equal(t, fleet.HostTargets{LabelIDs: []uint{1}}, sweep.Targets)
+	assert.Nil(t, sweep.LastRunAt)
+
+	_, err = ds.NewQuerySweep(context.Background(), &fleet.QuerySweep{Name: "listening ports", Query: "select 1"})
+	require.Error(t, err)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	sweep.Interval = 60
+	sweep.WebhookURL = "https://example.com/sweeps"
+	sweep.LastRunAt = &now
+	sweep.LastCampaignID = ptr.Uint(12)
+	sweep.DiffedCampaignID = ptr.Uint(11)
+	require.NoError(t, ds.SaveQuerySweep(context.Background(), sweep))
+
+	loaded, err := ds.QuerySweep(context.Background(), sweep.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(60), loaded.Interval)
+	assert.Equal(t, "https://example.com/sweeps", loaded.WebhookURL)
+	require.NotNil(t, loaded.LastRunAt)
+	assert.Equal(t, now, loaded.LastRunAt.UTC())
+	assert.Equal(t, ptr.Uint(12), loaded.LastCampaignID)
+	assert.Equal(t, ptr.Uint(11), loaded.DiffedCampaignID)
+
+	_, err = ds.NewQuerySweep(context.Background(), &fleet.QuerySweep{Name: "processes", Query: "select name from processes"})
+	require.NoError(t, err)
+	sweeps, err := ds.ListQuerySweeps(context.Background(), fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, sweeps, 2)
+	assert.Equal(t, "listening ports", sweeps[0].Name)
+	assert.Equal(t, "processes", sweeps[1].Name)
+
+	require.NoError(t, ds.SaveQuerySweepHostResults(context.Background(), sweep.ID, map[uint][]map[string]string{
+		1: {{"port": "22"}},
+		2: nil,
+	}))
+	require.NoError(t, ds.SaveQuerySweepHostResults(context.Background(), sweep.ID, map[uint][]map[string]string{
+		1: {{"port": "22"}, {"port": "80"}},
+	}))
+	results, err := ds.QuerySweepHostResults(context.Background(), sweep.ID, []uint{1, 2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, map[uint][]map[string]string{
+		1: {{"port": "22"}, {"port": "80"}},
+		2: {},
+	}, results)
+
+	require.NoError(t, ds.DeleteQuerySweep(context.Background(), sweep.ID))
+	_, err = ds.QuerySweep(context.Background(), sweep.ID)
+	require.Error(t, err)
+	require.Error(t, ds.DeleteQuerySweep(context.Background(), sweep.ID))
+	results, err = ds.QuerySweepHostResults(context.Background(), sweep.ID, []uint{1, 2})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+}
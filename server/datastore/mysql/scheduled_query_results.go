@@ -0,0 +1,167 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// scheduledQueryResultsBatchSize is how many rows of results are inserted per
+// statement.
+const scheduledQueryResultsBatchSize = 1000
+
+func (d *Datastore) SaveScheduledQueryResults(ctx context.Context, hostID uint, logs []*fleet.ScheduledQueryResultLog, maxRows int) error {
+	if len(logs) == 0 || maxRows <= 0 {
+		return nil
+	}
+
+	return d.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		type packQuery struct{ pack, query string }
+		queryIDs := make(map[packQuery]uint)
+		var touched []uint
+		for _, log := range logs {
+			key := packQuery{log.PackName, log.QueryName}
+			queryID, ok := queryIDs[key]
+			if !ok {
+				err := sqlx.GetContext(ctx, tx, &queryID, `
+					SELECT sq.id FROM scheduled_queries sq JOIN packs p ON (sq.pack_id = p.id)
+					WHERE p.name = ? AND sq.name = ?
+				`, log.PackName, log.QueryName)
+				switch {
+				case err == sql.ErrNoRows:
+					// The query was deleted or was never scheduled by Fleet.
+					queryIDs[key] = 0
+					continue
+				case err != nil:
+					return errors.Wrap(err, "select scheduled query")
+				}
+				queryIDs[key] = queryID
+				touched = append(touched, queryID)
+			}
+			if queryID == 0 {
+				continue
+			}
+
+			if log.Snapshot {
+				if _, err := tx.ExecContext(ctx,
+					`DELETE FROM scheduled_query_results WHERE scheduled_query_id = ? AND host_id = ?`, queryID, hostID,
+				); err != nil {
+					return errors.Wrap(err, "delete previous scheduled query results")
+				}
+			}
+			if err := insertScheduledQueryResultsDB(ctx, tx, queryID, hostID, log, maxRows); err != nil {
+				return err
+			}
+		}
+
+		// Only keep the latest rows of each query.
+		for _, queryID := range touched {
+			if _, err := tx.ExecContext(ctx, `
+				DELETE FROM scheduled_query_results
+				WHERE scheduled_query_id = ? AND host_id = ? AND id <= (
+					SELECT id FROM (
+						SELECT id FROM scheduled_query_results
+						WHERE scheduled_query_id = ? AND host_id = ?
+						ORDER BY id DESC LIMIT 1 OFFSET ?
+					) oldest
+				)
+			`, queryID, hostID, queryID, hostID, maxRows); err != nil {
+				return errors.Wrap(err, "delete oldest scheduled query results")
+			}
+		}
+		return nil
+	})
+}
+
+func insertScheduledQueryResultsDB(ctx context.Context, tx sqlx.ExtContext, queryID, hostID uint, log *fleet.ScheduledQueryResultLog, maxRows int) error {
+	rows := log.Rows
+	if len(rows) > maxRows {
+		rows = rows[len(rows)-maxRows:]
+	}
+	for len(rows) > 0 {
+		batch := rows
+		if len(batch) > scheduledQueryResultsBatchSize {
+			batch = batch[:scheduledQueryResultsBatchSize]
+		}
+		rows = rows[len(batch):]
+
+		args := make([]interface{}, 0, len(batch)*5)
+		for _, row := range batch {
+			data, err := json.Marshal(row.Data)
+			if err != nil {
+				return errors.Wrap(err, "marshal scheduled query result row")
+			}
+			args = append(args, queryID, hostID, row.Action, data, log.CollectedAt)
+		}
+		values := strings.TrimSuffix(strings.Repeat("(?,?,?,?,?),", len(batch)), ",")
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO scheduled_query_results (scheduled_query_id, host_id, action, data, collected_at)
+			VALUES `+values, args...,
+		); err != nil {
+			return errors.Wrap(err, "insert scheduled query results")
+		}
+	}
+	return nil
+}
+
+func (d *Datastore) ListScheduledQueryResults(ctx context.Context, filter fleet.TeamFilter, opt fleet.ScheduledQueryResultsListOptions) ([]*fleet.ScheduledQueryResult, error) {
+	if opt.OrderKey == "" {
+		opt.OrderKey = "id"
+	}
+	var where []string
+	var args []interface{}
+	if opt.ScheduledQueryID != nil {
+		where = append(where, "sqr.scheduled_query_id = ?")
+		args = append(args, *opt.ScheduledQueryID)
+	}
+	if opt.HostID != nil {
+		where = append(where, "sqr.host_id = ?")
+		args = append(args, *opt.HostID)
+	}
+	where = append(where, d.whereFilterHostsByTeams(filter, "h"))
+
+	sqlStatement := `
+		SELECT * FROM (
+			SELECT sqr.id, sqr.scheduled_query_id, sqr.host_id, h.hostname, sqr.action, sqr.data, sqr.collected_at
+			FROM scheduled_query_results sqr
+			JOIN hosts h ON (h.id = sqr.host_id)
+			WHERE ` + strings.Join(where, " AND ") + `
+		) results
+	`
+	sqlStatement = appendListOptionsToSQL(sqlStatement, opt.ListOptions)
+
+	var rows []struct {
+		fleet.ScheduledQueryResult
+		Data json.RawMessage `db:"data"`
+	}
+	if err := sqlx.SelectContext(ctx, d.reader, &rows, sqlStatement, args...); err != nil {
+		return nil, errors.Wrap(err, "list scheduled query results")
+	}
+
+	results := make([]*fleet.ScheduledQueryResult, 0, len(rows))
+	for _, row := range rows {
+		result := row.ScheduledQueryResult
+		if err := json.Unmarshal(row.Data, &result.Data); err != nil {
+			return nil, errors.Wrap(err, "unmarshal scheduled query result row")
+		}
+		results = append(results, &result)
+	}
+	return results, nil
+}
+
+func (d *Datastore) CleanupOrphanScheduledQueryResults(ctx context.Context) error {
+	_, err := d.writer.ExecContext(ctx, `DELETE FROM scheduled_query_results WHERE scheduled_query_id NOT IN (SELECT id FROM scheduled_queries)`)
+	if err != nil {
+		return errors.Wrap(err, "cleaning orphan scheduled_query_results by scheduled_query")
+	}
+	_, err = d.writer.ExecContext(ctx, `DELETE FROM scheduled_query_results WHERE host_id NOT IN (SELECT id FROM hosts)`)
+	if err != nil {
+		return errors.Wrap(err, "cleaning orphan scheduled_query_results by host")
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduledQueryResults(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	user := test.NewUser(t, ds, "Admin", "admin@fleet.co", true)
+	query := test.NewQuery(t, ds, "processes", "select name from processes", user.ID, true)
+	pack := test.NewPack(t, ds, "baz")
+	sq := test.NewScheduledQuery(t, ds, pack.ID, query.ID, 60, false, false, "processes")
+	host1 := test.NewHost(t, ds, "foo.local", "192.168.1.10", "1", "1", time.Now())
+	host2 := test.NewHost(t, ds, "bar.local", "192.168.1.11", "2", "2", time.Now())
+
+	collectedAt := time.Now().UTC().Truncate(time.Second)
+	snapshot := func(names ...string) *fleet.ScheduledQueryResultLog {
+		log := &fleet.ScheduledQueryResultLog{PackName: "baz", QueryName: "processes", Snapshot: true, CollectedAt: collectedAt}
+		for _, name := range names {
+			log.Rows = append(log.Rows, fleet.ScheduledQueryResultRow{Action: fleet.ScheduledQueryResultSnapshot, Data: map[string]string{"name": name}})
+		}
+		return log
+	}
+	listNames := func(opt fleet.ScheduledQueryResultsListOptions) []string {
+		results, err := ds.ListScheduledQueryResults(context.Background(), fleet.TeamFilter{User: test.UserAdmin}, opt)
+		require.NoError(t, err)
+		var names []string
+		for _, res := range results {
+			names = append(names, res.Action+":"+res.Data["name"])
+		}
+		return names
+	}
+
+	// Logs of unknown queries are ignored.
+	require.NoError(t, ds.SaveScheduledQueryResults(context.Background(), host1.ID, []*fleet.ScheduledQueryResultLog{
+		{PackName: "baz", QueryName: "unknown", Snapshot: true, Rows: snapshot("a").Rows},
+		snapshot("osqueryd", "sshd"),
+	}, 3))
+	require.NoError(t, ds.SaveScheduledQueryResults(context.Background(), host2.ID, []*fleet.ScheduledQueryResultLog{
+		snapshot("launchd"),
+	}, 3))
+
+	assert.Equal(t, []string{"snapshot:osqueryd", "snapshot:sshd"}, listNames(fleet.ScheduledQueryResultsListOptions{HostID: &host1.ID}))
+	assert.Equal(t, []string{"snapshot:osqueryd", "snapshot:sshd", "snapshot:launchd"}, listNames(fleet.ScheduledQueryResultsListOptions{ScheduledQueryID: &sq.ID}))
+	assert.Empty(t, listNames(fleet.ScheduledQueryResultsListOptions{ScheduledQueryID: ptr.Uint(999)}))
+
+	results, err := ds.ListScheduledQueryResults(context.Background(), fleet.TeamFilter{User: test.UserAdmin},
+		fleet.ScheduledQueryResultsListOptions{HostID: &host2.ID})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, sq.ID, results[0].ScheduledQueryID)
+	assert.Equal(t, "bar.local", results[0].Hostname)
+	assert.Equal(t, collectedAt, results[0].CollectedAt.UTC())
+
+	// A snapshot replaces the previous rows.
+	require.NoError(t, ds.SaveScheduledQueryResults(context.Background(), host1.ID, []*fleet.ScheduledQueryResultLog{
+		snapshot("cron"),
+	}, 3))
+	assert.Equal(t, []string{"snapshot:cron"}, listNames(fleet.ScheduledQueryResultsListOptions{HostID: &host1.ID}))
+
+	// Differential results are added, and only the latest rows are kept.
+	require.NoError(t, ds.SaveScheduledQueryResults(context.Background(), host1.ID, []*fleet.ScheduledQueryResultLog{
+		{PackName: "baz", QueryName: "processes", CollectedAt: collectedAt, Rows: []fleet.ScheduledQueryResultRow{
+			{Action: fleet.ScheduledQueryResultAdded, Data: map[string]string{"name": "nginx"}},
+			{Action: fleet.ScheduledQueryResultRemoved, Data: map[string]string{"name": "cron"}},
+			{Action: fleet.ScheduledQueryResultAdded, Data: map[string]string{"name": "redis"}},
+		}},
+	}, 3))
+	assert.Equal(t, []string{"added:nginx", "removed:cron", "added:redis"}, listNames(fleet.ScheduledQueryResultsListOptions{HostID: &host1.ID}))
+	assert.Equal(t, []string{"removed:cron"}, listNames(fleet.ScheduledQueryResultsListOptions{HostID: &host1.ID, ListOptions: fleet.ListOptions{Page: 1, PerPage: 1}}))
+
+	// Observers of no team see nothing.
+	results, err = ds.ListScheduledQueryResults(context.Background(), fleet.TeamFilter{User: test.UserNoRoles}, fleet.ScheduledQueryResultsListOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// The results of deleted hosts are cleaned up.
+	require.NoError(t, ds.DeleteHost(context.Background(), host2.ID))
+	require.NoError(t, ds.CleanupOrphanScheduledQueryResults(context.Background()))
+	var count int
+	require.NoError(t, ds.writer.Get(&count, `SELECT COUNT(*) FROM scheduled_query_results WHERE host_id = ?`, host2.ID))
+	assert.Zero(t, count)
+	require.NoError(t, ds.writer.Get(&count, `SELECT COUNT(*) FROM scheduled_query_results`))
+	assert.Equal(t, 3, count)
+}
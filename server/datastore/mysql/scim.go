@@ -0,0 +1,171 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+func (d *Datastore) NewSCIMGroup(ctx context.Context, group *fleet.SCIMGroup) (*fleet.SCIMGroup, error) {
+	var id int64
+	err := d.withTx(ctx, func(tx sqlx.ExtContext) error {
+		result, err := tx.ExecContext(ctx, `INSERT INTO scim_groups (display_name) VALUES (?)`, group.DisplayName)
+		if err != nil {
+			if isDuplicate(err) {
+				return alreadyExists("SCIMGroup", group.DisplayName)
+			}
+			return errors.Wrap(err, "inserting scim group")
+		}
+		id, _ = result.LastInsertId()
+		return insertSCIMGroupMembersDB(ctx, tx, uint(id), group.UserIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return d.SCIMGroup(ctx, uint(id))
+}
+
+func insertSCIMGroupMembersDB(ctx context.Context, tx sqlx.ExtContext, groupID uint, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	// The duplicate members are removed here rather than with INSERT IGNORE,
+	// which would also ignore the unknown users. Only the users provisioned
+	// through the SCIM API can be members of the groups.
+	inserted := make(map[uint]bool, len(userIDs))
+	var args []uint
+	for _, userID := range userIDs {
+		if !inserted[userID] {
+			inserted[userID] = true
+			args = append(args, userID)
+		}
+	}
+	sql, sqlArgs, err := sqlx.In(`
+		INSERT INTO scim_group_members (group_id, user_id)
+		SELECT ?, id FROM users WHERE id IN (?) AND scim_managed = 1
+	`, groupID, args)
+	if err != nil {
+		return errors.Wrap(err, "sqlx.In insert scim group members")
+	}
+	result, err := tx.ExecContext(ctx, sql, sqlArgs...)
+	if err != nil {
+		return errors.Wrap(err, "insert scim group members")
+	}
+	if rows, _ := result.RowsAffected(); rows != int64(len(args)) {
+		return notFound("User")
+	}
+	return nil
+}
+
+func (d *Datastore) SCIMGroup(ctx context.Context, id uint) (*fleet.SCIMGroup, error) {
+	// Read from the primary, as the group is loaded right after its changes.
+	var group fleet.SCIMGroup
+	err := sqlx.GetContext(ctx, d.writer, &group, `SELECT * FROM scim_groups WHERE id = ?`, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFound("SCIMGroup").WithID(id)
+		}
+		return nil, errors.Wrap(err, "selecting scim group")
+	}
+
+	group.UserIDs = []uint{}
+	sqlStatement := `SELECT user_id FROM scim_group_members WHERE group_id = ? ORDER BY user_id`
+	if err := sqlx.SelectContext(ctx, d.writer, &group.UserIDs, sqlStatement, id); err != nil {
+		return nil, errors.Wrap(err, "selecting scim group members")
+	}
+	return &group, nil
+}
+
+func (d *Datastore) ListSCIMGroups(ctx context.Context) ([]*fleet.SCIMGroup, error) {
+	var groups []*fleet.SCIMGroup
+	if err := sqlx.SelectContext(ctx, d.reader, &groups, `SELECT * FROM scim_groups ORDER BY id`); err != nil {
+		return nil, errors.Wrap(err, "selecting scim groups")
+	}
+
+	var members []struct {
+		GroupID uint `db:"group_id"`
+		UserID  uint `db:"user_id"`
+	}
+	sqlStatement := `SELECT group_id, user_id FROM scim_group_members ORDER BY group_id, user_id`
+	if err := sqlx.SelectContext(ctx, d.reader, &members, sqlStatement); err != nil {
+		return nil, errors.Wrap(err, "selecting scim group members")
+	}
+
+	groupsByID := make(map[uint]*fleet.SCIMGroup, len(groups))
+	for _, group := range groups {
+		// Initialize empty slice so we get an array in JSON responses instead
+		// of null if it is empty
+		group.UserIDs = []uint{}
+		groupsByID[group.ID] = group
+	}
+	for _, member := range members {
+		if group, ok := groupsByID[member.GroupID]; ok {
+			group.UserIDs = append(group.UserIDs, member.UserID)
+		}
+	}
+	return groups, nil
+}
+
+func (d *Datastore) SaveSCIMGroup(ctx context.Context, group *fleet.SCIMGroup) error {
+	return d.withTx(ctx, func(tx sqlx.ExtContext) error {
+		_, err := tx.ExecContext(ctx, `UPDATE scim_groups SET display_name = ? WHERE id = ?`, group.DisplayName, group.ID)
+		if err != nil {
+			if isDuplicate(err) {
+				return alreadyExists("SCIMGroup", group.DisplayName)
+			}
+			return errors.Wrap(err, "save scim group")
+		}
+
+		// Do a full members update by deleting the existing members and then
+		// inserting the current members.
+		if _, err := tx.ExecContext(ctx, `DELETE FROM scim_group_members WHERE group_id = ?`, group.ID); err != nil {
+			return errors.Wrap(err, "delete existing scim group members")
+		}
+		return insertSCIMGroupMembersDB(ctx, tx, group.ID, group.UserIDs)
+	})
+}
+
+func (d *Datastore) DeleteSCIMGroup(ctx context.Context, id uint) error {
+	return d.deleteEntity(ctx, "scim_groups", id)
+}
+
+func (d *Datastore) SCIMUserByID(ctx context.Context, id uint) (*fleet.User, error) {
+	user, err := d.UserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.SCIMManaged {
+		return nil, notFound("User").WithID(id)
+	}
+	return user, nil
+}
+
+func (d *Datastore) ListSCIMUsers(ctx context.Context, opt fleet.SCIMUserListOptions) ([]*fleet.User, int, error) {
+	whereClause := "WHERE scim_managed = 1"
+	var params []interface{}
+	if opt.UserName != "" {
+		// The collation of the emails is case insensitive.
+		whereClause += " AND email = ?"
+		params = append(params, opt.UserName)
+	}
+
+	var total int
+	if err := sqlx.GetContext(ctx, d.reader, &total, "SELECT COUNT(*) FROM users "+whereClause, params...); err != nil {
+		return nil, 0, errors.Wrap(err, "count scim users")
+	}
+
+	users := []*fleet.User{}
+	sqlStatement := "SELECT * FROM users " + whereClause + " ORDER BY id LIMIT ? OFFSET ?"
+	params = append(params, opt.Limit, opt.Offset)
+	if err := sqlx.SelectContext(ctx, d.reader, &users, sqlStatement, params...); err != nil {
+		return nil, 0, errors.Wrap(err, "list scim users")
+	}
+	if err := d.loadTeamsForUsers(ctx, users); err != nil {
+		return nil, 0, errors.Wrap(err, "load teams")
+	}
+	return users, total, nil
+}
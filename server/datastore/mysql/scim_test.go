@@ -0,0 +1,120 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSCIMGroups(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	alice := newSCIMUser(t, ds, "alice@fleet.co")
+	bob := newSCIMUser(t, ds, "bob@fleet.co")
+	local := test.NewUser(t, ds, "Local", "local@fleet.co", true)
+
+	admins, err := ds.NewSCIMGroup(context.Background(), &fleet.SCIMGroup{
+		DisplayName: "Fleet Admins",
+		UserIDs:     []uint{bob.ID, alice.ID, bob.ID},
+	})
+	require.NoError(t, err)
+	assert.NotZero(t, admins.ID)
+	assert.Equal(t, []uint{alice.ID, bob.ID}, admins.UserIDs)
+
+	// The display names are unique.
+	_, err = ds.NewSCIMGroup(context.Background(), &fleet.SCIMGroup{DisplayName: "Fleet Admins"})
+	require.Error(t, err)
+	// The members must exist.
+	_, err = ds.NewSCIMGroup(context.Background(), &fleet.SCIMGroup{DisplayName: "Ghosts", UserIDs: []uint{999}})
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+	// The members must be provisioned through SCIM.
+	_, err = ds.NewSCIMGroup(context.Background(), &fleet.SCIMGroup{DisplayName: "Locals", UserIDs: []uint{alice.ID, local.ID}})
+	require.Error(t, err)
+	assert.True(t, fleet.IsNotFound(err))
+
+	empty, err := ds.NewSCIMGroup(context.Background(), &fleet.SCIMGroup{DisplayName: "Empty"})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{}, empty.UserIDs)
+
+	admins.DisplayName = "Admins"
+	admins.UserIDs = []uint{bob.ID}
+	require.NoError(t, ds.SaveSCIMGroup(context.Background(), admins))
+	admins, err = ds.SCIMGroup(context.Background(), admins.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Admins", admins.DisplayName)
+	assert.Equal(t, []uint{bob.ID}, admins.UserIDs)
+
+	empty.DisplayName = "Admins"
+	require.Error(t, ds.SaveSCIMGroup(context.Background(), empty))
+
+	// Deleting a user removes it from its groups.
+	require.NoError(t, ds.DeleteUser(context.Background(), bob.ID))
+	groups, err := ds.ListSCIMGroups(context.Background())
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, admins.ID, groups[0].ID)
+	assert.Equal(t, []uint{}, groups[0].UserIDs)
+	assert.Equal(t, "Empty", groups[1].DisplayName)
+
+	require.NoError(t, ds.DeleteSCIMGroup(context.Background(), admins.ID))
+	_, err = ds.SCIMGroup(context.Background(), admins.ID)
+	assert.True(t, fleet.IsNotFound(err))
+	assert.True(t, fleet.IsNotFound(ds.DeleteSCIMGroup(context.Background(), admins.ID)))
+}
+
+func newSCIMUser(t *testing.T, ds *Datastore, email string) *fleet.User {
+	user, err := ds.NewUser(context.Background(), &fleet.User{
+		Password:    []byte("garbage"),
+		Salt:        "garbage",
+		Name:        email,
+		Email:       email,
+		SSOEnabled:  true,
+		GlobalRole:  ptr.String(fleet.RoleObserver),
+		SCIMManaged: true,
+	})
+	require.NoError(t, err)
+	return user
+}
+
+func TestSCIMUsers(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	var scimUsers []*fleet.User
+	for _, email := range []string{"alice@fleet.co", "bob@fleet.co", "carol@fleet.co"} {
+		scimUsers = append(scimUsers, newSCIMUser(t, ds, email))
+	}
+	local := test.NewUser(t, ds, "Local", "local@fleet.co", true)
+
+	user, err := ds.SCIMUserByID(context.Background(), scimUsers[0].ID)
+	require.NoError(t, err)
+	assert.True(t, user.SCIMManaged)
+	assert.Equal(t, "alice@fleet.co", user.Email)
+	_, err = ds.SCIMUserByID(context.Background(), local.ID)
+	assert.True(t, fleet.IsNotFound(err))
+
+	users, total, err := ds.ListSCIMUsers(context.Background(), fleet.SCIMUserListOptions{Offset: 1, Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+	require.Len(t, users, 1)
+	assert.Equal(t, scimUsers[1].ID, users[0].ID)
+
+	// The user names are case insensitive.
+	users, total, err = ds.ListSCIMUsers(context.Background(), fleet.SCIMUserListOptions{UserName: "CAROL@fleet.co", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+	require.Len(t, users, 1)
+	assert.Equal(t, scimUsers[2].ID, users[0].ID)
+
+	users, total, err = ds.ListSCIMUsers(context.Background(), fleet.SCIMUserListOptions{UserName: "local@fleet.co", Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 0, total)
+	assert.Empty(t, users)
+}
@@ -3,7 +3,9 @@ package mysql
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/jmoiron/sqlx"
@@ -14,6 +16,7 @@ const (
 	maxSoftwareNameLen    = 255
 	maxSoftwareVersionLen = 255
 	maxSoftwareSourceLen  = 64
+	maxSoftwareReleaseLen = 64
 )
 
 func truncateString(str string, length int) string {
@@ -24,7 +27,7 @@ func truncateString(str string, length int) string {
 }
 
 func softwareToUniqueString(s fleet.Software) string {
-	return strings.Join([]string{s.Name, s.Version, s.Source}, "\u0000")
+	return strings.Join([]string{s.Name, s.Version, s.Source, s.Release}, "\u0000")
 }
 
 func uniqueStringToSoftware(s string) fleet.Software {
@@ -33,6 +36,7 @@ func uniqueStringToSoftware(s string) fleet.Software {
 		Name:    truncateString(parts[0], maxSoftwareNameLen),
 		Version: truncateString(parts[1], maxSoftwareVersionLen),
 		Source:  truncateString(parts[2], maxSoftwareSourceLen),
+		Release: truncateString(parts[3], maxSoftwareReleaseLen),
 	}
 }
 
@@ -104,7 +108,14 @@ func nothingChanged(current []fleet.Software, incoming []fleet.Software) bool {
 }
 
 func applyChangesForNewSoftwareDB(ctx context.Context, tx sqlx.ExtContext, host *fleet.Host) error {
-	storedCurrentSoftware, err := listSoftwareDB(ctx, tx, &host.ID, nil, fleet.ListOptions{})
+	// Only the identity of the software is needed to diff it, so the
+	// vulnerabilities are not loaded on this hot path.
+	var storedCurrentSoftware []fleet.Software
+	err := sqlx.SelectContext(ctx, tx, &storedCurrentSoftware, `
+		SELECT s.id, s.name, s.version, s.source, s.release
+		FROM host_software hs JOIN software s ON (hs.software_id=s.id)
+		WHERE hs.host_id=?
+	`, host.ID)
 	if err != nil {
 		return errors.Wrap(err, "loading current software for host")
 	}
@@ -161,8 +172,8 @@ func getOrGenerateSoftwareIdDB(ctx context.Context, tx sqlx.ExtContext, s fleet.
 	var existingId []int64
 	if err := sqlx.SelectContext(ctx, tx,
 		&existingId,
-		`SELECT id FROM software WHERE name = ? and version = ? and source = ?`,
-		s.Name, s.Version, s.Source,
+		"SELECT id FROM software WHERE name = ? and version = ? and source = ? and `release` = ?",
+		s.Name, s.Version, s.Source, s.Release,
 	); err != nil {
 		return 0, err
 	}
@@ -171,8 +182,8 @@ func getOrGenerateSoftwareIdDB(ctx context.Context, tx sqlx.ExtContext, s fleet.
 	}
 
 	result, err := tx.ExecContext(ctx,
-		`INSERT IGNORE INTO software (name, version, source) VALUES (?, ?, ?)`,
-		s.Name, s.Version, s.Source,
+		"INSERT IGNORE INTO software (name, version, source, `release`) VALUES (?, ?, ?, ?)",
+		s.Name, s.Version, s.Source, s.Release,
 	)
 	if err != nil {
 		return 0, errors.Wrap(err, "insert software")
@@ -212,49 +223,109 @@ func insertNewInstalledHostSoftwareDB(
 	return nil
 }
 
-func listSoftwareDB(ctx context.Context, q sqlx.QueryerContext, hostID *uint, teamID *uint, opt fleet.ListOptions) ([]fleet.Software, error) {
-	hostWhere := `hs.host_id=?`
-	if hostID == nil {
-		hostWhere = "TRUE"
-	}
-	teamWhere := `h.team_id=?`
-	if teamID == nil {
-		teamWhere = "TRUE"
+// softwareCVEsSQL selects the software_id and cve of all the vulnerable
+// software, whether the CVE was matched through the CPE of the software or
+// directly against the software.
+const softwareCVEsSQL = `
+	SELECT scp.software_id, scv.cve
+	FROM software_cpe scp
+	JOIN software_cve scv ON (scp.id=scv.cpe_id)
+	UNION
+	SELECT scv.software_id, scv.cve
+	FROM software_cve scv
+	WHERE scv.software_id IS NOT NULL
+`
+
+func listSoftwareDB(ctx context.Context, q sqlx.QueryerContext, hostID *uint, opts fleet.SoftwareListOptions) ([]fleet.Software, error) {
+	var args []interface{}
+	from := `FROM host_software hs JOIN software s ON (hs.software_id=s.id)`
+	where := `hs.host_id=?`
+	countColumn := ""
+	if hostID != nil {
+		args = append(args, hostID)
+	} else {
+		// The host counts are calculated periodically, so the software is
+		// still listed from host_software to include newly seen software.
+		var teamID uint
+		if opts.TeamID != nil {
+			teamID = *opts.TeamID
+		}
+		from = `FROM software s LEFT JOIN software_host_counts shc ON (s.id=shc.software_id AND shc.team_id=?)`
+		where = `EXISTS (SELECT 1 FROM host_software hs WHERE hs.software_id=s.id)`
+		if opts.TeamID != nil {
+			where = `EXISTS (SELECT 1 FROM host_software hs JOIN hosts h ON (hs.host_id=h.id) WHERE hs.software_id=s.id AND h.team_id=?)`
+		}
+		countColumn = ", coalesce(shc.hosts_count, 0) as hosts_count"
+		args = append(args, teamID)
+		if opts.TeamID != nil {
+			args = append(args, teamID)
+		}
 	}
+
 	sql := fmt.Sprintf(`
-		SELECT DISTINCT s.id, s.name, s.version, s.source, coalesce(scp.cpe, "") as generated_cpe
-		FROM host_software hs
-		JOIN hosts h ON (hs.host_id=h.id)
-		JOIN software s ON (hs.software_id=s.id)
+		SELECT DISTINCT s.id, s.name, s.version, s.source, s.release, coalesce(scp.cpe, "") as generated_cpe, sv.max_cvss_score%s
+		%s
 		LEFT JOIN software_cpe scp ON (s.id=scp.software_id)
-		WHERE %s AND %s
-		GROUP BY s.id, s.name, s.version, s.source, generated_cpe
-	`, hostWhere, teamWhere)
-	sql = appendListOptionsToSQL(sql, opt)
+		LEFT JOIN (
+			SELECT v.software_id, MAX(cm.cvss_score) as max_cvss_score
+			FROM (%s) v
+			JOIN cve_meta cm ON (v.cve=cm.cve)
+			GROUP BY v.software_id
+		) sv ON (s.id=sv.software_id)
+		WHERE %s
+	`, countColumn, from, softwareCVEsSQL, where)
 
-	var result []*fleet.Software
-	vars := []interface{}{}
-	if hostID != nil {
-		vars = append(vars, hostID)
+	if opts.VulnerableOnly {
+		sql += ` AND (
+			EXISTS (SELECT 1 FROM software_cve scv WHERE scv.cpe_id=scp.id) OR
+			EXISTS (SELECT 1 FROM software_cve scv WHERE scv.software_id=s.id)
+		)`
+	}
+	if minScore, ok := fleet.MinCVSSScoreForSeverity(opts.MinSeverity); ok {
+		sql += ` AND sv.max_cvss_score >= ?`
+		args = append(args, minScore)
 	}
-	if teamID != nil {
-		vars = append(vars, teamID)
+	if opts.Source != "" {
+		sql += ` AND s.source = ?`
+		args = append(args, opts.Source)
 	}
-	if err := sqlx.SelectContext(ctx, q, &result, sql, vars...); err != nil {
+	sql, args = searchLike(sql, args, opts.MatchQuery, "s.name", "s.version")
+
+	// Sorting by severity is sorting by the highest score.
+	if opts.OrderKey == "severity" {
+		opts.OrderKey = "max_cvss_score"
+	}
+	sql = appendListOptionsToSQL(sql, opts.ListOptions)
+
+	var result []*fleet.Software
+	if err := sqlx.SelectContext(ctx, q, &result, sql, args...); err != nil {
 		return nil, errors.Wrap(err, "load host software")
 	}
+	if len(result) == 0 {
+		return nil, nil
+	}
 
-	sql = fmt.Sprintf(`
-		SELECT DISTINCT s.id, scv.cve
-		FROM host_software hs
-		JOIN hosts h ON (hs.host_id=h.id)
-		JOIN software s
-		JOIN software_cpe scp ON (s.id=scp.software_id)
+	ids := make([]uint, 0, len(result))
+	for _, software := range result {
+		ids = append(ids, software.ID)
+	}
+	cvesSQL, cvesArgs, err := sqlx.In(`
+		SELECT scp.software_id, scv.cve, cm.cvss_score, cm.cvss_vector, cm.severity, cm.published
+		FROM software_cpe scp
 		JOIN software_cve scv ON (scp.id=scv.cpe_id)
-		WHERE %s AND %s
-	`, hostWhere, teamWhere)
+		LEFT JOIN cve_meta cm ON (scv.cve=cm.cve)
+		WHERE scp.software_id IN (?)
+		UNION
+		SELECT scv.software_id, scv.cve, cm.cvss_score, cm.cvss_vector, cm.severity, cm.published
+		FROM software_cve scv
+		LEFT JOIN cve_meta cm ON (scv.cve=cm.cve)
+		WHERE scv.software_id IN (?)
+	`, ids, ids)
+	if err != nil {
+		return nil, errors.Wrap(err, "build load software cves")
+	}
 
-	rows, err := q.QueryxContext(ctx, sql, vars...)
+	rows, err := q.QueryxContext(ctx, cvesSQL, cvesArgs...)
 	if err != nil {
 		return nil, errors.Wrap(err, "load host software")
 	}
@@ -263,14 +334,12 @@ func listSoftwareDB(ctx context.Context, q sqlx.QueryerContext, hostID *uint, te
 	cvesBySoftware := make(map[uint]fleet.VulnerabilitiesSlice)
 	for rows.Next() {
 		var id uint
-		var cve string
-		if err := rows.Scan(&id, &cve); err != nil {
+		var cve fleet.SoftwareCVE
+		if err := rows.Scan(&id, &cve.CVE, &cve.CVSSScore, &cve.CVSSVector, &cve.Severity, &cve.Published); err != nil {
 			return nil, errors.Wrap(err, "scanning cve")
 		}
-		cvesBySoftware[id] = append(cvesBySoftware[id], fleet.SoftwareCVE{
-			CVE:         cve,
-			DetailsLink: fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", cve),
-		})
+		cve.DetailsLink = fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", cve.CVE)
+		cvesBySoftware[id] = append(cvesBySoftware[id], cve)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, errors.Wrap(err, "error iterating through cve rows")
@@ -287,7 +356,7 @@ func listSoftwareDB(ctx context.Context, q sqlx.QueryerContext, hostID *uint, te
 
 func (d *Datastore) LoadHostSoftware(ctx context.Context, host *fleet.Host) error {
 	host.HostSoftware = fleet.HostSoftware{Modified: false}
-	software, err := listSoftwareDB(ctx, d.reader, &host.ID, nil, fleet.ListOptions{})
+	software, err := listSoftwareDB(ctx, d.reader, &host.ID, fleet.SoftwareListOptions{})
 	if err != nil {
 		return err
 	}
@@ -363,6 +432,246 @@ func (d *Datastore) InsertCVEForCPE(ctx context.Context, cve string, cpes []stri
 	return nil
 }
 
-func (d *Datastore) ListSoftware(ctx context.Context, teamId *uint, opt fleet.ListOptions) ([]fleet.Software, error) {
-	return listSoftwareDB(ctx, d.reader, nil, teamId, opt)
+func (d *Datastore) ListDistroSoftware(ctx context.Context) ([]fleet.DistroSoftware, error) {
+	sql := `
+		SELECT DISTINCT s.id, s.name, s.version, s.source, s.release, h.platform, h.platform_like, h.os_version
+		FROM host_software hs
+		JOIN hosts h ON (hs.host_id=h.id)
+		JOIN software s ON (hs.software_id=s.id)
+		WHERE s.source IN ('deb_packages', 'rpm_packages')
+	`
+	var software []fleet.DistroSoftware
+	if err := sqlx.SelectContext(ctx, d.reader, &software, sql); err != nil {
+		return nil, errors.Wrap(err, "load distro software")
+	}
+	return software, nil
+}
+
+func (d *Datastore) InsertCVEForSoftware(ctx context.Context, cve string, softwareIDs []uint) error {
+	if len(softwareIDs) == 0 {
+		return nil
+	}
+	values := strings.TrimSuffix(strings.Repeat("(?,?),", len(softwareIDs)), ",")
+	sql := fmt.Sprintf(`INSERT IGNORE INTO software_cve (software_id, cve) VALUES %s`, values)
+	var args []interface{}
+	for _, id := range softwareIDs {
+		args = append(args, id, cve)
+	}
+	if _, err := d.writer.ExecContext(ctx, sql, args...); err != nil {
+		return errors.Wrap(err, "insert software cve")
+	}
+	return nil
+}
+
+func (d *Datastore) ListSoftwareCVEs(ctx context.Context) ([]string, error) {
+	var cves []string
+	if err := sqlx.SelectContext(ctx, d.reader, &cves, `SELECT DISTINCT cve FROM software_cve`); err != nil {
+		return nil, errors.Wrap(err, "list software cves")
+	}
+	return cves, nil
+}
+
+func (d *Datastore) InsertCVEMeta(ctx context.Context, meta []fleet.CVEMeta) error {
+	const batchSize = 500
+	for i := 0; i < len(meta); i += batchSize {
+		end := i + batchSize
+		if end > len(meta) {
+			end = len(meta)
+		}
+		batch := meta[i:end]
+
+		values := strings.TrimSuffix(strings.Repeat("(?,?,?,?,?),", len(batch)), ",")
+		sql := fmt.Sprintf(`
+			INSERT INTO cve_meta (cve, cvss_score, cvss_vector, severity, published)
+			VALUES %s
+			ON DUPLICATE KEY UPDATE
+				cvss_score = VALUES(cvss_score),
+				cvss_vector = VALUES(cvss_vector),
+				severity = VALUES(severity),
+				published = VALUES(published)
+		`, values)
+		var args []interface{}
+		for _, m := range batch {
+			args = append(args, m.CVE, m.CVSSScore, m.CVSSVector, m.Severity, m.Published)
+		}
+		if _, err := d.writer.ExecContext(ctx, sql, args...); err != nil {
+			return errors.Wrap(err, "insert cve meta")
+		}
+	}
+	return nil
+}
+
+func (d *Datastore) ListSoftware(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, error) {
+	return listSoftwareDB(ctx, d.reader, nil, opt)
+}
+
+func (d *Datastore) SyncVulnerableHostCVEs(ctx context.Context, queue bool) error {
+	// The diff is done one CVE at a time, each in its own transaction, so
+	// that no single statement locks the whole table.
+	var pairs []struct {
+		SoftwareID uint   `db:"software_id"`
+		CVE        string `db:"cve"`
+	}
+	if err := sqlx.SelectContext(ctx, d.writer, &pairs, softwareCVEsSQL); err != nil {
+		return errors.Wrap(err, "list software cves")
+	}
+	softwareByCVE := make(map[string][]uint)
+	for _, p := range pairs {
+		softwareByCVE[p.CVE] = append(softwareByCVE[p.CVE], p.SoftwareID)
+	}
+
+	var known []string
+	if err := sqlx.SelectContext(ctx, d.writer, &known, `SELECT DISTINCT cve FROM vulnerable_host_cves`); err != nil {
+		return errors.Wrap(err, "list vulnerable host cves")
+	}
+	cves := make([]string, 0, len(softwareByCVE)+len(known))
+	for cve := range softwareByCVE {
+		cves = append(cves, cve)
+	}
+	for _, cve := range known {
+		if _, ok := softwareByCVE[cve]; !ok {
+			cves = append(cves, cve)
+		}
+	}
+	sort.Strings(cves)
+
+	for _, cve := range cves {
+		if err := d.syncVulnerableHostsForCVE(ctx, cve, softwareByCVE[cve], queue); err != nil {
+			return errors.Wrapf(err, "sync vulnerable hosts for %s", cve)
+		}
+	}
+	return nil
+}
+
+func (d *Datastore) syncVulnerableHostsForCVE(ctx context.Context, cve string, softwareIDs []uint, queue bool) error {
+	return d.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		if len(softwareIDs) == 0 {
+			if _, err := tx.ExecContext(ctx, `DELETE FROM vulnerable_host_cves WHERE cve = ?`, cve); err != nil {
+				return errors.Wrap(err, "delete vulnerable host cves")
+			}
+			return nil
+		}
+
+		deleteStmt, args, err := sqlx.In(`
+			DELETE FROM vulnerable_host_cves
+			WHERE cve = ? AND NOT EXISTS (
+				SELECT 1 FROM host_software hs
+				WHERE hs.host_id=vulnerable_host_cves.host_id AND hs.software_id IN (?)
+			)`, cve, softwareIDs,
+		)
+		if err != nil {
+			return errors.Wrap(err, "build delete stale vulnerable host cves")
+		}
+		if _, err := tx.ExecContext(ctx, deleteStmt, args...); err != nil {
+			return errors.Wrap(err, "delete stale vulnerable host cves")
+		}
+
+		insertStmt, args, err := sqlx.In(`
+			INSERT IGNORE INTO vulnerable_host_cves (host_id, cve, sent)
+			SELECT DISTINCT hs.host_id, ?, ? FROM host_software hs
+			WHERE hs.software_id IN (?)`, cve, !queue, softwareIDs,
+		)
+		if err != nil {
+			return errors.Wrap(err, "build insert vulnerable host cves")
+		}
+		if _, err := tx.ExecContext(ctx, insertStmt, args...); err != nil {
+			return errors.Wrap(err, "insert vulnerable host cves")
+		}
+		return nil
+	})
+}
+
+func (d *Datastore) ListQueuedVulnerableCVEs(ctx context.Context) ([]string, error) {
+	var cves []string
+	if err := sqlx.SelectContext(ctx, d.reader, &cves,
+		`SELECT DISTINCT cve FROM vulnerable_host_cves WHERE sent = FALSE ORDER BY cve`,
+	); err != nil {
+		return nil, errors.Wrap(err, "list queued vulnerable cves")
+	}
+	return cves, nil
+}
+
+func (d *Datastore) ListQueuedVulnerableHosts(ctx context.Context, cve string, limit int) ([]*fleet.VulnerableHost, error) {
+	query := `SELECT vhc.host_id, h.hostname
+		FROM vulnerable_host_cves vhc JOIN hosts h ON (vhc.host_id=h.id)
+		WHERE vhc.cve = ? AND vhc.sent = FALSE ORDER BY vhc.host_id`
+	args := []interface{}{cve}
+	if limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, limit)
+	}
+	var hosts []*fleet.VulnerableHost
+	// Read from the writer: callers mark the rows they sent between batches
+	// and a lagging replica would hand the same hosts out again.
+	if err := sqlx.SelectContext(ctx, d.writer, &hosts, query, args...); err != nil {
+		return nil, errors.Wrap(err, "list queued vulnerable hosts")
+	}
+	return hosts, nil
+}
+
+func (d *Datastore) MarkVulnerableHostsSent(ctx context.Context, cve string, hostIDs []uint) error {
+	if len(hostIDs) == 0 {
+		return nil
+	}
+	query, args, err := sqlx.In(`UPDATE vulnerable_host_cves SET sent = TRUE WHERE cve = ? AND host_id IN (?)`, cve, hostIDs)
+	if err != nil {
+		return errors.Wrap(err, "build mark vulnerable hosts sent")
+	}
+	if _, err := d.writer.ExecContext(ctx, query, args...); err != nil {
+		return errors.Wrap(err, "mark vulnerable hosts sent")
+	}
+	return nil
+}
+
+func (d *Datastore) ListHostsByCVE(ctx context.Context, filter fleet.TeamFilter, cve string, opt fleet.ListOptions) ([]*fleet.Host, error) {
+	sql := fmt.Sprintf(`
+		SELECT h.*, t.name AS team_name
+		FROM hosts h LEFT JOIN teams t ON (h.team_id = t.id)
+		WHERE h.id IN (
+			SELECT hs.host_id
+			FROM host_software hs
+			JOIN (%s) v ON (hs.software_id=v.software_id)
+			WHERE v.cve = ?
+		) AND %s
+	`, softwareCVEsSQL, d.whereFilterHostsByTeams(filter, "h"))
+	sql = appendListOptionsToSQL(sql, opt)
+
+	hosts := []*fleet.Host{}
+	if err := sqlx.SelectContext(ctx, d.reader, &hosts, sql, cve); err != nil {
+		return nil, errors.Wrap(err, "list hosts by cve")
+	}
+	return hosts, nil
+}
+
+func (d *Datastore) CalculateSoftwareHostCounts(ctx context.Context, updatedAt time.Time) error {
+	return d.withRetryTxx(ctx, func(tx sqlx.ExtContext) error {
+		// The table is rebuilt from scratch so that the software no longer
+		// installed on any host (of the team) is removed.
+		if _, err := tx.ExecContext(ctx, `DELETE FROM software_host_counts`); err != nil {
+			return errors.Wrap(err, "delete software host counts")
+		}
+
+		globalStmt := `
+			INSERT INTO software_host_counts (software_id, team_id, hosts_count, updated_at)
+			SELECT hs.software_id, 0, COUNT(*), ?
+			FROM host_software hs
+			GROUP BY hs.software_id
+		`
+		if _, err := tx.ExecContext(ctx, globalStmt, updatedAt); err != nil {
+			return errors.Wrap(err, "insert global software host counts")
+		}
+
+		teamStmt := `
+			INSERT INTO software_host_counts (software_id, team_id, hosts_count, updated_at)
+			SELECT hs.software_id, h.team_id, COUNT(*), ?
+			FROM host_software hs
+			JOIN hosts h ON (hs.host_id=h.id)
+			WHERE h.team_id IS NOT NULL
+			GROUP BY hs.software_id, h.team_id
+		`
+		if _, err := tx.ExecContext(ctx, teamStmt, updatedAt); err != nil {
+			return errors.Wrap(err, "insert team software host counts")
+		}
+		return nil
+	})
 }
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -103,6 +104,35 @@ func TestSaveHostSoftware(t *testing.T) {
 	test.ElementsMatchSkipID(t, soft1.Software, host1.HostSoftware.Software)
 }
 
+func TestSaveHostSoftwareRelease(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+
+	soft1 := fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{{Name: "openssl", Version: "1.1.1g", Release: "12.el8_3", Source: "rpm_packages"}},
+	}
+	host1.HostSoftware = soft1
+	soft2 := fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{{Name: "openssl", Version: "1.1.1g", Release: "15.el8_3", Source: "rpm_packages"}},
+	}
+	host2.HostSoftware = soft2
+
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host1))
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host2))
+
+	// The same version with different releases are different software.
+	require.NoError(t, ds.LoadHostSoftware(context.Background(), host1))
+	test.ElementsMatchSkipID(t, soft1.Software, host1.HostSoftware.Software)
+	require.NoError(t, ds.LoadHostSoftware(context.Background(), host2))
+	test.ElementsMatchSkipID(t, soft2.Software, host2.HostSoftware.Software)
+	assert.NotEqual(t, host1.Software[0].ID, host2.Software[0].ID)
+}
+
 func TestSoftwareCPE(t *testing.T) {
 	ds := CreateMySQLDS(t)
 	defer ds.Close()
@@ -399,19 +429,22 @@ func TestListSoftware(t *testing.T) {
 	require.NoError(t, ds.InsertCVEForCPE(context.Background(), "cve-321-432-543", []string{"somecpe"}))
 	require.NoError(t, ds.InsertCVEForCPE(context.Background(), "cve-333-444-555", []string{"somecpe"}))
 
+	require.NoError(t, ds.CalculateSoftwareHostCounts(context.Background(), time.Now()))
+
 	foo001 := fleet.Software{
 		Name: "foo", Version: "0.0.1", Source: "chrome_extensions", GenerateCPE: "somecpe",
 		Vulnerabilities: fleet.VulnerabilitiesSlice{
 			{CVE: "cve-321-432-543", DetailsLink: "https://nvd.nist.gov/vuln/detail/cve-321-432-543"},
 			{CVE: "cve-333-444-555", DetailsLink: "https://nvd.nist.gov/vuln/detail/cve-333-444-555"},
 		},
+		HostsCount: 1,
 	}
-	foo002 := fleet.Software{Name: "foo", Version: "0.0.2", Source: "chrome_extensions"}
-	foo003 := fleet.Software{Name: "foo", Version: "0.0.3", Source: "chrome_extensions", GenerateCPE: "someothercpewithoutvulns"}
-	bar003 := fleet.Software{Name: "bar", Version: "0.0.3", Source: "deb_packages"}
+	foo002 := fleet.Software{Name: "foo", Version: "0.0.2", Source: "chrome_extensions", HostsCount: 1}
+	foo003 := fleet.Software{Name: "foo", Version: "0.0.3", Source: "chrome_extensions", GenerateCPE: "someothercpewithoutvulns", HostsCount: 2}
+	bar003 := fleet.Software{Name: "bar", Version: "0.0.3", Source: "deb_packages", HostsCount: 1}
 
 	t.Run("lists everything", func(t *testing.T) {
-		software, err := ds.ListSoftware(context.Background(), nil, fleet.ListOptions{})
+		software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{})
 		require.NoError(t, err)
 
 		require.Len(t, software, 4)
@@ -420,7 +453,7 @@ func TestListSoftware(t *testing.T) {
 	})
 
 	t.Run("limits the results", func(t *testing.T) {
-		software, err := ds.ListSoftware(context.Background(), nil, fleet.ListOptions{PerPage: 1})
+		software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{PerPage: 1, OrderKey: "id"}})
 		require.NoError(t, err)
 
 		require.Len(t, software, 1)
@@ -429,7 +462,7 @@ func TestListSoftware(t *testing.T) {
 	})
 
 	t.Run("paginates", func(t *testing.T) {
-		software, err := ds.ListSoftware(context.Background(), nil, fleet.ListOptions{Page: 1, PerPage: 1})
+		software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{Page: 1, PerPage: 1, OrderKey: "id"}})
 		require.NoError(t, err)
 
 		require.Len(t, software, 1)
@@ -437,12 +470,55 @@ func TestListSoftware(t *testing.T) {
 		test.ElementsMatchSkipID(t, software, expected)
 	})
 
-	t.Run("filters by team", func(t *testing.T) {
-		team1, err := ds.NewTeam(context.Background(), &fleet.Team{Name: "team1"})
+	t.Run("orders by hosts count", func(t *testing.T) {
+		software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{PerPage: 1, OrderKey: "hosts_count", OrderDirection: fleet.OrderDescending}})
+		require.NoError(t, err)
+
+		require.Len(t, software, 1)
+		expected := []fleet.Software{foo003}
+		test.ElementsMatchSkipID(t, software, expected)
+	})
+
+	t.Run("filters vulnerable software", func(t *testing.T) {
+		software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{VulnerableOnly: true})
+		require.NoError(t, err)
+
+		expected := []fleet.Software{foo001}
+		test.ElementsMatchSkipID(t, software, expected)
+	})
+
+	t.Run("filters by source", func(t *testing.T) {
+		software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{Source: "deb_packages"})
 		require.NoError(t, err)
-		require.NoError(t, ds.AddHostsToTeam(context.Background(), &team1.ID, []uint{host1.ID}))
 
-		software, err := ds.ListSoftware(context.Background(), &team1.ID, fleet.ListOptions{})
+		expected := []fleet.Software{bar003}
+		test.ElementsMatchSkipID(t, software, expected)
+	})
+
+	t.Run("searches by name and version", func(t *testing.T) {
+		software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{MatchQuery: "0.0.3"}})
+		require.NoError(t, err)
+
+		expected := []fleet.Software{foo003, bar003}
+		test.ElementsMatchSkipID(t, software, expected)
+
+		software, err = ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{MatchQuery: "ba"}})
+		require.NoError(t, err)
+
+		expected = []fleet.Software{bar003}
+		test.ElementsMatchSkipID(t, software, expected)
+	})
+
+	team1, err := ds.NewTeam(context.Background(), &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	require.NoError(t, ds.AddHostsToTeam(context.Background(), &team1.ID, []uint{host1.ID}))
+	require.NoError(t, ds.CalculateSoftwareHostCounts(context.Background(), time.Now()))
+
+	foo001.HostsCount = 1
+	foo003.HostsCount = 1
+
+	t.Run("filters by team", func(t *testing.T) {
+		software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{TeamID: &team1.ID})
 		require.NoError(t, err)
 
 		require.Len(t, software, 2)
@@ -451,11 +527,7 @@ func TestListSoftware(t *testing.T) {
 	})
 
 	t.Run("filters by team and paginates", func(t *testing.T) {
-		team1, err := ds.NewTeam(context.Background(), &fleet.Team{Name: "team1-" + t.Name()})
-		require.NoError(t, err)
-		require.NoError(t, ds.AddHostsToTeam(context.Background(), &team1.ID, []uint{host1.ID}))
-
-		software, err := ds.ListSoftware(context.Background(), &team1.ID, fleet.ListOptions{PerPage: 1, Page: 1, OrderKey: "id"})
+		software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{PerPage: 1, Page: 1, OrderKey: "id"}, TeamID: &team1.ID})
 		require.NoError(t, err)
 
 		require.Len(t, software, 1)
@@ -463,3 +535,295 @@ func TestListSoftware(t *testing.T) {
 		test.ElementsMatchSkipID(t, software, expected)
 	})
 }
+
+func TestCalculateSoftwareHostCounts(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+	host1.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"}},
+	}
+	host2.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{
+			{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"},
+			{Name: "bar", Version: "0.0.2", Source: "deb_packages"},
+		},
+	}
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host1))
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host2))
+
+	// The software is listed with no hosts until the counts are calculated.
+	software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{})
+	require.NoError(t, err)
+	require.Len(t, software, 2)
+	assert.Zero(t, software[0].HostsCount)
+	assert.Zero(t, software[1].HostsCount)
+
+	require.NoError(t, ds.CalculateSoftwareHostCounts(context.Background(), time.Now()))
+	software, err = ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{ListOptions: fleet.ListOptions{OrderKey: "name"}})
+	require.NoError(t, err)
+	require.Len(t, software, 2)
+	assert.Equal(t, "bar", software[0].Name)
+	assert.Equal(t, 1, software[0].HostsCount)
+	assert.Equal(t, "foo", software[1].Name)
+	assert.Equal(t, 2, software[1].HostsCount)
+
+	// Software no longer installed is removed on the next calculation.
+	host2.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"}},
+	}
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host2))
+	require.NoError(t, ds.CalculateSoftwareHostCounts(context.Background(), time.Now()))
+	software, err = ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{})
+	require.NoError(t, err)
+	require.Len(t, software, 1)
+	assert.Equal(t, "foo", software[0].Name)
+	assert.Equal(t, 2, software[0].HostsCount)
+
+	var count int
+	require.NoError(t, ds.writer.Get(&count, `SELECT COUNT(*) FROM software_host_counts`))
+	assert.Equal(t, 1, count)
+}
+
+func TestSoftwareCVEMeta(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	host := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{
+			{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"},
+			{Name: "bar", Version: "0.0.2", Source: "apps"},
+			{Name: "baz", Version: "0.0.3", Source: "apps"},
+		},
+	}
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host))
+	require.NoError(t, ds.LoadHostSoftware(context.Background(), host))
+
+	sort.Slice(host.Software, func(i, j int) bool { return host.Software[i].Name < host.Software[j].Name })
+	bar, baz, foo := host.Software[0], host.Software[1], host.Software[2]
+	require.NoError(t, ds.AddCPEForSoftware(context.Background(), foo, "foocpe"))
+	require.NoError(t, ds.InsertCVEForCPE(context.Background(), "CVE-2021-0001", []string{"foocpe"}))
+	require.NoError(t, ds.InsertCVEForCPE(context.Background(), "CVE-2021-0002", []string{"foocpe"}))
+	require.NoError(t, ds.InsertCVEForSoftware(context.Background(), "CVE-2021-0003", []uint{bar.ID}))
+
+	cves, err := ds.ListSoftwareCVEs(context.Background())
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"CVE-2021-0001", "CVE-2021-0002", "CVE-2021-0003"}, cves)
+
+	published := time.Date(2021, 9, 1, 10, 0, 0, 0, time.UTC)
+	require.NoError(t, ds.InsertCVEMeta(context.Background(), []fleet.CVEMeta{
+		{CVE: "CVE-2021-0001", CVSSScore: ptr.Float64(5.3), Severity: ptr.String("MEDIUM")},
+		{CVE: "CVE-2021-0003", CVSSScore: ptr.Float64(7.5), Severity: ptr.String("HIGH")},
+	}))
+	// Inserting again updates the stored meta.
+	require.NoError(t, ds.InsertCVEMeta(context.Background(), []fleet.CVEMeta{
+		{
+			CVE:        "CVE-2021-0001",
+			CVSSScore:  ptr.Float64(9.8),
+			CVSSVector: ptr.String("CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H"),
+			Severity:   ptr.String("CRITICAL"),
+			Published:  &published,
+		},
+	}))
+
+	require.NoError(t, ds.CalculateSoftwareHostCounts(context.Background(), time.Now()))
+	software, err := ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{
+		ListOptions: fleet.ListOptions{OrderKey: "severity", OrderDirection: fleet.OrderDescending},
+	})
+	require.NoError(t, err)
+	require.Len(t, software, 3)
+	assert.Equal(t, foo.ID, software[0].ID)
+	assert.Equal(t, bar.ID, software[1].ID)
+	assert.Equal(t, baz.ID, software[2].ID)
+	require.NotNil(t, software[0].MaxCVSSScore)
+	assert.Equal(t, 9.8, *software[0].MaxCVSSScore)
+	assert.Nil(t, software[2].MaxCVSSScore)
+
+	vulns := software[0].Vulnerabilities
+	require.Len(t, vulns, 2)
+	sort.Slice(vulns, func(i, j int) bool { return vulns[i].CVE < vulns[j].CVE })
+	require.NotNil(t, vulns[0].CVSSScore)
+	assert.Equal(t, 9.8, *vulns[0].CVSSScore)
+	assert.Equal(t, "CRITICAL", *vulns[0].Severity)
+	assert.Equal(t, "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:H/I:H/A:H", *vulns[0].CVSSVector)
+	require.NotNil(t, vulns[0].Published)
+	assert.True(t, published.Equal(*vulns[0].Published))
+	// There is no meta for the second CVE.
+	assert.Nil(t, vulns[1].CVSSScore)
+	assert.Nil(t, vulns[1].Severity)
+
+	software, err = ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{MinSeverity: "high"})
+	require.NoError(t, err)
+	require.Len(t, software, 2)
+
+	software, err = ds.ListSoftware(context.Background(), fleet.SoftwareListOptions{MinSeverity: "critical"})
+	require.NoError(t, err)
+	require.Len(t, software, 1)
+	assert.Equal(t, foo.ID, software[0].ID)
+
+	require.NoError(t, ds.LoadHostSoftware(context.Background(), host))
+	for _, s := range host.Software {
+		if s.ID == bar.ID {
+			require.Len(t, s.Vulnerabilities, 1)
+			assert.Equal(t, "HIGH", *s.Vulnerabilities[0].Severity)
+		}
+	}
+}
+
+func TestVulnerableHostCVEs(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	host1 := test.NewHost(t, ds, "host1", "", "host1key", "host1uuid", time.Now())
+	host2 := test.NewHost(t, ds, "host2", "", "host2key", "host2uuid", time.Now())
+	host1.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"}},
+	}
+	host2.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{
+			{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"},
+			{Name: "bar", Version: "0.0.2", Source: "deb_packages"},
+		},
+	}
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host1))
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host2))
+	require.NoError(t, ds.LoadHostSoftware(context.Background(), host2))
+	sort.Slice(host2.Software, func(i, j int) bool { return host2.Software[i].Name < host2.Software[j].Name })
+	bar, foo := host2.Software[0], host2.Software[1]
+
+	require.NoError(t, ds.AddCPEForSoftware(context.Background(), foo, "foocpe"))
+	require.NoError(t, ds.InsertCVEForCPE(context.Background(), "CVE-2021-0001", []string{"foocpe"}))
+
+	// Associations found while the webhook is disabled are not queued.
+	require.NoError(t, ds.SyncVulnerableHostCVEs(context.Background(), false))
+	cves, err := ds.ListQueuedVulnerableCVEs(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, cves)
+
+	require.NoError(t, ds.InsertCVEForSoftware(context.Background(), "CVE-2021-0002", []uint{bar.ID}))
+	require.NoError(t, ds.SyncVulnerableHostCVEs(context.Background(), true))
+	cves, err = ds.ListQueuedVulnerableCVEs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CVE-2021-0002"}, cves)
+
+	queued, err := ds.ListQueuedVulnerableHosts(context.Background(), "CVE-2021-0002", 0)
+	require.NoError(t, err)
+	require.Len(t, queued, 1)
+	assert.Equal(t, host2.ID, queued[0].HostID)
+	assert.Equal(t, "host2", queued[0].Hostname)
+
+	require.NoError(t, ds.MarkVulnerableHostsSent(context.Background(), "CVE-2021-0002", []uint{host2.ID}))
+	cves, err = ds.ListQueuedVulnerableCVEs(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, cves)
+
+	// Syncing again doesn't queue the hosts already sent.
+	require.NoError(t, ds.SyncVulnerableHostCVEs(context.Background(), true))
+	cves, err = ds.ListQueuedVulnerableCVEs(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, cves)
+
+	user := &fleet.User{GlobalRole: ptr.String(fleet.RoleAdmin)}
+	filter := fleet.TeamFilter{User: user}
+	hosts, err := ds.ListHostsByCVE(context.Background(), filter, "CVE-2021-0001", fleet.ListOptions{OrderKey: "id"})
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+	assert.Equal(t, host1.ID, hosts[0].ID)
+	assert.Equal(t, host2.ID, hosts[1].ID)
+
+	hosts, err = ds.ListHostsByCVE(context.Background(), filter, "CVE-2021-0002", fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, host2.ID, hosts[0].ID)
+
+	// Hosts of other teams are filtered out.
+	team, err := ds.NewTeam(context.Background(), &fleet.Team{Name: "team1"})
+	require.NoError(t, err)
+	require.NoError(t, ds.AddHostsToTeam(context.Background(), &team.ID, []uint{host1.ID}))
+	teamUser := &fleet.User{Teams: []fleet.UserTeam{{Team: *team, Role: fleet.RoleObserver}}}
+	hosts, err = ds.ListHostsByCVE(context.Background(), fleet.TeamFilter{User: teamUser, IncludeObserver: true}, "CVE-2021-0001", fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, host1.ID, hosts[0].ID)
+
+	// Associations that no longer exist are forgotten, and are new again if
+	// they come back.
+	host2.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"}},
+	}
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host2))
+	require.NoError(t, ds.SyncVulnerableHostCVEs(context.Background(), true))
+	host2.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{
+			{Name: "foo", Version: "0.0.1", Source: "chrome_extensions"},
+			{Name: "bar", Version: "0.0.2", Source: "deb_packages"},
+		},
+	}
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host2))
+	require.NoError(t, ds.SyncVulnerableHostCVEs(context.Background(), true))
+	cves, err = ds.ListQueuedVulnerableCVEs(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"CVE-2021-0002"}, cves)
+}
+
+func TestDistroSoftwareCVEs(t *testing.T) {
+	ds := CreateMySQLDS(t)
+	defer ds.Close()
+
+	host, err := ds.NewHost(context.Background(), &fleet.Host{
+		OsqueryHostID:   "1",
+		DetailUpdatedAt: time.Now(),
+		LabelUpdatedAt:  time.Now(),
+		SeenTime:        time.Now(),
+		NodeKey:         "1",
+		UUID:            "1",
+		Hostname:        "foo.local",
+		Platform:        "ubuntu",
+		PlatformLike:    "debian",
+		OSVersion:       "Ubuntu 20.4.0",
+	})
+	require.NoError(t, err)
+
+	host.HostSoftware = fleet.HostSoftware{
+		Modified: true,
+		Software: []fleet.Software{
+			{Name: "openssl", Version: "1.1.1f-1ubuntu2.4", Source: "deb_packages"},
+			{Name: "bar", Version: "0.0.3", Source: "apps"},
+		},
+	}
+	require.NoError(t, ds.SaveHostSoftware(context.Background(), host))
+
+	distroSoftware, err := ds.ListDistroSoftware(context.Background())
+	require.NoError(t, err)
+	require.Len(t, distroSoftware, 1)
+	assert.Equal(t, "openssl", distroSoftware[0].Name)
+	assert.Equal(t, "1.1.1f-1ubuntu2.4", distroSoftware[0].Version)
+	assert.Equal(t, "ubuntu", distroSoftware[0].Platform)
+	assert.Equal(t, "debian", distroSoftware[0].PlatformLike)
+	assert.Equal(t, "Ubuntu 20.4.0", distroSoftware[0].OSVersion)
+
+	require.NoError(t, ds.InsertCVEForSoftware(context.Background(), "CVE-2021-3449", []uint{distroSoftware[0].ID}))
+	// Inserting the same CVE twice is a no-op.
+	require.NoError(t, ds.InsertCVEForSoftware(context.Background(), "CVE-2021-3449", []uint{distroSoftware[0].ID}))
+
+	require.NoError(t, ds.LoadHostSoftware(context.Background(), host))
+	for _, s := range host.Software {
+		if s.Name != "openssl" {
+			assert.Empty(t, s.Vulnerabilities)
+			continue
+		}
+		require.Len(t, s.Vulnerabilities, 1)
+		assert.Equal(t, "CVE-2021-3449", s.Vulnerabilities[0].CVE)
+	}
+}
@@ -31,8 +31,9 @@ func (d *Datastore) NewUser(ctx context.Context, user *fleet.User) (*fleet.User,
       	position,
         sso_enabled,
 		api_only,
-		global_role
-      ) VALUES (?,?,?,?,?,?,?,?,?,?)
+		global_role,
+		scim_managed
+      ) VALUES (?,?,?,?,?,?,?,?,?,?,?)
       `
 		result, err := tx.ExecContext(ctx, sqlStatement,
 			user.Password,
@@ -44,7 +45,8 @@ func (d *Datastore) NewUser(ctx context.Context, user *fleet.User) (*fleet.User,
 			user.Position,
 			user.SSOEnabled,
 			user.APIOnly,
-			user.GlobalRole)
+			user.GlobalRole,
+			user.SCIMManaged)
 		if err != nil {
 			return errors.Wrap(err, "create new user")
 		}
@@ -29,6 +29,9 @@ const (
 	ActivityTypeDeletedTeam = "deleted_team"
 	// ActivityTypeLiveQuery is the activity type for live queries
 	ActivityTypeLiveQuery = "live_query"
+	// ActivityTypeQuerySweepChanges is the activity type for the rows added
+	// and removed on hosts between two runs of a query sweep
+	ActivityTypeQuerySweepChanges = "query_sweep_changes"
 )
 
 type Activity struct {
@@ -0,0 +1,91 @@
+package fleet
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// APITokenPrefix is the prefix of the keys of the API tokens, that
+// distinguishes them from the keys of the sessions.
+const APITokenPrefix = "fleet_api_"
+
+// APIToken is a named token a user creates to authenticate to the API from
+// automation without a password. Only the hash of its key is stored.
+type APIToken struct {
+	CreateTimestamp
+	ID     uint   `json:"id"`
+	UserID uint   `json:"user_id" db:"user_id"`
+	Name   string `json:"name"`
+	// Key is only set in the response to the creation of the token.
+	Key     string `json:"key,omitempty" db:"-"`
+	KeyHash string `json:"-" db:"key_hash"`
+	// ReadOnly restricts the token to the read and list actions.
+	ReadOnly bool `json:"read_only" db:"read_only"`
+	// TeamIDs, if not empty, restricts the token to the roles of the user in
+	// these teams.
+	TeamIDs    []uint     `json:"team_ids" db:"-"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+}
+
+func (t APIToken) AuthzType() string {
+	return "api_token"
+}
+
+// Expired returns whether the token is expired at now.
+func (t *APIToken) Expired(now time.Time) bool {
+	return t.ExpiresAt != nil && !now.Before(*t.ExpiresAt)
+}
+
+// ScopedUser returns a copy of the user with the roles restricted to the
+// teams of the token. Global roles are turned into the equivalent role in
+// each of the teams of the token. The copy is only meant for authorization,
+// and must not be saved.
+func (t *APIToken) ScopedUser(user *User) *User {
+	if len(t.TeamIDs) == 0 {
+		return user
+	}
+	scoped := *user
+	scoped.GlobalRole = nil
+	scoped.Teams = []UserTeam{}
+	for _, teamID := range t.TeamIDs {
+		if user.GlobalRole != nil {
+			role := *user.GlobalRole
+			if role == RoleAdmin {
+				// Admin is not a team role.
+				role = RoleMaintainer
+			}
+			scoped.Teams = append(scoped.Teams, UserTeam{Team: Team{ID: teamID}, Role: role})
+			continue
+		}
+		for _, team := range user.Teams {
+			if team.ID == teamID {
+				scoped.Teams = append(scoped.Teams, team)
+			}
+		}
+	}
+	return &scoped
+}
+
+// APITokenPayload is used to create an API token.
+type APITokenPayload struct {
+	Name      string     `json:"name"`
+	ReadOnly  bool       `json:"read_only"`
+	TeamIDs   []uint     `json:"team_ids"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// IsAPITokenKey returns whether the key is the key of an API token, rather
+// than of a session.
+func IsAPITokenKey(key string) bool {
+	return strings.HasPrefix(key, APITokenPrefix)
+}
+
+// HashAPITokenKey returns the hash of the key of an API token, as stored in
+// the datastore. The keys are random, so that a fast hash is enough.
+func HashAPITokenKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,41 @@
+package fleet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPITokenScopedUser(t *testing.T) {
+	teamUser := &User{ID: 1, Teams: []UserTeam{
+		{Team: Team{ID: 1}, Role: RoleObserver},
+		{Team: Team{ID: 2}, Role: RoleMaintainer},
+	}}
+	globalAdmin := &User{ID: 2, GlobalRole: ptr.String(RoleAdmin), Teams: []UserTeam{}}
+
+	// Tokens without teams are not restricted.
+	token := &APIToken{}
+	assert.Equal(t, teamUser, token.ScopedUser(teamUser))
+
+	token = &APIToken{TeamIDs: []uint{2, 3}}
+	scoped := token.ScopedUser(teamUser)
+	assert.Equal(t, []UserTeam{{Team: Team{ID: 2}, Role: RoleMaintainer}}, scoped.Teams)
+	assert.Len(t, teamUser.Teams, 2)
+
+	scoped = token.ScopedUser(globalAdmin)
+	assert.Nil(t, scoped.GlobalRole)
+	assert.Equal(t, []UserTeam{
+		{Team: Team{ID: 2}, Role: RoleMaintainer},
+		{Team: Team{ID: 3}, Role: RoleMaintainer},
+	}, scoped.Teams)
+	assert.Equal(t, RoleAdmin, *globalAdmin.GlobalRole)
+}
+
+func TestAPITokenExpired(t *testing.T) {
+	now := time.Now()
+	assert.False(t, (&APIToken{}).Expired(now))
+	assert.False(t, (&APIToken{ExpiresAt: ptr.Time(now.Add(time.Second))}).Expired(now))
+	assert.True(t, (&APIToken{ExpiresAt: ptr.Time(now)}).Expired(now))
+}
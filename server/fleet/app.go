@@ -36,6 +36,14 @@ type ModifyAppConfigRequest struct {
 	AppConfig AppConfig `json:"app_config"`
 }
 
+const (
+	// SSOProviderSAML is the SSO provider type of SAML identity providers,
+	// the default.
+	SSOProviderSAML = "saml"
+	// SSOProviderOIDC is the SSO provider type of OpenID Connect providers.
+	SSOProviderOIDC = "oidc"
+)
+
 // SSOSettings wire format for SSO settings
 type SSOSettings struct {
 	// EntityID is a uri that identifies this service provider
@@ -56,6 +64,35 @@ type SSOSettings struct {
 	// EnableSSOIdPLogin flag to determine whether or not to allow IdP-initiated
 	// login.
 	EnableSSOIdPLogin bool `json:"enable_sso_idp_login"`
+	// ProviderType is the protocol of the IDP, either SSOProviderSAML or
+	// SSOProviderOIDC. It is SSOProviderSAML if empty.
+	ProviderType string `json:"provider_type"`
+	// OIDCIssuerURL identifies the OpenID Connect provider, and its
+	// configuration is discovered from it.
+	OIDCIssuerURL string `json:"oidc_issuer_url"`
+	// OIDCClientID is the client ID of Fleet in the OpenID Connect provider.
+	OIDCClientID string `json:"oidc_client_id"`
+	// OIDCClientSecret is the client secret of Fleet in the OpenID Connect
+	// provider.
+	OIDCClientSecret string `json:"oidc_client_secret"`
+	// OIDCScopes are requested in addition to the openid, email and profile
+	// scopes, e.g. to include the groups of the user in the ID token.
+	OIDCScopes []string `json:"oidc_scopes"`
+	// OIDCEmailClaim is the ID token claim holding the email of the user,
+	// "email" if empty.
+	OIDCEmailClaim string `json:"oidc_email_claim"`
+	// EnableJITProvisioning flag to determine whether or not to create the
+	// users that log in with SSO for the first time.
+	EnableJITProvisioning bool `json:"enable_jit_provisioning"`
+	// JITRoleAttribute is the name of the SAML attribute or of the OIDC claim
+	// that lists the groups of the user in the IDP.
+	JITRoleAttribute string `json:"jit_role_attribute"`
+	// JITGroupRoles maps the groups of the JITRoleAttribute to Fleet roles,
+	// in the "group=role;group=team_id:role" format.
+	JITGroupRoles string `json:"jit_group_roles"`
+	// JITTeamIDs limits the roles given by JITGroupRoles to these teams if
+	// it is not empty.
+	JITTeamIDs []uint `json:"jit_team_ids"`
 }
 
 // SMTPSettings is part of the AppConfig which defines the wire representation
@@ -157,8 +194,10 @@ func (d *Duration) UnmarshalJSON(b []byte) error {
 }
 
 type WebhookSettings struct {
-	HostStatusWebhook HostStatusWebhookSettings `json:"host_status_webhook"`
-	Interval          Duration                  `json:"interval"`
+	HostStatusWebhook      HostStatusWebhookSettings      `json:"host_status_webhook"`
+	FailingPoliciesWebhook FailingPoliciesWebhookSettings `json:"failing_policies_webhook"`
+	VulnerabilitiesWebhook VulnerabilitiesWebhookSettings `json:"vulnerabilities_webhook"`
+	Interval               Duration                       `json:"interval"`
 }
 
 type HostStatusWebhookSettings struct {
@@ -168,6 +207,45 @@ type HostStatusWebhookSettings struct {
 	DaysCount      int     `json:"days_count"`
 }
 
+// FailingPoliciesWebhookSettings holds the settings for the webhook that is
+// triggered when hosts start failing any of the selected policies.
+type FailingPoliciesWebhookSettings struct {
+	Enable         bool   `json:"enable_failing_policies_webhook"`
+	DestinationURL string `json:"destination_url"`
+	// PolicyIDs are the IDs of the global or team policies that trigger the
+	// webhook.
+	PolicyIDs []uint `json:"policy_ids"`
+	// HostBatchSize is the maximum number of hosts sent in a single request
+	// to the destination URL. Zero means all failing hosts of a policy are
+	// sent in one request.
+	HostBatchSize int `json:"host_batch_size"`
+}
+
+// IsPolicyEnabled returns whether the webhook is enabled and the given policy
+// is one of the policies that trigger it.
+func (s FailingPoliciesWebhookSettings) IsPolicyEnabled(policyID uint) bool {
+	if !s.Enable {
+		return false
+	}
+	for _, id := range s.PolicyIDs {
+		if id == policyID {
+			return true
+		}
+	}
+	return false
+}
+
+// VulnerabilitiesWebhookSettings holds the settings for the webhook that is
+// triggered when CVEs are found to affect hosts.
+type VulnerabilitiesWebhookSettings struct {
+	Enable         bool   `json:"enable_vulnerabilities_webhook"`
+	DestinationURL string `json:"destination_url"`
+	// HostBatchSize is the maximum number of hosts sent in a single request
+	// to the destination URL. Zero means all the newly affected hosts of a
+	// CVE are sent in one request.
+	HostBatchSize int `json:"host_batch_size"`
+}
+
 func (c *AppConfig) ApplyDefaultsForNewInstalls() {
 	c.ServerSettings.EnableAnalytics = true
 	c.SMTPSettings.SMTPPort = 587
@@ -312,6 +390,19 @@ type Logging struct {
 	Json   bool          `json:"json"`
 	Result LoggingPlugin `json:"result"`
 	Status LoggingPlugin `json:"status"`
+	// ResultPlugins and StatusPlugins are all the plugins the logs are sent
+	// to, when they are sent to several plugins. Result and Status are the
+	// first of them.
+	ResultPlugins []LoggingPlugin `json:"result_plugins,omitempty"`
+	StatusPlugins []LoggingPlugin `json:"status_plugins,omitempty"`
+	// ResultRoutes are the routes of the result logs to other plugins, by
+	// query name.
+	ResultRoutes []LoggingRoute `json:"result_routes,omitempty"`
+}
+
+type LoggingRoute struct {
+	Pattern string   `json:"pattern"`
+	Plugins []string `json:"plugins"`
 }
 
 type UpdateIntervalConfig struct {
@@ -351,3 +442,33 @@ type LambdaConfig struct {
 	StatusFunction string `json:"status_function"`
 	ResultFunction string `json:"result_function"`
 }
+
+// KafkaConfig shadows config.KafkaConfig only exposing a subset of fields
+type KafkaConfig struct {
+	Brokers     string `json:"brokers"`
+	StatusTopic string `json:"status_topic"`
+	ResultTopic string `json:"result_topic"`
+}
+
+// HTTPLogConfig shadows config.HTTPLogConfig only exposing a subset of fields
+type HTTPLogConfig struct {
+	StatusURL string `json:"status_url"`
+	ResultURL string `json:"result_url"`
+}
+
+// SplunkConfig shadows config.SplunkConfig only exposing a subset of fields
+type SplunkConfig struct {
+	URL              string `json:"url"`
+	StatusIndex      string `json:"status_index"`
+	ResultIndex      string `json:"result_index"`
+	StatusSourcetype string `json:"status_sourcetype"`
+	ResultSourcetype string `json:"result_sourcetype"`
+}
+
+// ElasticsearchConfig shadows config.ElasticsearchConfig only exposing a
+// subset of fields
+type ElasticsearchConfig struct {
+	URL         string `json:"url"`
+	StatusIndex string `json:"status_index"`
+	ResultIndex string `json:"result_index"`
+}
@@ -1,5 +1,11 @@
 package fleet
 
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
 // DistributedQueryStatus is the lifecycle status of a distributed query
 // campaign.
 type DistributedQueryStatus int
@@ -42,6 +48,50 @@ const (
 	ExecutionFailed
 )
 
+func (s DistributedQueryExecutionStatus) MarshalJSON() ([]byte, error) {
+	switch s {
+	case ExecutionWaiting:
+		return []byte(`"waiting"`), nil
+	case ExecutionRequested:
+		return []byte(`"requested"`), nil
+	case ExecutionSucceeded:
+		return []byte(`"succeeded"`), nil
+	case ExecutionFailed:
+		return []byte(`"failed"`), nil
+	default:
+		return nil, errors.Errorf("invalid DistributedQueryExecutionStatus: %d", s)
+	}
+}
+
+func (s *DistributedQueryExecutionStatus) UnmarshalJSON(b []byte) error {
+	switch string(b) {
+	case `"waiting"`:
+		*s = ExecutionWaiting
+	case `"requested"`:
+		*s = ExecutionRequested
+	case `"succeeded"`:
+		*s = ExecutionSucceeded
+	case `"failed"`:
+		*s = ExecutionFailed
+	default:
+		return errors.Errorf("invalid DistributedQueryExecutionStatus: %s", string(b))
+	}
+	return nil
+}
+
+// DistributedQueryCampaignHost is the execution status of a distributed query
+// campaign on one of the hosts it targets. Hosts that did not respond before
+// the campaign ended stay in the waiting status if they never retrieved the
+// query, and in the requested status otherwise.
+type DistributedQueryCampaignHost struct {
+	HostID    uint                            `json:"host_id" db:"host_id"`
+	Hostname  string                          `json:"hostname" db:"hostname"`
+	Status    DistributedQueryExecutionStatus `json:"status" db:"status"`
+	RowsCount uint                            `json:"rows_count" db:"rows_count"`
+	Error     *string                         `json:"error" db:"error"`
+	UpdatedAt time.Time                       `json:"updated_at" db:"updated_at"`
+}
+
 // DistributedQueryResult is the result returned from the execution of a
 // distributed query on a single host.
 type DistributedQueryResult struct {
@@ -54,3 +104,25 @@ type DistributedQueryResult struct {
 	// implementing that interface may not (un)marshal properly
 	Error *string `json:"error"`
 }
+
+// DistributedQueryCampaignResult is the result of a distributed query
+// campaign on a single host, as stored for retrieval after the campaign
+// finished.
+type DistributedQueryCampaignResult struct {
+	CampaignID uint                `json:"campaign_id" db:"distributed_query_campaign_id"`
+	HostID     uint                `json:"host_id" db:"host_id"`
+	Hostname   string              `json:"hostname" db:"hostname"`
+	Rows       []map[string]string `json:"rows" db:"-"`
+	Error      *string             `json:"error" db:"error"`
+	CreatedAt  time.Time           `json:"created_at" db:"created_at"`
+}
+
+// DistributedQueryCampaignStatus is the progress of a distributed query
+// campaign. The campaign is finished once as many results were received as
+// there are online hosts targeted by the campaign.
+type DistributedQueryCampaignStatus struct {
+	Campaign        *DistributedQueryCampaign `json:"campaign"`
+	ExpectedResults uint                      `json:"expected_results"`
+	ActualResults   uint                      `json:"actual_results"`
+	Status          string                    `json:"status"`
+}
@@ -86,6 +86,38 @@ type Datastore interface {
 	// easier to test. The return values indicate how many campaigns were expired and any error.
 	CleanupDistributedQueryCampaigns(ctx context.Context, now time.Time) (expired uint, err error)
 
+	// CompleteExpiredDistributedQueryCampaigns moves the distributed query campaigns created before the provided
+	// time to QueryComplete, and returns the IDs of the campaigns that were not complete yet.
+	CompleteExpiredDistributedQueryCampaigns(ctx context.Context, createdBefore time.Time) ([]uint, error)
+
+	// SaveDistributedQueryCampaignResult stores the result of a distributed query campaign on a host.
+	SaveDistributedQueryCampaignResult(ctx context.Context, result *DistributedQueryCampaignResult) error
+	// ListDistributedQueryCampaignResults lists the stored results of the distributed query campaign of the provided
+	// ID, ordered by hostname.
+	ListDistributedQueryCampaignResults(ctx context.Context, campaignID uint, opt ListOptions) ([]*DistributedQueryCampaignResult, error)
+	// CountDistributedQueryCampaignResults returns the number of hosts that returned results for the distributed
+	// query campaign of the provided ID.
+	CountDistributedQueryCampaignResults(ctx context.Context, campaignID uint) (uint, error)
+	// CleanupDistributedQueryCampaignResults deletes the distributed query campaign results stored before the
+	// provided time.
+	CleanupDistributedQueryCampaignResults(ctx context.Context, olderThan time.Time) error
+
+	// NewDistributedQueryCampaignHosts records the hosts targeted by the distributed query campaign of the provided
+	// ID, in the waiting status.
+	NewDistributedQueryCampaignHosts(ctx context.Context, campaignID uint, hostIDs []uint) error
+	// UpdateDistributedQueryCampaignHostStatus updates the execution status of the distributed query campaign of the
+	// provided ID on a host. The status never goes back, so a host that already returned results stays in the
+	// succeeded or failed status.
+	UpdateDistributedQueryCampaignHostStatus(
+		ctx context.Context, campaignID, hostID uint, status DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error
+	// ListDistributedQueryCampaignHosts lists the execution status of the distributed query campaign of the provided
+	// ID on each of the hosts it targets, ordered by hostname.
+	ListDistributedQueryCampaignHosts(ctx context.Context, campaignID uint, opt ListOptions) ([]*DistributedQueryCampaignHost, error)
+	// CleanupDistributedQueryCampaignHosts deletes the execution status of the distributed query campaigns created
+	// before the provided time.
+	CleanupDistributedQueryCampaignHosts(ctx context.Context, olderThan time.Time) error
+
 	///////////////////////////////////////////////////////////////////////////////
 	// PackStore is the datastore interface for managing query packs.
 
@@ -199,6 +231,9 @@ type Datastore interface {
 	AddHostsToTeam(ctx context.Context, teamID *uint, hostIDs []uint) error
 
 	TotalAndUnseenHostsSince(ctx context.Context, daysCount int) (int, int, error)
+	// LoadHostVitals loads the disk encryption, MDM, munki and battery
+	// information collected for the host.
+	LoadHostVitals(ctx context.Context, host *Host) error
 
 	///////////////////////////////////////////////////////////////////////////////
 	// TargetStore
@@ -242,6 +277,45 @@ type Datastore interface {
 	// MarkSessionAccessed marks the currently tracked session as access to extend expiration
 	MarkSessionAccessed(ctx context.Context, session *Session) error
 
+	///////////////////////////////////////////////////////////////////////////////
+	// APITokenStore
+
+	// NewAPIToken stores a new API token. The name of the token must be
+	// unique for its user.
+	NewAPIToken(ctx context.Context, token *APIToken) (*APIToken, error)
+	// APIToken returns the API token with the given id.
+	APIToken(ctx context.Context, id uint) (*APIToken, error)
+	// APITokenByKeyHash returns the API token with the given hash of its key.
+	APITokenByKeyHash(ctx context.Context, keyHash string) (*APIToken, error)
+	// ListAPITokensForUser returns the API tokens of the user.
+	ListAPITokensForUser(ctx context.Context, userID uint) ([]*APIToken, error)
+	// DeleteAPIToken deletes the API token with the given id.
+	DeleteAPIToken(ctx context.Context, id uint) error
+	// MarkAPITokenUsed sets the last time the API token was used.
+	MarkAPITokenUsed(ctx context.Context, id uint, usedAt time.Time) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// SCIMGroupStore
+
+	// NewSCIMGroup stores a new SCIM group with its members. The display name
+	// of the group must be unique.
+	NewSCIMGroup(ctx context.Context, group *SCIMGroup) (*SCIMGroup, error)
+	// SCIMGroup returns the SCIM group with the given id.
+	SCIMGroup(ctx context.Context, id uint) (*SCIMGroup, error)
+	// ListSCIMGroups returns all the SCIM groups, ordered by id.
+	ListSCIMGroups(ctx context.Context) ([]*SCIMGroup, error)
+	// SaveSCIMGroup saves the display name and replaces the members of the
+	// SCIM group.
+	SaveSCIMGroup(ctx context.Context, group *SCIMGroup) error
+	// DeleteSCIMGroup deletes the SCIM group with the given id.
+	DeleteSCIMGroup(ctx context.Context, id uint) error
+	// SCIMUserByID returns the user with the given id, if it was provisioned
+	// through the SCIM API.
+	SCIMUserByID(ctx context.Context, id uint) (*User, error)
+	// ListSCIMUsers returns the page of the users provisioned through the SCIM
+	// API, ordered by id, and the total number of users matching the options.
+	ListSCIMUsers(ctx context.Context, opt SCIMUserListOptions) ([]*User, int, error)
+
 	///////////////////////////////////////////////////////////////////////////////
 	// AppConfigStore contains method for saving and retrieving application configuration
 
@@ -288,6 +362,15 @@ type Datastore interface {
 	ScheduledQuery(ctx context.Context, id uint) (*ScheduledQuery, error)
 	CleanupOrphanScheduledQueryStats(ctx context.Context) error
 
+	// SaveScheduledQueryResults stores the rows of the result logs of the
+	// scheduled queries sent by the host, keeping at most maxRows rows per
+	// scheduled query and host. The logs of unknown queries are ignored.
+	SaveScheduledQueryResults(ctx context.Context, hostID uint, logs []*ScheduledQueryResultLog, maxRows int) error
+	ListScheduledQueryResults(ctx context.Context, filter TeamFilter, opt ScheduledQueryResultsListOptions) ([]*ScheduledQueryResult, error)
+	// CleanupOrphanScheduledQueryResults deletes the results of the deleted
+	// scheduled queries and hosts.
+	CleanupOrphanScheduledQueryResults(ctx context.Context) error
+
 	///////////////////////////////////////////////////////////////////////////////
 	// TeamStore
 
@@ -317,6 +400,35 @@ type Datastore interface {
 	AddCPEForSoftware(ctx context.Context, software Software, cpe string) error
 	AllCPEs(ctx context.Context) ([]string, error)
 	InsertCVEForCPE(ctx context.Context, cve string, cpes []string) error
+	// ListDistroSoftware returns the deb and rpm packages installed on hosts,
+	// once for every distinct operating system of the hosts they are
+	// installed on.
+	ListDistroSoftware(ctx context.Context) ([]DistroSoftware, error)
+	// InsertCVEForSoftware records that the given software is affected by the
+	// CVE, independently of its CPE.
+	InsertCVEForSoftware(ctx context.Context, cve string, softwareIDs []uint) error
+	// ListSoftwareCVEs returns the distinct CVEs that affect any software.
+	ListSoftwareCVEs(ctx context.Context) ([]string, error)
+	// InsertCVEMeta stores the scoring information of the given CVEs,
+	// replacing what was previously stored.
+	InsertCVEMeta(ctx context.Context, meta []CVEMeta) error
+	// SyncVulnerableHostCVEs records the CVEs that affect each host, and
+	// forgets the ones that no longer do. When queue is true, the newly found
+	// associations are queued to be sent to the vulnerabilities webhook,
+	// otherwise they are recorded as already sent.
+	SyncVulnerableHostCVEs(ctx context.Context, queue bool) error
+	// ListQueuedVulnerableCVEs returns the CVEs with hosts queued for the
+	// vulnerabilities webhook.
+	ListQueuedVulnerableCVEs(ctx context.Context) ([]string, error)
+	// ListQueuedVulnerableHosts returns up to limit hosts queued for the
+	// vulnerabilities webhook for the given CVE. A limit of zero returns all
+	// queued hosts.
+	ListQueuedVulnerableHosts(ctx context.Context, cve string, limit int) ([]*VulnerableHost, error)
+	// MarkVulnerableHostsSent records that the given hosts were sent to the
+	// vulnerabilities webhook for the given CVE.
+	MarkVulnerableHostsSent(ctx context.Context, cve string, hostIDs []uint) error
+	// ListHostsByCVE returns the hosts affected by the given CVE.
+	ListHostsByCVE(ctx context.Context, filter TeamFilter, cve string, opt ListOptions) ([]*Host, error)
 
 	///////////////////////////////////////////////////////////////////////////////
 	// ActivitiesStore
@@ -333,7 +445,7 @@ type Datastore interface {
 	///////////////////////////////////////////////////////////////////////////////
 	// GlobalPoliciesStore
 
-	NewGlobalPolicy(ctx context.Context, queryID uint) (*Policy, error)
+	NewGlobalPolicy(ctx context.Context, args PolicyPayload) (*Policy, error)
 	Policy(ctx context.Context, id uint) (*Policy, error)
 	RecordPolicyQueryExecutions(ctx context.Context, host *Host, results map[uint]*bool, updated time.Time) error
 
@@ -342,6 +454,34 @@ type Datastore interface {
 
 	PolicyQueriesForHost(ctx context.Context, host *Host) (map[string]string, error)
 
+	// ApplyPolicySpecs creates or updates the policies in the specs, matching
+	// existing policies by name within their team.
+	ApplyPolicySpecs(ctx context.Context, specs []*PolicySpec) error
+
+	// NewlyFailingPoliciesForHost returns the IDs of the policies for which
+	// the given results flip the host from passing (or never run) to failing.
+	NewlyFailingPoliciesForHost(ctx context.Context, hostID uint, results map[uint]*bool) ([]uint, error)
+	// QueueFailingPolicyHost queues the host to be sent to the failing
+	// policies webhook for each of the given policies.
+	QueueFailingPolicyHost(ctx context.Context, hostID uint, policyIDs []uint, updated time.Time) error
+	// ListQueuedFailingPolicyHosts returns up to limit hosts queued for the
+	// failing policies webhook for the given policy. A limit of zero returns
+	// all queued hosts.
+	ListQueuedFailingPolicyHosts(ctx context.Context, policyID uint, limit int) ([]*PolicyFailingHost, error)
+	// DeleteQueuedFailingPolicyHosts removes the given queue entries once they
+	// have been sent to the failing policies webhook.
+	DeleteQueuedFailingPolicyHosts(ctx context.Context, ids []uint) error
+
+	// ListPoliciesHistory returns the summary of the results of every policy
+	// between since and now for the hosts matching the team filter.
+	ListPoliciesHistory(ctx context.Context, filter TeamFilter, since, now time.Time) ([]*PolicyHistorySummary, error)
+	// PolicyHistory returns the result transitions of the policy between
+	// since and now for the hosts matching the team filter.
+	PolicyHistory(ctx context.Context, filter TeamFilter, policyID uint, opt PolicyHistoryOptions, since, now time.Time) ([]*PolicyTransition, error)
+	// CleanupPolicyMembershipHistory removes the policy result transitions
+	// that ended before the given time.
+	CleanupPolicyMembershipHistory(ctx context.Context, before time.Time) error
+
 	// MigrateTables creates and migrates the table schemas
 	MigrateTables(ctx context.Context) error
 	// MigrateData populates built-in data
@@ -349,12 +489,43 @@ type Datastore interface {
 	// MigrationStatus returns nil if migrations are complete, and an error if migrations need to be run.
 	MigrationStatus(ctx context.Context) (MigrationStatus, error)
 
-	ListSoftware(ctx context.Context, teamId *uint, opt ListOptions) ([]Software, error)
+	// ListSoftware returns the software installed on hosts, with the number
+	// of hosts it is installed on as of the last CalculateSoftwareHostCounts.
+	ListSoftware(ctx context.Context, opt SoftwareListOptions) ([]Software, error)
+	// CalculateSoftwareHostCounts calculates the number of hosts each software
+	// is installed on, across all hosts and per team.
+	CalculateSoftwareHostCounts(ctx context.Context, updatedAt time.Time) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// OSVersionsStore
+
+	// OSVersions returns the number of hosts per platform, OS version and
+	// osquery version as of the last CalculateOSVersionHostCounts.
+	OSVersions(ctx context.Context, opt OSVersionsOptions) (*OSVersions, error)
+	// CalculateOSVersionHostCounts calculates the number of hosts per
+	// platform, OS version and osquery version, across all hosts and per team.
+	CalculateOSVersionHostCounts(ctx context.Context, updatedAt time.Time) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// QuerySweepStore
+
+	NewQuerySweep(ctx context.Context, sweep *QuerySweep) (*QuerySweep, error)
+	QuerySweep(ctx context.Context, id uint) (*QuerySweep, error)
+	ListQuerySweeps(ctx context.Context, opt ListOptions) ([]*QuerySweep, error)
+	SaveQuerySweep(ctx context.Context, sweep *QuerySweep) error
+	DeleteQuerySweep(ctx context.Context, id uint) error
+	// QuerySweepHostResults returns the rows returned by each of the given
+	// hosts in the last run of the query sweep they responded to. Hosts that
+	// never responded are not included.
+	QuerySweepHostResults(ctx context.Context, sweepID uint, hostIDs []uint) (map[uint][]map[string]string, error)
+	// SaveQuerySweepHostResults replaces the rows returned by the given hosts
+	// in the last run of the query sweep.
+	SaveQuerySweepHostResults(ctx context.Context, sweepID uint, results map[uint][]map[string]string) error
 
 	///////////////////////////////////////////////////////////////////////////////
 	// Team Policies
 
-	NewTeamPolicy(ctx context.Context, teamID uint, queryID uint) (*Policy, error)
+	NewTeamPolicy(ctx context.Context, teamID uint, args PolicyPayload) (*Policy, error)
 	ListTeamPolicies(ctx context.Context, teamID uint) ([]*Policy, error)
 	DeleteTeamPolicies(ctx context.Context, teamID uint, ids []uint) ([]uint, error)
 	TeamPolicy(ctx context.Context, teamID uint, policyID uint) (*Policy, error)
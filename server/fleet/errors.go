@@ -215,6 +215,9 @@ const (
 	ErrNoOneAdminNeeded = 2
 	//ErrNoUnknownTranslate is returned when an item type in the translate payload is unknown
 	ErrNoUnknownTranslate = 3
+	// ErrNoLiveQueryResultsDisabled is returned when running a live query that
+	// needs its results to be stored while storing them is disabled
+	ErrNoLiveQueryResultsDisabled = 4
 )
 
 // NewError returns a fleet error with the code and message specified
@@ -1,12 +1,27 @@
 package fleet
 
+import (
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
 type Policy struct {
-	ID               uint   `json:"id"`
-	QueryID          uint   `json:"query_id" db:"query_id"`
-	QueryName        string `json:"query_name" db:"query_name"`
-	PassingHostCount uint   `json:"passing_host_count" db:"passing_host_count"`
-	FailingHostCount uint   `json:"failing_host_count" db:"failing_host_count"`
-	TeamID           *uint  `db:"team_id"`
+	ID          uint   `json:"id"`
+	Name        string `json:"name" db:"name"`
+	Query       string `json:"query" db:"query"`
+	Description string `json:"description" db:"description"`
+	Resolution  string `json:"resolution" db:"resolution"`
+	// Platform is a comma-separated list of the platforms the policy targets,
+	// empty if it targets all of them.
+	Platform string `json:"platform" db:"platforms"`
+	// QueryID is the ID of the saved query the policy was created from, if
+	// any.
+	QueryID          *uint `json:"query_id" db:"query_id"`
+	TeamID           *uint `json:"team_id" db:"team_id"`
+	PassingHostCount uint  `json:"passing_host_count" db:"passing_host_count"`
+	FailingHostCount uint  `json:"failing_host_count" db:"failing_host_count"`
 
 	UpdateCreateTimestamps
 }
@@ -14,3 +29,134 @@ type Policy struct {
 func (Policy) AuthzType() string {
 	return "policy"
 }
+
+// PolicyPayload holds the fields used to create a policy. A policy is either
+// created from a saved query, in which case the name, query and description
+// default to those of the query, or from its own query.
+type PolicyPayload struct {
+	QueryID     *uint  `json:"query_id"`
+	Name        string `json:"name"`
+	Query       string `json:"query"`
+	Description string `json:"description"`
+	Resolution  string `json:"resolution"`
+	Platform    string `json:"platform"`
+}
+
+const (
+	PolicyKind = "policy"
+)
+
+// PolicySpec is the representation of a policy used by fleetctl apply/get.
+// Policies are identified by their name within their team, or among the
+// global policies if Team is empty.
+type PolicySpec struct {
+	Name        string `json:"name"`
+	Query       string `json:"query"`
+	Description string `json:"description"`
+	Resolution  string `json:"resolution,omitempty"`
+	// Team is the name of the team the policy belongs to, empty for a global
+	// policy.
+	Team     string `json:"team,omitempty"`
+	Platform string `json:"platform,omitempty"`
+}
+
+// PolicyPlatforms are the platforms a policy can target.
+var PolicyPlatforms = []string{"darwin", "windows", "linux"}
+
+// ValidatePolicyPlatform returns an error if the comma-separated list of
+// platforms contains an unknown platform.
+func ValidatePolicyPlatform(platform string) error {
+	if platform == "" {
+		return nil
+	}
+	for _, p := range strings.Split(platform, ",") {
+		found := false
+		for _, valid := range PolicyPlatforms {
+			if strings.TrimSpace(p) == valid {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return errors.Errorf("invalid policy platform %q, must be one of %s", p, strings.Join(PolicyPlatforms, ", "))
+		}
+	}
+	return nil
+}
+
+// PolicyTargetsHostPlatform returns whether a policy targeting the given
+// comma-separated list of platforms should run on a host of the given
+// platform. Hosts that are neither darwin nor windows are considered linux.
+func PolicyTargetsHostPlatform(policyPlatform, hostPlatform string) bool {
+	if policyPlatform == "" {
+		return true
+	}
+	switch hostPlatform {
+	case "darwin", "windows":
+	case "":
+		return false
+	default:
+		hostPlatform = "linux"
+	}
+	for _, p := range strings.Split(policyPlatform, ",") {
+		if strings.TrimSpace(p) == hostPlatform {
+			return true
+		}
+	}
+	return false
+}
+
+// PolicyFailingHost is a host that started failing a policy and is queued to
+// be sent to the failing policies webhook.
+type PolicyFailingHost struct {
+	ID       uint   `json:"-" db:"id"`
+	PolicyID uint   `json:"-" db:"policy_id"`
+	HostID   uint   `json:"id" db:"host_id"`
+	Hostname string `json:"hostname" db:"hostname"`
+}
+
+const (
+	// DefaultPolicyHistoryDays is the number of days the policy history is
+	// computed for when not specified.
+	DefaultPolicyHistoryDays = 30
+	// PolicyHistoryRetention is how long the policy result transitions are
+	// kept for. The latest result of each host and policy is always kept.
+	PolicyHistoryRetention = 90 * 24 * time.Hour
+)
+
+// PolicyHistoryOptions are the options to get the history of policy results.
+type PolicyHistoryOptions struct {
+	// Days is the number of days back the history is computed for.
+	Days uint
+	// HostID optionally restricts the history to a single host.
+	HostID *uint
+}
+
+// PolicyHistorySummary aggregates the results of a policy over a period of
+// time.
+type PolicyHistorySummary struct {
+	PolicyID         uint   `json:"policy_id" db:"policy_id"`
+	Name             string `json:"name" db:"name"`
+	TeamID           *uint  `json:"team_id" db:"team_id"`
+	FailingHostCount uint   `json:"failing_host_count" db:"failing_host_count"`
+	// DaysFailing is the sum of the time each host spent failing the policy
+	// during the period, in days.
+	DaysFailing float64 `json:"days_failing" db:"days_failing"`
+	// PassRate is the fraction of the time the hosts that ran the policy
+	// during the period were passing it.
+	PassRate float64 `json:"pass_rate" db:"pass_rate"`
+}
+
+// PolicyTransition is a period of time during which a host had the same
+// result for a policy.
+type PolicyTransition struct {
+	PolicyID uint   `json:"policy_id" db:"policy_id"`
+	HostID   uint   `json:"host_id" db:"host_id"`
+	Hostname string `json:"hostname" db:"hostname"`
+	// Passes is nil if the policy query failed to run.
+	Passes    *bool     `json:"passes" db:"passes"`
+	StartedAt time.Time `json:"started_at" db:"started_at"`
+	// EndedAt is nil for the current result of the host.
+	EndedAt    *time.Time `json:"ended_at" db:"ended_at"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+}
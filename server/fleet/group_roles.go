@@ -0,0 +1,121 @@
+package fleet
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/pkg/errors"
+)
+
+// GroupRole is the role given to the members of a group of the identity
+// provider, either globally or in the team TeamID.
+type GroupRole struct {
+	TeamID *uint
+	Role   string
+}
+
+// GroupRoles maps the names of the groups of the identity provider to the
+// roles of their members.
+type GroupRoles map[string]GroupRole
+
+// ParseGroupRoles parses the semicolon-separated list of group roles, in the
+// "group=role" format for global roles and the "group=team_id:role" format
+// for team roles.
+func ParseGroupRoles(s string) (GroupRoles, error) {
+	groupRoles := make(GroupRoles)
+	for _, mapping := range strings.Split(s, ";") {
+		if strings.TrimSpace(mapping) == "" {
+			continue
+		}
+		// Group names may contain "=", but roles may not.
+		i := strings.LastIndex(mapping, "=")
+		if i < 0 || strings.TrimSpace(mapping[:i]) == "" {
+			return nil, errors.Errorf("invalid group role %q, expected \"group=role\" or \"group=team_id:role\"", mapping)
+		}
+		group, role := strings.TrimSpace(mapping[:i]), strings.TrimSpace(mapping[i+1:])
+		if _, ok := groupRoles[group]; ok {
+			return nil, errors.Errorf("duplicate group %q", group)
+		}
+
+		var groupRole GroupRole
+		if parts := strings.SplitN(role, ":", 2); len(parts) == 2 {
+			teamID, err := strconv.ParseUint(strings.TrimSpace(parts[0]), 10, 0)
+			if err != nil || teamID == 0 {
+				return nil, errors.Errorf("invalid team ID in group role %q", mapping)
+			}
+			groupRole.TeamID = ptr.Uint(uint(teamID))
+			groupRole.Role = strings.TrimSpace(parts[1])
+			if !ValidTeamRole(groupRole.Role) {
+				return nil, errors.Errorf("invalid team role %q for group %q", groupRole.Role, group)
+			}
+		} else {
+			groupRole.Role = role
+			if !ValidGlobalRole(groupRole.Role) {
+				return nil, errors.Errorf("invalid global role %q for group %q", groupRole.Role, group)
+			}
+		}
+		groupRoles[group] = groupRole
+	}
+	return groupRoles, nil
+}
+
+// InTeams returns the group roles of the teams teamIDs, without the global
+// roles.
+func (m GroupRoles) InTeams(teamIDs []uint) GroupRoles {
+	allowed := make(map[uint]bool, len(teamIDs))
+	for _, teamID := range teamIDs {
+		allowed[teamID] = true
+	}
+	groupRoles := make(GroupRoles)
+	for group, groupRole := range m {
+		if groupRole.TeamID != nil && allowed[*groupRole.TeamID] {
+			groupRoles[group] = groupRole
+		}
+	}
+	return groupRoles
+}
+
+// roleRanks orders the roles by the permissions they give.
+var roleRanks = map[string]int{
+	RoleObserver:   1,
+	RoleMaintainer: 2,
+	RoleAdmin:      3,
+}
+
+// Roles returns the roles of a member of the groups. The highest global role
+// of the groups wins over the team roles, and otherwise the user has the
+// highest role of the groups in each team. ok is false if none of the groups
+// is mapped to a role.
+func (m GroupRoles) Roles(groups []string) (globalRole *string, teams []UserTeam, ok bool) {
+	teamRoles := make(map[uint]string)
+	for _, group := range groups {
+		groupRole, mapped := m[group]
+		if !mapped {
+			continue
+		}
+		if groupRole.TeamID == nil {
+			if globalRole == nil || roleRanks[groupRole.Role] > roleRanks[*globalRole] {
+				globalRole = ptr.String(groupRole.Role)
+			}
+			continue
+		}
+		if roleRanks[groupRole.Role] > roleRanks[teamRoles[*groupRole.TeamID]] {
+			teamRoles[*groupRole.TeamID] = groupRole.Role
+		}
+	}
+
+	if globalRole != nil {
+		return globalRole, []UserTeam{}, true
+	}
+	if len(teamRoles) == 0 {
+		return nil, nil, false
+	}
+	teams = make([]UserTeam, 0, len(teamRoles))
+	for teamID, role := range teamRoles {
+		teams = append(teams, UserTeam{Team: Team{ID: teamID}, Role: role})
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ID < teams[j].ID })
+	return nil, teams, true
+}
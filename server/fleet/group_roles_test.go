@@ -0,0 +1,77 @@
+package fleet
+
+import (
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGroupRoles(t *testing.T) {
+	groupRoles, err := ParseGroupRoles(" Fleet Admins = admin ;a=b=observer;Workstations=2:maintainer;")
+	require.NoError(t, err)
+	assert.Equal(t, GroupRoles{
+		"Fleet Admins": {Role: RoleAdmin},
+		"a=b":          {Role: RoleObserver},
+		"Workstations": {TeamID: ptr.Uint(2), Role: RoleMaintainer},
+	}, groupRoles)
+
+	groupRoles, err = ParseGroupRoles("")
+	require.NoError(t, err)
+	assert.Empty(t, groupRoles)
+
+	for _, s := range []string{
+		"admin",
+		"=admin",
+		"a=owner",
+		"a=1:admin",
+		"a=x:observer",
+		"a=0:observer",
+		"a=admin;a=observer",
+	} {
+		_, err := ParseGroupRoles(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestGroupRolesRoles(t *testing.T) {
+	groupRoles := GroupRoles{
+		"admins":            {Role: RoleAdmin},
+		"observers":         {Role: RoleObserver},
+		"team1 observers":   {TeamID: ptr.Uint(1), Role: RoleObserver},
+		"team1 maintainers": {TeamID: ptr.Uint(1), Role: RoleMaintainer},
+		"team2 observers":   {TeamID: ptr.Uint(2), Role: RoleObserver},
+	}
+
+	_, _, ok := groupRoles.Roles([]string{"unmapped"})
+	assert.False(t, ok)
+
+	// The highest global role wins over the team roles.
+	globalRole, teams, ok := groupRoles.Roles([]string{"observers", "team1 maintainers", "admins"})
+	assert.True(t, ok)
+	assert.Equal(t, ptr.String(RoleAdmin), globalRole)
+	assert.Empty(t, teams)
+
+	globalRole, teams, ok = groupRoles.Roles([]string{"team2 observers", "team1 maintainers", "team1 observers", "unmapped"})
+	assert.True(t, ok)
+	assert.Nil(t, globalRole)
+	assert.Equal(t, []UserTeam{
+		{Team: Team{ID: 1}, Role: RoleMaintainer},
+		{Team: Team{ID: 2}, Role: RoleObserver},
+	}, teams)
+	assert.NoError(t, ValidateRole(globalRole, teams))
+}
+
+func TestGroupRolesInTeams(t *testing.T) {
+	groupRoles := GroupRoles{
+		"admins":          {Role: RoleAdmin},
+		"team1 observers": {TeamID: ptr.Uint(1), Role: RoleObserver},
+		"team2 observers": {TeamID: ptr.Uint(2), Role: RoleObserver},
+	}
+
+	assert.Equal(t, GroupRoles{
+		"team2 observers": {TeamID: ptr.Uint(2), Role: RoleObserver},
+	}, groupRoles.InTeams([]uint{2, 3}))
+	assert.Empty(t, groupRoles.InTeams(nil))
+}
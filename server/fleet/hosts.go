@@ -45,6 +45,18 @@ type HostListOptions struct {
 
 	PolicyIDFilter       *uint
 	PolicyResponseFilter *bool
+
+	// SoftwareIDFilter selects the hosts that have the specified software
+	// installed.
+	SoftwareIDFilter *uint
+	// CVEFilter selects the hosts that have software installed affected by
+	// the specified CVE.
+	CVEFilter string
+	// OSVersionFilter selects the hosts running the specified OS version.
+	OSVersionFilter string
+	// LabelIDsFilter selects the hosts that are members of all the
+	// specified labels.
+	LabelIDsFilter []uint
 }
 
 type HostUser struct {
@@ -114,9 +126,41 @@ type Host struct {
 	GigsDiskSpaceAvailable    float64 `json:"gigs_disk_space_available" db:"gigs_disk_space_available"`
 	PercentDiskSpaceAvailable float64 `json:"percent_disk_space_available" db:"percent_disk_space_available"`
 
+	// The vitals below are stored in their own tables, and are only saved
+	// when set. They are loaded with LoadHostVitals.
+	DiskEncryption *HostDiskEncryption `json:"disk_encryption,omitempty" db:"-"`
+	MDM            *HostMDM            `json:"mdm,omitempty" db:"-"`
+	Munki          *HostMunkiInfo      `json:"munki,omitempty" db:"-"`
+	// Batteries is nil when unknown and empty when the host has no battery.
+	Batteries []HostBattery `json:"batteries,omitempty" db:"-"`
+
 	Modified bool `json:"-"`
 }
 
+// HostDiskEncryption is the encryption status of the disk the host boots
+// from.
+type HostDiskEncryption struct {
+	Encrypted bool `json:"encrypted" db:"encrypted"`
+}
+
+// HostMDM is the MDM enrollment of a macOS host.
+type HostMDM struct {
+	Enrolled  bool   `json:"enrolled" db:"enrolled"`
+	ServerURL string `json:"server_url" db:"server_url"`
+}
+
+// HostMunkiInfo is the munki installation of a macOS host.
+type HostMunkiInfo struct {
+	Version string `json:"version" db:"version"`
+}
+
+// HostBattery is the health of a battery of the host.
+type HostBattery struct {
+	SerialNumber string `json:"serial_number" db:"serial_number"`
+	CycleCount   int    `json:"cycle_count" db:"cycle_count"`
+	Health       string `json:"health" db:"health"`
+}
+
 func (h Host) AuthzType() string {
 	return "host"
 }
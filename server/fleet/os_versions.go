@@ -0,0 +1,28 @@
+package fleet
+
+import "time"
+
+// OSVersion is the number of hosts of a platform that run an OS version with
+// an osquery version.
+type OSVersion struct {
+	Platform       string `json:"platform" db:"platform"`
+	OSVersion      string `json:"os_version" db:"os_version"`
+	OsqueryVersion string `json:"osquery_version" db:"osquery_version"`
+	HostsCount     int    `json:"hosts_count" db:"hosts_count"`
+}
+
+// OSVersions is the inventory of the OS versions run by the hosts, as of the
+// last time it was calculated.
+type OSVersions struct {
+	// CountsUpdatedAt is nil if the inventory was never calculated.
+	CountsUpdatedAt *time.Time  `json:"counts_updated_at"`
+	OSVersions      []OSVersion `json:"os_versions"`
+}
+
+// OSVersionsOptions filters the OS versions inventory.
+type OSVersionsOptions struct {
+	// TeamID selects the hosts of the team, all hosts are counted if nil.
+	TeamID *uint
+	// Platform selects the hosts of the platform.
+	Platform string
+}
@@ -0,0 +1,53 @@
+package fleet
+
+import "time"
+
+// QuerySweep is a live query that the server runs on its targets at a
+// regular interval, reporting the rows added and removed on each host since
+// the previous run.
+type QuerySweep struct {
+	UpdateCreateTimestamps
+	ID          uint        `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Query       string      `json:"query"`
+	Targets     HostTargets `json:"targets"`
+	// Interval is the number of seconds between two runs of the sweep.
+	Interval uint `json:"interval"`
+	// WebhookURL is the URL the changes are posted to. If empty, they are
+	// recorded in the activity feed.
+	WebhookURL string `json:"webhook_url"`
+	// AuthorID is the user the sweep runs as, to select the hosts it
+	// targets.
+	AuthorID  *uint      `json:"author_id"`
+	LastRunAt *time.Time `json:"last_run_at"`
+	// LastCampaignID is the campaign of the last run of the sweep.
+	LastCampaignID *uint `json:"last_campaign_id"`
+	// DiffedCampaignID is the campaign of the last run whose results were
+	// compared with the previous ones.
+	DiffedCampaignID *uint `json:"-"`
+}
+
+func (s QuerySweep) AuthzType() string {
+	return "query_sweep"
+}
+
+// QuerySweepPayload is the payload to create or modify a query sweep. Only
+// the set fields are modified.
+type QuerySweepPayload struct {
+	Name        *string      `json:"name"`
+	Description *string      `json:"description"`
+	Query       *string      `json:"query"`
+	Targets     *HostTargets `json:"targets"`
+	Interval    *uint        `json:"interval"`
+	WebhookURL  *string      `json:"webhook_url"`
+}
+
+// QuerySweepHostChanges are the rows added and removed on a host between two
+// runs of a query sweep.
+type QuerySweepHostChanges struct {
+	HostID   uint                `json:"host_id"`
+	Hostname string              `json:"hostname"`
+	Added    []map[string]string `json:"added"`
+	Removed  []map[string]string `json:"removed"`
+}
@@ -0,0 +1,49 @@
+package fleet
+
+import "time"
+
+// The actions of the rows of results of scheduled queries, as logged by
+// osquery.
+const (
+	ScheduledQueryResultSnapshot = "snapshot"
+	ScheduledQueryResultAdded    = "added"
+	ScheduledQueryResultRemoved  = "removed"
+)
+
+// ScheduledQueryResult is a row of the results of a scheduled query, as
+// stored by Fleet.
+type ScheduledQueryResult struct {
+	ID               uint   `json:"id"`
+	ScheduledQueryID uint   `json:"scheduled_query_id" db:"scheduled_query_id"`
+	HostID           uint   `json:"host_id" db:"host_id"`
+	Hostname         string `json:"hostname"`
+	// Action is whether the row is part of a snapshot, or was added or
+	// removed since the previous run of the query.
+	Action      string            `json:"action"`
+	Data        map[string]string `json:"data" db:"-"`
+	CollectedAt time.Time         `json:"collected_at" db:"collected_at"`
+}
+
+// ScheduledQueryResultLog is a result log of a scheduled query, sent by
+// osquery, with the names of the pack and query it ran as.
+type ScheduledQueryResultLog struct {
+	PackName  string
+	QueryName string
+	// Snapshot is whether the rows replace the previously stored rows.
+	Snapshot    bool
+	CollectedAt time.Time
+	Rows        []ScheduledQueryResultRow
+}
+
+// ScheduledQueryResultRow is a row of a scheduled query result log.
+type ScheduledQueryResultRow struct {
+	Action string
+	Data   map[string]string
+}
+
+type ScheduledQueryResultsListOptions struct {
+	ListOptions
+
+	ScheduledQueryID *uint
+	HostID           *uint
+}
@@ -0,0 +1,34 @@
+package fleet
+
+// SCIMGroup is a group of users provisioned by the identity provider through
+// the SCIM API. The members of the group get the role mapped to its display
+// name in the SCIM configuration.
+type SCIMGroup struct {
+	UpdateCreateTimestamps
+	ID          uint   `json:"id"`
+	DisplayName string `json:"display_name" db:"display_name"`
+	// UserIDs is the IDs of the members of the group.
+	UserIDs []uint `json:"user_ids" db:"-"`
+}
+
+func (g SCIMGroup) AuthzType() string {
+	return "scim_group"
+}
+
+// SCIMUserPayload is used to provision a user through the SCIM API.
+type SCIMUserPayload struct {
+	Email string
+	Name  string
+}
+
+// SCIMUserListOptions are the options to list the users provisioned through
+// the SCIM API.
+type SCIMUserListOptions struct {
+	// UserName, if not empty, only lists the user with this email, case
+	// insensitively.
+	UserName string
+	// Offset is the number of users skipped, and Limit the maximum number of
+	// users listed.
+	Offset uint
+	Limit  uint
+}
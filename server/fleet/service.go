@@ -3,6 +3,7 @@ package fleet
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/fleetdm/fleet/v4/server/websocket"
 	"github.com/kolide/kit/version"
@@ -15,10 +16,12 @@ type OsqueryService interface {
 	AuthenticateHost(ctx context.Context, nodeKey string) (host *Host, debug bool, err error)
 	GetClientConfig(ctx context.Context) (config map[string]interface{}, err error)
 	// GetDistributedQueries retrieves the distributed queries to run for the host in the provided context. These may be
-	// detail queries, label queries, or user-initiated distributed queries. A map from query name to query is returned.
+	// detail queries, label queries, or user-initiated distributed queries. A map from query name to query is returned,
+	// along with a map from query name to discovery query for the queries that only run when the discovery query
+	// returns rows.
 	// To enable the osquery "accelerated checkins" feature, a positive integer (number of seconds to activate for)
 	// should be returned. Returning 0 for this will not activate the feature.
-	GetDistributedQueries(ctx context.Context) (queries map[string]string, accelerate uint, err error)
+	GetDistributedQueries(ctx context.Context) (queries map[string]string, discovery map[string]string, accelerate uint, err error)
 	SubmitDistributedQueryResults(
 		ctx context.Context,
 		results OsqueryDistributedQueryResults,
@@ -102,8 +105,17 @@ type Service interface {
 	// when prompted for login.
 	CallbackSSO(ctx context.Context, auth Auth) (*SSOSession, error)
 
+	// CallbackOIDC handles the redirection from the OpenID Connect provider, like CallbackSSO handles the SAML
+	// response.
+	CallbackOIDC(ctx context.Context, callback OIDCCallback) (*SSOSession, error)
+
 	// SSOSettings returns non-sensitive single sign on information used before authentication
 	SSOSettings(ctx context.Context) (*SessionSSOSettings, error)
+
+	// SSOServiceProviderMetadata returns the SAML metadata describing Fleet as a service provider, which is used to
+	// configure Fleet in the IDP.
+	SSOServiceProviderMetadata(ctx context.Context) ([]byte, error)
+
 	Login(ctx context.Context, email, password string) (user *User, sessionKey string, err error)
 	Logout(ctx context.Context) (err error)
 	DestroySession(ctx context.Context) (err error)
@@ -113,6 +125,50 @@ type Service interface {
 	GetSessionByKey(ctx context.Context, key string) (session *Session, err error)
 	DeleteSession(ctx context.Context, id uint) (err error)
 
+	///////////////////////////////////////////////////////////////////////////////
+	// API tokens
+
+	// GetAPITokenByKey returns the API token with the given key, if it is not
+	// expired, and marks it as used.
+	GetAPITokenByKey(ctx context.Context, key string) (*APIToken, error)
+	// CreateAPIToken creates an API token for the current user. The key of the
+	// token is only returned by this method.
+	CreateAPIToken(ctx context.Context, payload APITokenPayload) (*APIToken, error)
+	// ListAPITokens returns the API tokens of the current user.
+	ListAPITokens(ctx context.Context) ([]*APIToken, error)
+	// DeleteAPIToken revokes the API token with the given id.
+	DeleteAPIToken(ctx context.Context, id uint) error
+
+	///////////////////////////////////////////////////////////////////////////////
+	// SCIM provisioning
+
+	// CreateSCIMUser provisions an SSO user, with the role of the SCIM groups
+	// it is already a member of, or the default SCIM role.
+	CreateSCIMUser(ctx context.Context, payload SCIMUserPayload) (*User, error)
+	// GetSCIMUser returns the user with the given id, if it was provisioned
+	// through the SCIM API.
+	GetSCIMUser(ctx context.Context, id uint) (*User, error)
+	// ListSCIMUsers returns the page of the users provisioned through the
+	// SCIM API, and the total number of users matching the options.
+	ListSCIMUsers(ctx context.Context, opt SCIMUserListOptions) ([]*User, int, error)
+	// SaveSCIMUser updates the email and name of a provisioned user.
+	SaveSCIMUser(ctx context.Context, id uint, payload SCIMUserPayload) (*User, error)
+	// DeprovisionSCIMUser destroys the sessions of the user and deletes it.
+	DeprovisionSCIMUser(ctx context.Context, id uint) error
+	// ListSCIMGroups returns all the SCIM groups.
+	ListSCIMGroups(ctx context.Context) ([]*SCIMGroup, error)
+	// GetSCIMGroup returns the SCIM group with the given id.
+	GetSCIMGroup(ctx context.Context, id uint) (*SCIMGroup, error)
+	// CreateSCIMGroup creates a SCIM group, and updates the roles of its
+	// members.
+	CreateSCIMGroup(ctx context.Context, group *SCIMGroup) (*SCIMGroup, error)
+	// SaveSCIMGroup saves a SCIM group, and updates the roles of its current
+	// and former members.
+	SaveSCIMGroup(ctx context.Context, group *SCIMGroup) (*SCIMGroup, error)
+	// DeleteSCIMGroup deletes a SCIM group, and updates the roles of its
+	// former members.
+	DeleteSCIMGroup(ctx context.Context, id uint) error
+
 	///////////////////////////////////////////////////////////////////////////////
 	// PackService is the service interface for managing query packs.
 
@@ -214,6 +270,33 @@ type Service interface {
 	// go-kit RPC style.
 	StreamCampaignResults(ctx context.Context, conn *websocket.Conn, campaignID uint)
 
+	// CampaignResults returns the stored results of the distributed query campaign of the provided ID.
+	CampaignResults(ctx context.Context, campaignID uint, opt ListOptions) ([]*DistributedQueryCampaignResult, error)
+
+	// ExportCampaignResults returns all the stored results of the distributed query campaign of the provided ID.
+	ExportCampaignResults(ctx context.Context, campaignID uint) ([]*DistributedQueryCampaignResult, error)
+
+	// RunLiveQuery starts a distributed query campaign that runs without a websocket listening to its results, which
+	// are stored instead. It waits up to the provided duration for the campaign to finish and returns its status.
+	RunLiveQuery(
+		ctx context.Context, queryString string, queryID *uint, targets HostTargets, wait time.Duration,
+	) (*DistributedQueryCampaignStatus, error)
+
+	// CampaignStatus returns the status of the distributed query campaign of the provided ID.
+	CampaignStatus(ctx context.Context, campaignID uint) (*DistributedQueryCampaignStatus, error)
+
+	// CampaignHosts returns the execution status of the distributed query campaign of the provided ID on each of the
+	// hosts it targets.
+	CampaignHosts(ctx context.Context, campaignID uint, opt ListOptions) ([]*DistributedQueryCampaignHost, error)
+
+	// CancelCampaign stops the distributed query campaign of the provided ID.
+	CancelCampaign(ctx context.Context, campaignID uint) error
+
+	// CompleteExpiredCampaigns stops the distributed query campaigns that were created longer than the live query
+	// timeout before now and are not complete, e.g. because the server that listened to their results restarted. It
+	// is called periodically by the server, not by users.
+	CompleteExpiredCampaigns(ctx context.Context, now time.Time) error
+
 	///////////////////////////////////////////////////////////////////////////////
 	// AgentOptionsService
 
@@ -393,20 +476,58 @@ type Service interface {
 	///////////////////////////////////////////////////////////////////////////////
 	// GlobalPolicyService
 
-	NewGlobalPolicy(ctx context.Context, queryID uint) (*Policy, error)
+	NewGlobalPolicy(ctx context.Context, p PolicyPayload) (*Policy, error)
 	ListGlobalPolicies(ctx context.Context) ([]*Policy, error)
 	DeleteGlobalPolicies(ctx context.Context, ids []uint) ([]uint, error)
 	GetPolicyByIDQueries(ctx context.Context, policyID uint) (*Policy, error)
+	// ApplyPolicySpecs creates or updates the global and team policies in
+	// the specs.
+	ApplyPolicySpecs(ctx context.Context, specs []*PolicySpec) error
+	// ListPoliciesHistory returns the summary of the results of every policy
+	// over the last days, scoped to the hosts the user can see.
+	ListPoliciesHistory(ctx context.Context, opt PolicyHistoryOptions) ([]*PolicyHistorySummary, error)
+	// PolicyHistory returns the per-host timeline of the results of a policy
+	// over the last days, scoped to the hosts the user can see.
+	PolicyHistory(ctx context.Context, policyID uint, opt PolicyHistoryOptions) ([]*PolicyTransition, error)
 
 	///////////////////////////////////////////////////////////////////////////////
 	// Software
 
-	ListSoftware(ctx context.Context, teamID *uint, opt ListOptions) ([]Software, error)
+	ListSoftware(ctx context.Context, opt SoftwareListOptions) ([]Software, error)
+	// ListHostsByCVE returns the hosts affected by the given CVE.
+	ListHostsByCVE(ctx context.Context, cve string, opt ListOptions) ([]*Host, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// OS versions
+
+	// OSVersions returns the number of hosts per platform, OS version and
+	// osquery version, of the team if one is given.
+	OSVersions(ctx context.Context, opt OSVersionsOptions) (*OSVersions, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// Scheduled query results
+
+	// ListScheduledQueryResults returns the stored rows of the results of the
+	// scheduled queries on the hosts the user can see.
+	ListScheduledQueryResults(ctx context.Context, opt ScheduledQueryResultsListOptions) ([]*ScheduledQueryResult, error)
+
+	///////////////////////////////////////////////////////////////////////////////
+	// Query sweeps
+
+	NewQuerySweep(ctx context.Context, p QuerySweepPayload) (*QuerySweep, error)
+	ListQuerySweeps(ctx context.Context, opt ListOptions) ([]*QuerySweep, error)
+	GetQuerySweep(ctx context.Context, id uint) (*QuerySweep, error)
+	ModifyQuerySweep(ctx context.Context, id uint, p QuerySweepPayload) (*QuerySweep, error)
+	DeleteQuerySweep(ctx context.Context, id uint) error
+	// RunQuerySweeps reports the changes found by the query sweeps whose last
+	// run completed, and starts the sweeps that are due. It is called
+	// periodically by the server, not by users.
+	RunQuerySweeps(ctx context.Context, now time.Time) error
 
 	///////////////////////////////////////////////////////////////////////////////
 	// Team Policies
 
-	NewTeamPolicy(ctx context.Context, teamID uint, queryID uint) (*Policy, error)
+	NewTeamPolicy(ctx context.Context, teamID uint, p PolicyPayload) (*Policy, error)
 	ListTeamPolicies(ctx context.Context, teamID uint) ([]*Policy, error)
 	DeleteTeamPolicies(ctx context.Context, teamID uint, ids []uint) ([]uint, error)
 	GetTeamPolicyByIDQueries(ctx context.Context, teamID uint, policyID uint) (*Policy, error)
@@ -7,6 +7,9 @@ import (
 type Auth interface {
 	UserID() string
 	RequestID() string
+	// Attributes returns the values of the attributes of the assertion by
+	// attribute name.
+	Attributes() map[string][]string
 }
 
 type SSOSession struct {
@@ -14,6 +17,16 @@ type SSOSession struct {
 	RedirectURL string
 }
 
+// OIDCCallback is the redirection of the user from the OpenID Connect
+// provider to Fleet.
+type OIDCCallback struct {
+	State string
+	Code  string
+	// Error and ErrorDescription are set if the authorization failed.
+	Error            string
+	ErrorDescription string
+}
+
 // SessionSSOSettings SSO information used prior to authentication.
 type SessionSSOSettings struct {
 	// IDPName is a human readable name for the IDP
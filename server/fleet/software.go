@@ -1,8 +1,47 @@
 package fleet
 
+import (
+	"strings"
+	"time"
+)
+
 type SoftwareCVE struct {
 	CVE         string `json:"cve" db:"cve"`
 	DetailsLink string `json:"details_link" db:"details_link"`
+	// CVSSScore is the CVSS v3 base score of the CVE.
+	CVSSScore *float64 `json:"cvss_score,omitempty" db:"cvss_score"`
+	// CVSSVector is the CVSS v3 vector string of the CVE.
+	CVSSVector *string `json:"cvss_vector,omitempty" db:"cvss_vector"`
+	// Severity is the CVSS v3 severity of the CVE (LOW, MEDIUM, HIGH or
+	// CRITICAL).
+	Severity *string `json:"severity,omitempty" db:"severity"`
+	// Published is when the CVE was published in the NVD.
+	Published *time.Time `json:"cve_published,omitempty" db:"published"`
+}
+
+// CVEMeta is the scoring information of a CVE, as published in the NVD feeds.
+type CVEMeta struct {
+	CVE        string     `db:"cve"`
+	CVSSScore  *float64   `db:"cvss_score"`
+	CVSSVector *string    `db:"cvss_vector"`
+	Severity   *string    `db:"severity"`
+	Published  *time.Time `db:"published"`
+}
+
+// cvssSeverityMinScores are the lowest CVSS v3 base scores of each severity
+// rating.
+var cvssSeverityMinScores = map[string]float64{
+	"low":      0.1,
+	"medium":   4.0,
+	"high":     7.0,
+	"critical": 9.0,
+}
+
+// MinCVSSScoreForSeverity returns the lowest CVSS v3 base score of a severity
+// rating, and false if the severity is not known.
+func MinCVSSScoreForSeverity(severity string) (float64, bool) {
+	score, ok := cvssSeverityMinScores[strings.ToLower(severity)]
+	return score, ok
 }
 
 // Software is a named and versioned piece of software installed on a device.
@@ -12,6 +51,9 @@ type Software struct {
 	Name string `json:"name" db:"name"`
 	// Version is reported version.
 	Version string `json:"version" db:"version"`
+	// Release is the reported release of rpm packages, which is empty for
+	// the other sources.
+	Release string `json:"release,omitempty" db:"release"`
 	// Source is the source of the data (osquery table name).
 	Source string `json:"source" db:"source"`
 
@@ -19,6 +61,28 @@ type Software struct {
 	GenerateCPE string `json:"generated_cpe" db:"generated_cpe"`
 	// Vulnerabilities lists all the found CVEs for the CPE
 	Vulnerabilities VulnerabilitiesSlice `json:"vulnerabilities"`
+	// MaxCVSSScore is the highest CVSS v3 base score of the vulnerabilities
+	// of the software.
+	MaxCVSSScore *float64 `json:"max_cvss_score,omitempty" db:"max_cvss_score"`
+	// HostsCount is the number of hosts with the software installed, as of
+	// the last time the counts were calculated.
+	HostsCount int `json:"hosts_count,omitempty" db:"hosts_count"`
+}
+
+// SoftwareListOptions are the options to list software.
+type SoftwareListOptions struct {
+	ListOptions
+
+	// TeamID selects the software of the hosts of the team.
+	TeamID *uint
+	// MinSeverity selects the software with at least one vulnerability of
+	// this CVSS v3 severity (low, medium, high or critical) or higher.
+	MinSeverity string
+	// VulnerableOnly selects the software with at least one vulnerability.
+	VulnerableOnly bool
+	// Source selects the software of the given source, such as apps or
+	// deb_packages.
+	Source string
 }
 
 func (Software) AuthzType() string {
@@ -38,6 +102,22 @@ type HostSoftware struct {
 	Modified bool `json:"-"`
 }
 
+// DistroSoftware is a package of a Linux distribution along with the
+// operating system of a host it is installed on.
+type DistroSoftware struct {
+	Software
+	Platform     string `db:"platform"`
+	PlatformLike string `db:"platform_like"`
+	OSVersion    string `db:"os_version"`
+}
+
+// VulnerableHost is a host newly found to be affected by a CVE, queued to be
+// sent to the vulnerabilities webhook.
+type VulnerableHost struct {
+	HostID   uint   `db:"host_id"`
+	Hostname string `db:"hostname"`
+}
+
 type SoftwareIterator interface {
 	Next() bool
 	Value() (*Software, error)
@@ -22,6 +22,9 @@ type User struct {
 	SSOEnabled bool    `json:"sso_enabled" db:"sso_enabled"`
 	GlobalRole *string `json:"global_role" db:"global_role"`
 	APIOnly    bool    `json:"api_only" db:"api_only"`
+	// SCIMManaged is true if the user was provisioned through the SCIM API,
+	// which only manages these users.
+	SCIMManaged bool `json:"scim_managed" db:"scim_managed"`
 
 	// Teams is the teams this user has roles in.
 	Teams []UserTeam `json:"teams"`
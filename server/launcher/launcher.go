@@ -65,13 +65,14 @@ func (svc *launcherWrapper) RequestQueries(ctx context.Context, nodeKey string)
 		return nil, invalid, err
 	}
 
-	queryMap, accelerate, err := svc.tls.GetDistributedQueries(newCtx)
+	queryMap, discovery, accelerate, err := svc.tls.GetDistributedQueries(newCtx)
 	if err != nil {
 		return nil, false, errors.Wrap(err, "get queries for launcher")
 	}
 
 	result := &distributed.GetQueriesResult{
 		Queries:           queryMap,
+		Discovery:         discovery,
 		AccelerateSeconds: int(accelerate),
 	}
 
@@ -0,0 +1,319 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-kit/kit/metrics"
+	kitprometheus "github.com/go-kit/kit/metrics/prometheus"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	bufferedMinBackoff = 1 * time.Second
+	// bufferedDefaultWriteTimeout is how long the logs are written to the
+	// writer before they are queued, as the writers retry for a while.
+	bufferedDefaultWriteTimeout = 2 * time.Second
+	// bufferedSegmentExt is the extension of the segment files, named after
+	// their sequence number and their number of logs.
+	bufferedSegmentExt = ".json"
+)
+
+var (
+	bufferQueueLogs = kitprometheus.NewGaugeFrom(prometheus.GaugeOpts{
+		Namespace: "fleet",
+		Subsystem: "log_buffer",
+		Name:      "queue_logs",
+		Help:      "Number of osquery logs waiting in the disk queue of the log buffer.",
+	}, []string{"log_type", "plugin"})
+	bufferQueueBytes = kitprometheus.NewGaugeFrom(prometheus.GaugeOpts{
+		Namespace: "fleet",
+		Subsystem: "log_buffer",
+		Name:      "queue_bytes",
+		Help:      "Size in bytes of the disk queue of the log buffer.",
+	}, []string{"log_type", "plugin"})
+)
+
+// bufferedLogWriter writes the logs to another writer, and spills them to a
+// queue on disk when it fails or does not complete within the write timeout.
+// While the queue is not empty, the writer is considered failing and the logs
+// are added to the queue without trying the writer, and the queue is drained
+// in the background with backoff once the writer recovers. This way, the
+// hosts sending logs are not impacted by the outage of the destination of
+// the logs.
+type bufferedLogWriter struct {
+	writer       fleet.JSONLogger
+	dir          string
+	maxSize      int64
+	maxBackoff   time.Duration
+	writeTimeout time.Duration
+	logger       log.Logger
+	queueLogs    metrics.Gauge
+	queueBytes   metrics.Gauge
+
+	mu       sync.Mutex
+	segments []bufferedSegment
+	logs     int
+	size     int64
+	nextSeq  uint64
+
+	notify chan struct{}
+	done   chan struct{}
+}
+
+// bufferedSegment is a file of the queue, holding the logs of a failed
+// write.
+type bufferedSegment struct {
+	seq  uint64
+	logs int
+	size int64
+}
+
+func (s bufferedSegment) name() string {
+	return fmt.Sprintf("%020d-%d%s", s.seq, s.logs, bufferedSegmentExt)
+}
+
+// NewBufferedLogWriter returns a writer buffering the logs of writer, the
+// plugin for logType, in dir, with a queue of at most maxSize bytes. The logs
+// queued by a previous run are drained first.
+func NewBufferedLogWriter(
+	writer fleet.JSONLogger, dir, logType, plugin string, maxSize int64, maxBackoff, writeTimeout time.Duration, logger log.Logger,
+) (*bufferedLogWriter, error) {
+	return newBufferedLogWriter(
+		writer, dir, maxSize, maxBackoff, writeTimeout,
+		log.With(logger, "component", "log-buffer", "log_type", logType, "plugin", plugin),
+		bufferQueueLogs.With("log_type", logType, "plugin", plugin),
+		bufferQueueBytes.With("log_type", logType, "plugin", plugin),
+	)
+}
+
+func newBufferedLogWriter(
+	writer fleet.JSONLogger, dir string, maxSize int64, maxBackoff, writeTimeout time.Duration, logger log.Logger, queueLogs, queueBytes metrics.Gauge,
+) (*bufferedLogWriter, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, errors.Wrap(err, "create log buffer directory")
+	}
+	if maxBackoff < bufferedMinBackoff {
+		maxBackoff = bufferedMinBackoff
+	}
+	if writeTimeout <= 0 {
+		writeTimeout = bufferedDefaultWriteTimeout
+	}
+
+	b := &bufferedLogWriter{
+		writer:       writer,
+		dir:          dir,
+		maxSize:      maxSize,
+		maxBackoff:   maxBackoff,
+		writeTimeout: writeTimeout,
+		logger:       logger,
+		queueLogs:    queueLogs,
+		queueBytes:   queueBytes,
+		notify:       make(chan struct{}, 1),
+		done:         make(chan struct{}),
+	}
+	if err := b.loadSegments(); err != nil {
+		return nil, errors.Wrap(err, "load log buffer")
+	}
+	go b.drain()
+	return b, nil
+}
+
+// loadSegments loads the segments left in the queue by a previous run.
+func (b *bufferedLogWriter) loadSegments() error {
+	files, err := ioutil.ReadDir(b.dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := file.Name()
+		if strings.HasSuffix(name, ".tmp") {
+			// Partially written segment.
+			os.Remove(filepath.Join(b.dir, name))
+			continue
+		}
+		if file.IsDir() || !strings.HasSuffix(name, bufferedSegmentExt) {
+			continue
+		}
+		parts := strings.SplitN(strings.TrimSuffix(name, bufferedSegmentExt), "-", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		seq, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			continue
+		}
+		logs, err := strconv.Atoi(parts[1])
+		if err != nil {
+			continue
+		}
+		b.segments = append(b.segments, bufferedSegment{seq: seq, logs: logs, size: file.Size()})
+		b.logs += logs
+		b.size += file.Size()
+		if seq >= b.nextSeq {
+			b.nextSeq = seq + 1
+		}
+	}
+	sort.Slice(b.segments, func(i, j int) bool { return b.segments[i].seq < b.segments[j].seq })
+	b.updateMetrics()
+	if len(b.segments) > 0 {
+		level.Info(b.logger).Log("msg", "draining logs queued by a previous run", "segments", len(b.segments))
+	}
+	return nil
+}
+
+func (b *bufferedLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	if len(logs) == 0 {
+		return nil
+	}
+
+	// The logs are queued after the logs already in the queue, to keep
+	// their order and not wait for a writer that is known to fail.
+	b.mu.Lock()
+	queued := len(b.segments) > 0
+	b.mu.Unlock()
+	if !queued {
+		// The writer is given a short time, so that the hosts do not wait
+		// for its retries. The logs it may still write after the timeout
+		// are duplicated once the queue is drained.
+		writeCtx, cancel := context.WithTimeout(ctx, b.writeTimeout)
+		err := b.writer.Write(writeCtx, logs)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		level.Info(b.logger).Log("msg", "write failed, queueing logs", "err", err)
+	}
+
+	return b.enqueue(logs)
+}
+
+// enqueue adds the logs to the queue. It fails when the queue is full, so
+// that the logs are sent again later.
+func (b *bufferedLogWriter) enqueue(logs []json.RawMessage) error {
+	data, err := json.Marshal(logs)
+	if err != nil {
+		return errors.Wrap(err, "encode logs")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.size+int64(len(data)) > b.maxSize {
+		return errors.Errorf("log buffer is full (%d bytes)", b.size)
+	}
+
+	segment := bufferedSegment{seq: b.nextSeq, logs: len(logs), size: int64(len(data))}
+	// The segment is written to a temporary file first, so that partially
+	// written segments are not loaded after a crash.
+	path := filepath.Join(b.dir, segment.name())
+	if err := ioutil.WriteFile(path+".tmp", data, 0o600); err != nil {
+		return errors.Wrap(err, "write log buffer segment")
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return errors.Wrap(err, "write log buffer segment")
+	}
+	b.nextSeq++
+	b.segments = append(b.segments, segment)
+	b.logs += segment.logs
+	b.size += segment.size
+	b.updateMetrics()
+
+	select {
+	case b.notify <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// drain writes the segments of the queue in order, backing off while the
+// writer fails.
+func (b *bufferedLogWriter) drain() {
+	backoff := bufferedMinBackoff
+	for {
+		b.mu.Lock()
+		empty := len(b.segments) == 0
+		var segment bufferedSegment
+		if !empty {
+			segment = b.segments[0]
+		}
+		b.mu.Unlock()
+
+		if empty {
+			select {
+			case <-b.notify:
+				continue
+			case <-b.done:
+				return
+			}
+		}
+
+		if err := b.writeSegment(segment); err != nil {
+			level.Info(b.logger).Log("msg", "drain log buffer", "err", err, "retry_in", backoff)
+			select {
+			case <-time.After(backoff):
+			case <-b.done:
+				return
+			}
+			if backoff *= 2; backoff > b.maxBackoff {
+				backoff = b.maxBackoff
+			}
+			continue
+		}
+		backoff = bufferedMinBackoff
+		b.removeSegment(segment)
+	}
+}
+
+func (b *bufferedLogWriter) writeSegment(segment bufferedSegment) error {
+	data, err := ioutil.ReadFile(filepath.Join(b.dir, segment.name()))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "read log buffer segment")
+	}
+	var logs []json.RawMessage
+	if err := json.Unmarshal(data, &logs); err != nil {
+		// Corrupted segments would block the queue forever.
+		level.Error(b.logger).Log("msg", "dropping corrupted log buffer segment", "segment", segment.name(), "err", err)
+		return nil
+	}
+	return b.writer.Write(context.Background(), logs)
+}
+
+func (b *bufferedLogWriter) removeSegment(segment bufferedSegment) {
+	if err := os.Remove(filepath.Join(b.dir, segment.name())); err != nil && !os.IsNotExist(err) {
+		level.Error(b.logger).Log("msg", "remove log buffer segment", "segment", segment.name(), "err", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.segments = b.segments[1:]
+	b.logs -= segment.logs
+	b.size -= segment.size
+	b.updateMetrics()
+}
+
+// updateMetrics sets the depth of the queue. b.mu must be held.
+func (b *bufferedLogWriter) updateMetrics() {
+	b.queueLogs.Set(float64(b.logs))
+	b.queueBytes.Set(float64(b.size))
+}
+
+// stop stops draining the queue.
+func (b *bufferedLogWriter) stop() {
+	close(b.done)
+}
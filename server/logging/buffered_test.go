@@ -0,0 +1,182 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/metrics/generic"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFailingWriter collects the logs written to it, and fails while it is
+// down.
+type testFailingWriter struct {
+	mu   sync.Mutex
+	down bool
+	logs []json.RawMessage
+}
+
+func (w *testFailingWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.down {
+		return errors.New("backend down")
+	}
+	w.logs = append(w.logs, logs...)
+	return nil
+}
+
+func (w *testFailingWriter) setDown(down bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.down = down
+}
+
+func (w *testFailingWriter) writtenLogs() []json.RawMessage {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]json.RawMessage(nil), w.logs...)
+}
+
+// testGauges are the depth of the queue of a test writer.
+type testGauges struct {
+	logs  *generic.Gauge
+	bytes *generic.Gauge
+}
+
+func newTestBufferedWriter(t *testing.T, writer *testFailingWriter, dir string, maxSize int64) (*bufferedLogWriter, testGauges) {
+	gauges := testGauges{logs: generic.NewGauge("logs"), bytes: generic.NewGauge("bytes")}
+	b, err := newBufferedLogWriter(writer, dir, maxSize, time.Second, time.Second, log.NewNopLogger(), gauges.logs, gauges.bytes)
+	require.NoError(t, err)
+	t.Cleanup(b.stop)
+	return b, gauges
+}
+
+func waitForQueue(t *testing.T, b *bufferedLogWriter, segments int) {
+	require.Eventually(t, func() bool {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		return len(b.segments) == segments
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestBufferedWriteThrough(t *testing.T) {
+	writer := &testFailingWriter{}
+	b, _ := newTestBufferedWriter(t, writer, t.TempDir(), 1<<20)
+
+	require.NoError(t, b.Write(context.Background(), logs))
+	assert.Equal(t, logs, writer.writtenLogs())
+	waitForQueue(t, b, 0)
+}
+
+func TestBufferedSpillAndDrain(t *testing.T) {
+	dir := t.TempDir()
+	writer := &testFailingWriter{down: true}
+	b, gauges := newTestBufferedWriter(t, writer, dir, 1<<20)
+
+	// The writes succeed while the backend is down.
+	more := []json.RawMessage{json.RawMessage(`{"more":"logs"}`)}
+	require.NoError(t, b.Write(context.Background(), logs))
+	require.NoError(t, b.Write(context.Background(), more))
+	b.mu.Lock()
+	assert.Len(t, b.segments, 2)
+	assert.Equal(t, float64(len(logs)+1), gauges.logs.Value())
+	assert.Equal(t, float64(b.size), gauges.bytes.Value())
+	b.mu.Unlock()
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+
+	// The queue is drained in order once the backend recovers.
+	writer.setDown(false)
+	waitForQueue(t, b, 0)
+	assert.Zero(t, gauges.logs.Value())
+	assert.Zero(t, gauges.bytes.Value())
+	// The logs are compacted in the queue.
+	expected, err := json.Marshal(append(append([]json.RawMessage{}, logs...), more...))
+	require.NoError(t, err)
+	actual, err := json.Marshal(writer.writtenLogs())
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+	files, err = filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+// testHangingWriter blocks until the context of the write is done, as a
+// writer retrying an unavailable backend.
+type testHangingWriter struct {
+	mu    sync.Mutex
+	calls int
+}
+
+func (w *testHangingWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	w.mu.Lock()
+	w.calls++
+	w.mu.Unlock()
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (w *testHangingWriter) writeCalls() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.calls
+}
+
+func TestBufferedWriteTimeout(t *testing.T) {
+	writer := &testHangingWriter{}
+	gauges := testGauges{logs: generic.NewGauge("logs"), bytes: generic.NewGauge("bytes")}
+	b, err := newBufferedLogWriter(writer, t.TempDir(), 1<<20, time.Minute, 50*time.Millisecond, log.NewNopLogger(), gauges.logs, gauges.bytes)
+	require.NoError(t, err)
+	defer b.stop()
+
+	// The logs are queued once the write times out.
+	start := time.Now()
+	require.NoError(t, b.Write(context.Background(), logs))
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+	assert.Equal(t, float64(len(logs)), gauges.logs.Value())
+
+	// While the writer fails, the logs are queued without trying it. Only
+	// the first write and the drain of the queue call it.
+	calls := writer.writeCalls()
+	require.NoError(t, b.Write(context.Background(), logs))
+	assert.LessOrEqual(t, writer.writeCalls(), calls+1)
+	assert.Equal(t, float64(2*len(logs)), gauges.logs.Value())
+}
+
+func TestBufferedQueueFull(t *testing.T) {
+	writer := &testFailingWriter{down: true}
+	b, _ := newTestBufferedWriter(t, writer, t.TempDir(), 60)
+
+	require.NoError(t, b.Write(context.Background(), logs))
+	// The queue is full, the logs must be sent again later.
+	require.Error(t, b.Write(context.Background(), logs))
+}
+
+func TestBufferedReload(t *testing.T) {
+	dir := t.TempDir()
+	writer := &testFailingWriter{down: true}
+	b, err := NewBufferedLogWriter(writer, dir, "result", "kinesis", 1<<20, time.Second, time.Second, log.NewNopLogger())
+	require.NoError(t, err)
+	require.NoError(t, b.Write(context.Background(), logs))
+	b.stop()
+
+	// A partially written segment is ignored.
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00000000000000000001-1.json.tmp"), []byte(`[{"partial`), 0o600))
+
+	// The queue of the previous run is drained by the next one.
+	writer = &testFailingWriter{}
+	b, _ = newTestBufferedWriter(t, writer, dir, 1<<20)
+	waitForQueue(t, b, 0)
+	assert.Len(t, writer.writtenLogs(), len(logs))
+	assert.NoFileExists(t, filepath.Join(dir, "00000000000000000001-1.json.tmp"))
+}
@@ -0,0 +1,195 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+const (
+	elasticsearchMaxRetries = 8
+
+	// Batches are kept well under the default http.max_content_length of
+	// Elasticsearch, as recommended for bulk requests.
+	elasticsearchMaxDocumentsInBatch = 500
+	elasticsearchMaxSizeOfBatch      = 5 * 1000 * 1000 // 5 MB
+)
+
+type elasticsearchLogWriter struct {
+	client   *http.Client
+	url      string
+	username string
+	password string
+	apiKey   string
+	// action is the line preceding each document in the bulk requests.
+	action []byte
+	logger log.Logger
+}
+
+// elasticsearchBulkResponse is the response of a bulk request. Each item is
+// the result of the action on a document, keyed by the action.
+type elasticsearchBulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+func NewElasticsearchLogWriter(conf config.ElasticsearchConfig, index string, logger log.Logger) (*elasticsearchLogWriter, error) {
+	u, err := url.Parse(conf.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Errorf("invalid Elasticsearch URL: %q", conf.URL)
+	}
+	if index == "" {
+		return nil, errors.New("elasticsearch index is required")
+	}
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = httpDefaultTimeout
+	}
+
+	// Documents are created rather than indexed, so that the index can be
+	// a data stream.
+	action, err := json.Marshal(map[string]map[string]string{"create": {"_index": index}})
+	if err != nil {
+		return nil, errors.Wrap(err, "encode bulk action")
+	}
+
+	return &elasticsearchLogWriter{
+		client:   &http.Client{Timeout: timeout},
+		url:      strings.TrimSuffix(conf.URL, "/") + "/_bulk",
+		username: conf.Username,
+		password: conf.Password,
+		apiKey:   conf.APIKey,
+		action:   action,
+		logger:   logger,
+	}, nil
+}
+
+func (e *elasticsearchLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	var docs [][]byte
+	totalBytes := 0
+	for _, log := range logs {
+		// The bulk API is line delimited, so that the documents must be on
+		// a single line.
+		doc := []byte(log)
+		if bytes.ContainsAny(doc, "\r\n") {
+			var buf bytes.Buffer
+			if err := json.Compact(&buf, doc); err != nil {
+				return errors.Wrap(err, "compact log")
+			}
+			doc = buf.Bytes()
+		}
+		size := len(e.action) + len(doc) + 2
+
+		if len(docs) > 0 && (len(docs) >= elasticsearchMaxDocumentsInBatch || totalBytes+size > elasticsearchMaxSizeOfBatch) {
+			if err := e.bulk(ctx, 0, docs); err != nil {
+				return errors.Wrap(err, "bulk index")
+			}
+			totalBytes = 0
+			docs = nil
+		}
+
+		docs = append(docs, doc)
+		totalBytes += size
+	}
+
+	// Send the final batch
+	if len(docs) > 0 {
+		if err := e.bulk(ctx, 0, docs); err != nil {
+			return errors.Wrap(err, "bulk index")
+		}
+	}
+
+	return nil
+}
+
+func (e *elasticsearchLogWriter) bulk(ctx context.Context, try int, docs [][]byte) error {
+	if try > 0 {
+		if err := waitForRetry(ctx, try); err != nil {
+			return err
+		}
+	}
+
+	var body bytes.Buffer
+	for _, doc := range docs {
+		body.Write(e.action)
+		body.WriteByte('\n')
+		body.Write(doc)
+		body.WriteByte('\n')
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, &body)
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if e.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+e.apiKey)
+	} else if e.username != "" {
+		req.SetBasicAuth(e.username, e.password)
+	}
+
+	respBody, err := doLogRequest(e.client, req)
+	if err != nil {
+		if _, ok := err.(httpRetriableError); ok && try < elasticsearchMaxRetries {
+			// Retry with backoff
+			return e.bulk(ctx, try+1, docs)
+		}
+		return err
+	}
+
+	var resp elasticsearchBulkResponse
+	if err := json.Unmarshal(respBody, &resp); err != nil {
+		return errors.Wrap(err, "decode bulk response")
+	}
+	if !resp.Errors {
+		return nil
+	}
+	if len(resp.Items) != len(docs) {
+		return errors.Errorf("bulk response has %d items for %d documents", len(resp.Items), len(docs))
+	}
+
+	// Check errors on individual documents. The documents rejected because
+	// of the load of the cluster are retried, the others are dropped as
+	// they would be rejected again.
+	var failedDocs [][]byte
+	var firstErr json.RawMessage
+	for i, item := range resp.Items {
+		for _, result := range item {
+			switch {
+			case result.Status < 300:
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				failedDocs = append(failedDocs, docs[i])
+				if firstErr == nil {
+					firstErr = result.Error
+				}
+			default:
+				level.Info(e.logger).Log(
+					"msg", "dropping log rejected by Elasticsearch",
+					"status", result.Status,
+					"err", string(result.Error),
+					"log", truncateLog(docs[i]),
+				)
+			}
+		}
+	}
+	if len(failedDocs) == 0 {
+		return nil
+	}
+	if try >= elasticsearchMaxRetries {
+		return errors.Errorf(
+			"failed to index %d documents, retries exhausted. First error: %s",
+			len(failedDocs), firstErr,
+		)
+	}
+	return e.bulk(ctx, try+1, failedDocs)
+}
@@ -0,0 +1,150 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testElasticsearchCluster is a stand-in Elasticsearch cluster, that
+// collects the documents created with bulk requests. The documents are
+// rejected with the statuses set for their "status" field, the first times
+// they are sent.
+type testElasticsearchCluster struct {
+	mu       sync.Mutex
+	calls    int
+	indexes  map[string]bool
+	docs     []json.RawMessage
+	rejected map[string]int
+}
+
+func (c *testElasticsearchCluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if username, password, _ := r.BasicAuth(); username != "elastic" || password != "secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var resp elasticsearchBulkResponse
+	scanner := bufio.NewScanner(r.Body)
+	scanner.Buffer(nil, elasticsearchMaxSizeOfBatch)
+	for scanner.Scan() {
+		var action map[string]map[string]string
+		if err := json.Unmarshal(scanner.Bytes(), &action); err != nil || !scanner.Scan() {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		c.indexes[action["create"]["_index"]] = true
+		doc := json.RawMessage(append([]byte(nil), scanner.Bytes()...))
+
+		var fields struct {
+			Status int `json:"status"`
+		}
+		json.Unmarshal(doc, &fields)
+		status := http.StatusCreated
+		if fields.Status != 0 && c.rejected[string(doc)] < 2 {
+			c.rejected[string(doc)]++
+			status = fields.Status
+			resp.Errors = true
+		} else {
+			c.docs = append(c.docs, doc)
+		}
+		item := map[string]struct {
+			Status int             `json:"status"`
+			Error  json.RawMessage `json:"error"`
+		}{}
+		result := item["create"]
+		result.Status = status
+		if status != http.StatusCreated {
+			result.Error = json.RawMessage(`{"type":"error","reason":"rejected"}`)
+		}
+		item["create"] = result
+		resp.Items = append(resp.Items, item)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (c *testElasticsearchCluster) indexedLogs() []json.RawMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.docs
+}
+
+func newTestElasticsearchWriter(t *testing.T) (*elasticsearchLogWriter, *testElasticsearchCluster) {
+	cluster := &testElasticsearchCluster{indexes: make(map[string]bool), rejected: make(map[string]int)}
+	server := httptest.NewServer(cluster)
+	t.Cleanup(server.Close)
+
+	conf := config.ElasticsearchConfig{URL: server.URL, Username: "elastic", Password: "secret"}
+	writer, err := NewElasticsearchLogWriter(conf, "osquery_result", log.NewNopLogger())
+	require.NoError(t, err)
+	return writer, cluster
+}
+
+func TestElasticsearchWrite(t *testing.T) {
+	writer, cluster := newTestElasticsearchWriter(t)
+
+	pretty := json.RawMessage("{\n  \"foo\": \"bar\"\n}")
+	require.NoError(t, writer.Write(context.Background(), append(logs, pretty)))
+	assert.Equal(t, append(logs, json.RawMessage(`{"foo":"bar"}`)), cluster.indexedLogs())
+	assert.Equal(t, map[string]bool{"osquery_result": true}, cluster.indexes)
+
+	writer.password = "wrong"
+	require.Error(t, writer.Write(context.Background(), logs))
+}
+
+func TestElasticsearchPartialFailure(t *testing.T) {
+	writer, cluster := newTestElasticsearchWriter(t)
+
+	logs := []json.RawMessage{
+		json.RawMessage(`{"i":0}`),
+		json.RawMessage(`{"i":1,"status":429}`),
+		json.RawMessage(`{"i":2,"status":400}`),
+		json.RawMessage(`{"i":3,"status":503}`),
+	}
+	require.NoError(t, writer.Write(context.Background(), logs))
+	// The documents rejected by the load of the cluster are retried, the
+	// others are dropped.
+	assert.Equal(t, []json.RawMessage{logs[0], logs[1], logs[3]}, cluster.indexedLogs())
+	assert.Equal(t, 3, cluster.calls)
+}
+
+func TestElasticsearchBatching(t *testing.T) {
+	writer, cluster := newTestElasticsearchWriter(t)
+
+	var logs []json.RawMessage
+	for i := 0; i < elasticsearchMaxDocumentsInBatch+10; i++ {
+		logs = append(logs, json.RawMessage(`{"i":`+strconv.Itoa(i)+`}`))
+	}
+	// Fill a batch by size.
+	big := json.RawMessage(`"` + string(bytes.Repeat([]byte("b"), elasticsearchMaxSizeOfBatch/2)) + `"`)
+	logs = append(logs, big, big)
+
+	require.NoError(t, writer.Write(context.Background(), logs))
+	assert.Equal(t, 3, cluster.calls)
+	assert.Equal(t, logs, cluster.indexedLogs())
+}
+
+func TestNewElasticsearchLogWriterInvalid(t *testing.T) {
+	_, err := NewElasticsearchLogWriter(config.ElasticsearchConfig{}, "osquery_result", log.NewNopLogger())
+	require.Error(t, err)
+	_, err = NewElasticsearchLogWriter(config.ElasticsearchConfig{URL: "http://localhost:9200"}, "", log.NewNopLogger())
+	require.Error(t, err)
+}
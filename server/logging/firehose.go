@@ -3,8 +3,6 @@ package logging
 import (
 	"context"
 	"encoding/json"
-	"math"
-	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/awserr"
@@ -120,7 +118,7 @@ func (f *firehoseLogWriter) Write(ctx context.Context, logs []json.RawMessage) e
 		// adding any more.
 		if len(records) >= firehoseMaxRecordsInBatch ||
 			totalBytes+len(log) > firehoseMaxSizeOfBatch {
-			if err := f.putRecordBatch(0, records); err != nil {
+			if err := f.putRecordBatch(ctx, 0, records); err != nil {
 				return errors.Wrap(err, "put records")
 			}
 			totalBytes = 0
@@ -133,7 +131,7 @@ func (f *firehoseLogWriter) Write(ctx context.Context, logs []json.RawMessage) e
 
 	// Push the final batch
 	if len(records) > 0 {
-		if err := f.putRecordBatch(0, records); err != nil {
+		if err := f.putRecordBatch(ctx, 0, records); err != nil {
 			return errors.Wrap(err, "put records")
 		}
 	}
@@ -141,21 +139,23 @@ func (f *firehoseLogWriter) Write(ctx context.Context, logs []json.RawMessage) e
 	return nil
 }
 
-func (f *firehoseLogWriter) putRecordBatch(try int, records []*firehose.Record) error {
+func (f *firehoseLogWriter) putRecordBatch(ctx context.Context, try int, records []*firehose.Record) error {
 	if try > 0 {
-		time.Sleep(100 * time.Millisecond * time.Duration(math.Pow(2.0, float64(try))))
+		if err := waitForRetry(ctx, try); err != nil {
+			return err
+		}
 	}
 	input := &firehose.PutRecordBatchInput{
 		DeliveryStreamName: &f.stream,
 		Records:            records,
 	}
 
-	output, err := f.client.PutRecordBatch(input)
+	output, err := f.client.PutRecordBatchWithContext(ctx, input)
 	if err != nil {
 		if aerr, ok := err.(awserr.Error); ok {
 			if aerr.Code() == firehose.ErrCodeServiceUnavailableException && try < firehoseMaxRetries {
 				// Retry with backoff
-				return f.putRecordBatch(try+1, records)
+				return f.putRecordBatch(ctx, try+1, records)
 			}
 		}
 
@@ -191,7 +191,7 @@ func (f *firehoseLogWriter) putRecordBatch(try int, records []*firehose.Record)
 			}
 		}
 
-		return f.putRecordBatch(try+1, failedRecords)
+		return f.putRecordBatch(ctx, try+1, failedRecords)
 	}
 
 	return nil
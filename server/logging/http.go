@@ -0,0 +1,193 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+)
+
+const (
+	httpMaxRetries = 8
+
+	// Logs are posted in batches, as JSON arrays.
+	httpMaxLogsInBatch  = 500
+	httpMaxSizeOfBatch  = 5 * 1000 * 1000 // 5 MB
+	httpDefaultTimeout  = 10 * time.Second
+	httpMaxErrorBodyLen = 512
+)
+
+type httpLogWriter struct {
+	client  *http.Client
+	url     string
+	headers http.Header
+	gzip    bool
+	logger  log.Logger
+}
+
+func NewHTTPLogWriter(conf config.HTTPLogConfig, logURL string, logger log.Logger) (*httpLogWriter, error) {
+	u, err := url.Parse(logURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Errorf("invalid log URL: %q", logURL)
+	}
+	headers, err := parseHTTPLogHeaders(conf.Headers)
+	if err != nil {
+		return nil, err
+	}
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = httpDefaultTimeout
+	}
+
+	return &httpLogWriter{
+		client:  &http.Client{Timeout: timeout},
+		url:     logURL,
+		headers: headers,
+		gzip:    conf.Gzip,
+		logger:  logger,
+	}, nil
+}
+
+// parseHTTPLogHeaders parses the comma-separated list of "Name: value"
+// headers.
+func parseHTTPLogHeaders(s string) (http.Header, error) {
+	headers := make(http.Header)
+	for _, header := range strings.Split(s, ",") {
+		if strings.TrimSpace(header) == "" {
+			continue
+		}
+		parts := strings.SplitN(header, ":", 2)
+		name := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || name == "" {
+			return nil, errors.Errorf("invalid header %q, expected \"Name: value\"", header)
+		}
+		headers.Add(name, strings.TrimSpace(parts[1]))
+	}
+	return headers, nil
+}
+
+func (h *httpLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	var batch []json.RawMessage
+	totalBytes := 0
+	for _, log := range logs {
+		// If adding this log will exceed the limit on number of logs in
+		// the batch, or the limit on total size of the batch, we need to
+		// post this batch before adding any more.
+		if len(batch) > 0 && (len(batch) >= httpMaxLogsInBatch || totalBytes+len(log)+1 > httpMaxSizeOfBatch) {
+			if err := h.post(ctx, 0, batch); err != nil {
+				return errors.Wrap(err, "post logs")
+			}
+			totalBytes = 0
+			batch = nil
+		}
+
+		batch = append(batch, log)
+		totalBytes += len(log) + 1
+	}
+
+	// Post the final batch
+	if len(batch) > 0 {
+		if err := h.post(ctx, 0, batch); err != nil {
+			return errors.Wrap(err, "post logs")
+		}
+	}
+
+	return nil
+}
+
+// httpRetriableError is an error that is worth retrying: a network error,
+// or a server error or rate limit response.
+type httpRetriableError struct {
+	error
+}
+
+func (h *httpLogWriter) post(ctx context.Context, try int, batch []json.RawMessage) error {
+	if try > 0 {
+		if err := waitForRetry(ctx, try); err != nil {
+			return err
+		}
+	}
+
+	err := h.send(ctx, batch)
+	if _, ok := err.(httpRetriableError); ok && try < httpMaxRetries {
+		// Retry with backoff
+		return h.post(ctx, try+1, batch)
+	}
+	return err
+}
+
+func (h *httpLogWriter) send(ctx context.Context, batch []json.RawMessage) error {
+	// The logs are already JSON, and are posted as they were received.
+	body := append([]byte{'['}, bytes.Join(rawMessagesToBytes(batch), []byte{','})...)
+	body = append(body, ']')
+	if h.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return errors.Wrap(err, "compress logs")
+		}
+		if err := zw.Close(); err != nil {
+			return errors.Wrap(err, "compress logs")
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	for name, values := range h.headers {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+
+	_, err = doLogRequest(h.client, req)
+	return err
+}
+
+// doLogRequest sends the request and returns the body of the response. The
+// body is also returned with the error of a request that failed with an error
+// status, as some destinations describe the error in it. Network errors and
+// server errors or rate limit responses are returned as httpRetriableError.
+func doLogRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, httpRetriableError{err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, httpRetriableError{errors.Wrap(err, "read response")}
+		}
+		return body, nil
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, httpMaxErrorBodyLen))
+	err = errors.Errorf("%s returned %d: %s", req.URL.Redacted(), resp.StatusCode, strings.TrimSpace(string(body)))
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		return body, httpRetriableError{err}
+	}
+	return body, err
+}
+
+func rawMessagesToBytes(logs []json.RawMessage) [][]byte {
+	b := make([][]byte, len(logs))
+	for i, log := range logs {
+		b[i] = log
+	}
+	return b
+}
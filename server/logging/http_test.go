@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testHTTPLogServer collects the batches of logs posted to it, after
+// returning the given status codes in turn.
+type testHTTPLogServer struct {
+	mu       sync.Mutex
+	statuses []int
+	calls    int
+	batches  [][]json.RawMessage
+	headers  http.Header
+}
+
+func (s *testHTTPLogServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	s.headers = r.Header
+	if len(s.statuses) > 0 {
+		var status int
+		status, s.statuses = s.statuses[0], s.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if body, err = ioutil.ReadAll(zr); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	s.batches = append(s.batches, batch)
+}
+
+func (s *testHTTPLogServer) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *testHTTPLogServer) postedLogs() []json.RawMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var logs []json.RawMessage
+	for _, batch := range s.batches {
+		logs = append(logs, batch...)
+	}
+	return logs
+}
+
+func TestHTTPWrite(t *testing.T) {
+	handler := &testHTTPLogServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	conf := config.HTTPLogConfig{
+		Headers: "Authorization: Bearer token, X-Source:fleet",
+		Gzip:    true,
+	}
+	writer, err := NewHTTPLogWriter(conf, server.URL, log.NewNopLogger())
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write(context.Background(), logs))
+	assert.Equal(t, logs, handler.postedLogs())
+	assert.Equal(t, "Bearer token", handler.headers.Get("Authorization"))
+	assert.Equal(t, "fleet", handler.headers.Get("X-Source"))
+	assert.Equal(t, "gzip", handler.headers.Get("Content-Encoding"))
+	assert.Equal(t, "application/json", handler.headers.Get("Content-Type"))
+}
+
+func TestHTTPRetryableFailure(t *testing.T) {
+	handler := &testHTTPLogServer{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	writer, err := NewHTTPLogWriter(config.HTTPLogConfig{}, server.URL, log.NewNopLogger())
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write(context.Background(), logs))
+	assert.Equal(t, 3, handler.callCount())
+	assert.Equal(t, logs, handler.postedLogs())
+
+	// Client errors are not retried.
+	handler.mu.Lock()
+	handler.statuses = []int{http.StatusUnauthorized}
+	handler.mu.Unlock()
+	require.Error(t, writer.Write(context.Background(), logs))
+	assert.Equal(t, 4, handler.callCount())
+}
+
+func TestHTTPBatching(t *testing.T) {
+	handler := &testHTTPLogServer{}
+	server := httptest.NewServer(handler)
+	defer server.Close()
+
+	writer, err := NewHTTPLogWriter(config.HTTPLogConfig{}, server.URL, log.NewNopLogger())
+	require.NoError(t, err)
+
+	var logs []json.RawMessage
+	for i := 0; i < httpMaxLogsInBatch+10; i++ {
+		logs = append(logs, json.RawMessage(`{"i":`+strconv.Itoa(i)+`}`))
+	}
+	require.NoError(t, writer.Write(context.Background(), logs))
+	require.Len(t, handler.batches, 2)
+	assert.Len(t, handler.batches[0], httpMaxLogsInBatch)
+	assert.Len(t, handler.batches[1], 10)
+	assert.Equal(t, logs, handler.postedLogs())
+}
+
+func TestNewHTTPLogWriterInvalid(t *testing.T) {
+	_, err := NewHTTPLogWriter(config.HTTPLogConfig{}, "", log.NewNopLogger())
+	require.Error(t, err)
+	_, err = NewHTTPLogWriter(config.HTTPLogConfig{}, "ftp://example.com", log.NewNopLogger())
+	require.Error(t, err)
+	_, err = NewHTTPLogWriter(config.HTTPLogConfig{Headers: "Authorization"}, "https://example.com", log.NewNopLogger())
+	require.Error(t, err)
+}
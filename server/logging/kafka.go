@@ -0,0 +1,201 @@
+package logging
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+)
+
+const (
+	// kafkaMaxAttempts is the number of times the writer sends a batch of
+	// records before giving up, with a backoff of up to a second.
+	kafkaMaxAttempts = 3
+
+	// The size of a batch is kept under the default max.message.bytes of
+	// the topics, with the overhead of the records format.
+	kafkaMaxRecordsInBatch = 500
+	kafkaMaxSizeOfBatch    = 1000 * 1000 // 1,000 KB
+	kafkaRecordOverhead    = 32
+
+	// kafkaBatchTimeout is how long the writer waits for a batch to fill up
+	// before sending it, the batches are already built by the log writer.
+	kafkaBatchTimeout = 10 * time.Millisecond
+)
+
+// kafkaProducer sends records to a Kafka topic.
+type kafkaProducer interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+}
+
+type kafkaLogWriter struct {
+	client kafkaProducer
+	topic  string
+	logger log.Logger
+}
+
+func NewKafkaLogWriter(conf config.KafkaConfig, topic string, logger log.Logger) (*kafkaLogWriter, error) {
+	var brokers []string
+	for _, broker := range strings.Split(conf.Brokers, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			brokers = append(brokers, broker)
+		}
+	}
+	if len(brokers) == 0 {
+		return nil, errors.New("no kafka broker configured")
+	}
+
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	dialer := &kafka.Dialer{
+		ClientID:  "fleet",
+		Timeout:   timeout,
+		DualStack: true,
+	}
+	if conf.UseTLS {
+		var err error
+		dialer.TLS, err = kafkaTLSConfig(conf)
+		if err != nil {
+			return nil, errors.Wrap(err, "create Kafka TLS config")
+		}
+	}
+	mechanism, err := kafkaSASLMechanism(conf)
+	if err != nil {
+		return nil, err
+	}
+	dialer.SASLMechanism = mechanism
+
+	if err := validateKafkaTopic(dialer, brokers, topic); err != nil {
+		return nil, errors.Wrap(err, "create Kafka writer")
+	}
+
+	client := kafka.NewWriter(kafka.WriterConfig{
+		Brokers:      brokers,
+		Topic:        topic,
+		Dialer:       dialer,
+		MaxAttempts:  kafkaMaxAttempts,
+		BatchSize:    kafkaMaxRecordsInBatch,
+		BatchBytes:   kafkaMaxSizeOfBatch,
+		BatchTimeout: kafkaBatchTimeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		// Wait for all the in-sync replicas to acknowledge the records.
+		RequiredAcks: -1,
+	})
+	return &kafkaLogWriter{client: client, topic: topic, logger: logger}, nil
+}
+
+func kafkaTLSConfig(conf config.KafkaConfig) (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: conf.TLSServerName}
+	if conf.TLSCA != "" {
+		pem, err := ioutil.ReadFile(conf.TLSCA)
+		if err != nil {
+			return nil, errors.Wrap(err, "read CA certificate")
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("failed to append CA certificate")
+		}
+	}
+	if conf.TLSCert != "" {
+		cert, err := tls.LoadX509KeyPair(conf.TLSCert, conf.TLSKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "load client certificate and key")
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// kafkaSASLMechanism returns the SASL mechanism used to authenticate to the
+// brokers, or nil if authentication is disabled.
+func kafkaSASLMechanism(conf config.KafkaConfig) (sasl.Mechanism, error) {
+	if conf.SASLMechanism == "" {
+		return nil, nil
+	}
+	if conf.SASLUsername == "" {
+		return nil, errors.New("kafka SASL username is required")
+	}
+	switch conf.SASLMechanism {
+	case "PLAIN":
+		return plain.Mechanism{Username: conf.SASLUsername, Password: conf.SASLPassword}, nil
+	case "SCRAM-SHA-256":
+		mechanism, err := scram.Mechanism(scram.SHA256, conf.SASLUsername, conf.SASLPassword)
+		return mechanism, errors.Wrap(err, "create Kafka SCRAM-SHA-256 mechanism")
+	case "SCRAM-SHA-512":
+		mechanism, err := scram.Mechanism(scram.SHA512, conf.SASLUsername, conf.SASLPassword)
+		return mechanism, errors.Wrap(err, "create Kafka SCRAM-SHA-512 mechanism")
+	default:
+		return nil, errors.Errorf("unsupported kafka SASL mechanism: %s", conf.SASLMechanism)
+	}
+}
+
+// validateKafkaTopic checks that the topic exists, and that one of the
+// brokers can be reached with the configured credentials.
+func validateKafkaTopic(dialer *kafka.Dialer, brokers []string, topic string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dialer.Timeout)
+	defer cancel()
+	var err error
+	for _, broker := range brokers {
+		if _, err = dialer.LookupPartitions(ctx, "tcp", broker, topic); err == nil {
+			return nil
+		}
+	}
+	return errors.Wrapf(err, "describe topic %s", topic)
+}
+
+func (k *kafkaLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	var records []kafka.Message
+	totalBytes := 0
+	for _, log := range logs {
+		// As for Kinesis, logs that are too big are dropped, with their
+		// beginning to help diagnose the query generating them.
+		if len(log)+kafkaRecordOverhead > kafkaMaxSizeOfBatch {
+			level.Info(k.logger).Log(
+				"msg", "dropping log over 1MB Kafka limit",
+				"size", len(log),
+				"log", string(log[:100])+"...",
+			)
+			continue
+		}
+
+		// If adding this log will exceed the limit on number of
+		// records in the batch, or the limit on total size of the
+		// records in the batch, we need to push this batch before
+		// adding any more.
+		if len(records) >= kafkaMaxRecordsInBatch ||
+			totalBytes+len(log)+kafkaRecordOverhead > kafkaMaxSizeOfBatch {
+			if err := k.client.WriteMessages(ctx, records...); err != nil {
+				return errors.Wrap(err, "produce records")
+			}
+			totalBytes = 0
+			records = nil
+		}
+
+		records = append(records, kafka.Message{Value: []byte(log)})
+		totalBytes += len(log) + kafkaRecordOverhead
+	}
+
+	// Push the final batch
+	if len(records) > 0 {
+		if err := k.client.WriteMessages(ctx, records...); err != nil {
+			return errors.Wrap(err, "produce records")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaSASLMechanism(t *testing.T) {
+	mechanism, err := kafkaSASLMechanism(config.KafkaConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, mechanism)
+
+	for _, name := range []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"} {
+		mechanism, err := kafkaSASLMechanism(config.KafkaConfig{SASLMechanism: name, SASLUsername: "fleet", SASLPassword: "secret"})
+		require.NoError(t, err)
+		assert.Equal(t, name, mechanism.Name())
+
+		_, err = kafkaSASLMechanism(config.KafkaConfig{SASLMechanism: name})
+		require.Error(t, err)
+	}
+
+	_, err = kafkaSASLMechanism(config.KafkaConfig{SASLMechanism: "GSSAPI", SASLUsername: "fleet"})
+	require.Error(t, err)
+}
+
+func TestKafkaUnreachableBrokers(t *testing.T) {
+	_, err := NewKafkaLogWriter(config.KafkaConfig{}, "osquery_result", log.NewNopLogger())
+	require.Error(t, err)
+
+	// Nothing listens on the address once the listener is closed.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	require.NoError(t, listener.Close())
+
+	_, err = NewKafkaLogWriter(config.KafkaConfig{Brokers: addr, Timeout: time.Second}, "osquery_result", log.NewNopLogger())
+	require.Error(t, err)
+}
+
+type testKafkaProducer struct {
+	calls [][]kafka.Message
+}
+
+func (p *testKafkaProducer) WriteMessages(ctx context.Context, msgs ...kafka.Message) error {
+	p.calls = append(p.calls, msgs)
+	return nil
+}
+
+func TestKafkaWrite(t *testing.T) {
+	producer := &testKafkaProducer{}
+	writer := &kafkaLogWriter{client: producer, topic: "osquery_result", logger: log.NewNopLogger()}
+
+	require.NoError(t, writer.Write(context.Background(), logs))
+	require.Len(t, producer.calls, 1)
+	var produced []json.RawMessage
+	for _, msg := range producer.calls[0] {
+		produced = append(produced, json.RawMessage(msg.Value))
+	}
+	assert.Equal(t, logs, produced)
+}
+
+func TestKafkaBatching(t *testing.T) {
+	producer := &testKafkaProducer{}
+	writer := &kafkaLogWriter{client: producer, topic: "foobar", logger: log.NewNopLogger()}
+
+	var logs []json.RawMessage
+	for i := 0; i < kafkaMaxRecordsInBatch+10; i++ {
+		logs = append(logs, json.RawMessage(`{"i":`+strconv.Itoa(i)+`}`))
+	}
+	// Too big for any batch, dropped.
+	logs = append(logs, json.RawMessage(`"`+string(bytes.Repeat([]byte("a"), kafkaMaxSizeOfBatch))+`"`))
+	// Fill a batch by size.
+	big := json.RawMessage(`"` + string(bytes.Repeat([]byte("b"), kafkaMaxSizeOfBatch/2)) + `"`)
+	logs = append(logs, big, big)
+
+	require.NoError(t, writer.Write(context.Background(), logs))
+	require.Len(t, producer.calls, 3)
+	assert.Len(t, producer.calls[0], kafkaMaxRecordsInBatch)
+	assert.Len(t, producer.calls[1], 11)
+	assert.Len(t, producer.calls[2], 1)
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"math"
 	"math/rand"
 	"time"
 
@@ -128,7 +127,7 @@ func (k *kinesisLogWriter) Write(ctx context.Context, logs []json.RawMessage) er
 		// adding any more.
 		if len(records) >= kinesisMaxRecordsInBatch ||
 			totalBytes+len(log)+len(partitionKey) > kinesisMaxSizeOfBatch {
-			if err := k.putRecords(0, records); err != nil {
+			if err := k.putRecords(ctx, 0, records); err != nil {
 				return errors.Wrap(err, "put records")
 			}
 			totalBytes = 0
@@ -141,7 +140,7 @@ func (k *kinesisLogWriter) Write(ctx context.Context, logs []json.RawMessage) er
 
 	// Push the final batch
 	if len(records) > 0 {
-		if err := k.putRecords(0, records); err != nil {
+		if err := k.putRecords(ctx, 0, records); err != nil {
 			return errors.Wrap(err, "put records")
 		}
 	}
@@ -149,21 +148,23 @@ func (k *kinesisLogWriter) Write(ctx context.Context, logs []json.RawMessage) er
 	return nil
 }
 
-func (k *kinesisLogWriter) putRecords(try int, records []*kinesis.PutRecordsRequestEntry) error {
+func (k *kinesisLogWriter) putRecords(ctx context.Context, try int, records []*kinesis.PutRecordsRequestEntry) error {
 	if try > 0 {
-		time.Sleep(100 * time.Millisecond * time.Duration(math.Pow(2.0, float64(try))))
+		if err := waitForRetry(ctx, try); err != nil {
+			return err
+		}
 	}
 	input := &kinesis.PutRecordsInput{
 		StreamName: &k.stream,
 		Records:    records,
 	}
 
-	output, err := k.client.PutRecords(input)
+	output, err := k.client.PutRecordsWithContext(ctx, input)
 	if err != nil {
 		if _, ok := err.(awserr.Error); ok {
 			if try < kinesisMaxRetries {
 				// Retry with backoff
-				return k.putRecords(try+1, records)
+				return k.putRecords(ctx, try+1, records)
 			}
 		}
 
@@ -199,7 +200,7 @@ func (k *kinesisLogWriter) putRecords(try int, records []*kinesis.PutRecordsRequ
 			}
 		}
 
-		return k.putRecords(try+1, failedRecords)
+		return k.putRecords(ctx, try+1, failedRecords)
 	}
 
 	return nil
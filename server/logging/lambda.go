@@ -104,7 +104,8 @@ func (f *lambdaLogWriter) Write(ctx context.Context, logs []json.RawMessage) err
 			continue
 		}
 
-		out, err := f.client.Invoke(
+		out, err := f.client.InvokeWithContext(
+			ctx,
 			&lambda.InvokeInput{
 				FunctionName: &f.functionName,
 				Payload:      []byte(log),
@@ -3,6 +3,10 @@
 package logging
 
 import (
+	"context"
+	"math"
+	"time"
+
 	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/go-kit/kit/log"
@@ -16,10 +20,22 @@ type OsqueryLogger struct {
 }
 
 func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
-	var status, result fleet.JSONLogger
+	status, err := newRoutedLogWriter("status", config.Osquery.StatusLogPlugin, "", config, logger, newStatusLogWriter)
+	if err != nil {
+		return nil, err
+	}
+	result, err := newRoutedLogWriter("result", config.Osquery.ResultLogPlugin, config.Osquery.ResultLogRoutes, config, logger, newResultLogWriter)
+	if err != nil {
+		return nil, err
+	}
+	return &OsqueryLogger{Status: status, Result: result}, nil
+}
+
+func newStatusLogWriter(plugin string, config config.FleetConfig, logger log.Logger) (fleet.JSONLogger, error) {
+	var status fleet.JSONLogger
 	var err error
 
-	switch config.Osquery.StatusLogPlugin {
+	switch plugin {
 	case "":
 		// Allow "" to mean filesystem for backwards compatibility
 		level.Info(logger).Log("msg", "fleet_status_log_plugin not explicitly specified. Assuming 'filesystem'")
@@ -82,6 +98,26 @@ func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "create pubsub status logger")
 		}
+	case "splunk":
+		status, err = NewSplunkLogWriter(config.Splunk, config.Splunk.StatusIndex, config.Splunk.StatusSourcetype, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "create splunk status logger")
+		}
+	case "elasticsearch":
+		status, err = NewElasticsearchLogWriter(config.Elasticsearch, config.Elasticsearch.StatusIndex, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "create elasticsearch status logger")
+		}
+	case "kafka":
+		status, err = NewKafkaLogWriter(config.Kafka, config.Kafka.StatusTopic, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "create kafka status logger")
+		}
+	case "http":
+		status, err = NewHTTPLogWriter(config.HTTPLog, config.HTTPLog.StatusURL, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "create http status logger")
+		}
 	case "stdout":
 		status, err = NewStdoutLogWriter()
 		if err != nil {
@@ -89,11 +125,17 @@ func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
 		}
 	default:
 		return nil, errors.Errorf(
-			"unknown status log plugin: %s", config.Osquery.StatusLogPlugin,
+			"unknown status log plugin: %s", plugin,
 		)
 	}
+	return status, nil
+}
 
-	switch config.Osquery.ResultLogPlugin {
+func newResultLogWriter(plugin string, config config.FleetConfig, logger log.Logger) (fleet.JSONLogger, error) {
+	var result fleet.JSONLogger
+	var err error
+
+	switch plugin {
 	case "":
 		// Allow "" to mean filesystem for backwards compatibility
 		level.Info(logger).Log("msg", "fleet_result_log_plugin not explicitly specified. Assuming 'filesystem'")
@@ -156,6 +198,26 @@ func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
 		if err != nil {
 			return nil, errors.Wrap(err, "create pubsub result logger")
 		}
+	case "splunk":
+		result, err = NewSplunkLogWriter(config.Splunk, config.Splunk.ResultIndex, config.Splunk.ResultSourcetype, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "create splunk result logger")
+		}
+	case "elasticsearch":
+		result, err = NewElasticsearchLogWriter(config.Elasticsearch, config.Elasticsearch.ResultIndex, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "create elasticsearch result logger")
+		}
+	case "kafka":
+		result, err = NewKafkaLogWriter(config.Kafka, config.Kafka.ResultTopic, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "create kafka result logger")
+		}
+	case "http":
+		result, err = NewHTTPLogWriter(config.HTTPLog, config.HTTPLog.ResultURL, logger)
+		if err != nil {
+			return nil, errors.Wrap(err, "create http result logger")
+		}
 	case "stdout":
 		result, err = NewStdoutLogWriter()
 		if err != nil {
@@ -163,8 +225,21 @@ func New(config config.FleetConfig, logger log.Logger) (*OsqueryLogger, error) {
 		}
 	default:
 		return nil, errors.Errorf(
-			"unknown result log plugin: %s", config.Osquery.StatusLogPlugin,
+			"unknown result log plugin: %s", plugin,
 		)
 	}
-	return &OsqueryLogger{Status: status, Result: result}, nil
+	return result, nil
+}
+
+// waitForRetry waits with an exponential backoff before the given retry of a
+// write, and returns early with an error when ctx is done.
+func waitForRetry(ctx context.Context, try int) error {
+	timer := time.NewTimer(100 * time.Millisecond * time.Duration(math.Pow(2.0, float64(try))))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/firehose"
 	"github.com/aws/aws-sdk-go/service/firehose/firehoseiface"
 )
@@ -27,3 +29,7 @@ func (f *FirehoseMock) DescribeDeliveryStream(input *firehose.DescribeDeliverySt
 	f.DescribeDeliveryStreamFuncInvoked = true
 	return f.DescribeDeliveryStreamFunc(input)
 }
+
+func (f *FirehoseMock) PutRecordBatchWithContext(ctx aws.Context, input *firehose.PutRecordBatchInput, opts ...request.Option) (*firehose.PutRecordBatchOutput, error) {
+	return f.PutRecordBatch(input)
+}
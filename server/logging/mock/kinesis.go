@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/kinesis"
 	"github.com/aws/aws-sdk-go/service/kinesis/kinesisiface"
 )
@@ -27,3 +29,7 @@ func (k *KinesisMock) DescribeStream(input *kinesis.DescribeStreamInput) (*kines
 	k.DescribeStreamFuncInvoked = true
 	return k.DescribeStreamFunc(input)
 }
+
+func (k *KinesisMock) PutRecordsWithContext(ctx aws.Context, input *kinesis.PutRecordsInput, opts ...request.Option) (*kinesis.PutRecordsOutput, error) {
+	return k.PutRecords(input)
+}
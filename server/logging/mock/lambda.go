@@ -1,6 +1,8 @@
 package mock
 
 import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/service/lambda"
 	"github.com/aws/aws-sdk-go/service/lambda/lambdaiface"
 	"github.com/stretchr/testify/mock"
@@ -19,3 +21,7 @@ func (l *LambdaMock) Invoke(input *lambda.InvokeInput) (*lambda.InvokeOutput, er
 	}
 	return out.(*lambda.InvokeOutput), err
 }
+
+func (l *LambdaMock) InvokeWithContext(ctx aws.Context, input *lambda.InvokeInput, opts ...request.Option) (*lambda.InvokeOutput, error) {
+	return l.Invoke(input)
+}
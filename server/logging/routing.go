@@ -0,0 +1,207 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log"
+	"github.com/pkg/errors"
+)
+
+// logRoute sends the logs of the queries with a name matching pattern to
+// the writers at the given indexes.
+type logRoute struct {
+	pattern string
+	writers []int
+}
+
+// routedLogWriter writes each log to the writers of the first route matching
+// the name of its query, or to the default writers when no route matches.
+type routedLogWriter struct {
+	writers  []fleet.JSONLogger
+	defaults []int
+	routes   []logRoute
+}
+
+// newRoutedLogWriter returns the writer of the logs of logType, for the
+// comma-separated list of plugins and the routes. Each plugin is created
+// once, even when it is used by several routes, and it is buffered on disk
+// when a log buffer is configured, which is required for several plugins.
+func newRoutedLogWriter(
+	logType, plugins, routes string,
+	config config.FleetConfig,
+	logger log.Logger,
+	newWriter func(plugin string, config config.FleetConfig, logger log.Logger) (fleet.JSONLogger, error),
+) (fleet.JSONLogger, error) {
+	r := &routedLogWriter{}
+	indexes := make(map[string]int)
+	getWriters := func(plugins []string) ([]int, error) {
+		var list []int
+		for _, plugin := range plugins {
+			// "" is the filesystem plugin, for backwards compatibility.
+			name := plugin
+			if name == "" {
+				name = "filesystem"
+			}
+			if i, ok := indexes[name]; ok {
+				list = append(list, i)
+				continue
+			}
+			w, err := newWriter(plugin, config, logger)
+			if err != nil {
+				return nil, err
+			}
+			if dir := config.LogBuffer.Directory; dir != "" {
+				maxSize := int64(config.LogBuffer.MaxSizeMB) * 1024 * 1024
+				w, err = NewBufferedLogWriter(
+					w, filepath.Join(dir, logType, name), logType, name, maxSize,
+					config.LogBuffer.MaxBackoff, config.LogBuffer.WriteTimeout, logger,
+				)
+				if err != nil {
+					return nil, errors.Wrapf(err, "create %s log buffer", logType)
+				}
+			}
+			indexes[name] = len(r.writers)
+			list = append(list, len(r.writers))
+			r.writers = append(r.writers, w)
+		}
+		return list, nil
+	}
+
+	var err error
+	r.defaults, err = getWriters(SplitLogPlugins(plugins))
+	if err != nil {
+		return nil, err
+	}
+	rules, err := ParseLogRoutes(routes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse %s log routes", logType)
+	}
+	if len(r.writers) == 1 && len(rules) == 0 {
+		return r.writers[0], nil
+	}
+
+	for _, rule := range rules {
+		routeWriters, err := getWriters(rule.Plugins)
+		if err != nil {
+			return nil, err
+		}
+		r.routes = append(r.routes, logRoute{pattern: rule.Pattern, writers: routeWriters})
+	}
+	// A failed write is retried by osquery, which sends all its logs again,
+	// so that the logs would be duplicated in the other destinations. The
+	// logs are queued for the destinations that fail instead.
+	if len(r.writers) > 1 && config.LogBuffer.Directory == "" {
+		return nil, errors.Errorf("the %s logs are sent to several plugins, which requires log_buffer_directory to be set", logType)
+	}
+	return r, nil
+}
+
+// SplitLogPlugins splits the comma-separated list of plugins. An empty list
+// is the default plugin.
+func SplitLogPlugins(s string) []string {
+	var plugins []string
+	for _, plugin := range strings.Split(s, ",") {
+		if plugin = strings.TrimSpace(plugin); plugin != "" {
+			plugins = append(plugins, plugin)
+		}
+	}
+	if len(plugins) == 0 {
+		return []string{""}
+	}
+	return plugins
+}
+
+// LogRoute sends the result logs of the queries with a name matching
+// Pattern, as matched by path.Match, to Plugins.
+type LogRoute struct {
+	Pattern string
+	Plugins []string
+}
+
+// ParseLogRoutes parses the semicolon-separated list of routes, in the
+// "pattern=plugin[,plugin]" format.
+func ParseLogRoutes(s string) ([]LogRoute, error) {
+	var rules []LogRoute
+	for _, route := range strings.Split(s, ";") {
+		if strings.TrimSpace(route) == "" {
+			continue
+		}
+		parts := strings.SplitN(route, "=", 2)
+		pattern := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || pattern == "" {
+			return nil, errors.Errorf("invalid route %q, expected \"pattern=plugin[,plugin]\"", route)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, errors.Wrapf(err, "invalid pattern %q", pattern)
+		}
+		var plugins []string
+		for _, plugin := range strings.Split(parts[1], ",") {
+			if plugin = strings.TrimSpace(plugin); plugin != "" {
+				plugins = append(plugins, plugin)
+			}
+		}
+		if len(plugins) == 0 {
+			return nil, errors.Errorf("no plugin for route %q", route)
+		}
+		rules = append(rules, LogRoute{Pattern: pattern, Plugins: plugins})
+	}
+	return rules, nil
+}
+
+func (r *routedLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	// The logs are grouped by writer, keeping their order, and each writer
+	// is called once.
+	batches := make([][]json.RawMessage, len(r.writers))
+	for _, log := range logs {
+		for _, i := range r.writersFor(log) {
+			batches[i] = append(batches[i], log)
+		}
+	}
+
+	// All the writers are called even if one fails, so that an outage of
+	// one destination does not impact the others. The writers are buffered,
+	// so that they only fail when their buffer is full. The logs are then
+	// sent again by osquery, to all the destinations: the delivery is at
+	// least once, and the destinations that did not fail get duplicates.
+	var firstErr error
+	failed := 0
+	for i, w := range r.writers {
+		if len(batches[i]) == 0 {
+			continue
+		}
+		if err := w.Write(ctx, batches[i]); err != nil {
+			failed++
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr != nil {
+		return errors.Wrapf(firstErr, "write logs to %d destinations", failed)
+	}
+	return nil
+}
+
+func (r *routedLogWriter) writersFor(log json.RawMessage) []int {
+	if len(r.routes) == 0 {
+		return r.defaults
+	}
+	var query struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(log, &query); err != nil || query.Name == "" {
+		return r.defaults
+	}
+	for _, route := range r.routes {
+		if ok, _ := path.Match(route.pattern, query.Name); ok {
+			return route.writers
+		}
+	}
+	return r.defaults
+}
@@ -0,0 +1,149 @@
+package logging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRoutingWriters creates test writers by plugin name, and counts how
+// many times each plugin is created.
+type testRoutingWriters struct {
+	writers map[string]*testFailingWriter
+	created map[string]int
+}
+
+func (w *testRoutingWriters) newWriter(plugin string, config config.FleetConfig, logger log.Logger) (fleet.JSONLogger, error) {
+	if plugin == "unknown" {
+		return nil, errors.New("unknown plugin")
+	}
+	w.created[plugin]++
+	if _, ok := w.writers[plugin]; !ok {
+		w.writers[plugin] = &testFailingWriter{}
+	}
+	return w.writers[plugin], nil
+}
+
+func newTestRoutingWriters() *testRoutingWriters {
+	return &testRoutingWriters{writers: make(map[string]*testFailingWriter), created: make(map[string]int)}
+}
+
+// newTestRoutingConfig returns a config with a log buffer of maxSizeMB.
+func newTestRoutingConfig(t *testing.T, maxSizeMB int) config.FleetConfig {
+	var conf config.FleetConfig
+	conf.LogBuffer.Directory = t.TempDir()
+	conf.LogBuffer.MaxSizeMB = maxSizeMB
+	conf.LogBuffer.MaxBackoff = time.Second
+	return conf
+}
+
+// stopTestRoutedLogWriter stops draining the buffers of the writers of w.
+func stopTestRoutedLogWriter(t *testing.T, w fleet.JSONLogger) {
+	t.Cleanup(func() {
+		for _, writer := range w.(*routedLogWriter).writers {
+			writer.(*bufferedLogWriter).stop()
+		}
+	})
+}
+
+func TestRoutedLogWriter(t *testing.T) {
+	writers := newTestRoutingWriters()
+	w, err := newRoutedLogWriter(
+		"result",
+		"firehose, filesystem",
+		"pack/noisy/*=filesystem; pack/Global/processes=kinesis,firehose",
+		newTestRoutingConfig(t, 1), log.NewNopLogger(), writers.newWriter,
+	)
+	require.NoError(t, err)
+	require.IsType(t, &routedLogWriter{}, w)
+	stopTestRoutedLogWriter(t, w)
+	// The plugins used by several routes are created once.
+	assert.Equal(t, map[string]int{"firehose": 1, "filesystem": 1, "kinesis": 1}, writers.created)
+
+	logs := []json.RawMessage{
+		json.RawMessage(`{"name":"pack/noisy/users","action":"added"}`),
+		json.RawMessage(`{"name":"pack/Global/processes","action":"added"}`),
+		json.RawMessage(`{"name":"pack/Global/time","action":"added"}`),
+		json.RawMessage(`{"status":"no name"}`),
+	}
+	require.NoError(t, w.Write(context.Background(), logs))
+	assert.Equal(t, []json.RawMessage{logs[1], logs[2], logs[3]}, writers.writers["firehose"].writtenLogs())
+	assert.Equal(t, []json.RawMessage{logs[0], logs[2], logs[3]}, writers.writers["filesystem"].writtenLogs())
+	assert.Equal(t, []json.RawMessage{logs[1]}, writers.writers["kinesis"].writtenLogs())
+
+	// The other destinations are written when one fails, whose logs are
+	// queued.
+	writers.writers["firehose"].setDown(true)
+	require.NoError(t, w.Write(context.Background(), logs[2:3]))
+	assert.Len(t, writers.writers["filesystem"].writtenLogs(), 4)
+	writers.writers["firehose"].setDown(false)
+	require.Eventually(t, func() bool {
+		return len(writers.writers["firehose"].writtenLogs()) == 4
+	}, 10*time.Second, 10*time.Millisecond)
+
+	// Several plugins require a log buffer.
+	_, err = newRoutedLogWriter("result", "firehose,filesystem", "", config.FleetConfig{}, log.NewNopLogger(), newTestRoutingWriters().newWriter)
+	require.Error(t, err)
+	_, err = newRoutedLogWriter("result", "firehose", "pack/*=filesystem", config.FleetConfig{}, log.NewNopLogger(), newTestRoutingWriters().newWriter)
+	require.Error(t, err)
+}
+
+func TestRoutedLogWriterBufferFull(t *testing.T) {
+	writers := newTestRoutingWriters()
+	w, err := newRoutedLogWriter("status", "firehose,filesystem", "", newTestRoutingConfig(t, 0), log.NewNopLogger(), writers.newWriter)
+	require.NoError(t, err)
+	stopTestRoutedLogWriter(t, w)
+
+	// When the buffer of a failing destination is full, the write fails and
+	// osquery sends the logs again: the other destinations get duplicates.
+	logs := []json.RawMessage{json.RawMessage(`{"status":"first"}`)}
+	writers.writers["firehose"].setDown(true)
+	require.Error(t, w.Write(context.Background(), logs))
+	writers.writers["firehose"].setDown(false)
+	require.NoError(t, w.Write(context.Background(), logs))
+	assert.Equal(t, logs, writers.writers["firehose"].writtenLogs())
+	assert.Equal(t, []json.RawMessage{logs[0], logs[0]}, writers.writers["filesystem"].writtenLogs())
+}
+
+func TestRoutedLogWriterSinglePlugin(t *testing.T) {
+	writers := newTestRoutingWriters()
+	w, err := newRoutedLogWriter("status", "", "", config.FleetConfig{}, log.NewNopLogger(), writers.newWriter)
+	require.NoError(t, err)
+	// A single plugin is not wrapped.
+	assert.Equal(t, writers.writers[""], w)
+
+	// "" is the filesystem plugin.
+	writers = newTestRoutingWriters()
+	_, err = newRoutedLogWriter("result", "", "pack/*=filesystem", config.FleetConfig{}, log.NewNopLogger(), writers.newWriter)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"": 1}, writers.created)
+
+	_, err = newRoutedLogWriter("result", "kinesis", "pack/*=unknown", config.FleetConfig{}, log.NewNopLogger(), writers.newWriter)
+	require.Error(t, err)
+}
+
+func TestParseLogRoutes(t *testing.T) {
+	routes, err := ParseLogRoutes(" pack/noisy/* = filesystem ; pack/Global/processes=kinesis, firehose;")
+	require.NoError(t, err)
+	assert.Equal(t, []LogRoute{
+		{Pattern: "pack/noisy/*", Plugins: []string{"filesystem"}},
+		{Pattern: "pack/Global/processes", Plugins: []string{"kinesis", "firehose"}},
+	}, routes)
+
+	routes, err = ParseLogRoutes("")
+	require.NoError(t, err)
+	assert.Empty(t, routes)
+
+	for _, invalid := range []string{"filesystem", "=filesystem", "pack/*=", "pack/[=filesystem"} {
+		_, err := ParseLogRoutes(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
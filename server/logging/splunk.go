@@ -0,0 +1,189 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
+
+const (
+	splunkMaxRetries = 8
+
+	// The size of a batch is kept under the default max_content_length of
+	// Splunk Cloud.
+	splunkMaxEventsInBatch = 500
+	splunkMaxSizeOfBatch   = 1000 * 1000 // 1,000 KB
+)
+
+type splunkLogWriter struct {
+	client     *http.Client
+	url        string
+	token      string
+	index      string
+	sourcetype string
+	logger     log.Logger
+}
+
+// splunkEvent is an event sent to the HTTP Event Collector. When the index is
+// empty, the events are sent to the default index of the token.
+type splunkEvent struct {
+	Time       int64           `json:"time,omitempty"`
+	Index      string          `json:"index,omitempty"`
+	Sourcetype string          `json:"sourcetype,omitempty"`
+	Event      json.RawMessage `json:"event"`
+}
+
+// splunkResponse is the response of the HTTP Event Collector. When an event
+// is invalid, the events before it are indexed.
+type splunkResponse struct {
+	Text               string `json:"text"`
+	Code               int    `json:"code"`
+	InvalidEventNumber *int   `json:"invalid-event-number"`
+}
+
+func NewSplunkLogWriter(conf config.SplunkConfig, index, sourcetype string, logger log.Logger) (*splunkLogWriter, error) {
+	u, err := url.Parse(conf.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Errorf("invalid Splunk URL: %q", conf.URL)
+	}
+	if conf.Token == "" {
+		return nil, errors.New("splunk token is required")
+	}
+	timeout := conf.Timeout
+	if timeout <= 0 {
+		timeout = httpDefaultTimeout
+	}
+
+	return &splunkLogWriter{
+		client:     &http.Client{Timeout: timeout},
+		url:        strings.TrimSuffix(conf.URL, "/") + "/services/collector/event",
+		token:      conf.Token,
+		index:      index,
+		sourcetype: sourcetype,
+		logger:     logger,
+	}, nil
+}
+
+func (s *splunkLogWriter) Write(ctx context.Context, logs []json.RawMessage) error {
+	var events [][]byte
+	totalBytes := 0
+	for _, log := range logs {
+		event, err := s.event(log)
+		if err != nil {
+			return errors.Wrap(err, "encode event")
+		}
+
+		// As for Kinesis, logs that are too big are dropped, with their
+		// beginning to help diagnose the query generating them.
+		if len(event)+1 > splunkMaxSizeOfBatch {
+			level.Info(s.logger).Log(
+				"msg", "dropping log over 1MB Splunk limit",
+				"size", len(log),
+				"log", string(log[:100])+"...",
+			)
+			continue
+		}
+
+		// If adding this log will exceed the limit on number of events
+		// in the batch, or the limit on total size of the batch, we need
+		// to send this batch before adding any more.
+		if len(events) >= splunkMaxEventsInBatch || totalBytes+len(event)+1 > splunkMaxSizeOfBatch {
+			if err := s.send(ctx, 0, events); err != nil {
+				return errors.Wrap(err, "send events")
+			}
+			totalBytes = 0
+			events = nil
+		}
+
+		events = append(events, event)
+		totalBytes += len(event) + 1
+	}
+
+	// Send the final batch
+	if len(events) > 0 {
+		if err := s.send(ctx, 0, events); err != nil {
+			return errors.Wrap(err, "send events")
+		}
+	}
+
+	return nil
+}
+
+// event returns the event of the log, timestamped with the time of the log
+// when it has one so that Splunk does not use the time it is received.
+func (s *splunkLogWriter) event(log json.RawMessage) ([]byte, error) {
+	var timestamp struct {
+		UnixTime interface{} `json:"unixTime"`
+	}
+	// Logs that are not objects are sent without a time.
+	_ = json.Unmarshal(log, &timestamp)
+
+	return json.Marshal(splunkEvent{
+		Time:       cast.ToInt64(timestamp.UnixTime),
+		Index:      s.index,
+		Sourcetype: s.sourcetype,
+		Event:      log,
+	})
+}
+
+func (s *splunkLogWriter) send(ctx context.Context, try int, events [][]byte) error {
+	if try > 0 {
+		if err := waitForRetry(ctx, try); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(bytes.Join(events, []byte{'\n'})))
+	if err != nil {
+		return errors.Wrap(err, "create request")
+	}
+	req.Header.Set("Authorization", "Splunk "+s.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	body, err := doLogRequest(s.client, req)
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(httpRetriableError); ok {
+		if try < splunkMaxRetries {
+			// Retry with backoff
+			return s.send(ctx, try+1, events)
+		}
+		return err
+	}
+
+	// The events before the invalid one were indexed. The invalid event
+	// is dropped, and the events after it are sent again.
+	var resp splunkResponse
+	if json.Unmarshal(body, &resp) != nil || resp.InvalidEventNumber == nil ||
+		*resp.InvalidEventNumber < 0 || *resp.InvalidEventNumber >= len(events) {
+		return err
+	}
+	invalid := *resp.InvalidEventNumber
+	level.Info(s.logger).Log(
+		"msg", "dropping log rejected by Splunk",
+		"err", resp.Text,
+		"log", truncateLog(events[invalid]),
+	)
+	if invalid+1 < len(events) {
+		return s.send(ctx, try, events[invalid+1:])
+	}
+	return nil
+}
+
+// truncateLog returns the beginning of the log, to identify it in messages.
+func truncateLog(log []byte) string {
+	if len(log) <= 100 {
+		return string(log)
+	}
+	return string(log[:100]) + "..."
+}
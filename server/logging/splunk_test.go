@@ -0,0 +1,171 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSplunkCollector is a stand-in HTTP Event Collector, that collects the
+// events sent to it. Events with an "invalid" field are rejected, as Splunk
+// does, after indexing the events before them.
+type testSplunkCollector struct {
+	mu       sync.Mutex
+	statuses []int
+	calls    int
+	events   []splunkEvent
+}
+
+func (c *testSplunkCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls++
+	if r.URL.Path != "/services/collector/event" || r.Header.Get("Authorization") != "Splunk token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if len(c.statuses) > 0 {
+		var status int
+		status, c.statuses = c.statuses[0], c.statuses[1:]
+		w.WriteHeader(status)
+		return
+	}
+
+	dec := json.NewDecoder(r.Body)
+	for i := 0; dec.More(); i++ {
+		var event splunkEvent
+		if err := dec.Decode(&event); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if bytes.Contains(event.Event, []byte(`"invalid"`)) {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"text":"Invalid data format","code":6,"invalid-event-number":` + strconv.Itoa(i) + `}`))
+			return
+		}
+		c.events = append(c.events, event)
+	}
+	w.Write([]byte(`{"text":"Success","code":0}`))
+}
+
+func (c *testSplunkCollector) callCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+func (c *testSplunkCollector) indexedLogs() []json.RawMessage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var logs []json.RawMessage
+	for _, event := range c.events {
+		logs = append(logs, event.Event)
+	}
+	return logs
+}
+
+func TestSplunkWrite(t *testing.T) {
+	collector := &testSplunkCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	conf := config.SplunkConfig{URL: server.URL + "/", Token: "token"}
+	writer, err := NewSplunkLogWriter(conf, "osquery", "osquery:result", log.NewNopLogger())
+	require.NoError(t, err)
+
+	logs := []json.RawMessage{
+		json.RawMessage(`{"name":"pack/Global/time","unixTime":1511049728}`),
+		json.RawMessage(`{"severity":"0","unixTime":"1511049729"}`),
+		json.RawMessage(`{"foo":"bar"}`),
+	}
+	require.NoError(t, writer.Write(context.Background(), logs))
+	require.Len(t, collector.events, 3)
+	assert.Equal(t, logs, collector.indexedLogs())
+	for _, event := range collector.events {
+		assert.Equal(t, "osquery", event.Index)
+		assert.Equal(t, "osquery:result", event.Sourcetype)
+	}
+	assert.Equal(t, int64(1511049728), collector.events[0].Time)
+	assert.Equal(t, int64(1511049729), collector.events[1].Time)
+	assert.Zero(t, collector.events[2].Time)
+}
+
+func TestSplunkRetryableFailure(t *testing.T) {
+	collector := &testSplunkCollector{statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	writer, err := NewSplunkLogWriter(config.SplunkConfig{URL: server.URL, Token: "token"}, "", "", log.NewNopLogger())
+	require.NoError(t, err)
+
+	require.NoError(t, writer.Write(context.Background(), logs))
+	assert.Equal(t, 3, collector.callCount())
+	// The events are compacted when they are encoded.
+	actual, err := json.Marshal(collector.indexedLogs())
+	require.NoError(t, err)
+	expected, err := json.Marshal(logs)
+	require.NoError(t, err)
+	assert.JSONEq(t, string(expected), string(actual))
+
+	// Authentication errors are not retried.
+	writer.token = "wrong"
+	require.Error(t, writer.Write(context.Background(), logs))
+	assert.Equal(t, 4, collector.callCount())
+}
+
+func TestSplunkInvalidEvent(t *testing.T) {
+	collector := &testSplunkCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	writer, err := NewSplunkLogWriter(config.SplunkConfig{URL: server.URL, Token: "token"}, "", "", log.NewNopLogger())
+	require.NoError(t, err)
+
+	logs := []json.RawMessage{
+		json.RawMessage(`{"i":0}`),
+		json.RawMessage(`{"invalid":1}`),
+		json.RawMessage(`{"i":2}`),
+		json.RawMessage(`{"invalid":3}`),
+	}
+	require.NoError(t, writer.Write(context.Background(), logs))
+	// The invalid events are dropped, and the events after them are sent
+	// again.
+	assert.Equal(t, []json.RawMessage{logs[0], logs[2]}, collector.indexedLogs())
+	assert.Equal(t, 2, collector.callCount())
+}
+
+func TestSplunkBatching(t *testing.T) {
+	collector := &testSplunkCollector{}
+	server := httptest.NewServer(collector)
+	defer server.Close()
+
+	writer, err := NewSplunkLogWriter(config.SplunkConfig{URL: server.URL, Token: "token"}, "", "", log.NewNopLogger())
+	require.NoError(t, err)
+
+	var logs []json.RawMessage
+	for i := 0; i < splunkMaxEventsInBatch+10; i++ {
+		logs = append(logs, json.RawMessage(`{"i":`+strconv.Itoa(i)+`}`))
+	}
+	// Too big for any batch, dropped.
+	tooBig := json.RawMessage(`"` + string(bytes.Repeat([]byte("a"), splunkMaxSizeOfBatch)) + `"`)
+	require.NoError(t, writer.Write(context.Background(), append(logs, tooBig)))
+	assert.Equal(t, 2, collector.callCount())
+	assert.Equal(t, logs, collector.indexedLogs())
+}
+
+func TestNewSplunkLogWriterInvalid(t *testing.T) {
+	_, err := NewSplunkLogWriter(config.SplunkConfig{Token: "token"}, "", "", log.NewNopLogger())
+	require.Error(t, err)
+	_, err = NewSplunkLogWriter(config.SplunkConfig{URL: "https://splunk.example.com:8088"}, "", "", log.NewNopLogger())
+	require.Error(t, err)
+}
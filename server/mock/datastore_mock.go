@@ -75,6 +75,24 @@ type NewDistributedQueryCampaignTargetFunc func(ctx context.Context, target *fle
 
 type CleanupDistributedQueryCampaignsFunc func(ctx context.Context, now time.Time) (expired uint, err error)
 
+type SaveDistributedQueryCampaignResultFunc func(ctx context.Context, result *fleet.DistributedQueryCampaignResult) error
+
+type ListDistributedQueryCampaignResultsFunc func(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignResult, error)
+
+type CountDistributedQueryCampaignResultsFunc func(ctx context.Context, campaignID uint) (uint, error)
+
+type CleanupDistributedQueryCampaignResultsFunc func(ctx context.Context, olderThan time.Time) error
+
+type NewDistributedQueryCampaignHostsFunc func(ctx context.Context, campaignID uint, hostIDs []uint) error
+
+type CompleteExpiredDistributedQueryCampaignsFunc func(ctx context.Context, createdBefore time.Time) ([]uint, error)
+
+type UpdateDistributedQueryCampaignHostStatusFunc func(ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string) error
+
+type ListDistributedQueryCampaignHostsFunc func(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignHost, error)
+
+type CleanupDistributedQueryCampaignHostsFunc func(ctx context.Context, olderThan time.Time) error
+
 type ApplyPackSpecsFunc func(ctx context.Context, specs []*fleet.PackSpec) error
 
 type GetPackSpecsFunc func(ctx context.Context) ([]*fleet.PackSpec, error)
@@ -161,6 +179,8 @@ type AddHostsToTeamFunc func(ctx context.Context, teamID *uint, hostIDs []uint)
 
 type TotalAndUnseenHostsSinceFunc func(ctx context.Context, daysCount int) (int, int, error)
 
+type LoadHostVitalsFunc func(ctx context.Context, host *fleet.Host) error
+
 type CountHostsInTargetsFunc func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error)
 
 type HostIDsInTargetsFunc func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error)
@@ -185,6 +205,32 @@ type DestroyAllSessionsForUserFunc func(ctx context.Context, id uint) error
 
 type MarkSessionAccessedFunc func(ctx context.Context, session *fleet.Session) error
 
+type NewAPITokenFunc func(ctx context.Context, token *fleet.APIToken) (*fleet.APIToken, error)
+
+type APITokenFunc func(ctx context.Context, id uint) (*fleet.APIToken, error)
+
+type APITokenByKeyHashFunc func(ctx context.Context, keyHash string) (*fleet.APIToken, error)
+
+type ListAPITokensForUserFunc func(ctx context.Context, userID uint) ([]*fleet.APIToken, error)
+
+type DeleteAPITokenFunc func(ctx context.Context, id uint) error
+
+type MarkAPITokenUsedFunc func(ctx context.Context, id uint, usedAt time.Time) error
+
+type NewSCIMGroupFunc func(ctx context.Context, group *fleet.SCIMGroup) (*fleet.SCIMGroup, error)
+
+type SCIMGroupFunc func(ctx context.Context, id uint) (*fleet.SCIMGroup, error)
+
+type ListSCIMGroupsFunc func(ctx context.Context) ([]*fleet.SCIMGroup, error)
+
+type SaveSCIMGroupFunc func(ctx context.Context, group *fleet.SCIMGroup) error
+
+type DeleteSCIMGroupFunc func(ctx context.Context, id uint) error
+
+type SCIMUserByIDFunc func(ctx context.Context, id uint) (*fleet.User, error)
+
+type ListSCIMUsersFunc func(ctx context.Context, opt fleet.SCIMUserListOptions) ([]*fleet.User, int, error)
+
 type NewAppConfigFunc func(ctx context.Context, info *fleet.AppConfig) (*fleet.AppConfig, error)
 
 type AppConfigFunc func(ctx context.Context) (*fleet.AppConfig, error)
@@ -221,6 +267,12 @@ type ScheduledQueryFunc func(ctx context.Context, id uint) (*fleet.ScheduledQuer
 
 type CleanupOrphanScheduledQueryStatsFunc func(ctx context.Context) error
 
+type SaveScheduledQueryResultsFunc func(ctx context.Context, hostID uint, logs []*fleet.ScheduledQueryResultLog, maxRows int) error
+
+type ListScheduledQueryResultsFunc func(ctx context.Context, filter fleet.TeamFilter, opt fleet.ScheduledQueryResultsListOptions) ([]*fleet.ScheduledQueryResult, error)
+
+type CleanupOrphanScheduledQueryResultsFunc func(ctx context.Context) error
+
 type NewTeamFunc func(ctx context.Context, team *fleet.Team) (*fleet.Team, error)
 
 type SaveTeamFunc func(ctx context.Context, team *fleet.Team) (*fleet.Team, error)
@@ -249,6 +301,24 @@ type AllCPEsFunc func(ctx context.Context) ([]string, error)
 
 type InsertCVEForCPEFunc func(ctx context.Context, cve string, cpes []string) error
 
+type ListDistroSoftwareFunc func(ctx context.Context) ([]fleet.DistroSoftware, error)
+
+type InsertCVEForSoftwareFunc func(ctx context.Context, cve string, softwareIDs []uint) error
+
+type ListSoftwareCVEsFunc func(ctx context.Context) ([]string, error)
+
+type InsertCVEMetaFunc func(ctx context.Context, meta []fleet.CVEMeta) error
+
+type SyncVulnerableHostCVEsFunc func(ctx context.Context, queue bool) error
+
+type ListQueuedVulnerableCVEsFunc func(ctx context.Context) ([]string, error)
+
+type ListQueuedVulnerableHostsFunc func(ctx context.Context, cve string, limit int) ([]*fleet.VulnerableHost, error)
+
+type MarkVulnerableHostsSentFunc func(ctx context.Context, cve string, hostIDs []uint) error
+
+type ListHostsByCVEFunc func(ctx context.Context, filter fleet.TeamFilter, cve string, opt fleet.ListOptions) ([]*fleet.Host, error)
+
 type NewActivityFunc func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error
 
 type ListActivitiesFunc func(ctx context.Context, opt fleet.ListOptions) ([]*fleet.Activity, error)
@@ -257,7 +327,7 @@ type ShouldSendStatisticsFunc func(ctx context.Context, frequency time.Duration)
 
 type RecordStatisticsSentFunc func(ctx context.Context) error
 
-type NewGlobalPolicyFunc func(ctx context.Context, queryID uint) (*fleet.Policy, error)
+type NewGlobalPolicyFunc func(ctx context.Context, args fleet.PolicyPayload) (*fleet.Policy, error)
 
 type PolicyFunc func(ctx context.Context, id uint) (*fleet.Policy, error)
 
@@ -269,15 +339,51 @@ type DeleteGlobalPoliciesFunc func(ctx context.Context, ids []uint) ([]uint, err
 
 type PolicyQueriesForHostFunc func(ctx context.Context, host *fleet.Host) (map[string]string, error)
 
+type ApplyPolicySpecsFunc func(ctx context.Context, specs []*fleet.PolicySpec) error
+
+type NewlyFailingPoliciesForHostFunc func(ctx context.Context, hostID uint, results map[uint]*bool) ([]uint, error)
+
+type QueueFailingPolicyHostFunc func(ctx context.Context, hostID uint, policyIDs []uint, updated time.Time) error
+
+type ListQueuedFailingPolicyHostsFunc func(ctx context.Context, policyID uint, limit int) ([]*fleet.PolicyFailingHost, error)
+
+type DeleteQueuedFailingPolicyHostsFunc func(ctx context.Context, ids []uint) error
+
+type ListPoliciesHistoryFunc func(ctx context.Context, filter fleet.TeamFilter, since time.Time, now time.Time) ([]*fleet.PolicyHistorySummary, error)
+
+type PolicyHistoryFunc func(ctx context.Context, filter fleet.TeamFilter, policyID uint, opt fleet.PolicyHistoryOptions, since time.Time, now time.Time) ([]*fleet.PolicyTransition, error)
+
+type CleanupPolicyMembershipHistoryFunc func(ctx context.Context, before time.Time) error
+
 type MigrateTablesFunc func(ctx context.Context) error
 
 type MigrateDataFunc func(ctx context.Context) error
 
 type MigrationStatusFunc func(ctx context.Context) (fleet.MigrationStatus, error)
 
-type ListSoftwareFunc func(ctx context.Context, teamId *uint, opt fleet.ListOptions) ([]fleet.Software, error)
+type ListSoftwareFunc func(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, error)
+
+type CalculateSoftwareHostCountsFunc func(ctx context.Context, updatedAt time.Time) error
+
+type OSVersionsFunc func(ctx context.Context, opt fleet.OSVersionsOptions) (*fleet.OSVersions, error)
+
+type CalculateOSVersionHostCountsFunc func(ctx context.Context, updatedAt time.Time) error
+
+type NewQuerySweepFunc func(ctx context.Context, sweep *fleet.QuerySweep) (*fleet.QuerySweep, error)
+
+type QuerySweepFunc func(ctx context.Context, id uint) (*fleet.QuerySweep, error)
+
+type ListQuerySweepsFunc func(ctx context.Context, opt fleet.ListOptions) ([]*fleet.QuerySweep, error)
+
+type SaveQuerySweepFunc func(ctx context.Context, sweep *fleet.QuerySweep) error
+
+type DeleteQuerySweepFunc func(ctx context.Context, id uint) error
+
+type QuerySweepHostResultsFunc func(ctx context.Context, sweepID uint, hostIDs []uint) (map[uint][]map[string]string, error)
+
+type SaveQuerySweepHostResultsFunc func(ctx context.Context, sweepID uint, results map[uint][]map[string]string) error
 
-type NewTeamPolicyFunc func(ctx context.Context, teamID uint, queryID uint) (*fleet.Policy, error)
+type NewTeamPolicyFunc func(ctx context.Context, teamID uint, args fleet.PolicyPayload) (*fleet.Policy, error)
 
 type ListTeamPoliciesFunc func(ctx context.Context, teamID uint) ([]*fleet.Policy, error)
 
@@ -382,6 +488,33 @@ type DataStore struct {
 	CleanupDistributedQueryCampaignsFunc        CleanupDistributedQueryCampaignsFunc
 	CleanupDistributedQueryCampaignsFuncInvoked bool
 
+	SaveDistributedQueryCampaignResultFunc        SaveDistributedQueryCampaignResultFunc
+	SaveDistributedQueryCampaignResultFuncInvoked bool
+
+	ListDistributedQueryCampaignResultsFunc        ListDistributedQueryCampaignResultsFunc
+	ListDistributedQueryCampaignResultsFuncInvoked bool
+
+	CountDistributedQueryCampaignResultsFunc        CountDistributedQueryCampaignResultsFunc
+	CountDistributedQueryCampaignResultsFuncInvoked bool
+
+	CleanupDistributedQueryCampaignResultsFunc        CleanupDistributedQueryCampaignResultsFunc
+	CleanupDistributedQueryCampaignResultsFuncInvoked bool
+
+	NewDistributedQueryCampaignHostsFunc        NewDistributedQueryCampaignHostsFunc
+	NewDistributedQueryCampaignHostsFuncInvoked bool
+
+	CompleteExpiredDistributedQueryCampaignsFunc        CompleteExpiredDistributedQueryCampaignsFunc
+	CompleteExpiredDistributedQueryCampaignsFuncInvoked bool
+
+	UpdateDistributedQueryCampaignHostStatusFunc        UpdateDistributedQueryCampaignHostStatusFunc
+	UpdateDistributedQueryCampaignHostStatusFuncInvoked bool
+
+	ListDistributedQueryCampaignHostsFunc        ListDistributedQueryCampaignHostsFunc
+	ListDistributedQueryCampaignHostsFuncInvoked bool
+
+	CleanupDistributedQueryCampaignHostsFunc        CleanupDistributedQueryCampaignHostsFunc
+	CleanupDistributedQueryCampaignHostsFuncInvoked bool
+
 	ApplyPackSpecsFunc        ApplyPackSpecsFunc
 	ApplyPackSpecsFuncInvoked bool
 
@@ -511,6 +644,9 @@ type DataStore struct {
 	TotalAndUnseenHostsSinceFunc        TotalAndUnseenHostsSinceFunc
 	TotalAndUnseenHostsSinceFuncInvoked bool
 
+	LoadHostVitalsFunc        LoadHostVitalsFunc
+	LoadHostVitalsFuncInvoked bool
+
 	CountHostsInTargetsFunc        CountHostsInTargetsFunc
 	CountHostsInTargetsFuncInvoked bool
 
@@ -547,6 +683,45 @@ type DataStore struct {
 	MarkSessionAccessedFunc        MarkSessionAccessedFunc
 	MarkSessionAccessedFuncInvoked bool
 
+	NewAPITokenFunc        NewAPITokenFunc
+	NewAPITokenFuncInvoked bool
+
+	APITokenFunc        APITokenFunc
+	APITokenFuncInvoked bool
+
+	APITokenByKeyHashFunc        APITokenByKeyHashFunc
+	APITokenByKeyHashFuncInvoked bool
+
+	ListAPITokensForUserFunc        ListAPITokensForUserFunc
+	ListAPITokensForUserFuncInvoked bool
+
+	DeleteAPITokenFunc        DeleteAPITokenFunc
+	DeleteAPITokenFuncInvoked bool
+
+	MarkAPITokenUsedFunc        MarkAPITokenUsedFunc
+	MarkAPITokenUsedFuncInvoked bool
+
+	NewSCIMGroupFunc        NewSCIMGroupFunc
+	NewSCIMGroupFuncInvoked bool
+
+	SCIMGroupFunc        SCIMGroupFunc
+	SCIMGroupFuncInvoked bool
+
+	ListSCIMGroupsFunc        ListSCIMGroupsFunc
+	ListSCIMGroupsFuncInvoked bool
+
+	SaveSCIMGroupFunc        SaveSCIMGroupFunc
+	SaveSCIMGroupFuncInvoked bool
+
+	DeleteSCIMGroupFunc        DeleteSCIMGroupFunc
+	DeleteSCIMGroupFuncInvoked bool
+
+	SCIMUserByIDFunc        SCIMUserByIDFunc
+	SCIMUserByIDFuncInvoked bool
+
+	ListSCIMUsersFunc        ListSCIMUsersFunc
+	ListSCIMUsersFuncInvoked bool
+
 	NewAppConfigFunc        NewAppConfigFunc
 	NewAppConfigFuncInvoked bool
 
@@ -601,6 +776,15 @@ type DataStore struct {
 	CleanupOrphanScheduledQueryStatsFunc        CleanupOrphanScheduledQueryStatsFunc
 	CleanupOrphanScheduledQueryStatsFuncInvoked bool
 
+	SaveScheduledQueryResultsFunc        SaveScheduledQueryResultsFunc
+	SaveScheduledQueryResultsFuncInvoked bool
+
+	ListScheduledQueryResultsFunc        ListScheduledQueryResultsFunc
+	ListScheduledQueryResultsFuncInvoked bool
+
+	CleanupOrphanScheduledQueryResultsFunc        CleanupOrphanScheduledQueryResultsFunc
+	CleanupOrphanScheduledQueryResultsFuncInvoked bool
+
 	NewTeamFunc        NewTeamFunc
 	NewTeamFuncInvoked bool
 
@@ -643,6 +827,33 @@ type DataStore struct {
 	InsertCVEForCPEFunc        InsertCVEForCPEFunc
 	InsertCVEForCPEFuncInvoked bool
 
+	ListDistroSoftwareFunc        ListDistroSoftwareFunc
+	ListDistroSoftwareFuncInvoked bool
+
+	InsertCVEForSoftwareFunc        InsertCVEForSoftwareFunc
+	InsertCVEForSoftwareFuncInvoked bool
+
+	ListSoftwareCVEsFunc        ListSoftwareCVEsFunc
+	ListSoftwareCVEsFuncInvoked bool
+
+	InsertCVEMetaFunc        InsertCVEMetaFunc
+	InsertCVEMetaFuncInvoked bool
+
+	SyncVulnerableHostCVEsFunc        SyncVulnerableHostCVEsFunc
+	SyncVulnerableHostCVEsFuncInvoked bool
+
+	ListQueuedVulnerableCVEsFunc        ListQueuedVulnerableCVEsFunc
+	ListQueuedVulnerableCVEsFuncInvoked bool
+
+	ListQueuedVulnerableHostsFunc        ListQueuedVulnerableHostsFunc
+	ListQueuedVulnerableHostsFuncInvoked bool
+
+	MarkVulnerableHostsSentFunc        MarkVulnerableHostsSentFunc
+	MarkVulnerableHostsSentFuncInvoked bool
+
+	ListHostsByCVEFunc        ListHostsByCVEFunc
+	ListHostsByCVEFuncInvoked bool
+
 	NewActivityFunc        NewActivityFunc
 	NewActivityFuncInvoked bool
 
@@ -673,6 +884,30 @@ type DataStore struct {
 	PolicyQueriesForHostFunc        PolicyQueriesForHostFunc
 	PolicyQueriesForHostFuncInvoked bool
 
+	ApplyPolicySpecsFunc        ApplyPolicySpecsFunc
+	ApplyPolicySpecsFuncInvoked bool
+
+	NewlyFailingPoliciesForHostFunc        NewlyFailingPoliciesForHostFunc
+	NewlyFailingPoliciesForHostFuncInvoked bool
+
+	QueueFailingPolicyHostFunc        QueueFailingPolicyHostFunc
+	QueueFailingPolicyHostFuncInvoked bool
+
+	ListQueuedFailingPolicyHostsFunc        ListQueuedFailingPolicyHostsFunc
+	ListQueuedFailingPolicyHostsFuncInvoked bool
+
+	DeleteQueuedFailingPolicyHostsFunc        DeleteQueuedFailingPolicyHostsFunc
+	DeleteQueuedFailingPolicyHostsFuncInvoked bool
+
+	ListPoliciesHistoryFunc        ListPoliciesHistoryFunc
+	ListPoliciesHistoryFuncInvoked bool
+
+	PolicyHistoryFunc        PolicyHistoryFunc
+	PolicyHistoryFuncInvoked bool
+
+	CleanupPolicyMembershipHistoryFunc        CleanupPolicyMembershipHistoryFunc
+	CleanupPolicyMembershipHistoryFuncInvoked bool
+
 	MigrateTablesFunc        MigrateTablesFunc
 	MigrateTablesFuncInvoked bool
 
@@ -685,6 +920,36 @@ type DataStore struct {
 	ListSoftwareFunc        ListSoftwareFunc
 	ListSoftwareFuncInvoked bool
 
+	CalculateSoftwareHostCountsFunc        CalculateSoftwareHostCountsFunc
+	CalculateSoftwareHostCountsFuncInvoked bool
+
+	OSVersionsFunc        OSVersionsFunc
+	OSVersionsFuncInvoked bool
+
+	CalculateOSVersionHostCountsFunc        CalculateOSVersionHostCountsFunc
+	CalculateOSVersionHostCountsFuncInvoked bool
+
+	NewQuerySweepFunc        NewQuerySweepFunc
+	NewQuerySweepFuncInvoked bool
+
+	QuerySweepFunc        QuerySweepFunc
+	QuerySweepFuncInvoked bool
+
+	ListQuerySweepsFunc        ListQuerySweepsFunc
+	ListQuerySweepsFuncInvoked bool
+
+	SaveQuerySweepFunc        SaveQuerySweepFunc
+	SaveQuerySweepFuncInvoked bool
+
+	DeleteQuerySweepFunc        DeleteQuerySweepFunc
+	DeleteQuerySweepFuncInvoked bool
+
+	QuerySweepHostResultsFunc        QuerySweepHostResultsFunc
+	QuerySweepHostResultsFuncInvoked bool
+
+	SaveQuerySweepHostResultsFunc        SaveQuerySweepHostResultsFunc
+	SaveQuerySweepHostResultsFuncInvoked bool
+
 	NewTeamPolicyFunc        NewTeamPolicyFunc
 	NewTeamPolicyFuncInvoked bool
 
@@ -858,6 +1123,51 @@ func (s *DataStore) CleanupDistributedQueryCampaigns(ctx context.Context, now ti
 	return s.CleanupDistributedQueryCampaignsFunc(ctx, now)
 }
 
+func (s *DataStore) SaveDistributedQueryCampaignResult(ctx context.Context, result *fleet.DistributedQueryCampaignResult) error {
+	s.SaveDistributedQueryCampaignResultFuncInvoked = true
+	return s.SaveDistributedQueryCampaignResultFunc(ctx, result)
+}
+
+func (s *DataStore) ListDistributedQueryCampaignResults(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignResult, error) {
+	s.ListDistributedQueryCampaignResultsFuncInvoked = true
+	return s.ListDistributedQueryCampaignResultsFunc(ctx, campaignID, opt)
+}
+
+func (s *DataStore) CountDistributedQueryCampaignResults(ctx context.Context, campaignID uint) (uint, error) {
+	s.CountDistributedQueryCampaignResultsFuncInvoked = true
+	return s.CountDistributedQueryCampaignResultsFunc(ctx, campaignID)
+}
+
+func (s *DataStore) CleanupDistributedQueryCampaignResults(ctx context.Context, olderThan time.Time) error {
+	s.CleanupDistributedQueryCampaignResultsFuncInvoked = true
+	return s.CleanupDistributedQueryCampaignResultsFunc(ctx, olderThan)
+}
+
+func (s *DataStore) NewDistributedQueryCampaignHosts(ctx context.Context, campaignID uint, hostIDs []uint) error {
+	s.NewDistributedQueryCampaignHostsFuncInvoked = true
+	return s.NewDistributedQueryCampaignHostsFunc(ctx, campaignID, hostIDs)
+}
+
+func (s *DataStore) CompleteExpiredDistributedQueryCampaigns(ctx context.Context, createdBefore time.Time) ([]uint, error) {
+	s.CompleteExpiredDistributedQueryCampaignsFuncInvoked = true
+	return s.CompleteExpiredDistributedQueryCampaignsFunc(ctx, createdBefore)
+}
+
+func (s *DataStore) UpdateDistributedQueryCampaignHostStatus(ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string) error {
+	s.UpdateDistributedQueryCampaignHostStatusFuncInvoked = true
+	return s.UpdateDistributedQueryCampaignHostStatusFunc(ctx, campaignID, hostID, status, rowsCount, errMsg)
+}
+
+func (s *DataStore) ListDistributedQueryCampaignHosts(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignHost, error) {
+	s.ListDistributedQueryCampaignHostsFuncInvoked = true
+	return s.ListDistributedQueryCampaignHostsFunc(ctx, campaignID, opt)
+}
+
+func (s *DataStore) CleanupDistributedQueryCampaignHosts(ctx context.Context, olderThan time.Time) error {
+	s.CleanupDistributedQueryCampaignHostsFuncInvoked = true
+	return s.CleanupDistributedQueryCampaignHostsFunc(ctx, olderThan)
+}
+
 func (s *DataStore) ApplyPackSpecs(ctx context.Context, specs []*fleet.PackSpec) error {
 	s.ApplyPackSpecsFuncInvoked = true
 	return s.ApplyPackSpecsFunc(ctx, specs)
@@ -1073,6 +1383,11 @@ func (s *DataStore) TotalAndUnseenHostsSince(ctx context.Context, daysCount int)
 	return s.TotalAndUnseenHostsSinceFunc(ctx, daysCount)
 }
 
+func (s *DataStore) LoadHostVitals(ctx context.Context, host *fleet.Host) error {
+	s.LoadHostVitalsFuncInvoked = true
+	return s.LoadHostVitalsFunc(ctx, host)
+}
+
 func (s *DataStore) CountHostsInTargets(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
 	s.CountHostsInTargetsFuncInvoked = true
 	return s.CountHostsInTargetsFunc(ctx, filter, targets, now)
@@ -1133,6 +1448,71 @@ func (s *DataStore) MarkSessionAccessed(ctx context.Context, session *fleet.Sess
 	return s.MarkSessionAccessedFunc(ctx, session)
 }
 
+func (s *DataStore) NewAPIToken(ctx context.Context, token *fleet.APIToken) (*fleet.APIToken, error) {
+	s.NewAPITokenFuncInvoked = true
+	return s.NewAPITokenFunc(ctx, token)
+}
+
+func (s *DataStore) APIToken(ctx context.Context, id uint) (*fleet.APIToken, error) {
+	s.APITokenFuncInvoked = true
+	return s.APITokenFunc(ctx, id)
+}
+
+func (s *DataStore) APITokenByKeyHash(ctx context.Context, keyHash string) (*fleet.APIToken, error) {
+	s.APITokenByKeyHashFuncInvoked = true
+	return s.APITokenByKeyHashFunc(ctx, keyHash)
+}
+
+func (s *DataStore) ListAPITokensForUser(ctx context.Context, userID uint) ([]*fleet.APIToken, error) {
+	s.ListAPITokensForUserFuncInvoked = true
+	return s.ListAPITokensForUserFunc(ctx, userID)
+}
+
+func (s *DataStore) DeleteAPIToken(ctx context.Context, id uint) error {
+	s.DeleteAPITokenFuncInvoked = true
+	return s.DeleteAPITokenFunc(ctx, id)
+}
+
+func (s *DataStore) MarkAPITokenUsed(ctx context.Context, id uint, usedAt time.Time) error {
+	s.MarkAPITokenUsedFuncInvoked = true
+	return s.MarkAPITokenUsedFunc(ctx, id, usedAt)
+}
+
+func (s *DataStore) NewSCIMGroup(ctx context.Context, group *fleet.SCIMGroup) (*fleet.SCIMGroup, error) {
+	s.NewSCIMGroupFuncInvoked = true
+	return s.NewSCIMGroupFunc(ctx, group)
+}
+
+func (s *DataStore) SCIMGroup(ctx context.Context, id uint) (*fleet.SCIMGroup, error) {
+	s.SCIMGroupFuncInvoked = true
+	return s.SCIMGroupFunc(ctx, id)
+}
+
+func (s *DataStore) ListSCIMGroups(ctx context.Context) ([]*fleet.SCIMGroup, error) {
+	s.ListSCIMGroupsFuncInvoked = true
+	return s.ListSCIMGroupsFunc(ctx)
+}
+
+func (s *DataStore) SaveSCIMGroup(ctx context.Context, group *fleet.SCIMGroup) error {
+	s.SaveSCIMGroupFuncInvoked = true
+	return s.SaveSCIMGroupFunc(ctx, group)
+}
+
+func (s *DataStore) DeleteSCIMGroup(ctx context.Context, id uint) error {
+	s.DeleteSCIMGroupFuncInvoked = true
+	return s.DeleteSCIMGroupFunc(ctx, id)
+}
+
+func (s *DataStore) SCIMUserByID(ctx context.Context, id uint) (*fleet.User, error) {
+	s.SCIMUserByIDFuncInvoked = true
+	return s.SCIMUserByIDFunc(ctx, id)
+}
+
+func (s *DataStore) ListSCIMUsers(ctx context.Context, opt fleet.SCIMUserListOptions) ([]*fleet.User, int, error) {
+	s.ListSCIMUsersFuncInvoked = true
+	return s.ListSCIMUsersFunc(ctx, opt)
+}
+
 func (s *DataStore) NewAppConfig(ctx context.Context, info *fleet.AppConfig) (*fleet.AppConfig, error) {
 	s.NewAppConfigFuncInvoked = true
 	return s.NewAppConfigFunc(ctx, info)
@@ -1223,6 +1603,21 @@ func (s *DataStore) CleanupOrphanScheduledQueryStats(ctx context.Context) error
 	return s.CleanupOrphanScheduledQueryStatsFunc(ctx)
 }
 
+func (s *DataStore) SaveScheduledQueryResults(ctx context.Context, hostID uint, logs []*fleet.ScheduledQueryResultLog, maxRows int) error {
+	s.SaveScheduledQueryResultsFuncInvoked = true
+	return s.SaveScheduledQueryResultsFunc(ctx, hostID, logs, maxRows)
+}
+
+func (s *DataStore) ListScheduledQueryResults(ctx context.Context, filter fleet.TeamFilter, opt fleet.ScheduledQueryResultsListOptions) ([]*fleet.ScheduledQueryResult, error) {
+	s.ListScheduledQueryResultsFuncInvoked = true
+	return s.ListScheduledQueryResultsFunc(ctx, filter, opt)
+}
+
+func (s *DataStore) CleanupOrphanScheduledQueryResults(ctx context.Context) error {
+	s.CleanupOrphanScheduledQueryResultsFuncInvoked = true
+	return s.CleanupOrphanScheduledQueryResultsFunc(ctx)
+}
+
 func (s *DataStore) NewTeam(ctx context.Context, team *fleet.Team) (*fleet.Team, error) {
 	s.NewTeamFuncInvoked = true
 	return s.NewTeamFunc(ctx, team)
@@ -1293,6 +1688,51 @@ func (s *DataStore) InsertCVEForCPE(ctx context.Context, cve string, cpes []stri
 	return s.InsertCVEForCPEFunc(ctx, cve, cpes)
 }
 
+func (s *DataStore) ListDistroSoftware(ctx context.Context) ([]fleet.DistroSoftware, error) {
+	s.ListDistroSoftwareFuncInvoked = true
+	return s.ListDistroSoftwareFunc(ctx)
+}
+
+func (s *DataStore) InsertCVEForSoftware(ctx context.Context, cve string, softwareIDs []uint) error {
+	s.InsertCVEForSoftwareFuncInvoked = true
+	return s.InsertCVEForSoftwareFunc(ctx, cve, softwareIDs)
+}
+
+func (s *DataStore) ListSoftwareCVEs(ctx context.Context) ([]string, error) {
+	s.ListSoftwareCVEsFuncInvoked = true
+	return s.ListSoftwareCVEsFunc(ctx)
+}
+
+func (s *DataStore) InsertCVEMeta(ctx context.Context, meta []fleet.CVEMeta) error {
+	s.InsertCVEMetaFuncInvoked = true
+	return s.InsertCVEMetaFunc(ctx, meta)
+}
+
+func (s *DataStore) SyncVulnerableHostCVEs(ctx context.Context, queue bool) error {
+	s.SyncVulnerableHostCVEsFuncInvoked = true
+	return s.SyncVulnerableHostCVEsFunc(ctx, queue)
+}
+
+func (s *DataStore) ListQueuedVulnerableCVEs(ctx context.Context) ([]string, error) {
+	s.ListQueuedVulnerableCVEsFuncInvoked = true
+	return s.ListQueuedVulnerableCVEsFunc(ctx)
+}
+
+func (s *DataStore) ListQueuedVulnerableHosts(ctx context.Context, cve string, limit int) ([]*fleet.VulnerableHost, error) {
+	s.ListQueuedVulnerableHostsFuncInvoked = true
+	return s.ListQueuedVulnerableHostsFunc(ctx, cve, limit)
+}
+
+func (s *DataStore) MarkVulnerableHostsSent(ctx context.Context, cve string, hostIDs []uint) error {
+	s.MarkVulnerableHostsSentFuncInvoked = true
+	return s.MarkVulnerableHostsSentFunc(ctx, cve, hostIDs)
+}
+
+func (s *DataStore) ListHostsByCVE(ctx context.Context, filter fleet.TeamFilter, cve string, opt fleet.ListOptions) ([]*fleet.Host, error) {
+	s.ListHostsByCVEFuncInvoked = true
+	return s.ListHostsByCVEFunc(ctx, filter, cve, opt)
+}
+
 func (s *DataStore) NewActivity(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
 	s.NewActivityFuncInvoked = true
 	return s.NewActivityFunc(ctx, user, activityType, details)
@@ -1313,9 +1753,9 @@ func (s *DataStore) RecordStatisticsSent(ctx context.Context) error {
 	return s.RecordStatisticsSentFunc(ctx)
 }
 
-func (s *DataStore) NewGlobalPolicy(ctx context.Context, queryID uint) (*fleet.Policy, error) {
+func (s *DataStore) NewGlobalPolicy(ctx context.Context, args fleet.PolicyPayload) (*fleet.Policy, error) {
 	s.NewGlobalPolicyFuncInvoked = true
-	return s.NewGlobalPolicyFunc(ctx, queryID)
+	return s.NewGlobalPolicyFunc(ctx, args)
 }
 
 func (s *DataStore) Policy(ctx context.Context, id uint) (*fleet.Policy, error) {
@@ -1343,6 +1783,46 @@ func (s *DataStore) PolicyQueriesForHost(ctx context.Context, host *fleet.Host)
 	return s.PolicyQueriesForHostFunc(ctx, host)
 }
 
+func (s *DataStore) ApplyPolicySpecs(ctx context.Context, specs []*fleet.PolicySpec) error {
+	s.ApplyPolicySpecsFuncInvoked = true
+	return s.ApplyPolicySpecsFunc(ctx, specs)
+}
+
+func (s *DataStore) NewlyFailingPoliciesForHost(ctx context.Context, hostID uint, results map[uint]*bool) ([]uint, error) {
+	s.NewlyFailingPoliciesForHostFuncInvoked = true
+	return s.NewlyFailingPoliciesForHostFunc(ctx, hostID, results)
+}
+
+func (s *DataStore) QueueFailingPolicyHost(ctx context.Context, hostID uint, policyIDs []uint, updated time.Time) error {
+	s.QueueFailingPolicyHostFuncInvoked = true
+	return s.QueueFailingPolicyHostFunc(ctx, hostID, policyIDs, updated)
+}
+
+func (s *DataStore) ListQueuedFailingPolicyHosts(ctx context.Context, policyID uint, limit int) ([]*fleet.PolicyFailingHost, error) {
+	s.ListQueuedFailingPolicyHostsFuncInvoked = true
+	return s.ListQueuedFailingPolicyHostsFunc(ctx, policyID, limit)
+}
+
+func (s *DataStore) DeleteQueuedFailingPolicyHosts(ctx context.Context, ids []uint) error {
+	s.DeleteQueuedFailingPolicyHostsFuncInvoked = true
+	return s.DeleteQueuedFailingPolicyHostsFunc(ctx, ids)
+}
+
+func (s *DataStore) ListPoliciesHistory(ctx context.Context, filter fleet.TeamFilter, since time.Time, now time.Time) ([]*fleet.PolicyHistorySummary, error) {
+	s.ListPoliciesHistoryFuncInvoked = true
+	return s.ListPoliciesHistoryFunc(ctx, filter, since, now)
+}
+
+func (s *DataStore) PolicyHistory(ctx context.Context, filter fleet.TeamFilter, policyID uint, opt fleet.PolicyHistoryOptions, since time.Time, now time.Time) ([]*fleet.PolicyTransition, error) {
+	s.PolicyHistoryFuncInvoked = true
+	return s.PolicyHistoryFunc(ctx, filter, policyID, opt, since, now)
+}
+
+func (s *DataStore) CleanupPolicyMembershipHistory(ctx context.Context, before time.Time) error {
+	s.CleanupPolicyMembershipHistoryFuncInvoked = true
+	return s.CleanupPolicyMembershipHistoryFunc(ctx, before)
+}
+
 func (s *DataStore) MigrateTables(ctx context.Context) error {
 	s.MigrateTablesFuncInvoked = true
 	return s.MigrateTablesFunc(ctx)
@@ -1358,14 +1838,64 @@ func (s *DataStore) MigrationStatus(ctx context.Context) (fleet.MigrationStatus,
 	return s.MigrationStatusFunc(ctx)
 }
 
-func (s *DataStore) ListSoftware(ctx context.Context, teamId *uint, opt fleet.ListOptions) ([]fleet.Software, error) {
+func (s *DataStore) ListSoftware(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, error) {
 	s.ListSoftwareFuncInvoked = true
-	return s.ListSoftwareFunc(ctx, teamId, opt)
+	return s.ListSoftwareFunc(ctx, opt)
+}
+
+func (s *DataStore) CalculateSoftwareHostCounts(ctx context.Context, updatedAt time.Time) error {
+	s.CalculateSoftwareHostCountsFuncInvoked = true
+	return s.CalculateSoftwareHostCountsFunc(ctx, updatedAt)
+}
+
+func (s *DataStore) OSVersions(ctx context.Context, opt fleet.OSVersionsOptions) (*fleet.OSVersions, error) {
+	s.OSVersionsFuncInvoked = true
+	return s.OSVersionsFunc(ctx, opt)
+}
+
+func (s *DataStore) CalculateOSVersionHostCounts(ctx context.Context, updatedAt time.Time) error {
+	s.CalculateOSVersionHostCountsFuncInvoked = true
+	return s.CalculateOSVersionHostCountsFunc(ctx, updatedAt)
+}
+
+func (s *DataStore) NewQuerySweep(ctx context.Context, sweep *fleet.QuerySweep) (*fleet.QuerySweep, error) {
+	s.NewQuerySweepFuncInvoked = true
+	return s.NewQuerySweepFunc(ctx, sweep)
+}
+
+func (s *DataStore) QuerySweep(ctx context.Context, id uint) (*fleet.QuerySweep, error) {
+	s.QuerySweepFuncInvoked = true
+	return s.QuerySweepFunc(ctx, id)
+}
+
+func (s *DataStore) ListQuerySweeps(ctx context.Context, opt fleet.ListOptions) ([]*fleet.QuerySweep, error) {
+	s.ListQuerySweepsFuncInvoked = true
+	return s.ListQuerySweepsFunc(ctx, opt)
+}
+
+func (s *DataStore) SaveQuerySweep(ctx context.Context, sweep *fleet.QuerySweep) error {
+	s.SaveQuerySweepFuncInvoked = true
+	return s.SaveQuerySweepFunc(ctx, sweep)
+}
+
+func (s *DataStore) DeleteQuerySweep(ctx context.Context, id uint) error {
+	s.DeleteQuerySweepFuncInvoked = true
+	return s.DeleteQuerySweepFunc(ctx, id)
+}
+
+func (s *DataStore) QuerySweepHostResults(ctx context.Context, sweepID uint, hostIDs []uint) (map[uint][]map[string]string, error) {
+	s.QuerySweepHostResultsFuncInvoked = true
+	return s.QuerySweepHostResultsFunc(ctx, sweepID, hostIDs)
+}
+
+func (s *DataStore) SaveQuerySweepHostResults(ctx context.Context, sweepID uint, results map[uint][]map[string]string) error {
+	s.SaveQuerySweepHostResultsFuncInvoked = true
+	return s.SaveQuerySweepHostResultsFunc(ctx, sweepID, results)
 }
 
-func (s *DataStore) NewTeamPolicy(ctx context.Context, teamID uint, queryID uint) (*fleet.Policy, error) {
+func (s *DataStore) NewTeamPolicy(ctx context.Context, teamID uint, args fleet.PolicyPayload) (*fleet.Policy, error) {
 	s.NewTeamPolicyFuncInvoked = true
-	return s.NewTeamPolicyFunc(ctx, teamID, queryID)
+	return s.NewTeamPolicyFunc(ctx, teamID, args)
 }
 
 func (s *DataStore) ListTeamPolicies(ctx context.Context, teamID uint) ([]*fleet.Policy, error) {
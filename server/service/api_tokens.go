@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// apiTokenKeySize is the number of random bytes of the keys of the API
+// tokens.
+const apiTokenKeySize = 32
+
+// apiTokenUsedResolution is how often the last time an API token was used is
+// updated, to avoid writing to the database on each request.
+const apiTokenUsedResolution = time.Minute
+
+/////////////////////////////////////////////////////////////////////////////////
+// Create
+/////////////////////////////////////////////////////////////////////////////////
+
+type createAPITokenRequest struct {
+	fleet.APITokenPayload
+}
+
+type createAPITokenResponse struct {
+	APIToken *fleet.APIToken `json:"api_token,omitempty"`
+	Err      error           `json:"error,omitempty"`
+}
+
+func (r createAPITokenResponse) error() error { return r.Err }
+
+func createAPITokenEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*createAPITokenRequest)
+	token, err := svc.CreateAPIToken(ctx, req.APITokenPayload)
+	if err != nil {
+		return createAPITokenResponse{Err: err}, nil
+	}
+	return createAPITokenResponse{APIToken: token}, nil
+}
+
+func (svc Service) CreateAPIToken(ctx context.Context, payload fleet.APITokenPayload) (*fleet.APIToken, error) {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.APIToken{UserID: vc.UserID()}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Name == "" {
+		return nil, fleet.NewInvalidArgumentError("name", "API token name cannot be empty")
+	}
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(svc.clock.Now()) {
+		return nil, fleet.NewInvalidArgumentError("expires_at", "API token expiration must be in the future")
+	}
+	// The token cannot give access to teams the user has no role in.
+	if vc.User.GlobalRole == nil {
+		for _, teamID := range payload.TeamIDs {
+			member := false
+			for _, team := range vc.User.Teams {
+				member = member || team.ID == teamID
+			}
+			if !member {
+				return nil, fleet.NewInvalidArgumentError("team_ids", "user is not a member of the team")
+			}
+		}
+	}
+
+	rawKey := make([]byte, apiTokenKeySize)
+	if _, err := rand.Read(rawKey); err != nil {
+		return nil, errors.Wrap(err, "generate api token key")
+	}
+	key := fleet.APITokenPrefix + base64.RawURLEncoding.EncodeToString(rawKey)
+
+	token, err := svc.ds.NewAPIToken(ctx, &fleet.APIToken{
+		UserID:    vc.UserID(),
+		Name:      payload.Name,
+		KeyHash:   fleet.HashAPITokenKey(key),
+		ReadOnly:  payload.ReadOnly,
+		TeamIDs:   payload.TeamIDs,
+		ExpiresAt: payload.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	token.Key = key
+	return token, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// List
+/////////////////////////////////////////////////////////////////////////////////
+
+type listAPITokensRequest struct{}
+
+type listAPITokensResponse struct {
+	APITokens []*fleet.APIToken `json:"api_tokens"`
+	Err       error             `json:"error,omitempty"`
+}
+
+func (r listAPITokensResponse) error() error { return r.Err }
+
+func listAPITokensEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	tokens, err := svc.ListAPITokens(ctx)
+	if err != nil {
+		return listAPITokensResponse{Err: err}, nil
+	}
+	return listAPITokensResponse{APITokens: tokens}, nil
+}
+
+func (svc Service) ListAPITokens(ctx context.Context) ([]*fleet.APIToken, error) {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	if err := svc.authz.Authorize(ctx, &fleet.APIToken{UserID: vc.UserID()}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.ListAPITokensForUser(ctx, vc.UserID())
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Delete
+/////////////////////////////////////////////////////////////////////////////////
+
+type deleteAPITokenRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteAPITokenResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteAPITokenResponse) error() error { return r.Err }
+
+func deleteAPITokenEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*deleteAPITokenRequest)
+	if err := svc.DeleteAPIToken(ctx, req.ID); err != nil {
+		return deleteAPITokenResponse{Err: err}, nil
+	}
+	return deleteAPITokenResponse{}, nil
+}
+
+func (svc Service) DeleteAPIToken(ctx context.Context, id uint) error {
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return fleet.ErrNoContext
+	}
+	token, err := svc.ds.APIToken(ctx, id)
+	if err != nil {
+		// Check the authorization before returning the error, so that the
+		// request is not rejected for a missing authorization check.
+		if authErr := svc.authz.Authorize(ctx, &fleet.APIToken{UserID: vc.UserID()}, fleet.ActionWrite); authErr != nil {
+			return authErr
+		}
+		return err
+	}
+	// Admins can revoke the tokens of all the users.
+	if err := svc.authz.Authorize(ctx, token, fleet.ActionWrite); err != nil {
+		return err
+	}
+	return svc.ds.DeleteAPIToken(ctx, id)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Authenticate
+/////////////////////////////////////////////////////////////////////////////////
+
+func (svc Service) GetAPITokenByKey(ctx context.Context, key string) (*fleet.APIToken, error) {
+	// skipauth: The token is used to authenticate the user.
+	svc.authz.SkipAuthorization(ctx)
+
+	token, err := svc.ds.APITokenByKeyHash(ctx, fleet.HashAPITokenKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	now := svc.clock.Now()
+	if token.Expired(now) {
+		return nil, errors.New("expired API token")
+	}
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenUsedResolution {
+		if err := svc.ds.MarkAPITokenUsed(ctx, token.ID, now); err != nil {
+			// The request is still authenticated.
+			level.Info(svc.logger).Log("msg", "mark api token used", "id", token.ID, "err", err)
+		} else {
+			token.LastUsedAt = &now
+		}
+	}
+	return token, nil
+}
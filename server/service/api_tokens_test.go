@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAPIToken(t *testing.T) {
+	ds := new(mock.Store)
+	var stored *fleet.APIToken
+	ds.NewAPITokenFunc = func(ctx context.Context, token *fleet.APIToken) (*fleet.APIToken, error) {
+		stored = token
+		token.ID = 1
+		return token, nil
+	}
+	mockClock := clock.NewMockClock()
+	svc := newTestServiceWithClock(ds, nil, nil, mockClock)
+
+	teamUser := &fleet.User{ID: 4, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}}
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: teamUser})
+	token, err := svc.CreateAPIToken(ctx, fleet.APITokenPayload{
+		Name:      " ci ",
+		ReadOnly:  true,
+		TeamIDs:   []uint{1},
+		ExpiresAt: ptr.Time(mockClock.Now().Add(time.Hour)),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "ci", token.Name)
+	assert.Equal(t, uint(4), token.UserID)
+	assert.True(t, token.ReadOnly)
+	assert.Equal(t, []uint{1}, token.TeamIDs)
+	// Only the hash of the key is stored.
+	assert.True(t, fleet.IsAPITokenKey(token.Key))
+	assert.Equal(t, fleet.HashAPITokenKey(token.Key), stored.KeyHash)
+
+	_, err = svc.CreateAPIToken(ctx, fleet.APITokenPayload{Name: "other team", TeamIDs: []uint{2}})
+	require.Error(t, err)
+	_, err = svc.CreateAPIToken(ctx, fleet.APITokenPayload{Name: "expired", ExpiresAt: ptr.Time(mockClock.Now())})
+	require.Error(t, err)
+	_, err = svc.CreateAPIToken(ctx, fleet.APITokenPayload{Name: " "})
+	require.Error(t, err)
+
+	// API tokens cannot create API tokens.
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: teamUser, APIToken: token})
+	_, err = svc.CreateAPIToken(ctx, fleet.APITokenPayload{Name: "ci2"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), authz.ForbiddenErrorMessage)
+}
+
+func TestDeleteAPIToken(t *testing.T) {
+	ds := new(mock.Store)
+	ds.APITokenFunc = func(ctx context.Context, id uint) (*fleet.APIToken, error) {
+		return &fleet.APIToken{ID: id, UserID: test.UserObserver.ID}, nil
+	}
+	ds.DeleteAPITokenFunc = func(ctx context.Context, id uint) error {
+		return nil
+	}
+	svc := newTestService(ds, nil, nil)
+
+	// Users can revoke their tokens, and admins the tokens of all the users.
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: test.UserMaintainer})
+	require.Error(t, svc.DeleteAPIToken(ctx, 1))
+	assert.False(t, ds.DeleteAPITokenFuncInvoked)
+
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: test.UserObserver})
+	require.NoError(t, svc.DeleteAPIToken(ctx, 1))
+	assert.True(t, ds.DeleteAPITokenFuncInvoked)
+
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: test.UserAdmin})
+	require.NoError(t, svc.DeleteAPIToken(ctx, 1))
+}
+
+func TestAPITokenAuthentication(t *testing.T) {
+	ds := new(mock.Store)
+	mockClock := clock.NewMockClock()
+	key := fleet.APITokenPrefix + "secret"
+	token := &fleet.APIToken{ID: 1, UserID: test.UserAdmin.ID, TeamIDs: []uint{3}, ExpiresAt: ptr.Time(mockClock.Now().Add(time.Hour))}
+	ds.APITokenByKeyHashFunc = func(ctx context.Context, keyHash string) (*fleet.APIToken, error) {
+		if keyHash != fleet.HashAPITokenKey(key) {
+			return nil, fleet.NewAuthRequiredError("not found")
+		}
+		copy := *token
+		return &copy, nil
+	}
+	ds.MarkAPITokenUsedFunc = func(ctx context.Context, id uint, usedAt time.Time) error {
+		token.LastUsedAt = &usedAt
+		return nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return test.UserAdmin, nil
+	}
+	ds.SessionByKeyFunc = func(ctx context.Context, key string) (*fleet.Session, error) {
+		t.Fatal("API token keys are not session keys")
+		return nil, nil
+	}
+	svc := newTestServiceWithClock(ds, nil, nil, mockClock)
+
+	v, err := authViewer(context.Background(), key, svc)
+	require.NoError(t, err)
+	assert.True(t, v.IsLoggedIn())
+	assert.Nil(t, v.Session)
+	assert.Equal(t, uint(1), v.APIToken.ID)
+	// The global admin is a maintainer of the teams of the token.
+	assert.Nil(t, v.User.GlobalRole)
+	assert.Equal(t, []fleet.UserTeam{{Team: fleet.Team{ID: 3}, Role: fleet.RoleMaintainer}}, v.User.Teams)
+	require.NotNil(t, token.LastUsedAt)
+	assert.Equal(t, mockClock.Now(), *token.LastUsedAt)
+
+	// The last use is only updated once per minute.
+	ds.MarkAPITokenUsedFuncInvoked = false
+	mockClock.AddTime(30 * time.Second)
+	_, err = authViewer(context.Background(), key, svc)
+	require.NoError(t, err)
+	assert.False(t, ds.MarkAPITokenUsedFuncInvoked)
+
+	_, err = authViewer(context.Background(), fleet.APITokenPrefix+"wrong", svc)
+	require.Error(t, err)
+
+	mockClock.AddTime(time.Hour)
+	_, err = authViewer(context.Background(), key, svc)
+	var authErr *fleet.AuthRequiredError
+	require.True(t, errors.As(err, &authErr))
+	assert.Contains(t, authErr.Internal(), "expired API token")
+}
+
+func TestAPITokenChangePassword(t *testing.T) {
+	ds := new(mock.Store)
+	key := fleet.APITokenPrefix + "secret"
+	ds.APITokenByKeyHashFunc = func(ctx context.Context, keyHash string) (*fleet.APIToken, error) {
+		return &fleet.APIToken{ID: 1, UserID: 2, TeamIDs: []uint{3}}, nil
+	}
+	ds.MarkAPITokenUsedFunc = func(ctx context.Context, id uint, usedAt time.Time) error {
+		return nil
+	}
+	stored := &fleet.User{
+		ID:         2,
+		Email:      "admin@example.com",
+		GlobalRole: ptr.String(fleet.RoleAdmin),
+		Teams:      []fleet.UserTeam{},
+	}
+	require.NoError(t, stored.SetPassword("foobarbaz1234!", 24, 10))
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		user := *stored
+		return &user, nil
+	}
+	var saved *fleet.User
+	ds.SaveUserFunc = func(ctx context.Context, user *fleet.User) error {
+		saved = user
+		return nil
+	}
+	svc := newTestService(ds, nil, nil)
+
+	// API tokens cannot change the password of their user, nor modify it.
+	v, err := authViewer(context.Background(), key, svc)
+	require.NoError(t, err)
+	ctx := viewer.NewContext(context.Background(), *v)
+	err = svc.ChangePassword(ctx, "foobarbaz1234!", "12345cat!")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), authz.ForbiddenErrorMessage)
+	_, err = svc.ModifyUser(ctx, 2, fleet.UserPayload{Name: ptr.String("automation")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), authz.ForbiddenErrorMessage)
+	assert.False(t, ds.SaveUserFuncInvoked)
+
+	// The roles of the viewer are not saved with the password, but the stored
+	// roles of the user.
+	scoped := *v.User
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: &scoped, Session: &fleet.Session{ID: 1, UserID: 2}})
+	require.NoError(t, svc.ChangePassword(ctx, "foobarbaz1234!", "12345cat!"))
+	require.NotNil(t, saved)
+	require.NotNil(t, saved.GlobalRole)
+	assert.Equal(t, fleet.RoleAdmin, *saved.GlobalRole)
+	assert.Empty(t, saved.Teams)
+	assert.NoError(t, saved.ValidatePassword("12345cat!"))
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/fleetdm/fleet/v4/server/authz"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+/////////////////////////////////////////////////////////////////////////////////
+// Get campaign results
+/////////////////////////////////////////////////////////////////////////////////
+
+type getCampaignResultsRequest struct {
+	ID          uint              `url:"id"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type getCampaignResultsResponse struct {
+	Results []*fleet.DistributedQueryCampaignResult `json:"results"`
+	Err     error                                   `json:"error,omitempty"`
+}
+
+func (r getCampaignResultsResponse) error() error { return r.Err }
+
+func getCampaignResultsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getCampaignResultsRequest)
+	results, err := svc.CampaignResults(ctx, req.ID, req.ListOptions)
+	if err != nil {
+		return getCampaignResultsResponse{Err: err}, nil
+	}
+	return getCampaignResultsResponse{Results: results}, nil
+}
+
+func (svc Service) CampaignResults(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignResult, error) {
+	if _, err := svc.authorizeCampaign(ctx, campaignID); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.ListDistributedQueryCampaignResults(ctx, campaignID, opt)
+}
+
+// authorizeCampaign checks that the user in the context can read the results
+// of the campaign and manage it, and returns the campaign. As for the streamed
+// results, only the user that started the campaign can do so.
+func (svc Service) authorizeCampaign(ctx context.Context, campaignID uint) (*fleet.DistributedQueryCampaign, error) {
+	// ObserverCanRun is set because the observer check already happened with
+	// the actual value of the query when the campaign was started.
+	if err := svc.authz.Authorize(ctx, &fleet.Query{ObserverCanRun: true}, fleet.ActionRun); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+
+	campaign, err := svc.ds.DistributedQueryCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	if campaign.UserID != vc.User.ID {
+		return nil, authz.ForbiddenWithInternal("campaign started by another user", vc.User, campaign, fleet.ActionRead)
+	}
+	return campaign, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Export campaign results
+/////////////////////////////////////////////////////////////////////////////////
+
+const (
+	campaignResultsFormatCSV  = "csv"
+	campaignResultsFormatJSON = "json"
+)
+
+type exportCampaignResultsRequest struct {
+	ID     uint   `url:"id"`
+	Format string `query:"format,optional"`
+}
+
+type exportCampaignResultsResponse struct {
+	CampaignID uint
+	Format     string
+	Results    []*fleet.DistributedQueryCampaignResult
+	Err        error
+}
+
+func (r exportCampaignResultsResponse) error() error { return r.Err }
+
+func (r exportCampaignResultsResponse) filename() string {
+	return fmt.Sprintf("campaign-%d-results.%s", r.CampaignID, r.Format)
+}
+
+func (r exportCampaignResultsResponse) contentType() string {
+	if r.Format == campaignResultsFormatJSON {
+		return "application/json"
+	}
+	return "text/csv"
+}
+
+func (r exportCampaignResultsResponse) writeFile(w io.Writer) error {
+	if r.Format == campaignResultsFormatJSON {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.Results)
+	}
+	return writeCampaignResultsCSV(w, r.Results)
+}
+
+func exportCampaignResultsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*exportCampaignResultsRequest)
+	format := req.Format
+	switch format {
+	case "":
+		format = campaignResultsFormatCSV
+	case campaignResultsFormatCSV, campaignResultsFormatJSON:
+	default:
+		return exportCampaignResultsResponse{
+			Err: fleet.NewInvalidArgumentError("format", "must be one of csv or json"),
+		}, nil
+	}
+
+	results, err := svc.ExportCampaignResults(ctx, req.ID)
+	if err != nil {
+		return exportCampaignResultsResponse{Err: err}, nil
+	}
+	return exportCampaignResultsResponse{CampaignID: req.ID, Format: format, Results: results}, nil
+}
+
+func (svc Service) ExportCampaignResults(ctx context.Context, campaignID uint) ([]*fleet.DistributedQueryCampaignResult, error) {
+	if _, err := svc.authorizeCampaign(ctx, campaignID); err != nil {
+		return nil, err
+	}
+
+	return svc.allCampaignResults(ctx, campaignID)
+}
+
+// allCampaignResults returns all the stored results of the campaign.
+func (svc Service) allCampaignResults(ctx context.Context, campaignID uint) ([]*fleet.DistributedQueryCampaignResult, error) {
+	const perPage = 1000
+	results := []*fleet.DistributedQueryCampaignResult{}
+	for page := uint(0); ; page++ {
+		pageResults, err := svc.ds.ListDistributedQueryCampaignResults(ctx, campaignID, fleet.ListOptions{Page: page, PerPage: perPage})
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, pageResults...)
+		if len(pageResults) < perPage {
+			return results, nil
+		}
+	}
+}
+
+// writeCampaignResultsCSV writes one line per result row, with the hostname
+// of the host that returned it in the "host_hostname" column, as in the
+// results streamed to the UI. The "error" column is only added if a host
+// failed to run the query.
+func writeCampaignResultsCSV(w io.Writer, results []*fleet.DistributedQueryCampaignResult) error {
+	columnSet := make(map[string]bool)
+	hasErrors := false
+	for _, res := range results {
+		for _, row := range res.Rows {
+			for col := range row {
+				columnSet[col] = true
+			}
+		}
+		if res.Error != nil {
+			hasErrors = true
+		}
+	}
+	delete(columnSet, "host_hostname")
+	columns := make([]string, 0, len(columnSet))
+	for col := range columnSet {
+		columns = append(columns, col)
+	}
+	sort.Strings(columns)
+
+	header := append([]string{"host_hostname"}, columns...)
+	if hasErrors {
+		header = append(header, "error")
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, res := range results {
+		var errMsg string
+		if res.Error != nil {
+			errMsg = *res.Error
+		}
+		rows := res.Rows
+		if len(rows) == 0 && res.Error != nil {
+			// Keep a line for the failed host so that it shows in the export.
+			rows = []map[string]string{{}}
+		}
+		for _, row := range rows {
+			record := []string{res.Hostname}
+			for _, col := range columns {
+				record = append(record, row[col])
+			}
+			if hasErrors {
+				record = append(record, errMsg)
+			}
+			if err := cw.Write(record); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
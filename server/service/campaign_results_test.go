@@ -0,0 +1,74 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_CampaignResults(t *testing.T) {
+	ds := new(mock.Store)
+
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return &fleet.DistributedQueryCampaign{ID: id, UserID: 3}, nil
+	}
+	var pages []uint
+	ds.ListDistributedQueryCampaignResultsFunc = func(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignResult, error) {
+		pages = append(pages, opt.Page)
+		if opt.Page > 0 {
+			return nil, nil
+		}
+		results := make([]*fleet.DistributedQueryCampaignResult, opt.PerPage)
+		for i := range results {
+			results[i] = &fleet.DistributedQueryCampaignResult{CampaignID: campaignID, HostID: uint(i)}
+		}
+		return results, nil
+	}
+
+	svc := newTestService(ds, nil, nil)
+
+	owner := &fleet.User{ID: 3, GlobalRole: ptr.String(fleet.RoleObserver)}
+	otherUser := &fleet.User{ID: 4, GlobalRole: ptr.String(fleet.RoleAdmin)}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: otherUser})
+	_, err := svc.CampaignResults(ctx, 42, fleet.ListOptions{})
+	require.Error(t, err)
+	_, err = svc.ExportCampaignResults(ctx, 42)
+	require.Error(t, err)
+	assert.False(t, ds.ListDistributedQueryCampaignResultsFuncInvoked)
+
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: owner})
+	results, err := svc.CampaignResults(ctx, 42, fleet.ListOptions{PerPage: 2})
+	require.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	// The export reads all the pages of results.
+	pages = nil
+	results, err = svc.ExportCampaignResults(ctx, 42)
+	require.NoError(t, err)
+	assert.Len(t, results, 1000)
+	assert.Equal(t, []uint{0, 1}, pages)
+}
+
+func TestWriteCampaignResultsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, writeCampaignResultsCSV(&buf, []*fleet.DistributedQueryCampaignResult{
+		{Hostname: "foo", Rows: []map[string]string{{"name": "osqueryd", "pid": "1"}, {"name": "sh"}}},
+		{Hostname: "bar", Rows: []map[string]string{{"pid": "2", "path": "/bin/sh"}}},
+	}))
+	assert.Equal(t, "host_hostname,name,path,pid\nfoo,osqueryd,,1\nfoo,sh,,\nbar,,/bin/sh,2\n", buf.String())
+
+	buf.Reset()
+	require.NoError(t, writeCampaignResultsCSV(&buf, []*fleet.DistributedQueryCampaignResult{
+		{Hostname: "foo", Rows: []map[string]string{{"pid": "1"}}},
+		{Hostname: "bar", Error: ptr.String("no such table")},
+	}))
+	assert.Equal(t, "host_hostname,pid,error\nfoo,1,\nbar,,no such table\n", buf.String())
+}
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// CreateAPIToken creates an API token for the current user. The key of the
+// token is only returned by this call.
+func (c *Client) CreateAPIToken(payload fleet.APITokenPayload) (*fleet.APIToken, error) {
+	verb, path := "POST", "/api/v1/fleet/api_tokens"
+	var responseBody createAPITokenResponse
+	err := c.authenticatedRequest(createAPITokenRequest{APITokenPayload: payload}, verb, path, &responseBody)
+	if err != nil {
+		return nil, err
+	}
+	return responseBody.APIToken, nil
+}
+
+// ListAPITokens retrieves the API tokens of the current user.
+func (c *Client) ListAPITokens() ([]*fleet.APIToken, error) {
+	verb, path := "GET", "/api/v1/fleet/api_tokens"
+	var responseBody listAPITokensResponse
+	err := c.authenticatedRequest(nil, verb, path, &responseBody)
+	if err != nil {
+		return nil, err
+	}
+	return responseBody.APITokens, nil
+}
+
+// DeleteAPIToken revokes the API token with the given id.
+func (c *Client) DeleteAPIToken(id uint) error {
+	verb, path := "DELETE", fmt.Sprintf("/api/v1/fleet/api_tokens/%d", id)
+	var responseBody deleteAPITokenResponse
+	return c.authenticatedRequest(nil, verb, path, &responseBody)
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/pkg/errors"
+)
+
+// ExportCampaignResults downloads the stored results of the live query
+// campaign with the given ID, in the given format (csv or json).
+func (c *Client) ExportCampaignResults(id uint, format string) ([]byte, error) {
+	verb, path := "GET", fmt.Sprintf("/api/v1/fleet/campaigns/%d/results/export", id)
+	query := url.Values{"format": []string{format}}
+	response, err := c.AuthenticatedDo(verb, path, query.Encode(), nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "%s %s", verb, path)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.Errorf(
+			"%s %s received status %d %s",
+			verb, path,
+			response.StatusCode,
+			extractServerErrorText(response.Body),
+		)
+	}
+
+	data, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrapf(err, "read %s %s response", verb, path)
+	}
+	return data, nil
+}
+
+// CampaignHosts retrieves the execution status of the live query campaign with
+// the given ID on each of the hosts it targets.
+func (c *Client) CampaignHosts(id uint) ([]*fleet.DistributedQueryCampaignHost, error) {
+	const perPage = 1000
+	verb, path := "GET", fmt.Sprintf("/api/v1/fleet/campaigns/%d/hosts", id)
+	hosts := []*fleet.DistributedQueryCampaignHost{}
+	for page := 0; ; page++ {
+		var responseBody getCampaignHostsResponse
+		query := fmt.Sprintf("page=%d&per_page=%d", page, perPage)
+		if err := c.authenticatedRequestWithQuery(nil, verb, path, &responseBody, query); err != nil {
+			return nil, err
+		}
+		hosts = append(hosts, responseBody.Hosts...)
+		if len(responseBody.Hosts) < perPage {
+			return hosts, nil
+		}
+	}
+}
+
+// CancelCampaign stops the live query campaign with the given ID.
+func (c *Client) CancelCampaign(id uint) error {
+	verb, path := "POST", fmt.Sprintf("/api/v1/fleet/campaigns/%d/cancel", id)
+	var responseBody cancelCampaignResponse
+	return c.authenticatedRequest(nil, verb, path, &responseBody)
+}
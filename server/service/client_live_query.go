@@ -19,10 +19,11 @@ import (
 // LiveQueryResultsHandler provides access to all of the information about an
 // incoming stream of live query results.
 type LiveQueryResultsHandler struct {
-	errors  chan error
-	results chan fleet.DistributedQueryResult
-	totals  atomic.Value // real type: targetTotals
-	status  atomic.Value // real type: campaignStatus
+	campaignID uint
+	errors     chan error
+	results    chan fleet.DistributedQueryResult
+	totals     atomic.Value // real type: targetTotals
+	status     atomic.Value // real type: campaignStatus
 }
 
 func NewLiveQueryResultsHandler() *LiveQueryResultsHandler {
@@ -32,6 +33,11 @@ func NewLiveQueryResultsHandler() *LiveQueryResultsHandler {
 	}
 }
 
+// CampaignID returns the ID of the campaign running the live query.
+func (h *LiveQueryResultsHandler) CampaignID() uint {
+	return h.campaignID
+}
+
 // Errors returns a read channel that includes any errors returned by the
 // server or receiving the results.
 func (h *LiveQueryResultsHandler) Errors() <-chan error {
@@ -133,6 +139,7 @@ func (c *Client) LiveQueryWithContext(ctx context.Context, query string, labels
 	}
 
 	resHandler := NewLiveQueryResultsHandler()
+	resHandler.campaignID = responseBody.Campaign.ID
 	go func() {
 		defer conn.Close()
 		for {
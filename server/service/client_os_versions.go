@@ -0,0 +1,17 @@
+package service
+
+import (
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// OSVersions retrieves the number of hosts per platform, OS version and
+// osquery version, filtered by the given URL query string.
+func (c *Client) OSVersions(query string) (*fleet.OSVersions, error) {
+	verb, path := "GET", "/api/v1/fleet/os_versions"
+	var responseBody getOSVersionsResponse
+	err := c.authenticatedRequestWithQuery(nil, verb, path, &responseBody, query)
+	if err != nil {
+		return nil, err
+	}
+	return responseBody.OSVersions, nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+// ListPolicies retrieves the list of global policies, or the policies of the
+// given team if teamID is not nil.
+func (c *Client) ListPolicies(teamID *uint) ([]*fleet.Policy, error) {
+	verb, path := "GET", "/api/v1/fleet/global/policies"
+	if teamID != nil {
+		path = fmt.Sprintf("/api/v1/fleet/team/%d/policies", *teamID)
+	}
+	var responseBody listGlobalPoliciesResponse
+	err := c.authenticatedRequest(nil, verb, path, &responseBody)
+	if err != nil {
+		return nil, err
+	}
+	return responseBody.Policies, nil
+}
+
+// ListPoliciesHistory retrieves the summary of the results of every policy
+// over the last days. Zero days uses the server default.
+func (c *Client) ListPoliciesHistory(days uint) ([]*fleet.PolicyHistorySummary, error) {
+	verb, path := "GET", "/api/v1/fleet/policies/history"
+	query := ""
+	if days != 0 {
+		query = fmt.Sprintf("days=%d", days)
+	}
+	var responseBody listPoliciesHistoryResponse
+	err := c.authenticatedRequestWithQuery(nil, verb, path, &responseBody, query)
+	if err != nil {
+		return nil, err
+	}
+	return responseBody.Policies, nil
+}
+
+// ApplyPolicies sends the list of policies to be applied to the Fleet
+// instance.
+func (c *Client) ApplyPolicies(specs []*fleet.PolicySpec) error {
+	req := applyPolicySpecsRequest{Specs: specs}
+	verb, path := "POST", "/api/v1/fleet/spec/policies"
+	var responseBody applyPolicySpecsResponse
+	return c.authenticatedRequest(req, verb, path, &responseBody)
+}
+
+// DeletePolicy deletes the policy with the name of the given spec, looked up
+// among the global policies or the policies of the spec's team.
+func (c *Client) DeletePolicy(spec *fleet.PolicySpec) error {
+	var teamID *uint
+	if spec.Team != "" {
+		teams, err := c.ListTeams()
+		if err != nil {
+			return err
+		}
+		for _, team := range teams {
+			if team.Name == spec.Team {
+				id := team.ID
+				teamID = &id
+				break
+			}
+		}
+		if teamID == nil {
+			return notFoundErr{}
+		}
+	}
+
+	policies, err := c.ListPolicies(teamID)
+	if err != nil {
+		return err
+	}
+	for _, policy := range policies {
+		if policy.Name != spec.Name {
+			continue
+		}
+		verb, path := "POST", "/api/v1/fleet/global/policies/delete"
+		if teamID != nil {
+			path = fmt.Sprintf("/api/v1/fleet/team/%d/policies/delete", *teamID)
+		}
+		req := deleteGlobalPoliciesRequest{IDs: []uint{policy.ID}}
+		var responseBody deleteGlobalPoliciesResponse
+		return c.authenticatedRequest(req, verb, path, &responseBody)
+	}
+	return notFoundErr{}
+}
@@ -63,6 +63,9 @@ func makeGetAppConfigEndpoint(svc fleet.Service) endpoint.Endpoint {
 				smtpSettings.SMTPPassword = "********"
 			}
 			ssoSettings = config.SSOSettings
+			if ssoSettings.OIDCClientSecret != "" {
+				ssoSettings.OIDCClientSecret = "********"
+			}
 			hostExpirySettings = config.HostExpirySettings
 			agentOptions = config.AgentOptions
 		}
@@ -113,6 +116,9 @@ func makeModifyAppConfigEndpoint(svc fleet.Service) endpoint.Endpoint {
 		if response.SMTPSettings.SMTPPassword != "" {
 			response.SMTPSettings.SMTPPassword = "********"
 		}
+		if response.SSOSettings.OIDCClientSecret != "" {
+			response.SSOSettings.OIDCClientSecret = "********"
+		}
 		return response, nil
 	}
 }
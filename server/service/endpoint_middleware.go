@@ -153,8 +153,12 @@ func logged(next endpoint.Endpoint) endpoint.Endpoint {
 	}
 }
 
-// authViewer creates an authenticated viewer by validating the session key.
+// authViewer creates an authenticated viewer by validating the session key,
+// or the key of an API token.
 func authViewer(ctx context.Context, sessionKey string, svc fleet.Service) (*viewer.Viewer, error) {
+	if fleet.IsAPITokenKey(sessionKey) {
+		return apiTokenViewer(ctx, sessionKey, svc)
+	}
 	session, err := svc.GetSessionByKey(ctx, sessionKey)
 	if err != nil {
 		return nil, fleet.NewAuthRequiredError(err.Error())
@@ -166,6 +170,20 @@ func authViewer(ctx context.Context, sessionKey string, svc fleet.Service) (*vie
 	return &viewer.Viewer{User: user, Session: session}, nil
 }
 
+// apiTokenViewer creates an authenticated viewer by validating the key of an
+// API token. The roles of the user are restricted to the teams of the token.
+func apiTokenViewer(ctx context.Context, key string, svc fleet.Service) (*viewer.Viewer, error) {
+	token, err := svc.GetAPITokenByKey(ctx, key)
+	if err != nil {
+		return nil, fleet.NewAuthRequiredError(err.Error())
+	}
+	user, err := svc.UserUnauthorized(ctx, token.UserID)
+	if err != nil {
+		return nil, fleet.NewAuthRequiredError(err.Error())
+	}
+	return &viewer.Viewer{User: token.ScopedUser(user), APIToken: token}, nil
+}
+
 func canPerformPasswordReset(next endpoint.Endpoint) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		vc, ok := viewer.FromContext(ctx)
@@ -74,6 +74,7 @@ type getDistributedQueriesRequest struct {
 
 type getDistributedQueriesResponse struct {
 	Queries    map[string]string `json:"queries"`
+	Discovery  map[string]string `json:"discovery"`
 	Accelerate uint              `json:"accelerate,omitempty"`
 	Err        error             `json:"error,omitempty"`
 }
@@ -82,11 +83,11 @@ func (r getDistributedQueriesResponse) error() error { return r.Err }
 
 func makeGetDistributedQueriesEndpoint(svc fleet.Service) endpoint.Endpoint {
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
-		queries, accelerate, err := svc.GetDistributedQueries(ctx)
+		queries, discovery, accelerate, err := svc.GetDistributedQueries(ctx)
 		if err != nil {
 			return getDistributedQueriesResponse{Err: err}, nil
 		}
-		return getDistributedQueriesResponse{Queries: queries, Accelerate: accelerate}, nil
+		return getDistributedQueriesResponse{Queries: queries, Discovery: discovery, Accelerate: accelerate}, nil
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"html/template"
+	"io"
 	"time"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
@@ -211,17 +212,32 @@ func makeCallbackSSOEndpoint(svc fleet.Service, urlPrefix string) endpoint.Endpo
 	return func(ctx context.Context, request interface{}) (interface{}, error) {
 		authResponse := request.(fleet.Auth)
 		session, err := svc.CallbackSSO(ctx, authResponse)
-		var resp callbackSSOResponse
-		if err != nil {
-			// redirect to login page on front end if there was some problem,
-			// errors should still be logged
-			session = &fleet.SSOSession{
-				RedirectURL: urlPrefix + "/login",
-				Token:       "",
-			}
-			resp.Err = err
+		return makeCallbackSSOResponse(session, err, urlPrefix)
+	}
+}
+
+func makeCallbackOIDCEndpoint(svc fleet.Service, urlPrefix string) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		callback := request.(fleet.OIDCCallback)
+		session, err := svc.CallbackOIDC(ctx, callback)
+		return makeCallbackSSOResponse(session, err, urlPrefix)
+	}
+}
+
+// makeCallbackSSOResponse returns the page that stores the token of the SSO
+// session and redirects to Fleet.
+func makeCallbackSSOResponse(session *fleet.SSOSession, err error, urlPrefix string) (interface{}, error) {
+	var resp callbackSSOResponse
+	if err != nil {
+		// redirect to login page on front end if there was some problem,
+		// errors should still be logged
+		session = &fleet.SSOSession{
+			RedirectURL: urlPrefix + "/login",
+			Token:       "",
 		}
-		relayStateLoadPage := ` <html>
+		resp.Err = err
+	}
+	relayStateLoadPage := ` <html>
      <script type='text/javascript'>
      var redirectURL = {{ .RedirectURL }};
      window.localStorage.setItem('FLEET::auth_token', '{{ .Token }}');
@@ -232,18 +248,17 @@ func makeCallbackSSOEndpoint(svc fleet.Service, urlPrefix string) endpoint.Endpo
      </body>
      </html>
     `
-		tmpl, err := template.New("relayStateLoader").Parse(relayStateLoadPage)
-		if err != nil {
-			return nil, err
-		}
-		var writer bytes.Buffer
-		err = tmpl.Execute(&writer, session)
-		if err != nil {
-			return nil, err
-		}
-		resp.content = writer.String()
-		return resp, nil
+	tmpl, err := template.New("relayStateLoader").Parse(relayStateLoadPage)
+	if err != nil {
+		return nil, err
 	}
+	var writer bytes.Buffer
+	err = tmpl.Execute(&writer, session)
+	if err != nil {
+		return nil, err
+	}
+	resp.content = writer.String()
+	return resp, nil
 }
 
 type ssoSettingsResponse struct {
@@ -262,3 +277,29 @@ func makeSSOSettingsEndpoint(svc fleet.Service) endpoint.Endpoint {
 		return ssoSettingsResponse{Settings: settings}, nil
 	}
 }
+
+type ssoMetadataResponse struct {
+	Metadata []byte
+	Err      error
+}
+
+func (r ssoMetadataResponse) error() error { return r.Err }
+
+func (r ssoMetadataResponse) filename() string { return "fleet-saml-metadata.xml" }
+
+func (r ssoMetadataResponse) contentType() string { return "application/samlmetadata+xml" }
+
+func (r ssoMetadataResponse) writeFile(w io.Writer) error {
+	_, err := w.Write(r.Metadata)
+	return err
+}
+
+func makeSSOMetadataEndpoint(svc fleet.Service) endpoint.Endpoint {
+	return func(ctx context.Context, unused interface{}) (interface{}, error) {
+		metadata, err := svc.SSOServiceProviderMetadata(ctx)
+		if err != nil {
+			return ssoMetadataResponse{Err: err}, nil
+		}
+		return ssoMetadataResponse{Metadata: metadata}, nil
+	}
+}
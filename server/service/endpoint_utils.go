@@ -68,7 +68,8 @@ func allFields(ifv reflect.Value) []reflect.StructField {
 // it'll unmarshall the body. If the struct has a `url` tag with value list-options it'll gather fleet.ListOptions
 // from the URL. And finally, any other `url` tag will be treated as an ID from the URL path pattern, and it'll
 // be decoded and set accordingly.
-// IDs are expected to be uint, and can be optional by setting the tag as follows: `url:"some-id,optional"`
+// IDs are expected to be uint, unless the field is a string, in which case the path variable is set as is. They
+// can be optional by setting the tag as follows: `url:"some-id,optional"`
 // list-options are optional by default and it'll ignore the optional portion of the tag.
 func makeDecoder(iface interface{}) kithttp.DecodeRequestFunc {
 	if iface == nil {
@@ -118,6 +119,18 @@ func makeDecoder(iface interface{}) kithttp.DecodeRequestFunc {
 					continue
 				}
 
+				if field.Kind() == reflect.String {
+					name, err := nameFromRequest(r, urlTagValue)
+					if err != nil {
+						if err == errBadRoute && optional {
+							continue
+						}
+						return nil, err
+					}
+					field.SetString(name)
+					continue
+				}
+
 				id, err := idFromRequest(r, urlTagValue)
 				if err != nil {
 					if err == errBadRoute && optional {
@@ -164,6 +177,12 @@ func makeDecoder(iface interface{}) kithttp.DecodeRequestFunc {
 						return nil, err
 					}
 					field.SetUint(uint64(queryValUint))
+				case reflect.Bool:
+					queryValBool, err := strconv.ParseBool(queryVal)
+					if err != nil {
+						return nil, err
+					}
+					field.SetBool(queryValBool)
 				default:
 					return nil, errors.Errorf("Cant handle type for field %s %s", f.Name, field.Kind())
 				}
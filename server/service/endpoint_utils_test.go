@@ -36,6 +36,30 @@ func TestUniversalDecoderIDs(t *testing.T) {
 	require.Error(t, err)
 }
 
+func TestUniversalDecoderStringURLParams(t *testing.T) {
+	type universalStruct struct {
+		Name         string `url:"name"`
+		OptionalName string `url:"other-name,optional"`
+	}
+	decoder := makeDecoder(universalStruct{})
+
+	req := httptest.NewRequest("GET", "/target", nil)
+	req = mux.SetURLVars(req, map[string]string{"name": "CVE-2021-3449"})
+
+	decoded, err := decoder(context.Background(), req)
+	require.NoError(t, err)
+	casted, ok := decoded.(*universalStruct)
+	require.True(t, ok)
+
+	assert.Equal(t, "CVE-2021-3449", casted.Name)
+	assert.Equal(t, "", casted.OptionalName)
+
+	// fails if non optional params are not provided
+	req = httptest.NewRequest("GET", "/target", nil)
+	_, err = decoder(context.Background(), req)
+	require.Error(t, err)
+}
+
 func TestUniversalDecoderIDsAndJSON(t *testing.T) {
 	type universalStruct struct {
 		ID1        uint   `url:"some-id"`
@@ -170,6 +194,25 @@ func TestUniversalDecoderOptionalQueryParams(t *testing.T) {
 	assert.Equal(t, uint(321), *casted.ID1)
 }
 
+func TestUniversalDecoderOptionalQueryParamBool(t *testing.T) {
+	type universalStruct struct {
+		Flag *bool `query:"flag,optional"`
+	}
+	decoder := makeDecoder(universalStruct{})
+
+	decoded, err := decoder(context.Background(), httptest.NewRequest("GET", "/target", nil))
+	require.NoError(t, err)
+	assert.Nil(t, decoded.(*universalStruct).Flag)
+
+	decoded, err = decoder(context.Background(), httptest.NewRequest("GET", "/target?flag=true", nil))
+	require.NoError(t, err)
+	require.NotNil(t, decoded.(*universalStruct).Flag)
+	assert.True(t, *decoded.(*universalStruct).Flag)
+
+	_, err = decoder(context.Background(), httptest.NewRequest("GET", "/target?flag=maybe", nil))
+	require.Error(t, err)
+}
+
 func TestUniversalDecoderOptionalQueryParamString(t *testing.T) {
 	type universalStruct struct {
 		ID1 *string `query:"some_val,optional"`
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/pkg/errors"
 )
 
 /////////////////////////////////////////////////////////////////////////////////
@@ -11,7 +12,12 @@ import (
 /////////////////////////////////////////////////////////////////////////////////
 
 type globalPolicyRequest struct {
-	QueryID uint `json:"query_id"`
+	QueryID     *uint  `json:"query_id"`
+	Name        string `json:"name"`
+	Query       string `json:"query"`
+	Description string `json:"description"`
+	Resolution  string `json:"resolution"`
+	Platform    string `json:"platform"`
 }
 
 type globalPolicyResponse struct {
@@ -23,19 +29,62 @@ func (r globalPolicyResponse) error() error { return r.Err }
 
 func globalPolicyEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*globalPolicyRequest)
-	resp, err := svc.NewGlobalPolicy(ctx, req.QueryID)
+	resp, err := svc.NewGlobalPolicy(ctx, fleet.PolicyPayload{
+		QueryID:     req.QueryID,
+		Name:        req.Name,
+		Query:       req.Query,
+		Description: req.Description,
+		Resolution:  req.Resolution,
+		Platform:    req.Platform,
+	})
 	if err != nil {
 		return globalPolicyResponse{Err: err}, nil
 	}
 	return globalPolicyResponse{Policy: resp}, nil
 }
 
-func (svc Service) NewGlobalPolicy(ctx context.Context, queryID uint) (*fleet.Policy, error) {
+func (svc Service) NewGlobalPolicy(ctx context.Context, p fleet.PolicyPayload) (*fleet.Policy, error) {
 	if err := svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionWrite); err != nil {
 		return nil, err
 	}
 
-	return svc.ds.NewGlobalPolicy(ctx, queryID)
+	p, err := svc.populatePolicyPayload(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.ds.NewGlobalPolicy(ctx, p)
+}
+
+// populatePolicyPayload fills in the name, query and description from the
+// saved query when the policy is created from one, and validates the result.
+func (svc Service) populatePolicyPayload(ctx context.Context, p fleet.PolicyPayload) (fleet.PolicyPayload, error) {
+	if p.QueryID != nil {
+		q, err := svc.ds.Query(ctx, *p.QueryID)
+		if err != nil {
+			return p, errors.Wrap(err, "get query for policy")
+		}
+		if p.Name == "" {
+			p.Name = q.Name
+		}
+		if p.Query == "" {
+			p.Query = q.Query
+		}
+		if p.Description == "" {
+			p.Description = q.Description
+		}
+	}
+
+	if p.Name == "" {
+		return p, fleet.NewInvalidArgumentError("name", "policy name must not be empty")
+	}
+	if p.Query == "" {
+		return p, fleet.NewInvalidArgumentError("query", "policy query must not be empty")
+	}
+	if err := fleet.ValidatePolicyPlatform(p.Platform); err != nil {
+		return p, fleet.NewInvalidArgumentError("platform", err.Error())
+	}
+	return p, nil
 }
 
 /////////////////////////////////////////////////////////////////////////////////
@@ -133,3 +182,73 @@ func (svc Service) DeleteGlobalPolicies(ctx context.Context, ids []uint) ([]uint
 
 	return svc.ds.DeleteGlobalPolicies(ctx, ids)
 }
+
+/////////////////////////////////////////////////////////////////////////////////
+// Apply Spec
+/////////////////////////////////////////////////////////////////////////////////
+
+type applyPolicySpecsRequest struct {
+	Specs []*fleet.PolicySpec `json:"specs"`
+}
+
+type applyPolicySpecsResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r applyPolicySpecsResponse) error() error { return r.Err }
+
+func applyPolicySpecsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*applyPolicySpecsRequest)
+	err := svc.ApplyPolicySpecs(ctx, req.Specs)
+	if err != nil {
+		return applyPolicySpecsResponse{Err: err}, nil
+	}
+	return applyPolicySpecsResponse{}, nil
+}
+
+func (svc Service) ApplyPolicySpecs(ctx context.Context, policies []*fleet.PolicySpec) error {
+	if len(policies) == 0 {
+		return svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionWrite)
+	}
+
+	checkedGlobal := false
+	checkedTeams := make(map[string]bool)
+	for _, policy := range policies {
+		if policy.Name == "" {
+			return fleet.NewInvalidArgumentError("name", "policy name must not be empty")
+		}
+		if policy.Query == "" {
+			return fleet.NewInvalidArgumentError("query", "policy query must not be empty")
+		}
+		if err := fleet.ValidatePolicyPlatform(policy.Platform); err != nil {
+			return fleet.NewInvalidArgumentError("platform", err.Error())
+		}
+
+		// Global policies run on every host, so only global admins and
+		// maintainers can apply them.
+		if policy.Team == "" {
+			if checkedGlobal {
+				continue
+			}
+			if err := svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionWrite); err != nil {
+				return err
+			}
+			checkedGlobal = true
+			continue
+		}
+
+		if checkedTeams[policy.Team] {
+			continue
+		}
+		team, err := svc.ds.TeamByName(ctx, policy.Team)
+		if err != nil {
+			return errors.Wrapf(err, "get team %s", policy.Team)
+		}
+		if err := svc.authz.Authorize(ctx, &fleet.Policy{TeamID: &team.ID}, fleet.ActionWrite); err != nil {
+			return err
+		}
+		checkedTeams[policy.Team] = true
+	}
+
+	return svc.ds.ApplyPolicySpecs(ctx, policies)
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_ApplyPolicySpecs(t *testing.T) {
+	ds := new(mock.Store)
+	ds.TeamByNameFunc = func(ctx context.Context, name string) (*fleet.Team, error) {
+		return &fleet.Team{ID: 1, Name: name}, nil
+	}
+	ds.ApplyPolicySpecsFunc = func(ctx context.Context, specs []*fleet.PolicySpec) error {
+		return nil
+	}
+
+	teamMaintainer := &fleet.User{
+		ID:    3,
+		Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}},
+	}
+	globalMaintainer := &fleet.User{ID: 4, GlobalRole: ptr.String(fleet.RoleMaintainer)}
+
+	svc := newTestService(ds, nil, nil)
+	teamSpec := &fleet.PolicySpec{Name: "team policy", Query: "select 1", Team: "team1"}
+	globalSpec := &fleet.PolicySpec{Name: "global policy", Query: "select 1"}
+
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: teamMaintainer})
+	require.NoError(t, svc.ApplyPolicySpecs(ctx, []*fleet.PolicySpec{teamSpec}))
+	assert.True(t, ds.ApplyPolicySpecsFuncInvoked)
+
+	// Team maintainers can't apply global policies.
+	ds.ApplyPolicySpecsFuncInvoked = false
+	require.Error(t, svc.ApplyPolicySpecs(ctx, []*fleet.PolicySpec{teamSpec, globalSpec}))
+	assert.False(t, ds.ApplyPolicySpecsFuncInvoked)
+
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: globalMaintainer})
+	require.NoError(t, svc.ApplyPolicySpecs(ctx, []*fleet.PolicySpec{teamSpec, globalSpec}))
+	assert.True(t, ds.ApplyPolicySpecsFuncInvoked)
+}
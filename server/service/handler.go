@@ -107,7 +107,9 @@ type FleetEndpoints struct {
 	ChangeEmail                           endpoint.Endpoint
 	InitiateSSO                           endpoint.Endpoint
 	CallbackSSO                           endpoint.Endpoint
+	CallbackOIDC                          endpoint.Endpoint
 	SSOSettings                           endpoint.Endpoint
+	SSOMetadata                           endpoint.Endpoint
 	StatusResultStore                     endpoint.Endpoint
 	StatusLiveQuery                       endpoint.Endpoint
 	ListCarves                            endpoint.Endpoint
@@ -145,7 +147,9 @@ func MakeFleetServerEndpoints(svc fleet.Service, urlPrefix string, limitStore th
 		VerifyInvite:         logged(makeVerifyInviteEndpoint(svc)),
 		InitiateSSO:          logged(makeInitiateSSOEndpoint(svc)),
 		CallbackSSO:          logged(makeCallbackSSOEndpoint(svc, urlPrefix)),
+		CallbackOIDC:         logged(makeCallbackOIDCEndpoint(svc, urlPrefix)),
 		SSOSettings:          logged(makeSSOSettingsEndpoint(svc)),
+		SSOMetadata:          logged(makeSSOMetadataEndpoint(svc)),
 
 		// PerformRequiredPasswordReset needs only to authenticate the
 		// logged in user
@@ -343,7 +347,9 @@ type fleetHandlers struct {
 	ChangeEmail                           http.Handler
 	InitiateSSO                           http.Handler
 	CallbackSSO                           http.Handler
+	CallbackOIDC                          http.Handler
 	SettingsSSO                           http.Handler
+	MetadataSSO                           http.Handler
 	StatusResultStore                     http.Handler
 	StatusLiveQuery                       http.Handler
 	ListCarves                            http.Handler
@@ -454,7 +460,9 @@ func makeKitHandlers(e FleetEndpoints, opts []kithttp.ServerOption) *fleetHandle
 		ChangeEmail:                           newServer(e.ChangeEmail, decodeChangeEmailRequest),
 		InitiateSSO:                           newServer(e.InitiateSSO, decodeInitiateSSORequest),
 		CallbackSSO:                           newServer(e.CallbackSSO, decodeCallbackSSORequest),
+		CallbackOIDC:                          newServer(e.CallbackOIDC, decodeCallbackOIDCRequest),
 		SettingsSSO:                           newServer(e.SSOSettings, decodeNoParamsRequest),
+		MetadataSSO:                           newServer(e.SSOMetadata, decodeNoParamsRequest),
 		StatusResultStore:                     newServer(e.StatusResultStore, decodeNoParamsRequest),
 		StatusLiveQuery:                       newServer(e.StatusLiveQuery, decodeNoParamsRequest),
 		ListCarves:                            newServer(e.ListCarves, decodeListCarvesRequest),
@@ -548,6 +556,7 @@ func MakeHandler(svc fleet.Service, config config.FleetConfig, logger kitlog.Log
 
 	attachFleetAPIRoutes(r, fleetHandlers)
 	attachNewStyleFleetAPIRoutes(r, svc, fleetAPIOptions)
+	attachSCIMRoutes(r, svc, logger)
 
 	// Results endpoint is handled different due to websockets use
 	r.PathPrefix("/api/v1/fleet/results/").
@@ -579,6 +588,8 @@ func attachFleetAPIRoutes(r *mux.Router, h *fleetHandlers) {
 	r.Handle("/api/v1/fleet/sso", h.InitiateSSO).Methods("POST").Name("intiate_sso")
 	r.Handle("/api/v1/fleet/sso", h.SettingsSSO).Methods("GET").Name("sso_config")
 	r.Handle("/api/v1/fleet/sso/callback", h.CallbackSSO).Methods("POST").Name("callback_sso")
+	r.Handle("/api/v1/fleet/sso/metadata", h.MetadataSSO).Methods("GET").Name("sso_metadata")
+	r.Handle("/api/v1/fleet/sso/oidc/callback", h.CallbackOIDC).Methods("GET").Name("callback_oidc")
 	r.Handle("/api/v1/fleet/users", h.ListUsers).Methods("GET").Name("list_users")
 	r.Handle("/api/v1/fleet/users", h.CreateUserWithInvite).Methods("POST").Name("create_user_with_invite")
 	r.Handle("/api/v1/fleet/users/admin", h.CreateUser).Methods("POST").Name("create_user")
@@ -704,6 +715,9 @@ func attachNewStyleFleetAPIRoutes(r *mux.Router, svc fleet.Service, opts []kitht
 	e.GET("/api/v1/fleet/global/policies", listGlobalPoliciesEndpoint, nil)
 	e.GET("/api/v1/fleet/global/policies/{policy_id}", getPolicyByIDEndpoint, getPolicyByIDRequest{})
 	e.POST("/api/v1/fleet/global/policies/delete", deleteGlobalPoliciesEndpoint, deleteGlobalPoliciesRequest{})
+	e.GET("/api/v1/fleet/policies/history", listPoliciesHistoryEndpoint, listPoliciesHistoryRequest{})
+	e.GET("/api/v1/fleet/policies/{policy_id}/history", getPolicyHistoryEndpoint, getPolicyHistoryRequest{})
+	e.POST("/api/v1/fleet/spec/policies", applyPolicySpecsEndpoint, applyPolicySpecsRequest{})
 
 	e.POST("/api/v1/fleet/team/{team_id}/policies", teamPolicyEndpoint, teamPolicyRequest{})
 	e.GET("/api/v1/fleet/team/{team_id}/policies", listTeamPoliciesEndpoint, listTeamPoliciesRequest{})
@@ -711,6 +725,28 @@ func attachNewStyleFleetAPIRoutes(r *mux.Router, svc fleet.Service, opts []kitht
 	e.POST("/api/v1/fleet/team/{team_id}/policies/delete", deleteTeamPoliciesEndpoint, deleteTeamPoliciesRequest{})
 
 	e.GET("/api/v1/fleet/software", listSoftwareEndpoint, listSoftwareRequest{})
+	e.GET("/api/v1/fleet/software/vulnerabilities/{cve}/hosts", listHostsByCVEEndpoint, listHostsByCVERequest{})
+
+	e.GET("/api/v1/fleet/os_versions", getOSVersionsEndpoint, getOSVersionsRequest{})
+
+	e.GET("/api/v1/fleet/scheduled_query_results", listScheduledQueryResultsEndpoint, listScheduledQueryResultsRequest{})
+
+	e.POST("/api/v1/fleet/api_tokens", createAPITokenEndpoint, createAPITokenRequest{})
+	e.GET("/api/v1/fleet/api_tokens", listAPITokensEndpoint, listAPITokensRequest{})
+	e.DELETE("/api/v1/fleet/api_tokens/{id}", deleteAPITokenEndpoint, deleteAPITokenRequest{})
+
+	e.POST("/api/v1/fleet/campaigns", runLiveQueryEndpoint, runLiveQueryRequest{})
+	e.GET("/api/v1/fleet/campaigns/{id}", getCampaignStatusEndpoint, getCampaignStatusRequest{})
+	e.GET("/api/v1/fleet/campaigns/{id}/results", getCampaignResultsEndpoint, getCampaignResultsRequest{})
+	e.GET("/api/v1/fleet/campaigns/{id}/results/export", exportCampaignResultsEndpoint, exportCampaignResultsRequest{})
+	e.GET("/api/v1/fleet/campaigns/{id}/hosts", getCampaignHostsEndpoint, getCampaignHostsRequest{})
+	e.POST("/api/v1/fleet/campaigns/{id}/cancel", cancelCampaignEndpoint, cancelCampaignRequest{})
+
+	e.POST("/api/v1/fleet/query_sweeps", createQuerySweepEndpoint, createQuerySweepRequest{})
+	e.GET("/api/v1/fleet/query_sweeps", listQuerySweepsEndpoint, listQuerySweepsRequest{})
+	e.GET("/api/v1/fleet/query_sweeps/{id}", getQuerySweepEndpoint, getQuerySweepRequest{})
+	e.PATCH("/api/v1/fleet/query_sweeps/{id}", modifyQuerySweepEndpoint, modifyQuerySweepRequest{})
+	e.DELETE("/api/v1/fleet/query_sweeps/{id}", deleteQuerySweepEndpoint, deleteQuerySweepRequest{})
 }
 
 // TODO: this duplicates the one in makeKitHandler
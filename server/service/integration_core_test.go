@@ -344,22 +344,26 @@ func (s *integrationTestSuite) TestGlobalPolicies() {
 	})
 	require.NoError(t, err)
 
-	gpParams := globalPolicyRequest{QueryID: qr.ID}
+	gpParams := globalPolicyRequest{QueryID: &qr.ID, Resolution: "some global resolution"}
 	gpResp := globalPolicyResponse{}
 	s.DoJSON("POST", "/api/v1/fleet/global/policies", gpParams, http.StatusOK, &gpResp)
 	require.NotNil(t, gpResp.Policy)
-	assert.Equal(t, qr.ID, gpResp.Policy.QueryID)
+	assert.Equal(t, &qr.ID, gpResp.Policy.QueryID)
+	assert.Equal(t, qr.Name, gpResp.Policy.Name)
+	assert.Equal(t, qr.Query, gpResp.Policy.Query)
+	assert.Equal(t, qr.Description, gpResp.Policy.Description)
+	assert.Equal(t, "some global resolution", gpResp.Policy.Resolution)
 
 	policiesResponse := listGlobalPoliciesResponse{}
 	s.DoJSON("GET", "/api/v1/fleet/global/policies", nil, http.StatusOK, &policiesResponse)
 	require.Len(t, policiesResponse.Policies, 1)
-	assert.Equal(t, qr.ID, policiesResponse.Policies[0].QueryID)
+	assert.Equal(t, &qr.ID, policiesResponse.Policies[0].QueryID)
 
 	singlePolicyResponse := getPolicyByIDResponse{}
 	singlePolicyURL := fmt.Sprintf("/api/v1/fleet/global/policies/%d", policiesResponse.Policies[0].ID)
 	s.DoJSON("GET", singlePolicyURL, nil, http.StatusOK, &singlePolicyResponse)
-	assert.Equal(t, qr.ID, singlePolicyResponse.Policy.QueryID)
-	assert.Equal(t, qr.Name, singlePolicyResponse.Policy.QueryName)
+	assert.Equal(t, &qr.ID, singlePolicyResponse.Policy.QueryID)
+	assert.Equal(t, qr.Name, singlePolicyResponse.Policy.Name)
 
 	listHostsURL := fmt.Sprintf("/api/v1/fleet/hosts?policy_id=%d", policiesResponse.Policies[0].ID)
 	listHostsResp := listHostsResponse{}
@@ -186,15 +186,15 @@ func (s *integrationEnterpriseTestSuite) TestTeamPolicies() {
 	qr, err := s.ds.NewQuery(context.Background(), &fleet.Query{Name: "TestQuery2", Description: "Some description", Query: "select * from osquery;", ObserverCanRun: true})
 	require.NoError(t, err)
 
-	tpParams := teamPolicyRequest{QueryID: qr.ID}
+	tpParams := teamPolicyRequest{QueryID: &qr.ID}
 	r := teamPolicyResponse{}
 	s.DoJSON("POST", fmt.Sprintf("/api/v1/fleet/team/%d/policies", team1.ID), tpParams, http.StatusOK, &r)
 
 	ts = listTeamPoliciesResponse{}
 	s.DoJSON("GET", fmt.Sprintf("/api/v1/fleet/team/%d/policies", team1.ID), nil, http.StatusOK, &ts)
 	require.Len(t, ts.Policies, 1)
-	assert.Equal(t, "TestQuery2", ts.Policies[0].QueryName)
-	assert.Equal(t, qr.ID, ts.Policies[0].QueryID)
+	assert.Equal(t, "TestQuery2", ts.Policies[0].Name)
+	assert.Equal(t, &qr.ID, ts.Policies[0].QueryID)
 
 	deletePolicyParams := deleteTeamPoliciesRequest{IDs: []uint{ts.Policies[0].ID}}
 	deletePolicyResp := deleteTeamPoliciesResponse{}
@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// maxLiveQueryWait is the longest a request to run a live query can wait for
+// the campaign to finish, kept below the server write timeout.
+const maxLiveQueryWait = 30 * time.Second
+
+/////////////////////////////////////////////////////////////////////////////////
+// Run live query
+/////////////////////////////////////////////////////////////////////////////////
+
+type runLiveQueryRequest struct {
+	QuerySQL string            `json:"query"`
+	QueryID  *uint             `json:"query_id"`
+	Selected fleet.HostTargets `json:"selected"`
+	// Timeout is how many seconds to wait for the campaign to finish before
+	// responding.
+	Timeout uint `json:"timeout"`
+}
+
+type runLiveQueryResponse struct {
+	*fleet.DistributedQueryCampaignStatus
+	Err error `json:"error,omitempty"`
+}
+
+func (r runLiveQueryResponse) error() error { return r.Err }
+
+func runLiveQueryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*runLiveQueryRequest)
+	wait := time.Duration(req.Timeout) * time.Second
+	if wait > maxLiveQueryWait {
+		return runLiveQueryResponse{
+			Err: fleet.NewInvalidArgumentError("timeout", "must be at most 30 seconds"),
+		}, nil
+	}
+
+	status, err := svc.RunLiveQuery(ctx, req.QuerySQL, req.QueryID, req.Selected, wait)
+	if err != nil {
+		return runLiveQueryResponse{Err: err}, nil
+	}
+	return runLiveQueryResponse{DistributedQueryCampaignStatus: status}, nil
+}
+
+func (svc Service) RunLiveQuery(ctx context.Context, queryString string, queryID *uint, targets fleet.HostTargets, wait time.Duration) (*fleet.DistributedQueryCampaignStatus, error) {
+	// The actual query is authorized when creating the campaign, this only
+	// checks that the user can run live queries at all.
+	if err := svc.authz.Authorize(ctx, &fleet.Query{ObserverCanRun: true}, fleet.ActionRun); err != nil {
+		return nil, err
+	}
+	if svc.config.Osquery.LiveQueryResultsRetention == 0 {
+		return nil, fleet.NewError(fleet.ErrNoLiveQueryResultsDisabled,
+			"Storing live query results is disabled, results can only be streamed over a websocket.")
+	}
+
+	campaign, err := svc.NewDistributedQueryCampaign(ctx, queryString, queryID, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	// The campaign outlives the request, so it runs with its own context that
+	// only keeps the user to count the targeted hosts.
+	campaignCtx, cancel := context.WithTimeout(viewer.NewContext(context.Background(), vc), svc.config.Osquery.LiveQueryTimeout)
+	readChan, err := svc.resultStore.ReadChannel(campaignCtx, *campaign)
+	if err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "open read channel for campaign")
+	}
+	campaign.Status = fleet.QueryRunning
+	if err := svc.ds.SaveDistributedQueryCampaign(ctx, campaign); err != nil {
+		cancel()
+		return nil, errors.Wrap(err, "save campaign")
+	}
+	// The listener works on its own copy of the campaign, as the status
+	// computed below updates its metrics.
+	listenedCampaign := *campaign
+	go func() {
+		defer cancel()
+		svc.listenToCampaign(campaignCtx, &listenedCampaign, readChan)
+	}()
+
+	status, err := svc.campaignStatus(ctx, campaign)
+	if err != nil {
+		return nil, err
+	}
+	if wait <= 0 || status.Status == campaignStatusFinished {
+		return status, nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-timer.C:
+			return status, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+
+		status, err = svc.campaignStatus(ctx, campaign)
+		if err != nil {
+			return nil, err
+		}
+		if status.Status == campaignStatusFinished {
+			return status, nil
+		}
+	}
+}
+
+// listenToCampaign keeps a campaign started through the REST API running
+// until all the targeted hosts responded, it is cancelled or ctx is done (when
+// the campaign timed out), so that the hosts
+// that come online in the meantime also run the query. It stands in for the
+// websocket listening to the results of the campaigns started from the UI,
+// so that the campaign is not considered orphaned. The results themselves
+// are stored as they are received.
+func (svc Service) listenToCampaign(ctx context.Context, campaign *fleet.DistributedQueryCampaign, readChan <-chan interface{}) {
+	defer func() {
+		// Use a new context, ctx may already be done.
+		campaign.Status = fleet.QueryComplete
+		if err := svc.ds.SaveDistributedQueryCampaign(context.Background(), campaign); err != nil {
+			level.Error(svc.logger).Log("msg", "complete campaign", "campaign_id", campaign.ID, "err", err)
+		}
+		if err := svc.liveQueryStore.StopQuery(strconv.Itoa(int(campaign.ID))); err != nil {
+			level.Error(svc.logger).Log("msg", "stop campaign query", "campaign_id", campaign.ID, "err", err)
+		}
+	}()
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-readChan:
+			continue
+		case <-ticker.C:
+		}
+
+		if cancelled, err := svc.campaignCancelled(ctx, campaign.ID); err != nil {
+			level.Error(svc.logger).Log("msg", "load campaign", "campaign_id", campaign.ID, "err", err)
+		} else if cancelled {
+			return
+		}
+
+		status, err := svc.campaignStatus(ctx, campaign)
+		if err != nil {
+			level.Error(svc.logger).Log("msg", "campaign status", "campaign_id", campaign.ID, "err", err)
+			continue
+		}
+		if status.ActualResults >= status.Campaign.Metrics.TotalHosts {
+			return
+		}
+	}
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Get campaign status
+/////////////////////////////////////////////////////////////////////////////////
+
+type getCampaignStatusRequest struct {
+	ID uint `url:"id"`
+}
+
+type getCampaignStatusResponse struct {
+	*fleet.DistributedQueryCampaignStatus
+	Err error `json:"error,omitempty"`
+}
+
+func (r getCampaignStatusResponse) error() error { return r.Err }
+
+func getCampaignStatusEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getCampaignStatusRequest)
+	status, err := svc.CampaignStatus(ctx, req.ID)
+	if err != nil {
+		return getCampaignStatusResponse{Err: err}, nil
+	}
+	return getCampaignStatusResponse{DistributedQueryCampaignStatus: status}, nil
+}
+
+func (svc Service) CampaignStatus(ctx context.Context, campaignID uint) (*fleet.DistributedQueryCampaignStatus, error) {
+	campaign, err := svc.authorizeCampaign(ctx, campaignID)
+	if err != nil {
+		return nil, err
+	}
+	return svc.campaignStatus(ctx, campaign)
+}
+
+// campaignStatus computes the status of the campaign from its stored results,
+// as the websocket does from the results it streams.
+func (svc Service) campaignStatus(ctx context.Context, campaign *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaignStatus, error) {
+	targets, err := svc.ds.DistributedQueryCampaignTargetIDs(ctx, campaign.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieve campaign targets")
+	}
+	metrics, err := svc.CountHostsInTargets(ctx, &campaign.QueryID, *targets)
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieve target counts")
+	}
+	campaign.Metrics = *metrics
+
+	actual, err := svc.ds.CountDistributedQueryCampaignResults(ctx, campaign.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &fleet.DistributedQueryCampaignStatus{
+		Campaign:        campaign,
+		ExpectedResults: metrics.OnlineHosts,
+		ActualResults:   actual,
+		Status:          campaignStatusPending,
+	}
+	if status.ActualResults >= status.ExpectedResults {
+		status.Status = campaignStatusFinished
+	}
+	return status, nil
+}
+
+// campaignCancelled returns whether the campaign was completed by someone
+// else than the listener of its results, i.e. cancelled.
+func (svc Service) campaignCancelled(ctx context.Context, campaignID uint) (bool, error) {
+	campaign, err := svc.ds.DistributedQueryCampaign(ctx, campaignID)
+	if err != nil {
+		return false, err
+	}
+	return campaign.Status == fleet.QueryComplete, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Get campaign hosts
+/////////////////////////////////////////////////////////////////////////////////
+
+type getCampaignHostsRequest struct {
+	ID          uint              `url:"id"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type getCampaignHostsResponse struct {
+	Hosts []*fleet.DistributedQueryCampaignHost `json:"hosts"`
+	Err   error                                 `json:"error,omitempty"`
+}
+
+func (r getCampaignHostsResponse) error() error { return r.Err }
+
+func getCampaignHostsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getCampaignHostsRequest)
+	hosts, err := svc.CampaignHosts(ctx, req.ID, req.ListOptions)
+	if err != nil {
+		return getCampaignHostsResponse{Err: err}, nil
+	}
+	return getCampaignHostsResponse{Hosts: hosts}, nil
+}
+
+func (svc Service) CampaignHosts(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignHost, error) {
+	if _, err := svc.authorizeCampaign(ctx, campaignID); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.ListDistributedQueryCampaignHosts(ctx, campaignID, opt)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Cancel campaign
+/////////////////////////////////////////////////////////////////////////////////
+
+type cancelCampaignRequest struct {
+	ID uint `url:"id"`
+}
+
+type cancelCampaignResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r cancelCampaignResponse) error() error { return r.Err }
+
+func cancelCampaignEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*cancelCampaignRequest)
+	if err := svc.CancelCampaign(ctx, req.ID); err != nil {
+		return cancelCampaignResponse{Err: err}, nil
+	}
+	return cancelCampaignResponse{}, nil
+}
+
+func (svc Service) CancelCampaign(ctx context.Context, campaignID uint) error {
+	campaign, err := svc.authorizeCampaign(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+
+	// The listener of the campaign results (websocket or REST API) stops
+	// when it sees the campaign completed.
+	if campaign.Status != fleet.QueryComplete {
+		campaign.Status = fleet.QueryComplete
+		if err := svc.ds.SaveDistributedQueryCampaign(ctx, campaign); err != nil {
+			return errors.Wrap(err, "save campaign")
+		}
+	}
+	if err := svc.liveQueryStore.StopQuery(strconv.Itoa(int(campaign.ID))); err != nil {
+		return errors.Wrap(err, "stop campaign query")
+	}
+	return nil
+}
+
+func (svc Service) CompleteExpiredCampaigns(ctx context.Context, now time.Time) error {
+	// skipauth: Expired campaigns are completed by the server.
+	svc.authz.SkipAuthorization(ctx)
+
+	expiredIDs, err := svc.ds.CompleteExpiredDistributedQueryCampaigns(ctx, now.Add(-svc.config.Osquery.LiveQueryTimeout))
+	if err != nil {
+		return errors.Wrap(err, "complete expired campaigns")
+	}
+	for _, id := range expiredIDs {
+		if err := svc.liveQueryStore.StopQuery(strconv.Itoa(int(id))); err != nil {
+			return errors.Wrap(err, "stop campaign query")
+		}
+	}
+	return nil
+}
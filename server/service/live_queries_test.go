@@ -0,0 +1,247 @@
+package service
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/live_query"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/fleetdm/fleet/v4/server/pubsub"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_RunLiveQuery(t *testing.T) {
+	ds := new(mock.Store)
+	ds.NewDistributedQueryCampaignHostsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint) error {
+		return nil
+	}
+	rs := pubsub.NewInmemQueryResults()
+	lq := new(live_query.MockLiveQuery)
+
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{}, nil
+	}
+	ds.NewQueryFunc = func(ctx context.Context, query *fleet.Query, opts ...fleet.OptionalArg) (*fleet.Query, error) {
+		query.ID = 7
+		return query, nil
+	}
+	ds.NewDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) (*fleet.DistributedQueryCampaign, error) {
+		camp.ID = 42
+		return camp, nil
+	}
+	ds.NewDistributedQueryCampaignTargetFunc = func(ctx context.Context, target *fleet.DistributedQueryCampaignTarget) (*fleet.DistributedQueryCampaignTarget, error) {
+		return target, nil
+	}
+	ds.HostIDsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets) ([]uint, error) {
+		return []uint{1, 2}, nil
+	}
+	ds.CountHostsInTargetsFunc = func(ctx context.Context, filter fleet.TeamFilter, targets fleet.HostTargets, now time.Time) (fleet.TargetMetrics, error) {
+		return fleet.TargetMetrics{TotalHosts: 2, OnlineHosts: 1, OfflineHosts: 1}, nil
+	}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, details *map[string]interface{}) error {
+		return nil
+	}
+	ds.QueryFunc = func(ctx context.Context, id uint) (*fleet.Query, error) {
+		return &fleet.Query{ID: id}, nil
+	}
+	var savedStatuses []fleet.DistributedQueryStatus
+	completed := make(chan struct{}, 1)
+	ds.SaveDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) error {
+		savedStatuses = append(savedStatuses, camp.Status)
+		if camp.Status == fleet.QueryComplete {
+			completed <- struct{}{}
+		}
+		return nil
+	}
+	ds.DistributedQueryCampaignTargetIDsFunc = func(ctx context.Context, id uint) (*fleet.HostTargets, error) {
+		return &fleet.HostTargets{HostIDs: []uint{1, 2}}, nil
+	}
+	var resultsCount uint32
+	ds.CountDistributedQueryCampaignResultsFunc = func(ctx context.Context, campaignID uint) (uint, error) {
+		return uint(atomic.LoadUint32(&resultsCount)), nil
+	}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return &fleet.DistributedQueryCampaign{ID: id, Status: fleet.QueryRunning}, nil
+	}
+	lq.On("RunQuery", "42", "select 1", []uint{1, 2}).Return(nil)
+	lq.On("StopQuery", "42").Return(nil)
+
+	user := &fleet.User{ID: 3, GlobalRole: ptr.String(fleet.RoleAdmin)}
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: user})
+	targets := fleet.HostTargets{HostIDs: []uint{1, 2}}
+
+	// Results must be stored to be retrieved without a websocket.
+	svc := newTestService(ds, rs, lq)
+	_, err := svc.RunLiveQuery(ctx, "select 1", nil, targets, 0)
+	require.Error(t, err)
+	assert.False(t, ds.NewDistributedQueryCampaignFuncInvoked)
+
+	cfg := config.TestConfig()
+	cfg.Osquery.LiveQueryResultsRetention = time.Hour
+	// The campaigns time out before their listener checks their status.
+	cfg.Osquery.LiveQueryTimeout = 500 * time.Millisecond
+	svc = newTestServiceWithConfig(ds, cfg, rs, lq)
+
+	status, err := svc.RunLiveQuery(ctx, "select 1", nil, targets, 0)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), status.Campaign.ID)
+	assert.Equal(t, fleet.TargetMetrics{TotalHosts: 2, OnlineHosts: 1, OfflineHosts: 1}, status.Campaign.Metrics)
+	assert.Equal(t, uint(1), status.ExpectedResults)
+	assert.Equal(t, uint(0), status.ActualResults)
+	assert.Equal(t, campaignStatusPending, status.Status)
+
+	// The campaign is completed once it timed out.
+	<-completed
+	assert.Equal(t, []fleet.DistributedQueryStatus{fleet.QueryRunning, fleet.QueryComplete}, savedStatuses)
+
+	// Waiting returns as soon as the online hosts responded.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		atomic.StoreUint32(&resultsCount, 1)
+	}()
+	start := time.Now()
+	status, err = svc.RunLiveQuery(ctx, "select 1", nil, targets, 10*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, campaignStatusFinished, status.Status)
+	assert.Equal(t, uint(1), status.ActualResults)
+	assert.Less(t, int64(time.Since(start)), int64(5*time.Second))
+	<-completed
+}
+
+func TestService_ListenToCampaign(t *testing.T) {
+	ds := new(mock.Store)
+	lq := new(live_query.MockLiveQuery)
+	svc := &Service{
+		ds:             ds,
+		liveQueryStore: lq,
+		logger:         kitlog.NewNopLogger(),
+	}
+
+	var savedStatus fleet.DistributedQueryStatus
+	ds.SaveDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) error {
+		savedStatus = camp.Status
+		return nil
+	}
+	lq.On("StopQuery", "42").Return(nil)
+
+	// The campaign is completed and its query stopped once the listener is
+	// done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	campaign := &fleet.DistributedQueryCampaign{ID: 42, Status: fleet.QueryRunning}
+	svc.listenToCampaign(ctx, campaign, make(chan interface{}))
+	assert.Equal(t, fleet.QueryComplete, savedStatus)
+	lq.AssertExpectations(t)
+}
+
+func TestService_ListenToCampaignCancelled(t *testing.T) {
+	ds := new(mock.Store)
+	lq := new(live_query.MockLiveQuery)
+	svc := &Service{
+		ds:             ds,
+		liveQueryStore: lq,
+		logger:         kitlog.NewNopLogger(),
+	}
+
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return &fleet.DistributedQueryCampaign{ID: id, Status: fleet.QueryComplete}, nil
+	}
+	ds.SaveDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) error {
+		return nil
+	}
+	lq.On("StopQuery", "42").Return(nil)
+
+	// The listener stops on its next check once the campaign is cancelled.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	campaign := &fleet.DistributedQueryCampaign{ID: 42, Status: fleet.QueryRunning}
+	svc.listenToCampaign(ctx, campaign, make(chan interface{}))
+	assert.True(t, ds.DistributedQueryCampaignFuncInvoked)
+	assert.False(t, ds.DistributedQueryCampaignTargetIDsFuncInvoked)
+	require.NoError(t, ctx.Err())
+	lq.AssertExpectations(t)
+}
+
+func TestService_CampaignHosts(t *testing.T) {
+	ds := new(mock.Store)
+
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return &fleet.DistributedQueryCampaign{ID: id, UserID: 3}, nil
+	}
+	ds.ListDistributedQueryCampaignHostsFunc = func(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignHost, error) {
+		return []*fleet.DistributedQueryCampaignHost{
+			{HostID: 1, Hostname: "foo", Status: fleet.ExecutionSucceeded, RowsCount: 2},
+			{HostID: 2, Hostname: "bar", Status: fleet.ExecutionWaiting},
+		}, nil
+	}
+
+	svc := newTestService(ds, nil, nil)
+
+	otherUser := &fleet.User{ID: 4, GlobalRole: ptr.String(fleet.RoleAdmin)}
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: otherUser})
+	_, err := svc.CampaignHosts(ctx, 42, fleet.ListOptions{})
+	require.Error(t, err)
+	assert.False(t, ds.ListDistributedQueryCampaignHostsFuncInvoked)
+
+	owner := &fleet.User{ID: 3, GlobalRole: ptr.String(fleet.RoleObserver)}
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: owner})
+	hosts, err := svc.CampaignHosts(ctx, 42, fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, hosts, 2)
+	assert.Equal(t, fleet.ExecutionWaiting, hosts[1].Status)
+}
+
+func TestService_CancelCampaign(t *testing.T) {
+	ds := new(mock.Store)
+	lq := new(live_query.MockLiveQuery)
+
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return &fleet.DistributedQueryCampaign{ID: id, UserID: 3, Status: fleet.QueryRunning}, nil
+	}
+	var savedStatus fleet.DistributedQueryStatus
+	ds.SaveDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) error {
+		savedStatus = camp.Status
+		return nil
+	}
+	lq.On("StopQuery", "42").Return(nil)
+
+	svc := newTestService(ds, nil, lq)
+
+	otherUser := &fleet.User{ID: 4, GlobalRole: ptr.String(fleet.RoleAdmin)}
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: otherUser})
+	require.Error(t, svc.CancelCampaign(ctx, 42))
+	assert.False(t, ds.SaveDistributedQueryCampaignFuncInvoked)
+
+	owner := &fleet.User{ID: 3, GlobalRole: ptr.String(fleet.RoleObserver)}
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: owner})
+	require.NoError(t, svc.CancelCampaign(ctx, 42))
+	assert.Equal(t, fleet.QueryComplete, savedStatus)
+	lq.AssertExpectations(t)
+}
+
+func TestService_CompleteExpiredCampaigns(t *testing.T) {
+	ds := new(mock.Store)
+	lq := new(live_query.MockLiveQuery)
+
+	now := time.Now()
+	ds.CompleteExpiredDistributedQueryCampaignsFunc = func(ctx context.Context, createdBefore time.Time) ([]uint, error) {
+		assert.Equal(t, now.Add(-time.Hour), createdBefore)
+		return []uint{42, 43}, nil
+	}
+	lq.On("StopQuery", "42").Return(nil)
+	lq.On("StopQuery", "43").Return(nil)
+
+	svc := newTestService(ds, nil, lq)
+
+	require.NoError(t, svc.CompleteExpiredCampaigns(context.Background(), now))
+	assert.True(t, ds.CompleteExpiredDistributedQueryCampaignsFuncInvoked)
+	lq.AssertExpectations(t)
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// logEnrichmentKey is the key of the object holding the host metadata in the
+// enriched logs.
+const logEnrichmentKey = "fleet"
+
+// The host metadata fields that can be injected in the logs.
+const (
+	logEnrichmentHostID         = "host_id"
+	logEnrichmentHostname       = "hostname"
+	logEnrichmentTeamName       = "team_name"
+	logEnrichmentLabels         = "labels"
+	logEnrichmentHardwareSerial = "hardware_serial"
+	logEnrichmentPrimaryIP      = "primary_ip"
+)
+
+var logEnrichmentFields = map[string]bool{
+	logEnrichmentHostID:         true,
+	logEnrichmentHostname:       true,
+	logEnrichmentTeamName:       true,
+	logEnrichmentLabels:         true,
+	logEnrichmentHardwareSerial: true,
+	logEnrichmentPrimaryIP:      true,
+}
+
+// parseLogEnrichmentFields parses the comma-separated list of the host
+// metadata fields to inject in the logs.
+func parseLogEnrichmentFields(s string) ([]string, error) {
+	var fields []string
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !logEnrichmentFields[field] {
+			return nil, errors.Errorf("unknown log enrichment field %q", field)
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// logEnrichmentCacheTTL is how long the team and label names of the hosts
+// are cached, so that they are not loaded for each batch of logs.
+const logEnrichmentCacheTTL = 1 * time.Minute
+
+// logEnrichment holds the host metadata fields injected in the logs, and
+// caches the team names and the label names of the hosts.
+type logEnrichment struct {
+	fields []string
+	ttl    time.Duration
+
+	mu         sync.Mutex
+	teamNames  map[uint]cachedLogEnrichment
+	hostLabels map[uint]cachedLogEnrichment
+	prunedAt   time.Time
+}
+
+type cachedLogEnrichment struct {
+	value    interface{}
+	loadedAt time.Time
+}
+
+// newLogEnrichment parses the comma-separated list of the host metadata
+// fields to inject in the logs.
+func newLogEnrichment(s string, ttl time.Duration) (*logEnrichment, error) {
+	fields, err := parseLogEnrichmentFields(s)
+	if err != nil {
+		return nil, err
+	}
+	return &logEnrichment{
+		fields:     fields,
+		ttl:        ttl,
+		teamNames:  make(map[uint]cachedLogEnrichment),
+		hostLabels: make(map[uint]cachedLogEnrichment),
+	}, nil
+}
+
+// cached returns the value cached for id, and loads it when it is missing or
+// expired. Failing loads are not cached.
+func (e *logEnrichment) cached(
+	cache map[uint]cachedLogEnrichment, id uint, now time.Time, load func() (interface{}, error),
+) (interface{}, error) {
+	e.mu.Lock()
+	entry, ok := cache[id]
+	e.mu.Unlock()
+	if ok && now.Sub(entry.loadedAt) < e.ttl {
+		return entry.value, nil
+	}
+
+	value, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	cache[id] = cachedLogEnrichment{value: value, loadedAt: now}
+	// The expired entries are removed from time to time, so that the caches
+	// do not keep the hosts that stopped sending logs.
+	if now.Sub(e.prunedAt) >= e.ttl {
+		for _, c := range []map[uint]cachedLogEnrichment{e.teamNames, e.hostLabels} {
+			for id, entry := range c {
+				if now.Sub(entry.loadedAt) >= e.ttl {
+					delete(c, id)
+				}
+			}
+		}
+		e.prunedAt = now
+	}
+	return value, nil
+}
+
+// enrichLogs injects the configured metadata of the host in the context in
+// each log, under the "fleet" key. The logs that are not JSON objects are
+// left unchanged. Failing to load the metadata is only logged, so that the
+// logs are still written.
+func (svc *Service) enrichLogs(ctx context.Context, logs []json.RawMessage) []json.RawMessage {
+	if svc.logEnrichment == nil || len(svc.logEnrichment.fields) == 0 {
+		return logs
+	}
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return logs
+	}
+
+	now := svc.clock.Now()
+	fields := svc.logEnrichment.fields
+	metadata := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		switch field {
+		case logEnrichmentHostID:
+			metadata[field] = host.ID
+		case logEnrichmentHostname:
+			metadata[field] = host.Hostname
+		case logEnrichmentHardwareSerial:
+			metadata[field] = host.HardwareSerial
+		case logEnrichmentPrimaryIP:
+			metadata[field] = host.PrimaryIP
+		case logEnrichmentTeamName:
+			// The host in the context is loaded without its team.
+			var teamName *string
+			if host.TeamID != nil {
+				name, err := svc.logEnrichment.cached(svc.logEnrichment.teamNames, *host.TeamID, now, func() (interface{}, error) {
+					team, err := svc.ds.Team(ctx, *host.TeamID)
+					if err != nil {
+						return nil, err
+					}
+					return team.Name, nil
+				})
+				if err != nil {
+					level.Error(svc.logger).Log("msg", "load team for log enrichment", "host", host.Hostname, "err", err)
+				} else {
+					teamName = ptr.String(name.(string))
+				}
+			}
+			metadata[field] = teamName
+		case logEnrichmentLabels:
+			names, err := svc.logEnrichment.cached(svc.logEnrichment.hostLabels, host.ID, now, func() (interface{}, error) {
+				labels, err := svc.ds.ListLabelsForHost(ctx, host.ID)
+				if err != nil {
+					return nil, err
+				}
+				names := make([]string, 0, len(labels))
+				for _, label := range labels {
+					names = append(names, label.Name)
+				}
+				return names, nil
+			})
+			if err != nil {
+				level.Error(svc.logger).Log("msg", "load labels for log enrichment", "host", host.Hostname, "err", err)
+				names = []string{}
+			}
+			metadata[field] = names
+		}
+	}
+	encoded, err := json.Marshal(metadata)
+	if err != nil {
+		level.Error(svc.logger).Log("msg", "encode log enrichment", "host", host.Hostname, "err", err)
+		return logs
+	}
+
+	enriched := make([]json.RawMessage, 0, len(logs))
+	for _, log := range logs {
+		enriched = append(enriched, injectLogField(log, logEnrichmentKey, encoded))
+	}
+	return enriched
+}
+
+// injectLogField adds the key with value at the end of the JSON object log.
+// The rest of the log is kept as is.
+func injectLogField(log json.RawMessage, key string, value []byte) json.RawMessage {
+	trimmed := bytes.TrimSpace(log)
+	if len(trimmed) < 2 || trimmed[0] != '{' || trimmed[len(trimmed)-1] != '}' {
+		return log
+	}
+	body := bytes.TrimSpace(trimmed[1 : len(trimmed)-1])
+
+	var buf bytes.Buffer
+	buf.Grow(len(log) + len(key) + len(value) + 4)
+	buf.WriteByte('{')
+	if len(body) > 0 {
+		buf.Write(body)
+		buf.WriteByte(',')
+	}
+	encodedKey, _ := json.Marshal(key)
+	buf.Write(encodedKey)
+	buf.WriteByte(':')
+	buf.Write(value)
+	buf.WriteByte('}')
+	return buf.Bytes()
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/WatchBeam/clock"
+	"github.com/fleetdm/fleet/v4/server/config"
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/logging"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubmitLogsEnrichment(t *testing.T) {
+	ds := new(mock.Store)
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		assert.Equal(t, uint(3), tid)
+		return &fleet.Team{ID: 3, Name: "Workstations"}, nil
+	}
+	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
+		assert.Equal(t, uint(42), hid)
+		return []*fleet.Label{{Name: "All Hosts"}, {Name: "macOS"}}, nil
+	}
+
+	resultLogger, statusLogger := &testJSONLogger{}, &testJSONLogger{}
+	mockClock := clock.NewMockClock()
+	svc := &Service{
+		ds:               ds,
+		logger:           kitlog.NewNopLogger(),
+		config:           config.TestConfig(),
+		clock:            mockClock,
+		osqueryLogWriter: &logging.OsqueryLogger{Result: resultLogger, Status: statusLogger},
+	}
+	setFields := func(s string) {
+		var err error
+		svc.logEnrichment, err = newLogEnrichment(s, logEnrichmentCacheTTL)
+		require.NoError(t, err)
+	}
+	host := fleet.Host{ID: 42, Hostname: "foo.local", HardwareSerial: "C02XYZ", PrimaryIP: "10.0.0.2", TeamID: ptr.Uint(3)}
+	ctx := hostctx.NewContext(context.Background(), host)
+	logs := []json.RawMessage{
+		json.RawMessage(`{"name":"pack/Global/time","action":"added"}`),
+		json.RawMessage(` { } `),
+		json.RawMessage(`"not an object"`),
+	}
+
+	// The logs are not enriched by default.
+	require.NoError(t, svc.SubmitResultLogs(ctx, logs))
+	assert.Equal(t, logs, resultLogger.logs)
+	assert.False(t, ds.TeamFuncInvoked)
+
+	setFields("host_id, hostname,team_name,labels,hardware_serial,primary_ip")
+	require.NoError(t, svc.SubmitResultLogs(ctx, logs))
+	fleetJSON := `{"host_id":42,"hostname":"foo.local","team_name":"Workstations","labels":["All Hosts","macOS"],"hardware_serial":"C02XYZ","primary_ip":"10.0.0.2"}`
+	require.Len(t, resultLogger.logs, 3)
+	// The fields of the logs are kept as is.
+	assert.Contains(t, string(resultLogger.logs[0]), `{"name":"pack/Global/time","action":"added","fleet":`)
+	assert.JSONEq(t, `{"name":"pack/Global/time","action":"added","fleet":`+fleetJSON+`}`, string(resultLogger.logs[0]))
+	assert.JSONEq(t, `{"fleet":`+fleetJSON+`}`, string(resultLogger.logs[1]))
+	assert.Equal(t, logs[2], resultLogger.logs[2])
+
+	// The team and label names are cached.
+	ds.TeamFuncInvoked, ds.ListLabelsForHostFuncInvoked = false, false
+	resultLogger.logs = nil
+	require.NoError(t, svc.SubmitResultLogs(ctx, logs[:1]))
+	assert.JSONEq(t, `{"name":"pack/Global/time","action":"added","fleet":`+fleetJSON+`}`, string(resultLogger.logs[0]))
+	assert.False(t, ds.TeamFuncInvoked)
+	assert.False(t, ds.ListLabelsForHostFuncInvoked)
+
+	// And loaded again once expired.
+	mockClock.AddTime(logEnrichmentCacheTTL)
+	require.NoError(t, svc.SubmitResultLogs(ctx, logs[:1]))
+	assert.True(t, ds.TeamFuncInvoked)
+	assert.True(t, ds.ListLabelsForHostFuncInvoked)
+
+	// Hosts without a team, and failures to load the labels.
+	setFields("team_name,labels")
+	ds.TeamFuncInvoked = false
+	ds.ListLabelsForHostFunc = func(ctx context.Context, hid uint) ([]*fleet.Label, error) {
+		return nil, errors.New("boom")
+	}
+	ctx = hostctx.NewContext(context.Background(), fleet.Host{ID: 42})
+	require.NoError(t, svc.SubmitStatusLogs(ctx, logs[:1]))
+	require.Len(t, statusLogger.logs, 1)
+	assert.JSONEq(t, `{"name":"pack/Global/time","action":"added","fleet":{"team_name":null,"labels":[]}}`, string(statusLogger.logs[0]))
+	assert.False(t, ds.TeamFuncInvoked)
+}
+
+func TestParseLogEnrichmentFields(t *testing.T) {
+	fields, err := parseLogEnrichmentFields(" host_id,labels ,")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"host_id", "labels"}, fields)
+
+	fields, err = parseLogEnrichmentFields("")
+	require.NoError(t, err)
+	assert.Empty(t, fields)
+
+	_, err = parseLogEnrichmentFields("host_id,uuid")
+	require.Error(t, err)
+
+	// The fields are validated when the service is created.
+	conf := config.TestConfig()
+	conf.Osquery.LogEnrichmentFields = "uuid"
+	_, err = NewService(new(mock.Store), nil, kitlog.NewNopLogger(), nil, conf, nil, nil, nil, nil, nil, fleet.LicenseInfo{})
+	require.Error(t, err)
+}
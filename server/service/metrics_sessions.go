@@ -38,6 +38,26 @@ func (mw metricsMiddleware) CallbackSSO(ctx context.Context, auth fleet.Auth) (s
 	return
 }
 
+func (mw metricsMiddleware) CallbackOIDC(ctx context.Context, callback fleet.OIDCCallback) (sess *fleet.SSOSession, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "CallbackOIDC", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	sess, err = mw.Service.CallbackOIDC(ctx, callback)
+	return
+}
+
+func (mw metricsMiddleware) SSOServiceProviderMetadata(ctx context.Context) (metadata []byte, err error) {
+	defer func(begin time.Time) {
+		lvs := []string{"method", "SSOServiceProviderMetadata", "error", fmt.Sprint(err != nil)}
+		mw.requestCount.With(lvs...).Add(1)
+		mw.requestLatency.With(lvs...).Observe(time.Since(begin).Seconds())
+	}(time.Now())
+	metadata, err = mw.Service.SSOServiceProviderMetadata(ctx)
+	return
+}
+
 func (mw metricsMiddleware) Login(ctx context.Context, email string, password string) (*fleet.User, string, error) {
 	var (
 		user  *fleet.User
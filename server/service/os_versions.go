@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+/////////////////////////////////////////////////////////////////////////////////
+// Get OS versions
+/////////////////////////////////////////////////////////////////////////////////
+
+type getOSVersionsRequest struct {
+	TeamID   *uint  `query:"team_id,optional"`
+	Platform string `query:"platform,optional"`
+}
+
+type getOSVersionsResponse struct {
+	*fleet.OSVersions
+	Err error `json:"error,omitempty"`
+}
+
+func (r getOSVersionsResponse) error() error { return r.Err }
+
+func getOSVersionsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getOSVersionsRequest)
+	osVersions, err := svc.OSVersions(ctx, fleet.OSVersionsOptions{TeamID: req.TeamID, Platform: req.Platform})
+	if err != nil {
+		return getOSVersionsResponse{Err: err}, nil
+	}
+	return getOSVersionsResponse{OSVersions: osVersions}, nil
+}
+
+func (svc Service) OSVersions(ctx context.Context, opt fleet.OSVersionsOptions) (*fleet.OSVersions, error) {
+	// The counts cover all the hosts of the team, or all hosts if no team is
+	// given, so the user must be able to read all of them.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{TeamID: opt.TeamID}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.OSVersions(ctx, opt)
+}
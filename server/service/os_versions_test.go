@@ -0,0 +1,56 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_OSVersions(t *testing.T) {
+	ds := new(mock.Store)
+
+	var calledWithOpt fleet.OSVersionsOptions
+	ds.OSVersionsFunc = func(ctx context.Context, opt fleet.OSVersionsOptions) (*fleet.OSVersions, error) {
+		calledWithOpt = opt
+		return &fleet.OSVersions{}, nil
+	}
+
+	svc := newTestService(ds, nil, nil)
+
+	globalObserver := &fleet.User{ID: 3, GlobalRole: ptr.String(fleet.RoleObserver)}
+	teamObserver := &fleet.User{ID: 4, Teams: []fleet.UserTeam{{Team: fleet.Team{ID: 42}, Role: fleet.RoleObserver}}}
+
+	testCases := []struct {
+		name       string
+		user       *fleet.User
+		opt        fleet.OSVersionsOptions
+		shouldFail bool
+	}{
+		{"global observer all hosts", globalObserver, fleet.OSVersionsOptions{Platform: "darwin"}, false},
+		{"global observer team", globalObserver, fleet.OSVersionsOptions{TeamID: ptr.Uint(42)}, false},
+		{"team observer own team", teamObserver, fleet.OSVersionsOptions{TeamID: ptr.Uint(42)}, false},
+		{"team observer other team", teamObserver, fleet.OSVersionsOptions{TeamID: ptr.Uint(43)}, true},
+		{"team observer all hosts", teamObserver, fleet.OSVersionsOptions{}, true},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			ds.OSVersionsFuncInvoked = false
+			ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: tt.user})
+
+			_, err := svc.OSVersions(ctx, tt.opt)
+			if tt.shouldFail {
+				require.Error(t, err)
+				assert.False(t, ds.OSVersionsFuncInvoked)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.opt, calledWithOpt)
+		})
+	}
+}
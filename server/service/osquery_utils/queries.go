@@ -17,6 +17,9 @@ import (
 
 type DetailQuery struct {
 	Query string
+	// Discovery is the SQL of a discovery query, osquery only runs the query
+	// when it returns rows. If this value is empty, the query always runs.
+	Discovery string
 	// Platforms is a list of platforms to run the query on. If this value is
 	// empty, run on all platforms.
 	Platforms  []string
@@ -36,6 +39,12 @@ func (q *DetailQuery) RunsForPlatform(platform string) bool {
 	return false
 }
 
+// discoveryTable returns a discovery query that returns rows only when the
+// table is registered, for tables provided by osquery extensions.
+func discoveryTable(tableName string) string {
+	return fmt.Sprintf("SELECT 1 FROM osquery_registry WHERE active = true AND registry = 'table' AND name = '%s';", tableName)
+}
+
 // detailQueries defines the detail queries that should be run on the host, as
 // well as how the results of those queries should be ingested into the
 // fleet.Host data model. This map should not be modified at runtime.
@@ -352,6 +361,40 @@ FROM logical_drives WHERE file_system = 'NTFS' LIMIT 1;`,
 		Platforms:  []string{"windows"},
 		IngestFunc: ingestDiskSpace,
 	},
+	"disk_encryption_unix": {
+		Query: `
+SELECT de.encrypted
+FROM mounts m JOIN disk_encryption de ON (m.device_alias = de.name)
+WHERE m.path = '/' LIMIT 1;`,
+		Platforms:  []string{"darwin", "linux", "rhel", "ubuntu", "centos"},
+		IngestFunc: ingestDiskEncryption,
+	},
+	"disk_encryption_windows": {
+		Query: `
+SELECT (protection_status = 1) AS encrypted
+FROM bitlocker_info WHERE drive_letter = 'C:' LIMIT 1;`,
+		Platforms:  []string{"windows"},
+		IngestFunc: ingestDiskEncryption,
+	},
+	"mdm": {
+		// The mdm table is provided by the macadmins osquery extension.
+		Query:      `SELECT enrolled, server_url FROM mdm;`,
+		Discovery:  discoveryTable("mdm"),
+		Platforms:  []string{"darwin"},
+		IngestFunc: ingestMDM,
+	},
+	"munki_info": {
+		// The munki_info table is provided by the macadmins osquery extension.
+		Query:      `SELECT version FROM munki_info;`,
+		Discovery:  discoveryTable("munki_info"),
+		Platforms:  []string{"darwin"},
+		IngestFunc: ingestMunkiInfo,
+	},
+	"battery": {
+		Query:      `SELECT serial_number, cycle_count, health FROM battery;`,
+		Platforms:  []string{"darwin"},
+		IngestFunc: ingestBattery,
+	},
 }
 
 var softwareMacOS = DetailQuery{
@@ -407,6 +450,7 @@ var softwareLinux = DetailQuery{
 SELECT
   name AS name,
   version AS version,
+  '' AS release,
   'Package (deb)' AS type,
   'deb_packages' AS source
 FROM deb_packages
@@ -414,6 +458,7 @@ UNION
 SELECT
   package AS name,
   version AS version,
+  '' AS release,
   'Package (Portage)' AS type,
   'portage_packages' AS source
 FROM portage_packages
@@ -421,6 +466,7 @@ UNION
 SELECT
   name AS name,
   version AS version,
+  release AS release,
   'Package (RPM)' AS type,
   'rpm_packages' AS source
 FROM rpm_packages
@@ -428,6 +474,7 @@ UNION
 SELECT
   name AS name,
   version AS version,
+  '' AS release,
   'Package (NPM)' AS type,
   'npm_packages' AS source
 FROM npm_packages
@@ -435,6 +482,7 @@ UNION
 SELECT
   name AS name,
   version AS version,
+  '' AS release,
   'Package (Atom)' AS type,
   'atom_packages' AS source
 FROM atom_packages
@@ -442,6 +490,7 @@ UNION
 SELECT
   name AS name,
   version AS version,
+  '' AS release,
   'Package (Python)' AS type,
   'python_packages' AS source
 FROM python_packages;
@@ -563,7 +612,7 @@ func ingestSoftware(logger log.Logger, host *fleet.Host, rows []map[string]strin
 			)
 			continue
 		}
-		s := fleet.Software{Name: name, Version: version, Source: source}
+		s := fleet.Software{Name: name, Version: version, Release: row["release"], Source: source}
 		software.Software = append(software.Software, s)
 	}
 
@@ -591,6 +640,81 @@ func ingestDiskSpace(logger log.Logger, host *fleet.Host, rows []map[string]stri
 	return nil
 }
 
+// ingestDiskEncryption records whether the boot disk is encrypted. No rows
+// means the status is unknown, and nothing is recorded.
+func ingestDiskEncryption(logger log.Logger, host *fleet.Host, rows []map[string]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if len(rows) > 1 {
+		logger.Log("component", "service", "method", "ingestDiskEncryption", "err",
+			fmt.Sprintf("detail_query_disk_encryption expected single result got %d", len(rows)))
+		return nil
+	}
+
+	encrypted, err := strconv.ParseBool(EmptyToZero(rows[0]["encrypted"]))
+	if err != nil {
+		return errors.Wrapf(err, "parsing encrypted %s", rows[0]["encrypted"])
+	}
+	host.DiskEncryption = &fleet.HostDiskEncryption{Encrypted: encrypted}
+	return nil
+}
+
+// ingestMDM records the MDM enrollment of the host. The query only runs when
+// the macadmins extension is loaded, no rows means nothing is recorded.
+func ingestMDM(logger log.Logger, host *fleet.Host, rows []map[string]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if len(rows) > 1 {
+		logger.Log("component", "service", "method", "ingestMDM", "err",
+			fmt.Sprintf("detail_query_mdm expected single result got %d", len(rows)))
+		return nil
+	}
+
+	enrolled, err := strconv.ParseBool(EmptyToZero(rows[0]["enrolled"]))
+	if err != nil {
+		return errors.Wrapf(err, "parsing enrolled %s", rows[0]["enrolled"])
+	}
+	host.MDM = &fleet.HostMDM{Enrolled: enrolled, ServerURL: rows[0]["server_url"]}
+	return nil
+}
+
+// ingestMunkiInfo records the munki version of the host. The query only runs
+// when the macadmins extension is loaded, no rows means nothing is recorded.
+func ingestMunkiInfo(logger log.Logger, host *fleet.Host, rows []map[string]string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if len(rows) > 1 {
+		logger.Log("component", "service", "method", "ingestMunkiInfo", "err",
+			fmt.Sprintf("detail_query_munki_info expected single result got %d", len(rows)))
+		return nil
+	}
+
+	host.Munki = &fleet.HostMunkiInfo{Version: rows[0]["version"]}
+	return nil
+}
+
+// ingestBattery records the batteries of the host. No rows means the host
+// has no battery.
+func ingestBattery(logger log.Logger, host *fleet.Host, rows []map[string]string) error {
+	batteries := []fleet.HostBattery{}
+	for _, row := range rows {
+		cycleCount, err := strconv.Atoi(EmptyToZero(row["cycle_count"]))
+		if err != nil {
+			return errors.Wrapf(err, "parsing cycle_count %s", row["cycle_count"])
+		}
+		batteries = append(batteries, fleet.HostBattery{
+			SerialNumber: row["serial_number"],
+			CycleCount:   cycleCount,
+			Health:       row["health"],
+		})
+	}
+	host.Batteries = batteries
+	return nil
+}
+
 func GetDetailQueries(ac *fleet.AppConfig) map[string]DetailQuery {
 	generatedMap := make(map[string]DetailQuery)
 	for key, query := range detailQueries {
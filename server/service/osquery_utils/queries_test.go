@@ -276,7 +276,7 @@ func sortedKeysCompare(t *testing.T, m map[string]DetailQuery, expectedKeys []st
 
 func TestGetDetailQueries(t *testing.T) {
 	queriesNoConfig := GetDetailQueries(nil)
-	require.Len(t, queriesNoConfig, 9)
+	require.Len(t, queriesNoConfig, 14)
 	baseQueries := []string{
 		"network_interface",
 		"os_version",
@@ -287,19 +287,122 @@ func TestGetDetailQueries(t *testing.T) {
 		"uptime",
 		"disk_space_unix",
 		"disk_space_windows",
+		"disk_encryption_unix",
+		"disk_encryption_windows",
+		"mdm",
+		"munki_info",
+		"battery",
 	}
 	sortedKeysCompare(t, queriesNoConfig, baseQueries)
 
 	queriesWithUsers := GetDetailQueries(&fleet.AppConfig{HostSettings: fleet.HostSettings{EnableHostUsers: true}})
-	require.Len(t, queriesWithUsers, 10)
+	require.Len(t, queriesWithUsers, 15)
 	sortedKeysCompare(t, queriesWithUsers, append(baseQueries, "users"))
 
 	require.NoError(t, os.Setenv("FLEET_BETA_SOFTWARE_INVENTORY", "1"))
 
 	queriesWithUsersAndSoftware := GetDetailQueries(&fleet.AppConfig{HostSettings: fleet.HostSettings{EnableHostUsers: true}})
-	require.Len(t, queriesWithUsersAndSoftware, 13)
+	require.Len(t, queriesWithUsersAndSoftware, 18)
 	sortedKeysCompare(t, queriesWithUsersAndSoftware,
 		append(baseQueries, "users", "software_macos", "software_linux", "software_windows"))
 
 	require.NoError(t, os.Setenv("FLEET_BETA_SOFTWARE_INVENTORY", ""))
 }
+
+func TestDetailQueryDiskEncryption(t *testing.T) {
+	for _, name := range []string{"disk_encryption_unix", "disk_encryption_windows"} {
+		t.Run(name, func(t *testing.T) {
+			var host fleet.Host
+
+			ingest := GetDetailQueries(nil)[name].IngestFunc
+
+			// The status is unknown when no rows are returned.
+			assert.NoError(t, ingest(log.NewNopLogger(), &host, nil))
+			assert.Nil(t, host.DiskEncryption)
+
+			assert.NoError(t, ingest(log.NewNopLogger(), &host, []map[string]string{{"encrypted": "1"}}))
+			assert.Equal(t, &fleet.HostDiskEncryption{Encrypted: true}, host.DiskEncryption)
+
+			assert.NoError(t, ingest(log.NewNopLogger(), &host, []map[string]string{{"encrypted": "0"}}))
+			assert.Equal(t, &fleet.HostDiskEncryption{Encrypted: false}, host.DiskEncryption)
+
+			assert.Error(t, ingest(log.NewNopLogger(), &host, []map[string]string{{"encrypted": "foo"}}))
+		})
+	}
+}
+
+func TestDetailQueryMDM(t *testing.T) {
+	var host fleet.Host
+
+	ingest := GetDetailQueries(nil)["mdm"].IngestFunc
+
+	assert.NoError(t, ingest(log.NewNopLogger(), &host, nil))
+	assert.Nil(t, host.MDM)
+
+	var rows []map[string]string
+	require.NoError(t, json.Unmarshal([]byte(`
+[
+  {"enrolled":"true","server_url":"https://mdm.example.com/mdm/apple/mdm"}
+]`),
+		&rows,
+	))
+	assert.NoError(t, ingest(log.NewNopLogger(), &host, rows))
+	assert.Equal(t, &fleet.HostMDM{Enrolled: true, ServerURL: "https://mdm.example.com/mdm/apple/mdm"}, host.MDM)
+
+	assert.NoError(t, ingest(log.NewNopLogger(), &host, []map[string]string{{"enrolled": "false", "server_url": ""}}))
+	assert.Equal(t, &fleet.HostMDM{Enrolled: false}, host.MDM)
+}
+
+func TestDetailQueryMunkiInfo(t *testing.T) {
+	var host fleet.Host
+
+	ingest := GetDetailQueries(nil)["munki_info"].IngestFunc
+
+	assert.NoError(t, ingest(log.NewNopLogger(), &host, nil))
+	assert.Nil(t, host.Munki)
+
+	assert.NoError(t, ingest(log.NewNopLogger(), &host, []map[string]string{{"version": "5.5.1.4365"}}))
+	assert.Equal(t, &fleet.HostMunkiInfo{Version: "5.5.1.4365"}, host.Munki)
+}
+
+func TestDetailQueryDiscovery(t *testing.T) {
+	for name, query := range GetDetailQueries(nil) {
+		switch name {
+		case "mdm", "munki_info":
+			// The tables are provided by the macadmins extension.
+			assert.Equal(t,
+				"SELECT 1 FROM osquery_registry WHERE active = true AND registry = 'table' AND name = '"+name+"';",
+				query.Discovery,
+			)
+		default:
+			assert.Empty(t, query.Discovery, name)
+		}
+	}
+}
+
+func TestDetailQueryBattery(t *testing.T) {
+	var host fleet.Host
+
+	ingest := GetDetailQueries(nil)["battery"].IngestFunc
+
+	var rows []map[string]string
+	require.NoError(t, json.Unmarshal([]byte(`
+[
+  {"serial_number":"D865465HB9HGRFQAX","cycle_count":"312","health":"Good"},
+  {"serial_number":"D865465HB9HGRFQAY","cycle_count":"","health":"Poor"}
+]`),
+		&rows,
+	))
+	assert.NoError(t, ingest(log.NewNopLogger(), &host, rows))
+	assert.Equal(t, []fleet.HostBattery{
+		{SerialNumber: "D865465HB9HGRFQAX", CycleCount: 312, Health: "Good"},
+		{SerialNumber: "D865465HB9HGRFQAY", CycleCount: 0, Health: "Poor"},
+	}, host.Batteries)
+
+	// A host without battery has an empty list of batteries.
+	assert.NoError(t, ingest(log.NewNopLogger(), &host, nil))
+	assert.NotNil(t, host.Batteries)
+	assert.Empty(t, host.Batteries)
+
+	assert.Error(t, ingest(log.NewNopLogger(), &host, []map[string]string{{"serial_number": "x", "cycle_count": "foo"}}))
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+)
+
+/////////////////////////////////////////////////////////////////////////////////
+// List history
+/////////////////////////////////////////////////////////////////////////////////
+
+type listPoliciesHistoryRequest struct {
+	Days *uint `query:"days,optional"`
+}
+
+type listPoliciesHistoryResponse struct {
+	Policies []*fleet.PolicyHistorySummary `json:"policies"`
+	Err      error                         `json:"error,omitempty"`
+}
+
+func (r listPoliciesHistoryResponse) error() error { return r.Err }
+
+func listPoliciesHistoryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listPoliciesHistoryRequest)
+	resp, err := svc.ListPoliciesHistory(ctx, policyHistoryOptions(req.Days, nil))
+	if err != nil {
+		return listPoliciesHistoryResponse{Err: err}, nil
+	}
+	return listPoliciesHistoryResponse{Policies: resp}, nil
+}
+
+func (svc Service) ListPoliciesHistory(ctx context.Context, opt fleet.PolicyHistoryOptions) ([]*fleet.PolicyHistorySummary, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
+
+	now := svc.clock.Now()
+	return svc.ds.ListPoliciesHistory(ctx, filter, policyHistorySince(now, opt), now)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Get history by id
+/////////////////////////////////////////////////////////////////////////////////
+
+type getPolicyHistoryRequest struct {
+	PolicyID uint  `url:"policy_id"`
+	Days     *uint `query:"days,optional"`
+	HostID   *uint `query:"host_id,optional"`
+}
+
+type getPolicyHistoryResponse struct {
+	History []*fleet.PolicyTransition `json:"history"`
+	Err     error                     `json:"error,omitempty"`
+}
+
+func (r getPolicyHistoryResponse) error() error { return r.Err }
+
+func getPolicyHistoryEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getPolicyHistoryRequest)
+	resp, err := svc.PolicyHistory(ctx, req.PolicyID, policyHistoryOptions(req.Days, req.HostID))
+	if err != nil {
+		return getPolicyHistoryResponse{Err: err}, nil
+	}
+	return getPolicyHistoryResponse{History: resp}, nil
+}
+
+func (svc Service) PolicyHistory(ctx context.Context, policyID uint, opt fleet.PolicyHistoryOptions) ([]*fleet.PolicyTransition, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
+
+	// Make sure the policy exists so that a missing policy is reported as
+	// such instead of as an empty history.
+	if _, err := svc.ds.Policy(ctx, policyID); err != nil {
+		return nil, err
+	}
+
+	now := svc.clock.Now()
+	return svc.ds.PolicyHistory(ctx, filter, policyID, opt, policyHistorySince(now, opt), now)
+}
+
+func policyHistoryOptions(days *uint, hostID *uint) fleet.PolicyHistoryOptions {
+	opt := fleet.PolicyHistoryOptions{HostID: hostID}
+	if days != nil {
+		opt.Days = *days
+	}
+	return opt
+}
+
+func policyHistorySince(now time.Time, opt fleet.PolicyHistoryOptions) time.Time {
+	days := opt.Days
+	if days == 0 {
+		days = fleet.DefaultPolicyHistoryDays
+	}
+	return now.Add(-time.Duration(days) * 24 * time.Hour)
+}
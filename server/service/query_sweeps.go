@@ -0,0 +1,437 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// minQuerySweepInterval is the shortest interval between two runs of a query
+// sweep, as the sweeps are checked every minute.
+const minQuerySweepInterval = 60
+
+/////////////////////////////////////////////////////////////////////////////////
+// Create
+/////////////////////////////////////////////////////////////////////////////////
+
+type createQuerySweepRequest struct {
+	fleet.QuerySweepPayload
+}
+
+type createQuerySweepResponse struct {
+	QuerySweep *fleet.QuerySweep `json:"query_sweep,omitempty"`
+	Err        error             `json:"error,omitempty"`
+}
+
+func (r createQuerySweepResponse) error() error { return r.Err }
+
+func createQuerySweepEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*createQuerySweepRequest)
+	sweep, err := svc.NewQuerySweep(ctx, req.QuerySweepPayload)
+	if err != nil {
+		return createQuerySweepResponse{Err: err}, nil
+	}
+	return createQuerySweepResponse{QuerySweep: sweep}, nil
+}
+
+func (svc Service) NewQuerySweep(ctx context.Context, p fleet.QuerySweepPayload) (*fleet.QuerySweep, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.QuerySweep{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	// The runs are diffed from their stored results.
+	if svc.config.Osquery.LiveQueryResultsRetention == 0 {
+		return nil, fleet.NewError(fleet.ErrNoLiveQueryResultsDisabled,
+			"Storing live query results is disabled, query sweeps cannot run.")
+	}
+
+	sweep := &fleet.QuerySweep{AuthorID: &vc.User.ID}
+	if err := applyQuerySweepPayload(sweep, p); err != nil {
+		return nil, err
+	}
+	return svc.ds.NewQuerySweep(ctx, sweep)
+}
+
+// applyQuerySweepPayload sets the fields of the payload on the sweep, and
+// validates the result.
+func applyQuerySweepPayload(sweep *fleet.QuerySweep, p fleet.QuerySweepPayload) error {
+	if p.Name != nil {
+		sweep.Name = *p.Name
+	}
+	if p.Description != nil {
+		sweep.Description = *p.Description
+	}
+	if p.Query != nil {
+		sweep.Query = *p.Query
+	}
+	if p.Targets != nil {
+		sweep.Targets = *p.Targets
+	}
+	if p.Interval != nil {
+		sweep.Interval = *p.Interval
+	}
+	if p.WebhookURL != nil {
+		sweep.WebhookURL = *p.WebhookURL
+	}
+
+	if sweep.Name == "" {
+		return fleet.NewInvalidArgumentError("name", "query sweep name must not be empty")
+	}
+	if sweep.Query == "" {
+		return fleet.NewInvalidArgumentError("query", "query sweep query must not be empty")
+	}
+	if len(sweep.Targets.HostIDs) == 0 && len(sweep.Targets.LabelIDs) == 0 && len(sweep.Targets.TeamIDs) == 0 {
+		return fleet.NewInvalidArgumentError("targets", "query sweep must target hosts, labels or teams")
+	}
+	if sweep.Interval < minQuerySweepInterval {
+		return fleet.NewInvalidArgumentError("interval", "must be at least 60 seconds")
+	}
+	if sweep.WebhookURL != "" {
+		if u, err := url.Parse(sweep.WebhookURL); err != nil || u.Scheme == "" || u.Host == "" {
+			return fleet.NewInvalidArgumentError("webhook_url", "must be a valid URL")
+		}
+	}
+	return nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// List
+/////////////////////////////////////////////////////////////////////////////////
+
+type listQuerySweepsRequest struct {
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+type listQuerySweepsResponse struct {
+	QuerySweeps []*fleet.QuerySweep `json:"query_sweeps"`
+	Err         error               `json:"error,omitempty"`
+}
+
+func (r listQuerySweepsResponse) error() error { return r.Err }
+
+func listQuerySweepsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listQuerySweepsRequest)
+	sweeps, err := svc.ListQuerySweeps(ctx, req.ListOptions)
+	if err != nil {
+		return listQuerySweepsResponse{Err: err}, nil
+	}
+	return listQuerySweepsResponse{QuerySweeps: sweeps}, nil
+}
+
+func (svc Service) ListQuerySweeps(ctx context.Context, opt fleet.ListOptions) ([]*fleet.QuerySweep, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.QuerySweep{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.ListQuerySweeps(ctx, opt)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Get by id
+/////////////////////////////////////////////////////////////////////////////////
+
+type getQuerySweepRequest struct {
+	ID uint `url:"id"`
+}
+
+type getQuerySweepResponse struct {
+	QuerySweep *fleet.QuerySweep `json:"query_sweep,omitempty"`
+	Err        error             `json:"error,omitempty"`
+}
+
+func (r getQuerySweepResponse) error() error { return r.Err }
+
+func getQuerySweepEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*getQuerySweepRequest)
+	sweep, err := svc.GetQuerySweep(ctx, req.ID)
+	if err != nil {
+		return getQuerySweepResponse{Err: err}, nil
+	}
+	return getQuerySweepResponse{QuerySweep: sweep}, nil
+}
+
+func (svc Service) GetQuerySweep(ctx context.Context, id uint) (*fleet.QuerySweep, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.QuerySweep{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+
+	return svc.ds.QuerySweep(ctx, id)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Modify
+/////////////////////////////////////////////////////////////////////////////////
+
+type modifyQuerySweepRequest struct {
+	ID uint `url:"id"`
+	fleet.QuerySweepPayload
+}
+
+type modifyQuerySweepResponse struct {
+	QuerySweep *fleet.QuerySweep `json:"query_sweep,omitempty"`
+	Err        error             `json:"error,omitempty"`
+}
+
+func (r modifyQuerySweepResponse) error() error { return r.Err }
+
+func modifyQuerySweepEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*modifyQuerySweepRequest)
+	sweep, err := svc.ModifyQuerySweep(ctx, req.ID, req.QuerySweepPayload)
+	if err != nil {
+		return modifyQuerySweepResponse{Err: err}, nil
+	}
+	return modifyQuerySweepResponse{QuerySweep: sweep}, nil
+}
+
+func (svc Service) ModifyQuerySweep(ctx context.Context, id uint, p fleet.QuerySweepPayload) (*fleet.QuerySweep, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.QuerySweep{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	sweep, err := svc.ds.QuerySweep(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyQuerySweepPayload(sweep, p); err != nil {
+		return nil, err
+	}
+	if err := svc.ds.SaveQuerySweep(ctx, sweep); err != nil {
+		return nil, err
+	}
+	return sweep, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Delete
+/////////////////////////////////////////////////////////////////////////////////
+
+type deleteQuerySweepRequest struct {
+	ID uint `url:"id"`
+}
+
+type deleteQuerySweepResponse struct {
+	Err error `json:"error,omitempty"`
+}
+
+func (r deleteQuerySweepResponse) error() error { return r.Err }
+
+func deleteQuerySweepEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*deleteQuerySweepRequest)
+	if err := svc.DeleteQuerySweep(ctx, req.ID); err != nil {
+		return deleteQuerySweepResponse{Err: err}, nil
+	}
+	return deleteQuerySweepResponse{}, nil
+}
+
+func (svc Service) DeleteQuerySweep(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.QuerySweep{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	return svc.ds.DeleteQuerySweep(ctx, id)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Run
+/////////////////////////////////////////////////////////////////////////////////
+
+func (svc Service) RunQuerySweeps(ctx context.Context, now time.Time) error {
+	// skipauth: Sweeps are run by the server, each as the user that created
+	// it, which is authorized when starting its campaign.
+	svc.authz.SkipAuthorization(ctx)
+
+	const perPage = 1000
+	for page := uint(0); ; page++ {
+		sweeps, err := svc.ds.ListQuerySweeps(ctx, fleet.ListOptions{Page: page, PerPage: perPage})
+		if err != nil {
+			return errors.Wrap(err, "list query sweeps")
+		}
+		for _, sweep := range sweeps {
+			if err := svc.runQuerySweep(ctx, sweep, now); err != nil {
+				level.Error(svc.logger).Log("msg", "run query sweep", "query_sweep_id", sweep.ID, "err", err)
+			}
+		}
+		if len(sweeps) < perPage {
+			return nil
+		}
+	}
+}
+
+// runQuerySweep reports the changes found by the last run of the sweep once
+// it completed, then starts a new run if it is due.
+func (svc Service) runQuerySweep(ctx context.Context, sweep *fleet.QuerySweep, now time.Time) error {
+	if sweep.AuthorID == nil {
+		return errors.New("the user that created the query sweep was deleted")
+	}
+	author, err := svc.ds.UserByID(ctx, *sweep.AuthorID)
+	if err != nil {
+		return errors.Wrap(err, "get query sweep author")
+	}
+
+	if sweep.LastCampaignID != nil && (sweep.DiffedCampaignID == nil || *sweep.DiffedCampaignID != *sweep.LastCampaignID) {
+		campaign, err := svc.ds.DistributedQueryCampaign(ctx, *sweep.LastCampaignID)
+		if err != nil {
+			return errors.Wrap(err, "get last query sweep campaign")
+		}
+		if campaign.Status != fleet.QueryComplete {
+			if now.Before(campaign.CreatedAt.Add(svc.config.Osquery.LiveQueryTimeout)) {
+				// Wait for the last run to complete before starting a new one.
+				return nil
+			}
+			// The campaign timed out but was not completed, e.g. because
+			// the server listening to its results restarted.
+			campaign.Status = fleet.QueryComplete
+			if err := svc.ds.SaveDistributedQueryCampaign(ctx, campaign); err != nil {
+				return errors.Wrap(err, "complete last query sweep campaign")
+			}
+			if err := svc.liveQueryStore.StopQuery(strconv.Itoa(int(campaign.ID))); err != nil {
+				return errors.Wrap(err, "stop last query sweep campaign query")
+			}
+		}
+		if err := svc.diffQuerySweepRun(ctx, sweep, author, campaign.ID); err != nil {
+			return err
+		}
+		sweep.DiffedCampaignID = &campaign.ID
+		if err := svc.ds.SaveQuerySweep(ctx, sweep); err != nil {
+			return errors.Wrap(err, "save query sweep")
+		}
+	}
+
+	if sweep.LastRunAt != nil && now.Before(sweep.LastRunAt.Add(time.Duration(sweep.Interval)*time.Second)) {
+		return nil
+	}
+
+	runCtx := viewer.NewContext(ctx, viewer.Viewer{User: author})
+	status, err := svc.RunLiveQuery(runCtx, sweep.Query, nil, sweep.Targets, 0)
+	if err != nil {
+		return errors.Wrap(err, "start query sweep campaign")
+	}
+	sweep.LastRunAt = &now
+	sweep.LastCampaignID = &status.Campaign.ID
+	if err := svc.ds.SaveQuerySweep(ctx, sweep); err != nil {
+		return errors.Wrap(err, "save query sweep")
+	}
+	return nil
+}
+
+// diffQuerySweepRun compares the results of the campaign with the results
+// the hosts returned in the previous runs of the sweep, and reports the
+// changes. The first results of a host are only recorded, and the hosts that
+// failed to run the query are ignored.
+func (svc Service) diffQuerySweepRun(ctx context.Context, sweep *fleet.QuerySweep, author *fleet.User, campaignID uint) error {
+	results, err := svc.allCampaignResults(ctx, campaignID)
+	if err != nil {
+		return err
+	}
+
+	current := make(map[uint][]map[string]string)
+	hostnames := make(map[uint]string)
+	hostIDs := make([]uint, 0, len(results))
+	for _, res := range results {
+		if res.Error != nil {
+			continue
+		}
+		current[res.HostID] = res.Rows
+		hostnames[res.HostID] = res.Hostname
+		hostIDs = append(hostIDs, res.HostID)
+	}
+	previous, err := svc.ds.QuerySweepHostResults(ctx, sweep.ID, hostIDs)
+	if err != nil {
+		return errors.Wrap(err, "get previous query sweep results")
+	}
+
+	changes := []*fleet.QuerySweepHostChanges{}
+	for _, hostID := range hostIDs {
+		prevRows, ok := previous[hostID]
+		if !ok {
+			continue
+		}
+		added, removed := diffRows(prevRows, current[hostID])
+		if len(added) == 0 && len(removed) == 0 {
+			continue
+		}
+		changes = append(changes, &fleet.QuerySweepHostChanges{
+			HostID:   hostID,
+			Hostname: hostnames[hostID],
+			Added:    added,
+			Removed:  removed,
+		})
+	}
+
+	if len(changes) > 0 {
+		// The results are only recorded once the changes are reported, so
+		// that they are reported again on failure.
+		if err := svc.reportQuerySweepChanges(ctx, sweep, author, campaignID, changes); err != nil {
+			return err
+		}
+	}
+	return svc.ds.SaveQuerySweepHostResults(ctx, sweep.ID, current)
+}
+
+func (svc Service) reportQuerySweepChanges(
+	ctx context.Context, sweep *fleet.QuerySweep, author *fleet.User, campaignID uint, changes []*fleet.QuerySweepHostChanges,
+) error {
+	if sweep.WebhookURL != "" {
+		payload := map[string]interface{}{
+			"query_sweep": sweep,
+			"campaign_id": campaignID,
+			"hosts":       changes,
+		}
+		return errors.Wrapf(
+			server.PostJSONWithTimeout(ctx, sweep.WebhookURL, &payload),
+			"posting to %s", sweep.WebhookURL,
+		)
+	}
+
+	return svc.ds.NewActivity(ctx, author, fleet.ActivityTypeQuerySweepChanges, &map[string]interface{}{
+		"query_sweep_id":   sweep.ID,
+		"query_sweep_name": sweep.Name,
+		"campaign_id":      campaignID,
+		"hosts":            changes,
+	})
+}
+
+// diffRows returns the rows of current that are not in previous, and the
+// rows of previous that are not in current. Identical rows are counted, so
+// that a duplicated row that went away is reported as removed.
+func diffRows(previous, current []map[string]string) (added, removed []map[string]string) {
+	counts := make(map[string]int)
+	for _, row := range previous {
+		counts[rowKey(row)]++
+	}
+	for _, row := range current {
+		key := rowKey(row)
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+		added = append(added, row)
+	}
+	for _, row := range previous {
+		key := rowKey(row)
+		if counts[key] > 0 {
+			counts[key]--
+			removed = append(removed, row)
+		}
+	}
+	return added, removed
+}
+
+// rowKey returns a key identifying the values of the row, as JSON encodes
+// the columns in sorted order.
+func rowKey(row map[string]string) string {
+	b, _ := json.Marshal(row)
+	return string(b)
+}
@@ -0,0 +1,254 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/live_query"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_NewQuerySweep(t *testing.T) {
+	ds := new(mock.Store)
+	ds.NewQuerySweepFunc = func(ctx context.Context, sweep *fleet.QuerySweep) (*fleet.QuerySweep, error) {
+		sweep.ID = 1
+		return sweep, nil
+	}
+
+	admin := &fleet.User{ID: 3, GlobalRole: ptr.String(fleet.RoleAdmin)}
+	observer := &fleet.User{ID: 4, GlobalRole: ptr.String(fleet.RoleObserver)}
+	payload := fleet.QuerySweepPayload{
+		Name:     ptr.String("processes"),
+		Query:    ptr.String("select name from processes"),
+		Targets:  &fleet.HostTargets{LabelIDs: []uint{1}},
+		Interval: ptr.Uint(3600),
+	}
+
+	// Results must be stored to be diffed.
+	svc := newTestService(ds, nil, nil)
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: admin})
+	_, err := svc.NewQuerySweep(ctx, payload)
+	require.Error(t, err)
+
+	cfg := config.TestConfig()
+	cfg.Osquery.LiveQueryResultsRetention = time.Hour
+	svc = newTestServiceWithConfig(ds, cfg, nil, nil)
+
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: observer})
+	_, err = svc.NewQuerySweep(ctx, payload)
+	require.Error(t, err)
+	assert.False(t, ds.NewQuerySweepFuncInvoked)
+
+	ctx = viewer.NewContext(context.Background(), viewer.Viewer{User: admin})
+	sweep, err := svc.NewQuerySweep(ctx, payload)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), sweep.ID)
+	assert.Equal(t, ptr.Uint(3), sweep.AuthorID)
+
+	invalid := []fleet.QuerySweepPayload{
+		{Query: payload.Query, Targets: payload.Targets, Interval: payload.Interval},
+		{Name: payload.Name, Targets: payload.Targets, Interval: payload.Interval},
+		{Name: payload.Name, Query: payload.Query, Interval: payload.Interval},
+		{Name: payload.Name, Query: payload.Query, Targets: payload.Targets, Interval: ptr.Uint(10)},
+		{Name: payload.Name, Query: payload.Query, Targets: payload.Targets, Interval: payload.Interval, WebhookURL: ptr.String("not a url")},
+	}
+	for _, p := range invalid {
+		_, err := svc.NewQuerySweep(ctx, p)
+		assert.Error(t, err)
+	}
+}
+
+func TestService_ModifyQuerySweep(t *testing.T) {
+	ds := new(mock.Store)
+	ds.QuerySweepFunc = func(ctx context.Context, id uint) (*fleet.QuerySweep, error) {
+		return &fleet.QuerySweep{
+			ID:       id,
+			Name:     "processes",
+			Query:    "select name from processes",
+			Targets:  fleet.HostTargets{HostIDs: []uint{1}},
+			Interval: 3600,
+		}, nil
+	}
+	var saved *fleet.QuerySweep
+	ds.SaveQuerySweepFunc = func(ctx context.Context, sweep *fleet.QuerySweep) error {
+		saved = sweep
+		return nil
+	}
+
+	svc := newTestService(ds, nil, nil)
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{ID: 3, GlobalRole: ptr.String(fleet.RoleMaintainer)}})
+
+	// Only the set fields are modified.
+	sweep, err := svc.ModifyQuerySweep(ctx, 1, fleet.QuerySweepPayload{Interval: ptr.Uint(600)})
+	require.NoError(t, err)
+	assert.Equal(t, uint(600), saved.Interval)
+	assert.Equal(t, "processes", sweep.Name)
+
+	saved = nil
+	_, err = svc.ModifyQuerySweep(ctx, 1, fleet.QuerySweepPayload{Query: ptr.String("")})
+	require.Error(t, err)
+	assert.Nil(t, saved)
+}
+
+func TestService_RunQuerySweeps(t *testing.T) {
+	ds := new(mock.Store)
+
+	now := time.Now()
+	sweeps := []*fleet.QuerySweep{
+		// The last run completed and was not diffed yet.
+		{ID: 1, Name: "processes", Interval: 3600, AuthorID: ptr.Uint(3), LastRunAt: &now, LastCampaignID: ptr.Uint(10), DiffedCampaignID: ptr.Uint(9)},
+		// The last run is still running.
+		{ID: 2, Name: "users", Interval: 3600, AuthorID: ptr.Uint(3), LastRunAt: &now, LastCampaignID: ptr.Uint(20), DiffedCampaignID: ptr.Uint(19)},
+	}
+	ds.ListQuerySweepsFunc = func(ctx context.Context, opt fleet.ListOptions) ([]*fleet.QuerySweep, error) {
+		if opt.Page > 0 {
+			return nil, nil
+		}
+		return sweeps, nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return &fleet.User{ID: id}, nil
+	}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		if id == 20 {
+			campaign := &fleet.DistributedQueryCampaign{ID: id, Status: fleet.QueryRunning}
+			campaign.CreatedAt = now
+			return campaign, nil
+		}
+		return &fleet.DistributedQueryCampaign{ID: id, Status: fleet.QueryComplete}, nil
+	}
+	ds.ListDistributedQueryCampaignResultsFunc = func(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignResult, error) {
+		require.Equal(t, uint(10), campaignID)
+		return []*fleet.DistributedQueryCampaignResult{
+			{HostID: 1, Hostname: "foo", Rows: []map[string]string{{"name": "osqueryd"}, {"name": "sshd"}}},
+			{HostID: 2, Hostname: "bar", Rows: []map[string]string{{"name": "osqueryd"}}},
+			{HostID: 3, Hostname: "baz", Rows: []map[string]string{{"name": "osqueryd"}}},
+			{HostID: 4, Hostname: "qux", Error: ptr.String("failed")},
+		}, nil
+	}
+	ds.QuerySweepHostResultsFunc = func(ctx context.Context, sweepID uint, hostIDs []uint) (map[uint][]map[string]string, error) {
+		assert.Equal(t, []uint{1, 2, 3}, hostIDs)
+		return map[uint][]map[string]string{
+			1: {{"name": "osqueryd"}, {"name": "cron"}},
+			2: {{"name": "osqueryd"}},
+		}, nil
+	}
+	var savedResults map[uint][]map[string]string
+	ds.SaveQuerySweepHostResultsFunc = func(ctx context.Context, sweepID uint, results map[uint][]map[string]string) error {
+		savedResults = results
+		return nil
+	}
+	var details map[string]interface{}
+	ds.NewActivityFunc = func(ctx context.Context, user *fleet.User, activityType string, d *map[string]interface{}) error {
+		assert.Equal(t, fleet.ActivityTypeQuerySweepChanges, activityType)
+		details = *d
+		return nil
+	}
+	ds.SaveQuerySweepFunc = func(ctx context.Context, sweep *fleet.QuerySweep) error {
+		return nil
+	}
+
+	svc := newTestService(ds, nil, nil)
+	require.NoError(t, svc.RunQuerySweeps(context.Background(), now.Add(time.Minute)))
+
+	// Only the changes of the hosts that had results before are reported.
+	assert.Equal(t, []*fleet.QuerySweepHostChanges{
+		{HostID: 1, Hostname: "foo", Added: []map[string]string{{"name": "sshd"}}, Removed: []map[string]string{{"name": "cron"}}},
+	}, details["hosts"])
+	assert.Equal(t, uint(10), details["campaign_id"])
+	assert.Len(t, savedResults, 3)
+	assert.Equal(t, ptr.Uint(10), sweeps[0].DiffedCampaignID)
+	assert.Equal(t, ptr.Uint(19), sweeps[1].DiffedCampaignID)
+
+	// The changes are posted to the webhook of the sweep if it has one.
+	var posted map[string]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&posted))
+	}))
+	defer srv.Close()
+
+	ds.NewActivityFuncInvoked = false
+	sweeps = []*fleet.QuerySweep{
+		{ID: 1, Name: "processes", Interval: 3600, AuthorID: ptr.Uint(3), LastRunAt: &now, LastCampaignID: ptr.Uint(10), WebhookURL: srv.URL},
+	}
+	require.NoError(t, svc.RunQuerySweeps(context.Background(), now.Add(time.Minute)))
+	assert.False(t, ds.NewActivityFuncInvoked)
+	assert.JSONEq(t, `[{"host_id": 1, "hostname": "foo", "added": [{"name": "sshd"}], "removed": [{"name": "cron"}]}]`, string(posted["hosts"]))
+	assert.Equal(t, ptr.Uint(10), sweeps[0].DiffedCampaignID)
+}
+
+func TestService_RunQuerySweepsTimedOutCampaign(t *testing.T) {
+	ds := new(mock.Store)
+	lq := new(live_query.MockLiveQuery)
+
+	now := time.Now()
+	sweep := &fleet.QuerySweep{ID: 1, Name: "processes", Interval: 3600, AuthorID: ptr.Uint(3), LastRunAt: &now, LastCampaignID: ptr.Uint(10)}
+	ds.ListQuerySweepsFunc = func(ctx context.Context, opt fleet.ListOptions) ([]*fleet.QuerySweep, error) {
+		if opt.Page > 0 {
+			return nil, nil
+		}
+		return []*fleet.QuerySweep{sweep}, nil
+	}
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		return &fleet.User{ID: id}, nil
+	}
+	// The campaign is still running, but was created longer than the live
+	// query timeout ago.
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		campaign := &fleet.DistributedQueryCampaign{ID: id, Status: fleet.QueryRunning}
+		campaign.CreatedAt = now.Add(-2 * time.Hour)
+		return campaign, nil
+	}
+	var savedStatus fleet.DistributedQueryStatus
+	ds.SaveDistributedQueryCampaignFunc = func(ctx context.Context, camp *fleet.DistributedQueryCampaign) error {
+		savedStatus = camp.Status
+		return nil
+	}
+	lq.On("StopQuery", "10").Return(nil)
+	ds.ListDistributedQueryCampaignResultsFunc = func(ctx context.Context, campaignID uint, opt fleet.ListOptions) ([]*fleet.DistributedQueryCampaignResult, error) {
+		return []*fleet.DistributedQueryCampaignResult{
+			{HostID: 1, Hostname: "foo", Rows: []map[string]string{{"name": "osqueryd"}}},
+		}, nil
+	}
+	ds.QuerySweepHostResultsFunc = func(ctx context.Context, sweepID uint, hostIDs []uint) (map[uint][]map[string]string, error) {
+		return map[uint][]map[string]string{}, nil
+	}
+	ds.SaveQuerySweepHostResultsFunc = func(ctx context.Context, sweepID uint, results map[uint][]map[string]string) error {
+		return nil
+	}
+	ds.SaveQuerySweepFunc = func(ctx context.Context, sweep *fleet.QuerySweep) error {
+		return nil
+	}
+
+	svc := newTestService(ds, nil, lq)
+	require.NoError(t, svc.RunQuerySweeps(context.Background(), now.Add(time.Minute)))
+
+	// The campaign is completed, and its results diffed.
+	assert.Equal(t, fleet.QueryComplete, savedStatus)
+	lq.AssertExpectations(t)
+	assert.True(t, ds.SaveQuerySweepHostResultsFuncInvoked)
+	assert.Equal(t, ptr.Uint(10), sweep.DiffedCampaignID)
+}
+
+func TestDiffRows(t *testing.T) {
+	previous := []map[string]string{{"a": "1"}, {"a": "1"}, {"a": "2"}}
+	current := []map[string]string{{"a": "1"}, {"a": "3"}, {"a": "2"}}
+
+	added, removed := diffRows(previous, current)
+	assert.Equal(t, []map[string]string{{"a": "3"}}, added)
+	assert.Equal(t, []map[string]string{{"a": "1"}}, removed)
+
+	added, removed = diffRows(previous, previous)
+	assert.Empty(t, added)
+	assert.Empty(t, removed)
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"time"
+
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/go-kit/kit/log/level"
+	"github.com/spf13/cast"
+)
+
+/////////////////////////////////////////////////////////////////////////////////
+// List scheduled query results
+/////////////////////////////////////////////////////////////////////////////////
+
+type listScheduledQueryResultsRequest struct {
+	ListOptions      fleet.ListOptions `url:"list_options"`
+	ScheduledQueryID *uint             `query:"scheduled_query_id,optional"`
+	HostID           *uint             `query:"host_id,optional"`
+}
+
+type listScheduledQueryResultsResponse struct {
+	Results []*fleet.ScheduledQueryResult `json:"results"`
+	Err     error                         `json:"error,omitempty"`
+}
+
+func (r listScheduledQueryResultsResponse) error() error { return r.Err }
+
+func listScheduledQueryResultsEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listScheduledQueryResultsRequest)
+	results, err := svc.ListScheduledQueryResults(ctx, fleet.ScheduledQueryResultsListOptions{
+		ListOptions:      req.ListOptions,
+		ScheduledQueryID: req.ScheduledQueryID,
+		HostID:           req.HostID,
+	})
+	if err != nil {
+		return listScheduledQueryResultsResponse{Err: err}, nil
+	}
+	return listScheduledQueryResultsResponse{Results: results}, nil
+}
+
+func (svc Service) ListScheduledQueryResults(ctx context.Context, opt fleet.ScheduledQueryResultsListOptions) ([]*fleet.ScheduledQueryResult, error) {
+	// The results are those of the hosts the user can see.
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
+
+	return svc.ds.ListScheduledQueryResults(ctx, filter, opt)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Store scheduled query results
+/////////////////////////////////////////////////////////////////////////////////
+
+// scheduledQueryResultLog is a result log as sent by osquery, in either the
+// snapshot, event or batch format. The lists of rows are decoded separately,
+// as osquery logs empty ones as "".
+type scheduledQueryResultLog struct {
+	Name     string                 `json:"name"`
+	UnixTime interface{}            `json:"unixTime"`
+	Action   string                 `json:"action"`
+	Columns  map[string]interface{} `json:"columns"`
+	Snapshot json.RawMessage        `json:"snapshot"`
+	Diff     *struct {
+		Added   json.RawMessage `json:"added"`
+		Removed json.RawMessage `json:"removed"`
+	} `json:"diffResults"`
+}
+
+// storeScheduledQueryResults stores the result logs sent by the host in the
+// context. The logs are already written to the result log plugin, so that
+// failing to store them is only logged.
+func (svc *Service) storeScheduledQueryResults(ctx context.Context, logs []json.RawMessage) {
+	host, ok := hostctx.FromContext(ctx)
+	if !ok {
+		return
+	}
+
+	results := make([]*fleet.ScheduledQueryResultLog, 0, len(logs))
+	for _, raw := range logs {
+		result, err := parseScheduledQueryResultLog(raw)
+		if err != nil {
+			level.Debug(svc.logger).Log("msg", "parse scheduled query result log", "host", host.Hostname, "err", err)
+			continue
+		}
+		if result != nil {
+			results = append(results, result)
+		}
+	}
+
+	if err := svc.ds.SaveScheduledQueryResults(ctx, host.ID, results, svc.config.Osquery.ResultStoreMaxRows); err != nil {
+		level.Error(svc.logger).Log("msg", "store scheduled query results", "host", host.Hostname, "err", err)
+	}
+}
+
+// parseScheduledQueryResultLog returns the rows of the result log, or nil if
+// it is not the result of a query scheduled in a pack.
+func parseScheduledQueryResultLog(raw json.RawMessage) (*fleet.ScheduledQueryResultLog, error) {
+	var log scheduledQueryResultLog
+	if err := json.Unmarshal(raw, &log); err != nil {
+		return nil, err
+	}
+
+	// Fleet configures osquery with "/" as the pack delimiter. Split with a
+	// limit of 2 in case the query name includes the delimiter.
+	if !strings.HasPrefix(log.Name, "pack/") {
+		return nil, nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(log.Name, "pack/"), "/", 2)
+	if len(parts) != 2 {
+		return nil, nil
+	}
+
+	result := &fleet.ScheduledQueryResultLog{
+		PackName:    parts[0],
+		QueryName:   parts[1],
+		CollectedAt: time.Unix(cast.ToInt64(log.UnixTime), 0).UTC(),
+	}
+	addRows := func(action string, raw json.RawMessage) error {
+		var rows []map[string]interface{}
+		if len(raw) > 0 && raw[0] == '[' {
+			if err := json.Unmarshal(raw, &rows); err != nil {
+				return err
+			}
+		}
+		for _, row := range rows {
+			result.Rows = append(result.Rows, fleet.ScheduledQueryResultRow{Action: action, Data: stringColumns(row)})
+		}
+		return nil
+	}
+	switch {
+	case log.Action == fleet.ScheduledQueryResultSnapshot:
+		result.Snapshot = true
+		if err := addRows(fleet.ScheduledQueryResultSnapshot, log.Snapshot); err != nil {
+			return nil, err
+		}
+	case log.Diff != nil:
+		if err := addRows(fleet.ScheduledQueryResultAdded, log.Diff.Added); err != nil {
+			return nil, err
+		}
+		if err := addRows(fleet.ScheduledQueryResultRemoved, log.Diff.Removed); err != nil {
+			return nil, err
+		}
+	case log.Action == fleet.ScheduledQueryResultAdded || log.Action == fleet.ScheduledQueryResultRemoved:
+		result.Rows = []fleet.ScheduledQueryResultRow{{Action: log.Action, Data: stringColumns(log.Columns)}}
+	default:
+		return nil, nil
+	}
+	return result, nil
+}
+
+// stringColumns returns the columns of a row as strings, as they are logged
+// as numbers when osquery is configured with log_numerics_as_numbers.
+func stringColumns(row map[string]interface{}) map[string]string {
+	columns := make(map[string]string, len(row))
+	for col, val := range row {
+		columns[col] = cast.ToString(val)
+	}
+	return columns
+}
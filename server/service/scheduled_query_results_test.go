@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	hostctx "github.com/fleetdm/fleet/v4/server/contexts/host"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/logging"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseScheduledQueryResultLog(t *testing.T) {
+	collectedAt := time.Unix(1511049728, 0).UTC()
+	testCases := []struct {
+		log      string
+		expected *fleet.ScheduledQueryResultLog
+	}{
+		{
+			log: `{"snapshot":[{"hour":"20","minutes":8}],"action":"snapshot","name":"pack/Global/time","unixTime":1511049728}`,
+			expected: &fleet.ScheduledQueryResultLog{
+				PackName: "Global", QueryName: "time", Snapshot: true, CollectedAt: collectedAt,
+				Rows: []fleet.ScheduledQueryResultRow{{Action: "snapshot", Data: map[string]string{"hour": "20", "minutes": "8"}}},
+			},
+		},
+		{
+			log: `{"diffResults":{"removed":[{"address":"127.0.0.1"}],"added":""},"name":"pack/team-1/hosts/all","unixTime":"1511049728"}`,
+			expected: &fleet.ScheduledQueryResultLog{
+				PackName: "team-1", QueryName: "hosts/all", CollectedAt: collectedAt,
+				Rows: []fleet.ScheduledQueryResultRow{{Action: "removed", Data: map[string]string{"address": "127.0.0.1"}}},
+			},
+		},
+		{
+			log: `{"name":"pack/test/users","columns":{"username":"root"},"action":"added","unixTime":1511049728}`,
+			expected: &fleet.ScheduledQueryResultLog{
+				PackName: "test", QueryName: "users", CollectedAt: collectedAt,
+				Rows: []fleet.ScheduledQueryResultRow{{Action: "added", Data: map[string]string{"username": "root"}}},
+			},
+		},
+		// Queries not run from a pack are ignored.
+		{log: `{"name":"time","columns":{"hour":"20"},"action":"added"}`},
+		{log: `{"unknown":{"foo": [] }}`},
+	}
+	for _, tt := range testCases {
+		t.Run(tt.log, func(t *testing.T) {
+			result, err := parseScheduledQueryResultLog(json.RawMessage(tt.log))
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestSubmitResultLogsStoresResults(t *testing.T) {
+	ds := new(mock.Store)
+	var savedLogs []*fleet.ScheduledQueryResultLog
+	ds.SaveScheduledQueryResultsFunc = func(ctx context.Context, hostID uint, logs []*fleet.ScheduledQueryResultLog, maxRows int) error {
+		assert.Equal(t, uint(42), hostID)
+		assert.Equal(t, 100, maxRows)
+		savedLogs = logs
+		return nil
+	}
+
+	logs := []json.RawMessage{
+		json.RawMessage(`{"snapshot":[{"hour":"20"}],"action":"snapshot","name":"pack/Global/time","unixTime":1511049728}`),
+		json.RawMessage(`{"name":"time","columns":{"hour":"20"},"action":"added"}`),
+	}
+	ctx := hostctx.NewContext(context.Background(), fleet.Host{ID: 42})
+
+	// The results are not stored by default.
+	svc := &Service{ds: ds, config: config.TestConfig(), osqueryLogWriter: &logging.OsqueryLogger{Result: &testJSONLogger{}}}
+	require.NoError(t, svc.SubmitResultLogs(ctx, logs))
+	assert.False(t, ds.SaveScheduledQueryResultsFuncInvoked)
+
+	svc.config.Osquery.ResultStoreMaxRows = 100
+	require.NoError(t, svc.SubmitResultLogs(ctx, logs))
+	require.Len(t, savedLogs, 1)
+	assert.Equal(t, "time", savedLogs[0].QueryName)
+}
+
+func TestListScheduledQueryResults(t *testing.T) {
+	ds := new(mock.Store)
+	ds.ListScheduledQueryResultsFunc = func(ctx context.Context, filter fleet.TeamFilter, opt fleet.ScheduledQueryResultsListOptions) ([]*fleet.ScheduledQueryResult, error) {
+		assert.True(t, filter.IncludeObserver)
+		assert.Equal(t, ptr.Uint(3), opt.HostID)
+		return []*fleet.ScheduledQueryResult{{ID: 1, HostID: 3}}, nil
+	}
+
+	svc := newTestService(ds, nil, nil)
+
+	// The results are filtered by the teams of the user.
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: &fleet.User{ID: 1, GlobalRole: ptr.String(fleet.RoleObserver)}})
+	results, err := svc.ListScheduledQueryResults(ctx, fleet.ScheduledQueryResultsListOptions{HostID: ptr.Uint(3)})
+	require.NoError(t, err)
+	assert.Len(t, results, 1)
+}
@@ -0,0 +1,235 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	"github.com/pkg/errors"
+)
+
+// scimDefaultRole returns the global role of the provisioned users that are
+// not members of a SCIM group with a role.
+func scimDefaultRole(conf config.SCIMConfig) string {
+	if conf.DefaultRole == "" {
+		return fleet.RoleObserver
+	}
+	return conf.DefaultRole
+}
+
+// parseSCIMGroupRoles validates the SCIM configuration and returns its group
+// roles.
+func parseSCIMGroupRoles(conf config.SCIMConfig) (fleet.GroupRoles, error) {
+	if !fleet.ValidGlobalRole(scimDefaultRole(conf)) {
+		return nil, errors.Errorf("invalid default role %q", conf.DefaultRole)
+	}
+	return fleet.ParseGroupRoles(conf.GroupRoles)
+}
+
+func (svc *Service) CreateSCIMUser(ctx context.Context, payload fleet.SCIMUserPayload) (*fleet.User, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.User{}, fleet.ActionWriteRole); err != nil {
+		return nil, err
+	}
+
+	payload, err := validateSCIMUserPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	// The user is not a member of any group yet.
+	user, err := svc.payloadUser(fleet.UserPayload{
+		Name:       &payload.Name,
+		Email:      &payload.Email,
+		SSOEnabled: ptr.Bool(true),
+		GlobalRole: ptr.String(scimDefaultRole(svc.config.SCIM)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	user.SCIMManaged = true
+	return svc.ds.NewUser(ctx, user)
+}
+
+func (svc *Service) GetSCIMUser(ctx context.Context, id uint) (*fleet.User, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.User{ID: id}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.SCIMUserByID(ctx, id)
+}
+
+func (svc *Service) ListSCIMUsers(ctx context.Context, opt fleet.SCIMUserListOptions) ([]*fleet.User, int, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.User{}, fleet.ActionRead); err != nil {
+		return nil, 0, err
+	}
+	return svc.ds.ListSCIMUsers(ctx, opt)
+}
+
+func validateSCIMUserPayload(payload fleet.SCIMUserPayload) (fleet.SCIMUserPayload, error) {
+	payload.Email = strings.TrimSpace(payload.Email)
+	payload.Name = strings.TrimSpace(payload.Name)
+	if payload.Email == "" {
+		return payload, fleet.NewInvalidArgumentError("userName", "cannot be empty")
+	}
+	if payload.Name == "" {
+		payload.Name = payload.Email
+	}
+	return payload, nil
+}
+
+func (svc *Service) SaveSCIMUser(ctx context.Context, id uint, payload fleet.SCIMUserPayload) (*fleet.User, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.User{ID: id}, fleet.ActionWriteRole); err != nil {
+		return nil, err
+	}
+
+	payload, err := validateSCIMUserPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	user, err := svc.ds.SCIMUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	// The email address is managed by the identity provider, so that it is
+	// changed without confirmation.
+	user.Email = payload.Email
+	user.Name = payload.Name
+	if err := svc.saveUser(ctx, user); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+func (svc *Service) DeprovisionSCIMUser(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.User{ID: id}, fleet.ActionWriteRole); err != nil {
+		return err
+	}
+
+	if _, err := svc.ds.SCIMUserByID(ctx, id); err != nil {
+		return err
+	}
+	if err := svc.ds.DestroyAllSessionsForUser(ctx, id); err != nil {
+		return errors.Wrap(err, "destroy sessions of deprovisioned user")
+	}
+	return svc.ds.DeleteUser(ctx, id)
+}
+
+func (svc *Service) ListSCIMGroups(ctx context.Context) ([]*fleet.SCIMGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.SCIMGroup{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.ListSCIMGroups(ctx)
+}
+
+func (svc *Service) GetSCIMGroup(ctx context.Context, id uint) (*fleet.SCIMGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.SCIMGroup{}, fleet.ActionRead); err != nil {
+		return nil, err
+	}
+	return svc.ds.SCIMGroup(ctx, id)
+}
+
+func (svc *Service) CreateSCIMGroup(ctx context.Context, group *fleet.SCIMGroup) (*fleet.SCIMGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.SCIMGroup{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	group.DisplayName = strings.TrimSpace(group.DisplayName)
+	if group.DisplayName == "" {
+		return nil, fleet.NewInvalidArgumentError("displayName", "cannot be empty")
+	}
+	group, err := svc.ds.NewSCIMGroup(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.syncSCIMRoles(ctx, group.UserIDs); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (svc *Service) SaveSCIMGroup(ctx context.Context, group *fleet.SCIMGroup) (*fleet.SCIMGroup, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.SCIMGroup{}, fleet.ActionWrite); err != nil {
+		return nil, err
+	}
+
+	group.DisplayName = strings.TrimSpace(group.DisplayName)
+	if group.DisplayName == "" {
+		return nil, fleet.NewInvalidArgumentError("displayName", "cannot be empty")
+	}
+	previous, err := svc.ds.SCIMGroup(ctx, group.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := svc.ds.SaveSCIMGroup(ctx, group); err != nil {
+		return nil, err
+	}
+	// The former members may have lost the role of the group, and all the
+	// members change role if the group is renamed.
+	if err := svc.syncSCIMRoles(ctx, append(previous.UserIDs, group.UserIDs...)); err != nil {
+		return nil, err
+	}
+	return svc.ds.SCIMGroup(ctx, group.ID)
+}
+
+func (svc *Service) DeleteSCIMGroup(ctx context.Context, id uint) error {
+	if err := svc.authz.Authorize(ctx, &fleet.SCIMGroup{}, fleet.ActionWrite); err != nil {
+		return err
+	}
+
+	group, err := svc.ds.SCIMGroup(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := svc.ds.DeleteSCIMGroup(ctx, id); err != nil {
+		return err
+	}
+	return svc.syncSCIMRoles(ctx, group.UserIDs)
+}
+
+// syncSCIMRoles sets the roles of the users to the roles of the SCIM groups
+// they are members of, or to the default SCIM role if none of their groups
+// has a role. The users not provisioned through the SCIM API are skipped.
+func (svc *Service) syncSCIMRoles(ctx context.Context, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	groupRoles, err := parseSCIMGroupRoles(svc.config.SCIM)
+	if err != nil {
+		return errors.Wrap(err, "parse scim config")
+	}
+	groups, err := svc.ds.ListSCIMGroups(ctx)
+	if err != nil {
+		return err
+	}
+	memberOf := make(map[uint][]string)
+	for _, group := range groups {
+		for _, userID := range group.UserIDs {
+			memberOf[userID] = append(memberOf[userID], group.DisplayName)
+		}
+	}
+
+	var users []*fleet.User
+	synced := make(map[uint]bool)
+	for _, userID := range userIDs {
+		if synced[userID] {
+			continue
+		}
+		synced[userID] = true
+
+		user, err := svc.ds.SCIMUserByID(ctx, userID)
+		if err != nil {
+			if fleet.IsNotFound(err) {
+				// The user was deleted meanwhile, or is not managed by SCIM.
+				continue
+			}
+			return err
+		}
+		globalRole, teams, ok := groupRoles.Roles(memberOf[userID])
+		if !ok {
+			globalRole, teams = ptr.String(scimDefaultRole(svc.config.SCIM)), []fleet.UserTeam{}
+		}
+		user.GlobalRole, user.Teams = globalRole, teams
+		users = append(users, user)
+	}
+	return svc.ds.SaveUsers(ctx, users)
+}
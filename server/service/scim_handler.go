@@ -0,0 +1,783 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/contexts/token"
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+)
+
+// The SCIM 2.0 API (RFC 7643 and RFC 7644) lets identity providers provision
+// the users of Fleet, and the groups that give them their roles.
+const (
+	scimPathPrefix = "/api/v1/fleet/scim/v2"
+	scimMediaType  = "application/scim+json"
+
+	scimUserSchema         = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema        = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimListResponseSchema = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimErrorSchema        = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	// scimMaxResults is the maximum number of users listed at once.
+	scimMaxResults = 1000
+)
+
+type scimMeta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location"`
+}
+
+type scimName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type scimEmail struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type scimReference struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+}
+
+type scimUser struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	UserName    string          `json:"userName"`
+	Name        *scimName       `json:"name,omitempty"`
+	DisplayName string          `json:"displayName,omitempty"`
+	Emails      []scimEmail     `json:"emails,omitempty"`
+	Active      *bool           `json:"active,omitempty"`
+	Groups      []scimReference `json:"groups,omitempty"`
+	Meta        *scimMeta       `json:"meta,omitempty"`
+}
+
+// payload returns the Fleet attributes of the user. The name of the user is
+// its display name, or else its formatted or given and family names.
+func (u scimUser) payload() fleet.SCIMUserPayload {
+	payload := fleet.SCIMUserPayload{Email: u.UserName, Name: u.DisplayName}
+	if payload.Name == "" && u.Name != nil {
+		payload.Name = u.Name.Formatted
+		if payload.Name == "" {
+			payload.Name = strings.TrimSpace(u.Name.GivenName + " " + u.Name.FamilyName)
+		}
+	}
+	return payload
+}
+
+type scimGroup struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []scimReference `json:"members"`
+	Meta        *scimMeta       `json:"meta,omitempty"`
+}
+
+type scimListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+type scimPatchRequest struct {
+	Operations []scimPatchOperation `json:"Operations"`
+}
+
+type scimPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type scimErrorResponse struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+// scimRequestError is the error returned for the invalid SCIM requests, with
+// the HTTP status of the response. scimType is the SCIM error type of RFC
+// 7644 section 3.12.
+type scimRequestError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e scimRequestError) Error() string {
+	return e.detail
+}
+
+func newSCIMBadRequestError(scimType, format string, args ...interface{}) error {
+	return scimRequestError{status: http.StatusBadRequest, scimType: scimType, detail: fmt.Sprintf(format, args...)}
+}
+
+type scimHandler struct {
+	svc    fleet.Service
+	logger kitlog.Logger
+}
+
+// attachSCIMRoutes registers the routes of the SCIM API. The identity
+// provider authenticates with the API token of a global admin.
+func attachSCIMRoutes(r *mux.Router, svc fleet.Service, logger kitlog.Logger) {
+	h := &scimHandler{svc: svc, logger: logger}
+	r.Handle(scimPathPrefix+"/Users", h.handle(h.listUsers)).Methods("GET").Name("scim_list_users")
+	r.Handle(scimPathPrefix+"/Users", h.handle(h.createUser)).Methods("POST").Name("scim_create_user")
+	r.Handle(scimPathPrefix+"/Users/{id}", h.handle(h.getUser)).Methods("GET").Name("scim_get_user")
+	r.Handle(scimPathPrefix+"/Users/{id}", h.handle(h.replaceUser)).Methods("PUT").Name("scim_replace_user")
+	r.Handle(scimPathPrefix+"/Users/{id}", h.handle(h.patchUser)).Methods("PATCH").Name("scim_patch_user")
+	r.Handle(scimPathPrefix+"/Users/{id}", h.handle(h.deleteUser)).Methods("DELETE").Name("scim_delete_user")
+	r.Handle(scimPathPrefix+"/Groups", h.handle(h.listGroups)).Methods("GET").Name("scim_list_groups")
+	r.Handle(scimPathPrefix+"/Groups", h.handle(h.createGroup)).Methods("POST").Name("scim_create_group")
+	r.Handle(scimPathPrefix+"/Groups/{id}", h.handle(h.getGroup)).Methods("GET").Name("scim_get_group")
+	r.Handle(scimPathPrefix+"/Groups/{id}", h.handle(h.replaceGroup)).Methods("PUT").Name("scim_replace_group")
+	r.Handle(scimPathPrefix+"/Groups/{id}", h.handle(h.patchGroup)).Methods("PATCH").Name("scim_patch_group")
+	r.Handle(scimPathPrefix+"/Groups/{id}", h.handle(h.deleteGroup)).Methods("DELETE").Name("scim_delete_group")
+}
+
+// scimHandlerFunc handles an authenticated SCIM request, and returns the
+// status and resource of the response.
+type scimHandlerFunc func(ctx context.Context, r *http.Request) (int, interface{}, error)
+
+func (h *scimHandler) handle(fn scimHandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		status, resource, err := h.authenticate(ctx, r, fn)
+		if err != nil {
+			h.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", scimMediaType)
+		if created, ok := resource.(interface{ location() string }); ok && status == http.StatusCreated {
+			w.Header().Set("Location", created.location())
+		}
+		w.WriteHeader(status)
+		if resource != nil {
+			if err := json.NewEncoder(w).Encode(resource); err != nil {
+				level.Info(h.logger).Log("msg", "encode scim response", "err", err)
+			}
+		}
+	})
+}
+
+func (h *scimHandler) authenticate(ctx context.Context, r *http.Request, fn scimHandlerFunc) (int, interface{}, error) {
+	key := token.FromHTTPRequest(r)
+	if key == "" {
+		return 0, nil, fleet.NewAuthHeaderRequiredError("no auth token")
+	}
+	v, err := authViewer(ctx, string(key), h.svc)
+	if err != nil {
+		return 0, nil, err
+	}
+	if !v.CanPerformActions() {
+		return 0, nil, fleet.ErrPasswordResetRequired
+	}
+	return fn(viewer.NewContext(ctx, *v), r)
+}
+
+func (h *scimHandler) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	resp := scimErrorResponse{Schemas: []string{scimErrorSchema}, Detail: err.Error()}
+	status := http.StatusInternalServerError
+
+	cause := errors.Cause(err)
+	switch e := cause.(type) {
+	case scimRequestError:
+		status, resp.SCIMType = e.status, e.scimType
+	case validationErrorInterface:
+		status, resp.SCIMType = http.StatusBadRequest, "invalidValue"
+		if invalid := e.Invalid(); len(invalid) > 0 {
+			resp.Detail = invalid[0]["name"] + " " + invalid[0]["reason"]
+		}
+	case *fleet.Error:
+		status, resp.SCIMType = http.StatusBadRequest, "invalidValue"
+	case notFoundErrorInterface:
+		status = http.StatusNotFound
+	case existsErrorInterface:
+		status, resp.SCIMType = http.StatusConflict, "uniqueness"
+	case *mysql.MySQLError:
+		if e.Number == 1062 {
+			status, resp.SCIMType = http.StatusConflict, "uniqueness"
+			resp.Detail = "resource already exists"
+		}
+	case interface{ StatusCode() int }:
+		status = e.StatusCode()
+	}
+	if status == http.StatusInternalServerError {
+		level.Error(h.logger).Log("msg", "scim request", "method", r.Method, "path", r.URL.Path, "err", err)
+		resp.Detail = "internal server error"
+	}
+	resp.Status = strconv.Itoa(status)
+
+	w.Header().Set("Content-Type", scimMediaType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
+
+func decodeSCIMRequest(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		return newSCIMBadRequestError("invalidSyntax", "invalid request body: %s", err)
+	}
+	return nil
+}
+
+func scimID(r *http.Request) (uint, error) {
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 0)
+	if err != nil {
+		return 0, scimRequestError{status: http.StatusNotFound, detail: "resource not found"}
+	}
+	return uint(id), nil
+}
+
+var scimFilterRegexp = regexp.MustCompile(`^\s*(\S+)\s+(?i:eq)\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// parseSCIMFilter parses the filter of a list request. Only the equality
+// filter on the attribute is supported, which is what the identity providers
+// use to find existing resources.
+func parseSCIMFilter(filter, attribute string) (value string, ok bool, err error) {
+	if strings.TrimSpace(filter) == "" {
+		return "", false, nil
+	}
+	matches := scimFilterRegexp.FindStringSubmatch(filter)
+	if matches == nil || !strings.EqualFold(matches[1], attribute) {
+		return "", false, newSCIMBadRequestError("invalidFilter", "unsupported filter %q, only %s eq is supported", filter, attribute)
+	}
+	if err := json.Unmarshal([]byte(matches[2]), &value); err != nil {
+		return "", false, newSCIMBadRequestError("invalidFilter", "invalid filter value %s", matches[2])
+	}
+	return value, true, nil
+}
+
+// scimPageParams returns the startIndex (starting at 1) and count parameters
+// of a list request. count is -1 if it is not set.
+func scimPageParams(r *http.Request) (startIndex, count int, err error) {
+	startIndex, count = 1, -1
+	query := r.URL.Query()
+	if s := query.Get("startIndex"); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, 0, newSCIMBadRequestError("invalidValue", "invalid startIndex %q", s)
+		}
+		if i > 1 {
+			startIndex = i
+		}
+	}
+	if s := query.Get("count"); s != "" {
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, 0, newSCIMBadRequestError("invalidValue", "invalid count %q", s)
+		}
+		if i < 0 {
+			i = 0
+		}
+		count = i
+	}
+	return startIndex, count, nil
+}
+
+// scimPage returns the page of the resources requested by the startIndex
+// (starting at 1) and count parameters.
+func scimPage(r *http.Request, resources []interface{}) (*scimListResponse, error) {
+	startIndex, count, err := scimPageParams(r)
+	if err != nil {
+		return nil, err
+	}
+	if count < 0 {
+		count = len(resources)
+	}
+
+	page := []interface{}{}
+	if startIndex <= len(resources) {
+		page = resources[startIndex-1:]
+		if count < len(page) {
+			page = page[:count]
+		}
+	}
+	return &scimListResponse{
+		Schemas:      []string{scimListResponseSchema},
+		TotalResults: len(resources),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(page),
+		Resources:    page,
+	}, nil
+}
+
+func (u *scimUser) location() string {
+	return u.Meta.Location
+}
+
+func (g *scimGroup) location() string {
+	return g.Meta.Location
+}
+
+// newSCIMUser returns the SCIM resource of the user, as a member of the
+// groups.
+func newSCIMUser(user *fleet.User, groups []*fleet.SCIMGroup) *scimUser {
+	id := strconv.FormatUint(uint64(user.ID), 10)
+	resource := &scimUser{
+		Schemas:     []string{scimUserSchema},
+		ID:          id,
+		UserName:    user.Email,
+		Name:        &scimName{Formatted: user.Name},
+		DisplayName: user.Name,
+		Emails:      []scimEmail{{Value: user.Email, Type: "work", Primary: true}},
+		// Deprovisioned users are deleted, so that all the users are active.
+		Active: ptr.Bool(true),
+		Groups: []scimReference{},
+		Meta: &scimMeta{
+			ResourceType: "User",
+			Created:      &user.CreatedAt,
+			LastModified: &user.UpdatedAt,
+			Location:     scimPathPrefix + "/Users/" + id,
+		},
+	}
+	for _, group := range groups {
+		for _, userID := range group.UserIDs {
+			if userID == user.ID {
+				resource.Groups = append(resource.Groups, scimReference{
+					Value:   strconv.FormatUint(uint64(group.ID), 10),
+					Display: group.DisplayName,
+				})
+			}
+		}
+	}
+	return resource
+}
+
+func newSCIMGroup(group *fleet.SCIMGroup) *scimGroup {
+	id := strconv.FormatUint(uint64(group.ID), 10)
+	resource := &scimGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          id,
+		DisplayName: group.DisplayName,
+		Members:     []scimReference{},
+		Meta: &scimMeta{
+			ResourceType: "Group",
+			Created:      &group.CreatedAt,
+			LastModified: &group.UpdatedAt,
+			Location:     scimPathPrefix + "/Groups/" + id,
+		},
+	}
+	for _, userID := range group.UserIDs {
+		resource.Members = append(resource.Members, scimReference{Value: strconv.FormatUint(uint64(userID), 10)})
+	}
+	return resource
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Users
+/////////////////////////////////////////////////////////////////////////////////
+
+func (h *scimHandler) listUsers(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	userName, filtered, err := parseSCIMFilter(r.URL.Query().Get("filter"), "userName")
+	if err != nil {
+		return 0, nil, err
+	}
+	startIndex, count, err := scimPageParams(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if count < 0 || count > scimMaxResults {
+		count = scimMaxResults
+	}
+	groups, err := h.svc.ListSCIMGroups(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	opt := fleet.SCIMUserListOptions{Offset: uint(startIndex - 1), Limit: uint(count)}
+	if filtered {
+		opt.UserName = userName
+	}
+	users, total, err := h.svc.ListSCIMUsers(ctx, opt)
+	if err != nil {
+		return 0, nil, err
+	}
+	resources := make([]interface{}, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, newSCIMUser(user, groups))
+	}
+	return http.StatusOK, &scimListResponse{
+		Schemas:      []string{scimListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+func (h *scimHandler) getUser(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	id, err := scimID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	return h.userResponse(ctx, http.StatusOK, id)
+}
+
+func (h *scimHandler) userResponse(ctx context.Context, status int, id uint) (int, interface{}, error) {
+	user, err := h.svc.GetSCIMUser(ctx, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	groups, err := h.svc.ListSCIMGroups(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+	return status, newSCIMUser(user, groups), nil
+}
+
+func (h *scimHandler) createUser(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	var resource scimUser
+	if err := decodeSCIMRequest(r, &resource); err != nil {
+		return 0, nil, err
+	}
+	if resource.Active != nil && !*resource.Active {
+		return 0, nil, newSCIMBadRequestError("invalidValue", "inactive users cannot be provisioned")
+	}
+	user, err := h.svc.CreateSCIMUser(ctx, resource.payload())
+	if err != nil {
+		return 0, nil, err
+	}
+	return h.userResponse(ctx, http.StatusCreated, user.ID)
+}
+
+func (h *scimHandler) replaceUser(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	id, err := scimID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	var resource scimUser
+	if err := decodeSCIMRequest(r, &resource); err != nil {
+		return 0, nil, err
+	}
+	return h.saveUser(ctx, id, resource.payload(), resource.Active == nil || *resource.Active)
+}
+
+// saveUser saves the attributes of the user, or deprovisions it if it is not
+// active anymore.
+func (h *scimHandler) saveUser(ctx context.Context, id uint, payload fleet.SCIMUserPayload, active bool) (int, interface{}, error) {
+	if !active {
+		user, err := h.svc.GetSCIMUser(ctx, id)
+		if err != nil {
+			return 0, nil, err
+		}
+		if err := h.svc.DeprovisionSCIMUser(ctx, id); err != nil {
+			return 0, nil, err
+		}
+		resource := newSCIMUser(user, nil)
+		resource.Active = &active
+		return http.StatusOK, resource, nil
+	}
+
+	if _, err := h.svc.SaveSCIMUser(ctx, id, payload); err != nil {
+		return 0, nil, err
+	}
+	return h.userResponse(ctx, http.StatusOK, id)
+}
+
+func (h *scimHandler) patchUser(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	id, err := scimID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	var req scimPatchRequest
+	if err := decodeSCIMRequest(r, &req); err != nil {
+		return 0, nil, err
+	}
+	user, err := h.svc.GetSCIMUser(ctx, id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	payload, active := fleet.SCIMUserPayload{Email: user.Email, Name: user.Name}, true
+	for _, op := range req.Operations {
+		if !strings.EqualFold(op.Op, "replace") && !strings.EqualFold(op.Op, "add") {
+			return 0, nil, newSCIMBadRequestError("invalidValue", "unsupported operation %q on users", op.Op)
+		}
+		values := map[string]json.RawMessage{op.Path: op.Value}
+		if op.Path == "" {
+			values = nil
+			if err := json.Unmarshal(op.Value, &values); err != nil {
+				return 0, nil, newSCIMBadRequestError("invalidValue", "invalid value of operation without path")
+			}
+		}
+		for path, value := range values {
+			if err := patchSCIMUserAttribute(&payload, &active, path, value); err != nil {
+				return 0, nil, err
+			}
+		}
+	}
+	return h.saveUser(ctx, id, payload, active)
+}
+
+// patchSCIMUserAttribute sets the attribute of the user at the path. The
+// attributes that Fleet does not store are ignored.
+func patchSCIMUserAttribute(payload *fleet.SCIMUserPayload, active *bool, path string, value json.RawMessage) error {
+	var err error
+	switch strings.ToLower(path) {
+	case "username":
+		err = json.Unmarshal(value, &payload.Email)
+	case "displayname", "name.formatted":
+		err = json.Unmarshal(value, &payload.Name)
+	case "active":
+		// Some identity providers send the boolean as a string.
+		var s string
+		if json.Unmarshal(value, &s) == nil {
+			*active, err = strconv.ParseBool(s)
+		} else {
+			err = json.Unmarshal(value, active)
+		}
+	}
+	if err != nil {
+		return newSCIMBadRequestError("invalidValue", "invalid value of %s", path)
+	}
+	return nil
+}
+
+func (h *scimHandler) deleteUser(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	id, err := scimID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := h.svc.DeprovisionSCIMUser(ctx, id); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// Groups
+/////////////////////////////////////////////////////////////////////////////////
+
+func (h *scimHandler) listGroups(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	displayName, filtered, err := parseSCIMFilter(r.URL.Query().Get("filter"), "displayName")
+	if err != nil {
+		return 0, nil, err
+	}
+	groups, err := h.svc.ListSCIMGroups(ctx)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var resources []interface{}
+	for _, group := range groups {
+		if !filtered || group.DisplayName == displayName {
+			resources = append(resources, newSCIMGroup(group))
+		}
+	}
+	page, err := scimPage(r, resources)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, page, nil
+}
+
+func (h *scimHandler) getGroup(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	id, err := scimID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	group, err := h.svc.GetSCIMGroup(ctx, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, newSCIMGroup(group), nil
+}
+
+func (h *scimHandler) createGroup(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	var resource scimGroup
+	if err := decodeSCIMRequest(r, &resource); err != nil {
+		return 0, nil, err
+	}
+	userIDs, err := scimMemberIDs(resource.Members)
+	if err != nil {
+		return 0, nil, err
+	}
+	group, err := h.svc.CreateSCIMGroup(ctx, &fleet.SCIMGroup{DisplayName: resource.DisplayName, UserIDs: userIDs})
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusCreated, newSCIMGroup(group), nil
+}
+
+func (h *scimHandler) replaceGroup(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	id, err := scimID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	var resource scimGroup
+	if err := decodeSCIMRequest(r, &resource); err != nil {
+		return 0, nil, err
+	}
+	userIDs, err := scimMemberIDs(resource.Members)
+	if err != nil {
+		return 0, nil, err
+	}
+	group, err := h.svc.SaveSCIMGroup(ctx, &fleet.SCIMGroup{ID: id, DisplayName: resource.DisplayName, UserIDs: userIDs})
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, newSCIMGroup(group), nil
+}
+
+func (h *scimHandler) patchGroup(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	id, err := scimID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	var req scimPatchRequest
+	if err := decodeSCIMRequest(r, &req); err != nil {
+		return 0, nil, err
+	}
+	group, err := h.svc.GetSCIMGroup(ctx, id)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for _, op := range req.Operations {
+		if err := patchSCIMGroup(group, op); err != nil {
+			return 0, nil, err
+		}
+	}
+	group, err = h.svc.SaveSCIMGroup(ctx, group)
+	if err != nil {
+		return 0, nil, err
+	}
+	return http.StatusOK, newSCIMGroup(group), nil
+}
+
+var scimMemberPathRegexp = regexp.MustCompile(`^(?i:members)\[\s*(?i:value)\s+(?i:eq)\s+"([^"]*)"\s*\]$`)
+
+// patchSCIMGroup applies the operation to the display name or the members
+// of the group.
+func patchSCIMGroup(group *fleet.SCIMGroup, op scimPatchOperation) error {
+	path := strings.TrimSpace(op.Path)
+	switch {
+	case strings.EqualFold(op.Op, "remove") && scimMemberPathRegexp.MatchString(path):
+		userIDs, err := scimMemberIDs([]scimReference{{Value: scimMemberPathRegexp.FindStringSubmatch(path)[1]}})
+		if err != nil {
+			return err
+		}
+		group.UserIDs = removeSCIMMembers(group.UserIDs, userIDs)
+		return nil
+
+	case path == "":
+		if strings.EqualFold(op.Op, "remove") {
+			return newSCIMBadRequestError("noTarget", "remove operations require a path")
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(op.Value, &values); err != nil {
+			return newSCIMBadRequestError("invalidValue", "invalid value of operation without path")
+		}
+		for attribute, value := range values {
+			if err := patchSCIMGroup(group, scimPatchOperation{Op: op.Op, Path: attribute, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case strings.EqualFold(path, "displayName"):
+		if !strings.EqualFold(op.Op, "replace") && !strings.EqualFold(op.Op, "add") {
+			return newSCIMBadRequestError("mutability", "displayName cannot be removed")
+		}
+		if err := json.Unmarshal(op.Value, &group.DisplayName); err != nil {
+			return newSCIMBadRequestError("invalidValue", "invalid value of displayName")
+		}
+		return nil
+
+	case strings.EqualFold(path, "members"):
+		var members []scimReference
+		if len(op.Value) > 0 {
+			if err := json.Unmarshal(op.Value, &members); err != nil {
+				return newSCIMBadRequestError("invalidValue", "invalid value of members")
+			}
+		}
+		userIDs, err := scimMemberIDs(members)
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(op.Op) {
+		case "add":
+			group.UserIDs = append(removeSCIMMembers(group.UserIDs, userIDs), userIDs...)
+		case "replace":
+			group.UserIDs = userIDs
+		case "remove":
+			if len(op.Value) == 0 {
+				// Removes all the members.
+				group.UserIDs = []uint{}
+			} else {
+				group.UserIDs = removeSCIMMembers(group.UserIDs, userIDs)
+			}
+		default:
+			return newSCIMBadRequestError("invalidValue", "unsupported operation %q", op.Op)
+		}
+		sort.Slice(group.UserIDs, func(i, j int) bool { return group.UserIDs[i] < group.UserIDs[j] })
+		return nil
+
+	case strings.EqualFold(path, "id"), strings.EqualFold(path, "externalId"):
+		// Sent by some identity providers along with the other attributes.
+		return nil
+	}
+	return newSCIMBadRequestError("invalidPath", "unsupported path %q", op.Path)
+}
+
+func scimMemberIDs(members []scimReference) ([]uint, error) {
+	userIDs := make([]uint, 0, len(members))
+	for _, member := range members {
+		id, err := strconv.ParseUint(member.Value, 10, 0)
+		if err != nil {
+			return nil, newSCIMBadRequestError("invalidValue", "invalid member %q", member.Value)
+		}
+		userIDs = append(userIDs, uint(id))
+	}
+	return userIDs, nil
+}
+
+func removeSCIMMembers(userIDs, removed []uint) []uint {
+	kept := make([]uint, 0, len(userIDs))
+	for _, userID := range userIDs {
+		keep := true
+		for _, id := range removed {
+			keep = keep && id != userID
+		}
+		if keep {
+			kept = append(kept, userID)
+		}
+	}
+	return kept
+}
+
+func (h *scimHandler) deleteGroup(ctx context.Context, r *http.Request) (int, interface{}, error) {
+	id, err := scimID(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if err := h.svc.DeleteSCIMGroup(ctx, id); err != nil {
+		return 0, nil, err
+	}
+	return http.StatusNoContent, nil, nil
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-sql-driver/mysql"
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSCIMTestDatastore returns a datastore keeping the users and the SCIM
+// groups in memory.
+func newSCIMTestDatastore(users map[uint]*fleet.User) *mock.Store {
+	ds := new(mock.Store)
+	nextUserID := uint(1)
+	groups := make(map[uint]*fleet.SCIMGroup)
+	nextGroupID := uint(1)
+
+	copyUser := func(user *fleet.User) *fleet.User {
+		copy := *user
+		return &copy
+	}
+	copyGroup := func(group *fleet.SCIMGroup) *fleet.SCIMGroup {
+		copy := *group
+		copy.UserIDs = append([]uint{}, group.UserIDs...)
+		sort.Slice(copy.UserIDs, func(i, j int) bool { return copy.UserIDs[i] < copy.UserIDs[j] })
+		return &copy
+	}
+
+	ds.UserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		if user, ok := users[id]; ok {
+			return copyUser(user), nil
+		}
+		return nil, notFoundError{}
+	}
+	ds.SCIMUserByIDFunc = func(ctx context.Context, id uint) (*fleet.User, error) {
+		if user, ok := users[id]; ok && user.SCIMManaged {
+			return copyUser(user), nil
+		}
+		return nil, notFoundError{}
+	}
+	ds.ListSCIMUsersFunc = func(ctx context.Context, opt fleet.SCIMUserListOptions) ([]*fleet.User, int, error) {
+		var list []*fleet.User
+		for _, user := range users {
+			if user.SCIMManaged && (opt.UserName == "" || strings.EqualFold(user.Email, opt.UserName)) {
+				list = append(list, copyUser(user))
+			}
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+		total := len(list)
+		if int(opt.Offset) > len(list) {
+			opt.Offset = uint(len(list))
+		}
+		list = list[opt.Offset:]
+		if int(opt.Limit) < len(list) {
+			list = list[:opt.Limit]
+		}
+		return list, total, nil
+	}
+	ds.NewUserFunc = func(ctx context.Context, user *fleet.User) (*fleet.User, error) {
+		if err := fleet.ValidateRole(user.GlobalRole, user.Teams); err != nil {
+			return nil, err
+		}
+		for _, existing := range users {
+			if existing.Email == user.Email {
+				return nil, errors.Wrap(&mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}, "create new user")
+			}
+		}
+		for users[nextUserID] != nil {
+			nextUserID++
+		}
+		user.ID = nextUserID
+		users[user.ID] = copyUser(user)
+		return user, nil
+	}
+	ds.SaveUserFunc = func(ctx context.Context, user *fleet.User) error {
+		users[user.ID] = copyUser(user)
+		return nil
+	}
+	ds.SaveUsersFunc = func(ctx context.Context, saved []*fleet.User) error {
+		for _, user := range saved {
+			if err := fleet.ValidateRole(user.GlobalRole, user.Teams); err != nil {
+				return err
+			}
+			users[user.ID] = copyUser(user)
+		}
+		return nil
+	}
+	ds.DeleteUserFunc = func(ctx context.Context, id uint) error {
+		delete(users, id)
+		for _, group := range groups {
+			group.UserIDs = removeSCIMMembers(group.UserIDs, []uint{id})
+		}
+		return nil
+	}
+	// Only the users provisioned through SCIM can be members of the groups.
+	checkMembers := func(group *fleet.SCIMGroup) error {
+		for _, userID := range group.UserIDs {
+			if user, ok := users[userID]; !ok || !user.SCIMManaged {
+				return notFoundError{}
+			}
+		}
+		return nil
+	}
+	ds.NewSCIMGroupFunc = func(ctx context.Context, group *fleet.SCIMGroup) (*fleet.SCIMGroup, error) {
+		if err := checkMembers(group); err != nil {
+			return nil, err
+		}
+		group.ID = nextGroupID
+		nextGroupID++
+		groups[group.ID] = copyGroup(group)
+		return copyGroup(group), nil
+	}
+	ds.SCIMGroupFunc = func(ctx context.Context, id uint) (*fleet.SCIMGroup, error) {
+		if group, ok := groups[id]; ok {
+			return copyGroup(group), nil
+		}
+		return nil, notFoundError{}
+	}
+	ds.ListSCIMGroupsFunc = func(ctx context.Context) ([]*fleet.SCIMGroup, error) {
+		var list []*fleet.SCIMGroup
+		for _, group := range groups {
+			list = append(list, copyGroup(group))
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+		return list, nil
+	}
+	ds.SaveSCIMGroupFunc = func(ctx context.Context, group *fleet.SCIMGroup) error {
+		if err := checkMembers(group); err != nil {
+			return err
+		}
+		groups[group.ID] = copyGroup(group)
+		return nil
+	}
+	ds.DeleteSCIMGroupFunc = func(ctx context.Context, id uint) error {
+		delete(groups, id)
+		return nil
+	}
+	return ds
+}
+
+func TestSCIMProvisioning(t *testing.T) {
+	users := map[uint]*fleet.User{
+		1: {ID: 1, Email: "admin@example.com", GlobalRole: ptr.String(fleet.RoleAdmin)},
+		3: {ID: 3, Email: "bob@example.com", GlobalRole: ptr.String(fleet.RoleObserver), SCIMManaged: true},
+		4: {ID: 4, Email: "carol@example.com", GlobalRole: ptr.String(fleet.RoleAdmin), SSOEnabled: true},
+	}
+	ds := newSCIMTestDatastore(users)
+	tokens := map[string]*fleet.APIToken{
+		fleet.HashAPITokenKey("fleet_api_admin"):    {ID: 1, UserID: 1},
+		fleet.HashAPITokenKey("fleet_api_observer"): {ID: 2, UserID: 3},
+	}
+	ds.APITokenByKeyHashFunc = func(ctx context.Context, keyHash string) (*fleet.APIToken, error) {
+		if token, ok := tokens[keyHash]; ok {
+			return token, nil
+		}
+		return nil, notFoundError{}
+	}
+	ds.MarkAPITokenUsedFunc = func(ctx context.Context, id uint, usedAt time.Time) error {
+		return nil
+	}
+	var destroyedSessions []uint
+	ds.DestroyAllSessionsForUserFunc = func(ctx context.Context, id uint) error {
+		destroyedSessions = append(destroyedSessions, id)
+		return nil
+	}
+
+	conf := config.TestConfig()
+	conf.SCIM = config.SCIMConfig{
+		GroupRoles:  "Fleet Admins=admin;Workstations maintainers=1:maintainer",
+		DefaultRole: fleet.RoleObserver,
+	}
+	svc := newTestServiceWithConfig(ds, conf, nil, nil)
+	r := mux.NewRouter()
+	attachSCIMRoutes(r, svc, kitlog.NewNopLogger())
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	do := func(key, method, path, fixture string, expectedStatus int) map[string]interface{} {
+		var body []byte
+		if fixture != "" {
+			var err error
+			body, err = ioutil.ReadFile(filepath.Join("testdata", "scim", fixture))
+			require.NoError(t, err)
+		}
+		req, err := http.NewRequest(method, server.URL+scimPathPrefix+path, bytes.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", scimMediaType)
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, expectedStatus, resp.StatusCode, "%s %s", method, path)
+		if resp.StatusCode == http.StatusNoContent {
+			return nil
+		}
+		assert.Equal(t, scimMediaType, resp.Header.Get("Content-Type"))
+		var resource map[string]interface{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&resource))
+		if resp.StatusCode == http.StatusCreated {
+			assert.Equal(t, resource["meta"].(map[string]interface{})["location"], resp.Header.Get("Location"))
+		}
+		return resource
+	}
+	const admin = "fleet_api_admin"
+
+	// The identity provider looks for the user before creating it.
+	list := do(admin, "GET", `/Users?filter=userName+eq+"jane@example.com"`, "", http.StatusOK)
+	assert.Equal(t, float64(0), list["totalResults"])
+
+	user := do(admin, "POST", "/Users", "create_user.json", http.StatusCreated)
+	assert.Equal(t, "2", user["id"])
+	assert.Equal(t, "jane@example.com", user["userName"])
+	assert.Equal(t, "Jane Doe", user["displayName"])
+	assert.Equal(t, true, user["active"])
+	// The user signs in through SSO, as an observer until it is a member of
+	// a group with a role.
+	require.Contains(t, users, uint(2))
+	assert.True(t, users[2].SSOEnabled)
+	assert.True(t, users[2].SCIMManaged)
+	assert.Equal(t, ptr.String(fleet.RoleObserver), users[2].GlobalRole)
+	do(admin, "POST", "/Users", "create_user.json", http.StatusConflict)
+
+	list = do(admin, "GET", `/Users?filter=userName+eq+"JANE@example.com"`, "", http.StatusOK)
+	assert.Equal(t, float64(1), list["totalResults"])
+	// Only the users provisioned through SCIM are listed.
+	list = do(admin, "GET", "/Users?startIndex=2&count=1", "", http.StatusOK)
+	assert.Equal(t, float64(2), list["totalResults"])
+	require.Len(t, list["Resources"], 1)
+	assert.Equal(t, "3", list["Resources"].([]interface{})[0].(map[string]interface{})["id"])
+	list = do(admin, "GET", `/Users?filter=userName+eq+"admin@example.com"`, "", http.StatusOK)
+	assert.Equal(t, float64(0), list["totalResults"])
+
+	user = do(admin, "PUT", "/Users/2", "replace_user.json", http.StatusOK)
+	assert.Equal(t, "jane.doe@example.com", user["userName"])
+	assert.Equal(t, "Jane Smith", users[2].Name)
+
+	// The members of the groups get the roles of the groups.
+	group := do(admin, "POST", "/Groups", "create_group.json", http.StatusCreated)
+	assert.Equal(t, "Fleet Admins", group["displayName"])
+	assert.Equal(t, ptr.String(fleet.RoleAdmin), users[2].GlobalRole)
+	user = do(admin, "GET", "/Users/2", "", http.StatusOK)
+	assert.Equal(t, []interface{}{map[string]interface{}{"value": "1", "display": "Fleet Admins"}}, user["groups"])
+
+	list = do(admin, "GET", `/Groups?filter=displayName%20eq%20"Fleet%20Admins"`, "", http.StatusOK)
+	assert.Equal(t, float64(1), list["totalResults"])
+
+	group = do(admin, "PATCH", "/Groups/1", "patch_group_members.json", http.StatusOK)
+	assert.Equal(t, "Workstations maintainers", group["displayName"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"value": "2"}, map[string]interface{}{"value": "3"}}, group["members"])
+	for _, id := range []uint{2, 3} {
+		assert.Nil(t, users[id].GlobalRole)
+		assert.Equal(t, []fleet.UserTeam{{Team: fleet.Team{ID: 1}, Role: fleet.RoleMaintainer}}, users[id].Teams)
+	}
+
+	// The users that were not provisioned through SCIM are not managed by it.
+	do(admin, "GET", "/Users/4", "", http.StatusNotFound)
+	do(admin, "PUT", "/Users/4", "replace_user.json", http.StatusNotFound)
+	do(admin, "PATCH", "/Users/4", "deactivate_user.json", http.StatusNotFound)
+	do(admin, "DELETE", "/Users/4", "", http.StatusNotFound)
+	do(admin, "PATCH", "/Groups/1", "add_group_local_admin.json", http.StatusNotFound)
+	require.Contains(t, users, uint(4))
+	assert.Equal(t, "carol@example.com", users[4].Email)
+	assert.Equal(t, ptr.String(fleet.RoleAdmin), users[4].GlobalRole)
+	assert.Empty(t, destroyedSessions)
+
+	// Deactivated users are deleted, and signed out.
+	user = do(admin, "PATCH", "/Users/2", "deactivate_user.json", http.StatusOK)
+	assert.Equal(t, false, user["active"])
+	assert.NotContains(t, users, uint(2))
+	assert.Equal(t, []uint{2}, destroyedSessions)
+	do(admin, "GET", "/Users/2", "", http.StatusNotFound)
+
+	// The former members of the groups go back to the default role.
+	do(admin, "DELETE", "/Groups/1", "", http.StatusNoContent)
+	assert.Equal(t, ptr.String(fleet.RoleObserver), users[3].GlobalRole)
+	assert.Empty(t, users[3].Teams)
+	do(admin, "DELETE", "/Users/3", "", http.StatusNoContent)
+	assert.Equal(t, []uint{2, 3}, destroyedSessions)
+	do(admin, "DELETE", "/Users/3", "", http.StatusNotFound)
+
+	// Errors are in the SCIM format.
+	scimErr := do("", "GET", "/Users", "", http.StatusUnauthorized)
+	assert.Equal(t, []interface{}{scimErrorSchema}, scimErr["schemas"])
+	assert.Equal(t, "401", scimErr["status"])
+	scimErr = do(admin, "GET", `/Users?filter=emails+co+"example.com"`, "", http.StatusBadRequest)
+	assert.Equal(t, "invalidFilter", scimErr["scimType"])
+	do(admin, "GET", "/Groups/foo", "", http.StatusNotFound)
+	do("fleet_api_unknown", "GET", "/Groups", "", http.StatusUnauthorized)
+	users[3] = &fleet.User{ID: 3, Email: "bob@example.com", GlobalRole: ptr.String(fleet.RoleObserver)}
+	do("fleet_api_observer", "GET", "/Groups", "", http.StatusForbidden)
+	do("fleet_api_observer", "POST", "/Users", "create_user.json", http.StatusForbidden)
+}
+
+func TestParseSCIMFilter(t *testing.T) {
+	value, ok, err := parseSCIMFilter(`userName eq "jane@example.com"`, "userName")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "jane@example.com", value)
+
+	// The attributes and operators are case insensitive.
+	value, ok, err = parseSCIMFilter(` username EQ "say \"hi\"" `, "userName")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `say "hi"`, value)
+
+	_, ok, err = parseSCIMFilter("", "userName")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	for _, filter := range []string{
+		`displayName eq "x"`,
+		`userName sw "x"`,
+		`userName eq "x" and active eq true`,
+		`userName eq x`,
+	} {
+		_, _, err := parseSCIMFilter(filter, "userName")
+		assert.Error(t, err, filter)
+	}
+}
+
+func TestSCIMConfigValidation(t *testing.T) {
+	// The default role defaults to observer.
+	conf := config.TestConfig()
+	_, err := NewService(new(mock.Store), nil, kitlog.NewNopLogger(), nil, conf, nil, nil, nil, nil, nil, fleet.LicenseInfo{})
+	require.NoError(t, err)
+
+	conf.SCIM.DefaultRole = "owner"
+	_, err = NewService(new(mock.Store), nil, kitlog.NewNopLogger(), nil, conf, nil, nil, nil, nil, nil, fleet.LicenseInfo{})
+	require.Error(t, err)
+
+	conf.SCIM = config.SCIMConfig{GroupRoles: "Fleet Admins=1:admin"}
+	_, err = NewService(new(mock.Store), nil, kitlog.NewNopLogger(), nil, conf, nil, nil, nil, nil, nil, fleet.LicenseInfo{})
+	require.Error(t, err)
+}
@@ -3,8 +3,11 @@
 package service
 
 import (
+	"crypto/tls"
 	"html/template"
+	"net/http"
 	"sync"
+	"time"
 
 	"github.com/WatchBeam/clock"
 	"github.com/fleetdm/fleet/v4/server/authz"
@@ -28,19 +31,24 @@ type Service struct {
 	license        fleet.LicenseInfo
 
 	osqueryLogWriter *logging.OsqueryLogger
+	logEnrichment    *logEnrichment
 
 	mailService     fleet.MailService
 	ssoSessionStore sso.SessionStore
+	ssoSPCert       *tls.Certificate
+	oidcProviders   *sso.OIDCProviders
 
 	seenHostSet *seenHostSet
 
+	requestedCampaignHostSet *requestedCampaignHostSet
+
 	authz *authz.Authorizer
 }
 
 // NewService creates a new service from the config struct
 func NewService(ds fleet.Datastore, resultStore fleet.QueryResultStore,
 	logger kitlog.Logger, osqueryLogger *logging.OsqueryLogger, config config.FleetConfig, mailService fleet.MailService,
-	c clock.Clock, sso sso.SessionStore, lq fleet.LiveQueryStore, carveStore fleet.CarveStore,
+	c clock.Clock, ssoStore sso.SessionStore, lq fleet.LiveQueryStore, carveStore fleet.CarveStore,
 	license fleet.LicenseInfo) (fleet.Service, error) {
 	var svc fleet.Service
 
@@ -48,6 +56,17 @@ func NewService(ds fleet.Datastore, resultStore fleet.QueryResultStore,
 	if err != nil {
 		return nil, errors.Wrap(err, "new authorizer")
 	}
+	logEnrichment, err := newLogEnrichment(config.Osquery.LogEnrichmentFields, logEnrichmentCacheTTL)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse osquery log enrichment fields")
+	}
+	if _, err := parseSCIMGroupRoles(config.SCIM); err != nil {
+		return nil, errors.Wrap(err, "parse scim config")
+	}
+	ssoSPCert, err := loadSAMLServiceProviderCert(config.SAML)
+	if err != nil {
+		return nil, err
+	}
 
 	svc = &Service{
 		ds:               ds,
@@ -58,13 +77,18 @@ func NewService(ds fleet.Datastore, resultStore fleet.QueryResultStore,
 		config:           config,
 		clock:            c,
 		osqueryLogWriter: osqueryLogger,
+		logEnrichment:    logEnrichment,
 		mailService:      mailService,
-		ssoSessionStore:  sso,
+		ssoSessionStore:  ssoStore,
+		ssoSPCert:        ssoSPCert,
+		oidcProviders:    sso.NewOIDCProviders(&http.Client{Timeout: 5 * time.Second}),
 		seenHostSet:      newSeenHostSet(),
 		license:          license,
 		authz:            authorizer,
+
+		requestedCampaignHostSet: newRequestedCampaignHostSet(config.Osquery.LiveQueryTimeout),
 	}
-	svc = validationMiddleware{svc, ds, sso}
+	svc = validationMiddleware{svc, ds, ssoStore}
 	return svc, nil
 }
 
@@ -115,3 +139,59 @@ func (m *seenHostSet) getAndClearHostIDs() []uint {
 	m.hostIDs = make(map[uint]bool)
 	return ids
 }
+
+// requestedCampaignHostSet implements synchronized storage for the hosts that
+// retrieved the query of each live query campaign, so that their requested
+// status is only written once rather than at each distributed query poll.
+type requestedCampaignHostSet struct {
+	mutex sync.Mutex
+	// ttl is how long the hosts of a campaign are kept, after which the
+	// campaign has timed out.
+	ttl       time.Duration
+	campaigns map[uint]*requestedCampaignHosts
+}
+
+type requestedCampaignHosts struct {
+	seenAt  time.Time
+	hostIDs map[uint]bool
+}
+
+func newRequestedCampaignHostSet(ttl time.Duration) *requestedCampaignHostSet {
+	return &requestedCampaignHostSet{
+		mutex:     sync.Mutex{},
+		ttl:       ttl,
+		campaigns: make(map[uint]*requestedCampaignHosts),
+	}
+}
+
+// addHostID adds the host identified by ID to the set of the campaign, and
+// returns false if it was already in it.
+func (m *requestedCampaignHostSet) addHostID(campaignID, hostID uint, now time.Time) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for id, campaign := range m.campaigns {
+		if now.Sub(campaign.seenAt) > m.ttl {
+			delete(m.campaigns, id)
+		}
+	}
+	campaign, ok := m.campaigns[campaignID]
+	if !ok {
+		campaign = &requestedCampaignHosts{seenAt: now, hostIDs: make(map[uint]bool)}
+		m.campaigns[campaignID] = campaign
+	}
+	if campaign.hostIDs[hostID] {
+		return false
+	}
+	campaign.hostIDs[hostID] = true
+	return true
+}
+
+// removeHostID removes the host identified by ID from the set of the
+// campaign.
+func (m *requestedCampaignHostSet) removeHostID(campaignID, hostID uint) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if campaign, ok := m.campaigns[campaignID]; ok {
+		delete(campaign.hostIDs, hostID)
+	}
+}
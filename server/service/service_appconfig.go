@@ -8,8 +8,10 @@ import (
 	"strings"
 
 	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/logging"
 	"github.com/fleetdm/fleet/v4/server/mail"
 	"github.com/kolide/kit/version"
 	"github.com/pkg/errors"
@@ -189,100 +191,132 @@ func (svc *Service) LoggingConfig(ctx context.Context) (*fleet.Logging, error) {
 		return nil, err
 	}
 	conf := svc.config
-	logging := &fleet.Logging{
+	loggingConfig := &fleet.Logging{
 		Debug: conf.Logging.Debug,
 		Json:  conf.Logging.JSON,
 	}
 
-	switch conf.Osquery.StatusLogPlugin {
-	case "", "filesystem":
-		logging.Status = fleet.LoggingPlugin{
-			Plugin: "filesystem",
-			Config: fleet.FilesystemConfig{FilesystemConfig: conf.Filesystem},
-		}
-	case "kinesis":
-		logging.Status = fleet.LoggingPlugin{
-			Plugin: "kinesis",
-			Config: fleet.KinesisConfig{
-				Region:       conf.Kinesis.Region,
-				StatusStream: conf.Kinesis.StatusStream,
-				ResultStream: conf.Kinesis.ResultStream,
-			},
-		}
-	case "firehose":
-		logging.Status = fleet.LoggingPlugin{
-			Plugin: "firehose",
-			Config: fleet.FirehoseConfig{
-				Region:       conf.Firehose.Region,
-				StatusStream: conf.Firehose.StatusStream,
-				ResultStream: conf.Firehose.ResultStream,
-			},
-		}
-	case "lambda":
-		logging.Status = fleet.LoggingPlugin{
-			Plugin: "lambda",
-			Config: fleet.LambdaConfig{
-				Region:         conf.Lambda.Region,
-				StatusFunction: conf.Lambda.StatusFunction,
-				ResultFunction: conf.Lambda.ResultFunction,
-			},
-		}
-	case "pubsub":
-		logging.Status = fleet.LoggingPlugin{
-			Plugin: "pubsub",
-			Config: fleet.PubSubConfig{PubSubConfig: conf.PubSub},
+	statusPlugins, err := loggingPlugins(conf, conf.Osquery.StatusLogPlugin)
+	if err != nil {
+		return nil, err
+	}
+	loggingConfig.Status = statusPlugins[0]
+	if len(statusPlugins) > 1 {
+		loggingConfig.StatusPlugins = statusPlugins
+	}
+
+	resultPlugins, err := loggingPlugins(conf, conf.Osquery.ResultLogPlugin)
+	if err != nil {
+		return nil, err
+	}
+	loggingConfig.Result = resultPlugins[0]
+	if len(resultPlugins) > 1 {
+		loggingConfig.ResultPlugins = resultPlugins
+	}
+
+	routes, err := logging.ParseLogRoutes(conf.Osquery.ResultLogRoutes)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse result log routes")
+	}
+	for _, route := range routes {
+		loggingConfig.ResultRoutes = append(loggingConfig.ResultRoutes, fleet.LoggingRoute{Pattern: route.Pattern, Plugins: route.Plugins})
+	}
+	return loggingConfig, nil
+}
+
+// loggingPlugins returns the configuration of the comma-separated list of
+// plugins.
+func loggingPlugins(conf config.FleetConfig, plugins string) ([]fleet.LoggingPlugin, error) {
+	var loggingPlugins []fleet.LoggingPlugin
+	for _, plugin := range logging.SplitLogPlugins(plugins) {
+		loggingPlugin, err := loggingPluginConfig(conf, plugin)
+		if err != nil {
+			return nil, err
 		}
-	case "stdout":
-		logging.Status = fleet.LoggingPlugin{Plugin: "stdout"}
-	default:
-		return nil, errors.Errorf("unrecognized logging plugin: %s", conf.Osquery.StatusLogPlugin)
+		loggingPlugins = append(loggingPlugins, loggingPlugin)
 	}
+	return loggingPlugins, nil
+}
 
-	switch conf.Osquery.ResultLogPlugin {
+func loggingPluginConfig(conf config.FleetConfig, plugin string) (fleet.LoggingPlugin, error) {
+	switch plugin {
 	case "", "filesystem":
-		logging.Result = fleet.LoggingPlugin{
+		return fleet.LoggingPlugin{
 			Plugin: "filesystem",
 			Config: fleet.FilesystemConfig{FilesystemConfig: conf.Filesystem},
-		}
+		}, nil
 	case "kinesis":
-		logging.Result = fleet.LoggingPlugin{
+		return fleet.LoggingPlugin{
 			Plugin: "kinesis",
 			Config: fleet.KinesisConfig{
 				Region:       conf.Kinesis.Region,
 				StatusStream: conf.Kinesis.StatusStream,
 				ResultStream: conf.Kinesis.ResultStream,
 			},
-		}
+		}, nil
 	case "firehose":
-		logging.Result = fleet.LoggingPlugin{
+		return fleet.LoggingPlugin{
 			Plugin: "firehose",
 			Config: fleet.FirehoseConfig{
 				Region:       conf.Firehose.Region,
 				StatusStream: conf.Firehose.StatusStream,
 				ResultStream: conf.Firehose.ResultStream,
 			},
-		}
+		}, nil
 	case "lambda":
-		logging.Result = fleet.LoggingPlugin{
+		return fleet.LoggingPlugin{
 			Plugin: "lambda",
 			Config: fleet.LambdaConfig{
 				Region:         conf.Lambda.Region,
 				StatusFunction: conf.Lambda.StatusFunction,
 				ResultFunction: conf.Lambda.ResultFunction,
 			},
-		}
+		}, nil
 	case "pubsub":
-		logging.Result = fleet.LoggingPlugin{
+		return fleet.LoggingPlugin{
 			Plugin: "pubsub",
 			Config: fleet.PubSubConfig{PubSubConfig: conf.PubSub},
-		}
+		}, nil
+	case "kafka":
+		return fleet.LoggingPlugin{
+			Plugin: "kafka",
+			Config: fleet.KafkaConfig{
+				Brokers:     conf.Kafka.Brokers,
+				StatusTopic: conf.Kafka.StatusTopic,
+				ResultTopic: conf.Kafka.ResultTopic,
+			},
+		}, nil
+	case "http":
+		return fleet.LoggingPlugin{
+			Plugin: "http",
+			Config: fleet.HTTPLogConfig{
+				StatusURL: conf.HTTPLog.StatusURL,
+				ResultURL: conf.HTTPLog.ResultURL,
+			},
+		}, nil
+	case "splunk":
+		return fleet.LoggingPlugin{
+			Plugin: "splunk",
+			Config: fleet.SplunkConfig{
+				URL:              conf.Splunk.URL,
+				StatusIndex:      conf.Splunk.StatusIndex,
+				ResultIndex:      conf.Splunk.ResultIndex,
+				StatusSourcetype: conf.Splunk.StatusSourcetype,
+				ResultSourcetype: conf.Splunk.ResultSourcetype,
+			},
+		}, nil
+	case "elasticsearch":
+		return fleet.LoggingPlugin{
+			Plugin: "elasticsearch",
+			Config: fleet.ElasticsearchConfig{
+				URL:         conf.Elasticsearch.URL,
+				StatusIndex: conf.Elasticsearch.StatusIndex,
+				ResultIndex: conf.Elasticsearch.ResultIndex,
+			},
+		}, nil
 	case "stdout":
-		logging.Result = fleet.LoggingPlugin{
-			Plugin: "stdout",
-		}
+		return fleet.LoggingPlugin{Plugin: "stdout"}, nil
 	default:
-		return nil, errors.Errorf("unrecognized logging plugin: %s", conf.Osquery.ResultLogPlugin)
-
+		return fleet.LoggingPlugin{}, errors.Errorf("unrecognized logging plugin: %s", plugin)
 	}
-	return logging, nil
 }
@@ -278,6 +278,34 @@ func TestService_LoggingConfig(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "test multiple plugins and routes",
+			fields: fields{config: func() config.FleetConfig {
+				c := testKinesisPluginConfig()
+				c.Osquery.ResultLogPlugin = "kinesis, kafka"
+				c.Osquery.ResultLogRoutes = "pack/noisy/*=filesystem"
+				c.Kafka = config.KafkaConfig{Brokers: "kafka:9092", ResultTopic: "osquery_result", SASLPassword: "secret"}
+				return c
+			}()},
+			args: args{ctx: test.UserContext(test.UserAdmin)},
+			want: &fleet.Logging{
+				Debug: true,
+				Json:  false,
+				Result: fleet.LoggingPlugin{
+					Plugin: "kinesis",
+					Config: kinesisConfig,
+				},
+				Status: fleet.LoggingPlugin{
+					Plugin: "kinesis",
+					Config: kinesisConfig,
+				},
+				ResultPlugins: []fleet.LoggingPlugin{
+					{Plugin: "kinesis", Config: kinesisConfig},
+					{Plugin: "kafka", Config: fleet.KafkaConfig{Brokers: "kafka:9092", ResultTopic: "osquery_result"}},
+				},
+				ResultRoutes: []fleet.LoggingRoute{{Pattern: "pack/noisy/*", Plugins: []string{"filesystem"}}},
+			},
+		},
 		{
 			name: "test unrecognized config",
 			fields: fields{config: config.FleetConfig{
@@ -31,6 +31,14 @@ func TestStreamCampaignResultsClosesReditOnWSClose(t *testing.T) {
 
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		return nil
+	}
+	ds.NewDistributedQueryCampaignHostsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint) error {
+		return nil
+	}
 	lq := new(live_query.MockLiveQuery)
 	svc := newTestServiceWithClock(ds, store, lq, mockClock)
 
@@ -144,6 +144,10 @@ func (svc Service) NewDistributedQueryCampaign(ctx context.Context, queryString
 		return nil, errors.Wrap(err, "get target IDs")
 	}
 
+	if err := svc.ds.NewDistributedQueryCampaignHosts(ctx, campaign.ID, hostIDs); err != nil {
+		return nil, errors.Wrap(err, "record campaign hosts")
+	}
+
 	err = svc.liveQueryStore.RunQuery(strconv.Itoa(int(campaign.ID)), queryString, hostIDs)
 	if err != nil {
 		return nil, errors.Wrap(err, "run query")
@@ -320,6 +324,10 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 	// Push status updates every 5 seconds at most
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
+	// Stop the campaign once it ran for the configured timeout, even if the
+	// client is still listening.
+	timeout := time.NewTimer(svc.config.Osquery.LiveQueryTimeout)
+	defer timeout.Stop()
 	// Loop, pushing updates to results and expected totals
 	for {
 		// Update the expected hosts total (Should happen before
@@ -349,11 +357,22 @@ func (svc Service) StreamCampaignResults(ctx context.Context, conn *websocket.Co
 				// by the client
 				return
 			}
+			// Stop if the campaign was cancelled
+			if cancelled, err := svc.campaignCancelled(ctx, campaign.ID); err != nil {
+				svc.logger.Log("msg", "error loading campaign", "err", err)
+			} else if cancelled {
+				_ = conn.WriteJSONError("campaign cancelled")
+				return
+			}
 			// Update status
 			if err := updateStatus(); err != nil {
 				svc.logger.Log("msg", "error updating status", "err", err)
 				return
 			}
+
+		case <-timeout.C:
+			_ = conn.WriteJSONError("campaign timed out")
+			return
 		}
 	}
 }
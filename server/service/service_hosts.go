@@ -65,6 +65,10 @@ func (svc Service) getHostDetails(ctx context.Context, host *fleet.Host) (*fleet
 		return nil, errors.Wrap(err, "load host software")
 	}
 
+	if err := svc.ds.LoadHostVitals(ctx, host); err != nil {
+		return nil, errors.Wrap(err, "load host vitals")
+	}
+
 	labels, err := svc.ds.ListLabelsForHost(ctx, host.ID)
 	if err != nil {
 		return nil, errors.Wrap(err, "get labels for host")
@@ -89,11 +89,16 @@ func TestHostDetails(t *testing.T) {
 	ds.LoadHostSoftwareFunc = func(ctx context.Context, host *fleet.Host) error {
 		return nil
 	}
+	ds.LoadHostVitalsFunc = func(ctx context.Context, host *fleet.Host) error {
+		host.MDM = &fleet.HostMDM{Enrolled: true, ServerURL: "https://mdm.example.com"}
+		return nil
+	}
 
 	hostDetail, err := svc.getHostDetails(test.UserContext(test.UserAdmin), host)
 	require.NoError(t, err)
 	assert.Equal(t, expectedLabels, hostDetail.Labels)
 	assert.Equal(t, expectedPacks, hostDetail.Packs)
+	assert.Equal(t, &fleet.HostMDM{Enrolled: true, ServerURL: "https://mdm.example.com"}, hostDetail.MDM)
 }
 
 func TestRefetchHost(t *testing.T) {
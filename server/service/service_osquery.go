@@ -349,7 +349,7 @@ func (svc *Service) SubmitStatusLogs(ctx context.Context, logs []json.RawMessage
 
 	logIPs(ctx)
 
-	if err := svc.osqueryLogWriter.Status.Write(ctx, logs); err != nil {
+	if err := svc.osqueryLogWriter.Status.Write(ctx, svc.enrichLogs(ctx, logs)); err != nil {
 		return osqueryError{message: "error writing status logs: " + err.Error()}
 	}
 	return nil
@@ -372,9 +372,12 @@ func (svc *Service) SubmitResultLogs(ctx context.Context, logs []json.RawMessage
 
 	logIPs(ctx)
 
-	if err := svc.osqueryLogWriter.Result.Write(ctx, logs); err != nil {
+	if err := svc.osqueryLogWriter.Result.Write(ctx, svc.enrichLogs(ctx, logs)); err != nil {
 		return osqueryError{message: "error writing result logs: " + err.Error()}
 	}
+	if svc.config.Osquery.ResultStoreMaxRows > 0 {
+		svc.storeScheduledQueryResults(ctx, logs)
+	}
 	return nil
 }
 
@@ -401,44 +404,49 @@ const hostPolicyQueryPrefix = "fleet_policy_query_"
 const hostDistributedQueryPrefix = "fleet_distributed_query_"
 
 // hostDetailQueries returns the map of queries that should be executed by
-// osqueryd to fill in the host details
-func (svc *Service) hostDetailQueries(ctx context.Context, host fleet.Host) (map[string]string, error) {
+// osqueryd to fill in the host details, and the map of discovery queries of
+// the detail queries that depend on an osquery extension.
+func (svc *Service) hostDetailQueries(ctx context.Context, host fleet.Host) (map[string]string, map[string]string, error) {
 	queries := make(map[string]string)
+	discovery := make(map[string]string)
 	if host.DetailUpdatedAt.After(svc.clock.Now().Add(-svc.config.Osquery.DetailUpdateInterval)) && !host.RefetchRequested {
 		// No need to update already fresh details
-		return queries, nil
+		return queries, discovery, nil
 	}
 	config, err := svc.ds.AppConfig(ctx)
 	if err != nil {
-		return nil, osqueryError{message: "get additional queries: " + err.Error()}
+		return nil, nil, osqueryError{message: "get additional queries: " + err.Error()}
 	}
 
 	detailQueries := osquery_utils.GetDetailQueries(config)
 	for name, query := range detailQueries {
 		if query.RunsForPlatform(host.Platform) {
 			queries[hostDetailQueryPrefix+name] = query.Query
+			if query.Discovery != "" {
+				discovery[hostDetailQueryPrefix+name] = query.Discovery
+			}
 		}
 	}
 
 	// Get additional queries
 	if config.HostSettings.AdditionalQueries == nil {
 		// No additional queries set
-		return queries, nil
+		return queries, discovery, nil
 	}
 
 	var additionalQueries map[string]string
 	if err := json.Unmarshal(*config.HostSettings.AdditionalQueries, &additionalQueries); err != nil {
-		return nil, osqueryError{message: "unmarshal additional queries: " + err.Error()}
+		return nil, nil, osqueryError{message: "unmarshal additional queries: " + err.Error()}
 	}
 
 	for name, query := range additionalQueries {
 		queries[hostAdditionalQueryPrefix+name] = query
 	}
 
-	return queries, nil
+	return queries, discovery, nil
 }
 
-func (svc *Service) GetDistributedQueries(ctx context.Context) (map[string]string, uint, error) {
+func (svc *Service) GetDistributedQueries(ctx context.Context) (map[string]string, map[string]string, uint, error) {
 	// skipauth: Authorization is currently for user endpoints only.
 	svc.authz.SkipAuthorization(ctx)
 
@@ -446,19 +454,19 @@ func (svc *Service) GetDistributedQueries(ctx context.Context) (map[string]strin
 
 	host, ok := hostctx.FromContext(ctx)
 	if !ok {
-		return nil, 0, osqueryError{message: "internal error: missing host from request context"}
+		return nil, nil, 0, osqueryError{message: "internal error: missing host from request context"}
 	}
 
-	queries, err := svc.hostDetailQueries(ctx, host)
+	queries, discovery, err := svc.hostDetailQueries(ctx, host)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, 0, err
 	}
 
 	// Retrieve the label queries that should be updated
 	cutoff := svc.clock.Now().Add(-svc.config.Osquery.LabelUpdateInterval)
 	labelQueries, err := svc.ds.LabelQueriesForHost(ctx, &host, cutoff)
 	if err != nil {
-		return nil, 0, osqueryError{message: "retrieving label queries: " + err.Error()}
+		return nil, nil, 0, osqueryError{message: "retrieving label queries: " + err.Error()}
 	}
 
 	for name, query := range labelQueries {
@@ -467,16 +475,29 @@ func (svc *Service) GetDistributedQueries(ctx context.Context) (map[string]strin
 
 	liveQueries, err := svc.liveQueryStore.QueriesForHost(host.ID)
 	if err != nil {
-		return nil, 0, osqueryError{message: "retrieve live queries: " + err.Error()}
+		return nil, nil, 0, osqueryError{message: "retrieve live queries: " + err.Error()}
 	}
 
 	for name, query := range liveQueries {
 		queries[hostDistributedQueryPrefix+name] = query
+
+		// The hosts retrieve the query until they return its results, so that
+		// the requested status is only written the first time.
+		campaignID, err := strconv.Atoi(name)
+		if err != nil || !svc.requestedCampaignHostSet.addHostID(uint(campaignID), host.ID, svc.clock.Now()) {
+			continue
+		}
+		if err := svc.ds.UpdateDistributedQueryCampaignHostStatus(
+			ctx, uint(campaignID), host.ID, fleet.ExecutionRequested, 0, nil,
+		); err != nil {
+			svc.requestedCampaignHostSet.removeHostID(uint(campaignID), host.ID)
+			logging.WithErr(ctx, errors.Wrap(err, "update campaign host status"))
+		}
 	}
 
 	policyQueries, err := svc.ds.PolicyQueriesForHost(ctx, &host)
 	if err != nil {
-		return nil, 0, osqueryError{message: "retrieving policy queries: " + err.Error()}
+		return nil, nil, 0, osqueryError{message: "retrieving policy queries: " + err.Error()}
 	}
 
 	for name, query := range policyQueries {
@@ -491,7 +512,7 @@ func (svc *Service) GetDistributedQueries(ctx context.Context) (map[string]strin
 		accelerate = 10
 	}
 
-	return queries, accelerate, nil
+	return queries, discovery, accelerate, nil
 }
 
 // ingestDetailQuery takes the results of a detail query and modifies the
@@ -547,6 +568,35 @@ func ingestMembershipQuery(
 	return nil
 }
 
+// queueFailingPolicyHost queues the host for the failing policies webhook for
+// each of the policies configured in the webhook that the host starts failing
+// with the given results.
+func (svc *Service) queueFailingPolicyHost(ctx context.Context, host fleet.Host, results map[uint]*bool) error {
+	config, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return errors.Wrap(err, "getting app config for failing policies webhook")
+	}
+	webhook := config.WebhookSettings.FailingPoliciesWebhook
+	if !webhook.Enable {
+		return nil
+	}
+
+	newlyFailing, err := svc.ds.NewlyFailingPoliciesForHost(ctx, host.ID, results)
+	if err != nil {
+		return errors.Wrap(err, "getting newly failing policies")
+	}
+	var policyIDs []uint
+	for _, policyID := range newlyFailing {
+		if webhook.IsPolicyEnabled(policyID) {
+			policyIDs = append(policyIDs, policyID)
+		}
+	}
+	if err := svc.ds.QueueFailingPolicyHost(ctx, host.ID, policyIDs, svc.clock.Now()); err != nil {
+		return errors.Wrap(err, "queueing failing policy host")
+	}
+	return nil
+}
+
 // ingestDistributedQuery takes the results of a distributed query and modifies the
 // provided fleet.Host appropriately.
 func (svc *Service) ingestDistributedQuery(ctx context.Context, host fleet.Host, name string, rows []map[string]string, failed bool, errMsg string) error {
@@ -567,6 +617,42 @@ func (svc *Service) ingestDistributedQuery(ctx context.Context, host fleet.Host,
 		res.Error = &errMsg
 	}
 
+	status := fleet.ExecutionSucceeded
+	if failed {
+		status = fleet.ExecutionFailed
+	}
+	rowsCount := 0
+	for _, row := range rows {
+		if row != nil {
+			rowsCount++
+		}
+	}
+	if err := svc.ds.UpdateDistributedQueryCampaignHostStatus(
+		ctx, uint(campaignID), host.ID, status, uint(rowsCount), res.Error,
+	); err != nil {
+		logging.WithErr(ctx, errors.Wrap(err, "update campaign host status"))
+	}
+
+	// Store the results so that they can be retrieved after the campaign
+	// finished, even if nobody was listening when they were received.
+	if svc.config.Osquery.LiveQueryResultsRetention > 0 {
+		storedRows := make([]map[string]string, 0, len(rows))
+		for _, row := range rows {
+			if row != nil {
+				storedRows = append(storedRows, row)
+			}
+		}
+		if err := svc.ds.SaveDistributedQueryCampaignResult(ctx, &fleet.DistributedQueryCampaignResult{
+			CampaignID: uint(campaignID),
+			HostID:     host.ID,
+			Hostname:   host.Hostname,
+			Rows:       storedRows,
+			Error:      res.Error,
+		}); err != nil {
+			logging.WithErr(ctx, errors.Wrap(err, "save campaign result"))
+		}
+	}
+
 	err = svc.resultStore.WriteResult(res)
 	if err != nil {
 		nErr, ok := err.(pubsub.Error)
@@ -691,6 +777,9 @@ func (svc *Service) SubmitDistributedQueryResults(
 	}
 
 	if len(policyResults) > 0 {
+		if err := svc.queueFailingPolicyHost(ctx, host, policyResults); err != nil {
+			logging.WithErr(ctx, err)
+		}
 		err = svc.ds.RecordPolicyQueryExecutions(ctx, &host, policyResults, svc.clock.Now())
 		if err != nil {
 			logging.WithErr(ctx, err)
@@ -31,9 +31,18 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-// One of these queries is the disk space, only one of the two works in a platform
-var expectedDetailQueries = len(osquery_utils.GetDetailQueries(
-	&fleet.AppConfig{HostSettings: fleet.HostSettings{EnableHostUsers: true}})) - 1
+// expectedDetailQueries returns the number of detail queries that run on the
+// platform, some of them only work in a platform.
+func expectedDetailQueries(platform string) int {
+	var count int
+	for _, query := range osquery_utils.GetDetailQueries(
+		&fleet.AppConfig{HostSettings: fleet.HostSettings{EnableHostUsers: true}}) {
+		if query.RunsForPlatform(platform) {
+			count++
+		}
+	}
+	return count
+}
 
 func TestEnrollAgent(t *testing.T) {
 	ds := new(mock.Store)
@@ -280,13 +289,13 @@ func TestHostDetailQueries(t *testing.T) {
 
 	svc := &Service{clock: mockClock, config: config.TestConfig(), ds: ds}
 
-	queries, err := svc.hostDetailQueries(context.Background(), host)
+	queries, _, err := svc.hostDetailQueries(context.Background(), host)
 	assert.Nil(t, err)
 	assert.Empty(t, queries)
 
 	// With refetch requested queries should be returned
 	host.RefetchRequested = true
-	queries, err = svc.hostDetailQueries(context.Background(), host)
+	queries, _, err = svc.hostDetailQueries(context.Background(), host)
 	assert.Nil(t, err)
 	assert.NotEmpty(t, queries)
 	host.RefetchRequested = false
@@ -294,9 +303,9 @@ func TestHostDetailQueries(t *testing.T) {
 	// Advance the time
 	mockClock.AddTime(1*time.Hour + 1*time.Minute)
 
-	queries, err = svc.hostDetailQueries(context.Background(), host)
+	queries, _, err = svc.hostDetailQueries(context.Background(), host)
 	assert.Nil(t, err)
-	assert.Len(t, queries, expectedDetailQueries+2)
+	assert.Len(t, queries, expectedDetailQueries("rhel")+2)
 	for name := range queries {
 		assert.True(t,
 			strings.HasPrefix(name, hostDetailQueryPrefix) || strings.HasPrefix(name, hostAdditionalQueryPrefix),
@@ -309,7 +318,7 @@ func TestHostDetailQueries(t *testing.T) {
 func TestGetDistributedQueriesMissingHost(t *testing.T) {
 	svc := newTestService(&mock.Store{}, nil, nil)
 
-	_, _, err := svc.GetDistributedQueries(context.Background())
+	_, _, _, err := svc.GetDistributedQueries(context.Background())
 	require.NotNil(t, err)
 	assert.Contains(t, err.Error(), "missing host")
 }
@@ -346,17 +355,22 @@ func TestLabelQueries(t *testing.T) {
 
 	// With a new host, we should get the detail queries (and accelerate
 	// should be turned on so that we can quickly fill labels)
-	queries, acc, err := svc.GetDistributedQueries(ctx)
+	queries, discovery, acc, err := svc.GetDistributedQueries(ctx)
 	assert.Nil(t, err)
-	assert.Len(t, queries, expectedDetailQueries)
+	assert.Len(t, queries, expectedDetailQueries("darwin"))
 	assert.NotZero(t, acc)
+	// The queries on the tables of the macadmins extension only run when
+	// the extension is loaded.
+	assert.Len(t, discovery, 2)
+	assert.Contains(t, discovery[hostDetailQueryPrefix+"mdm"], "osquery_registry")
+	assert.Contains(t, discovery[hostDetailQueryPrefix+"munki_info"], "osquery_registry")
 
 	// Simulate the detail queries being added
 	host.DetailUpdatedAt = mockClock.Now().Add(-1 * time.Minute)
 	host.Hostname = "zwass.local"
 	ctx = hostctx.NewContext(ctx, *host)
 
-	queries, acc, err = svc.GetDistributedQueries(ctx)
+	queries, _, acc, err = svc.GetDistributedQueries(ctx)
 	assert.Nil(t, err)
 	assert.Len(t, queries, 0)
 	assert.Zero(t, acc)
@@ -370,7 +384,7 @@ func TestLabelQueries(t *testing.T) {
 	}
 
 	// Now we should get the label queries
-	queries, acc, err = svc.GetDistributedQueries(ctx)
+	queries, _, acc, err = svc.GetDistributedQueries(ctx)
 	assert.Nil(t, err)
 	assert.Len(t, queries, 3)
 	assert.Zero(t, acc)
@@ -550,9 +564,9 @@ func TestDetailQueriesWithEmptyStrings(t *testing.T) {
 
 	// With a new host, we should get the detail queries (and accelerated
 	// queries)
-	queries, acc, err := svc.GetDistributedQueries(ctx)
+	queries, _, acc, err := svc.GetDistributedQueries(ctx)
 	assert.Nil(t, err)
-	assert.Len(t, queries, expectedDetailQueries)
+	assert.Len(t, queries, expectedDetailQueries("windows"))
 	assert.NotZero(t, acc)
 
 	resultJSON := `
@@ -687,7 +701,7 @@ func TestDetailQueriesWithEmptyStrings(t *testing.T) {
 
 	// Now no detail queries should be required
 	ctx = hostctx.NewContext(context.Background(), host)
-	queries, acc, err = svc.GetDistributedQueries(ctx)
+	queries, _, acc, err = svc.GetDistributedQueries(ctx)
 	assert.Nil(t, err)
 	assert.Len(t, queries, 0)
 	assert.Zero(t, acc)
@@ -695,9 +709,9 @@ func TestDetailQueriesWithEmptyStrings(t *testing.T) {
 	// Advance clock and queries should exist again
 	mockClock.AddTime(1*time.Hour + 1*time.Minute)
 
-	queries, acc, err = svc.GetDistributedQueries(ctx)
+	queries, _, acc, err = svc.GetDistributedQueries(ctx)
 	assert.Nil(t, err)
-	assert.Len(t, queries, expectedDetailQueries)
+	assert.Len(t, queries, expectedDetailQueries("windows"))
 	assert.Zero(t, acc)
 }
 
@@ -724,9 +738,9 @@ func TestDetailQueries(t *testing.T) {
 
 	// With a new host, we should get the detail queries (and accelerated
 	// queries)
-	queries, acc, err := svc.GetDistributedQueries(ctx)
+	queries, _, acc, err := svc.GetDistributedQueries(ctx)
 	assert.Nil(t, err)
-	assert.Len(t, queries, expectedDetailQueries)
+	assert.Len(t, queries, expectedDetailQueries("linux"))
 	assert.NotZero(t, acc)
 
 	resultJSON := `
@@ -892,7 +906,7 @@ func TestDetailQueries(t *testing.T) {
 
 	// Now no detail queries should be required
 	ctx = hostctx.NewContext(ctx, host)
-	queries, acc, err = svc.GetDistributedQueries(ctx)
+	queries, _, acc, err = svc.GetDistributedQueries(ctx)
 	assert.Nil(t, err)
 	assert.Len(t, queries, 0)
 	assert.Zero(t, acc)
@@ -900,14 +914,17 @@ func TestDetailQueries(t *testing.T) {
 	// Advance clock and queries should exist again
 	mockClock.AddTime(1*time.Hour + 1*time.Minute)
 
-	queries, acc, err = svc.GetDistributedQueries(ctx)
+	queries, _, acc, err = svc.GetDistributedQueries(ctx)
 	assert.Nil(t, err)
-	assert.Len(t, queries, expectedDetailQueries)
+	assert.Len(t, queries, expectedDetailQueries("darwin"))
 	assert.Zero(t, acc)
 }
 
 func TestNewDistributedQueryCampaign(t *testing.T) {
 	ds := new(mock.Store)
+	ds.NewDistributedQueryCampaignHostsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint) error {
+		return nil
+	}
 	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
 		return &fleet.AppConfig{}, nil
 	}
@@ -983,6 +1000,15 @@ func TestNewDistributedQueryCampaign(t *testing.T) {
 func TestDistributedQueryResults(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
+	var requested int
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		if status == fleet.ExecutionRequested {
+			requested++
+		}
+		return nil
+	}
 	rs := pubsub.NewInmemQueryResults()
 	lq := new(live_query.MockLiveQuery)
 	svc := newTestServiceWithClock(ds, rs, lq, mockClock)
@@ -1014,12 +1040,20 @@ func TestDistributedQueryResults(t *testing.T) {
 	lq.On("QueryCompletedByHost", strconv.Itoa(int(campaign.ID)), host.ID).Return(nil)
 
 	// Now we should get the active distributed query
-	queries, acc, err := svc.GetDistributedQueries(hostCtx)
+	queries, _, acc, err := svc.GetDistributedQueries(hostCtx)
 	require.Nil(t, err)
-	assert.Len(t, queries, expectedDetailQueries+1)
+	assert.Len(t, queries, expectedDetailQueries("windows")+1)
 	queryKey := fmt.Sprintf("%s%d", hostDistributedQueryPrefix, campaign.ID)
 	assert.Equal(t, "select * from time", queries[queryKey])
 	assert.NotZero(t, acc)
+	assert.Equal(t, 1, requested)
+
+	// The requested status is only recorded the first time the host
+	// retrieves the query.
+	queries, _, _, err = svc.GetDistributedQueries(hostCtx)
+	require.Nil(t, err)
+	assert.Equal(t, "select * from time", queries[queryKey])
+	assert.Equal(t, 1, requested)
 
 	expectedRows := []map[string]string{
 		{
@@ -1097,6 +1131,15 @@ func TestIngestDistributedQueryParseIdError(t *testing.T) {
 func TestIngestDistributedQueryOrphanedCampaignLoadError(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
+	var requested int
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		if status == fleet.ExecutionRequested {
+			requested++
+		}
+		return nil
+	}
 	rs := pubsub.NewInmemQueryResults()
 	lq := new(live_query.MockLiveQuery)
 	svc := &Service{
@@ -1123,6 +1166,15 @@ func TestIngestDistributedQueryOrphanedCampaignLoadError(t *testing.T) {
 func TestIngestDistributedQueryOrphanedCampaignWaitListener(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
+	var requested int
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		if status == fleet.ExecutionRequested {
+			requested++
+		}
+		return nil
+	}
 	rs := pubsub.NewInmemQueryResults()
 	lq := new(live_query.MockLiveQuery)
 	svc := &Service{
@@ -1156,6 +1208,15 @@ func TestIngestDistributedQueryOrphanedCampaignWaitListener(t *testing.T) {
 func TestIngestDistributedQueryOrphanedCloseError(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
+	var requested int
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		if status == fleet.ExecutionRequested {
+			requested++
+		}
+		return nil
+	}
 	rs := pubsub.NewInmemQueryResults()
 	lq := new(live_query.MockLiveQuery)
 	svc := &Service{
@@ -1192,6 +1253,15 @@ func TestIngestDistributedQueryOrphanedCloseError(t *testing.T) {
 func TestIngestDistributedQueryOrphanedStopError(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
+	var requested int
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		if status == fleet.ExecutionRequested {
+			requested++
+		}
+		return nil
+	}
 	rs := pubsub.NewInmemQueryResults()
 	lq := new(live_query.MockLiveQuery)
 	svc := &Service{
@@ -1229,6 +1299,15 @@ func TestIngestDistributedQueryOrphanedStopError(t *testing.T) {
 func TestIngestDistributedQueryOrphanedStop(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
+	var requested int
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		if status == fleet.ExecutionRequested {
+			requested++
+		}
+		return nil
+	}
 	rs := pubsub.NewInmemQueryResults()
 	lq := new(live_query.MockLiveQuery)
 	svc := &Service{
@@ -1267,6 +1346,15 @@ func TestIngestDistributedQueryOrphanedStop(t *testing.T) {
 func TestIngestDistributedQueryRecordCompletionError(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
+	var requested int
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		if status == fleet.ExecutionRequested {
+			requested++
+		}
+		return nil
+	}
 	rs := pubsub.NewInmemQueryResults()
 	lq := new(live_query.MockLiveQuery)
 	svc := &Service{
@@ -1298,6 +1386,15 @@ func TestIngestDistributedQueryRecordCompletionError(t *testing.T) {
 func TestIngestDistributedQuery(t *testing.T) {
 	mockClock := clock.NewMockClock()
 	ds := new(mock.Store)
+	var requested int
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		if status == fleet.ExecutionRequested {
+			requested++
+		}
+		return nil
+	}
 	rs := pubsub.NewInmemQueryResults()
 	lq := new(live_query.MockLiveQuery)
 	svc := &Service{
@@ -1325,6 +1422,69 @@ func TestIngestDistributedQuery(t *testing.T) {
 	lq.AssertExpectations(t)
 }
 
+func TestIngestDistributedQueryStoresResults(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	ds := new(mock.Store)
+	var requested int
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		if status == fleet.ExecutionRequested {
+			requested++
+		}
+		return nil
+	}
+	rs := pubsub.NewInmemQueryResults()
+	lq := new(live_query.MockLiveQuery)
+	cfg := config.TestConfig()
+	cfg.Osquery.LiveQueryResultsRetention = time.Hour
+	svc := &Service{
+		ds:             ds,
+		resultStore:    rs,
+		liveQueryStore: lq,
+		logger:         log.NewNopLogger(),
+		clock:          mockClock,
+		config:         cfg,
+	}
+
+	var stored []*fleet.DistributedQueryCampaignResult
+	ds.SaveDistributedQueryCampaignResultFunc = func(ctx context.Context, result *fleet.DistributedQueryCampaignResult) error {
+		stored = append(stored, result)
+		return nil
+	}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return &fleet.DistributedQueryCampaign{ID: id, UpdateCreateTimestamps: fleet.UpdateCreateTimestamps{
+			CreateTimestamp: fleet.CreateTimestamp{CreatedAt: mockClock.Now()},
+		}}, nil
+	}
+
+	// The results are stored even if nobody is listening to the campaign.
+	host := fleet.Host{ID: 1, Hostname: "foo"}
+	rows := []map[string]string{{"hour": "10"}, nil}
+	err := svc.ingestDistributedQuery(context.Background(), host, "fleet_distributed_query_42", rows, false, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "campaign waiting for listener")
+
+	host = fleet.Host{ID: 2, Hostname: "bar"}
+	err = svc.ingestDistributedQuery(context.Background(), host, "fleet_distributed_query_42", nil, true, "no such table")
+	require.Error(t, err)
+
+	require.Len(t, stored, 2)
+	assert.Equal(t, &fleet.DistributedQueryCampaignResult{
+		CampaignID: 42,
+		HostID:     1,
+		Hostname:   "foo",
+		Rows:       []map[string]string{{"hour": "10"}},
+	}, stored[0])
+	assert.Equal(t, &fleet.DistributedQueryCampaignResult{
+		CampaignID: 42,
+		HostID:     2,
+		Hostname:   "bar",
+		Rows:       []map[string]string{},
+		Error:      ptr.String("no such table"),
+	}, stored[1])
+}
+
 func TestUpdateHostIntervals(t *testing.T) {
 	ds := new(mock.Store)
 
@@ -1674,6 +1834,9 @@ func TestDistributedQueriesReloadsHostIfDetailsAreIn(t *testing.T) {
 
 func TestObserversCanOnlyRunDistributedCampaigns(t *testing.T) {
 	ds := new(mock.Store)
+	ds.NewDistributedQueryCampaignHostsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint) error {
+		return nil
+	}
 	rs := &mock.QueryResultStore{
 		HealthCheckFunc: func() error {
 			return nil
@@ -1748,6 +1911,9 @@ func TestObserversCanOnlyRunDistributedCampaigns(t *testing.T) {
 
 func TestTeamMaintainerCanRunNewDistributedCampaigns(t *testing.T) {
 	ds := new(mock.Store)
+	ds.NewDistributedQueryCampaignHostsFunc = func(ctx context.Context, campaignID uint, hostIDs []uint) error {
+		return nil
+	}
 	rs := &mock.QueryResultStore{
 		HealthCheckFunc: func() error {
 			return nil
@@ -1839,9 +2005,9 @@ func TestPolicyQueries(t *testing.T) {
 
 	ctx := hostctx.NewContext(context.Background(), *host)
 
-	queries, _, err := svc.GetDistributedQueries(ctx)
+	queries, _, _, err := svc.GetDistributedQueries(ctx)
 	require.NoError(t, err)
-	require.Len(t, queries, expectedDetailQueries+2)
+	require.Len(t, queries, expectedDetailQueries("darwin")+2)
 
 	hasPolicy1, hasPolicy2 := false, false
 	for name := range queries {
@@ -1873,3 +2039,111 @@ func TestPolicyQueries(t *testing.T) {
 	require.Equal(t, true, *recordedResults[1])
 	require.Nil(t, recordedResults[2])
 }
+
+func TestPolicyQueriesQueueFailingPolicyHost(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	ds := new(mock.Store)
+	lq := new(live_query.MockLiveQuery)
+	svc := newTestServiceWithClock(ds, nil, lq, mockClock)
+
+	host := &fleet.Host{
+		ID:       1,
+		Platform: "darwin",
+	}
+
+	ds.HostFunc = func(ctx context.Context, id uint) (*fleet.Host, error) {
+		return host, nil
+	}
+	ds.SaveHostFunc = func(ctx context.Context, host *fleet.Host) error {
+		return nil
+	}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return &fleet.AppConfig{
+			WebhookSettings: fleet.WebhookSettings{
+				FailingPoliciesWebhook: fleet.FailingPoliciesWebhookSettings{
+					Enable:    true,
+					PolicyIDs: []uint{1, 3},
+				},
+			},
+		}, nil
+	}
+	ds.NewlyFailingPoliciesForHostFunc = func(ctx context.Context, hostID uint, results map[uint]*bool) ([]uint, error) {
+		assert.Equal(t, uint(1), hostID)
+		return []uint{1, 2}, nil
+	}
+	var queuedPolicyIDs []uint
+	ds.QueueFailingPolicyHostFunc = func(ctx context.Context, hostID uint, policyIDs []uint, updated time.Time) error {
+		assert.Equal(t, uint(1), hostID)
+		queuedPolicyIDs = policyIDs
+		return nil
+	}
+	ds.RecordPolicyQueryExecutionsFunc = func(ctx context.Context, host *fleet.Host, results map[uint]*bool, updated time.Time) error {
+		return nil
+	}
+
+	ctx := hostctx.NewContext(context.Background(), *host)
+	err := svc.SubmitDistributedQueryResults(
+		ctx,
+		map[string][]map[string]string{
+			hostPolicyQueryPrefix + "1": {},
+			hostPolicyQueryPrefix + "2": {},
+			hostPolicyQueryPrefix + "3": {{"col1": "val1"}},
+		},
+		map[string]fleet.OsqueryStatus{},
+		map[string]string{},
+	)
+	require.NoError(t, err)
+	assert.True(t, ds.RecordPolicyQueryExecutionsFuncInvoked)
+	// Policy 2 is newly failing but not configured in the webhook.
+	assert.Equal(t, []uint{1}, queuedPolicyIDs)
+}
+
+func TestIngestDistributedQueryUpdatesHostStatus(t *testing.T) {
+	mockClock := clock.NewMockClock()
+	ds := new(mock.Store)
+	rs := pubsub.NewInmemQueryResults()
+	lq := new(live_query.MockLiveQuery)
+	svc := &Service{
+		ds:             ds,
+		resultStore:    rs,
+		liveQueryStore: lq,
+		logger:         log.NewNopLogger(),
+		clock:          mockClock,
+		config:         config.TestConfig(),
+	}
+
+	type hostStatus struct {
+		hostID    uint
+		status    fleet.DistributedQueryExecutionStatus
+		rowsCount uint
+		errMsg    *string
+	}
+	var updates []hostStatus
+	ds.UpdateDistributedQueryCampaignHostStatusFunc = func(
+		ctx context.Context, campaignID, hostID uint, status fleet.DistributedQueryExecutionStatus, rowsCount uint, errMsg *string,
+	) error {
+		assert.Equal(t, uint(42), campaignID)
+		updates = append(updates, hostStatus{hostID, status, rowsCount, errMsg})
+		return nil
+	}
+	ds.DistributedQueryCampaignFunc = func(ctx context.Context, id uint) (*fleet.DistributedQueryCampaign, error) {
+		return &fleet.DistributedQueryCampaign{ID: id, UpdateCreateTimestamps: fleet.UpdateCreateTimestamps{
+			CreateTimestamp: fleet.CreateTimestamp{CreatedAt: mockClock.Now()},
+		}}, nil
+	}
+
+	// The status is updated even if the results are not stored.
+	rows := []map[string]string{{"hour": "10"}, nil, {"hour": "11"}}
+	err := svc.ingestDistributedQuery(context.Background(), fleet.Host{ID: 1}, "fleet_distributed_query_42", rows, false, "")
+	require.Error(t, err)
+	err = svc.ingestDistributedQuery(context.Background(), fleet.Host{ID: 2}, "fleet_distributed_query_42", nil, false, "")
+	require.Error(t, err)
+	err = svc.ingestDistributedQuery(context.Background(), fleet.Host{ID: 3}, "fleet_distributed_query_42", nil, true, "no such table")
+	require.Error(t, err)
+
+	assert.Equal(t, []hostStatus{
+		{1, fleet.ExecutionSucceeded, 2, nil},
+		{2, fleet.ExecutionSucceeded, 0, nil},
+		{3, fleet.ExecutionFailed, 0, ptr.String("no such table")},
+	}, updates)
+}
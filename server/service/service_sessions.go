@@ -3,14 +3,19 @@ package service
 import (
 	"context"
 	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/xml"
 	"net/url"
+	"sort"
 	"time"
 
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/contexts/logging"
 	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/sso"
 	"github.com/go-kit/kit/log/level"
 	"github.com/pkg/errors"
@@ -48,33 +53,32 @@ func (svc *Service) InitiateSSO(ctx context.Context, redirectURL string) (string
 		return "", errors.Wrap(err, "InitiateSSO getting app config")
 	}
 
+	if appConfig.SSOSettings.ProviderType == fleet.SSOProviderOIDC {
+		idpURL, err := svc.oidcProviders.CreateAuthorizationRequest(ctx, svc.oidcSettings(appConfig, redirectURL))
+		if err != nil {
+			return "", errors.Wrap(err, "InitiateSSO creating oidc authorization")
+		}
+		return idpURL, nil
+	}
+
 	metadata, err := svc.getMetadata(appConfig)
 	if err != nil {
 		return "", errors.Wrap(err, "InitiateSSO getting metadata")
 	}
 
-	serverURL := appConfig.ServerSettings.ServerURL
+	entityID, err := ssoEntityID(appConfig)
+	if err != nil {
+		return "", errors.Wrap(err, "InitiateSSO getting entity id")
+	}
 	settings := sso.Settings{
 		Metadata: metadata,
 		// Construct call back url to send to idp
-		AssertionConsumerServiceURL: serverURL + svc.config.Server.URLPrefix + "/api/v1/fleet/sso/callback",
+		AssertionConsumerServiceURL: svc.ssoCallbackURL(appConfig),
 		SessionStore:                svc.ssoSessionStore,
 		OriginalURL:                 redirectURL,
+		SPCertificate:               svc.ssoSPCert,
 	}
-
-	// If issuer is not explicitly set, default to host name.
-	var issuer string
-	entityID := appConfig.SSOSettings.EntityID
-	if entityID == "" {
-		u, err := url.Parse(serverURL)
-		if err != nil {
-			return "", errors.Wrap(err, "parse server url")
-		}
-		issuer = u.Hostname()
-	} else {
-		issuer = entityID
-	}
-	idpURL, err := sso.CreateAuthorizationRequest(&settings, issuer)
+	idpURL, err := sso.CreateAuthorizationRequest(&settings, entityID)
 	if err != nil {
 		return "", errors.Wrap(err, "InitiateSSO creating authorization")
 	}
@@ -82,6 +86,62 @@ func (svc *Service) InitiateSSO(ctx context.Context, redirectURL string) (string
 	return idpURL, nil
 }
 
+// ssoEntityID returns the entity ID of Fleet as a service provider. If the
+// entity ID is not explicitly set, it defaults to the host name of the server.
+func ssoEntityID(appConfig *fleet.AppConfig) (string, error) {
+	if appConfig.SSOSettings.EntityID != "" {
+		return appConfig.SSOSettings.EntityID, nil
+	}
+	u, err := url.Parse(appConfig.ServerSettings.ServerURL)
+	if err != nil {
+		return "", errors.Wrap(err, "parse server url")
+	}
+	if u.Hostname() == "" {
+		return "", errors.New("missing server url")
+	}
+	return u.Hostname(), nil
+}
+
+// ssoCallbackURL returns the URL the identity provider sends its responses to.
+func (svc *Service) ssoCallbackURL(appConfig *fleet.AppConfig) string {
+	return appConfig.ServerSettings.ServerURL + svc.config.Server.URLPrefix + "/api/v1/fleet/sso/callback"
+}
+
+// loadSAMLServiceProviderCert loads the certificate signing the authorization
+// requests, if one is configured.
+func loadSAMLServiceProviderCert(conf config.SAMLConfig) (*tls.Certificate, error) {
+	if conf.SPCert == "" && conf.SPKey == "" {
+		return nil, nil
+	}
+	if conf.SPCert == "" || conf.SPKey == "" {
+		return nil, errors.New("saml.sp_cert and saml.sp_key must be set together")
+	}
+	cert, err := tls.LoadX509KeyPair(conf.SPCert, conf.SPKey)
+	if err != nil {
+		return nil, errors.Wrap(err, "load saml service provider certificate")
+	}
+	if _, ok := cert.PrivateKey.(*rsa.PrivateKey); !ok {
+		return nil, errors.New("saml service provider key must be an RSA key")
+	}
+	return &cert, nil
+}
+
+func (svc *Service) SSOServiceProviderMetadata(ctx context.Context) ([]byte, error) {
+	// skipauth: The identity providers fetch the metadata without
+	// authentication, and it only contains public information.
+	svc.authz.SkipAuthorization(ctx)
+
+	appConfig, err := svc.ds.AppConfig(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "get config for sso metadata")
+	}
+	entityID, err := ssoEntityID(appConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "get sso entity id")
+	}
+	return sso.ServiceProviderMetadata(entityID, svc.ssoCallbackURL(appConfig), svc.ssoSPCert)
+}
+
 func (svc *Service) getMetadata(config *fleet.AppConfig) (*sso.Metadata, error) {
 	if config.SSOSettings.MetadataURL != "" {
 		metadata, err := sso.GetMetadata(config.SSOSettings.MetadataURL)
@@ -145,7 +205,16 @@ func (svc *Service) CallbackSSO(ctx context.Context, auth fleet.Auth) (*fleet.SS
 	}
 
 	// Validate response
-	validator, err := sso.NewValidator(*metadata)
+	entityID, err := ssoEntityID(appConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "get sso entity id")
+	}
+	validator, err := sso.NewValidator(*metadata,
+		sso.Audience(entityID),
+		sso.Recipient(svc.ssoCallbackURL(appConfig)),
+		sso.ClockSkew(svc.config.SAML.ClockSkew),
+		sso.ReplayStore(svc.ssoSessionStore),
+	)
 	if err != nil {
 		return nil, errors.Wrap(err, "create validator from metadata")
 	}
@@ -160,14 +229,53 @@ func (svc *Service) CallbackSSO(ctx context.Context, auth fleet.Auth) (*fleet.SS
 		return nil, errors.Wrap(err, "response validation failed")
 	}
 
-	// Get and log in user
-	user, err := svc.ds.UserByEmail(ctx, auth.UserID())
+	return svc.ssoSession(ctx, appConfig.SSOSettings, auth, redirectURL)
+}
+
+// oidcSettings returns the settings of the OpenID Connect provider.
+func (svc *Service) oidcSettings(appConfig *fleet.AppConfig, originalURL string) *sso.OIDCSettings {
+	return &sso.OIDCSettings{
+		IssuerURL:    appConfig.SSOSettings.OIDCIssuerURL,
+		ClientID:     appConfig.SSOSettings.OIDCClientID,
+		ClientSecret: appConfig.SSOSettings.OIDCClientSecret,
+		RedirectURL:  appConfig.ServerSettings.ServerURL + svc.config.Server.URLPrefix + "/api/v1/fleet/sso/oidc/callback",
+		Scopes:       appConfig.SSOSettings.OIDCScopes,
+		EmailClaim:   appConfig.SSOSettings.OIDCEmailClaim,
+		SessionStore: svc.ssoSessionStore,
+		OriginalURL:  originalURL,
+	}
+}
+
+func (svc *Service) CallbackOIDC(ctx context.Context, callback fleet.OIDCCallback) (*fleet.SSOSession, error) {
+	// skipauth: User context does not yet exist. Unauthenticated users may
+	// hit the OIDC callback.
+	svc.authz.SkipAuthorization(ctx)
+
+	logging.WithLevel(ctx, level.Info)
+
+	if callback.Error != "" {
+		return nil, errors.Errorf("oidc authorization failed: %s %s", callback.Error, callback.ErrorDescription)
+	}
+	appConfig, err := svc.ds.AppConfig(ctx)
 	if err != nil {
-		return nil, errors.Wrap(err, "find user in sso callback")
+		return nil, errors.Wrap(err, "get config for oidc")
 	}
-	// if the user is not sso enabled they are not authorized
-	if !user.SSOEnabled {
-		return nil, errors.New("user not configured to use sso")
+	if appConfig.SSOSettings.ProviderType != fleet.SSOProviderOIDC {
+		return nil, errors.New("oidc sso is not configured")
+	}
+
+	auth, redirectURL, err := svc.oidcProviders.Authorize(ctx, svc.oidcSettings(appConfig, ""), callback.State, callback.Code)
+	if err != nil {
+		return nil, errors.Wrap(err, "oidc authorization failed")
+	}
+	return svc.ssoSession(ctx, appConfig.SSOSettings, auth, redirectURL)
+}
+
+// ssoSession logs in the user authenticated by the IDP.
+func (svc *Service) ssoSession(ctx context.Context, settings fleet.SSOSettings, auth fleet.Auth, redirectURL string) (*fleet.SSOSession, error) {
+	user, err := svc.ssoUser(ctx, settings, auth)
+	if err != nil {
+		return nil, err
 	}
 	token, err := svc.makeSession(ctx, user.ID)
 	if err != nil {
@@ -180,6 +288,97 @@ func (svc *Service) CallbackSSO(ctx context.Context, auth fleet.Auth) (*fleet.SS
 	return result, nil
 }
 
+// ssoUser returns the user of the SSO response. If JIT provisioning is
+// enabled, the user is created if it does not exist yet, and its roles are
+// updated from the groups of the response.
+func (svc *Service) ssoUser(ctx context.Context, settings fleet.SSOSettings, auth fleet.Auth) (*fleet.User, error) {
+	user, err := svc.ds.UserByEmail(ctx, auth.UserID())
+	if err != nil && !(fleet.IsNotFound(err) && settings.EnableJITProvisioning) {
+		return nil, errors.Wrap(err, "find user in sso callback")
+	}
+	// if the user is not sso enabled they are not authorized
+	if user != nil && !user.SSOEnabled {
+		return nil, errors.New("user not configured to use sso")
+	}
+	if !settings.EnableJITProvisioning {
+		return user, nil
+	}
+
+	globalRole, teams, err := ssoRoles(settings, auth.Attributes())
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		if globalRole == nil && len(teams) == 0 {
+			return nil, errors.New("no role mapped to the sso groups of the user")
+		}
+		user, err = svc.newUser(ctx, fleet.UserPayload{
+			Name:       ptr.String(auth.UserID()),
+			Email:      ptr.String(auth.UserID()),
+			SSOEnabled: ptr.Bool(true),
+			GlobalRole: globalRole,
+			Teams:      &teams,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "create user in sso callback")
+		}
+		return user, nil
+	}
+
+	if len(settings.JITTeamIDs) > 0 {
+		// Users with a global role are not managed by JIT provisioning limited
+		// to teams, and only the roles of the other users in these teams are
+		// updated.
+		if user.GlobalRole != nil {
+			return user, nil
+		}
+		globalRole, teams = nil, mergeSSOTeams(user.Teams, teams, settings.JITTeamIDs)
+	}
+	if globalRole == nil && len(teams) == 0 {
+		return nil, errors.New("no role mapped to the sso groups of the user")
+	}
+	user.GlobalRole, user.Teams = globalRole, teams
+	if err := svc.saveUser(ctx, user); err != nil {
+		return nil, errors.Wrap(err, "update user roles in sso callback")
+	}
+	return user, nil
+}
+
+// ssoRoles returns the roles mapped to the groups listed in the JIT role
+// attribute of the SAML response, or in the JIT role claim of the ID token.
+// If JIT provisioning is limited to teams, only the roles in these teams are
+// returned.
+func ssoRoles(settings fleet.SSOSettings, attributes map[string][]string) (*string, []fleet.UserTeam, error) {
+	groupRoles, err := fleet.ParseGroupRoles(settings.JITGroupRoles)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "parse jit group roles")
+	}
+	if len(settings.JITTeamIDs) > 0 {
+		groupRoles = groupRoles.InTeams(settings.JITTeamIDs)
+	}
+	globalRole, teams, _ := groupRoles.Roles(attributes[settings.JITRoleAttribute])
+	return globalRole, teams, nil
+}
+
+// mergeSSOTeams replaces the memberships of the user in the teams teamIDs by
+// the memberships mapped from the SSO groups, keeping the memberships in the
+// other teams.
+func mergeSSOTeams(userTeams, ssoTeams []fleet.UserTeam, teamIDs []uint) []fleet.UserTeam {
+	managed := make(map[uint]bool, len(teamIDs))
+	for _, teamID := range teamIDs {
+		managed[teamID] = true
+	}
+	teams := make([]fleet.UserTeam, 0, len(userTeams)+len(ssoTeams))
+	for _, team := range userTeams {
+		if !managed[team.ID] {
+			teams = append(teams, team)
+		}
+	}
+	teams = append(teams, ssoTeams...)
+	sort.Slice(teams, func(i, j int) bool { return teams[i].ID < teams[j].ID })
+	return teams
+}
+
 func (svc *Service) Login(ctx context.Context, email, password string) (*fleet.User, string, error) {
 	// skipauth: No user context available yet to authorize against.
 	svc.authz.SkipAuthorization(ctx)
@@ -1,10 +1,26 @@
 package service
 
 import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/fleetdm/fleet/v4/server/config"
 	"github.com/fleetdm/fleet/v4/server/datastore/mysql"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/fleetdm/fleet/v4/server/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -52,3 +68,212 @@ func TestAuthenticate(t *testing.T) {
 		})
 	}
 }
+
+type testSSOAuth struct {
+	userID     string
+	attributes map[string][]string
+}
+
+func (a testSSOAuth) UserID() string                  { return a.userID }
+func (a testSSOAuth) RequestID() string               { return "" }
+func (a testSSOAuth) Attributes() map[string][]string { return a.attributes }
+
+func TestSSOUserJITProvisioning(t *testing.T) {
+	ds := new(mock.Store)
+	svc := &Service{ds: ds, config: config.TestConfig()}
+	ctx := context.Background()
+
+	users := map[string]*fleet.User{
+		"password@example.com": {ID: 1, Email: "password@example.com"},
+	}
+	ds.UserByEmailFunc = func(ctx context.Context, email string) (*fleet.User, error) {
+		if user, ok := users[email]; ok {
+			return user, nil
+		}
+		return nil, notFoundError{}
+	}
+	ds.NewUserFunc = func(ctx context.Context, user *fleet.User) (*fleet.User, error) {
+		user.ID = uint(len(users) + 1)
+		users[user.Email] = user
+		return user, nil
+	}
+	ds.SaveUserFunc = func(ctx context.Context, user *fleet.User) error {
+		users[user.Email] = user
+		return nil
+	}
+
+	settings := fleet.SSOSettings{
+		EnableSSO:        true,
+		JITRoleAttribute: "groups",
+		JITGroupRoles:    "fleet-admins=admin;team1-observers=1:observer;team2-maintainers=2:maintainer",
+	}
+	alice := testSSOAuth{userID: "alice@example.com", attributes: map[string][]string{
+		"groups": {"team1-observers", "team2-maintainers", "unmapped"},
+	}}
+
+	// Unknown users are not created without JIT provisioning.
+	_, err := svc.ssoUser(ctx, settings, alice)
+	require.Error(t, err)
+	assert.False(t, ds.NewUserFuncInvoked)
+
+	settings.EnableJITProvisioning = true
+	user, err := svc.ssoUser(ctx, settings, alice)
+	require.NoError(t, err)
+	assert.True(t, ds.NewUserFuncInvoked)
+	assert.Equal(t, "alice@example.com", user.Email)
+	assert.True(t, user.SSOEnabled)
+	assert.Nil(t, user.GlobalRole)
+	assert.Equal(t, []fleet.UserTeam{
+		{Team: fleet.Team{ID: 1}, Role: fleet.RoleObserver},
+		{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer},
+	}, user.Teams)
+
+	// The roles are updated on every login.
+	alice.attributes["groups"] = []string{"fleet-admins"}
+	user, err = svc.ssoUser(ctx, settings, alice)
+	require.NoError(t, err)
+	assert.True(t, ds.SaveUserFuncInvoked)
+	assert.Equal(t, ptr.String(fleet.RoleAdmin), user.GlobalRole)
+	assert.Empty(t, user.Teams)
+
+	// Users without a mapped role are refused.
+	alice.attributes["groups"] = []string{"unmapped"}
+	_, err = svc.ssoUser(ctx, settings, alice)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no role")
+
+	// With JIT provisioning limited to teams, the global admin keeps their
+	// role.
+	settings.JITTeamIDs = []uint{2}
+	ds.SaveUserFuncInvoked = false
+	alice.attributes["groups"] = []string{"fleet-admins", "team1-observers", "team2-maintainers"}
+	user, err = svc.ssoUser(ctx, settings, alice)
+	require.NoError(t, err)
+	assert.False(t, ds.SaveUserFuncInvoked)
+	assert.Equal(t, ptr.String(fleet.RoleAdmin), user.GlobalRole)
+	assert.Empty(t, user.Teams)
+	assert.Equal(t, ptr.String(fleet.RoleAdmin), users["alice@example.com"].GlobalRole)
+
+	// Only the roles in the allowed teams are given to new users.
+	bob := testSSOAuth{userID: "bob@example.com", attributes: map[string][]string{
+		"groups": {"fleet-admins", "team1-observers", "team2-maintainers"},
+	}}
+	user, err = svc.ssoUser(ctx, settings, bob)
+	require.NoError(t, err)
+	assert.Nil(t, user.GlobalRole)
+	assert.Equal(t, []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer}}, user.Teams)
+
+	// The memberships in the other teams are kept.
+	users["bob@example.com"].Teams = []fleet.UserTeam{
+		{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer},
+		{Team: fleet.Team{ID: 3}, Role: fleet.RoleObserver},
+	}
+	bob.attributes["groups"] = []string{"fleet-admins"}
+	user, err = svc.ssoUser(ctx, settings, bob)
+	require.NoError(t, err)
+	assert.Nil(t, user.GlobalRole)
+	assert.Equal(t, []fleet.UserTeam{{Team: fleet.Team{ID: 3}, Role: fleet.RoleObserver}}, user.Teams)
+
+	// Users left without any role are refused.
+	users["bob@example.com"].Teams = []fleet.UserTeam{{Team: fleet.Team{ID: 2}, Role: fleet.RoleMaintainer}}
+	_, err = svc.ssoUser(ctx, settings, bob)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no role")
+	_, err = svc.ssoUser(ctx, settings, testSSOAuth{userID: "carol@example.com", attributes: bob.attributes})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no role")
+
+	// Users that do not use SSO are still refused.
+	_, err = svc.ssoUser(ctx, settings, testSSOAuth{userID: "password@example.com"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not configured to use sso")
+}
+
+func TestSSOServiceProviderMetadata(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(ds, nil, nil)
+
+	appConfig := &fleet.AppConfig{ServerSettings: fleet.ServerSettings{ServerURL: "https://fleet.example.com:8080"}}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appConfig, nil
+	}
+
+	metadata, err := svc.SSOServiceProviderMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, string(metadata), `entityID="fleet.example.com"`)
+	assert.Contains(t, string(metadata), `Location="https://fleet.example.com:8080/api/v1/fleet/sso/callback"`)
+	assert.Contains(t, string(metadata), `AuthnRequestsSigned="false"`)
+
+	appConfig.SSOSettings.EntityID = "fleet"
+	metadata, err = svc.SSOServiceProviderMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, string(metadata), `entityID="fleet"`)
+
+	appConfig.SSOSettings.EntityID = ""
+	appConfig.ServerSettings.ServerURL = ""
+	_, err = svc.SSOServiceProviderMetadata(context.Background())
+	assert.Error(t, err)
+}
+
+func writeTestKeyPair(t *testing.T, dir string, key crypto.Signer, keyPEM *pem.Block) (certPath, keyPath string) {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fleet.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	require.NoError(t, err)
+	certPath, keyPath = filepath.Join(dir, keyPEM.Type+".crt"), filepath.Join(dir, keyPEM.Type+".key")
+	require.NoError(t, ioutil.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, ioutil.WriteFile(keyPath, pem.EncodeToMemory(keyPEM), 0600))
+	return certPath, keyPath
+}
+
+func TestLoadSAMLServiceProviderCert(t *testing.T) {
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	rsaCert, rsaKeyPath := writeTestKeyPair(t, dir, rsaKey, &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	ecDER, err := x509.MarshalECPrivateKey(ecKey)
+	require.NoError(t, err)
+	ecCert, ecKeyPath := writeTestKeyPair(t, dir, ecKey, &pem.Block{Type: "EC PRIVATE KEY", Bytes: ecDER})
+
+	cert, err := loadSAMLServiceProviderCert(config.SAMLConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, cert)
+
+	cert, err = loadSAMLServiceProviderCert(config.SAMLConfig{SPCert: rsaCert, SPKey: rsaKeyPath})
+	require.NoError(t, err)
+	assert.NotNil(t, cert)
+
+	_, err = loadSAMLServiceProviderCert(config.SAMLConfig{SPCert: rsaCert})
+	assert.Error(t, err)
+	_, err = loadSAMLServiceProviderCert(config.SAMLConfig{SPCert: rsaCert, SPKey: ecKeyPath})
+	assert.Error(t, err)
+	_, err = loadSAMLServiceProviderCert(config.SAMLConfig{SPCert: ecCert, SPKey: ecKeyPath})
+	assert.Error(t, err)
+}
+
+func TestCallbackOIDCErrors(t *testing.T) {
+	ds := new(mock.Store)
+	svc := newTestService(ds, nil, nil)
+
+	appConfig := &fleet.AppConfig{}
+	ds.AppConfigFunc = func(ctx context.Context) (*fleet.AppConfig, error) {
+		return appConfig, nil
+	}
+
+	// The provider redirects with an error if the user did not authorize Fleet.
+	_, err := svc.CallbackOIDC(context.Background(), fleet.OIDCCallback{Error: "access_denied"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "access_denied")
+	assert.False(t, ds.AppConfigFuncInvoked)
+
+	// OIDC callbacks are rejected when the IDP is a SAML IDP.
+	_, err = svc.CallbackOIDC(context.Background(), fleet.OIDCCallback{State: "state", Code: "code"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "oidc sso is not configured")
+}
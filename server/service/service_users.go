@@ -74,6 +74,16 @@ func (svc *Service) CreateInitialUser(ctx context.Context, p fleet.UserPayload)
 }
 
 func (svc *Service) newUser(ctx context.Context, p fleet.UserPayload) (*fleet.User, error) {
+	user, err := svc.payloadUser(p)
+	if err != nil {
+		return nil, err
+	}
+	return svc.ds.NewUser(ctx, user)
+}
+
+// payloadUser returns the user of the payload, with a stand-in password for
+// the SSO users.
+func (svc *Service) payloadUser(p fleet.UserPayload) (*fleet.User, error) {
 	var ssoEnabled bool
 	// if user is SSO generate a fake password
 	if (p.SSOInvite != nil && *p.SSOInvite) || (p.SSOEnabled != nil && *p.SSOEnabled) {
@@ -89,10 +99,6 @@ func (svc *Service) newUser(ctx context.Context, p fleet.UserPayload) (*fleet.Us
 		return nil, err
 	}
 	user.SSOEnabled = ssoEnabled
-	user, err = svc.ds.NewUser(ctx, user)
-	if err != nil {
-		return nil, err
-	}
 	return user, nil
 }
 
@@ -281,18 +287,25 @@ func (svc *Service) ChangePassword(ctx context.Context, oldPass, newPass string)
 		return err
 	}
 
-	if vc.User.SSOEnabled {
+	// The roles of the viewer may be restricted by an API token, so that the
+	// user is reloaded to not save them.
+	user, err := svc.ds.UserByID(ctx, vc.UserID())
+	if err != nil {
+		return errors.Wrap(err, "retrieving user")
+	}
+
+	if user.SSOEnabled {
 		return errors.New("change password for single sign on user not allowed")
 	}
-	if err := vc.User.ValidatePassword(newPass); err == nil {
+	if err := user.ValidatePassword(newPass); err == nil {
 		return fleet.NewInvalidArgumentError("new_password", "cannot reuse old password")
 	}
 
-	if err := vc.User.ValidatePassword(oldPass); err != nil {
+	if err := user.ValidatePassword(oldPass); err != nil {
 		return fleet.NewInvalidArgumentError("old_password", "old password does not match")
 	}
 
-	if err := svc.setNewPassword(ctx, vc.User, newPass); err != nil {
+	if err := svc.setNewPassword(ctx, user, newPass); err != nil {
 		return errors.Wrap(err, "setting new password")
 	}
 	return nil
@@ -346,12 +359,17 @@ func (svc *Service) PerformRequiredPasswordReset(ctx context.Context, password s
 	if !ok {
 		return nil, fleet.ErrNoContext
 	}
-	user := vc.User
-
-	if err := svc.authz.Authorize(ctx, user, fleet.ActionWrite); err != nil {
+	if err := svc.authz.Authorize(ctx, vc.User, fleet.ActionWrite); err != nil {
 		return nil, err
 	}
 
+	// The roles of the viewer may be restricted by an API token, so that the
+	// user is reloaded to not save them.
+	user, err := svc.ds.UserByID(ctx, vc.UserID())
+	if err != nil {
+		return nil, errors.Wrap(err, "retrieving user")
+	}
+
 	if user.SSOEnabled {
 		return nil, errors.New("password reset for single sign on user not allowed")
 	}
@@ -365,7 +383,7 @@ func (svc *Service) PerformRequiredPasswordReset(ctx context.Context, password s
 	}
 
 	user.AdminForcedPasswordReset = false
-	err := svc.setNewPassword(ctx, user, password)
+	err = svc.setNewPassword(ctx, user, password)
 	if err != nil {
 		return nil, errors.Wrap(err, "setting new password")
 	}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 
+	"github.com/fleetdm/fleet/v4/server/contexts/viewer"
 	"github.com/fleetdm/fleet/v4/server/fleet"
 )
 
@@ -12,6 +13,9 @@ import (
 
 type listSoftwareRequest struct {
 	TeamID      *uint             `query:"team_id,optional"`
+	MinSeverity string            `query:"min_severity,optional"`
+	Vulnerable  *bool             `query:"vulnerable,optional"`
+	Source      string            `query:"source,optional"`
 	ListOptions fleet.ListOptions `url:"list_options"`
 }
 
@@ -24,17 +28,70 @@ func (r listSoftwareResponse) error() error { return r.Err }
 
 func listSoftwareEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*listSoftwareRequest)
-	resp, err := svc.ListSoftware(ctx, req.TeamID, req.ListOptions)
+	resp, err := svc.ListSoftware(ctx, fleet.SoftwareListOptions{
+		ListOptions:    req.ListOptions,
+		TeamID:         req.TeamID,
+		MinSeverity:    req.MinSeverity,
+		VulnerableOnly: req.Vulnerable != nil && *req.Vulnerable,
+		Source:         req.Source,
+	})
 	if err != nil {
 		return listSoftwareResponse{Err: err}, nil
 	}
 	return listSoftwareResponse{Software: resp}, nil
 }
 
-func (svc Service) ListSoftware(ctx context.Context, teamID *uint, opt fleet.ListOptions) ([]fleet.Software, error) {
+func (svc Service) ListSoftware(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, error) {
 	if err := svc.authz.Authorize(ctx, &fleet.Software{}, fleet.ActionRead); err != nil {
 		return nil, err
 	}
 
-	return svc.ds.ListSoftware(ctx, teamID, opt)
+	if opt.MinSeverity != "" {
+		if _, ok := fleet.MinCVSSScoreForSeverity(opt.MinSeverity); !ok {
+			return nil, fleet.NewInvalidArgumentError("min_severity", "must be one of low, medium, high or critical")
+		}
+	}
+
+	return svc.ds.ListSoftware(ctx, opt)
+}
+
+/////////////////////////////////////////////////////////////////////////////////
+// List hosts by CVE
+/////////////////////////////////////////////////////////////////////////////////
+
+type listHostsByCVERequest struct {
+	CVE         string            `url:"cve"`
+	ListOptions fleet.ListOptions `url:"list_options"`
+}
+
+func listHostsByCVEEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
+	req := request.(*listHostsByCVERequest)
+	hosts, err := svc.ListHostsByCVE(ctx, req.CVE, req.ListOptions)
+	if err != nil {
+		return listHostsResponse{Err: err}, nil
+	}
+
+	hostResponses := make([]HostResponse, len(hosts))
+	for i, host := range hosts {
+		h, err := hostResponseForHost(ctx, svc, host)
+		if err != nil {
+			return listHostsResponse{Err: err}, nil
+		}
+		hostResponses[i] = *h
+	}
+	return listHostsResponse{Hosts: hostResponses}, nil
+}
+
+func (svc Service) ListHostsByCVE(ctx context.Context, cve string, opt fleet.ListOptions) ([]*fleet.Host, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Host{}, fleet.ActionList); err != nil {
+		return nil, err
+	}
+
+	vc, ok := viewer.FromContext(ctx)
+	if !ok {
+		return nil, fleet.ErrNoContext
+	}
+	filter := fleet.TeamFilter{User: vc.User, IncludeObserver: true}
+
+	return svc.ds.ListHostsByCVE(ctx, filter, cve, opt)
 }
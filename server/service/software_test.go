@@ -15,10 +15,8 @@ import (
 func TestService_ListSoftware(t *testing.T) {
 	ds := new(mock.Store)
 
-	var calledWithTeamID *uint
-	var calledWithOpt fleet.ListOptions
-	ds.ListSoftwareFunc = func(ctx context.Context, teamId *uint, opt fleet.ListOptions) ([]fleet.Software, error) {
-		calledWithTeamID = teamId
+	var calledWithOpt fleet.SoftwareListOptions
+	ds.ListSoftwareFunc = func(ctx context.Context, opt fleet.SoftwareListOptions) ([]fleet.Software, error) {
 		calledWithOpt = opt
 		return []fleet.Software{}, nil
 	}
@@ -29,10 +27,42 @@ func TestService_ListSoftware(t *testing.T) {
 	ctx := context.Background()
 	ctx = viewer.NewContext(ctx, viewer.Viewer{User: user})
 
-	_, err := svc.ListSoftware(ctx, ptr.Uint(42), fleet.ListOptions{PerPage: 77, Page: 4})
+	opt := fleet.SoftwareListOptions{
+		ListOptions: fleet.ListOptions{PerPage: 77, Page: 4},
+		TeamID:      ptr.Uint(42),
+		MinSeverity: "high",
+	}
+	_, err := svc.ListSoftware(ctx, opt)
 	require.NoError(t, err)
 
 	assert.True(t, ds.ListSoftwareFuncInvoked)
-	assert.Equal(t, ptr.Uint(42), calledWithTeamID)
-	assert.Equal(t, fleet.ListOptions{PerPage: 77, Page: 4}, calledWithOpt)
+	assert.Equal(t, opt, calledWithOpt)
+
+	ds.ListSoftwareFuncInvoked = false
+	_, err = svc.ListSoftware(ctx, fleet.SoftwareListOptions{MinSeverity: "severe"})
+	require.Error(t, err)
+	assert.False(t, ds.ListSoftwareFuncInvoked)
+}
+
+func TestService_ListHostsByCVE(t *testing.T) {
+	ds := new(mock.Store)
+
+	user := &fleet.User{ID: 3, Email: "foo@bar.com", GlobalRole: ptr.String(fleet.RoleObserver)}
+
+	var calledWithFilter fleet.TeamFilter
+	var calledWithCVE string
+	ds.ListHostsByCVEFunc = func(ctx context.Context, filter fleet.TeamFilter, cve string, opt fleet.ListOptions) ([]*fleet.Host, error) {
+		calledWithFilter = filter
+		calledWithCVE = cve
+		return []*fleet.Host{{ID: 1}}, nil
+	}
+
+	svc := newTestService(ds, nil, nil)
+	ctx := viewer.NewContext(context.Background(), viewer.Viewer{User: user})
+
+	hosts, err := svc.ListHostsByCVE(ctx, "CVE-2021-3449", fleet.ListOptions{})
+	require.NoError(t, err)
+	require.Len(t, hosts, 1)
+	assert.Equal(t, "CVE-2021-3449", calledWithCVE)
+	assert.Equal(t, fleet.TeamFilter{User: user, IncludeObserver: true}, calledWithFilter)
 }
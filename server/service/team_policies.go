@@ -11,8 +11,13 @@ import (
 /////////////////////////////////////////////////////////////////////////////////
 
 type teamPolicyRequest struct {
-	TeamID  uint `url:"team_id"`
-	QueryID uint `json:"query_id"`
+	TeamID      uint   `url:"team_id"`
+	QueryID     *uint  `json:"query_id"`
+	Name        string `json:"name"`
+	Query       string `json:"query"`
+	Description string `json:"description"`
+	Resolution  string `json:"resolution"`
+	Platform    string `json:"platform"`
 }
 
 type teamPolicyResponse struct {
@@ -24,22 +29,34 @@ func (r teamPolicyResponse) error() error { return r.Err }
 
 func teamPolicyEndpoint(ctx context.Context, request interface{}, svc fleet.Service) (interface{}, error) {
 	req := request.(*teamPolicyRequest)
-	resp, err := svc.NewTeamPolicy(ctx, req.TeamID, req.QueryID)
+	resp, err := svc.NewTeamPolicy(ctx, req.TeamID, fleet.PolicyPayload{
+		QueryID:     req.QueryID,
+		Name:        req.Name,
+		Query:       req.Query,
+		Description: req.Description,
+		Resolution:  req.Resolution,
+		Platform:    req.Platform,
+	})
 	if err != nil {
 		return teamPolicyResponse{Err: err}, nil
 	}
 	return teamPolicyResponse{Policy: resp}, nil
 }
 
-func (svc Service) NewTeamPolicy(ctx context.Context, teamID uint, queryID uint) (*fleet.Policy, error) {
-	if err := svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionWrite); err != nil {
+func (svc Service) NewTeamPolicy(ctx context.Context, teamID uint, p fleet.PolicyPayload) (*fleet.Policy, error) {
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{TeamID: &teamID}, fleet.ActionWrite); err != nil {
 		return nil, err
 	}
 	if err := svc.authz.TeamAuthorize(ctx, teamID, fleet.ActionWrite); err != nil {
 		return nil, err
 	}
 
-	return svc.ds.NewTeamPolicy(ctx, teamID, queryID)
+	p, err := svc.populatePolicyPayload(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.ds.NewTeamPolicy(ctx, teamID, p)
 }
 
 /////////////////////////////////////////////////////////////////////////////////
@@ -144,7 +161,7 @@ func deleteTeamPoliciesEndpoint(ctx context.Context, request interface{}, svc fl
 }
 
 func (svc Service) DeleteTeamPolicies(ctx context.Context, teamID uint, ids []uint) ([]uint, error) {
-	if err := svc.authz.Authorize(ctx, &fleet.Policy{}, fleet.ActionWrite); err != nil {
+	if err := svc.authz.Authorize(ctx, &fleet.Policy{TeamID: &teamID}, fleet.ActionWrite); err != nil {
 		return nil, err
 	}
 	if err := svc.authz.TeamAuthorize(ctx, teamID, fleet.ActionWrite); err != nil {
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"net/url"
@@ -34,6 +35,12 @@ func encodeResponse(ctx context.Context, w http.ResponseWriter, response interfa
 		return nil
 	}
 
+	if f, ok := response.(fileResponse); ok {
+		w.Header().Set("Content-Type", f.contentType())
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, f.filename()))
+		return f.writeFile(w)
+	}
+
 	if e, ok := response.(statuser); ok {
 		w.WriteHeader(e.status())
 		if e.status() == http.StatusNoContent {
@@ -52,6 +59,14 @@ type statuser interface {
 	status() int
 }
 
+// fileResponse allows response types to be downloaded as a file instead of
+// being encoded as JSON
+type fileResponse interface {
+	filename() string
+	contentType() string
+	writeFile(w io.Writer) error
+}
+
 // loads a html page
 type htmlPage interface {
 	html() string
@@ -216,6 +231,30 @@ func hostListOptionsFromRequest(r *http.Request) (fleet.HostListOptions, error)
 		hopt.PolicyResponseFilter = v
 	}
 
+	software_id := r.URL.Query().Get("software_id")
+	if software_id != "" {
+		id, err := strconv.Atoi(software_id)
+		if err != nil {
+			return hopt, err
+		}
+		sid := uint(id)
+		hopt.SoftwareIDFilter = &sid
+	}
+
+	hopt.CVEFilter = r.URL.Query().Get("cve")
+	hopt.OSVersionFilter = r.URL.Query().Get("os_version")
+
+	label_ids := r.URL.Query().Get("label_ids")
+	if label_ids != "" {
+		for _, labelID := range strings.Split(label_ids, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(labelID))
+			if err != nil {
+				return hopt, errors.Wrap(err, "parse label_ids")
+			}
+			hopt.LabelIDsFilter = append(hopt.LabelIDsFilter, uint(id))
+		}
+	}
+
 	return hopt, nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/sso"
 	"github.com/pkg/errors"
 )
@@ -71,3 +72,13 @@ func decodeCallbackSSORequest(ctx context.Context, r *http.Request) (interface{}
 	}
 	return authResponse, nil
 }
+
+func decodeCallbackOIDCRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	qry := r.URL.Query()
+	return fleet.OIDCCallback{
+		State:            qry.Get("state"),
+		Code:             qry.Get("code"),
+		Error:            qry.Get("error"),
+		ErrorDescription: qry.Get("error_description"),
+	}, nil
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/ptr"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -111,3 +112,51 @@ func TestListOptionsFromRequest(t *testing.T) {
 		})
 	}
 }
+
+func TestHostListOptionsFromRequest(t *testing.T) {
+	var hostListOptionsTests = []struct {
+		// url string to parse
+		url string
+		// expected host list options
+		hostListOptions fleet.HostListOptions
+		// should cause an error
+		shouldErr bool
+	}{
+		{
+			url:             "/foo?software_id=3",
+			hostListOptions: fleet.HostListOptions{SoftwareIDFilter: ptr.Uint(3)},
+		},
+		{
+			url:             "/foo?cve=CVE-2021-0001&os_version=macOS%2011.6",
+			hostListOptions: fleet.HostListOptions{CVEFilter: "CVE-2021-0001", OSVersionFilter: "macOS 11.6"},
+		},
+		{
+			url:             "/foo?label_ids=1,%202",
+			hostListOptions: fleet.HostListOptions{LabelIDsFilter: []uint{1, 2}},
+		},
+		{
+			url:       "/foo?software_id=foo",
+			shouldErr: true,
+		},
+		{
+			url:       "/foo?label_ids=1,foo",
+			shouldErr: true,
+		},
+	}
+
+	for _, tt := range hostListOptionsTests {
+		t.Run(tt.url, func(t *testing.T) {
+			url, _ := url.Parse(tt.url)
+			req := &http.Request{URL: url}
+			opt, err := hostListOptionsFromRequest(req)
+
+			if tt.shouldErr {
+				assert.NotNil(t, err)
+				return
+			}
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.hostListOptions, opt)
+		})
+	}
+}
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"net/url"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/pkg/errors"
@@ -20,6 +22,9 @@ func (mw validationMiddleware) ModifyAppConfig(ctx context.Context, p []byte) (*
 		return nil, err
 	}
 	validateSSOSettings(appConfig, existing, invalid)
+	if err := validateSSOJITTeams(ctx, mw.ds, appConfig, invalid); err != nil {
+		return nil, err
+	}
 	if invalid.HasErrors() {
 		return nil, invalid
 	}
@@ -27,7 +32,30 @@ func (mw validationMiddleware) ModifyAppConfig(ctx context.Context, p []byte) (*
 }
 
 func validateSSOSettings(p fleet.AppConfig, existing *fleet.AppConfig, invalid *fleet.InvalidArgumentError) {
-	if p.SSOSettings.EnableSSO {
+	providerType := p.SSOSettings.ProviderType
+	if providerType == "" {
+		providerType = existing.SSOSettings.ProviderType
+	}
+	switch providerType {
+	case "", fleet.SSOProviderSAML, fleet.SSOProviderOIDC:
+	default:
+		invalid.Append("provider_type", fmt.Sprintf("must be %s or %s", fleet.SSOProviderSAML, fleet.SSOProviderOIDC))
+	}
+	if p.SSOSettings.OIDCIssuerURL != "" {
+		if u, err := url.Parse(p.SSOSettings.OIDCIssuerURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			invalid.Append("oidc_issuer_url", "must be an http or https URL")
+		}
+	}
+
+	if p.SSOSettings.EnableSSO && providerType == fleet.SSOProviderOIDC {
+		if p.SSOSettings.OIDCIssuerURL == "" && existing.SSOSettings.OIDCIssuerURL == "" {
+			invalid.Append("oidc_issuer_url", "required")
+		}
+		if p.SSOSettings.OIDCClientID == "" && existing.SSOSettings.OIDCClientID == "" {
+			invalid.Append("oidc_client_id", "required")
+		}
+		validateSSOIDPName(p, existing, invalid)
+	} else if p.SSOSettings.EnableSSO {
 		if p.SSOSettings.Metadata == "" && p.SSOSettings.MetadataURL == "" {
 			if existing.SSOSettings.Metadata == "" && existing.SSOSettings.MetadataURL == "" {
 				invalid.Append("metadata", "either metadata or metadata_url must be defined")
@@ -45,14 +73,67 @@ func validateSSOSettings(p fleet.AppConfig, existing *fleet.AppConfig, invalid *
 				invalid.Append("entity_id", "must be 5 or more characters")
 			}
 		}
-		if p.SSOSettings.IDPName == "" {
-			if existing.SSOSettings.IDPName == "" {
-				invalid.Append("idp_name", "required")
-			}
-		} else {
-			if len(p.SSOSettings.IDPName) < 4 {
-				invalid.Append("idp_name", "must be 4 or more characters")
+		validateSSOIDPName(p, existing, invalid)
+	}
+	if p.SSOSettings.JITGroupRoles != "" {
+		if _, err := fleet.ParseGroupRoles(p.SSOSettings.JITGroupRoles); err != nil {
+			invalid.Append("jit_group_roles", err.Error())
+		}
+	}
+	if p.SSOSettings.EnableJITProvisioning {
+		if p.SSOSettings.JITRoleAttribute == "" && existing.SSOSettings.JITRoleAttribute == "" {
+			invalid.Append("jit_role_attribute", "required")
+		}
+		if p.SSOSettings.JITGroupRoles == "" && existing.SSOSettings.JITGroupRoles == "" {
+			invalid.Append("jit_group_roles", "required")
+		}
+	}
+}
+
+func validateSSOIDPName(p fleet.AppConfig, existing *fleet.AppConfig, invalid *fleet.InvalidArgumentError) {
+	if p.SSOSettings.IDPName == "" {
+		if existing.SSOSettings.IDPName == "" {
+			invalid.Append("idp_name", "required")
+		}
+	} else {
+		if len(p.SSOSettings.IDPName) < 4 {
+			invalid.Append("idp_name", "must be 4 or more characters")
+		}
+	}
+}
+
+// validateSSOJITTeams checks that the teams of the JIT provisioning settings
+// exist.
+func validateSSOJITTeams(ctx context.Context, ds fleet.Datastore, p fleet.AppConfig, invalid *fleet.InvalidArgumentError) error {
+	type teamField struct {
+		name   string
+		teamID uint
+	}
+	var teams []teamField
+	for _, teamID := range p.SSOSettings.JITTeamIDs {
+		teams = append(teams, teamField{"jit_team_ids", teamID})
+	}
+	// Invalid group roles are reported by validateSSOSettings.
+	groupRoles, _ := fleet.ParseGroupRoles(p.SSOSettings.JITGroupRoles)
+	for _, groupRole := range groupRoles {
+		if groupRole.TeamID != nil {
+			teams = append(teams, teamField{"jit_group_roles", *groupRole.TeamID})
+		}
+	}
+
+	checked := make(map[teamField]bool)
+	for _, team := range teams {
+		if checked[team] {
+			continue
+		}
+		checked[team] = true
+		if _, err := ds.Team(ctx, team.teamID); err != nil {
+			if fleet.IsNotFound(err) {
+				invalid.Appendf(team.name, "team %d does not exist", team.teamID)
+				continue
 			}
+			return errors.Wrap(err, "fetching team in validation")
 		}
 	}
+	return nil
 }
@@ -1,9 +1,11 @@
 package service
 
 import (
+	"context"
 	"testing"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -46,3 +48,91 @@ func TestMissingMetadata(t *testing.T) {
 	assert.Contains(t, invalid.Error(), "metadata")
 	assert.Contains(t, invalid.Error(), "either metadata or metadata_url must be defined")
 }
+
+func TestSSOJITSettings(t *testing.T) {
+	invalid := &fleet.InvalidArgumentError{}
+	config := fleet.AppConfig{
+		SSOSettings: fleet.SSOSettings{
+			EnableJITProvisioning: true,
+			JITGroupRoles:         "admins=superuser",
+		},
+	}
+	validateSSOSettings(config, &fleet.AppConfig{}, invalid)
+	assert.ElementsMatch(t, []map[string]string{
+		{"name": "jit_group_roles", "reason": `invalid global role "superuser" for group "admins"`},
+		{"name": "jit_role_attribute", "reason": "required"},
+	}, invalid.Invalid())
+
+	invalid = &fleet.InvalidArgumentError{}
+	config.SSOSettings.JITGroupRoles = ""
+	existing := &fleet.AppConfig{
+		SSOSettings: fleet.SSOSettings{
+			JITRoleAttribute: "groups",
+			JITGroupRoles:    "admins=admin",
+		},
+	}
+	validateSSOSettings(config, existing, invalid)
+	assert.False(t, invalid.HasErrors())
+}
+
+func TestSSOOIDCSettings(t *testing.T) {
+	invalid := &fleet.InvalidArgumentError{}
+	config := fleet.AppConfig{
+		SSOSettings: fleet.SSOSettings{
+			EnableSSO:     true,
+			ProviderType:  fleet.SSOProviderOIDC,
+			OIDCIssuerURL: "idp.example.com",
+		},
+	}
+	validateSSOSettings(config, &fleet.AppConfig{}, invalid)
+	assert.ElementsMatch(t, []map[string]string{
+		{"name": "oidc_issuer_url", "reason": "must be an http or https URL"},
+		{"name": "oidc_client_id", "reason": "required"},
+		{"name": "idp_name", "reason": "required"},
+	}, invalid.Invalid())
+
+	// The SAML settings are not required, and the provider type may already
+	// be set.
+	invalid = &fleet.InvalidArgumentError{}
+	config.SSOSettings = fleet.SSOSettings{
+		EnableSSO:     true,
+		IDPName:       "Example IDP",
+		OIDCIssuerURL: "https://idp.example.com",
+		OIDCClientID:  "fleet",
+	}
+	existing := &fleet.AppConfig{SSOSettings: fleet.SSOSettings{ProviderType: fleet.SSOProviderOIDC}}
+	validateSSOSettings(config, existing, invalid)
+	assert.False(t, invalid.HasErrors())
+
+	invalid = &fleet.InvalidArgumentError{}
+	config.SSOSettings.ProviderType = "oauth"
+	validateSSOSettings(config, existing, invalid)
+	assert.ElementsMatch(t, []map[string]string{
+		{"name": "provider_type", "reason": "must be saml or oidc"},
+		{"name": "metadata", "reason": "either metadata or metadata_url must be defined"},
+		{"name": "entity_id", "reason": "required"},
+	}, invalid.Invalid())
+}
+
+func TestSSOJITTeams(t *testing.T) {
+	ds := new(mock.Store)
+	ds.TeamFunc = func(ctx context.Context, tid uint) (*fleet.Team, error) {
+		if tid == 1 {
+			return &fleet.Team{ID: 1}, nil
+		}
+		return nil, notFoundError{}
+	}
+
+	invalid := &fleet.InvalidArgumentError{}
+	config := fleet.AppConfig{
+		SSOSettings: fleet.SSOSettings{
+			JITGroupRoles: "team1=1:observer;team3=3:observer",
+			JITTeamIDs:    []uint{1, 2},
+		},
+	}
+	require.NoError(t, validateSSOJITTeams(context.Background(), ds, config, invalid))
+	assert.ElementsMatch(t, []map[string]string{
+		{"name": "jit_team_ids", "reason": "team 2 does not exist"},
+		{"name": "jit_group_roles", "reason": "team 3 does not exist"},
+	}, invalid.Invalid())
+}
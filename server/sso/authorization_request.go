@@ -3,12 +3,14 @@ package sso
 import (
 	"bytes"
 	"compress/flate"
+	"crypto/tls"
 	"encoding/base64"
 	"encoding/xml"
 	"net/url"
 	"time"
 
 	"github.com/pkg/errors"
+	dsig "github.com/russellhaering/goxmldsig"
 )
 
 const (
@@ -94,14 +96,41 @@ func CreateAuthorizationRequest(settings *Settings, issuer string, options ...fu
 	if err != nil {
 		return "", errors.Wrap(err, "unable to compress auth info")
 	}
-	qry.Set("SAMLRequest", authQueryVal)
+	// The signature covers the parameters in the order of the binding, which
+	// url.Values would not keep.
+	for _, param := range []string{"SAMLRequest", "RelayState", "SigAlg", "Signature"} {
+		qry.Del(param)
+	}
+	params := "SAMLRequest=" + url.QueryEscape(authQueryVal)
 	if optionalParams.relayState != "" {
-		qry.Set("RelayState", optionalParams.relayState)
+		params += "&RelayState=" + url.QueryEscape(optionalParams.relayState)
+	}
+	if settings.SPCertificate != nil {
+		params, err = signRequestParams(params, settings.SPCertificate)
+		if err != nil {
+			return "", errors.Wrap(err, "signing auth request")
+		}
+	}
+	if len(qry) > 0 {
+		params = qry.Encode() + "&" + params
 	}
-	u.RawQuery = qry.Encode()
+	u.RawQuery = params
 	return u.String(), nil
 }
 
+// signRequestParams appends the signature of the parameters with the private
+// key of the certificate.
+// See http://docs.oasis-open.org/security/saml/v2.0/saml-bindings-2.0-os.pdf Section 3.4.4.1
+func signRequestParams(params string, cert *tls.Certificate) (string, error) {
+	signingContext := dsig.NewDefaultSigningContext(dsig.TLSCertKeyStore(*cert))
+	params += "&SigAlg=" + url.QueryEscape(signingContext.GetSignatureMethodIdentifier())
+	signature, err := signingContext.SignString(params)
+	if err != nil {
+		return "", err
+	}
+	return params + "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(signature)), nil
+}
+
 func getDestinationURL(settings *Settings) (string, error) {
 	for _, sso := range settings.Metadata.IDPSSODescriptor.SingleSignOnService {
 		if sso.Binding == RedirectBinding {
@@ -2,6 +2,13 @@ package sso
 
 import (
 	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,3 +23,68 @@ func TestRequestCompression(t *testing.T) {
 	require.Nil(t, err)
 	assert.Equal(t, expected, compressed)
 }
+
+type fakeRequestStore struct {
+	SessionStore
+	created []string
+}
+
+func (s *fakeRequestStore) create(requestID, originalURL, metadata string, lifetimeSecs uint) error {
+	s.created = append(s.created, requestID)
+	return nil
+}
+
+func TestCreateSignedAuthorizationRequest(t *testing.T) {
+	spCert := testCertificate(t)
+	metadata := testIDPMetadata(t, testCertificate(t))
+	metadata.IDPSSODescriptor.SingleSignOnService[0].Location = "https://idp.example.com/sso?tenant=fleet"
+	store := &fakeRequestStore{}
+	settings := &Settings{
+		Metadata:                    &metadata,
+		AssertionConsumerServiceURL: "https://fleet.example.com/api/v1/fleet/sso/callback",
+		SessionStore:                store,
+		OriginalURL:                 "/",
+		SPCertificate:               spCert,
+	}
+
+	idpURL, err := CreateAuthorizationRequest(settings, "fleet.example.com", RelayState("state"))
+	require.NoError(t, err)
+	require.Len(t, store.created, 1)
+
+	u, err := url.Parse(idpURL)
+	require.NoError(t, err)
+	assert.Equal(t, "fleet", u.Query().Get("tenant"))
+	assert.Equal(t, "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256", u.Query().Get("SigAlg"))
+
+	// The signature covers the parameters of the binding in their order.
+	i := strings.Index(u.RawQuery, "SAMLRequest=")
+	j := strings.Index(u.RawQuery, "&Signature=")
+	require.True(t, i >= 0 && j > i)
+	signed := u.RawQuery[i:j]
+	assert.True(t, strings.HasPrefix(signed, "SAMLRequest="))
+	assert.Contains(t, signed, "&RelayState=state&SigAlg=")
+
+	signature, err := base64.StdEncoding.DecodeString(u.Query().Get("Signature"))
+	require.NoError(t, err)
+	digest := sha256.Sum256([]byte(signed))
+	cert, err := x509.ParseCertificate(spCert.Certificate[0])
+	require.NoError(t, err)
+	assert.NoError(t, rsa.VerifyPKCS1v15(cert.PublicKey.(*rsa.PublicKey), crypto.SHA256, digest[:], signature))
+}
+
+func TestCreateUnsignedAuthorizationRequest(t *testing.T) {
+	metadata := testIDPMetadata(t, testCertificate(t))
+	settings := &Settings{
+		Metadata:                    &metadata,
+		AssertionConsumerServiceURL: "https://fleet.example.com/api/v1/fleet/sso/callback",
+		SessionStore:                &fakeRequestStore{},
+	}
+
+	idpURL, err := CreateAuthorizationRequest(settings, "fleet.example.com")
+	require.NoError(t, err)
+	u, err := url.Parse(idpURL)
+	require.NoError(t, err)
+	assert.NotEmpty(t, u.Query().Get("SAMLRequest"))
+	assert.Empty(t, u.Query().Get("SigAlg"))
+	assert.Empty(t, u.Query().Get("Signature"))
+}
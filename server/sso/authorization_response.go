@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"encoding/base64"
 	"encoding/xml"
+	"html"
+	"strings"
 
 	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/pkg/errors"
@@ -85,6 +87,21 @@ func (r resp) UserID() string {
 	return ""
 }
 
+// Attributes returns the values of the attributes of the assertion by
+// attribute name.
+func (r resp) Attributes() map[string][]string {
+	attributes := make(map[string][]string)
+	if r.response == nil {
+		return attributes
+	}
+	for _, attribute := range r.response.Assertion.AttributeStatement.Attributes {
+		for _, value := range attribute.AttributeValues {
+			attributes[attribute.Name] = append(attributes[attribute.Name], html.UnescapeString(strings.TrimSpace(value.Value)))
+		}
+	}
+	return attributes
+}
+
 func (r resp) status() (int, error) {
 	if r.response != nil {
 		statusURI := r.response.Status.StatusCode.Value
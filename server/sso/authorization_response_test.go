@@ -1,6 +1,7 @@
 package sso
 
 import (
+	"encoding/base64"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,6 +19,12 @@ func TestDecodeSuccessfulSalesforceResponse(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, Success, status)
 	assert.Equal(t, "john@kolide.co", auth.UserID())
+	assert.Equal(t, map[string][]string{
+		"userId":         {"0056A000000Q6Rl"},
+		"username":       {"john@kolide.co"},
+		"email":          {"john@kolide.co"},
+		"is_portal_user": {"false"},
+	}, auth.Attributes())
 }
 
 func TestDecodeWithCommentInName(t *testing.T) {
@@ -50,5 +57,23 @@ func TestDecodeSuccessfulGoogleResponse(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, Success, status)
 	assert.Equal(t, "john@edilok.net", auth.UserID())
+	assert.Equal(t, map[string][]string{"myattribute": {"john@edilok.net"}}, auth.Attributes())
+}
 
+func TestDecodeMultiValuedAttributes(t *testing.T) {
+	samlResponse := base64.StdEncoding.EncodeToString([]byte(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion">
+<saml:Assertion>
+<saml:Subject><saml:NameID>john@example.com</saml:NameID></saml:Subject>
+<saml:AttributeStatement>
+<saml:Attribute Name="groups">
+<saml:AttributeValue> fleet-admins </saml:AttributeValue>
+<saml:AttributeValue>R&amp;D</saml:AttributeValue>
+</saml:Attribute>
+</saml:AttributeStatement>
+</saml:Assertion>
+</samlp:Response>`))
+	auth, err := DecodeAuthResponse(samlResponse)
+	require.NoError(t, err)
+	assert.Equal(t, "john@example.com", auth.UserID())
+	assert.Equal(t, map[string][]string{"groups": {"fleet-admins", "R&D"}}, auth.Attributes())
 }
@@ -0,0 +1,523 @@
+package sso
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pkg/errors"
+)
+
+const (
+	// oidcDiscoveryLifetime is how long the configuration of a provider is
+	// cached.
+	oidcDiscoveryLifetime = time.Hour
+	// oidcKeysLifetime is how long the signing keys of a provider are cached.
+	oidcKeysLifetime = time.Hour
+	// oidcKeysMinRefresh limits how often the signing keys are fetched again
+	// when a token is signed by an unknown key.
+	oidcKeysMinRefresh = time.Minute
+	// oidcClockSkew is the allowed difference between the clocks of the
+	// provider and of Fleet when checking the validity of ID tokens.
+	oidcClockSkew = 3 * time.Minute
+	// oidcSessionPrefix separates the OIDC requests from the SAML requests in
+	// the session store.
+	oidcSessionPrefix = "oidc:"
+)
+
+// OIDCSettings contains the information needed to sign in with an OpenID
+// Connect provider.
+type OIDCSettings struct {
+	// IssuerURL identifies the provider, and its configuration is discovered
+	// from it.
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is the call back on Fleet which receives the authorization
+	// code.
+	RedirectURL string
+	// Scopes are requested in addition to the openid, email and profile
+	// scopes.
+	Scopes []string
+	// EmailClaim is the ID token claim holding the email of the user, "email"
+	// if empty.
+	EmailClaim   string
+	SessionStore SessionStore
+	OriginalURL  string
+}
+
+// oidcProviderConfig is the configuration of a provider, from its discovery
+// document.
+// See https://openid.net/specs/openid-connect-discovery-1_0.html Section 3
+type oidcProviderConfig struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	JWKSURI                       string   `json:"jwks_uri"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+type oidcProvider struct {
+	config       oidcProviderConfig
+	discoveredAt time.Time
+
+	mu            sync.Mutex
+	keys          map[string]interface{}
+	keysFetchedAt time.Time
+}
+
+// oidcRequest is stored in the session store while the user authenticates
+// with the provider.
+type oidcRequest struct {
+	CodeVerifier string `json:"code_verifier"`
+	Nonce        string `json:"nonce"`
+}
+
+// OIDCProviders signs users in with OpenID Connect providers, using the
+// authorization code flow with PKCE. The configuration and the signing keys
+// of the providers are cached.
+type OIDCProviders struct {
+	client *http.Client
+	now    func() time.Time
+
+	mu        sync.Mutex
+	providers map[string]*oidcProvider
+}
+
+// NewOIDCProviders creates an OIDCProviders that uses client to call the
+// providers.
+func NewOIDCProviders(client *http.Client) *OIDCProviders {
+	return &OIDCProviders{
+		client:    client,
+		now:       time.Now,
+		providers: make(map[string]*oidcProvider),
+	}
+}
+
+// provider returns the provider of the issuer, discovering it if it is not
+// cached.
+func (p *OIDCProviders) provider(ctx context.Context, issuerURL string) (*oidcProvider, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if provider, ok := p.providers[issuerURL]; ok && p.now().Sub(provider.discoveredAt) < oidcDiscoveryLifetime {
+		return provider, nil
+	}
+
+	var config oidcProviderConfig
+	discoveryURL := strings.TrimSuffix(issuerURL, "/") + "/.well-known/openid-configuration"
+	if err := p.getJSON(ctx, discoveryURL, &config); err != nil {
+		return nil, errors.Wrap(err, "discover oidc provider")
+	}
+	if config.Issuer != issuerURL {
+		return nil, errors.Errorf("oidc provider issuer %s does not match %s", config.Issuer, issuerURL)
+	}
+	if config.AuthorizationEndpoint == "" || config.TokenEndpoint == "" || config.JWKSURI == "" {
+		return nil, errors.New("incomplete oidc provider configuration")
+	}
+	if len(config.CodeChallengeMethodsSupported) > 0 && !containsString(config.CodeChallengeMethodsSupported, "S256") {
+		return nil, errors.New("oidc provider does not support PKCE with S256")
+	}
+	provider := &oidcProvider{config: config, discoveredAt: p.now()}
+	p.providers[issuerURL] = provider
+	return provider, nil
+}
+
+func (p *OIDCProviders) getJSON(ctx context.Context, u string, v interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("%s returned %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// CreateAuthorizationRequest returns the URL that starts the authorization
+// of the user with the provider. The PKCE code verifier and the nonce of the
+// request are kept in the session store under the state of the request.
+// See https://openid.net/specs/openid-connect-core-1_0.html Section 3.1.2.1
+// and https://datatracker.ietf.org/doc/html/rfc7636 Section 4
+func (p *OIDCProviders) CreateAuthorizationRequest(ctx context.Context, settings *OIDCSettings) (string, error) {
+	provider, err := p.provider(ctx, settings.IssuerURL)
+	if err != nil {
+		return "", err
+	}
+	state, err := randomOIDCValue()
+	if err != nil {
+		return "", errors.Wrap(err, "generate state")
+	}
+	request := oidcRequest{}
+	if request.CodeVerifier, err = randomOIDCValue(); err != nil {
+		return "", errors.Wrap(err, "generate code verifier")
+	}
+	if request.Nonce, err = randomOIDCValue(); err != nil {
+		return "", errors.Wrap(err, "generate nonce")
+	}
+	encoded, err := json.Marshal(request)
+	if err != nil {
+		return "", errors.Wrap(err, "encode oidc request")
+	}
+	err = settings.SessionStore.create(oidcSessionPrefix+state, settings.OriginalURL, string(encoded), cacheLifetime)
+	if err != nil {
+		return "", errors.Wrap(err, "caching oidc request")
+	}
+
+	u, err := url.Parse(provider.config.AuthorizationEndpoint)
+	if err != nil {
+		return "", errors.Wrap(err, "parsing authorization endpoint")
+	}
+	challenge := sha256.Sum256([]byte(request.CodeVerifier))
+	qry := u.Query()
+	qry.Set("response_type", "code")
+	qry.Set("client_id", settings.ClientID)
+	qry.Set("redirect_uri", settings.RedirectURL)
+	qry.Set("scope", strings.Join(append([]string{"openid", "email", "profile"}, settings.Scopes...), " "))
+	qry.Set("state", state)
+	qry.Set("nonce", request.Nonce)
+	qry.Set("code_challenge", base64.RawURLEncoding.EncodeToString(challenge[:]))
+	qry.Set("code_challenge_method", "S256")
+	u.RawQuery = qry.Encode()
+	return u.String(), nil
+}
+
+// randomOIDCValue returns a random URL-safe value, used as state, nonce and
+// code verifier.
+func randomOIDCValue() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Authorize exchanges the authorization code returned with the state for an
+// ID token, and validates it. It returns the authenticated user and the URL
+// the user originally accessed. Each request can only be authorized once.
+// See https://openid.net/specs/openid-connect-core-1_0.html Section 3.1.3
+func (p *OIDCProviders) Authorize(ctx context.Context, settings *OIDCSettings, state, code string) (fleet.Auth, string, error) {
+	if state == "" || code == "" {
+		return nil, "", errors.New("missing state or code")
+	}
+	session, err := settings.SessionStore.Get(oidcSessionPrefix + state)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "oidc request invalid")
+	}
+	// Remove session so that it can't be reused before it expires.
+	if err := settings.SessionStore.Expire(oidcSessionPrefix + state); err != nil {
+		return nil, "", errors.Wrap(err, "remove oidc request")
+	}
+	var request oidcRequest
+	if err := json.Unmarshal([]byte(session.Metadata), &request); err != nil {
+		return nil, "", errors.Wrap(err, "decode oidc request")
+	}
+
+	provider, err := p.provider(ctx, settings.IssuerURL)
+	if err != nil {
+		return nil, "", err
+	}
+	rawIDToken, err := p.exchange(ctx, provider, settings, code, request.CodeVerifier)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "exchange authorization code")
+	}
+	claims, err := p.verifyIDToken(ctx, provider, settings.ClientID, request.Nonce, rawIDToken)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "id token validation failed")
+	}
+
+	emailClaim := settings.EmailClaim
+	if emailClaim == "" {
+		emailClaim = "email"
+	}
+	email, _ := claims[emailClaim].(string)
+	if email == "" {
+		return nil, "", errors.Errorf("missing %s claim in id token", emailClaim)
+	}
+	if verified, ok := claims["email_verified"].(bool); ok && !verified && emailClaim == "email" {
+		return nil, "", errors.New("email of the user is not verified")
+	}
+	return &oidcAuth{state: state, email: email, claims: claims}, session.OriginalURL, nil
+}
+
+type oidcTokenResponse struct {
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// exchange returns the ID token for the authorization code.
+// See https://openid.net/specs/openid-connect-core-1_0.html Section 3.1.3.1
+func (p *OIDCProviders) exchange(ctx context.Context, provider *oidcProvider, settings *OIDCSettings, code, codeVerifier string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {settings.RedirectURL},
+		"code_verifier": {codeVerifier},
+		"client_id":     {settings.ClientID},
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.config.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+	if settings.ClientSecret != "" {
+		request.SetBasicAuth(url.QueryEscape(settings.ClientID), url.QueryEscape(settings.ClientSecret))
+	}
+	resp, err := p.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", errors.Wrap(err, "read token response")
+	}
+
+	var token oidcTokenResponse
+	if err := json.Unmarshal(body, &token); err != nil {
+		return "", errors.Errorf("token endpoint returned %s", resp.Status)
+	}
+	if token.Error != "" {
+		return "", errors.Errorf("token endpoint returned %s: %s", token.Error, token.ErrorDescription)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.Errorf("token endpoint returned %s", resp.Status)
+	}
+	if token.IDToken == "" {
+		return "", errors.New("missing id token")
+	}
+	return token.IDToken, nil
+}
+
+// verifyIDToken validates the signature and the claims of the ID token.
+// See https://openid.net/specs/openid-connect-core-1_0.html Section 3.1.3.7
+func (p *OIDCProviders) verifyIDToken(ctx context.Context, provider *oidcProvider, clientID, nonce, rawIDToken string) (jwt.MapClaims, error) {
+	parser := jwt.Parser{
+		ValidMethods: []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"},
+		// The time claims are validated below with the clock skew.
+		SkipClaimsValidation: true,
+	}
+	claims := jwt.MapClaims{}
+	_, err := parser.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, provider, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if iss, _ := claims["iss"].(string); iss != provider.config.Issuer {
+		return nil, errors.Errorf("unexpected issuer %s", iss)
+	}
+	audiences := claimStrings(claims["aud"])
+	if !containsString(audiences, clientID) {
+		return nil, errors.New("id token is not intended for fleet")
+	}
+	if azp, ok := claims["azp"].(string); (ok || len(audiences) > 1) && azp != clientID {
+		return nil, errors.Errorf("unexpected authorized party %s", azp)
+	}
+	if tokenNonce, _ := claims["nonce"].(string); tokenNonce != nonce {
+		return nil, errors.New("id token does not match the request")
+	}
+	now := p.now()
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return nil, errors.New("missing exp claim")
+	}
+	if now.After(time.Unix(int64(exp), 0).Add(oidcClockSkew)) {
+		return nil, errors.New("id token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(time.Unix(int64(nbf), 0).Add(-oidcClockSkew)) {
+		return nil, errors.New("id token used before nbf")
+	}
+	if iat, ok := claims["iat"].(float64); ok && now.Before(time.Unix(int64(iat), 0).Add(-oidcClockSkew)) {
+		return nil, errors.New("id token issued in the future")
+	}
+	return claims, nil
+}
+
+// signingKey returns the key kid of the provider. The keys are fetched
+// again if the key is unknown, as the provider may have rotated its keys.
+func (p *OIDCProviders) signingKey(ctx context.Context, provider *oidcProvider, kid string) (interface{}, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	now := p.now()
+	if provider.keys == nil || now.Sub(provider.keysFetchedAt) >= oidcKeysLifetime {
+		if err := p.fetchKeys(ctx, provider); err != nil {
+			return nil, err
+		}
+	}
+	key, ok := lookupKey(provider.keys, kid)
+	if !ok && now.Sub(provider.keysFetchedAt) >= oidcKeysMinRefresh {
+		if err := p.fetchKeys(ctx, provider); err != nil {
+			return nil, err
+		}
+		key, ok = lookupKey(provider.keys, kid)
+	}
+	if !ok {
+		return nil, errors.Errorf("unknown signing key %q", kid)
+	}
+	return key, nil
+}
+
+func lookupKey(keys map[string]interface{}, kid string) (interface{}, bool) {
+	if kid == "" && len(keys) == 1 {
+		// Tokens may omit the key ID if the provider only has one key.
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// jsonWebKey is a public key of a JSON Web Key Set.
+// See https://datatracker.ietf.org/doc/html/rfc7517 Section 4
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (p *OIDCProviders) fetchKeys(ctx context.Context, provider *oidcProvider) error {
+	var jwks struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := p.getJSON(ctx, provider.config.JWKSURI, &jwks); err != nil {
+		return errors.Wrap(err, "fetch oidc provider keys")
+	}
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use == "enc" {
+			// Encryption keys cannot verify signatures.
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			// Skip the keys of unsupported types.
+			continue
+		}
+		keys[jwk.Kid] = key
+	}
+	provider.keys = keys
+	provider.keysFetchedAt = p.now()
+	return nil
+}
+
+func (k jsonWebKey) publicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode x")
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, errors.Wrap(err, "decode y")
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.New("invalid ec key")
+		}
+		return key, nil
+	default:
+		return nil, errors.Errorf("unsupported key type %s", k.Kty)
+	}
+}
+
+// oidcAuth is the user authenticated by an OIDC provider.
+type oidcAuth struct {
+	state  string
+	email  string
+	claims jwt.MapClaims
+}
+
+func (a *oidcAuth) UserID() string { return a.email }
+
+func (a *oidcAuth) RequestID() string { return a.state }
+
+// Attributes returns the string claims of the ID token, such as the groups
+// of the user.
+func (a *oidcAuth) Attributes() map[string][]string {
+	attributes := make(map[string][]string)
+	for name, value := range a.claims {
+		if values := claimStrings(value); len(values) > 0 {
+			attributes[name] = values
+		}
+	}
+	return attributes
+}
+
+// claimStrings returns the values of a claim that is either a string or an
+// array of strings.
+func claimStrings(claim interface{}) []string {
+	switch v := claim.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		var values []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,342 @@
+package sso
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSessionStore struct {
+	sessions map[string]*Session
+}
+
+func (s *fakeSessionStore) create(requestID, originalURL, metadata string, lifetimeSecs uint) error {
+	s.sessions[requestID] = &Session{OriginalURL: originalURL, Metadata: metadata}
+	return nil
+}
+
+func (s *fakeSessionStore) Get(requestID string) (*Session, error) {
+	sess, ok := s.sessions[requestID]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return sess, nil
+}
+
+func (s *fakeSessionStore) Expire(requestID string) error {
+	if _, ok := s.sessions[requestID]; !ok {
+		return ErrSessionNotFound
+	}
+	delete(s.sessions, requestID)
+	return nil
+}
+
+func (s *fakeSessionStore) markAssertionUsed(assertionID string, lifetimeSecs uint) error {
+	return nil
+}
+
+type fakeOIDCCode struct {
+	challenge string
+	nonce     string
+}
+
+// fakeOIDCProvider is an in-process OpenID Connect provider.
+type fakeOIDCProvider struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+	// claims override the claims of the ID tokens, and nil values remove
+	// them.
+	claims map[string]interface{}
+	codes  map[string]fakeOIDCCode
+
+	discoveryRequests int
+	jwksRequests      int
+}
+
+const (
+	testOIDCClientID     = "fleet-client"
+	testOIDCClientSecret = "fleet-secret"
+	testOIDCRedirectURL  = "https://fleet.example.com/api/v1/fleet/sso/oidc/callback"
+)
+
+func newFakeOIDCProvider(t *testing.T) *fakeOIDCProvider {
+	p := &fakeOIDCProvider{t: t, codes: make(map[string]fakeOIDCCode)}
+	p.rotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		p.discoveryRequests++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                           p.server.URL,
+			"authorization_endpoint":           p.server.URL + "/authorize?tenant=fleet",
+			"token_endpoint":                   p.server.URL + "/token",
+			"jwks_uri":                         p.server.URL + "/jwks",
+			"code_challenge_methods_supported": []string{"plain", "S256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.jwksRequests++
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"kid": p.kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+			}},
+		})
+	})
+	mux.HandleFunc("/token", p.token)
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *fakeOIDCProvider) rotateKey() {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(p.t, err)
+	p.key = key
+	p.kid = base64.RawURLEncoding.EncodeToString(key.N.Bytes()[:8])
+}
+
+// authorize authenticates the user as the browser would, and returns the
+// state and the code of the redirection to Fleet.
+func (p *fakeOIDCProvider) authorize(authURL string) (state, code string) {
+	u, err := url.Parse(authURL)
+	require.NoError(p.t, err)
+	qry := u.Query()
+	require.Equal(p.t, "fleet", qry.Get("tenant"))
+	require.Equal(p.t, "code", qry.Get("response_type"))
+	require.Equal(p.t, testOIDCClientID, qry.Get("client_id"))
+	require.Equal(p.t, testOIDCRedirectURL, qry.Get("redirect_uri"))
+	require.Equal(p.t, "S256", qry.Get("code_challenge_method"))
+
+	code, err = randomOIDCValue()
+	require.NoError(p.t, err)
+	p.codes[code] = fakeOIDCCode{challenge: qry.Get("code_challenge"), nonce: qry.Get("nonce")}
+	return qry.Get("state"), code
+}
+
+func (p *fakeOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	tokenError := func(code string) {
+		w.WriteHeader(http.StatusBadRequest)
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": code})
+	}
+	clientID, clientSecret, _ := r.BasicAuth()
+	if clientID != testOIDCClientID || clientSecret != testOIDCClientSecret {
+		tokenError("invalid_client")
+		return
+	}
+	issued, ok := p.codes[r.PostFormValue("code")]
+	if !ok || r.PostFormValue("redirect_uri") != testOIDCRedirectURL {
+		tokenError("invalid_grant")
+		return
+	}
+	delete(p.codes, r.PostFormValue("code"))
+	challenge := sha256.Sum256([]byte(r.PostFormValue("code_verifier")))
+	if base64.RawURLEncoding.EncodeToString(challenge[:]) != issued.challenge {
+		tokenError("invalid_grant")
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            p.server.URL,
+		"sub":            "1234",
+		"aud":            testOIDCClientID,
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"iat":            now.Unix(),
+		"nonce":          issued.nonce,
+		"email":          "bob@example.com",
+		"email_verified": true,
+		"groups":         []string{"Fleet Admins", "Engineering"},
+	}
+	for name, value := range p.claims {
+		if value == nil {
+			delete(claims, name)
+		} else {
+			claims[name] = value
+		}
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+	idToken, err := token.SignedString(p.key)
+	require.NoError(p.t, err)
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "access",
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func (p *fakeOIDCProvider) settings(store SessionStore) *OIDCSettings {
+	return &OIDCSettings{
+		IssuerURL:    p.server.URL,
+		ClientID:     testOIDCClientID,
+		ClientSecret: testOIDCClientSecret,
+		RedirectURL:  testOIDCRedirectURL,
+		Scopes:       []string{"groups"},
+		SessionStore: store,
+		OriginalURL:  "/hosts/manage",
+	}
+}
+
+func TestOIDCAuthorize(t *testing.T) {
+	ctx := context.Background()
+	provider := newFakeOIDCProvider(t)
+	providers := NewOIDCProviders(provider.server.Client())
+	store := &fakeSessionStore{sessions: make(map[string]*Session)}
+	settings := provider.settings(store)
+
+	authURL, err := providers.CreateAuthorizationRequest(ctx, settings)
+	require.NoError(t, err)
+	u, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "openid email profile groups", u.Query().Get("scope"))
+	assert.NotEmpty(t, u.Query().Get("nonce"))
+
+	state, code := provider.authorize(authURL)
+	auth, originalURL, err := providers.Authorize(ctx, settings, state, code)
+	require.NoError(t, err)
+	assert.Equal(t, "/hosts/manage", originalURL)
+	assert.Equal(t, "bob@example.com", auth.UserID())
+	assert.Equal(t, state, auth.RequestID())
+	assert.Equal(t, []string{"Fleet Admins", "Engineering"}, auth.Attributes()["groups"])
+
+	// The request can only be used once.
+	_, _, err = providers.Authorize(ctx, settings, state, code)
+	assert.Error(t, err)
+
+	// The configuration and the keys of the provider are cached.
+	authURL, err = providers.CreateAuthorizationRequest(ctx, settings)
+	require.NoError(t, err)
+	state, code = provider.authorize(authURL)
+	_, _, err = providers.Authorize(ctx, settings, state, code)
+	require.NoError(t, err)
+	assert.Equal(t, 1, provider.discoveryRequests)
+	assert.Equal(t, 1, provider.jwksRequests)
+}
+
+func TestOIDCAuthorizeFailures(t *testing.T) {
+	ctx := context.Background()
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		claims map[string]interface{}
+		key    *rsa.PrivateKey
+	}{
+		{"wrong audience", map[string]interface{}{"aud": "other-client"}, nil},
+		{"wrong authorized party", map[string]interface{}{"aud": []string{testOIDCClientID, "other-client"}, "azp": "other-client"}, nil},
+		{"wrong issuer", map[string]interface{}{"iss": "https://other.example.com"}, nil},
+		{"expired", map[string]interface{}{"exp": time.Now().Add(-10 * time.Minute).Unix()}, nil},
+		{"missing expiration", map[string]interface{}{"exp": nil}, nil},
+		{"wrong nonce", map[string]interface{}{"nonce": "other"}, nil},
+		{"missing email", map[string]interface{}{"email": nil}, nil},
+		{"unverified email", map[string]interface{}{"email_verified": false}, nil},
+		{"signed by another key", nil, otherKey},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := newFakeOIDCProvider(t)
+			provider.claims = tc.claims
+			providers := NewOIDCProviders(provider.server.Client())
+			store := &fakeSessionStore{sessions: make(map[string]*Session)}
+			settings := provider.settings(store)
+
+			authURL, err := providers.CreateAuthorizationRequest(ctx, settings)
+			require.NoError(t, err)
+			state, code := provider.authorize(authURL)
+			if tc.key != nil {
+				// Publish the key of the provider, but sign with another key.
+				require.NoError(t, providers.fetchKeysOf(ctx, settings.IssuerURL))
+				provider.key = tc.key
+			}
+			_, _, err = providers.Authorize(ctx, settings, state, code)
+			assert.Error(t, err)
+		})
+	}
+}
+
+// fetchKeysOf caches the keys of the provider.
+func (p *OIDCProviders) fetchKeysOf(ctx context.Context, issuerURL string) error {
+	provider, err := p.provider(ctx, issuerURL)
+	if err != nil {
+		return err
+	}
+	return p.fetchKeys(ctx, provider)
+}
+
+func TestOIDCAuthorizeWrongCodeVerifier(t *testing.T) {
+	ctx := context.Background()
+	provider := newFakeOIDCProvider(t)
+	providers := NewOIDCProviders(provider.server.Client())
+	store := &fakeSessionStore{sessions: make(map[string]*Session)}
+	settings := provider.settings(store)
+
+	authURL, err := providers.CreateAuthorizationRequest(ctx, settings)
+	require.NoError(t, err)
+	state, _ := provider.authorize(authURL)
+	authURL, err = providers.CreateAuthorizationRequest(ctx, settings)
+	require.NoError(t, err)
+	_, code := provider.authorize(authURL)
+
+	// The code of the second request is used with the code verifier of the
+	// first one.
+	_, _, err = providers.Authorize(ctx, settings, state, code)
+	assert.Error(t, err)
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	provider := newFakeOIDCProvider(t)
+	providers := NewOIDCProviders(provider.server.Client())
+	now := time.Now()
+	providers.now = func() time.Time { return now }
+	store := &fakeSessionStore{sessions: make(map[string]*Session)}
+	settings := provider.settings(store)
+
+	login := func() error {
+		authURL, err := providers.CreateAuthorizationRequest(ctx, settings)
+		require.NoError(t, err)
+		state, code := provider.authorize(authURL)
+		_, _, err = providers.Authorize(ctx, settings, state, code)
+		return err
+	}
+	require.NoError(t, login())
+
+	// The keys are not fetched again right away for an unknown key.
+	provider.rotateKey()
+	assert.Error(t, login())
+	assert.Equal(t, 1, provider.jwksRequests)
+
+	now = now.Add(oidcKeysMinRefresh)
+	assert.NoError(t, login())
+	assert.Equal(t, 2, provider.jwksRequests)
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	provider := newFakeOIDCProvider(t)
+	providers := NewOIDCProviders(provider.server.Client())
+	settings := provider.settings(&fakeSessionStore{sessions: make(map[string]*Session)})
+	settings.IssuerURL += "/"
+
+	_, err := providers.CreateAuthorizationRequest(context.Background(), settings)
+	assert.Error(t, err)
+}
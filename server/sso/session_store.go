@@ -31,6 +31,7 @@ type SessionStore interface {
 	create(requestID, originalURL, x509Cert string, lifetimeSecs uint) error
 	Get(requestID string) (*Session, error)
 	Expire(requestID string) error
+	markAssertionUsed(assertionID string, lifetimeSecs uint) error
 }
 
 // NewSessionStore creates a SessionStore
@@ -80,9 +81,37 @@ func (s *store) Get(requestID string) (*Session, error) {
 
 var ErrSessionNotFound = errors.New("session not found")
 
+// Expire removes the session. It returns ErrSessionNotFound if the session
+// was already removed, so that only one response is accepted for a request.
 func (s *store) Expire(requestID string) error {
 	conn := s.pool.ConfigureDoer(s.pool.Get())
 	defer conn.Close()
-	_, err := conn.Do("DEL", requestID)
-	return err
+	removed, err := redis.Int(conn.Do("DEL", requestID))
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+var ErrAssertionReplayed = errors.New("assertion already used")
+
+// markAssertionUsed records the assertion ID for the lifetime of the assertion.
+// It returns ErrAssertionReplayed if the assertion ID was already recorded.
+func (s *store) markAssertionUsed(assertionID string, lifetimeSecs uint) error {
+	if assertionID == "" {
+		return errors.New("missing assertion id")
+	}
+	conn := s.pool.ConfigureDoer(s.pool.Get())
+	defer conn.Close()
+	_, err := redis.String(conn.Do("SET", "sso:assertion:"+assertionID, 1, "EX", lifetimeSecs, "NX"))
+	if err != nil {
+		if err == redis.ErrNil {
+			return ErrAssertionReplayed
+		}
+		return err
+	}
+	return nil
 }
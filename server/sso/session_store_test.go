@@ -69,6 +69,18 @@ func TestSessionStore(t *testing.T) {
 		sess, err = store.Get("request123")
 		assert.Equal(t, ErrSessionNotFound, err)
 		assert.Nil(t, sess)
+
+		// A session can only be expired once.
+		err = store.create("request456", "https://originalurl.com", "some metadata", 60)
+		require.Nil(t, err)
+		require.Nil(t, store.Expire("request456"))
+		assert.Equal(t, ErrSessionNotFound, store.Expire("request456"))
+
+		// An assertion can only be used once while it is recorded.
+		require.Nil(t, store.markAssertionUsed("assertion123", 1))
+		assert.Equal(t, ErrAssertionReplayed, store.markAssertionUsed("assertion123", 1))
+		time.Sleep(1100 * time.Millisecond)
+		assert.Nil(t, store.markAssertionUsed("assertion123", 1))
 	}
 
 	t.Run("standalone", func(t *testing.T) {
@@ -1,6 +1,7 @@
 package sso
 
 import (
+	"crypto/tls"
 	"encoding/xml"
 	"io/ioutil"
 	"net/http"
@@ -45,6 +46,8 @@ type SingleSignOnService struct {
 const (
 	PasswordProtectedTransport = "urn:oasis:names:tc:SAML:2.0:ac:classes:PasswordProtectedTransport"
 	RedirectBinding            = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect"
+	PostBinding                = "urn:oasis:names:tc:SAML:2.0:bindings:HTTP-POST"
+	EmailAddressNameIDFormat   = "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress"
 )
 
 type Settings struct {
@@ -54,6 +57,8 @@ type Settings struct {
 	AssertionConsumerServiceURL string
 	SessionStore                SessionStore
 	OriginalURL                 string
+	// SPCertificate signs the authorization request if set.
+	SPCertificate *tls.Certificate
 }
 
 // ParseMetadata writes metadata xml to a struct
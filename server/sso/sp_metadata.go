@@ -0,0 +1,74 @@
+package sso
+
+import (
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/xml"
+
+	"github.com/pkg/errors"
+)
+
+// spEntityDescriptor describes Fleet as a service provider.
+// See http://docs.oasis-open.org/security/saml/v2.0/saml-metadata-2.0-os.pdf Section 2.4.4
+type spEntityDescriptor struct {
+	XMLName         xml.Name        `xml:"md:EntityDescriptor"`
+	MD              string          `xml:"xmlns:md,attr"`
+	DS              string          `xml:"xmlns:ds,attr"`
+	EntityID        string          `xml:"entityID,attr"`
+	SPSSODescriptor spSSODescriptor `xml:"md:SPSSODescriptor"`
+}
+
+type spSSODescriptor struct {
+	AuthnRequestsSigned        bool                         `xml:"AuthnRequestsSigned,attr"`
+	WantAssertionsSigned       bool                         `xml:"WantAssertionsSigned,attr"`
+	ProtocolSupportEnumeration string                       `xml:"protocolSupportEnumeration,attr"`
+	KeyDescriptors             []spKeyDescriptor            `xml:"md:KeyDescriptor"`
+	NameIDFormats              []string                     `xml:"md:NameIDFormat"`
+	AssertionConsumerServices  []spAssertionConsumerService `xml:"md:AssertionConsumerService"`
+}
+
+type spKeyDescriptor struct {
+	Use              string   `xml:"use,attr"`
+	X509Certificates []string `xml:"ds:KeyInfo>ds:X509Data>ds:X509Certificate"`
+}
+
+type spAssertionConsumerService struct {
+	Binding  string `xml:"Binding,attr"`
+	Location string `xml:"Location,attr"`
+	Index    int    `xml:"index,attr"`
+}
+
+// ServiceProviderMetadata returns the metadata that identity providers use to
+// configure Fleet as a service provider. cert is the certificate that signs
+// the authorization requests, if any.
+func ServiceProviderMetadata(entityID, acsURL string, cert *tls.Certificate) ([]byte, error) {
+	descriptor := spEntityDescriptor{
+		MD:       "urn:oasis:names:tc:SAML:2.0:metadata",
+		DS:       "http://www.w3.org/2000/09/xmldsig#",
+		EntityID: entityID,
+		SPSSODescriptor: spSSODescriptor{
+			AuthnRequestsSigned:        cert != nil,
+			WantAssertionsSigned:       true,
+			ProtocolSupportEnumeration: "urn:oasis:names:tc:SAML:2.0:protocol",
+			NameIDFormats:              []string{EmailAddressNameIDFormat},
+			AssertionConsumerServices: []spAssertionConsumerService{
+				{Binding: PostBinding, Location: acsURL, Index: 0},
+			},
+		},
+	}
+	if cert != nil {
+		if len(cert.Certificate) == 0 {
+			return nil, errors.New("missing service provider certificate")
+		}
+		descriptor.SPSSODescriptor.KeyDescriptors = []spKeyDescriptor{{
+			Use:              "signing",
+			X509Certificates: []string{base64.StdEncoding.EncodeToString(cert.Certificate[0])},
+		}}
+	}
+
+	metadata, err := xml.MarshalIndent(descriptor, "", "  ")
+	if err != nil {
+		return nil, errors.Wrap(err, "encoding service provider metadata")
+	}
+	return append([]byte(xml.Header), metadata...), nil
+}
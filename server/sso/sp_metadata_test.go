@@ -0,0 +1,52 @@
+package sso
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceProviderMetadata(t *testing.T) {
+	const acsURL = "https://fleet.example.com/api/v1/fleet/sso/callback"
+
+	var parsed struct {
+		EntityID        string `xml:"entityID,attr"`
+		SPSSODescriptor struct {
+			AuthnRequestsSigned  bool `xml:"AuthnRequestsSigned,attr"`
+			WantAssertionsSigned bool `xml:"WantAssertionsSigned,attr"`
+			KeyDescriptors       []struct {
+				Use             string `xml:"use,attr"`
+				X509Certificate string `xml:"KeyInfo>X509Data>X509Certificate"`
+			} `xml:"KeyDescriptor"`
+			NameIDFormats             []string `xml:"NameIDFormat"`
+			AssertionConsumerServices []struct {
+				Binding  string `xml:"Binding,attr"`
+				Location string `xml:"Location,attr"`
+			} `xml:"AssertionConsumerService"`
+		} `xml:"SPSSODescriptor"`
+	}
+
+	metadata, err := ServiceProviderMetadata("fleet.example.com", acsURL, nil)
+	require.NoError(t, err)
+	require.NoError(t, xml.Unmarshal(metadata, &parsed))
+	assert.Equal(t, "fleet.example.com", parsed.EntityID)
+	assert.False(t, parsed.SPSSODescriptor.AuthnRequestsSigned)
+	assert.True(t, parsed.SPSSODescriptor.WantAssertionsSigned)
+	assert.Empty(t, parsed.SPSSODescriptor.KeyDescriptors)
+	assert.Equal(t, []string{EmailAddressNameIDFormat}, parsed.SPSSODescriptor.NameIDFormats)
+	require.Len(t, parsed.SPSSODescriptor.AssertionConsumerServices, 1)
+	assert.Equal(t, PostBinding, parsed.SPSSODescriptor.AssertionConsumerServices[0].Binding)
+	assert.Equal(t, acsURL, parsed.SPSSODescriptor.AssertionConsumerServices[0].Location)
+
+	cert := testCertificate(t)
+	metadata, err = ServiceProviderMetadata("fleet.example.com", acsURL, cert)
+	require.NoError(t, err)
+	require.NoError(t, xml.Unmarshal(metadata, &parsed))
+	assert.True(t, parsed.SPSSODescriptor.AuthnRequestsSigned)
+	require.Len(t, parsed.SPSSODescriptor.KeyDescriptors, 1)
+	assert.Equal(t, "signing", parsed.SPSSODescriptor.KeyDescriptors[0].Use)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(cert.Certificate[0]), parsed.SPSSODescriptor.KeyDescriptors[0].X509Certificate)
+}
@@ -205,9 +205,15 @@ type Assertion struct {
 }
 
 type Conditions struct {
-	XMLName      xml.Name
-	NotBefore    string `xml:",attr"`
-	NotOnOrAfter string `xml:",attr"`
+	XMLName              xml.Name
+	NotBefore            string                `xml:",attr"`
+	NotOnOrAfter         string                `xml:",attr"`
+	AudienceRestrictions []AudienceRestriction `xml:"AudienceRestriction"`
+}
+
+type AudienceRestriction struct {
+	XMLName   xml.Name
+	Audiences []string `xml:"Audience"`
 }
 
 type Subject struct {
@@ -23,9 +23,13 @@ type Validator interface {
 }
 
 type validator struct {
-	context  *dsig.ValidationContext
-	clock    *dsig.Clock
-	metadata Metadata
+	context   *dsig.ValidationContext
+	clock     *dsig.Clock
+	metadata  Metadata
+	audience  string
+	recipient string
+	clockSkew time.Duration
+	store     SessionStore
 }
 
 func Clock(clock *dsig.Clock) func(v *validator) {
@@ -34,6 +38,39 @@ func Clock(clock *dsig.Clock) func(v *validator) {
 	}
 }
 
+// Audience requires the assertion to be restricted to the audience, which is
+// the entity ID of the service provider.
+func Audience(audience string) func(v *validator) {
+	return func(v *validator) {
+		v.audience = audience
+	}
+}
+
+// Recipient requires the response to be sent to the recipient, which is the
+// assertion consumer service URL of the service provider.
+func Recipient(recipient string) func(v *validator) {
+	return func(v *validator) {
+		v.recipient = recipient
+	}
+}
+
+// ClockSkew sets the allowed difference between the clocks of the identity
+// provider and of the service provider when checking the validity period of
+// the assertion.
+func ClockSkew(skew time.Duration) func(v *validator) {
+	return func(v *validator) {
+		v.clockSkew = skew
+	}
+}
+
+// ReplayStore records the IDs of the validated assertions in the store, so
+// that an assertion is rejected if it is used again while it is valid.
+func ReplayStore(store SessionStore) func(v *validator) {
+	return func(v *validator) {
+		v.store = store
+	}
+}
+
 // NewValidator is used to validate the response to an auth request.
 // metadata is from the IDP.
 func NewValidator(metadata Metadata, opts ...func(v *validator)) (Validator, error) {
@@ -43,18 +80,27 @@ func NewValidator(metadata Metadata, opts ...func(v *validator)) (Validator, err
 
 	var idpCertStore dsig.MemoryX509CertificateStore
 	for _, key := range v.metadata.IDPSSODescriptor.KeyDescriptors {
+		if key.Use == "encryption" {
+			// Encryption keys cannot verify signatures.
+			continue
+		}
 		if len(key.KeyInfo.X509Data.X509Certificates) == 0 {
 			return nil, errors.New("missing x509 cert")
 		}
-		certData, err := base64.StdEncoding.DecodeString(strings.TrimSpace(key.KeyInfo.X509Data.X509Certificates[0].Data))
-		if err != nil {
-			return nil, errors.Wrap(err, "decoding idp x509 cert")
+		for _, x509Cert := range key.KeyInfo.X509Data.X509Certificates {
+			certData, err := base64.StdEncoding.DecodeString(strings.TrimSpace(x509Cert.Data))
+			if err != nil {
+				return nil, errors.Wrap(err, "decoding idp x509 cert")
+			}
+			cert, err := x509.ParseCertificate(certData)
+			if err != nil {
+				return nil, errors.Wrap(err, "parsing idp x509 cert")
+			}
+			idpCertStore.Roots = append(idpCertStore.Roots, cert)
 		}
-		cert, err := x509.ParseCertificate(certData)
-		if err != nil {
-			return nil, errors.Wrap(err, "parsing idp x509 cert")
-		}
-		idpCertStore.Roots = append(idpCertStore.Roots, cert)
+	}
+	if len(idpCertStore.Roots) == 0 {
+		return nil, errors.New("missing idp signing cert in metadata")
 	}
 	for _, opt := range opts {
 		opt(&v)
@@ -69,28 +115,85 @@ func NewValidator(metadata Metadata, opts ...func(v *validator)) (Validator, err
 
 func (v *validator) ValidateResponse(auth fleet.Auth) error {
 	info := auth.(*resp)
+	assertion := info.response.Assertion
 	// make sure response is current
-	onOrAfter, err := time.Parse(time.RFC3339, info.response.Assertion.Conditions.NotOnOrAfter)
+	onOrAfter, err := time.Parse(time.RFC3339, assertion.Conditions.NotOnOrAfter)
 	if err != nil {
 		return errors.Wrap(err, "missing timestamp from condition")
 	}
-	notBefore, err := time.Parse(time.RFC3339, info.response.Assertion.Conditions.NotBefore)
+	notBefore, err := time.Parse(time.RFC3339, assertion.Conditions.NotBefore)
 	if err != nil {
 		return errors.Wrap(err, "missing timestamp from condition")
 	}
 	currentTime := v.clock.Now()
-	if currentTime.After(onOrAfter) {
+	if currentTime.After(onOrAfter.Add(v.clockSkew)) {
 		return errors.New("response expired")
 	}
-	if currentTime.Before(notBefore) {
+	if currentTime.Before(notBefore.Add(-v.clockSkew)) {
 		return errors.New("response too early")
 	}
 	if auth.UserID() == "" {
 		return errors.New("missing user id")
 	}
+
+	confirmation := assertion.Subject.SubjectConfirmation.SubjectConfirmationData
+	if confirmation.NotOnOrAfter != "" {
+		confirmationOnOrAfter, err := time.Parse(time.RFC3339, confirmation.NotOnOrAfter)
+		if err != nil {
+			return errors.Wrap(err, "parse subject confirmation timestamp")
+		}
+		if currentTime.After(confirmationOnOrAfter.Add(v.clockSkew)) {
+			return errors.New("subject confirmation expired")
+		}
+	}
+	if confirmation.InResponseTo != "" && confirmation.InResponseTo != info.response.InResponseTo {
+		return errors.New("subject confirmation does not match the request")
+	}
+	if v.audience != "" && !validAudience(assertion.Conditions.AudienceRestrictions, v.audience) {
+		return errors.Errorf("assertion is not restricted to audience %s", v.audience)
+	}
+	if v.recipient != "" {
+		if confirmation.Recipient != v.recipient {
+			return errors.Errorf("unexpected recipient %s", confirmation.Recipient)
+		}
+		// The destination is only required in signed responses.
+		if info.response.Destination != "" && info.response.Destination != v.recipient {
+			return errors.Errorf("unexpected destination %s", info.response.Destination)
+		}
+	}
+
+	if v.store != nil {
+		// Remember the assertion until it expires, after which it is rejected
+		// anyway.
+		lifetime := onOrAfter.Add(v.clockSkew).Sub(currentTime)
+		if err := v.store.markAssertionUsed(assertion.ID, uint(lifetime/time.Second)+1); err != nil {
+			return errors.Wrap(err, "record assertion")
+		}
+	}
 	return nil
 }
 
+// validAudience returns true if every audience restriction includes the
+// audience, and there is at least one restriction.
+func validAudience(restrictions []AudienceRestriction, audience string) bool {
+	if len(restrictions) == 0 {
+		return false
+	}
+	for _, restriction := range restrictions {
+		var found bool
+		for _, restrictionAudience := range restriction.Audiences {
+			if strings.TrimSpace(restrictionAudience) == audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
 func (v *validator) ValidateSignature(auth fleet.Auth) (fleet.Auth, error) {
 	info := auth.(*resp)
 	status, err := info.status()
@@ -122,6 +225,19 @@ func (v *validator) ValidateSignature(auth fleet.Auth) (fleet.Auth, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "signing verification failed")
 	}
+	// Only one assertion is expected, so that the attributes of several
+	// assertions are not merged together.
+	var assertions int
+	err = etreeutils.NSFindIterate(signed, samlAssertionNamespace, "Assertion", func(ctx etreeutils.NSContext, el *etree.Element) error {
+		assertions++
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "find assertions")
+	}
+	if assertions != 1 {
+		return nil, errors.Errorf("expected one assertion, found %d", assertions)
+	}
 	// We've verified that the response hasn't been tampered with at this point
 	signedDoc := etree.NewDocument()
 	signedDoc.SetRoot(signed)
@@ -175,9 +291,11 @@ func (v *validator) validateAssertionSignature(elt *etree.Element) error {
 		elt.AddChild(signed)
 		return nil
 	}
-	return etreeutils.NSFindIterate(elt, "urn:oasis:names:tc:SAML:2.0:assertion", "Assertion", validateAssertion)
+	return etreeutils.NSFindIterate(elt, samlAssertionNamespace, "Assertion", validateAssertion)
 }
 
+const samlAssertionNamespace = "urn:oasis:names:tc:SAML:2.0:assertion"
+
 const (
 	idPrefix   = "id"
 	idSize     = 16
@@ -2,11 +2,19 @@ package sso
 
 import (
 	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/base64"
 	"encoding/xml"
+	"errors"
+	"math/big"
 	"testing"
 	"time"
 
+	"github.com/beevik/etree"
 	dsig "github.com/russellhaering/goxmldsig"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -263,3 +271,203 @@ func TestIDPrefix(t *testing.T) {
 	require.Nil(t, err)
 	assert.Equal(t, idPrefix, id[:2])
 }
+
+// testCertificate returns a self-signed certificate with a new RSA key.
+func testCertificate(t *testing.T) *tls.Certificate {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "sso.example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return &tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+// testIDPMetadata returns the metadata of an identity provider signing with
+// the certificate.
+func testIDPMetadata(t *testing.T, cert *tls.Certificate) Metadata {
+	metadata, err := ParseMetadata(`<?xml version="1.0" encoding="UTF-8"?>
+<md:EntityDescriptor xmlns:md="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://idp.example.com" xmlns:ds="http://www.w3.org/2000/09/xmldsig#">
+  <md:IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <md:KeyDescriptor use="signing">
+      <ds:KeyInfo>
+        <ds:X509Data>
+          <ds:X509Certificate>` + base64.StdEncoding.EncodeToString(cert.Certificate[0]) + `</ds:X509Certificate>
+        </ds:X509Data>
+      </ds:KeyInfo>
+    </md:KeyDescriptor>
+    <md:SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://idp.example.com/sso"/>
+  </md:IDPSSODescriptor>
+</md:EntityDescriptor>`)
+	require.NoError(t, err)
+	return *metadata
+}
+
+type testAssertion struct {
+	id           string
+	audience     string
+	recipient    string
+	notOnOrAfter time.Time
+}
+
+// testSignedResponse returns a response to the request "request123" with the
+// assertions signed by the certificate.
+func testSignedResponse(t *testing.T, cert *tls.Certificate, destination string, assertions ...testAssertion) string {
+	doc := etree.NewDocument()
+	require.NoError(t, doc.ReadFromString(`<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" ID="response123" Version="2.0" IssueInstant="2017-04-30T22:00:00Z" Destination="`+destination+`" InResponseTo="request123">
+  <saml:Issuer xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion">https://idp.example.com</saml:Issuer>
+  <samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>
+</samlp:Response>`))
+
+	signingContext := dsig.NewDefaultSigningContext(dsig.TLSCertKeyStore(*cert))
+	signingContext.Canonicalizer = dsig.MakeC14N10ExclusiveCanonicalizerWithPrefixList("")
+	for _, assertion := range assertions {
+		assertionDoc := etree.NewDocument()
+		require.NoError(t, assertionDoc.ReadFromString(`<saml:Assertion xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" ID="`+assertion.id+`" Version="2.0" IssueInstant="2017-04-30T22:00:00Z">
+  <saml:Issuer>https://idp.example.com</saml:Issuer>
+  <saml:Subject>
+    <saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">bob@example.com</saml:NameID>
+    <saml:SubjectConfirmation Method="urn:oasis:names:tc:SAML:2.0:cm:bearer">
+      <saml:SubjectConfirmationData InResponseTo="request123" NotOnOrAfter="`+assertion.notOnOrAfter.Format(time.RFC3339)+`" Recipient="`+assertion.recipient+`"/>
+    </saml:SubjectConfirmation>
+  </saml:Subject>
+  <saml:Conditions NotBefore="`+assertion.notOnOrAfter.Add(-10*time.Minute).Format(time.RFC3339)+`" NotOnOrAfter="`+assertion.notOnOrAfter.Format(time.RFC3339)+`">
+    <saml:AudienceRestriction><saml:Audience>`+assertion.audience+`</saml:Audience></saml:AudienceRestriction>
+  </saml:Conditions>
+</saml:Assertion>`))
+		signed, err := signingContext.SignEnveloped(assertionDoc.Root())
+		require.NoError(t, err)
+		doc.Root().AddChild(signed)
+	}
+
+	raw, err := doc.WriteToBytes()
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+type fakeReplayStore struct {
+	SessionStore
+	used map[string]bool
+}
+
+func (s *fakeReplayStore) markAssertionUsed(assertionID string, lifetimeSecs uint) error {
+	if s.used[assertionID] {
+		return ErrAssertionReplayed
+	}
+	s.used[assertionID] = true
+	return nil
+}
+
+func TestValidateHardening(t *testing.T) {
+	const (
+		audience = "fleet.example.com"
+		acsURL   = "https://fleet.example.com/api/v1/fleet/sso/callback"
+	)
+	now := time.Now().UTC().Truncate(time.Second)
+	idpCert := testCertificate(t)
+	valid := testAssertion{id: "assertion123", audience: audience, recipient: acsURL, notOnOrAfter: now.Add(5 * time.Minute)}
+
+	testCases := []struct {
+		name        string
+		cert        *tls.Certificate
+		destination string
+		assertions  []testAssertion
+		signatureOK bool
+		responseOK  bool
+	}{
+		{"valid", idpCert, acsURL, []testAssertion{valid}, true, true},
+		{"no destination", idpCert, "", []testAssertion{valid}, true, true},
+		{
+			"wrong audience", idpCert, acsURL,
+			[]testAssertion{{id: "assertion123", audience: "other.example.com", recipient: acsURL, notOnOrAfter: now.Add(5 * time.Minute)}},
+			true, false,
+		},
+		{
+			"wrong recipient", idpCert, acsURL,
+			[]testAssertion{{id: "assertion123", audience: audience, recipient: "https://other.example.com/callback", notOnOrAfter: now.Add(5 * time.Minute)}},
+			true, false,
+		},
+		{"wrong destination", idpCert, "https://other.example.com/callback", []testAssertion{valid}, true, false},
+		{
+			"expired within skew", idpCert, acsURL,
+			[]testAssertion{{id: "assertion123", audience: audience, recipient: acsURL, notOnOrAfter: now.Add(-time.Minute)}},
+			true, true,
+		},
+		{
+			"expired beyond skew", idpCert, acsURL,
+			[]testAssertion{{id: "assertion123", audience: audience, recipient: acsURL, notOnOrAfter: now.Add(-3 * time.Minute)}},
+			true, false,
+		},
+		{"signed by another key", testCertificate(t), acsURL, []testAssertion{valid}, false, false},
+		{
+			"two assertions", idpCert, acsURL,
+			[]testAssertion{valid, {id: "assertion456", audience: audience, recipient: acsURL, notOnOrAfter: now.Add(5 * time.Minute)}},
+			false, false,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			validator, err := NewValidator(testIDPMetadata(t, idpCert),
+				Clock(dsig.NewFakeClockAt(now)),
+				Audience(audience),
+				Recipient(acsURL),
+				ClockSkew(2*time.Minute),
+				ReplayStore(&fakeReplayStore{used: make(map[string]bool)}),
+			)
+			require.NoError(t, err)
+
+			auth, err := DecodeAuthResponse(testSignedResponse(t, tc.cert, tc.destination, tc.assertions...))
+			require.NoError(t, err)
+			auth, err = validator.ValidateSignature(auth)
+			if !tc.signatureOK {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			err = validator.ValidateResponse(auth)
+			if tc.responseOK {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateReplayedAssertion(t *testing.T) {
+	const acsURL = "https://fleet.example.com/api/v1/fleet/sso/callback"
+	now := time.Now().UTC().Truncate(time.Second)
+	idpCert := testCertificate(t)
+	validator, err := NewValidator(testIDPMetadata(t, idpCert),
+		Clock(dsig.NewFakeClockAt(now)),
+		ReplayStore(&fakeReplayStore{used: make(map[string]bool)}),
+	)
+	require.NoError(t, err)
+
+	response := testSignedResponse(t, idpCert, acsURL, testAssertion{
+		id: "assertion123", audience: "fleet.example.com", recipient: acsURL, notOnOrAfter: now.Add(5 * time.Minute),
+	})
+	for _, expectErr := range []bool{false, true} {
+		auth, err := DecodeAuthResponse(response)
+		require.NoError(t, err)
+		auth, err = validator.ValidateSignature(auth)
+		require.NoError(t, err)
+		err = validator.ValidateResponse(auth)
+		if expectErr {
+			assert.True(t, errors.Is(err, ErrAssertionReplayed))
+		} else {
+			assert.NoError(t, err)
+		}
+	}
+}
+
+func TestNewValidatorMissingSigningCert(t *testing.T) {
+	metadata := testMetadata()
+	metadata.IDPSSODescriptor.KeyDescriptors[0].Use = "encryption"
+	_, err := NewValidator(metadata)
+	assert.Error(t, err)
+}
@@ -230,6 +230,12 @@ func TranslateSoftwareToCPE(
 		return errors.Wrap(err, "sync cpe db")
 	}
 
+	distroSoftware, err := ds.ListDistroSoftware(ctx)
+	if err != nil {
+		return errors.Wrap(err, "list distro software")
+	}
+	ovalCovered := OVALCoveredSoftware(distroSoftware)
+
 	iterator, err := ds.AllSoftwareWithoutCPEIterator(ctx)
 	if err != nil {
 		return errors.Wrap(err, "all software iterator")
@@ -247,6 +253,9 @@ func TranslateSoftwareToCPE(
 		if err != nil {
 			return errors.Wrap(err, "getting value from iterator")
 		}
+		if ovalCovered[software.ID] {
+			continue
+		}
 		cpe, err := CPEFromSoftware(db, software)
 		if err != nil {
 			level.Error(logger).Log("software->cpe", "error translating to CPE, skipping...", "err", err)
@@ -171,9 +171,22 @@ func TestTranslateSoftwareToCPE(t *testing.T) {
 				Version: "0.3",
 				Source:  "apps",
 			},
+			{
+				ID:      3,
+				Name:    "Product",
+				Version: "1.2.3",
+				Source:  "deb_packages",
+			},
 		},
 	}
 
+	// The deb package is matched through the OVAL definitions instead.
+	ds.ListDistroSoftwareFunc = func(ctx context.Context) ([]fleet.DistroSoftware, error) {
+		return []fleet.DistroSoftware{
+			{Software: *iterator.softwares[2], Platform: "ubuntu", PlatformLike: "debian", OSVersion: "Ubuntu 20.4.0"},
+		}, nil
+	}
+
 	ds.AllSoftwareWithoutCPEIteratorFunc = func(ctx context.Context) (fleet.SoftwareIterator, error) {
 		return iterator, nil
 	}
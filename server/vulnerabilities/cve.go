@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/facebookincubator/nvdtools/cvefeed"
+	feednvd "github.com/facebookincubator/nvdtools/cvefeed/nvd"
 	"github.com/facebookincubator/nvdtools/providers/nvd"
 	"github.com/facebookincubator/nvdtools/wfn"
 	"github.com/fleetdm/fleet/v4/server/config"
@@ -160,5 +161,56 @@ func TranslateCPEToCVE(
 
 	wg.Wait()
 
-	return nil
+	return syncCVEMeta(ctx, ds, dict)
+}
+
+// nvdTimeLayout is the layout of the dates in the NVD feeds.
+const nvdTimeLayout = "2006-01-02T15:04Z"
+
+// syncCVEMeta stores the CVSS v3 score and severity and the published date of
+// all the CVEs that affect any software, including the ones found through OVAL
+// definitions.
+func syncCVEMeta(ctx context.Context, ds fleet.Datastore, dict cvefeed.Dictionary) error {
+	cves, err := ds.ListSoftwareCVEs(ctx)
+	if err != nil {
+		return err
+	}
+
+	var meta []fleet.CVEMeta
+	for _, cve := range cves {
+		vuln, ok := dict[cve]
+		if !ok {
+			continue
+		}
+		meta = append(meta, cveMetaFromVuln(cve, vuln))
+	}
+	if len(meta) == 0 {
+		return nil
+	}
+	return ds.InsertCVEMeta(ctx, meta)
+}
+
+func cveMetaFromVuln(cve string, vuln cvefeed.Vuln) fleet.CVEMeta {
+	meta := fleet.CVEMeta{CVE: cve}
+	if score := vuln.CVSSv3BaseScore(); score > 0 {
+		meta.CVSSScore = &score
+	}
+	if vector := vuln.CVSSv3Vector(); vector != "" {
+		meta.CVSSVector = &vector
+	}
+
+	nvdVuln, ok := vuln.(*feednvd.Vuln)
+	if !ok || nvdVuln.Schema() == nil {
+		return meta
+	}
+	item := nvdVuln.Schema()
+	if item.Impact != nil && item.Impact.BaseMetricV3 != nil && item.Impact.BaseMetricV3.CVSSV3 != nil {
+		if severity := item.Impact.BaseMetricV3.CVSSV3.BaseSeverity; severity != "" {
+			meta.Severity = &severity
+		}
+	}
+	if published, err := time.Parse(nvdTimeLayout, item.PublishedDate); err == nil {
+		meta.Published = &published
+	}
+	return meta
 }
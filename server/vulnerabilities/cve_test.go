@@ -11,10 +11,14 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/facebookincubator/nvdtools/cvefeed"
 	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
 	"github.com/fleetdm/fleet/v4/server/mock"
 	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -47,16 +51,80 @@ func TestTranslateCPEToCVE(t *testing.T) {
 				cvesFound = append(cvesFound, cve)
 				return nil
 			}
+			ds.ListSoftwareCVEsFunc = func(ctx context.Context) ([]string, error) {
+				return cvesFound, nil
+			}
+			var metaFound []string
+			ds.InsertCVEMetaFunc = func(ctx context.Context, meta []fleet.CVEMeta) error {
+				for _, m := range meta {
+					metaFound = append(metaFound, m.CVE)
+				}
+				return nil
+			}
 
 			err := TranslateCPEToCVE(ctx, ds, tempDir, kitlog.NewLogfmtLogger(os.Stdout), config.FleetConfig{})
 			require.NoError(t, err)
 
 			require.Equal(t, []string{tt.cve}, cvesFound)
 			require.Equal(t, []string{tt.cpe}, cveToCPEs[tt.cve])
+			require.Equal(t, []string{tt.cve}, metaFound)
 		})
 	}
 }
 
+const cveFeedJSON = `{
+  "CVE_data_type": "CVE",
+  "CVE_data_format": "MITRE",
+  "CVE_data_version": "4.0",
+  "CVE_Items": [
+    {
+      "cve": {"CVE_data_meta": {"ID": "CVE-2021-3449"}},
+      "configurations": {"nodes": []},
+      "impact": {
+        "baseMetricV3": {
+          "cvssV3": {
+            "version": "3.1",
+            "vectorString": "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H",
+            "baseScore": 5.9,
+            "baseSeverity": "MEDIUM"
+          }
+        }
+      },
+      "publishedDate": "2021-03-25T15:15Z"
+    },
+    {
+      "cve": {"CVE_data_meta": {"ID": "CVE-2012-6369"}},
+      "configurations": {"nodes": []},
+      "impact": {},
+      "publishedDate": "2012-12-31T11:55Z"
+    }
+  ]
+}`
+
+func TestCVEMetaFromVuln(t *testing.T) {
+	vulns, err := cvefeed.ParseJSON(strings.NewReader(cveFeedJSON))
+	require.NoError(t, err)
+	require.Len(t, vulns, 2)
+
+	meta := cveMetaFromVuln("CVE-2021-3449", vulns[0])
+	assert.Equal(t, "CVE-2021-3449", meta.CVE)
+	require.NotNil(t, meta.CVSSScore)
+	assert.Equal(t, 5.9, *meta.CVSSScore)
+	require.NotNil(t, meta.CVSSVector)
+	assert.Equal(t, "CVSS:3.1/AV:N/AC:L/PR:N/UI:N/S:U/C:N/I:N/A:H", *meta.CVSSVector)
+	require.NotNil(t, meta.Severity)
+	assert.Equal(t, "MEDIUM", *meta.Severity)
+	require.NotNil(t, meta.Published)
+	assert.Equal(t, time.Date(2021, 3, 25, 15, 15, 0, 0, time.UTC), *meta.Published)
+
+	// CVEs without a CVSS v3 score only have a published date.
+	meta = cveMetaFromVuln("CVE-2012-6369", vulns[1])
+	assert.Nil(t, meta.CVSSScore)
+	assert.Nil(t, meta.CVSSVector)
+	assert.Nil(t, meta.Severity)
+	require.NotNil(t, meta.Published)
+}
+
 func TestSyncsCVEFromURL(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.RequestURI, ".meta") {
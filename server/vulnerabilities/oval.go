@@ -0,0 +1,199 @@
+package vulnerabilities
+
+import (
+	"compress/bzip2"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// ovalSyncInterval is how often the OVAL definitions of a distribution are
+// downloaded again.
+const ovalSyncInterval = 24 * time.Hour
+
+// OVALSource is the OVAL feed with the security definitions of a Linux
+// distribution release.
+type OVALSource struct {
+	// FileName is the name of the definitions file in the databases path.
+	FileName string
+	// URL is where the bzip2 compressed definitions are downloaded from.
+	URL string
+}
+
+var ubuntuCodeNames = map[string]string{
+	"14.04": "trusty",
+	"16.04": "xenial",
+	"18.04": "bionic",
+	"20.04": "focal",
+	"21.04": "hirsute",
+	"21.10": "impish",
+}
+
+var rhelPlatforms = map[string]bool{
+	"rhel":      true,
+	"centos":    true,
+	"rocky":     true,
+	"almalinux": true,
+}
+
+var osVersionRegex = regexp.MustCompile(`(\d+)(?:\.(\d+))?`)
+
+// OVALSourceForHost returns the OVAL feed that covers the packages of hosts
+// with the given platform and OS version, and false if there is none.
+func OVALSourceForHost(platform, platformLike, osVersion string) (OVALSource, bool) {
+	m := osVersionRegex.FindStringSubmatch(osVersion)
+	if m == nil {
+		return OVALSource{}, false
+	}
+	major := m[1]
+
+	switch {
+	case platform == "ubuntu":
+		minor, _ := strconv.Atoi(m[2])
+		codeName, ok := ubuntuCodeNames[fmt.Sprintf("%s.%02d", major, minor)]
+		if !ok {
+			return OVALSource{}, false
+		}
+		fileName := fmt.Sprintf("com.ubuntu.%s.usn.oval.xml", codeName)
+		return OVALSource{
+			FileName: fileName,
+			URL:      "https://security-metadata.canonical.com/oval/" + fileName + ".bz2",
+		}, true
+
+	case rhelPlatforms[platform] || (platform != "amzn" && strings.Contains(platformLike, "rhel")):
+		fileName := fmt.Sprintf("rhel-%s.oval.xml", major)
+		return OVALSource{
+			FileName: fileName,
+			URL:      fmt.Sprintf("https://www.redhat.com/security/data/oval/v2/RHEL%s/%s.bz2", major, fileName),
+		}, true
+	}
+
+	return OVALSource{}, false
+}
+
+// OVALCoveredSoftware returns the IDs of the distro packages that are only
+// installed on hosts whose distribution has an OVAL source. Their CVEs are
+// found through the OVAL definitions, which account for backported fixes,
+// so they must not be matched against the NVD feeds.
+func OVALCoveredSoftware(software []fleet.DistroSoftware) map[uint]bool {
+	covered := make(map[uint]bool)
+	uncovered := make(map[uint]bool)
+	for _, s := range software {
+		if _, ok := OVALSourceForHost(s.Platform, s.PlatformLike, s.OSVersion); ok {
+			covered[s.ID] = true
+		} else {
+			uncovered[s.ID] = true
+		}
+	}
+	for id := range uncovered {
+		delete(covered, id)
+	}
+	return covered
+}
+
+// SyncOVALData downloads the definitions of the source to the databases path,
+// unless they were downloaded recently.
+func SyncOVALData(client *http.Client, vulnPath string, source OVALSource, config config.FleetConfig) error {
+	if config.Vulnerabilities.DisableDataSync {
+		return nil
+	}
+
+	dst := filepath.Join(vulnPath, source.FileName)
+	if stat, err := os.Stat(dst); err == nil && time.Since(stat.ModTime()) < ovalSyncInterval {
+		return nil
+	}
+
+	resp, err := client.Get(source.URL)
+	if err != nil {
+		return errors.Wrapf(err, "downloading %s", source.URL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("downloading %s: unexpected status %d", source.URL, resp.StatusCode)
+	}
+
+	tmp, err := ioutil.TempFile(vulnPath, source.FileName+".*")
+	if err != nil {
+		return errors.Wrap(err, "creating oval file")
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, bzip2.NewReader(resp.Body)); err != nil {
+		tmp.Close()
+		return errors.Wrapf(err, "decompressing %s", source.URL)
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "closing oval file")
+	}
+	return os.Rename(tmp.Name(), dst)
+}
+
+// TranslateOVALToCVE matches the deb and rpm packages installed on hosts
+// against the OVAL definitions of their distribution, which account for the
+// fixes backported by the distribution, and records the CVEs found.
+func TranslateOVALToCVE(
+	ctx context.Context,
+	ds fleet.Datastore,
+	vulnPath string,
+	logger kitlog.Logger,
+	config config.FleetConfig,
+) error {
+	software, err := ds.ListDistroSoftware(ctx)
+	if err != nil {
+		return err
+	}
+
+	bySource := make(map[OVALSource][]fleet.Software)
+	for _, s := range software {
+		source, ok := OVALSourceForHost(s.Platform, s.PlatformLike, s.OSVersion)
+		if !ok {
+			continue
+		}
+		bySource[source] = append(bySource[source], s.Software)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Minute}
+	for source, packages := range bySource {
+		if err := SyncOVALData(client, vulnPath, source, config); err != nil {
+			level.Error(logger).Log("msg", "syncing oval definitions", "source", source.URL, "err", err)
+			continue
+		}
+
+		defs, err := loadOVALDefinitions(filepath.Join(vulnPath, source.FileName))
+		if err != nil {
+			level.Error(logger).Log("msg", "loading oval definitions", "file", source.FileName, "err", err)
+			continue
+		}
+
+		for cve, softwareIDs := range defs.Eval(packages) {
+			if err := ds.InsertCVEForSoftware(ctx, cve, softwareIDs); err != nil {
+				level.Error(logger).Log("oval processing", "error", "err", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func loadOVALDefinitions(path string) (*OVALDefinitions, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ParseOVAL(f)
+}
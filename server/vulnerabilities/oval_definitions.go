@@ -0,0 +1,354 @@
+package vulnerabilities
+
+import (
+	"encoding/xml"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/pkg/errors"
+)
+
+// The types below map the subset of the OVAL definitions schema used by the
+// Ubuntu and Red Hat security feeds. Elements are matched by their local name,
+// so both the linux (dpkginfo) and red-def (rpminfo) namespaces are covered.
+
+type ovalDocument struct {
+	Definitions []ovalDefinition `xml:"definitions>definition"`
+	Tests       struct {
+		Dpkg []ovalPackageTest `xml:"dpkginfo_test"`
+		RPM  []ovalPackageTest `xml:"rpminfo_test"`
+	} `xml:"tests"`
+	Objects struct {
+		Dpkg []ovalPackageObject `xml:"dpkginfo_object"`
+		RPM  []ovalPackageObject `xml:"rpminfo_object"`
+	} `xml:"objects"`
+	States struct {
+		Dpkg []ovalPackageState `xml:"dpkginfo_state"`
+		RPM  []ovalPackageState `xml:"rpminfo_state"`
+	} `xml:"states"`
+	Variables []ovalVariable `xml:"variables>constant_variable"`
+}
+
+type ovalDefinition struct {
+	ID         string `xml:"id,attr"`
+	Class      string `xml:"class,attr"`
+	Title      string `xml:"metadata>title"`
+	References []struct {
+		Source string `xml:"source,attr"`
+		RefID  string `xml:"ref_id,attr"`
+	} `xml:"metadata>reference"`
+	AdvisoryCVEs []string     `xml:"metadata>advisory>cve"`
+	Criteria     ovalCriteria `xml:"criteria"`
+}
+
+type ovalCriteria struct {
+	Operator   string          `xml:"operator,attr"`
+	Negate     bool            `xml:"negate,attr"`
+	Criteria   []ovalCriteria  `xml:"criteria"`
+	Criterions []ovalCriterion `xml:"criterion"`
+}
+
+type ovalCriterion struct {
+	TestRef string `xml:"test_ref,attr"`
+	Negate  bool   `xml:"negate,attr"`
+}
+
+type ovalPackageTest struct {
+	ID     string `xml:"id,attr"`
+	Check  string `xml:"check,attr"`
+	Object struct {
+		Ref string `xml:"object_ref,attr"`
+	} `xml:"object"`
+	States []struct {
+		Ref string `xml:"state_ref,attr"`
+	} `xml:"state"`
+}
+
+type ovalPackageObject struct {
+	ID   string `xml:"id,attr"`
+	Name struct {
+		Value  string `xml:",chardata"`
+		VarRef string `xml:"var_ref,attr"`
+	} `xml:"name"`
+}
+
+type ovalPackageState struct {
+	ID  string `xml:"id,attr"`
+	EVR *struct {
+		Value     string `xml:",chardata"`
+		Operation string `xml:"operation,attr"`
+	} `xml:"evr"`
+}
+
+type ovalVariable struct {
+	ID     string   `xml:"id,attr"`
+	Values []string `xml:"value"`
+}
+
+// packageTest checks whether a package is installed with a version that
+// satisfies all the version constraints.
+type packageTest struct {
+	rpm bool
+	// noneSatisfy is set when the test is true if no package satisfies it.
+	noneSatisfy bool
+	names       []string
+	constraints []versionConstraint
+}
+
+type versionConstraint struct {
+	operation string
+	evr       string
+}
+
+// OVALDefinitions are the security definitions of a Linux distribution,
+// parsed from its OVAL feed.
+type OVALDefinitions struct {
+	definitions []ovalDefinition
+	tests       map[string]*packageTest
+}
+
+// ParseOVAL parses an OVAL definitions document.
+func ParseOVAL(r io.Reader) (*OVALDefinitions, error) {
+	var doc ovalDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, errors.Wrap(err, "decoding oval definitions")
+	}
+
+	variables := make(map[string][]string, len(doc.Variables))
+	for _, v := range doc.Variables {
+		variables[v.ID] = v.Values
+	}
+
+	objects := make(map[string][]string)
+	for _, o := range append(doc.Objects.Dpkg, doc.Objects.RPM...) {
+		if o.Name.VarRef != "" {
+			objects[o.ID] = variables[o.Name.VarRef]
+			continue
+		}
+		objects[o.ID] = []string{strings.TrimSpace(o.Name.Value)}
+	}
+
+	states := make(map[string]*versionConstraint)
+	for _, s := range append(doc.States.Dpkg, doc.States.RPM...) {
+		if s.EVR == nil {
+			// States without a version, such as the signature key of the
+			// package, only require the package to be installed.
+			states[s.ID] = nil
+			continue
+		}
+		states[s.ID] = &versionConstraint{
+			operation: s.EVR.Operation,
+			evr:       strings.TrimSpace(s.EVR.Value),
+		}
+	}
+
+	defs := &OVALDefinitions{
+		definitions: doc.Definitions,
+		tests:       make(map[string]*packageTest),
+	}
+	addTests := func(tests []ovalPackageTest, rpm bool) {
+		for _, t := range tests {
+			pt := &packageTest{
+				rpm:         rpm,
+				noneSatisfy: t.Check == "none satisfy",
+				names:       objects[t.Object.Ref],
+			}
+			for _, s := range t.States {
+				if c := states[s.Ref]; c != nil {
+					pt.constraints = append(pt.constraints, *c)
+				}
+			}
+			defs.tests[t.ID] = pt
+		}
+	}
+	addTests(doc.Tests.Dpkg, false)
+	addTests(doc.Tests.RPM, true)
+
+	return defs, nil
+}
+
+// Eval evaluates the definitions against the given packages, all installed on
+// the same operating system. It returns the IDs of the vulnerable packages
+// keyed by CVE.
+func (d *OVALDefinitions) Eval(packages []fleet.Software) map[string][]uint {
+	byName := make(map[string][]fleet.Software)
+	for _, p := range packages {
+		byName[p.Name] = append(byName[p.Name], p)
+	}
+
+	vulnerable := make(map[string][]uint)
+	for _, def := range d.definitions {
+		if def.Class != "patch" && def.Class != "vulnerability" {
+			continue
+		}
+		ok, ids := d.evalCriteria(def.Criteria, byName)
+		if !ok || len(ids) == 0 {
+			continue
+		}
+		for _, cve := range def.cves() {
+			vulnerable[cve] = appendUniqueIDs(vulnerable[cve], ids)
+		}
+	}
+	return vulnerable
+}
+
+// cves returns the CVEs a definition is about, from both its references and
+// its advisory.
+func (def ovalDefinition) cves() []string {
+	seen := make(map[string]bool)
+	var cves []string
+	add := func(cve string) {
+		cve = strings.TrimSpace(cve)
+		if !strings.HasPrefix(cve, "CVE-") || seen[cve] {
+			return
+		}
+		seen[cve] = true
+		cves = append(cves, cve)
+	}
+	for _, ref := range def.References {
+		if ref.Source == "CVE" {
+			add(ref.RefID)
+		}
+	}
+	for _, cve := range def.AdvisoryCVEs {
+		add(cve)
+	}
+	sort.Strings(cves)
+	return cves
+}
+
+// evalCriteria returns whether the criteria are met and the IDs of the
+// packages that made them true.
+func (d *OVALDefinitions) evalCriteria(c ovalCriteria, byName map[string][]fleet.Software) (bool, []uint) {
+	and := c.Operator == "" || strings.EqualFold(c.Operator, "AND")
+
+	var results []bool
+	var ids []uint
+	add := func(ok bool, matched []uint) {
+		results = append(results, ok)
+		if ok {
+			ids = appendUniqueIDs(ids, matched)
+		}
+	}
+	for _, criterion := range c.Criterions {
+		ok, matched := d.evalTest(criterion.TestRef, byName)
+		if criterion.Negate {
+			ok, matched = !ok, nil
+		}
+		add(ok, matched)
+	}
+	for _, sub := range c.Criteria {
+		add(d.evalCriteria(sub, byName))
+	}
+
+	result := and
+	for _, ok := range results {
+		if and && !ok {
+			result = false
+			break
+		}
+		if !and && ok {
+			result = true
+			break
+		}
+	}
+	if len(results) == 0 {
+		result = false
+	}
+
+	if c.Negate {
+		return !result, nil
+	}
+	if !result {
+		return false, nil
+	}
+	return true, ids
+}
+
+// evalTest evaluates a single test. Tests other than package tests, such as
+// the checks of the release of the operating system, are considered true
+// since the definitions are only evaluated against packages of the operating
+// system they were published for.
+func (d *OVALDefinitions) evalTest(ref string, byName map[string][]fleet.Software) (bool, []uint) {
+	test, ok := d.tests[ref]
+	if !ok {
+		return true, nil
+	}
+
+	var ids []uint
+	for _, name := range test.names {
+		for _, p := range byName[name] {
+			if test.satisfiedBy(packageEVR(p)) {
+				ids = append(ids, p.ID)
+			}
+		}
+	}
+	if test.noneSatisfy {
+		return len(ids) == 0, nil
+	}
+	return len(ids) > 0, ids
+}
+
+// packageEVR returns the version of the package as compared against the
+// definitions, which includes the release of rpm packages.
+func packageEVR(p fleet.Software) string {
+	if p.Release == "" {
+		return p.Version
+	}
+	return p.Version + "-" + p.Release
+}
+
+func (t *packageTest) satisfiedBy(version string) bool {
+	for _, c := range t.constraints {
+		evr := c.evr
+		var cmp int
+		if t.rpm {
+			// The version of rpm packages is reported without the epoch, so
+			// the epoch of the definition is ignored when it is missing.
+			if _, _, hasEpoch := splitEpoch(version); !hasEpoch {
+				_, evr, _ = splitEpoch(evr)
+			}
+			cmp = compareRPMVersions(version, evr)
+		} else {
+			cmp = compareDebianVersions(version, evr)
+		}
+
+		var ok bool
+		switch c.operation {
+		case "less than":
+			ok = cmp < 0
+		case "less than or equal":
+			ok = cmp <= 0
+		case "equals", "":
+			ok = cmp == 0
+		case "not equal":
+			ok = cmp != 0
+		case "greater than":
+			ok = cmp > 0
+		case "greater than or equal":
+			ok = cmp >= 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func appendUniqueIDs(ids []uint, more []uint) []uint {
+	for _, id := range more {
+		found := false
+		for _, existing := range ids {
+			if existing == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
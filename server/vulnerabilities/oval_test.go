@@ -0,0 +1,211 @@
+package vulnerabilities
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/config"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareDebianVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "0:1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1:1.0", "2.0", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0", "1.0+b1", -1},
+		{"1.1.1f-1ubuntu2.1", "1.1.1f-1ubuntu2.3", -1},
+		{"1.1.1f-1ubuntu2.3", "0:1.1.1f-1ubuntu2.3", 0},
+		{"1.1.1f-1ubuntu2.10", "1.1.1f-1ubuntu2.3", 1},
+		{"2:8.1.2269-1ubuntu5", "2:8.1.2269-1ubuntu5.3", -1},
+		{"7.68.0-1ubuntu2.7", "7.68.0-1ubuntu2", 1},
+		{"1.0a", "1.0", 1},
+		{"1.0-1", "1.0", 1},
+	} {
+		assert.Equal(t, tt.want, compareDebianVersions(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+		assert.Equal(t, -tt.want, compareDebianVersions(tt.b, tt.a), "%s vs %s", tt.b, tt.a)
+	}
+}
+
+func TestCompareRPMVersions(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"1.0", "1.0", 0},
+		{"1.0", "1.1", -1},
+		{"1.10", "1.9", 1},
+		{"1:1.0", "2.0", 1},
+		{"1.0~rc1", "1.0", -1},
+		{"1.0a", "1.0", 1},
+		{"1.0.a", "1.0.1", -1},
+		{"1.1.1g-15.el8_3", "1.1.1g-12.el8_3", 1},
+		{"1.1.1g-12.el8_3", "1.1.1g-15.el8_3", -1},
+		{"7.61.1-18.el8", "7.61.1-18.el8_4.1", -1},
+		{"7.61.1", "7.61.1-18.el8_4.1", 0},
+		{"0:7.61.1-18.el8_4.1", "7.61.1-18.el8_4.1", 0},
+	} {
+		assert.Equal(t, tt.want, compareRPMVersions(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+		assert.Equal(t, -tt.want, compareRPMVersions(tt.b, tt.a), "%s vs %s", tt.b, tt.a)
+	}
+}
+
+func TestOVALSourceForHost(t *testing.T) {
+	source, ok := OVALSourceForHost("ubuntu", "debian", "Ubuntu 20.4.0")
+	require.True(t, ok)
+	assert.Equal(t, "com.ubuntu.focal.usn.oval.xml", source.FileName)
+	assert.Equal(t, "https://security-metadata.canonical.com/oval/com.ubuntu.focal.usn.oval.xml.bz2", source.URL)
+
+	source, ok = OVALSourceForHost("centos", "rhel fedora", "CentOS Linux 8.3.2011")
+	require.True(t, ok)
+	assert.Equal(t, "rhel-8.oval.xml", source.FileName)
+	assert.Equal(t, "https://www.redhat.com/security/data/oval/v2/RHEL8/rhel-8.oval.xml.bz2", source.URL)
+
+	source, ok = OVALSourceForHost("rocky", "", "Rocky Linux 8.4.0")
+	require.True(t, ok)
+	assert.Equal(t, "rhel-8.oval.xml", source.FileName)
+
+	for _, tt := range []struct{ platform, platformLike, osVersion string }{
+		{"ubuntu", "debian", "Ubuntu 12.4.0"},
+		{"debian", "", "Debian GNU/Linux 10.0.0"},
+		{"amzn", "centos rhel fedora", "Amazon Linux 2.0.0"},
+		{"darwin", "darwin", "Mac OS X 10.15.7"},
+		{"ubuntu", "debian", ""},
+	} {
+		_, ok := OVALSourceForHost(tt.platform, tt.platformLike, tt.osVersion)
+		assert.False(t, ok, tt.osVersion)
+	}
+}
+
+func TestOVALCoveredSoftware(t *testing.T) {
+	openssl := fleet.Software{ID: 1, Name: "openssl", Version: "1.1.1f-1ubuntu2.1", Source: "deb_packages"}
+	curl := fleet.Software{ID: 2, Name: "curl", Version: "7.64.0-4+deb10u2", Source: "deb_packages"}
+	covered := OVALCoveredSoftware([]fleet.DistroSoftware{
+		{Software: openssl, Platform: "ubuntu", PlatformLike: "debian", OSVersion: "Ubuntu 20.4.0"},
+		{Software: curl, Platform: "ubuntu", PlatformLike: "debian", OSVersion: "Ubuntu 20.4.0"},
+		// curl is also installed on a host with no OVAL source.
+		{Software: curl, Platform: "debian", OSVersion: "Debian GNU/Linux 10.0.0"},
+	})
+	assert.Equal(t, map[uint]bool{1: true}, covered)
+}
+
+func loadTestOVAL(t *testing.T, name string) *OVALDefinitions {
+	defs, err := loadOVALDefinitions(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	return defs
+}
+
+func sortedIDs(ids []uint) []uint {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func TestOVALEvalUbuntu(t *testing.T) {
+	defs := loadTestOVAL(t, "com.ubuntu.focal.usn.oval.xml")
+
+	vulnerable := defs.Eval([]fleet.Software{
+		{ID: 1, Name: "openssl", Version: "1.1.1f-1ubuntu2.1", Source: "deb_packages"},
+		{ID: 2, Name: "libssl1.1", Version: "1.1.1f-1ubuntu2.1", Source: "deb_packages"},
+		{ID: 3, Name: "vim", Version: "2:8.1.2269-1ubuntu5.3", Source: "deb_packages"},
+		{ID: 4, Name: "curl", Version: "7.68.0-1ubuntu2.5", Source: "deb_packages"},
+		{ID: 5, Name: "libcurl4", Version: "7.68.0-1ubuntu2.7", Source: "deb_packages"},
+		{ID: 6, Name: "bash", Version: "5.0-6ubuntu1.1", Source: "deb_packages"},
+	})
+
+	require.Len(t, vulnerable, 3)
+	assert.Equal(t, []uint{1, 2}, sortedIDs(vulnerable["CVE-2021-3449"]))
+	assert.Equal(t, []uint{1, 2}, sortedIDs(vulnerable["CVE-2021-3450"]))
+	// The installed version of vim is the fixed one.
+	assert.NotContains(t, vulnerable, "CVE-2021-3770")
+	// Only curl is older than the fixed version, not libcurl4.
+	assert.Equal(t, []uint{4}, vulnerable["CVE-2021-22946"])
+}
+
+func TestOVALEvalRHEL(t *testing.T) {
+	defs := loadTestOVAL(t, "rhel-8.oval.xml")
+
+	vulnerable := defs.Eval([]fleet.Software{
+		{ID: 1, Name: "openssl", Version: "1.1.1g", Release: "12.el8_3", Source: "rpm_packages"},
+		{ID: 2, Name: "openssl-libs", Version: "1.1.1g", Release: "15.el8_3", Source: "rpm_packages"},
+		{ID: 3, Name: "curl", Version: "7.61.1", Release: "18.el8_4.1", Source: "rpm_packages"},
+		{ID: 4, Name: "redhat-release", Version: "8.4", Release: "0.6.el8", Source: "rpm_packages"},
+	})
+
+	require.Len(t, vulnerable, 2)
+	assert.Equal(t, []uint{1}, vulnerable["CVE-2021-3449"])
+	assert.Equal(t, []uint{1}, vulnerable["CVE-2021-3450"])
+
+	// Nothing is vulnerable once the packages are updated.
+	vulnerable = defs.Eval([]fleet.Software{
+		{ID: 1, Name: "openssl", Version: "1.1.1g", Release: "15.el8_3", Source: "rpm_packages"},
+		{ID: 3, Name: "curl", Version: "7.61.1", Release: "18.el8_4.1", Source: "rpm_packages"},
+	})
+	assert.Empty(t, vulnerable)
+}
+
+func TestParseOVALInvalid(t *testing.T) {
+	_, err := loadOVALDefinitions(filepath.Join("testdata", "does-not-exist.xml"))
+	require.Error(t, err)
+
+	f, err := ioutil.TempFile(t.TempDir(), "*.xml")
+	require.NoError(t, err)
+	_, err = f.WriteString("<oval_definitions><definitions>")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	_, err = loadOVALDefinitions(f.Name())
+	require.Error(t, err)
+}
+
+func TestTranslateOVALToCVE(t *testing.T) {
+	tempDir := t.TempDir()
+	b, err := ioutil.ReadFile(filepath.Join("testdata", "com.ubuntu.focal.usn.oval.xml"))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(tempDir, "com.ubuntu.focal.usn.oval.xml"), b, 0o644))
+
+	ds := new(mock.Store)
+	ds.ListDistroSoftwareFunc = func(ctx context.Context) ([]fleet.DistroSoftware, error) {
+		return []fleet.DistroSoftware{
+			{
+				Software:     fleet.Software{ID: 1, Name: "openssl", Version: "1.1.1f-1ubuntu2.1", Source: "deb_packages"},
+				Platform:     "ubuntu",
+				PlatformLike: "debian",
+				OSVersion:    "Ubuntu 20.4.0",
+			},
+			{
+				// The OVAL definitions of Ubuntu 18.04 are not available.
+				Software:     fleet.Software{ID: 2, Name: "openssl", Version: "1.1.1-1ubuntu2.1~18.04.8", Source: "deb_packages"},
+				Platform:     "ubuntu",
+				PlatformLike: "debian",
+				OSVersion:    "Ubuntu 18.4.0",
+			},
+		}, nil
+	}
+	cves := make(map[string][]uint)
+	ds.InsertCVEForSoftwareFunc = func(ctx context.Context, cve string, softwareIDs []uint) error {
+		cves[cve] = softwareIDs
+		return nil
+	}
+
+	fleetConfig := config.FleetConfig{Vulnerabilities: config.VulnerabilitiesConfig{DisableDataSync: true}}
+	err = TranslateOVALToCVE(context.Background(), ds, tempDir, kitlog.NewLogfmtLogger(os.Stdout), fleetConfig)
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string][]uint{
+		"CVE-2021-3449": {1},
+		"CVE-2021-3450": {1},
+	}, cves)
+}
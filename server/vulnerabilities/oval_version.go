@@ -0,0 +1,216 @@
+package vulnerabilities
+
+import (
+	"strconv"
+	"strings"
+)
+
+// splitEpoch splits an epoch:version-release string in its epoch and the
+// rest. The epoch is 0 if it is not present.
+func splitEpoch(evr string) (int, string, bool) {
+	i := strings.Index(evr, ":")
+	if i < 0 {
+		return 0, evr, false
+	}
+	epoch, err := strconv.Atoi(evr[:i])
+	if err != nil {
+		return 0, evr, false
+	}
+	return epoch, evr[i+1:], true
+}
+
+// splitRevision splits a version-release string on its last dash.
+func splitRevision(vr string) (string, string) {
+	i := strings.LastIndex(vr, "-")
+	if i < 0 {
+		return vr, ""
+	}
+	return vr[:i], vr[i+1:]
+}
+
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// compareDebianVersions compares two Debian package versions following the
+// rules of dpkg. It returns -1, 0 or 1 if a is older, equal or newer than b.
+func compareDebianVersions(a, b string) int {
+	epochA, vrA, _ := splitEpoch(a)
+	epochB, vrB, _ := splitEpoch(b)
+	if c := compareInts(epochA, epochB); c != 0 {
+		return c
+	}
+	upstreamA, revisionA := splitRevision(vrA)
+	upstreamB, revisionB := splitRevision(vrB)
+	if c := debianVerRevCmp(upstreamA, upstreamB); c != 0 {
+		return c
+	}
+	return debianVerRevCmp(revisionA, revisionB)
+}
+
+// debianOrder is the sort weight of a character in a non-digit part of a
+// Debian version: the tilde sorts before anything, even the end of the part,
+// and letters sort before other characters.
+func debianOrder(s string, i int) int {
+	if i >= len(s) {
+		return 0
+	}
+	c := s[i]
+	switch {
+	case c >= '0' && c <= '9':
+		return 0
+	case (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z'):
+		return int(c)
+	case c == '~':
+		return -1
+	default:
+		return int(c) + 256
+	}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func debianVerRevCmp(a, b string) int {
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		for (i < len(a) && !isDigit(a[i])) || (j < len(b) && !isDigit(b[j])) {
+			if c := compareInts(debianOrder(a, i), debianOrder(b, j)); c != 0 {
+				return c
+			}
+			i++
+			j++
+		}
+		for i < len(a) && a[i] == '0' {
+			i++
+		}
+		for j < len(b) && b[j] == '0' {
+			j++
+		}
+		firstDiff := 0
+		for i < len(a) && isDigit(a[i]) && j < len(b) && isDigit(b[j]) {
+			if firstDiff == 0 {
+				firstDiff = compareInts(int(a[i]), int(b[j]))
+			}
+			i++
+			j++
+		}
+		if i < len(a) && isDigit(a[i]) {
+			return 1
+		}
+		if j < len(b) && isDigit(b[j]) {
+			return -1
+		}
+		if firstDiff != 0 {
+			return firstDiff
+		}
+	}
+	return 0
+}
+
+// compareRPMVersions compares two RPM epoch:version-release strings following
+// the rules of rpm. It returns -1, 0 or 1 if a is older, equal or newer than
+// b.
+func compareRPMVersions(a, b string) int {
+	epochA, vrA, _ := splitEpoch(a)
+	epochB, vrB, _ := splitEpoch(b)
+	if c := compareInts(epochA, epochB); c != 0 {
+		return c
+	}
+	versionA, releaseA := splitRevision(vrA)
+	versionB, releaseB := splitRevision(vrB)
+	if c := rpmVerCmp(versionA, versionB); c != 0 {
+		return c
+	}
+	// A missing release matches any release.
+	if releaseA == "" || releaseB == "" {
+		return 0
+	}
+	return rpmVerCmp(releaseA, releaseB)
+}
+
+func isAlpha(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isAlnum(c byte) bool {
+	return isDigit(c) || isAlpha(c)
+}
+
+func rpmVerCmp(a, b string) int {
+	if a == b {
+		return 0
+	}
+	for len(a) > 0 || len(b) > 0 {
+		for len(a) > 0 && !isAlnum(a[0]) && a[0] != '~' {
+			a = a[1:]
+		}
+		for len(b) > 0 && !isAlnum(b[0]) && b[0] != '~' {
+			b = b[1:]
+		}
+
+		// The tilde sorts before everything else.
+		if strings.HasPrefix(a, "~") || strings.HasPrefix(b, "~") {
+			if !strings.HasPrefix(a, "~") {
+				return 1
+			}
+			if !strings.HasPrefix(b, "~") {
+				return -1
+			}
+			a, b = a[1:], b[1:]
+			continue
+		}
+
+		if len(a) == 0 || len(b) == 0 {
+			break
+		}
+
+		isNum := isDigit(a[0])
+		segment := func(s string) (string, string) {
+			i := 0
+			for i < len(s) && ((isNum && isDigit(s[i])) || (!isNum && isAlpha(s[i]))) {
+				i++
+			}
+			return s[:i], s[i:]
+		}
+		var segA, segB string
+		segA, a = segment(a)
+		segB, b = segment(b)
+
+		// Numeric segments are newer than alphabetic ones.
+		if segB == "" {
+			if isNum {
+				return 1
+			}
+			return -1
+		}
+
+		if isNum {
+			segA = strings.TrimLeft(segA, "0")
+			segB = strings.TrimLeft(segB, "0")
+			if c := compareInts(len(segA), len(segB)); c != 0 {
+				return c
+			}
+		}
+		if c := strings.Compare(segA, segB); c != 0 {
+			return c
+		}
+	}
+
+	switch {
+	case len(a) == 0 && len(b) == 0:
+		return 0
+	case len(a) == 0:
+		return -1
+	default:
+		return 1
+	}
+}
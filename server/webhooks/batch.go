@@ -0,0 +1,73 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/pkg/errors"
+)
+
+// WebhookHost is the representation of a host sent in the webhook payloads
+// that list hosts.
+type WebhookHost struct {
+	ID       uint   `json:"id"`
+	Hostname string `json:"hostname"`
+	URL      string `json:"url"`
+}
+
+// queuedHost is a host waiting in one of the webhook queues. AckID identifies
+// the queue entry to acknowledge once the host was sent.
+type queuedHost struct {
+	AckID    uint
+	HostID   uint
+	Hostname string
+}
+
+// sendQueuedHosts drains a webhook queue in batches of at most batchSize
+// hosts (all of them if batchSize is not positive). Each batch is passed to
+// post and only acknowledged once post succeeded, so that hosts that failed
+// to be sent stay queued for the next run.
+func sendQueuedHosts(
+	ctx context.Context,
+	serverURL *url.URL,
+	batchSize int,
+	list func(ctx context.Context, limit int) ([]queuedHost, error),
+	post func(ctx context.Context, hosts []WebhookHost) error,
+	ack func(ctx context.Context, ackIDs []uint) error,
+) error {
+	for {
+		queued, err := list(ctx, batchSize)
+		if err != nil {
+			return errors.Wrap(err, "listing queued hosts")
+		}
+		if len(queued) == 0 {
+			return nil
+		}
+
+		hosts := make([]WebhookHost, 0, len(queued))
+		ackIDs := make([]uint, 0, len(queued))
+		for _, h := range queued {
+			hostURL := *serverURL
+			hostURL.Path = path.Join(serverURL.Path, "hosts", fmt.Sprint(h.HostID))
+			hosts = append(hosts, WebhookHost{
+				ID:       h.HostID,
+				Hostname: h.Hostname,
+				URL:      hostURL.String(),
+			})
+			ackIDs = append(ackIDs, h.AckID)
+		}
+
+		if err := post(ctx, hosts); err != nil {
+			return err
+		}
+		if err := ack(ctx, ackIDs); err != nil {
+			return errors.Wrap(err, "acknowledging sent hosts")
+		}
+
+		if batchSize <= 0 || len(queued) < batchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendQueuedHosts(t *testing.T) {
+	serverURL, err := url.Parse("https://fleet.example.com/fleet")
+	require.NoError(t, err)
+
+	queue := []queuedHost{
+		{AckID: 1, HostID: 10, Hostname: "host10"},
+		{AckID: 2, HostID: 11, Hostname: "host11"},
+		{AckID: 3, HostID: 12, Hostname: "host12"},
+	}
+	list := func(ctx context.Context, limit int) ([]queuedHost, error) {
+		if limit <= 0 || len(queue) < limit {
+			return queue, nil
+		}
+		return queue[:limit], nil
+	}
+	ack := func(ctx context.Context, ackIDs []uint) error {
+		queue = queue[len(ackIDs):]
+		return nil
+	}
+
+	// Hosts stay queued when the request fails.
+	failPost := func(ctx context.Context, hosts []WebhookHost) error {
+		return errors.New("unavailable")
+	}
+	require.Error(t, sendQueuedHosts(context.Background(), serverURL, 2, list, failPost, ack))
+	assert.Len(t, queue, 3)
+
+	var batches [][]WebhookHost
+	post := func(ctx context.Context, hosts []WebhookHost) error {
+		batches = append(batches, hosts)
+		return nil
+	}
+	require.NoError(t, sendQueuedHosts(context.Background(), serverURL, 2, list, post, ack))
+	require.Len(t, batches, 2)
+	assert.Equal(t, []WebhookHost{
+		{ID: 10, Hostname: "host10", URL: "https://fleet.example.com/fleet/hosts/10"},
+		{ID: 11, Hostname: "host11", URL: "https://fleet.example.com/fleet/hosts/11"},
+	}, batches[0])
+	assert.Equal(t, []WebhookHost{
+		{ID: 12, Hostname: "host12", URL: "https://fleet.example.com/fleet/hosts/12"},
+	}, batches[1])
+	assert.Empty(t, queue)
+
+	// Nothing is posted when the queue is empty.
+	batches = nil
+	require.NoError(t, sendQueuedHosts(context.Background(), serverURL, 0, list, post, ack))
+	assert.Empty(t, batches)
+}
@@ -0,0 +1,75 @@
+package webhooks
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// TriggerFailingPoliciesWebhook sends the hosts that started failing any of
+// the policies configured in the failing policies webhook to the destination
+// URL. Hosts are sent in batches of at most HostBatchSize hosts per request,
+// and are only removed from the queue once their request succeeded.
+func TriggerFailingPoliciesWebhook(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	appConfig *fleet.AppConfig,
+) error {
+	settings := appConfig.WebhookSettings.FailingPoliciesWebhook
+	if !settings.Enable {
+		return nil
+	}
+
+	level.Debug(logger).Log("enabled", "true")
+
+	serverURL, err := url.Parse(appConfig.ServerSettings.ServerURL)
+	if err != nil {
+		return errors.Wrap(err, "parsing server URL")
+	}
+
+	for _, policyID := range settings.PolicyIDs {
+		policy, err := ds.Policy(ctx, policyID)
+		if err != nil {
+			// The policy may have been deleted after being configured in
+			// the webhook, keep going with the rest.
+			level.Error(logger).Log("msg", "getting policy", "policy_id", policyID, "err", err)
+			continue
+		}
+
+		err = sendQueuedHosts(ctx, serverURL, settings.HostBatchSize,
+			func(ctx context.Context, limit int) ([]queuedHost, error) {
+				failing, err := ds.ListQueuedFailingPolicyHosts(ctx, policyID, limit)
+				if err != nil {
+					return nil, err
+				}
+				queued := make([]queuedHost, 0, len(failing))
+				for _, h := range failing {
+					queued = append(queued, queuedHost{AckID: h.ID, HostID: h.HostID, Hostname: h.Hostname})
+				}
+				return queued, nil
+			},
+			func(ctx context.Context, hosts []WebhookHost) error {
+				payload := map[string]interface{}{
+					"policy":        policy,
+					"failing_hosts": hosts,
+				}
+				return errors.Wrapf(
+					server.PostJSONWithTimeout(ctx, settings.DestinationURL, &payload),
+					"posting to %s", settings.DestinationURL,
+				)
+			},
+			ds.DeleteQueuedFailingPolicyHosts,
+		)
+		if err != nil {
+			return errors.Wrapf(err, "sending failing hosts for policy %d", policyID)
+		}
+	}
+
+	return nil
+}
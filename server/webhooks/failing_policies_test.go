@@ -0,0 +1,113 @@
+package webhooks
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerFailingPoliciesWebhook(t *testing.T) {
+	ds := new(mock.Store)
+
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestBodyBytes, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, string(requestBodyBytes))
+	}))
+	defer ts.Close()
+
+	ac := &fleet.AppConfig{
+		ServerSettings: fleet.ServerSettings{
+			ServerURL: "https://fleet.example.com",
+		},
+		WebhookSettings: fleet.WebhookSettings{
+			FailingPoliciesWebhook: fleet.FailingPoliciesWebhookSettings{
+				Enable:         true,
+				DestinationURL: ts.URL,
+				PolicyIDs:      []uint{1},
+				HostBatchSize:  2,
+			},
+		},
+	}
+
+	mockTime := time.Date(2021, 9, 21, 0, 0, 0, 0, time.UTC)
+	ds.PolicyFunc = func(ctx context.Context, id uint) (*fleet.Policy, error) {
+		return &fleet.Policy{
+			ID:               id,
+			Name:             "disk encryption",
+			Query:            "select 1 from disk_encryption where encrypted = 1;",
+			Resolution:       "Turn on FileVault",
+			Platform:         "darwin",
+			FailingHostCount: 3,
+			UpdateCreateTimestamps: fleet.UpdateCreateTimestamps{
+				CreateTimestamp: fleet.CreateTimestamp{CreatedAt: mockTime},
+				UpdateTimestamp: fleet.UpdateTimestamp{UpdatedAt: mockTime},
+			},
+		}, nil
+	}
+
+	queue := []*fleet.PolicyFailingHost{
+		{ID: 1, PolicyID: 1, HostID: 10, Hostname: "host10"},
+		{ID: 2, PolicyID: 1, HostID: 11, Hostname: "host11"},
+		{ID: 3, PolicyID: 1, HostID: 12, Hostname: "host12"},
+	}
+	ds.ListQueuedFailingPolicyHostsFunc = func(ctx context.Context, policyID uint, limit int) ([]*fleet.PolicyFailingHost, error) {
+		assert.Equal(t, uint(1), policyID)
+		assert.Equal(t, 2, limit)
+		if len(queue) < limit {
+			return queue, nil
+		}
+		return queue[:limit], nil
+	}
+	ds.DeleteQueuedFailingPolicyHostsFunc = func(ctx context.Context, ids []uint) error {
+		queue = queue[len(ids):]
+		return nil
+	}
+
+	require.NoError(t, TriggerFailingPoliciesWebhook(context.Background(), ds, kitlog.NewNopLogger(), ac))
+	require.Len(t, requests, 2)
+	assert.JSONEq(
+		t,
+		`{
+			"policy": {
+				"id": 1,
+				"name": "disk encryption",
+				"query": "select 1 from disk_encryption where encrypted = 1;",
+				"description": "",
+				"resolution": "Turn on FileVault",
+				"platform": "darwin",
+				"query_id": null,
+				"team_id": null,
+				"passing_host_count": 0,
+				"failing_host_count": 3,
+				"created_at": "2021-09-21T00:00:00Z",
+				"updated_at": "2021-09-21T00:00:00Z"
+			},
+			"failing_hosts": [
+				{"id": 10, "hostname": "host10", "url": "https://fleet.example.com/hosts/10"},
+				{"id": 11, "hostname": "host11", "url": "https://fleet.example.com/hosts/11"}
+			]
+		}`,
+		requests[0],
+	)
+	assert.Contains(t, requests[1], `"failing_hosts":[{"id":12,"hostname":"host12","url":"https://fleet.example.com/hosts/12"}]`)
+	assert.Empty(t, queue)
+
+	// Nothing is sent when the webhook is disabled.
+	requests = nil
+	ac.WebhookSettings.FailingPoliciesWebhook.Enable = false
+	ds.ListQueuedFailingPolicyHostsFuncInvoked = false
+	require.NoError(t, TriggerFailingPoliciesWebhook(context.Background(), ds, kitlog.NewNopLogger(), ac))
+	assert.False(t, ds.ListQueuedFailingPolicyHostsFuncInvoked)
+	assert.Empty(t, requests)
+}
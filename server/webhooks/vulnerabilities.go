@@ -0,0 +1,86 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/fleetdm/fleet/v4/server"
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/go-kit/kit/log/level"
+	"github.com/pkg/errors"
+)
+
+// VulnerabilityPayload is the payload sent to the vulnerabilities webhook for
+// each CVE.
+type VulnerabilityPayload struct {
+	CVE           string        `json:"cve"`
+	DetailsLink   string        `json:"details_link"`
+	HostsAffected []WebhookHost `json:"hosts_affected"`
+}
+
+// TriggerVulnerabilitiesWebhook sends the hosts newly found to be affected by
+// CVEs to the destination URL, in one request per CVE with at most
+// HostBatchSize hosts each. Hosts are only marked as sent once their request
+// succeeded.
+func TriggerVulnerabilitiesWebhook(
+	ctx context.Context,
+	ds fleet.Datastore,
+	logger kitlog.Logger,
+	appConfig *fleet.AppConfig,
+) error {
+	settings := appConfig.WebhookSettings.VulnerabilitiesWebhook
+	if !settings.Enable {
+		return nil
+	}
+
+	level.Debug(logger).Log("enabled", "true")
+
+	serverURL, err := url.Parse(appConfig.ServerSettings.ServerURL)
+	if err != nil {
+		return errors.Wrap(err, "parsing server URL")
+	}
+
+	cves, err := ds.ListQueuedVulnerableCVEs(ctx)
+	if err != nil {
+		return errors.Wrap(err, "listing queued cves")
+	}
+
+	for _, cve := range cves {
+		err = sendQueuedHosts(ctx, serverURL, settings.HostBatchSize,
+			func(ctx context.Context, limit int) ([]queuedHost, error) {
+				vulnerable, err := ds.ListQueuedVulnerableHosts(ctx, cve, limit)
+				if err != nil {
+					return nil, err
+				}
+				queued := make([]queuedHost, 0, len(vulnerable))
+				for _, h := range vulnerable {
+					queued = append(queued, queuedHost{AckID: h.HostID, HostID: h.HostID, Hostname: h.Hostname})
+				}
+				return queued, nil
+			},
+			func(ctx context.Context, hosts []WebhookHost) error {
+				payload := map[string]interface{}{
+					"vulnerability": VulnerabilityPayload{
+						CVE:           cve,
+						DetailsLink:   fmt.Sprintf("https://nvd.nist.gov/vuln/detail/%s", cve),
+						HostsAffected: hosts,
+					},
+				}
+				return errors.Wrapf(
+					server.PostJSONWithTimeout(ctx, settings.DestinationURL, &payload),
+					"posting to %s", settings.DestinationURL,
+				)
+			},
+			func(ctx context.Context, hostIDs []uint) error {
+				return ds.MarkVulnerableHostsSent(ctx, cve, hostIDs)
+			},
+		)
+		if err != nil {
+			return errors.Wrapf(err, "sending hosts for %s", cve)
+		}
+	}
+
+	return nil
+}
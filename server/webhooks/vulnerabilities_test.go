@@ -0,0 +1,94 @@
+package webhooks
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/fleetdm/fleet/v4/server/fleet"
+	"github.com/fleetdm/fleet/v4/server/mock"
+	kitlog "github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerVulnerabilitiesWebhook(t *testing.T) {
+	ds := new(mock.Store)
+
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestBodyBytes, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		requests = append(requests, string(requestBodyBytes))
+	}))
+	defer ts.Close()
+
+	ac := &fleet.AppConfig{
+		ServerSettings: fleet.ServerSettings{
+			ServerURL: "https://fleet.example.com",
+		},
+		WebhookSettings: fleet.WebhookSettings{
+			VulnerabilitiesWebhook: fleet.VulnerabilitiesWebhookSettings{
+				Enable:         true,
+				DestinationURL: ts.URL,
+				HostBatchSize:  2,
+			},
+		},
+	}
+
+	queue := map[string][]*fleet.VulnerableHost{
+		"CVE-2021-3449": {
+			{HostID: 10, Hostname: "host10"},
+			{HostID: 11, Hostname: "host11"},
+			{HostID: 12, Hostname: "host12"},
+		},
+		"CVE-2021-3450": {
+			{HostID: 10, Hostname: "host10"},
+		},
+	}
+	ds.ListQueuedVulnerableCVEsFunc = func(ctx context.Context) ([]string, error) {
+		return []string{"CVE-2021-3449", "CVE-2021-3450"}, nil
+	}
+	ds.ListQueuedVulnerableHostsFunc = func(ctx context.Context, cve string, limit int) ([]*fleet.VulnerableHost, error) {
+		assert.Equal(t, 2, limit)
+		if len(queue[cve]) < limit {
+			return queue[cve], nil
+		}
+		return queue[cve][:limit], nil
+	}
+	ds.MarkVulnerableHostsSentFunc = func(ctx context.Context, cve string, hostIDs []uint) error {
+		queue[cve] = queue[cve][len(hostIDs):]
+		return nil
+	}
+
+	require.NoError(t, TriggerVulnerabilitiesWebhook(context.Background(), ds, kitlog.NewNopLogger(), ac))
+	require.Len(t, requests, 3)
+	assert.JSONEq(
+		t,
+		`{
+			"vulnerability": {
+				"cve": "CVE-2021-3449",
+				"details_link": "https://nvd.nist.gov/vuln/detail/CVE-2021-3449",
+				"hosts_affected": [
+					{"id": 10, "hostname": "host10", "url": "https://fleet.example.com/hosts/10"},
+					{"id": 11, "hostname": "host11", "url": "https://fleet.example.com/hosts/11"}
+				]
+			}
+		}`,
+		requests[0],
+	)
+	assert.Contains(t, requests[1], `"hosts_affected":[{"id":12,"hostname":"host12","url":"https://fleet.example.com/hosts/12"}]`)
+	assert.Contains(t, requests[2], `"cve":"CVE-2021-3450"`)
+	assert.Empty(t, queue["CVE-2021-3449"])
+	assert.Empty(t, queue["CVE-2021-3450"])
+
+	// Nothing is sent when the webhook is disabled.
+	requests = nil
+	ac.WebhookSettings.VulnerabilitiesWebhook.Enable = false
+	ds.ListQueuedVulnerableCVEsFuncInvoked = false
+	require.NoError(t, TriggerVulnerabilitiesWebhook(context.Background(), ds, kitlog.NewNopLogger(), ac))
+	assert.False(t, ds.ListQueuedVulnerableCVEsFuncInvoked)
+	assert.Empty(t, requests)
+}